@@ -8,6 +8,7 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -18,7 +19,9 @@ import (
 	"github.com/mosleyit/reolink_server/internal/events"
 	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/internal/storage/repository"
+	"github.com/mosleyit/reolink_server/internal/storage/retention"
 )
 
 var (
@@ -48,6 +51,14 @@ func main() {
 		zap.String("version", version),
 		zap.String("build_time", buildTime),
 	)
+	for _, deprecation := range cfg.Deprecations {
+		logger.Warn("Deprecated configuration", zap.String("setting", deprecation))
+	}
+
+	// Fail fast on output directories the server cannot write to
+	if err := cfg.CheckOutputDirs(); err != nil {
+		logger.Fatal("Output directory check failed", zap.Error(err))
+	}
 
 	// Initialize database connection first
 	ctx := context.Background()
@@ -67,6 +78,14 @@ func main() {
 	eventRepo := repository.NewEventRepository(database)
 	recordingRepo := repository.NewRecordingRepository(database)
 	userRepo := repository.NewUserRepository(database)
+	configHistoryRepo := repository.NewConfigHistoryRepository(database)
+	snapshotRepo := repository.NewSnapshotRepository(database)
+	apiKeyRepo := repository.NewAPIKeyRepository(database)
+	cameraGroupRepo := repository.NewCameraGroupRepository(database)
+	// Back unique camera names with an index so concurrent adds cannot both pass the check
+	if err := cameraRepo.EnforceUniqueNames(ctx, cfg.Cameras.UniqueNames); err != nil {
+		logger.Warn("Failed to update unique camera name index, names are only checked before saving", zap.Error(err))
+	}
 	logger.Info("Database repositories initialized",
 		zap.String("camera_repo", "ready"),
 		zap.String("event_repo", "ready"),
@@ -75,12 +94,112 @@ func main() {
 
 	// Initialize camera manager with repository
 	cameraManager := camera.NewManager(nil, cameraRepo)
+	if cfg.Cameras.TokenCacheFile != "" {
+		tokenCache, err := camera.NewFileTokenCache(cfg.Cameras.TokenCacheFile)
+		if err != nil {
+			logger.Warn("Failed to load camera token cache, cameras will log in again", zap.Error(err))
+		} else {
+			cameraManager.SetTokenCache(tokenCache)
+		}
+	}
+	hostPolicy, err := camera.NewHostPolicy(cfg.Cameras.AllowedNetworks, cfg.Cameras.DeniedNetworks)
+	if err != nil {
+		logger.Fatal("Invalid camera network policy", zap.Error(err))
+	}
+	cameraManager.SetHostPolicy(hostPolicy)
+	connectionProfiles := make(map[string]camera.ConnectionProfile, len(cfg.Cameras.ConnectionProfiles))
+	for name, profile := range cfg.Cameras.ConnectionProfiles {
+		connectionProfiles[name] = camera.ConnectionProfile{
+			Timeout:       profile.Timeout,
+			MaxRetries:    profile.MaxRetries,
+			KeepAlive:     profile.KeepAlive,
+			RTSPTransport: profile.RTSPTransport,
+		}
+	}
+	cameraManager.SetConnectionProfiles(connectionProfiles)
+	if cfg.Cameras.WorkerPoolSize > 0 {
+		cameraManager.SetHealthCheckWorkers(cfg.Cameras.WorkerPoolSize)
+	}
+	if cfg.Cameras.LoadRetryInterval != 0 {
+		cameraManager.SetLoadRetryInterval(cfg.Cameras.LoadRetryInterval)
+	}
+	if cfg.Cameras.FeatureCheckInterval > 0 {
+		cameraManager.SetFeatureCheckInterval(cfg.Cameras.FeatureCheckInterval)
+	}
+	cameraManager.SetHddFullPercent(cfg.Cameras.HddFullPercent)
 	logger.Info("Camera manager initialized")
 
 	// Initialize event processor
-	eventProcessor := events.NewProcessor(cameraManager, nil)
+	processorConfig := events.DefaultConfig()
+	if cfg.Server.ShutdownTimeout > 0 {
+		processorConfig.DrainTimeout = cfg.Server.ShutdownTimeout
+	}
+	if cfg.Events.Workers > 0 {
+		processorConfig.MaxWorkers = cfg.Events.Workers
+	}
+	if cfg.Events.CameraQuota > 0 {
+		processorConfig.EventQuota = cfg.Events.CameraQuota
+	}
+	if cfg.Events.CameraQuotaWindow > 0 {
+		processorConfig.EventQuotaWindow = cfg.Events.CameraQuotaWindow
+	}
+	if cfg.Events.Cooldown > 0 {
+		processorConfig.EventCooldown = cfg.Events.Cooldown
+	}
+	if len(cfg.Events.Cooldowns) > 0 {
+		processorConfig.EventCooldowns = make(map[models.EventType]time.Duration, len(cfg.Events.Cooldowns))
+		for eventType, cooldown := range cfg.Events.Cooldowns {
+			processorConfig.EventCooldowns[models.EventType(eventType)] = cooldown
+		}
+	}
+	if cfg.Events.SnapshotMotion.Enabled {
+		processorConfig.SnapshotMotionEnabled = true
+		if cfg.Events.SnapshotMotion.Interval > 0 {
+			processorConfig.SnapshotMotionPeriod = cfg.Events.SnapshotMotion.Interval
+		}
+		if cfg.Events.SnapshotMotion.Threshold > 0 {
+			processorConfig.SnapshotMotionThreshold = cfg.Events.SnapshotMotion.Threshold
+		}
+		if cfg.Events.SnapshotMotion.Timeout > 0 {
+			processorConfig.SnapshotTimeout = cfg.Events.SnapshotMotion.Timeout
+		}
+	}
+	eventProcessor := events.NewProcessor(cameraManager, processorConfig)
 	logger.Info("Event processor initialized")
 
+	// Initialize automation rules
+	var ruleEngine *events.RuleEngine
+	if len(cfg.Events.Rules) > 0 {
+		rules := make([]events.Rule, 0, len(cfg.Events.Rules))
+		for _, rc := range cfg.Events.Rules {
+			rule := events.Rule{
+				Name:        rc.Name,
+				Tags:        rc.Tags,
+				ActiveFrom:  rc.ActiveFrom,
+				ActiveUntil: rc.ActiveUntil,
+				Cooldown:    rc.Cooldown,
+			}
+			for _, eventType := range rc.EventTypes {
+				rule.EventTypes = append(rule.EventTypes, models.EventType(eventType))
+			}
+			for _, action := range rc.Actions {
+				rule.Actions = append(rule.Actions, events.RuleAction{Type: action.Type, Duration: action.Duration})
+			}
+			rules = append(rules, rule)
+		}
+
+		var err error
+		ruleEngine, err = events.NewRuleEngine(cameraManager, rules)
+		if err != nil {
+			logger.Fatal("Invalid automation rules", zap.Error(err))
+		}
+		eventProcessor.Subscribe(ruleEngine)
+		logger.Info("Automation rules loaded", zap.Int("rules", len(rules)))
+	}
+
+	// Persist events to the database so the events API can serve them
+	eventProcessor.Subscribe(events.NewDBStore(eventRepo))
+
 	// Initialize event store (Redis)
 	var eventStore *events.Store
 	if cfg.Redis.Host != "" {
@@ -101,20 +220,46 @@ func main() {
 		}
 	}
 
+	// Publish events to MQTT for Home Assistant
+	var mqttPublisher *events.MQTTPublisher
+	if cfg.MQTT.Host != "" {
+		var err error
+		mqttPublisher, err = events.NewMQTTPublisher(&events.MQTTConfig{
+			BrokerURL:       cfg.MQTT.GetBrokerURL(),
+			ClientID:        cfg.MQTT.ClientID,
+			Username:        cfg.MQTT.Username,
+			Password:        cfg.MQTT.Password,
+			TopicPrefix:     cfg.MQTT.TopicPrefix,
+			DiscoveryPrefix: cfg.MQTT.DiscoveryPrefix,
+		})
+		if err != nil {
+			logger.Warn("Failed to initialize MQTT publisher, events will not be published to MQTT",
+				zap.Error(err))
+		} else {
+			eventProcessor.Subscribe(mqttPublisher)
+			logger.Info("MQTT publisher initialized and subscribed")
+		}
+	}
+
 	// Start event processor
 	if err := eventProcessor.Start(ctx); err != nil {
 		logger.Fatal("Failed to start event processor", zap.Error(err))
 	}
 	logger.Info("Event processor started")
 
-	// Load cameras from database into camera manager
+	// Load cameras from database into camera manager. Cameras that fail to
+	// load are retried by the health checks and reported offline meanwhile.
+	cameraManager.SetOnLoaded(func(client *camera.CameraClient) {
+		eventProcessor.AddCamera(ctx, client)
+	})
+	cameraManager.SetOnRemoved(eventProcessor.RemoveCamera)
 	cameras, err := cameraRepo.List(ctx)
 	if err != nil {
 		logger.Warn("Failed to load cameras from database", zap.Error(err))
 	} else {
 		loadedCount := 0
 		for _, camera := range cameras {
-			if err := cameraManager.AddCamera(ctx, camera); err != nil {
+			if err := cameraManager.LoadCamera(ctx, camera); err != nil {
 				logger.Error("Failed to add camera to manager",
 					zap.Error(err),
 					zap.String("camera_id", camera.ID),
@@ -138,6 +283,11 @@ func main() {
 	go cameraManager.StartHealthMonitoring(ctx)
 	logger.Info("Camera health monitoring started")
 
+	// Keep the camera manager in sync with the database
+	go cameraManager.StartReconciliation(ctx, func(client *camera.CameraClient) {
+		eventProcessor.AddCamera(ctx, client)
+	})
+
 	// Create event processor adapter for the router
 	type eventProcessorAdapter struct {
 		processor *events.Processor
@@ -158,8 +308,32 @@ func main() {
 		EventRepo:         eventRepo,
 		RecordingRepo:     recordingRepo,
 		UserRepo:          userRepo,
+		ConfigHistoryRepo: configHistoryRepo,
+		APIKeyRepo:        apiKeyRepo,
+		CameraGroupRepo:   cameraGroupRepo,
 	})
 
+	// Reboot cameras at their scheduled times, postponing while they are streamed
+	go cameraManager.StartRebootScheduler(ctx, router.IsStreaming)
+
+	// Keep connections of always-ready cameras warm
+	go cameraManager.StartKeepAlive(ctx, cfg.Cameras.KeepAliveInterval)
+
+	// Capture scheduled snapshots for timelapses
+	go cameraManager.StartSnapshotScheduler(ctx, snapshotRepo, cfg.Cameras.SnapshotDir)
+
+	// Start and stop PTZ patrols at their scheduled times
+	go cameraManager.StartPatrolScheduler(ctx)
+
+	// Delete events and recordings past their retention period
+	// Only files in the server's own directories are removed, never camera paths
+	retentionCleaner := retention.NewCleaner(eventRepo, recordingRepo)
+	retentionCleaner.SetOwnedDirs(cfg.Streams.ClipDir, cfg.Cameras.SnapshotDir)
+	retentionCleaner.SetThumbnailDir(cfg.Streams.ThumbnailDir)
+	retentionWorker := retention.NewWorker(retentionCleaner,
+		cfg.Retention.Interval, cfg.Retention.EventDays, cfg.Retention.RecordingDays)
+	go retentionWorker.Start(ctx)
+
 	// Create HTTP server
 	server := &http.Server{
 		Addr:         cfg.Server.GetServerAddr(),
@@ -169,17 +343,37 @@ func main() {
 		IdleTimeout:  cfg.Server.IdleTimeout,
 	}
 
+	// Configure HTTPS (if enabled)
+	redirectServer, err := api.ConfigureTLS(server, cfg.Server)
+	if err != nil {
+		logger.Fatal("Failed to configure TLS", zap.Error(err))
+	}
+
 	// Start server in a goroutine
 	go func() {
 		logger.Info("HTTP server starting",
 			zap.String("address", server.Addr),
+			zap.Bool("tls", server.TLSConfig != nil),
 		)
 
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		if err := api.ListenAndServe(server); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("HTTP server failed", zap.Error(err))
 		}
 	}()
 
+	// Start HTTP to HTTPS redirect server
+	if redirectServer != nil {
+		go func() {
+			logger.Info("HTTP redirect server starting",
+				zap.String("address", redirectServer.Addr),
+			)
+
+			if err := redirectServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("HTTP redirect server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -194,12 +388,21 @@ func main() {
 	if err := server.Shutdown(ctx); err != nil {
 		logger.Error("Server forced to shutdown", zap.Error(err))
 	}
+	if redirectServer != nil {
+		_ = redirectServer.Shutdown(ctx)
+	}
 
-	// Stop event processor
+	// Stop event processor; buffered events are drained to the store before it is closed
 	if err := eventProcessor.Stop(); err != nil {
 		logger.Error("Failed to stop event processor", zap.Error(err))
 	}
 
+	// Let triggered automation actions finish before cameras are disconnected,
+	// switching off white LEDs rules left on for a while
+	if ruleEngine != nil {
+		ruleEngine.Stop()
+	}
+
 	// Close event store
 	if eventStore != nil {
 		if err := eventStore.Close(); err != nil {
@@ -207,6 +410,13 @@ func main() {
 		}
 	}
 
+	// Disconnect from the MQTT broker
+	if mqttPublisher != nil {
+		if err := mqttPublisher.Close(); err != nil {
+			logger.Error("Failed to close MQTT publisher", zap.Error(err))
+		}
+	}
+
 	// Stop camera manager
 	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
 	defer shutdownCancel()
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+
+	"github.com/mosleyit/reolink_server/internal/api/middleware"
 	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/pkg/utils"
@@ -45,3 +49,119 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	// Return response
 	utils.RespondJSON(w, http.StatusOK, loginResp)
 }
+
+// SSEToken handles POST /api/v1/auth/sse-token, issuing a short-lived token
+// that browser EventSource clients pass as ?token= to the SSE endpoint
+func (h *AuthHandler) SSEToken(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID := middleware.GetUserID(ctx)
+	if userID == "" {
+		utils.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required", nil)
+		return
+	}
+
+	tokenResp, err := h.authService.IssueSSEToken(userID, middleware.GetUsername(ctx))
+	if err != nil {
+		utils.RespondInternalError(w, "Failed to issue SSE token")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, tokenResp)
+}
+
+// CreateAPIKey handles POST /api/v1/auth/keys, minting an API key for the
+// current user. The key is only returned in this response.
+func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	userID, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondBadRequest(w, "Invalid request body", nil)
+		return
+	}
+	if req.Name == "" {
+		utils.RespondBadRequest(w, "Name is required", nil)
+		return
+	}
+	if req.Scope == "" {
+		req.Scope = models.APIKeyScopeRead
+	}
+	if !models.ValidAPIKeyScope(req.Scope) {
+		utils.RespondBadRequest(w, "Scope must be read or control", nil)
+		return
+	}
+
+	resp, err := h.authService.CreateAPIKey(ctx, userID, &req)
+	if err != nil {
+		respondAPIKeyError(w, err, "Failed to create API key")
+		return
+	}
+
+	utils.RespondCreated(w, resp)
+}
+
+// ListAPIKeys handles GET /api/v1/auth/keys, listing the API keys of the current user
+func (h *AuthHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		respondAPIKeyError(w, err, "Failed to list API keys")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, keys)
+}
+
+// RevokeAPIKey handles DELETE /api/v1/auth/keys/{id}, revoking an API key of the current user
+func (h *AuthHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID, ok := requireSession(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		respondAPIKeyError(w, err, "Failed to revoke API key")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "API key revoked",
+	})
+}
+
+// requireSession returns the ID of the user signed in with a session token.
+// API keys cannot manage API keys, so a leaked key cannot mint more.
+func requireSession(w http.ResponseWriter, r *http.Request) (string, bool) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == "" {
+		utils.RespondError(w, http.StatusUnauthorized, "UNAUTHORIZED", "Authentication required", nil)
+		return "", false
+	}
+	if middleware.GetAPIKeyScope(r.Context()) != "" {
+		utils.RespondForbidden(w, "API keys cannot manage API keys")
+		return "", false
+	}
+	return userID, true
+}
+
+// respondAPIKeyError writes the error response for a failed API key operation
+func respondAPIKeyError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrAPIKeyNotFound):
+		utils.RespondNotFound(w, "API key not found")
+	case errors.Is(err, service.ErrAPIKeysDisabled):
+		utils.RespondError(w, http.StatusServiceUnavailable, "API_KEYS_DISABLED", "API keys are not configured", nil)
+	default:
+		utils.RespondInternalError(w, message)
+	}
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/api/middleware"
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// memoryAPIKeys is an in-memory API key repository
+type memoryAPIKeys struct {
+	keys []*models.APIKey
+}
+
+func (m *memoryAPIKeys) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = "key-1"
+	m.keys = append(m.keys, key)
+	return nil
+}
+
+func (m *memoryAPIKeys) GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	return nil, service.ErrAPIKeyNotFound
+}
+
+func (m *memoryAPIKeys) ListByUserID(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	return m.keys, nil
+}
+
+func (m *memoryAPIKeys) Revoke(ctx context.Context, id, userID string) error {
+	for _, key := range m.keys {
+		if key.ID == id && key.UserID == userID {
+			return nil
+		}
+	}
+	return service.ErrAPIKeyNotFound
+}
+
+func (m *memoryAPIKeys) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	return nil
+}
+
+func newAPIKeyTestHandler() (*AuthHandler, *memoryAPIKeys) {
+	keys := &memoryAPIKeys{}
+	authService := service.NewAuthService(nil, "test-secret-with-at-least-32-characters", time.Hour, time.Minute)
+	authService.SetAPIKeys(keys)
+	return NewAuthHandler(authService), keys
+}
+
+// asUser returns req authenticated as user-1, with an API key of scope if not empty
+func asUser(req *http.Request, scope models.APIKeyScope) *http.Request {
+	ctx := context.WithValue(req.Context(), middleware.UserIDKey, "user-1")
+	if scope != "" {
+		ctx = context.WithValue(ctx, middleware.APIKeyScopeKey, string(scope))
+	}
+	return req.WithContext(ctx)
+}
+
+func TestAuthHandler_CreateAPIKey(t *testing.T) {
+	handler, keys := newAPIKeyTestHandler()
+
+	body := `{"name":"home-assistant","scope":"control"}`
+	req := asUser(httptest.NewRequest(http.MethodPost, "/api/v1/auth/keys", bytes.NewReader([]byte(body))), "")
+	w := httptest.NewRecorder()
+	handler.CreateAPIKey(w, req)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp struct {
+		Data models.CreateAPIKeyResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.NotEmpty(t, resp.Data.Key)
+	assert.Equal(t, models.APIKeyScopeControl, resp.Data.APIKey.Scope)
+	assert.NotContains(t, w.Body.String(), keys.keys[0].KeyHash, "the hash is not returned")
+
+	// Listing keys never shows the key again
+	w = httptest.NewRecorder()
+	handler.ListAPIKeys(w, asUser(httptest.NewRequest(http.MethodGet, "/api/v1/auth/keys", nil), ""))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "home-assistant")
+	assert.NotContains(t, w.Body.String(), resp.Data.Key)
+}
+
+func TestAuthHandler_CreateAPIKey_Invalid(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		scope      models.APIKeyScope
+		wantStatus int
+	}{
+		{"missing name", `{"scope":"read"}`, "", http.StatusBadRequest},
+		{"unknown scope", `{"name":"nvr","scope":"admin"}`, "", http.StatusBadRequest},
+		{"invalid json", `{`, "", http.StatusBadRequest},
+		{"authenticated with api key", `{"name":"nvr","scope":"read"}`, models.APIKeyScopeControl, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, keys := newAPIKeyTestHandler()
+			req := asUser(httptest.NewRequest(http.MethodPost, "/api/v1/auth/keys", bytes.NewReader([]byte(tt.body))), tt.scope)
+			w := httptest.NewRecorder()
+			handler.CreateAPIKey(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Empty(t, keys.keys)
+		})
+	}
+}
+
+func TestAuthHandler_RevokeAPIKey(t *testing.T) {
+	handler, keys := newAPIKeyTestHandler()
+	keys.keys = []*models.APIKey{{ID: "key-1", UserID: "user-1"}}
+
+	revoke := func(id string) int {
+		req := asUser(httptest.NewRequest(http.MethodDelete, "/api/v1/auth/keys/"+id, nil), "")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.RevokeAPIKey(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, revoke("key-1"))
+	assert.Equal(t, http.StatusNotFound, revoke("key-2"))
+}
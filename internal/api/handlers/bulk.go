@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// decodeBulkRequest decodes the IDs of a bulk request, responding with an
+// error and returning false when they are missing or too many
+func decodeBulkRequest(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var req models.BulkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return nil, false
+	}
+
+	if len(req.IDs) == 0 {
+		utils.RespondBadRequest(w, "ids must list at least one ID", nil)
+		return nil, false
+	}
+	if len(req.IDs) > models.MaxBulkIDs {
+		utils.RespondBadRequest(w, fmt.Sprintf("ids must not list more than %d IDs", models.MaxBulkIDs), nil)
+		return nil, false
+	}
+
+	return req.IDs, true
+}
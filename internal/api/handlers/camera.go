@@ -1,10 +1,16 @@
 package handlers
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
@@ -22,17 +28,39 @@ type CameraServiceInterface interface {
 	AddCamera(ctx context.Context, camera *models.Camera) error
 	GetCamera(ctx context.Context, id string) (*models.Camera, error)
 	ListCameras(ctx context.Context) ([]*models.Camera, error)
+	ListCamerasByGroup(ctx context.Context, groupID string) ([]*models.Camera, error)
 	UpdateCamera(ctx context.Context, camera *models.Camera) error
 	DeleteCamera(ctx context.Context, id string) error
 	GetCameraStatus(ctx context.Context, id string) (*models.CameraStatus, error)
+	GetDeviceInfo(ctx context.Context, id string) (*models.CameraDeviceInfo, error)
 	GetCameraClient(id string) (*camera.CameraClient, error)
 	GetCameraEvents(ctx context.Context, cameraID string, limit, offset int) ([]*models.Event, error)
 	CountCameraEvents(ctx context.Context, cameraID string) (int, error)
+	RecordConfigBaseline(ctx context.Context, cam *models.Camera, configType string, current func() (interface{}, error))
+	RecordConfigChange(ctx context.Context, cam *models.Camera, configType string, changes interface{})
+	GetConfigHistory(ctx context.Context, cameraID, configType string, limit, offset int) ([]*models.CameraConfigVersion, error)
+	GetConfigVersion(ctx context.Context, cameraID, configType string, version int) (*models.CameraConfigVersion, error)
+	PurgeCameraHistory(ctx context.Context, cameraID string) (*models.CameraHistoryPurge, error)
+	ProbeCamera(ctx context.Context, id string, skipVerify *bool) (*camera.ProbeResult, error)
+	ProbeNewCamera(ctx context.Context, cam *models.Camera) *camera.ProbeResult
+	TestConnection(ctx context.Context, cam *models.Camera) (*camera.ConnectionTestResult, error)
+	GetSnapshot(ctx context.Context, id string, channel int, fresh bool) ([]byte, bool, error)
+	StreamSnapshot(ctx context.Context, id string, channel int, fresh bool) (*camera.SnapshotStream, bool, error)
+	RebootAndWait(ctx context.Context, id string, timeout time.Duration) (*camera.RebootStatus, error)
+	GetRebootStatus(id string) (*camera.RebootStatus, error)
 }
 
 // CameraHandler handles camera-related HTTP requests
 type CameraHandler struct {
 	cameraService CameraServiceInterface
+
+	// firmwareUploadTimeout is how long a firmware upload may go without
+	// receiving data; zero uses DefaultFirmwareUploadTimeout
+	firmwareUploadTimeout time.Duration
+
+	// snapshotStreaming streams snapshots from the camera instead of
+	// buffering them
+	snapshotStreaming bool
 }
 
 // NewCameraHandler creates a new camera handler
@@ -42,17 +70,70 @@ func NewCameraHandler(cameraService *service.CameraService) *CameraHandler {
 	}
 }
 
-// ListCameras handles GET /api/v1/cameras
+// SetSnapshotStreaming sets whether snapshots are streamed from the camera to
+// the client as they arrive rather than buffered in full first. Streamed
+// snapshots carry a Content-Length only when the camera announced one.
+func (h *CameraHandler) SetSnapshotStreaming(enabled bool) {
+	h.snapshotStreaming = enabled
+}
+
+// snapshotScheduleMessage explains invalid snapshot settings of a camera
+const snapshotScheduleMessage = "snapshot_interval must be a positive number of seconds when snapshot_enabled is set, and snapshot_channel must not be negative"
+
+// recentEventsLimit is how many events GetCamera embeds with
+// ?include=recent_events
+const recentEventsLimit = 5
+
+// eventModeMessage explains an invalid event mode of a camera
+const eventModeMessage = "event_mode must be onvif or poll, or empty to use ONVIF when the camera has it enabled"
+
+// patrolScheduleMessage explains invalid patrol settings of a camera
+const patrolScheduleMessage = "patrol_schedule must be a daily HH:MM-HH:MM window, and patrol_id and patrol_channel must not be negative"
+
+// respondCameraError responds to a failed camera operation. Unknown cameras
+// are 404, cameras that cannot be reached 503 and anything else is an error
+// from the camera's API, reported as 502 with the given code and message.
+func respondCameraError(w http.ResponseWriter, err error, code, message string) {
+	switch {
+	case errors.Is(err, camera.ErrNotFound), errors.Is(err, service.ErrCameraNotFound):
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+	case errors.Is(err, camera.ErrCircuitOpen), errors.Is(err, camera.ErrCameraOffline):
+		utils.RespondError(w, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE", "Camera is unavailable", nil)
+	default:
+		utils.RespondError(w, http.StatusBadGateway, code, message, nil)
+	}
+}
+
+// ListCameras handles GET /api/v1/cameras, optionally only the cameras of
+// the group given as ?group_id=, ordered by ?sort= and ?order=
 func (h *CameraHandler) ListCameras(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	query := r.URL.Query()
+
+	sortOrder, err := models.ParseSortOrder(query.Get("sort"), query.Get("order"), models.CameraSortFields, models.DefaultCameraSort)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
 
-	cameras, err := h.cameraService.ListCameras(ctx)
+	var cameras []*models.Camera
+	if groupID := query.Get("group_id"); groupID != "" {
+		cameras, err = h.cameraService.ListCamerasByGroup(ctx, groupID)
+	} else {
+		cameras, err = h.cameraService.ListCameras(ctx)
+	}
 	if err != nil {
 		logger.Error("Failed to list cameras", zap.Error(err))
 		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve cameras", nil)
 		return
 	}
 
+	// Cameras are listed by name; the list is not paginated, so other orders
+	// are applied here rather than in the query
+	if sortOrder != (models.SortOrder{}) {
+		models.SortCameras(cameras, sortOrder)
+	}
+
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
 		"cameras": cameras,
 		"total":   len(cameras),
@@ -77,6 +158,31 @@ func (h *CameraHandler) AddCamera(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !models.ValidRTSPTransport(req.RTSPTransport) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "rtsp_transport must be tcp or udp", nil)
+		return
+	}
+
+	if !models.ValidRebootTime(req.RebootTime) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "reboot_time must be a time of day in HH:MM format", nil)
+		return
+	}
+
+	if !models.ValidEventMode(req.EventMode) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", eventModeMessage, nil)
+		return
+	}
+
+	if !models.ValidSnapshotSchedule(req.SnapshotEnabled, req.SnapshotInterval, req.SnapshotChannel) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", snapshotScheduleMessage, nil)
+		return
+	}
+
+	if !models.ValidPatrolSchedule(req.PatrolSchedule, req.PatrolID, req.PatrolChannel) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", patrolScheduleMessage, nil)
+		return
+	}
+
 	// Set default port if not provided
 	if req.Port == 0 {
 		if req.UseHTTPS {
@@ -88,18 +194,35 @@ func (h *CameraHandler) AddCamera(w http.ResponseWriter, r *http.Request) {
 
 	// Create camera model
 	camera := &models.Camera{
-		Name:       req.Name,
-		Host:       req.Host,
-		Port:       req.Port,
-		Username:   req.Username,
-		Password:   req.Password,
-		UseHTTPS:   req.UseHTTPS,
-		SkipVerify: req.SkipVerify,
-		Status:     "offline",
+		Name:          req.Name,
+		Host:          req.Host,
+		Port:          req.Port,
+		Username:      req.Username,
+		Password:      req.Password,
+		UseHTTPS:      req.UseHTTPS,
+		SkipVerify:    req.SkipVerify,
+		RTSPTransport: req.RTSPTransport,
+		RebootTime:    req.RebootTime,
+		AlwaysReady:   req.AlwaysReady,
+		EventMode:     req.EventMode,
+		Status:        "offline",
+
+		ConnectionProfile: req.ConnectionProfile,
+
+		SnapshotEnabled:  req.SnapshotEnabled,
+		SnapshotInterval: req.SnapshotInterval,
+		SnapshotChannel:  req.SnapshotChannel,
+
+		PatrolSchedule: req.PatrolSchedule,
+		PatrolID:       req.PatrolID,
+		PatrolChannel:  req.PatrolChannel,
 	}
 
 	// Add camera via service
 	if err := h.cameraService.AddCamera(ctx, camera); err != nil {
+		if respondCameraRejected(w, err) {
+			return
+		}
 		logger.Error("Failed to add camera", zap.Error(err), zap.String("name", req.Name))
 		utils.RespondError(w, http.StatusInternalServerError, "ADD_CAMERA_ERROR", "Failed to add camera", nil)
 		return
@@ -109,11 +232,39 @@ func (h *CameraHandler) AddCamera(w http.ResponseWriter, r *http.Request) {
 	utils.RespondJSON(w, http.StatusCreated, camera)
 }
 
-// GetCamera handles GET /api/v1/cameras/{id}
+// respondCameraRejected responds to the service refusing the settings of a
+// camera being added or updated, reporting whether it did
+func respondCameraRejected(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, service.ErrCameraNameTaken):
+		utils.RespondError(w, http.StatusConflict, "CAMERA_NAME_TAKEN", "Another camera already uses this name", nil)
+	case errors.Is(err, camera.ErrUnknownConnectionProfile):
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// GetCamera handles GET /api/v1/cameras/{id}. With ?include=recent_events the
+// latest events of the camera are embedded as recent_events.
 func (h *CameraHandler) GetCamera(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 
+	includeEvents := false
+	if include := r.URL.Query().Get("include"); include != "" {
+		for _, value := range strings.Split(include, ",") {
+			switch strings.TrimSpace(value) {
+			case "recent_events":
+				includeEvents = true
+			default:
+				utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "include must be recent_events", nil)
+				return
+			}
+		}
+	}
+
 	camera, err := h.cameraService.GetCamera(ctx, cameraID)
 	if err != nil {
 		logger.Error("Failed to get camera", zap.Error(err), zap.String("id", cameraID))
@@ -121,7 +272,45 @@ func (h *CameraHandler) GetCamera(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	utils.RespondJSON(w, http.StatusOK, camera)
+	if !includeEvents {
+		utils.RespondJSON(w, http.StatusOK, camera)
+		return
+	}
+
+	events, err := h.cameraService.GetCameraEvents(ctx, cameraID, recentEventsLimit, 0)
+	if err != nil {
+		logger.Error("Failed to get recent camera events", zap.Error(err), zap.String("id", cameraID))
+		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve events", nil)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, struct {
+		*models.Camera
+		RecentEvents []*models.Event `json:"recent_events"`
+	}{camera, events})
+}
+
+// GetCameraCapabilities handles GET /api/v1/cameras/{id}/capabilities
+func (h *CameraHandler) GetCameraCapabilities(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	camera, err := h.cameraService.GetCamera(ctx, cameraID)
+	if err != nil {
+		logger.Error("Failed to get camera", zap.Error(err), zap.String("id", cameraID))
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	capabilities := camera.Capabilities
+	if capabilities == nil {
+		capabilities = models.CameraCapabilities{}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id":    camera.ID,
+		"capabilities": capabilities,
+	})
 }
 
 // UpdateCamera handles PUT /api/v1/cameras/{id}
@@ -164,9 +353,65 @@ func (h *CameraHandler) UpdateCamera(w http.ResponseWriter, r *http.Request) {
 	if req.SkipVerify != nil {
 		camera.SkipVerify = *req.SkipVerify
 	}
+	if req.RTSPTransport != nil {
+		if !models.ValidRTSPTransport(*req.RTSPTransport) {
+			utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "rtsp_transport must be tcp or udp", nil)
+			return
+		}
+		camera.RTSPTransport = *req.RTSPTransport
+	}
+	if req.RebootTime != nil {
+		if !models.ValidRebootTime(*req.RebootTime) {
+			utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "reboot_time must be a time of day in HH:MM format", nil)
+			return
+		}
+		camera.RebootTime = *req.RebootTime
+	}
+	if req.AlwaysReady != nil {
+		camera.AlwaysReady = *req.AlwaysReady
+	}
+	if req.ConnectionProfile != nil {
+		camera.ConnectionProfile = *req.ConnectionProfile
+	}
+	if req.EventMode != nil {
+		if !models.ValidEventMode(*req.EventMode) {
+			utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", eventModeMessage, nil)
+			return
+		}
+		camera.EventMode = *req.EventMode
+	}
+	if req.SnapshotEnabled != nil {
+		camera.SnapshotEnabled = *req.SnapshotEnabled
+	}
+	if req.SnapshotInterval != nil {
+		camera.SnapshotInterval = *req.SnapshotInterval
+	}
+	if req.SnapshotChannel != nil {
+		camera.SnapshotChannel = *req.SnapshotChannel
+	}
+	if !models.ValidSnapshotSchedule(camera.SnapshotEnabled, camera.SnapshotInterval, camera.SnapshotChannel) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", snapshotScheduleMessage, nil)
+		return
+	}
+	if req.PatrolSchedule != nil {
+		camera.PatrolSchedule = *req.PatrolSchedule
+	}
+	if req.PatrolID != nil {
+		camera.PatrolID = *req.PatrolID
+	}
+	if req.PatrolChannel != nil {
+		camera.PatrolChannel = *req.PatrolChannel
+	}
+	if !models.ValidPatrolSchedule(camera.PatrolSchedule, camera.PatrolID, camera.PatrolChannel) {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", patrolScheduleMessage, nil)
+		return
+	}
 
 	// Update camera via service
 	if err := h.cameraService.UpdateCamera(ctx, camera); err != nil {
+		if respondCameraRejected(w, err) {
+			return
+		}
 		logger.Error("Failed to update camera", zap.Error(err), zap.String("id", cameraID))
 		utils.RespondError(w, http.StatusInternalServerError, "UPDATE_CAMERA_ERROR", "Failed to update camera", nil)
 		return
@@ -194,6 +439,26 @@ func (h *CameraHandler) DeleteCamera(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// PurgeCameraHistory handles DELETE /api/v1/cameras/{id}/history
+func (h *CameraHandler) PurgeCameraHistory(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	if _, err := h.cameraService.GetCamera(ctx, cameraID); err != nil {
+		utils.RespondNotFound(w, "Camera not found")
+		return
+	}
+
+	purge, err := h.cameraService.PurgeCameraHistory(ctx, cameraID)
+	if err != nil {
+		logger.Error("Failed to purge camera history", zap.Error(err), zap.String("id", cameraID))
+		utils.RespondInternalError(w, "Failed to purge camera history")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, purge)
+}
+
 // GetCameraStatus handles GET /api/v1/cameras/{id}/status
 func (h *CameraHandler) GetCameraStatus(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -209,155 +474,162 @@ func (h *CameraHandler) GetCameraStatus(w http.ResponseWriter, r *http.Request)
 	utils.RespondJSON(w, http.StatusOK, status)
 }
 
-// RebootCamera handles POST /api/v1/cameras/{id}/reboot
-func (h *CameraHandler) RebootCamera(w http.ResponseWriter, r *http.Request) {
+// GetDeviceInfo handles GET /api/v1/cameras/{id}/info, querying the camera
+// for its model, versions and channel count
+func (h *CameraHandler) GetDeviceInfo(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 
-	client, err := h.cameraService.GetCameraClient(cameraID)
+	info, err := h.cameraService.GetDeviceInfo(ctx, cameraID)
 	if err != nil {
-		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
-		return
-	}
-
-	if err := client.Reboot(ctx); err != nil {
-		logger.Error("Failed to reboot camera", zap.Error(err), zap.String("id", cameraID))
-		utils.RespondError(w, http.StatusInternalServerError, "REBOOT_ERROR", "Failed to reboot camera", nil)
+		if !errors.Is(err, service.ErrCameraNotFound) {
+			logger.Error("Failed to get device info", zap.Error(err), zap.String("id", cameraID))
+		}
+		respondCameraError(w, err, "DEVICE_INFO_ERROR", "Failed to get device info")
 		return
 	}
 
-	logger.Info("Camera rebooted successfully", zap.String("id", cameraID))
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Camera reboot initiated",
-		"id":      cameraID,
-	})
+	utils.RespondJSON(w, http.StatusOK, info)
 }
 
-// GetSnapshot handles GET /api/v1/cameras/{id}/snapshot
-func (h *CameraHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+// ProbeCamera handles GET /api/v1/cameras/{id}/probe
+// The optional skip_verify query parameter overrides the camera's stored TLS
+// verification setting for this probe only.
+func (h *CameraHandler) ProbeCamera(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 
-	client, err := h.cameraService.GetCameraClient(cameraID)
-	if err != nil {
+	var skipVerify *bool
+	if value := r.URL.Query().Get("skip_verify"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondBadRequest(w, "skip_verify must be true or false", map[string]interface{}{"skip_verify": value})
+			return
+		}
+		skipVerify = &parsed
+	}
+
+	result, err := h.cameraService.ProbeCamera(ctx, cameraID, skipVerify)
+	if errors.Is(err, service.ErrCameraNotFound) {
 		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
 		return
 	}
-
-	// Get snapshot from camera
-	snapshot, err := client.GetSnapshot(ctx, 0) // Channel 0
 	if err != nil {
-		logger.Error("Failed to get snapshot", zap.Error(err), zap.String("id", cameraID))
-		utils.RespondError(w, http.StatusInternalServerError, "SNAPSHOT_ERROR", "Failed to capture snapshot", nil)
+		logger.Error("Failed to probe camera", zap.Error(err), zap.String("id", cameraID))
+		utils.RespondInternalError(w, "Failed to probe camera")
 		return
 	}
 
-	// Return image directly
-	w.Header().Set("Content-Type", "image/jpeg")
-	w.Header().Set("Content-Length", strconv.Itoa(len(snapshot)))
-	w.WriteHeader(http.StatusOK)
-	w.Write(snapshot)
+	utils.RespondJSON(w, http.StatusOK, result)
 }
 
-// PTZMove handles POST /api/v1/cameras/{id}/ptz/move
-func (h *CameraHandler) PTZMove(w http.ResponseWriter, r *http.Request) {
+// ProbeNewCamera handles POST /api/v1/cameras/probe
+// It tests the connection settings of a camera before it is added. Nothing is
+// stored; skip_verify only applies to this probe.
+func (h *CameraHandler) ProbeNewCamera(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	cameraID := chi.URLParam(r, "id")
 
 	var req struct {
-		Operation string `json:"operation"` // Up, Down, Left, Right, ZoomInc, ZoomDec, etc.
-		Speed     *int   `json:"speed,omitempty"`
-		Channel   *int   `json:"channel,omitempty"`
+		Host       string `json:"host"`
+		Port       int    `json:"port"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		UseHTTPS   bool   `json:"use_https"`
+		SkipVerify bool   `json:"skip_verify"`
 	}
-
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
 		return
 	}
 
-	client, err := h.cameraService.GetCameraClient(cameraID)
-	if err != nil {
-		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+	if req.Host == "" || req.Username == "" || req.Password == "" {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Missing required fields", map[string]interface{}{
+			"required": []string{"host", "username", "password"},
+		})
 		return
 	}
 
-	// Set defaults
-	speed := 32
-	if req.Speed != nil {
-		speed = *req.Speed
-	}
-	channel := 0
-	if req.Channel != nil {
-		channel = *req.Channel
-	}
-
-	// Execute PTZ operation
-	if err := client.PTZMove(ctx, req.Operation, speed, channel); err != nil {
-		logger.Error("Failed to execute PTZ operation", zap.Error(err), zap.String("id", cameraID), zap.String("operation", req.Operation))
-		utils.RespondError(w, http.StatusInternalServerError, "PTZ_ERROR", "Failed to execute PTZ operation", nil)
-		return
+	// Set default port if not provided
+	if req.Port == 0 {
+		if req.UseHTTPS {
+			req.Port = 443
+		} else {
+			req.Port = 80
+		}
 	}
 
-	logger.Info("PTZ operation executed", zap.String("id", cameraID), zap.String("operation", req.Operation))
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":   "PTZ operation executed",
-		"operation": req.Operation,
+	result := h.cameraService.ProbeNewCamera(ctx, &models.Camera{
+		Host:       req.Host,
+		Port:       req.Port,
+		Username:   req.Username,
+		Password:   req.Password,
+		UseHTTPS:   req.UseHTTPS,
+		SkipVerify: req.SkipVerify,
 	})
+
+	utils.RespondJSON(w, http.StatusOK, result)
 }
 
-// PTZPreset handles POST /api/v1/cameras/{id}/ptz/preset
-func (h *CameraHandler) PTZPreset(w http.ResponseWriter, r *http.Request) {
+// TestConnection handles POST /api/v1/cameras/test
+// It logs into a camera with the settings of an add request and reports its
+// model, firmware and channel count. Nothing is stored; failed logins are
+// reported in the result rather than as an error status.
+func (h *CameraHandler) TestConnection(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
-	cameraID := chi.URLParam(r, "id")
-
-	var req struct {
-		PresetID int  `json:"preset_id"`
-		Channel  *int `json:"channel,omitempty"`
-	}
 
+	var req models.CreateCameraRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
 		return
 	}
 
-	client, err := h.cameraService.GetCameraClient(cameraID)
-	if err != nil {
-		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+	if req.Host == "" || req.Username == "" || req.Password == "" {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Missing required fields", map[string]interface{}{
+			"required": []string{"host", "username", "password"},
+		})
 		return
 	}
 
-	channel := 0
-	if req.Channel != nil {
-		channel = *req.Channel
+	// Set default port if not provided
+	if req.Port == 0 {
+		if req.UseHTTPS {
+			req.Port = 443
+		} else {
+			req.Port = 80
+		}
 	}
 
-	// Go to preset
-	if err := client.PTZGotoPreset(ctx, channel, req.PresetID); err != nil {
-		logger.Error("Failed to go to preset", zap.Error(err), zap.String("id", cameraID), zap.Int("preset", req.PresetID))
-		utils.RespondError(w, http.StatusInternalServerError, "PTZ_ERROR", "Failed to go to preset", nil)
+	result, err := h.cameraService.TestConnection(ctx, &models.Camera{
+		Name:       req.Name,
+		Host:       req.Host,
+		Port:       req.Port,
+		Username:   req.Username,
+		Password:   req.Password,
+		UseHTTPS:   req.UseHTTPS,
+		SkipVerify: req.SkipVerify,
+
+		ConnectionProfile: req.ConnectionProfile,
+	})
+	if err != nil {
+		if respondCameraRejected(w, err) {
+			return
+		}
+		utils.RespondError(w, http.StatusInternalServerError, "TEST_ERROR", "Failed to test camera connection", nil)
 		return
 	}
 
-	logger.Info("Moved to PTZ preset", zap.String("id", cameraID), zap.Int("preset", req.PresetID))
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":   "Moved to preset",
-		"preset_id": req.PresetID,
-	})
+	utils.RespondJSON(w, http.StatusOK, result)
 }
 
-// ControlLED handles POST /api/v1/cameras/{id}/led
-func (h *CameraHandler) ControlLED(w http.ResponseWriter, r *http.Request) {
+// RebootCamera handles POST /api/v1/cameras/{id}/reboot
+// With ?wait=true the camera is polled until it is back online or ?timeout
+// (default 5m) passes; the outcome is reported by GET /api/v1/cameras/{id}/reboot.
+func (h *CameraHandler) RebootCamera(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 
-	var req struct {
-		Type    string `json:"type"`    // "white" or "ir"
-		Enabled bool   `json:"enabled"` // true or false
-		Channel *int   `json:"channel,omitempty"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+	if wait, _ := strconv.ParseBool(r.URL.Query().Get("wait")); wait {
+		h.rebootAndWait(w, r, cameraID)
 		return
 	}
 
@@ -367,301 +639,1312 @@ func (h *CameraHandler) ControlLED(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channel := 0
-	if req.Channel != nil {
-		channel = *req.Channel
+	err = client.Reboot(ctx)
+	if err != nil {
+		logger.Error("Failed to reboot camera", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "REBOOT_ERROR", "Failed to reboot camera")
+		return
 	}
 
-	// Control LED based on type
-	var controlErr error
-	switch req.Type {
-	case "white":
-		// SetWhiteLED requires a WhiteLed config
-		mode := 0
-		if req.Enabled {
-			mode = 1
-		}
-		config := &reolink.WhiteLed{
-			Channel: channel,
-			Mode:    mode,
+	logger.Info("Camera rebooted successfully", zap.String("id", cameraID))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Camera reboot initiated",
+		"id":      cameraID,
+	})
+}
+
+// rebootAndWait reboots a camera and responds with the pending reboot status
+func (h *CameraHandler) rebootAndWait(w http.ResponseWriter, r *http.Request, cameraID string) {
+	timeout := camera.DefaultRebootWaitTimeout
+	if timeoutStr := r.URL.Query().Get("timeout"); timeoutStr != "" {
+		var err error
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil || timeout <= 0 {
+			utils.RespondBadRequest(w, "Invalid timeout parameter", map[string]interface{}{"timeout": timeoutStr})
+			return
 		}
-		controlErr = client.SetWhiteLED(ctx, config)
-	case "ir":
-		// SetIRLights requires a state string
-		state := "Auto"
-		if req.Enabled {
-			state = "On"
-		} else {
-			state = "Off"
+	}
+
+	if _, err := h.cameraService.GetCameraClient(cameraID); err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	status, err := h.cameraService.RebootAndWait(r.Context(), cameraID, timeout)
+	if errors.Is(err, camera.ErrRebootInProgress) {
+		utils.RespondError(w, http.StatusConflict, "REBOOT_IN_PROGRESS", "Camera is already rebooting", nil)
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to reboot camera", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "REBOOT_ERROR", "Failed to reboot camera")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusAccepted, status)
+}
+
+// GetRebootStatus handles GET /api/v1/cameras/{id}/reboot
+func (h *CameraHandler) GetRebootStatus(w http.ResponseWriter, r *http.Request) {
+	cameraID := chi.URLParam(r, "id")
+
+	status, err := h.cameraService.GetRebootStatus(cameraID)
+	if err != nil {
+		utils.RespondNotFound(w, "No reboot recorded for camera")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, status)
+}
+
+// GetSnapshot handles GET /api/v1/cameras/{id}/snapshot
+// Snapshots are briefly cached per camera channel; ?fresh=true always asks the camera.
+func (h *CameraHandler) GetSnapshot(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+	query := r.URL.Query()
+
+	// Get channel from query params (default to 0)
+	channel := 0
+	if channelStr := query.Get("channel"); channelStr != "" {
+		var err error
+		channel, err = strconv.Atoi(channelStr)
+		if err != nil || channel < 0 {
+			utils.RespondBadRequest(w, "Invalid channel parameter", map[string]interface{}{"channel": channelStr})
+			return
 		}
-		controlErr = client.SetIRLights(ctx, channel, state)
-	default:
-		utils.RespondError(w, http.StatusBadRequest, "INVALID_LED_TYPE", "LED type must be 'white' or 'ir'", nil)
+	}
+
+	fresh := false
+	if freshStr := query.Get("fresh"); freshStr != "" {
+		var err error
+		fresh, err = strconv.ParseBool(freshStr)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid fresh parameter", map[string]interface{}{"fresh": freshStr})
+			return
+		}
+	}
+
+	if h.snapshotStreaming {
+		h.streamSnapshot(w, r, cameraID, channel, fresh)
+		return
+	}
+
+	snapshot, cached, err := h.cameraService.GetSnapshot(ctx, cameraID, channel, fresh)
+	if err != nil {
+		respondSnapshotError(w, err, cameraID)
+		return
+	}
+
+	// Return image directly
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(snapshot)))
+	w.Header().Set("X-Cache", snapshotCacheStatus(cached))
+	w.WriteHeader(http.StatusOK)
+	w.Write(snapshot)
+}
+
+// streamSnapshot serves a snapshot as it arrives from the camera. Without a
+// known size the Content-Length is left out and the response is chunked.
+func (h *CameraHandler) streamSnapshot(w http.ResponseWriter, r *http.Request, cameraID string, channel int, fresh bool) {
+	stream, cached, err := h.cameraService.StreamSnapshot(r.Context(), cameraID, channel, fresh)
+	if err != nil {
+		respondSnapshotError(w, err, cameraID)
+		return
+	}
+	defer stream.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	if stream.Size >= 0 {
+		w.Header().Set("Content-Length", strconv.FormatInt(stream.Size, 10))
+	}
+	w.Header().Set("X-Cache", snapshotCacheStatus(cached))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is sent, so a broken stream can only be logged
+	if _, err := io.Copy(w, stream); err != nil && r.Context().Err() == nil {
+		logger.Warn("Snapshot stream interrupted", zap.Error(err), zap.String("id", cameraID))
+	}
+}
+
+// respondSnapshotError responds to a snapshot that could not be taken
+func respondSnapshotError(w http.ResponseWriter, err error, cameraID string) {
+	if !errors.Is(err, service.ErrCameraNotFound) {
+		logger.Error("Failed to get snapshot", zap.Error(err), zap.String("id", cameraID))
+	}
+	respondCameraError(w, err, "SNAPSHOT_ERROR", "Failed to capture snapshot")
+}
+
+// snapshotCacheStatus is the X-Cache header of a snapshot response
+func snapshotCacheStatus(cached bool) string {
+	if cached {
+		return "HIT"
+	}
+	return "MISS"
+}
+
+// PTZMove handles POST /api/v1/cameras/{id}/ptz/move
+func (h *CameraHandler) PTZMove(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Operation string `json:"operation"` // Up, Down, Left, Right, ZoomInc, ZoomDec, etc.
+		Speed     *int   `json:"speed,omitempty"`
+		Channel   *int   `json:"channel,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	// Set defaults
+	speed := 32
+	if req.Speed != nil {
+		speed = *req.Speed
+	}
+	channel := 0
+	if req.Channel != nil {
+		channel = *req.Channel
+	}
+
+	// Execute PTZ operation
+	err = client.PTZMove(ctx, req.Operation, speed, channel)
+	if err != nil {
+		logger.Error("Failed to execute PTZ operation", zap.Error(err), zap.String("id", cameraID), zap.String("operation", req.Operation))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to execute PTZ operation")
+		return
+	}
+
+	logger.Info("PTZ operation executed", zap.String("id", cameraID), zap.String("operation", req.Operation))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":   "PTZ operation executed",
+		"operation": req.Operation,
+	})
+}
+
+// PTZPreset handles POST /api/v1/cameras/{id}/ptz/preset
+func (h *CameraHandler) PTZPreset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		PresetID int  `json:"preset_id"`
+		Channel  *int `json:"channel,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	channel := 0
+	if req.Channel != nil {
+		channel = *req.Channel
+	}
+
+	// Go to preset
+	err = client.PTZGotoPreset(ctx, channel, req.PresetID)
+	if err != nil {
+		logger.Error("Failed to go to preset", zap.Error(err), zap.String("id", cameraID), zap.Int("preset", req.PresetID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to go to preset")
+		return
+	}
+
+	logger.Info("Moved to PTZ preset", zap.String("id", cameraID), zap.Int("preset", req.PresetID))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":   "Moved to preset",
+		"preset_id": req.PresetID,
+	})
+}
+
+// ListPTZPresets handles GET /api/v1/cameras/{id}/ptz/presets
+// Only saved presets are listed; the camera reports unused slots as disabled.
+func (h *CameraHandler) ListPTZPresets(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	// Get channel from query params (default to 0)
+	channelStr := r.URL.Query().Get("channel")
+	channel := 0
+	if channelStr != "" {
+		var err error
+		channel, err = strconv.Atoi(channelStr)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid channel parameter", map[string]interface{}{"channel": channelStr})
+			return
+		}
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	all, err := client.GetPtzPreset(ctx, channel)
+	if err != nil {
+		logger.Error("Failed to get PTZ presets", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to get PTZ presets")
+		return
+	}
+
+	presets := make([]reolink.PtzPreset, 0, len(all))
+	for _, preset := range all {
+		if preset.Enable == 1 {
+			presets = append(presets, preset)
+		}
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id": cameraID,
+		"channel":   channel,
+		"presets":   presets,
+	})
+}
+
+// SavePTZPreset handles POST /api/v1/cameras/{id}/ptz/presets
+// It stores the camera's current position under the given preset ID,
+// creating the preset or overwriting an existing one.
+func (h *CameraHandler) SavePTZPreset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Name     string `json:"name"`
+		PresetID int    `json:"preset_id"`
+		Channel  *int   `json:"channel,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	if req.Name == "" || req.PresetID < 1 || req.PresetID > 64 {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "name is required and preset_id must be between 1 and 64", nil)
 		return
 	}
 
-	if controlErr != nil {
-		logger.Error("Failed to control LED", zap.Error(controlErr), zap.String("id", cameraID), zap.String("type", req.Type))
-		utils.RespondError(w, http.StatusInternalServerError, "LED_ERROR", "Failed to control LED", nil)
-		return
+	// The camera API stores presets without a channel, so only the first channel can be addressed
+	if req.Channel != nil && *req.Channel != 0 {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Presets can only be saved on channel 0", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	preset := reolink.PtzPreset{Enable: 1, ID: req.PresetID, Name: req.Name}
+
+	err = client.SetPtzPreset(ctx, preset)
+	if err != nil {
+		logger.Error("Failed to save PTZ preset", zap.Error(err), zap.String("id", cameraID), zap.Int("preset", req.PresetID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to save PTZ preset")
+		return
+	}
+
+	logger.Info("Saved PTZ preset", zap.String("id", cameraID), zap.Int("preset", req.PresetID), zap.String("name", req.Name))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Preset saved",
+		"preset":  preset,
+	})
+}
+
+// PTZHome handles POST /api/v1/cameras/{id}/ptz/home
+func (h *CameraHandler) PTZHome(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	// Get channel from query params (default to 0)
+	channelStr := r.URL.Query().Get("channel")
+	channel := 0
+	if channelStr != "" {
+		var err error
+		channel, err = strconv.Atoi(channelStr)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid channel parameter", map[string]interface{}{"channel": channelStr})
+			return
+		}
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	guard, err := client.GetPtzGuard(ctx, channel)
+	if err != nil {
+		logger.Error("Failed to get PTZ guard position", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to get guard position")
+		return
+	}
+	if guard.BExistPos == 0 {
+		utils.RespondError(w, http.StatusUnprocessableEntity, "NO_GUARD_POSITION", "No guard position configured", nil)
+		return
+	}
+
+	err = client.PTZGotoGuard(ctx, channel)
+	if err != nil {
+		logger.Error("Failed to move to guard position", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to move to guard position")
+		return
+	}
+
+	logger.Info("Moved to PTZ guard position", zap.String("id", cameraID), zap.Int("channel", channel))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Moved to guard position",
+		"channel": channel,
+	})
+}
+
+// PTZPatrolStart handles POST /api/v1/cameras/{id}/ptz/patrol/start
+func (h *CameraHandler) PTZPatrolStart(w http.ResponseWriter, r *http.Request) {
+	h.controlPTZPatrol(w, r, true)
+}
+
+// PTZPatrolStop handles POST /api/v1/cameras/{id}/ptz/patrol/stop
+func (h *CameraHandler) PTZPatrolStop(w http.ResponseWriter, r *http.Request) {
+	h.controlPTZPatrol(w, r, false)
+}
+
+// controlPTZPatrol starts or stops one of the patrols saved on a camera.
+// The request body is optional and defaults to patrol 0 on channel 0.
+func (h *CameraHandler) controlPTZPatrol(w http.ResponseWriter, r *http.Request, startPatrol bool) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		PatrolID int `json:"patrol_id"`
+		Channel  int `json:"channel"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	if req.PatrolID < 0 || req.Channel < 0 {
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "patrol_id and channel must not be negative", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	action := "stop"
+	if startPatrol {
+		action = "start"
+	}
+
+	err = client.PTZPatrolControl(ctx, req.Channel, req.PatrolID, startPatrol)
+	if err != nil {
+		logger.Error("Failed to control PTZ patrol", zap.Error(err), zap.String("id", cameraID), zap.String("action", action), zap.Int("patrol", req.PatrolID))
+		respondCameraError(w, err, "PTZ_ERROR", "Failed to "+action+" patrol")
+		return
+	}
+
+	logger.Info("Controlled PTZ patrol", zap.String("id", cameraID), zap.String("action", action), zap.Int("patrol", req.PatrolID), zap.Int("channel", req.Channel))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":   "Patrol " + action + " sent",
+		"patrol_id": req.PatrolID,
+		"channel":   req.Channel,
+	})
+}
+
+// ControlLED handles POST /api/v1/cameras/{id}/led
+func (h *CameraHandler) ControlLED(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Type    string `json:"type"`    // "white" or "ir"
+		Enabled bool   `json:"enabled"` // true or false
+		Channel *int   `json:"channel,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	channel := 0
+	if req.Channel != nil {
+		channel = *req.Channel
+	}
+
+	// Control LED based on type
+	var controlErr error
+	switch req.Type {
+	case "white":
+		// SetWhiteLED requires a WhiteLed config
+		mode := 0
+		if req.Enabled {
+			mode = 1
+		}
+		config := &reolink.WhiteLed{
+			Channel: channel,
+			Mode:    mode,
+		}
+		controlErr = client.SetWhiteLED(ctx, config)
+	case "ir":
+		// SetIRLights requires a state string
+		state := "Auto"
+		if req.Enabled {
+			state = "On"
+		} else {
+			state = "Off"
+		}
+		controlErr = client.SetIRLights(ctx, channel, state)
+	default:
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_LED_TYPE", "LED type must be 'white' or 'ir'", nil)
+		return
+	}
+
+	if controlErr != nil {
+		logger.Error("Failed to control LED", zap.Error(controlErr), zap.String("id", cameraID), zap.String("type", req.Type))
+		respondCameraError(w, controlErr, "LED_ERROR", "Failed to control LED")
+		return
+	}
+
+	logger.Info("LED controlled", zap.String("id", cameraID), zap.String("type", req.Type), zap.Bool("enabled", req.Enabled))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "LED controlled successfully",
+		"type":    req.Type,
+		"enabled": req.Enabled,
+	})
+}
+
+// TriggerSiren handles POST /api/v1/cameras/{id}/siren
+func (h *CameraHandler) TriggerSiren(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Duration int  `json:"duration"` // Duration in seconds
+		Channel  *int `json:"channel,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	channel := 0
+	if req.Channel != nil {
+		channel = *req.Channel
+	}
+
+	// Trigger audio alarm (siren)
+	err = client.TriggerSiren(ctx, channel, req.Duration)
+	if err != nil {
+		logger.Error("Failed to trigger siren", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "SIREN_ERROR", "Failed to trigger siren")
+		return
+	}
+
+	logger.Info("Siren triggered", zap.String("id", cameraID), zap.Int("duration", req.Duration))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Siren triggered",
+		"duration": req.Duration,
+	})
+}
+
+// cameraDisks converts the disks reported by a camera, identifying each by
+// its index
+func cameraDisks(infos []reolink.HddInfo) []models.CameraDisk {
+	disks := make([]models.CameraDisk, len(infos))
+	for i, info := range infos {
+		disk := models.CameraDisk{
+			ID:         i,
+			CapacityMB: info.Capacity,
+			UsedMB:     info.Size,
+			FreeMB:     max(info.Capacity-info.Size, 0),
+			Formatted:  info.Format == 1,
+			Mounted:    info.Mount == 1,
+			Status:     info.Status,
+		}
+		if info.Capacity > 0 {
+			disk.UsedPercent = float64(info.Size) * 100 / float64(info.Capacity)
+		}
+		disks[i] = disk
+	}
+	return disks
+}
+
+// GetCameraStorage handles GET /api/v1/cameras/{id}/storage
+func (h *CameraHandler) GetCameraStorage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	infos, err := client.GetHddInfo(ctx)
+	if err != nil {
+		logger.Error("Failed to get camera storage", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "STORAGE_ERROR", "Failed to get camera storage")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, cameraDisks(infos))
+}
+
+// FormatCameraStorage handles POST /api/v1/cameras/{id}/storage/{hddId}/format
+// Formatting erases every recording on the disk, so the body must confirm it
+// with {"confirm": true}.
+func (h *CameraHandler) FormatCameraStorage(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	hddIDStr := chi.URLParam(r, "hddId")
+	hddID, err := strconv.Atoi(hddIDStr)
+	if err != nil || hddID < 0 {
+		utils.RespondBadRequest(w, "Invalid disk ID", map[string]interface{}{"hddId": hddIDStr})
+		return
+	}
+
+	var req struct {
+		Confirm bool `json:"confirm"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+	if !req.Confirm {
+		utils.RespondError(w, http.StatusBadRequest, "CONFIRMATION_REQUIRED",
+			"Formatting erases all recordings on the disk; set confirm to true to proceed", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	infos, err := client.GetHddInfo(ctx)
+	if err != nil {
+		logger.Error("Failed to get camera storage", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "STORAGE_ERROR", "Failed to get camera storage")
+		return
+	}
+	if hddID >= len(infos) {
+		utils.RespondError(w, http.StatusNotFound, "DISK_NOT_FOUND", "Disk not found", nil)
+		return
+	}
+
+	err = client.Format(ctx, hddID)
+	if err != nil {
+		logger.Error("Failed to format camera disk", zap.Error(err), zap.String("id", cameraID), zap.Int("hdd_id", hddID))
+		respondCameraError(w, err, "FORMAT_ERROR", "Failed to format disk")
+		return
+	}
+
+	logger.Warn("Camera disk formatted", zap.String("id", cameraID), zap.Int("hdd_id", hddID))
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Disk format initiated",
+		"hdd_id":  hddID,
+	})
+}
+
+// GetCameraConfig handles GET /api/v1/cameras/{id}/config/{type}
+func (h *CameraHandler) GetCameraConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+	configType := chi.URLParam(r, "type")
+
+	// Validate config type first
+	supportedTypes := []string{
+		"time", "device_name", "auto_maint", "system", "encoding", "ai",
+		"motion_alarm", "alarm", "audio_alarm", "buzzer_alarm", "ai_alarm",
+		"recording", "osd", "image", "isp", "mask", "crop",
+		"network_port", "ntp", "wifi", "email", "ftp", "push",
+	}
+
+	validType := false
+	for _, t := range supportedTypes {
+		if t == configType {
+			validType = true
+			break
+		}
+	}
+
+	if !validType {
+		utils.RespondBadRequest(w, "Unsupported config type", map[string]interface{}{
+			"type":            configType,
+			"supported_types": supportedTypes,
+		})
+		return
+	}
+
+	sel, ok := parseConfigSelector(w, r)
+	if !ok {
+		return
+	}
+	channel := sel.channel
+
+	// Get camera client
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondNotFound(w, "Camera not found")
+		return
+	}
+
+	// Route to appropriate config getter based on type
+	var config interface{}
+	switch configType {
+	case "time":
+		config, err = client.GetTime(ctx)
+	case "device_name":
+		config, err = client.GetDeviceName(ctx)
+	case "auto_maint":
+		config, err = client.GetAutoMaint(ctx)
+	case "system":
+		config, err = client.GetSysCfg(ctx)
+	case "encoding":
+		config, err = client.GetEnc(ctx, channel)
+	case "ai":
+		config, err = client.GetAiCfg(ctx, channel)
+	case "motion_alarm":
+		config, err = client.GetMdAlarm(ctx, channel)
+	case "alarm":
+		config, err = client.GetAlarm(ctx, channel, sel.alarmType)
+	case "audio_alarm":
+		config, err = client.GetAudioAlarm(ctx, channel)
+	case "buzzer_alarm":
+		config, err = client.GetBuzzerAlarmV20(ctx, channel)
+	case "ai_alarm":
+		config, err = client.GetAiAlarm(ctx, channel, sel.aiType)
+	case "recording":
+		config, err = client.GetRec(ctx, channel)
+	case "osd":
+		config, err = client.GetOsd(ctx, channel)
+	case "image":
+		config, err = client.GetImage(ctx, channel)
+	case "isp":
+		config, err = client.GetIsp(ctx, channel)
+	case "mask":
+		config, err = client.GetMask(ctx, channel)
+	case "crop":
+		config, err = client.GetCrop(ctx, channel)
+	case "network_port":
+		config, err = client.GetNetPort(ctx)
+	case "ntp":
+		config, err = client.GetNtp(ctx)
+	case "wifi":
+		config, err = client.GetWifi(ctx)
+	case "email":
+		config, err = client.GetEmail(ctx)
+	case "ftp":
+		config, err = client.GetFtp(ctx)
+	case "push":
+		config, err = client.GetPush(ctx)
+	}
+
+	if err != nil {
+		logger.Error("Failed to get camera config",
+			zap.Error(err),
+			zap.String("camera_id", cameraID),
+			zap.String("config_type", configType))
+		respondCameraError(w, err, "CONFIG_ERROR", "Failed to get camera configuration")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, config)
+}
+
+// configSelector identifies which instance of a config type a request
+// addresses: the channel, and the alarm or AI detection type where relevant
+type configSelector struct {
+	channel   int
+	alarmType string
+	aiType    string
+}
+
+// parseConfigSelector reads the channel, alarm_type and ai_type query
+// parameters, responding with 400 on an invalid channel
+func parseConfigSelector(w http.ResponseWriter, r *http.Request) (configSelector, bool) {
+	query := r.URL.Query()
+	sel := configSelector{
+		alarmType: query.Get("alarm_type"),
+		aiType:    query.Get("ai_type"),
+	}
+
+	// Get channel from query params (default to 0)
+	if channelStr := query.Get("channel"); channelStr != "" {
+		channel, err := strconv.Atoi(channelStr)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid channel parameter", map[string]interface{}{"channel": channelStr})
+			return sel, false
+		}
+		sel.channel = channel
+	}
+
+	if sel.alarmType == "" {
+		sel.alarmType = "md" // default to motion detection
+	}
+	if sel.aiType == "" {
+		sel.aiType = "people" // default to people detection
+	}
+
+	return sel, true
+}
+
+// updatableConfigTypes lists the config types that can be written to a camera
+var updatableConfigTypes = []string{
+	"time", "device_name", "auto_maint", "system", "encoding", "ai",
+	"motion_alarm", "alarm", "audio_alarm", "buzzer_alarm", "ai_alarm",
+	"recording", "osd", "image", "isp", "mask", "crop",
+	"network_port", "ntp", "wifi", "email", "ftp", "push",
+}
+
+// isUpdatableConfigType reports whether a config type can be written to a camera
+func isUpdatableConfigType(configType string) bool {
+	for _, t := range updatableConfigTypes {
+		if t == configType {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeConfig decodes a JSON config body into a reolink config struct
+func decodeConfig[T any](body io.Reader) (*T, error) {
+	var cfg T
+	if err := json.NewDecoder(body).Decode(&cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// parseConfigUpdate decodes a config update body for the given config type.
+// The decoded value is also the form stored in the config history.
+// Channel-specific configs carry their channel in the body.
+func parseConfigUpdate(configType string, body io.Reader) (interface{}, error) {
+	switch configType {
+	case "device_name":
+		var req struct {
+			Name string `json:"name"`
+		}
+		if err := json.NewDecoder(body).Decode(&req); err != nil {
+			return nil, err
+		}
+		return map[string]string{"name": req.Name}, nil
+	case "time":
+		return decodeConfig[reolink.TimeConfig](body)
+	case "auto_maint":
+		return decodeConfig[reolink.AutoMaint](body)
+	case "system":
+		return decodeConfig[reolink.SysCfg](body)
+	case "encoding":
+		return decodeConfig[reolink.EncConfig](body)
+	case "ai":
+		return decodeConfig[reolink.AiCfg](body)
+	case "motion_alarm":
+		return decodeConfig[reolink.MdAlarm](body)
+	case "alarm":
+		return decodeConfig[reolink.Alarm](body)
+	case "audio_alarm":
+		return decodeConfig[reolink.AudioAlarm](body)
+	case "buzzer_alarm":
+		return decodeConfig[reolink.BuzzerAlarm](body)
+	case "ai_alarm":
+		return decodeConfig[reolink.AiAlarm](body)
+	case "recording":
+		return decodeConfig[reolink.Rec](body)
+	case "osd":
+		return decodeConfig[reolink.Osd](body)
+	case "image":
+		return decodeConfig[reolink.Image](body)
+	case "isp":
+		return decodeConfig[reolink.Isp](body)
+	case "mask":
+		return decodeConfig[reolink.Mask](body)
+	case "crop":
+		return decodeConfig[reolink.Crop](body)
+	case "network_port":
+		return decodeConfig[reolink.NetPort](body)
+	case "ntp":
+		return decodeConfig[reolink.Ntp](body)
+	case "wifi":
+		return decodeConfig[reolink.Wifi](body)
+	case "email":
+		return decodeConfig[reolink.Email](body)
+	case "ftp":
+		return decodeConfig[reolink.Ftp](body)
+	case "push":
+		return decodeConfig[reolink.Push](body)
+	}
+
+	return nil, fmt.Errorf("unsupported config type: %s", configType)
+}
+
+// applyConfigUpdate writes a parsed config update to the camera
+func applyConfigUpdate(ctx context.Context, client *camera.CameraClient, configType string, update interface{}) error {
+	switch configType {
+	case "device_name":
+		return client.SetDeviceName(ctx, update.(map[string]string)["name"])
+	case "time":
+		return client.SetTime(ctx, update.(*reolink.TimeConfig))
+	case "auto_maint":
+		return client.SetAutoMaint(ctx, *update.(*reolink.AutoMaint))
+	case "system":
+		return client.SetSysCfg(ctx, *update.(*reolink.SysCfg))
+	case "encoding":
+		return client.SetEnc(ctx, *update.(*reolink.EncConfig))
+	case "ai":
+		return client.SetAiCfg(ctx, *update.(*reolink.AiCfg))
+	case "motion_alarm":
+		return client.SetMdAlarm(ctx, *update.(*reolink.MdAlarm))
+	case "alarm":
+		return client.SetAlarm(ctx, *update.(*reolink.Alarm))
+	case "audio_alarm":
+		return client.SetAudioAlarm(ctx, *update.(*reolink.AudioAlarm))
+	case "buzzer_alarm":
+		return client.SetBuzzerAlarmV20(ctx, *update.(*reolink.BuzzerAlarm))
+	case "ai_alarm":
+		alarm := update.(*reolink.AiAlarm)
+		return client.SetAiAlarm(ctx, alarm.Channel, *alarm)
+	case "recording":
+		return client.SetRec(ctx, *update.(*reolink.Rec))
+	case "osd":
+		return client.SetOsd(ctx, *update.(*reolink.Osd))
+	case "image":
+		return client.SetImage(ctx, *update.(*reolink.Image))
+	case "isp":
+		return client.SetIsp(ctx, *update.(*reolink.Isp))
+	case "mask":
+		return client.SetMask(ctx, *update.(*reolink.Mask))
+	case "crop":
+		return client.SetCrop(ctx, *update.(*reolink.Crop))
+	case "network_port":
+		return client.SetNetPort(ctx, *update.(*reolink.NetPort))
+	case "ntp":
+		return client.SetNtp(ctx, *update.(*reolink.Ntp))
+	case "wifi":
+		return client.SetWifi(ctx, *update.(*reolink.Wifi))
+	case "email":
+		return client.SetEmail(ctx, *update.(*reolink.Email))
+	case "ftp":
+		return client.SetFtp(ctx, *update.(*reolink.Ftp))
+	case "push":
+		return client.SetPush(ctx, *update.(*reolink.Push))
+	}
+
+	return fmt.Errorf("unsupported config type: %s", configType)
+}
+
+// recordConfigBaseline stores the config of a type the camera has before its
+// first recorded change
+func (h *CameraHandler) recordConfigBaseline(ctx context.Context, client *camera.CameraClient, configType string, sel configSelector) {
+	h.cameraService.RecordConfigBaseline(ctx, client.Camera, configType, func() (interface{}, error) {
+		return currentConfigForUpdate(ctx, client, configType, sel)
+	})
+}
+
+// selectorOfUpdate returns the channel, alarm type and AI type a parsed
+// config update applies to, defaulting like parseConfigSelector
+func selectorOfUpdate(configType string, update interface{}) configSelector {
+	sel := configSelector{alarmType: "md", aiType: "people"}
+
+	var fields struct {
+		Channel int    `json:"channel"`
+		Type    string `json:"type"`
+		AiType  string `json:"ai_type"`
+	}
+	data, err := json.Marshal(update)
+	if err != nil || json.Unmarshal(data, &fields) != nil {
+		return sel
+	}
+
+	sel.channel = fields.Channel
+	if configType == "alarm" && fields.Type != "" {
+		sel.alarmType = fields.Type
+	}
+	if configType == "ai_alarm" && fields.AiType != "" {
+		sel.aiType = fields.AiType
+	}
+	return sel
+}
+
+// currentConfigForUpdate reads the current value of an updatable config type
+// in the same form returned by parseConfigUpdate
+func currentConfigForUpdate(ctx context.Context, client *camera.CameraClient, configType string, sel configSelector) (interface{}, error) {
+	switch configType {
+	case "device_name":
+		name, err := client.GetDeviceName(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"name": name}, nil
+	case "time":
+		return client.GetTime(ctx)
+	case "auto_maint":
+		return client.GetAutoMaint(ctx)
+	case "system":
+		return client.GetSysCfg(ctx)
+	case "encoding":
+		return client.GetEnc(ctx, sel.channel)
+	case "ai":
+		return client.GetAiCfg(ctx, sel.channel)
+	case "motion_alarm":
+		return client.GetMdAlarm(ctx, sel.channel)
+	case "alarm":
+		return client.GetAlarm(ctx, sel.channel, sel.alarmType)
+	case "audio_alarm":
+		return client.GetAudioAlarm(ctx, sel.channel)
+	case "buzzer_alarm":
+		return client.GetBuzzerAlarmV20(ctx, sel.channel)
+	case "ai_alarm":
+		return client.GetAiAlarm(ctx, sel.channel, sel.aiType)
+	case "recording":
+		return client.GetRec(ctx, sel.channel)
+	case "osd":
+		return client.GetOsd(ctx, sel.channel)
+	case "image":
+		return client.GetImage(ctx, sel.channel)
+	case "isp":
+		return client.GetIsp(ctx, sel.channel)
+	case "mask":
+		return client.GetMask(ctx, sel.channel)
+	case "crop":
+		return client.GetCrop(ctx, sel.channel)
+	case "network_port":
+		return client.GetNetPort(ctx)
+	case "ntp":
+		return client.GetNtp(ctx)
+	case "wifi":
+		return client.GetWifi(ctx)
+	case "email":
+		return client.GetEmail(ctx)
+	case "ftp":
+		return client.GetFtp(ctx)
+	case "push":
+		return client.GetPush(ctx)
+	}
+
+	return nil, fmt.Errorf("unsupported config type: %s", configType)
+}
+
+// mergePatch applies a JSON merge patch (RFC 7386) to a JSON document:
+// fields present in the patch replace those in the document, nested objects
+// are merged recursively and null removes a field
+func mergePatch(document, patch []byte) ([]byte, error) {
+	var doc, p interface{}
+	if err := json.Unmarshal(document, &doc); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
 	}
+	return json.Marshal(applyMergePatch(doc, p))
+}
 
-	logger.Info("LED controlled", zap.String("id", cameraID), zap.String("type", req.Type), zap.Bool("enabled", req.Enabled))
-	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "LED controlled successfully",
-		"type":    req.Type,
-		"enabled": req.Enabled,
-	})
+// applyMergePatch merges a decoded patch value into a decoded target value
+func applyMergePatch(target, patch interface{}) interface{} {
+	patchObj, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObj, ok := target.(map[string]interface{})
+	if !ok {
+		targetObj = make(map[string]interface{})
+	}
+
+	for key, value := range patchObj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = applyMergePatch(targetObj[key], value)
+	}
+
+	return targetObj
 }
 
-// TriggerSiren handles POST /api/v1/cameras/{id}/siren
-func (h *CameraHandler) TriggerSiren(w http.ResponseWriter, r *http.Request) {
+// UpdateCameraConfig handles PUT /api/v1/cameras/{id}/config/{type}
+func (h *CameraHandler) UpdateCameraConfig(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
+	configType := chi.URLParam(r, "type")
 
-	var req struct {
-		Duration int  `json:"duration"` // Duration in seconds
-		Channel  *int `json:"channel,omitempty"`
+	// Validate config type first
+	if !isUpdatableConfigType(configType) {
+		utils.RespondBadRequest(w, "Unsupported config type for update", map[string]interface{}{
+			"type":            configType,
+			"supported_types": updatableConfigTypes,
+			"note":            "Additional config types can be added as needed",
+		})
+		return
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+	// Parse and validate request body based on config type
+	update, err := parseConfigUpdate(configType, r.Body)
+	if err != nil {
+		utils.RespondBadRequest(w, "Invalid request body", nil)
 		return
 	}
 
+	// Get camera client after validating input
 	client, err := h.cameraService.GetCameraClient(cameraID)
 	if err != nil {
-		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		utils.RespondNotFound(w, "Camera not found")
 		return
 	}
 
-	channel := 0
-	if req.Channel != nil {
-		channel = *req.Channel
-	}
+	// Keep the value being replaced when the type has no history yet
+	h.recordConfigBaseline(ctx, client, configType, selectorOfUpdate(configType, update))
 
-	// Trigger audio alarm (siren)
-	if err := client.TriggerSiren(ctx, channel, req.Duration); err != nil {
-		logger.Error("Failed to trigger siren", zap.Error(err), zap.String("id", cameraID))
-		utils.RespondError(w, http.StatusInternalServerError, "SIREN_ERROR", "Failed to trigger siren", nil)
+	// Apply configuration changes
+	updateErr := applyConfigUpdate(ctx, client, configType, update)
+
+	if updateErr != nil {
+		logger.Error("Failed to update camera config",
+			zap.Error(updateErr),
+			zap.String("camera_id", cameraID),
+			zap.String("config_type", configType))
+		respondCameraError(w, updateErr, "CONFIG_ERROR", "Failed to update camera configuration")
 		return
 	}
 
-	logger.Info("Siren triggered", zap.String("id", cameraID), zap.Int("duration", req.Duration))
+	// Record the change in the config history and event stream
+	h.cameraService.RecordConfigChange(ctx, client.Camera, configType, update)
+
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message":  "Siren triggered",
-		"duration": req.Duration,
+		"message": "Configuration updated successfully",
+		"type":    configType,
 	})
 }
 
-// GetCameraConfig handles GET /api/v1/cameras/{id}/config/{type}
-func (h *CameraHandler) GetCameraConfig(w http.ResponseWriter, r *http.Request) {
+// PatchCameraConfig handles PATCH /api/v1/cameras/{id}/config/{type}
+// Only the fields present in the request body are changed; the rest of the
+// config is read from the camera and written back unchanged.
+func (h *CameraHandler) PatchCameraConfig(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 	configType := chi.URLParam(r, "type")
 
-	// Validate config type first
-	supportedTypes := []string{
-		"time", "device_name", "auto_maint", "system", "encoding", "ai",
-		"motion_alarm", "alarm", "audio_alarm", "buzzer_alarm", "ai_alarm",
-		"recording", "osd", "image", "isp", "mask", "crop",
-		"network_port", "ntp", "wifi", "email", "ftp", "push",
-	}
-
-	validType := false
-	for _, t := range supportedTypes {
-		if t == configType {
-			validType = true
-			break
-		}
-	}
-
-	if !validType {
-		utils.RespondBadRequest(w, "Unsupported config type", map[string]interface{}{
+	if !isUpdatableConfigType(configType) {
+		utils.RespondBadRequest(w, "Unsupported config type for update", map[string]interface{}{
 			"type":            configType,
-			"supported_types": supportedTypes,
+			"supported_types": updatableConfigTypes,
 		})
 		return
 	}
 
-	// Get channel from query params (default to 0)
-	channelStr := r.URL.Query().Get("channel")
-	channel := 0
-	if channelStr != "" {
-		var err error
-		channel, err = strconv.Atoi(channelStr)
-		if err != nil {
-			utils.RespondBadRequest(w, "Invalid channel parameter", map[string]interface{}{"channel": channelStr})
-			return
-		}
+	sel, ok := parseConfigSelector(w, r)
+	if !ok {
+		return
 	}
 
-	// Get camera client
+	// The patch must be a JSON object
+	var patch map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		utils.RespondBadRequest(w, "Invalid request body", nil)
+		return
+	}
+	patchJSON, _ := json.Marshal(patch)
+
 	client, err := h.cameraService.GetCameraClient(cameraID)
 	if err != nil {
 		utils.RespondNotFound(w, "Camera not found")
 		return
 	}
 
-	// Route to appropriate config getter based on type
-	var config interface{}
-	switch configType {
-	case "time":
-		config, err = client.GetTime(ctx)
-	case "device_name":
-		config, err = client.GetDeviceName(ctx)
-	case "auto_maint":
-		config, err = client.GetAutoMaint(ctx)
-	case "system":
-		config, err = client.GetSysCfg(ctx)
-	case "encoding":
-		config, err = client.GetEnc(ctx, channel)
-	case "ai":
-		config, err = client.GetAiCfg(ctx, channel)
-	case "motion_alarm":
-		config, err = client.GetMdAlarm(ctx, channel)
-	case "alarm":
-		alarmType := r.URL.Query().Get("alarm_type")
-		if alarmType == "" {
-			alarmType = "md" // default to motion detection
-		}
-		config, err = client.GetAlarm(ctx, channel, alarmType)
-	case "audio_alarm":
-		config, err = client.GetAudioAlarm(ctx, channel)
-	case "buzzer_alarm":
-		config, err = client.GetBuzzerAlarmV20(ctx, channel)
-	case "ai_alarm":
-		aiType := r.URL.Query().Get("ai_type")
-		if aiType == "" {
-			aiType = "people" // default to people detection
-		}
-		config, err = client.GetAiAlarm(ctx, channel, aiType)
-	case "recording":
-		config, err = client.GetRec(ctx, channel)
-	case "osd":
-		config, err = client.GetOsd(ctx, channel)
-	case "image":
-		config, err = client.GetImage(ctx, channel)
-	case "isp":
-		config, err = client.GetIsp(ctx, channel)
-	case "mask":
-		config, err = client.GetMask(ctx, channel)
-	case "crop":
-		config, err = client.GetCrop(ctx, channel)
-	case "network_port":
-		config, err = client.GetNetPort(ctx)
-	case "ntp":
-		config, err = client.GetNtp(ctx)
-	case "wifi":
-		config, err = client.GetWifi(ctx)
-	case "email":
-		config, err = client.GetEmail(ctx)
-	case "ftp":
-		config, err = client.GetFtp(ctx)
-	case "push":
-		config, err = client.GetPush(ctx)
-	}
-
+	// Read the current config from the camera
+	current, err := currentConfigForUpdate(ctx, client, configType, sel)
 	if err != nil {
 		logger.Error("Failed to get camera config",
 			zap.Error(err),
 			zap.String("camera_id", cameraID),
 			zap.String("config_type", configType))
+		respondCameraError(w, err, "CONFIG_ERROR", "Failed to get camera configuration")
+		return
+	}
+	currentJSON, err := json.Marshal(current)
+	if err != nil {
 		utils.RespondInternalError(w, "Failed to get camera configuration")
 		return
 	}
 
-	utils.RespondJSON(w, http.StatusOK, config)
+	// Apply the provided fields on top of the current config
+	merged, err := mergePatch(currentJSON, patchJSON)
+	if err != nil {
+		utils.RespondBadRequest(w, "Invalid request body", nil)
+		return
+	}
+	update, err := parseConfigUpdate(configType, bytes.NewReader(merged))
+	if err != nil {
+		utils.RespondBadRequest(w, "Invalid config fields", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	h.cameraService.RecordConfigBaseline(ctx, client.Camera, configType, func() (interface{}, error) {
+		return current, nil
+	})
+
+	updateErr := applyConfigUpdate(ctx, client, configType, update)
+
+	if updateErr != nil {
+		logger.Error("Failed to patch camera config",
+			zap.Error(updateErr),
+			zap.String("camera_id", cameraID),
+			zap.String("config_type", configType))
+		respondCameraError(w, updateErr, "CONFIG_ERROR", "Failed to update camera configuration")
+		return
+	}
+
+	h.cameraService.RecordConfigChange(ctx, client.Camera, configType, update)
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Configuration updated successfully",
+		"type":    configType,
+		"config":  update,
+	})
 }
 
-// UpdateCameraConfig handles PUT /api/v1/cameras/{id}/config/{type}
-func (h *CameraHandler) UpdateCameraConfig(w http.ResponseWriter, r *http.Request) {
+// GetCameraConfigHistory handles GET /api/v1/cameras/{id}/config/{type}/history
+func (h *CameraHandler) GetCameraConfigHistory(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	cameraID := chi.URLParam(r, "id")
 	configType := chi.URLParam(r, "type")
 
-	// Validate config type first
-	supportedTypes := []string{"device_name", "time", "system"}
-	validType := false
-	for _, t := range supportedTypes {
-		if t == configType {
-			validType = true
-			break
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
 		}
 	}
 
-	if !validType {
-		utils.RespondBadRequest(w, "Unsupported config type for update", map[string]interface{}{
+	history, err := h.cameraService.GetConfigHistory(ctx, cameraID, configType, limit, offset)
+	if err != nil {
+		logger.Error("Failed to get camera config history",
+			zap.Error(err),
+			zap.String("camera_id", cameraID),
+			zap.String("config_type", configType))
+		utils.RespondInternalError(w, "Failed to get camera configuration history")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id": cameraID,
+		"type":      configType,
+		"history":   history,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
+
+// RollbackCameraConfig handles POST /api/v1/cameras/{id}/config/{type}/rollback/{version}
+func (h *CameraHandler) RollbackCameraConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+	configType := chi.URLParam(r, "type")
+
+	if !isUpdatableConfigType(configType) {
+		utils.RespondBadRequest(w, "Unsupported config type for rollback", map[string]interface{}{
 			"type":            configType,
-			"supported_types": supportedTypes,
-			"note":            "Additional config types can be added as needed",
+			"supported_types": updatableConfigTypes,
 		})
 		return
 	}
 
-	// Parse and validate request body based on config type
-	var deviceName string
-	var timeConfig *reolink.TimeConfig
-	var sysCfg *reolink.SysCfg
-
-	switch configType {
-	case "device_name":
-		var req struct {
-			Name string `json:"name"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			utils.RespondBadRequest(w, "Invalid request body", nil)
-			return
-		}
-		deviceName = req.Name
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil || version <= 0 {
+		utils.RespondBadRequest(w, "Invalid version parameter", map[string]interface{}{"version": chi.URLParam(r, "version")})
+		return
+	}
 
-	case "time":
-		var cfg reolink.TimeConfig
-		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-			utils.RespondBadRequest(w, "Invalid request body", nil)
-			return
-		}
-		timeConfig = &cfg
+	snapshot, err := h.cameraService.GetConfigVersion(ctx, cameraID, configType, version)
+	if err != nil {
+		utils.RespondNotFound(w, "Config version not found")
+		return
+	}
 
-	case "system":
-		var cfg reolink.SysCfg
-		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-			utils.RespondBadRequest(w, "Invalid request body", nil)
-			return
-		}
-		sysCfg = &cfg
+	update, err := parseConfigUpdate(configType, bytes.NewReader(snapshot.Config))
+	if err != nil {
+		logger.Error("Failed to decode config snapshot",
+			zap.Error(err),
+			zap.String("camera_id", cameraID),
+			zap.String("config_type", configType),
+			zap.Int("version", version))
+		utils.RespondInternalError(w, "Stored configuration is invalid")
+		return
 	}
 
-	// Get camera client after validating input
 	client, err := h.cameraService.GetCameraClient(cameraID)
 	if err != nil {
 		utils.RespondNotFound(w, "Camera not found")
 		return
 	}
 
-	// Apply configuration changes
-	var updateErr error
-	switch configType {
-	case "device_name":
-		updateErr = client.SetDeviceName(ctx, deviceName)
-	case "time":
-		updateErr = client.SetTime(ctx, timeConfig)
-	case "system":
-		updateErr = client.SetSysCfg(ctx, *sysCfg)
-	}
+	updateErr := applyConfigUpdate(ctx, client, configType, update)
 
 	if updateErr != nil {
-		logger.Error("Failed to update camera config",
+		logger.Error("Failed to roll back camera config",
 			zap.Error(updateErr),
 			zap.String("camera_id", cameraID),
-			zap.String("config_type", configType))
-		utils.RespondInternalError(w, "Failed to update camera configuration")
+			zap.String("config_type", configType),
+			zap.Int("version", version))
+		respondCameraError(w, updateErr, "CONFIG_ERROR", "Failed to roll back camera configuration")
 		return
 	}
 
+	// A rollback is recorded as a new version so the history stays append-only
+	h.cameraService.RecordConfigChange(ctx, client.Camera, configType, update)
+
+	logger.Info("Camera config rolled back",
+		zap.String("camera_id", cameraID),
+		zap.String("config_type", configType),
+		zap.Int("version", version))
+
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"message": "Configuration updated successfully",
+		"message": "Configuration rolled back successfully",
 		"type":    configType,
+		"version": version,
 	})
 }
 
@@ -5,11 +5,18 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/stretchr/testify/assert"
@@ -43,6 +50,14 @@ func (m *MockCameraServiceForConfig) ListCameras(ctx context.Context) ([]*models
 	return args.Get(0).([]*models.Camera), args.Error(1)
 }
 
+func (m *MockCameraServiceForConfig) ListCamerasByGroup(ctx context.Context, groupID string) ([]*models.Camera, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Camera), args.Error(1)
+}
+
 func (m *MockCameraServiceForConfig) UpdateCamera(ctx context.Context, camera *models.Camera) error {
 	args := m.Called(ctx, camera)
 	return args.Error(0)
@@ -61,6 +76,14 @@ func (m *MockCameraServiceForConfig) GetCameraStatus(ctx context.Context, id str
 	return args.Get(0).(*models.CameraStatus), args.Error(1)
 }
 
+func (m *MockCameraServiceForConfig) GetDeviceInfo(ctx context.Context, id string) (*models.CameraDeviceInfo, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraDeviceInfo), args.Error(1)
+}
+
 func (m *MockCameraServiceForConfig) GetCameraClient(id string) (*camera.CameraClient, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -82,6 +105,91 @@ func (m *MockCameraServiceForConfig) CountCameraEvents(ctx context.Context, came
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockCameraServiceForConfig) RecordConfigBaseline(ctx context.Context, cam *models.Camera, configType string, current func() (interface{}, error)) {
+	m.Called(ctx, cam, configType, current)
+}
+
+func (m *MockCameraServiceForConfig) RecordConfigChange(ctx context.Context, cam *models.Camera, configType string, changes interface{}) {
+	m.Called(ctx, cam, configType, changes)
+}
+
+func (m *MockCameraServiceForConfig) GetConfigHistory(ctx context.Context, cameraID, configType string, limit, offset int) ([]*models.CameraConfigVersion, error) {
+	args := m.Called(ctx, cameraID, configType, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CameraConfigVersion), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) GetConfigVersion(ctx context.Context, cameraID, configType string, version int) (*models.CameraConfigVersion, error) {
+	args := m.Called(ctx, cameraID, configType, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraConfigVersion), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) PurgeCameraHistory(ctx context.Context, cameraID string) (*models.CameraHistoryPurge, error) {
+	args := m.Called(ctx, cameraID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraHistoryPurge), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) ProbeCamera(ctx context.Context, id string, skipVerify *bool) (*camera.ProbeResult, error) {
+	args := m.Called(ctx, id, skipVerify)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.ProbeResult), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) ProbeNewCamera(ctx context.Context, cam *models.Camera) *camera.ProbeResult {
+	args := m.Called(ctx, cam)
+	return args.Get(0).(*camera.ProbeResult)
+}
+
+func (m *MockCameraServiceForConfig) TestConnection(ctx context.Context, cam *models.Camera) (*camera.ConnectionTestResult, error) {
+	args := m.Called(ctx, cam)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.ConnectionTestResult), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) GetSnapshot(ctx context.Context, id string, channel int, fresh bool) ([]byte, bool, error) {
+	args := m.Called(ctx, id, channel, fresh)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]byte), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCameraServiceForConfig) StreamSnapshot(ctx context.Context, id string, channel int, fresh bool) (*camera.SnapshotStream, bool, error) {
+	args := m.Called(ctx, id, channel, fresh)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*camera.SnapshotStream), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCameraServiceForConfig) RebootAndWait(ctx context.Context, id string, timeout time.Duration) (*camera.RebootStatus, error) {
+	args := m.Called(ctx, id, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.RebootStatus), args.Error(1)
+}
+
+func (m *MockCameraServiceForConfig) GetRebootStatus(id string) (*camera.RebootStatus, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.RebootStatus), args.Error(1)
+}
+
 func TestCameraHandler_GetCameraConfig_DeviceName(t *testing.T) {
 	mockService := new(MockCameraServiceForConfig)
 	handler := &CameraHandler{cameraService: mockService}
@@ -312,3 +420,1332 @@ func TestCameraHandler_GetCameraConfig_SupportedTypes(t *testing.T) {
 
 	assert.Equal(t, len(supportedTypes), len(returnedTypes))
 }
+
+func TestCameraHandler_UpdateCameraConfig_RecordsConfigChange(t *testing.T) {
+	// Fake camera API accepting any command
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if cmd := r.URL.Query().Get("cmd"); cmd == "GetDevName" {
+			w.Write([]byte(`[{"cmd":"GetDevName","code":0,"value":{"DevName":{"name":"Front Door"}}}]`))
+			return
+		}
+		w.Write([]byte(`[{"cmd":"` + r.URL.Query().Get("cmd") + `","code":0,"value":{"rspCode":200}}]`))
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123", Name: "Front Door"}
+	client := &camera.CameraClient{
+		Camera: cam,
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	var baseline interface{}
+	mockService.On("RecordConfigBaseline", mock.Anything, cam, "device_name", mock.Anything).
+		Run(func(args mock.Arguments) {
+			// The value being replaced is read before the change is applied
+			baseline, _ = args.Get(3).(func() (interface{}, error))()
+		}).Return()
+	mockService.On("RecordConfigChange", mock.Anything, cam, "device_name", map[string]string{"name": "Back Door"}).Return()
+
+	bodyBytes, _ := json.Marshal(map[string]string{"name": "Back Door"})
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/cameras/camera-123/config/device_name", bytes.NewReader(bodyBytes))
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "camera-123")
+	rctx.URLParams.Add("type", "device_name")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.UpdateCameraConfig(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]string{"name": "Front Door"}, baseline)
+	mockService.AssertExpectations(t)
+}
+
+func TestSelectorOfUpdate(t *testing.T) {
+	assert.Equal(t, configSelector{channel: 0, alarmType: "md", aiType: "people"},
+		selectorOfUpdate("device_name", map[string]string{"name": "Back Door"}))
+	assert.Equal(t, configSelector{channel: 1, alarmType: "md", aiType: "people"},
+		selectorOfUpdate("osd", map[string]interface{}{"channel": 1}))
+	assert.Equal(t, configSelector{channel: 2, alarmType: "io", aiType: "people"},
+		selectorOfUpdate("alarm", map[string]interface{}{"channel": 2, "type": "io"}))
+	assert.Equal(t, configSelector{channel: 0, alarmType: "md", aiType: "vehicle"},
+		selectorOfUpdate("ai_alarm", map[string]interface{}{"ai_type": "vehicle"}))
+}
+
+// newConfigRequest builds a request with the chi URL params used by the config routes
+func newConfigRequest(method, target string, body []byte, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for k, v := range params {
+		rctx.URLParams.Add(k, v)
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestCameraHandler_GetCameraConfigHistory(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	history := []*models.CameraConfigVersion{
+		{CameraID: "camera-123", ConfigType: "device_name", Version: 2, Config: json.RawMessage(`{"name":"Garage"}`)},
+		{CameraID: "camera-123", ConfigType: "device_name", Version: 1, Config: json.RawMessage(`{"name":"Front Door"}`)},
+	}
+	mockService.On("GetConfigHistory", mock.Anything, "camera-123", "device_name", 10, 0).Return(history, nil)
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/config/device_name/history?limit=10", nil,
+		map[string]string{"id": "camera-123", "type": "device_name"})
+	w := httptest.NewRecorder()
+
+	handler.GetCameraConfigHistory(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			History []*models.CameraConfigVersion `json:"history"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Len(t, response.Data.History, 2)
+	assert.Equal(t, 2, response.Data.History[0].Version)
+	assert.JSONEq(t, `{"name":"Front Door"}`, string(response.Data.History[1].Config))
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_RollbackCameraConfig_AppliesSnapshot(t *testing.T) {
+	var appliedBody string
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		appliedBody = string(body)
+		w.Write([]byte(`[{"cmd":"` + r.URL.Query().Get("cmd") + `","code":0,"value":{"rspCode":200}}]`))
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123", Name: "Garage"}
+	client := &camera.CameraClient{
+		Camera: cam,
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	snapshot := &models.CameraConfigVersion{CameraID: "camera-123", ConfigType: "device_name", Version: 1, Config: json.RawMessage(`{"name":"Front Door"}`)}
+
+	mockService.On("GetConfigVersion", mock.Anything, "camera-123", "device_name", 1).Return(snapshot, nil)
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("RecordConfigChange", mock.Anything, cam, "device_name", map[string]string{"name": "Front Door"}).Return()
+
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/config/device_name/rollback/1", nil,
+		map[string]string{"id": "camera-123", "type": "device_name", "version": "1"})
+	w := httptest.NewRecorder()
+
+	handler.RollbackCameraConfig(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, appliedBody, `"SetDevName"`)
+	assert.Contains(t, appliedBody, `"Front Door"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_RollbackCameraConfig_VersionNotFound(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	mockService.On("GetConfigVersion", mock.Anything, "camera-123", "time", 7).Return(nil, errors.New("config version not found"))
+
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/config/time/rollback/7", nil,
+		map[string]string{"id": "camera-123", "type": "time", "version": "7"})
+	w := httptest.NewRecorder()
+
+	handler.RollbackCameraConfig(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_RollbackCameraConfig_InvalidInput(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	for _, params := range []map[string]string{
+		{"id": "camera-123", "type": "unsupported", "version": "1"},
+		{"id": "camera-123", "type": "time", "version": "abc"},
+		{"id": "camera-123", "type": "time", "version": "0"},
+	} {
+		req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/config/x/rollback/x", nil, params)
+		w := httptest.NewRecorder()
+
+		handler.RollbackCameraConfig(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, params)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_PatchCameraConfig_OnlyChangesProvidedFields(t *testing.T) {
+	var setBody []byte
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cmd") {
+		case "GetTime":
+			w.Write([]byte(`[{"cmd":"GetTime","code":0,"value":{"Time":{"year":2024,"mon":3,"day":14,"hour":9,"min":30,"sec":0,"timeZone":-3600,"timeFormat":"DD/MM/YYYY"}}}]`))
+		case "SetTime":
+			setBody, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`[{"cmd":"SetTime","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123"}
+	client := &camera.CameraClient{
+		Camera: cam,
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("RecordConfigBaseline", mock.Anything, cam, "time", mock.Anything).Return()
+	mockService.On("RecordConfigChange", mock.Anything, cam, "time", mock.Anything).Return()
+
+	req := newConfigRequest(http.MethodPatch, "/api/v1/cameras/camera-123/config/time",
+		[]byte(`{"hour":17,"timeFormat":"YYYY/MM/DD"}`),
+		map[string]string{"id": "camera-123", "type": "time"})
+	w := httptest.NewRecorder()
+
+	handler.PatchCameraConfig(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var written []struct {
+		Param struct {
+			Time reolink.TimeConfig `json:"Time"`
+		} `json:"param"`
+	}
+	require.NoError(t, json.Unmarshal(setBody, &written))
+	require.Len(t, written, 1)
+	assert.Equal(t, reolink.TimeConfig{
+		Year: 2024, Mon: 3, Day: 14,
+		Hour: 17, Min: 30, Sec: 0,
+		TimeZone:   -3600,
+		TimeFormat: "YYYY/MM/DD",
+	}, written[0].Param.Time)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_UpdateCameraConfig_ForwardsToSetter(t *testing.T) {
+	tests := []struct {
+		configType string
+		body       string
+		setCmd     string
+		paramKey   string
+	}{
+		{"osd", `{"channel":1,"osdChannel":{"enable":1,"name":"Gate","pos":"Upper Left"}}`, "SetOsd", "Osd"},
+		{"encoding", `{"channel":1,"audio":1}`, "SetEnc", "Enc"},
+		{"image", `{"channel":1,"bright":140}`, "SetImage", "Image"},
+		{"motion_alarm", `{"channel":1}`, "SetMdAlarm", "MdAlarm"},
+		{"ntp", `{"enable":1,"server":"pool.ntp.org","port":123}`, "SetNtp", "Ntp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.configType, func(t *testing.T) {
+			var setCmd string
+			var setBody []byte
+			cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				setCmd = r.URL.Query().Get("cmd")
+				setBody, _ = io.ReadAll(r.Body)
+				w.Write([]byte(`[{"cmd":"` + setCmd + `","code":0,"value":{"rspCode":200}}]`))
+			}))
+			defer cameraServer.Close()
+
+			mockService := new(MockCameraServiceForConfig)
+			handler := &CameraHandler{cameraService: mockService}
+
+			cam := &models.Camera{ID: "camera-123"}
+			client := &camera.CameraClient{
+				Camera: cam,
+				Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+			}
+			mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+			mockService.On("RecordConfigBaseline", mock.Anything, cam, tt.configType, mock.Anything).Return()
+			mockService.On("RecordConfigChange", mock.Anything, cam, tt.configType, mock.Anything).Return()
+
+			req := newConfigRequest(http.MethodPut, "/api/v1/cameras/camera-123/config/"+tt.configType,
+				[]byte(tt.body), map[string]string{"id": "camera-123", "type": tt.configType})
+			w := httptest.NewRecorder()
+
+			handler.UpdateCameraConfig(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			assert.Equal(t, tt.setCmd, setCmd)
+
+			var written []struct {
+				Param map[string]map[string]interface{} `json:"param"`
+			}
+			require.NoError(t, json.Unmarshal(setBody, &written))
+			require.Len(t, written, 1)
+
+			var want map[string]interface{}
+			require.NoError(t, json.Unmarshal([]byte(tt.body), &want))
+			for key, value := range want {
+				assert.Equal(t, value, written[0].Param[tt.paramKey][key], key)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCameraHandler_PatchCameraConfig_UsesChannel(t *testing.T) {
+	var getBody, setBody []byte
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("cmd") {
+		case "GetOsd":
+			getBody, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`[{"cmd":"GetOsd","code":0,"value":{"Osd":{"channel":2,"bgcolor":0,` +
+				`"osdChannel":{"enable":1,"name":"Gate","pos":"Upper Left"},"osdTime":{"enable":1,"pos":"Lower Right"},"watermark":1}}}]`))
+		case "SetOsd":
+			setBody, _ = io.ReadAll(r.Body)
+			w.Write([]byte(`[{"cmd":"SetOsd","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123"}
+	client := &camera.CameraClient{
+		Camera: cam,
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("RecordConfigBaseline", mock.Anything, cam, "osd", mock.Anything).Return()
+	mockService.On("RecordConfigChange", mock.Anything, cam, "osd", mock.Anything).Return()
+
+	req := newConfigRequest(http.MethodPatch, "/api/v1/cameras/camera-123/config/osd?channel=2",
+		[]byte(`{"watermark":0}`), map[string]string{"id": "camera-123", "type": "osd"})
+	w := httptest.NewRecorder()
+
+	handler.PatchCameraConfig(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, string(getBody), `"channel":2`)
+
+	var written []struct {
+		Param struct {
+			Osd reolink.Osd `json:"Osd"`
+		} `json:"param"`
+	}
+	require.NoError(t, json.Unmarshal(setBody, &written))
+	require.Len(t, written, 1)
+	assert.Equal(t, 2, written[0].Param.Osd.Channel)
+	assert.Equal(t, 0, written[0].Param.Osd.Watermark)
+	assert.Equal(t, "Gate", written[0].Param.Osd.OsdChannel.Name)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_PatchCameraConfig_InvalidInput(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	tests := []struct {
+		name       string
+		configType string
+		body       string
+	}{
+		{"unsupported type", "unsupported", `{"enable":1}`},
+		{"not an object", "time", `[1,2,3]`},
+		{"invalid json", "time", `{"hour":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodPatch, "/api/v1/cameras/camera-123/config/"+tt.configType,
+				[]byte(tt.body), map[string]string{"id": "camera-123", "type": tt.configType})
+			w := httptest.NewRecorder()
+
+			handler.PatchCameraConfig(w, req)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+		})
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestMergePatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		patch    string
+		want     string
+	}{
+		{"replace field", `{"a":1,"b":2}`, `{"b":3}`, `{"a":1,"b":3}`},
+		{"add field", `{"a":1}`, `{"b":2}`, `{"a":1,"b":2}`},
+		{"null removes field", `{"a":1,"b":2}`, `{"b":null}`, `{"a":1}`},
+		{"nested merge", `{"a":{"x":1,"y":2}}`, `{"a":{"y":3}}`, `{"a":{"x":1,"y":3}}`},
+		{"array replaced", `{"a":[1,2]}`, `{"a":[3]}`, `{"a":[3]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergePatch([]byte(tt.document), []byte(tt.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.want, string(got))
+		})
+	}
+}
+
+func TestCameraHandler_GetCameraCapabilities(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123", Capabilities: models.CameraCapabilities{"ptzCtrl": true}}
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(cam, nil)
+	mockService.On("GetCamera", mock.Anything, "missing").Return(nil, errors.New("camera not found"))
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/capabilities", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetCameraCapabilities(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"ptzCtrl": true}, data["capabilities"])
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/missing/capabilities", nil, map[string]string{"id": "missing"})
+	w = httptest.NewRecorder()
+	handler.GetCameraCapabilities(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_GetCamera_RecentEvents(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	cam := &models.Camera{ID: "camera-123", Name: "Front Door"}
+	events := []*models.Event{
+		{ID: "event-2", CameraID: "camera-123", Type: models.EventMotionDetected},
+		{ID: "event-1", CameraID: "camera-123", Type: models.EventMotionDetected},
+	}
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(cam, nil)
+	mockService.On("GetCameraEvents", mock.Anything, "camera-123", recentEventsLimit, 0).Return(events, nil).Once()
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetCamera(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, "Front Door", data["name"])
+	assert.NotContains(t, data, "recent_events")
+	mockService.AssertNotCalled(t, "GetCameraEvents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123?include=recent_events", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.GetCamera(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data = response["data"].(map[string]interface{})
+	assert.Equal(t, "Front Door", data["name"])
+	recent := data["recent_events"].([]interface{})
+	require.Len(t, recent, 2)
+	assert.Equal(t, "event-2", recent[0].(map[string]interface{})["id"])
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_GetCamera_RecentEventsErrors(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123?include=recordings", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetCamera(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(&models.Camera{ID: "camera-123"}, nil)
+	mockService.On("GetCameraEvents", mock.Anything, "camera-123", recentEventsLimit, 0).Return(nil, errors.New("db down"))
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123?include=recent_events", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.GetCamera(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCameraHandler_ProbeCamera(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	result := &camera.ProbeResult{
+		CameraID:    "camera-123",
+		FailedLayer: camera.ProbeLayerAuth,
+		Steps: []camera.ProbeStepResult{
+			{Layer: camera.ProbeLayerTCP, Status: "ok"},
+			{Layer: camera.ProbeLayerHTTP, Status: "ok"},
+			{Layer: camera.ProbeLayerAuth, Status: "failed", Error: "login failed"},
+		},
+	}
+	mockService.On("ProbeCamera", mock.Anything, "camera-123", (*bool)(nil)).Return(result, nil)
+	mockService.On("ProbeCamera", mock.Anything, "missing", (*bool)(nil)).Return(nil, fmt.Errorf("%w: missing", service.ErrCameraNotFound))
+	mockService.On("ProbeCamera", mock.Anything, "camera-456", (*bool)(nil)).Return(nil, errors.New("connection refused"))
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/probe", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.ProbeCamera(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.Equal(t, false, data["reachable"])
+	assert.Equal(t, "auth", data["failed_layer"])
+	assert.Len(t, data["steps"], 3)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/missing/probe", nil, map[string]string{"id": "missing"})
+	w = httptest.NewRecorder()
+	handler.ProbeCamera(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Failing to look the camera up is not reported as a missing camera
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-456/probe", nil, map[string]string{"id": "camera-456"})
+	w = httptest.NewRecorder()
+	handler.ProbeCamera(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
+
+func TestCameraHandler_ProbeCamera_SkipVerifyOverride(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	skipVerify := true
+	mockService.On("ProbeCamera", mock.Anything, "camera-123", &skipVerify).Return(&camera.ProbeResult{CameraID: "camera-123", Reachable: true}, nil)
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/probe?skip_verify=true", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.ProbeCamera(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/probe?skip_verify=maybe", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.ProbeCamera(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCameraHandler_ProbeNewCamera(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	mockService.On("ProbeNewCamera", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.Host == "192.168.1.50" && cam.Port == 443 && cam.UseHTTPS && cam.SkipVerify
+	})).Return(&camera.ProbeResult{Reachable: true})
+
+	body := []byte(`{"host":"192.168.1.50","username":"admin","password":"secret","use_https":true,"skip_verify":true}`)
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/probe", body, nil)
+	w := httptest.NewRecorder()
+	handler.ProbeNewCamera(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"reachable":true`)
+	mockService.AssertExpectations(t)
+
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/probe", []byte(`{"host":"192.168.1.50"}`), nil)
+	w = httptest.NewRecorder()
+	handler.ProbeNewCamera(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCameraHandler_TestConnection(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	decode := func(w *httptest.ResponseRecorder) map[string]interface{} {
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	// A camera accepting the login reports what it is
+	mockService.On("TestConnection", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.Host == "192.168.1.50" && cam.Port == 80 && cam.Password == "secret"
+	})).Return(&camera.ConnectionTestResult{
+		Reachable:       true,
+		Authenticated:   true,
+		Model:           "RLC-810A",
+		FirmwareVersion: "v3.1.0.956",
+		HardwareVersion: "IPC_523128M8MP",
+		ChannelCount:    1,
+		Duration:        time.Second,
+	}, nil).Once()
+
+	body := []byte(`{"name":"Front Door","host":"192.168.1.50","username":"admin","password":"secret"}`)
+	w := httptest.NewRecorder()
+	handler.TestConnection(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/test", body, nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"reachable":        true,
+		"authenticated":    true,
+		"model":            "RLC-810A",
+		"firmware_version": "v3.1.0.956",
+		"hardware_version": "IPC_523128M8MP",
+		"channel_count":    float64(1),
+		"duration_ns":      float64(time.Second),
+	}, decode(w))
+
+	// A camera refusing the credentials is still a successful test
+	mockService.On("TestConnection", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.Password == "wrong"
+	})).Return(&camera.ConnectionTestResult{
+		Reachable: true,
+		Duration:  time.Millisecond,
+		Error:     "reolink api error: cmd=Login code=1 rspCode=-7 detail=login failed",
+	}, nil).Once()
+
+	body = []byte(`{"host":"192.168.1.50","username":"admin","password":"wrong"}`)
+	w = httptest.NewRecorder()
+	handler.TestConnection(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/test", body, nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]interface{}{
+		"reachable":     true,
+		"authenticated": false,
+		"duration_ns":   float64(time.Millisecond),
+		"error":         "reolink api error: cmd=Login code=1 rspCode=-7 detail=login failed",
+	}, decode(w))
+
+	// Unknown connection profiles are rejected
+	mockService.On("TestConnection", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.ConnectionProfile == "satellite"
+	})).Return(nil, camera.ErrUnknownConnectionProfile).Once()
+
+	body = []byte(`{"host":"192.168.1.50","username":"admin","password":"secret","connection_profile":"satellite"}`)
+	w = httptest.NewRecorder()
+	handler.TestConnection(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/test", body, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+
+	// Incomplete requests never reach the camera
+	w = httptest.NewRecorder()
+	handler.TestConnection(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/test", []byte(`{"host":"192.168.1.50"}`), nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertNumberOfCalls(t, "TestConnection", 3)
+}
+
+func TestCameraHandler_GetSnapshot_Cache(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xD9}
+	mockService.On("GetSnapshot", mock.Anything, "camera-123", 0, false).Return(jpeg, true, nil)
+	mockService.On("GetSnapshot", mock.Anything, "camera-123", 1, true).Return(jpeg, false, nil)
+	mockService.On("GetSnapshot", mock.Anything, "missing", 0, false).Return(nil, false, fmt.Errorf("%w: missing", service.ErrCameraNotFound))
+
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		wantStatus int
+		wantCache  string
+	}{
+		{"cached", "camera-123", "", http.StatusOK, "HIT"},
+		{"fresh", "camera-123", "?channel=1&fresh=true", http.StatusOK, "MISS"},
+		{"invalid fresh", "camera-123", "?fresh=maybe", http.StatusBadRequest, ""},
+		{"invalid channel", "camera-123", "?channel=x", http.StatusBadRequest, ""},
+		{"unknown camera", "missing", "", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodGet, "/api/v1/cameras/"+tt.id+"/snapshot"+tt.query, nil, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.GetSnapshot(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantCache, w.Header().Get("X-Cache"))
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, jpeg, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestCameraHandler_GetSnapshot_Delivery(t *testing.T) {
+	jpeg := []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0xFF, 0xD9}
+
+	tests := []struct {
+		name       string
+		streaming  bool
+		stream     *camera.SnapshotStream
+		cached     bool
+		wantLength string
+		wantCache  string
+	}{
+		{"buffered", false, nil, false, strconv.Itoa(len(jpeg)), "MISS"},
+		{"streamed without size", true, &camera.SnapshotStream{Reader: bytes.NewReader(jpeg), Size: -1}, false, "", "MISS"},
+		{"streamed with size", true, &camera.SnapshotStream{Reader: bytes.NewReader(jpeg), Size: int64(len(jpeg))}, false, strconv.Itoa(len(jpeg)), "MISS"},
+		{"streamed from cache", true, camera.NewBufferedSnapshotStream(jpeg), true, strconv.Itoa(len(jpeg)), "HIT"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCameraServiceForConfig)
+			handler := &CameraHandler{cameraService: mockService}
+			handler.SetSnapshotStreaming(tt.streaming)
+			if tt.streaming {
+				mockService.On("StreamSnapshot", mock.Anything, "camera-123", 0, false).Return(tt.stream, tt.cached, nil)
+			} else {
+				mockService.On("GetSnapshot", mock.Anything, "camera-123", 0, false).Return(jpeg, tt.cached, nil)
+			}
+
+			req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/snapshot", nil, map[string]string{"id": "camera-123"})
+			w := httptest.NewRecorder()
+			handler.GetSnapshot(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantLength, w.Header().Get("Content-Length"))
+			assert.Equal(t, tt.wantCache, w.Header().Get("X-Cache"))
+			assert.Equal(t, jpeg, w.Body.Bytes())
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	// Streaming failures before the response starts are reported like buffered ones
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+	handler.SetSnapshotStreaming(true)
+	mockService.On("StreamSnapshot", mock.Anything, "missing", 0, false).Return(nil, false, fmt.Errorf("%w: missing", service.ErrCameraNotFound))
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/missing/snapshot", nil, map[string]string{"id": "missing"})
+	w := httptest.NewRecorder()
+	handler.GetSnapshot(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_CameraErrors(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		w.Write([]byte(`[{"cmd":"` + cmd + `","code":1,"error":{"rspCode":-9,"detail":"not support"}}]`))
+	}))
+	defer apiServer.Close()
+
+	offlineServer := httptest.NewServer(http.NotFoundHandler())
+	offlineServer.Close()
+
+	clients := map[string]*camera.CameraClient{
+		"circuit-open": {Camera: &models.Camera{ID: "circuit-open"}, CircuitOpen: true},
+		"offline": {
+			Camera: &models.Camera{ID: "offline"},
+			Client: reolink.NewClient(strings.TrimPrefix(offlineServer.URL, "http://"), reolink.WithToken("test-token")),
+		},
+		"api-error": {
+			Camera: &models.Camera{ID: "api-error"},
+			Client: reolink.NewClient(strings.TrimPrefix(apiServer.URL, "http://"), reolink.WithToken("test-token")),
+		},
+	}
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+	for id, client := range clients {
+		// The service passes the client's snapshot error through
+		_, snapshotErr := client.GetSnapshot(context.Background(), 0)
+		require.Error(t, snapshotErr)
+		mockService.On("GetCameraClient", id).Return(client, nil)
+		mockService.On("GetSnapshot", mock.Anything, id, 0, false).Return(nil, false, snapshotErr)
+	}
+
+	tests := []struct {
+		id         string
+		wantStatus int
+		wantCode   string
+	}{
+		{"circuit-open", http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE"},
+		{"offline", http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE"},
+		{"api-error", http.StatusBadGateway, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id+" snapshot", func(t *testing.T) {
+			req := newConfigRequest(http.MethodGet, "/api/v1/cameras/"+tt.id+"/snapshot", nil, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.GetSnapshot(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Contains(t, w.Body.String(), tt.wantCode)
+			}
+		})
+
+		t.Run(tt.id+" reboot", func(t *testing.T) {
+			req := newConfigRequest(http.MethodPost, "/api/v1/cameras/"+tt.id+"/reboot", nil, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.RebootCamera(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Contains(t, w.Body.String(), tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestCameraHandler_NameTaken(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	taken := fmt.Errorf("%w: Front Door", service.ErrCameraNameTaken)
+	mockService.On("AddCamera", mock.Anything, mock.Anything).Return(taken)
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(&models.Camera{ID: "camera-123", Name: "Garage"}, nil)
+	mockService.On("UpdateCamera", mock.Anything, mock.Anything).Return(taken)
+
+	body := []byte(`{"name":"Front Door","host":"192.168.1.100","username":"admin","password":"secret"}`)
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras", body, nil)
+	w := httptest.NewRecorder()
+	handler.AddCamera(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "CAMERA_NAME_TAKEN")
+
+	req = newConfigRequest(http.MethodPut, "/api/v1/cameras/camera-123", []byte(`{"name":"Front Door"}`), map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.UpdateCamera(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "CAMERA_NAME_TAKEN")
+}
+
+func TestCameraHandler_ConnectionProfile(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	unknown := fmt.Errorf("%w: satellite", camera.ErrUnknownConnectionProfile)
+	mockService.On("AddCamera", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.ConnectionProfile == "satellite"
+	})).Return(unknown)
+	mockService.On("AddCamera", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.ConnectionProfile == "cellular"
+	})).Return(nil)
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(&models.Camera{ID: "camera-123", Name: "Garage"}, nil)
+	mockService.On("UpdateCamera", mock.Anything, mock.MatchedBy(func(cam *models.Camera) bool {
+		return cam.ConnectionProfile == "satellite"
+	})).Return(unknown)
+
+	body := []byte(`{"name":"Barn","host":"192.168.1.100","username":"admin","password":"secret","connection_profile":"cellular"}`)
+	w := httptest.NewRecorder()
+	handler.AddCamera(w, newConfigRequest(http.MethodPost, "/api/v1/cameras", body, nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"connection_profile":"cellular"`)
+
+	body = []byte(`{"name":"Barn","host":"192.168.1.100","username":"admin","password":"secret","connection_profile":"satellite"}`)
+	w = httptest.NewRecorder()
+	handler.AddCamera(w, newConfigRequest(http.MethodPost, "/api/v1/cameras", body, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "unknown connection profile: satellite")
+
+	req := newConfigRequest(http.MethodPut, "/api/v1/cameras/camera-123", []byte(`{"connection_profile":"satellite"}`), map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.UpdateCamera(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_PTZHome(t *testing.T) {
+	tests := []struct {
+		name       string
+		existPos   int
+		wantStatus int
+		wantMove   bool
+	}{
+		{"guard configured", 1, http.StatusOK, true},
+		{"no guard configured", 0, http.StatusUnprocessableEntity, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var moveBody string
+			cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch cmd := r.URL.Query().Get("cmd"); cmd {
+				case "GetPtzGuard":
+					w.Write([]byte(`[{"cmd":"GetPtzGuard","code":0,"value":{"PtzGuard":{"channel":0,"benable":1,"bexistPos":` + strconv.Itoa(tt.existPos) + `,"timeout":60}}}]`))
+				default:
+					body, _ := io.ReadAll(r.Body)
+					moveBody = string(body)
+					w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+				}
+			}))
+			defer cameraServer.Close()
+
+			mockService := new(MockCameraServiceForConfig)
+			handler := &CameraHandler{cameraService: mockService}
+
+			client := &camera.CameraClient{
+				Camera: &models.Camera{ID: "camera-123"},
+				Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+			}
+			mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+
+			req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/ptz/home", nil, map[string]string{"id": "camera-123"})
+			w := httptest.NewRecorder()
+			handler.PTZHome(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantMove {
+				assert.Contains(t, moveBody, `"cmdStr":"toPos"`)
+			} else {
+				assert.Empty(t, moveBody)
+				assert.Contains(t, w.Body.String(), "NO_GUARD_POSITION")
+			}
+		})
+	}
+}
+
+func TestCameraHandler_PurgeCameraHistory(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	purge := &models.CameraHistoryPurge{CameraID: "camera-123", EventsDeleted: 12, RecordingsDeleted: 3, FilesDeleted: 5}
+	mockService.On("GetCamera", mock.Anything, "camera-123").Return(&models.Camera{ID: "camera-123"}, nil)
+	mockService.On("PurgeCameraHistory", mock.Anything, "camera-123").Return(purge, nil)
+	mockService.On("GetCamera", mock.Anything, "missing").Return(nil, errors.New("camera not found"))
+
+	req := newConfigRequest(http.MethodDelete, "/api/v1/cameras/camera-123/history", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.PurgeCameraHistory(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	data := response["data"].(map[string]interface{})
+	assert.EqualValues(t, 12, data["events_deleted"])
+	assert.EqualValues(t, 3, data["recordings_deleted"])
+	assert.EqualValues(t, 5, data["files_deleted"])
+
+	req = newConfigRequest(http.MethodDelete, "/api/v1/cameras/missing/history", nil, map[string]string{"id": "missing"})
+	w = httptest.NewRecorder()
+	handler.PurgeCameraHistory(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	mockService.AssertNotCalled(t, "PurgeCameraHistory", mock.Anything, "missing")
+}
+
+func TestCameraHandler_ListPTZPresets(t *testing.T) {
+	var requestBody string
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		w.Write([]byte(`[{"cmd":"GetPtzPreset","code":0,"value":{"PtzPreset":[{"enable":1,"id":1,"name":"gate"},{"enable":0,"id":2,"name":""},{"enable":1,"id":5,"name":"driveway"}]}}]`))
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/ptz/presets?channel=1", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.ListPTZPresets(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, requestBody, `"channel":1`)
+
+	var response struct {
+		Data struct {
+			Channel int                 `json:"channel"`
+			Presets []reolink.PtzPreset `json:"presets"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Data.Channel)
+	require.Len(t, response.Data.Presets, 2)
+	assert.Equal(t, "gate", response.Data.Presets[0].Name)
+	assert.Equal(t, 5, response.Data.Presets[1].ID)
+}
+
+func TestCameraHandler_ListPTZPresets_Errors(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"GetPtzPreset","code":1,"error":{"detail":"not supported","rspCode":-9}}]`))
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "missing").Return(nil, errors.New("camera not found"))
+
+	tests := []struct {
+		name       string
+		id         string
+		query      string
+		wantStatus int
+	}{
+		{"unknown camera", "missing", "", http.StatusNotFound},
+		{"invalid channel", "camera-123", "?channel=abc", http.StatusBadRequest},
+		{"camera error", "camera-123", "", http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodGet, "/api/v1/cameras/"+tt.id+"/ptz/presets"+tt.query, nil, map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.ListPTZPresets(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestCameraHandler_SavePTZPreset(t *testing.T) {
+	var requestBody string
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		w.Write([]byte(`[{"cmd":"SetPtzPreset","code":0,"value":{"rspCode":200}}]`))
+	}))
+	defer cameraServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "missing").Return(nil, errors.New("camera not found"))
+
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/ptz/presets", []byte(`{"name":"gate","preset_id":3,"channel":0}`), map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.SavePTZPreset(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, requestBody, `"cmd":"SetPtzPreset"`)
+	assert.Contains(t, requestBody, `"id":3`)
+	assert.Contains(t, requestBody, `"name":"gate"`)
+	assert.Contains(t, requestBody, `"enable":1`)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"unknown camera", "missing", `{"name":"gate","preset_id":3}`, http.StatusNotFound},
+		{"invalid json", "camera-123", `{`, http.StatusBadRequest},
+		{"missing name", "camera-123", `{"preset_id":3}`, http.StatusBadRequest},
+		{"preset id out of range", "camera-123", `{"name":"gate","preset_id":65}`, http.StatusBadRequest},
+		{"other channel", "camera-123", `{"name":"gate","preset_id":3,"channel":1}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodPost, "/api/v1/cameras/"+tt.id+"/ptz/presets", []byte(tt.body), map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.SavePTZPreset(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestCameraHandler_PTZPatrol(t *testing.T) {
+	var requestBody string
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requestBody = string(body)
+		w.Write([]byte(`[{"cmd":"PtzCtrl","code":0,"value":{"rspCode":200}}]`))
+	}))
+	defer cameraServer.Close()
+
+	offlineServer := httptest.NewServer(http.NotFoundHandler())
+	offlineServer.Close()
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	mockService.On("GetCameraClient", "camera-123").Return(&camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}, nil)
+	mockService.On("GetCameraClient", "offline").Return(&camera.CameraClient{
+		Camera: &models.Camera{ID: "offline"},
+		Client: reolink.NewClient(strings.TrimPrefix(offlineServer.URL, "http://"), reolink.WithToken("test-token")),
+	}, nil)
+	mockService.On("GetCameraClient", "missing").Return(nil, errors.New("camera not found"))
+
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/ptz/patrol/start", []byte(`{"patrol_id":2,"channel":1}`), map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.PTZPatrolStart(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, requestBody, `"op":"StartPatrol"`)
+	assert.Contains(t, requestBody, `"id":2`)
+	assert.Contains(t, requestBody, `"channel":1`)
+
+	// The body is optional
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/ptz/patrol/stop", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.PTZPatrolStop(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, requestBody, `"op":"StopPatrol"`)
+
+	tests := []struct {
+		name       string
+		id         string
+		body       string
+		wantStatus int
+	}{
+		{"unknown camera", "missing", `{"patrol_id":1}`, http.StatusNotFound},
+		{"offline camera", "offline", `{"patrol_id":1}`, http.StatusServiceUnavailable},
+		{"invalid json", "camera-123", `{`, http.StatusBadRequest},
+		{"negative patrol id", "camera-123", `{"patrol_id":-1}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodPost, "/api/v1/cameras/"+tt.id+"/ptz/patrol/start", []byte(tt.body), map[string]string{"id": tt.id})
+			w := httptest.NewRecorder()
+			handler.PTZPatrolStart(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestCameraHandler_RebootCamera_Wait(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	status := &camera.RebootStatus{CameraID: "camera-123", State: camera.RebootStateWaiting, StartedAt: time.Now(), Timeout: "2m0s"}
+	mockService.On("GetCameraClient", "camera-123").Return(&camera.CameraClient{Camera: &models.Camera{ID: "camera-123"}}, nil)
+	mockService.On("RebootAndWait", mock.Anything, "camera-123", 2*time.Minute).Return(status, nil).Once()
+
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/reboot?wait=true&timeout=2m", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.RebootCamera(w, req)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"waiting"`)
+
+	// A reboot already being waited on is refused
+	mockService.On("RebootAndWait", mock.Anything, "camera-123", camera.DefaultRebootWaitTimeout).Return(nil, camera.ErrRebootInProgress).Once()
+
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/reboot?wait=true", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.RebootCamera(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/reboot?wait=true&timeout=soon", nil, map[string]string{"id": "camera-123"})
+	w = httptest.NewRecorder()
+	handler.RebootCamera(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_GetRebootStatus(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	finished := time.Now()
+	status := &camera.RebootStatus{CameraID: "camera-123", State: camera.RebootStateTimeout, FinishedAt: &finished}
+	mockService.On("GetRebootStatus", "camera-123").Return(status, nil)
+	mockService.On("GetRebootStatus", "camera-999").Return(nil, errors.New("no reboot recorded"))
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/reboot", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetRebootStatus(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"timeout"`)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-999/reboot", nil, map[string]string{"id": "camera-999"})
+	w = httptest.NewRecorder()
+	handler.GetRebootStatus(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_GetDeviceInfo(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	info := &models.CameraDeviceInfo{
+		CameraID:     "camera-123",
+		Model:        "RLC-810A",
+		FirmwareVer:  "v3.1.0.956",
+		HardwareVer:  "IPC_523128M8MP",
+		ChannelCount: 1,
+		Detail:       "IPC_523128M8MP_V3.1.0.956",
+		Uptime:       3600,
+	}
+	mockService.On("GetDeviceInfo", mock.Anything, "camera-123").Return(info, nil)
+	mockService.On("GetDeviceInfo", mock.Anything, "camera-999").Return(nil, service.ErrCameraNotFound)
+	mockService.On("GetDeviceInfo", mock.Anything, "camera-off").Return(nil, camera.ErrCircuitOpen)
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/info", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetDeviceInfo(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data models.CameraDeviceInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, *info, resp.Data)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-999/info", nil, map[string]string{"id": "camera-999"})
+	w = httptest.NewRecorder()
+	handler.GetDeviceInfo(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-off/info", nil, map[string]string{"id": "camera-off"})
+	w = httptest.NewRecorder()
+	handler.GetDeviceInfo(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+// newStorageCameraServer fakes the camera API with one disk, recording the
+// disks asked to be formatted
+func newStorageCameraServer(t *testing.T, formatted *[]int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "GetHddInfo":
+			w.Write([]byte(`[{"cmd":"GetHddInfo","code":0,"value":{"HddInfo":[` +
+				`{"capacity":1000,"format":1,"mount":1,"size":250,"status":"ok"}]}}]`))
+		case "Format":
+			var req []struct {
+				Param reolink.FormatParam `json:"param"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			*formatted = append(*formatted, req[0].Param.Hdd.ID)
+			w.Write([]byte(`[{"cmd":"Format","code":0,"value":{"rspCode":200}}]`))
+		default:
+			t.Errorf("unexpected command %s", cmd)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCameraHandler_GetCameraStorage(t *testing.T) {
+	server := newStorageCameraServer(t, nil)
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	offline := &camera.CameraClient{Camera: &models.Camera{ID: "camera-off"}, CircuitOpen: true}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "camera-off").Return(offline, nil)
+	mockService.On("GetCameraClient", "camera-999").Return(nil, camera.ErrNotFound)
+
+	req := newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/storage", nil, map[string]string{"id": "camera-123"})
+	w := httptest.NewRecorder()
+	handler.GetCameraStorage(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data []models.CameraDisk `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []models.CameraDisk{{
+		ID:          0,
+		CapacityMB:  1000,
+		UsedMB:      250,
+		FreeMB:      750,
+		UsedPercent: 25,
+		Formatted:   true,
+		Mounted:     true,
+		Status:      "ok",
+	}}, resp.Data)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-999/storage", nil, map[string]string{"id": "camera-999"})
+	w = httptest.NewRecorder()
+	handler.GetCameraStorage(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	req = newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-off/storage", nil, map[string]string{"id": "camera-off"})
+	w = httptest.NewRecorder()
+	handler.GetCameraStorage(w, req)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestCameraHandler_FormatCameraStorage(t *testing.T) {
+	var formatted []int
+	server := newStorageCameraServer(t, &formatted)
+
+	mockService := new(MockCameraServiceForConfig)
+	handler := &CameraHandler{cameraService: mockService}
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "camera-999").Return(nil, camera.ErrNotFound)
+
+	tests := []struct {
+		name       string
+		cameraID   string
+		hddID      string
+		body       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"unconfirmed", "camera-123", "0", `{}`, http.StatusBadRequest, "CONFIRMATION_REQUIRED"},
+		{"declined", "camera-123", "0", `{"confirm":false}`, http.StatusBadRequest, "CONFIRMATION_REQUIRED"},
+		{"no body", "camera-123", "0", ``, http.StatusBadRequest, "INVALID_JSON"},
+		{"invalid disk ID", "camera-123", "sd", `{"confirm":true}`, http.StatusBadRequest, "BAD_REQUEST"},
+		{"unknown camera", "camera-999", "0", `{"confirm":true}`, http.StatusNotFound, "CAMERA_NOT_FOUND"},
+		{"unknown disk", "camera-123", "1", `{"confirm":true}`, http.StatusNotFound, "DISK_NOT_FOUND"},
+		{"confirmed", "camera-123", "0", `{"confirm":true}`, http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newConfigRequest(http.MethodPost, "/api/v1/cameras/"+tt.cameraID+"/storage/"+tt.hddID+"/format",
+				[]byte(tt.body), map[string]string{"id": tt.cameraID, "hddId": tt.hddID})
+			w := httptest.NewRecorder()
+			handler.FormatCameraStorage(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Contains(t, w.Body.String(), `"code":"`+tt.wantCode+`"`)
+			}
+		})
+	}
+
+	// Only the confirmed request reached the camera
+	assert.Equal(t, []int{0}, formatted)
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// errInvalidParams marks command failures caused by the request's params
+var errInvalidParams = errors.New("invalid params")
+
+// cameraCommand runs an action of the command endpoint on a camera. params
+// holds the raw "params" object of the request, or nothing if it was omitted.
+type cameraCommand func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error)
+
+// channelParams are the params of commands taking only a channel
+type channelParams struct {
+	Channel int `json:"channel"`
+}
+
+// cameraCommands maps the actions of POST /cameras/{id}/commands to the
+// CameraClient methods they run. New actions only need an entry here.
+var cameraCommands = map[string]cameraCommand{
+	"reboot": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return nil, client.Reboot(ctx)
+	},
+	"get_time": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return client.GetTime(ctx)
+	},
+	"get_ability": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return client.GetAbility(ctx)
+	},
+	"get_hdd_info": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return client.GetHddInfo(ctx)
+	},
+	"get_channel_status": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		if err := decodeParams(params, &struct{}{}); err != nil {
+			return nil, err
+		}
+		return client.GetChannelStatus(ctx)
+	},
+	"get_motion_state": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p channelParams
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		state, err := client.GetMotionState(ctx, p.Channel)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"channel": p.Channel, "state": state}, nil
+	},
+	"get_ai_state": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p channelParams
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		return client.GetAIState(ctx, p.Channel)
+	},
+	"ptz_move": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		p := struct {
+			Operation string `json:"operation"`
+			Speed     int    `json:"speed"`
+			Channel   int    `json:"channel"`
+		}{Speed: 32}
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		if p.Operation == "" {
+			return nil, fmt.Errorf("%w: operation is required", errInvalidParams)
+		}
+		if p.Speed < 1 || p.Speed > 64 {
+			return nil, fmt.Errorf("%w: speed must be between 1 and 64", errInvalidParams)
+		}
+		return nil, client.PTZMove(ctx, p.Operation, p.Speed, p.Channel)
+	},
+	"ptz_stop": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p channelParams
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		return nil, client.PTZStop(ctx, p.Channel)
+	},
+	"ptz_preset": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p struct {
+			PresetID *int `json:"preset_id"`
+			Channel  int  `json:"channel"`
+		}
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		if p.PresetID == nil {
+			return nil, fmt.Errorf("%w: preset_id is required", errInvalidParams)
+		}
+		return nil, client.PTZGotoPreset(ctx, p.Channel, *p.PresetID)
+	},
+	"ptz_home": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p channelParams
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		return nil, client.PTZGotoGuard(ctx, p.Channel)
+	},
+	"ir_lights": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p struct {
+			State   string `json:"state"`
+			Channel int    `json:"channel"`
+		}
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		if p.State != "Auto" && p.State != "On" && p.State != "Off" {
+			return nil, fmt.Errorf("%w: state must be Auto, On or Off", errInvalidParams)
+		}
+		return nil, client.SetIRLights(ctx, p.Channel, p.State)
+	},
+	"white_led": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p struct {
+			Enabled *bool `json:"enabled"`
+			Channel int   `json:"channel"`
+		}
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		if p.Enabled == nil {
+			return nil, fmt.Errorf("%w: enabled is required", errInvalidParams)
+		}
+		mode := 0
+		if *p.Enabled {
+			mode = 1
+		}
+		return nil, client.SetWhiteLED(ctx, &reolink.WhiteLed{Channel: p.Channel, Mode: mode})
+	},
+	"siren": func(ctx context.Context, client *camera.CameraClient, params json.RawMessage) (interface{}, error) {
+		var p struct {
+			Duration int `json:"duration"`
+			Channel  int `json:"channel"`
+		}
+		if err := decodeChannelParams(params, &p, &p.Channel); err != nil {
+			return nil, err
+		}
+		if p.Duration < 0 {
+			return nil, fmt.Errorf("%w: duration must not be negative", errInvalidParams)
+		}
+		return nil, client.TriggerSiren(ctx, p.Channel, p.Duration)
+	},
+}
+
+// decodeParams decodes command params into dst, rejecting unknown fields.
+// Omitted params leave dst unchanged.
+func decodeParams(params json.RawMessage, dst interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		return fmt.Errorf("%w: %v", errInvalidParams, err)
+	}
+	return nil
+}
+
+// decodeChannelParams is decodeParams for params carrying a channel, which
+// must not be negative
+func decodeChannelParams(params json.RawMessage, dst interface{}, channel *int) error {
+	if err := decodeParams(params, dst); err != nil {
+		return err
+	}
+	if *channel < 0 {
+		return fmt.Errorf("%w: channel must not be negative", errInvalidParams)
+	}
+	return nil
+}
+
+// commandActions returns the supported actions in alphabetical order
+func commandActions() []string {
+	actions := make([]string, 0, len(cameraCommands))
+	for action := range cameraCommands {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// RunCameraCommand handles POST /api/v1/cameras/{id}/commands
+func (h *CameraHandler) RunCameraCommand(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Action string          `json:"action"`
+		Params json.RawMessage `json:"params,omitempty"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	command, ok := cameraCommands[req.Action]
+	if !ok {
+		utils.RespondError(w, http.StatusBadRequest, "UNKNOWN_ACTION", fmt.Sprintf("Unknown action %q", req.Action), map[string]interface{}{
+			"actions": commandActions(),
+		})
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	result, err := command(ctx, client, req.Params)
+	if errors.Is(err, errInvalidParams) {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_PARAMS", err.Error(), nil)
+		return
+	}
+	if err != nil {
+		logger.Error("Camera command failed", zap.Error(err), zap.String("id", cameraID), zap.String("action", req.Action))
+		respondCameraError(w, err, "COMMAND_FAILED", "Failed to run camera command")
+		return
+	}
+
+	logger.Info("Camera command executed", zap.String("id", cameraID), zap.String("action", req.Action))
+
+	response := map[string]interface{}{
+		"action": req.Action,
+	}
+	if result != nil {
+		response["result"] = result
+	}
+	utils.RespondJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newCommandTestHandler returns a handler whose camera answers every command
+// with value and records the commands and bodies it received
+func newCommandTestHandler(t *testing.T, value string) (*CameraHandler, *[]string) {
+	var requests []string
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, cmd+" "+string(body))
+		w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":` + value + `}]`))
+	}))
+	t.Cleanup(cameraServer.Close)
+
+	mockService := new(MockCameraServiceForConfig)
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "missing").Return(nil, errors.New("camera not found"))
+
+	return &CameraHandler{cameraService: mockService}, &requests
+}
+
+func runCommand(handler *CameraHandler, cameraID, body string) *httptest.ResponseRecorder {
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/"+cameraID+"/commands", []byte(body), map[string]string{"id": cameraID})
+	w := httptest.NewRecorder()
+	handler.RunCameraCommand(w, req)
+	return w
+}
+
+func TestCameraHandler_RunCameraCommand_GetTime(t *testing.T) {
+	handler, requests := newCommandTestHandler(t, `{"Time":{"year":2025,"mon":10,"day":27,"hour":10,"min":0,"sec":0,"timeZone":0}}`)
+
+	w := runCommand(handler, "camera-123", `{"action":"get_time"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, *requests, 1)
+	assert.True(t, strings.HasPrefix((*requests)[0], "GetTime "))
+
+	var resp struct {
+		Data struct {
+			Action string             `json:"action"`
+			Result reolink.TimeConfig `json:"result"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "get_time", resp.Data.Action)
+	assert.Equal(t, 2025, resp.Data.Result.Year)
+}
+
+func TestCameraHandler_RunCameraCommand_PTZMove(t *testing.T) {
+	handler, requests := newCommandTestHandler(t, `{"rspCode":200}`)
+
+	w := runCommand(handler, "camera-123", `{"action":"ptz_move","params":{"operation":"Left","speed":10,"channel":1}}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, *requests, 1)
+	assert.Contains(t, (*requests)[0], `"op":"Left"`)
+	assert.Contains(t, (*requests)[0], `"speed":10`)
+	assert.Contains(t, (*requests)[0], `"channel":1`)
+	assert.NotContains(t, w.Body.String(), `"result"`)
+}
+
+func TestCameraHandler_RunCameraCommand_UnknownAction(t *testing.T) {
+	handler, requests := newCommandTestHandler(t, `{}`)
+
+	w := runCommand(handler, "camera-123", `{"action":"self_destruct"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "UNKNOWN_ACTION")
+	assert.Contains(t, w.Body.String(), `"ptz_move"`, "the supported actions are listed")
+	assert.Empty(t, *requests)
+}
+
+func TestCameraHandler_RunCameraCommand_InvalidParams(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"missing required param", `{"action":"ptz_move","params":{"speed":10}}`},
+		{"unknown param", `{"action":"ptz_stop","params":{"chanel":1}}`},
+		{"negative channel", `{"action":"siren","params":{"channel":-1}}`},
+		{"wrong type", `{"action":"white_led","params":{"enabled":"yes"}}`},
+		{"params for a command without params", `{"action":"reboot","params":{"now":true}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, requests := newCommandTestHandler(t, `{"rspCode":200}`)
+
+			w := runCommand(handler, "camera-123", tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			assert.Contains(t, w.Body.String(), "INVALID_PARAMS")
+			assert.Empty(t, *requests)
+		})
+	}
+}
+
+func TestCameraHandler_RunCameraCommand_CameraNotFound(t *testing.T) {
+	handler, _ := newCommandTestHandler(t, `{}`)
+
+	w := runCommand(handler, "missing", `{"action":"reboot"}`)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// Outcomes of importing one config section
+const (
+	configSectionApplied = "applied"
+	configSectionSkipped = "skipped"
+	configSectionFailed  = "failed"
+)
+
+// configTypeAbilities maps config types to the GetAbility entry a camera
+// must report to support them. Config types not listed are always included.
+var configTypeAbilities = map[string]string{
+	"auto_maint":   "autoMaint",
+	"encoding":     "enc",
+	"ai":           "aiTrack",
+	"audio_alarm":  "supportAudioAlarm",
+	"buzzer_alarm": "supportBuzzer",
+	"ai_alarm":     "supportAiPeople",
+	"recording":    "recCfg",
+	"osd":          "osd",
+	"image":        "image",
+	"isp":          "isp",
+	"mask":         "mask",
+	"ntp":          "ntp",
+	"wifi":         "wifi",
+	"email":        "email",
+	"ftp":          "ftp",
+	"push":         "push",
+}
+
+// configTypesNotBackedUp lists the config types left out of config exports
+// and imports. The time config holds the camera's clock, which restoring an
+// export would set back to when the export was taken.
+var configTypesNotBackedUp = []string{"time"}
+
+// supportsConfigType reports whether a camera supports a config type
+// according to its cached abilities. Cameras whose abilities are unknown are
+// assumed to support every type.
+func supportsConfigType(client *camera.CameraClient, configType string) bool {
+	caps := client.Camera.Capabilities
+	ability, ok := configTypeAbilities[configType]
+	if !ok || len(caps) == 0 {
+		return true
+	}
+	return caps[ability]
+}
+
+// cameraConfigExport is the full configuration of a camera, one section per
+// config type. It is also the body accepted by the config import.
+type cameraConfigExport struct {
+	CameraID   string                 `json:"camera_id"`
+	ExportedAt time.Time              `json:"exported_at"`
+	Channel    int                    `json:"channel"`
+	Sections   map[string]interface{} `json:"sections"`
+	Skipped    []string               `json:"skipped"`          // Types the camera does not support
+	Failed     map[string]string      `json:"failed,omitempty"` // Errors of types that could not be read
+}
+
+// configSectionResult reports the outcome of importing one config section
+type configSectionResult struct {
+	Type   string `json:"type"`
+	Status string `json:"status"` // applied, skipped or failed
+	Error  string `json:"error,omitempty"`
+}
+
+// ExportCameraConfig handles GET /api/v1/cameras/{id}/config/export
+// It reads every config type the camera supports into a single document,
+// except the camera's clock.
+// The channel, alarm_type and ai_type query parameters select the instance of
+// channel-specific types. Types that fail to read are reported in failed
+// instead of failing the export.
+func (h *CameraHandler) ExportCameraConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	sel, ok := parseConfigSelector(w, r)
+	if !ok {
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondNotFound(w, "Camera not found")
+		return
+	}
+
+	export := &cameraConfigExport{
+		CameraID:   cameraID,
+		ExportedAt: time.Now().UTC(),
+		Channel:    sel.channel,
+		Sections:   make(map[string]interface{}),
+		Skipped:    []string{},
+		Failed:     make(map[string]string),
+	}
+	for _, configType := range updatableConfigTypes {
+		if slices.Contains(configTypesNotBackedUp, configType) {
+			continue
+		}
+		if !supportsConfigType(client, configType) {
+			export.Skipped = append(export.Skipped, configType)
+			continue
+		}
+
+		config, err := currentConfigForUpdate(ctx, client, configType, sel)
+		if err != nil {
+			logger.Warn("Failed to export camera config section",
+				zap.Error(err),
+				zap.String("camera_id", cameraID),
+				zap.String("config_type", configType))
+			export.Failed[configType] = err.Error()
+			continue
+		}
+		export.Sections[configType] = config
+	}
+
+	utils.RespondJSON(w, http.StatusOK, export)
+}
+
+// ImportCameraConfig handles POST /api/v1/cameras/{id}/config/import
+// It writes each section of a config export back to the camera, continuing
+// past sections that fail, and reports the outcome of every section. Sections
+// the camera does not support are skipped, as are the time sections of older
+// exports.
+func (h *CameraHandler) ImportCameraConfig(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	var req struct {
+		Sections map[string]json.RawMessage `json:"sections"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+	if len(req.Sections) == 0 {
+		utils.RespondBadRequest(w, "sections must contain at least one config type", nil)
+		return
+	}
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondNotFound(w, "Camera not found")
+		return
+	}
+
+	// Apply sections in a fixed order, unknown types last
+	types := make([]string, 0, len(req.Sections))
+	for _, configType := range updatableConfigTypes {
+		if _, ok := req.Sections[configType]; ok {
+			types = append(types, configType)
+		}
+	}
+	var unknown []string
+	for configType := range req.Sections {
+		if !isUpdatableConfigType(configType) {
+			unknown = append(unknown, configType)
+		}
+	}
+	slices.Sort(unknown)
+
+	results := make([]configSectionResult, 0, len(req.Sections))
+	applied, skipped, failed := 0, 0, 0
+	for _, configType := range types {
+		result := h.importConfigSection(ctx, client, configType, req.Sections[configType])
+		switch result.Status {
+		case configSectionApplied:
+			applied++
+		case configSectionSkipped:
+			skipped++
+		case configSectionFailed:
+			failed++
+		}
+		results = append(results, result)
+	}
+	for _, configType := range unknown {
+		results = append(results, configSectionResult{Type: configType, Status: configSectionFailed, Error: "unsupported config type"})
+		failed++
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id": cameraID,
+		"applied":   applied,
+		"skipped":   skipped,
+		"failed":    failed,
+		"sections":  results,
+	})
+}
+
+// importConfigSection writes one section of a config import to the camera
+// and records it in the config history
+func (h *CameraHandler) importConfigSection(ctx context.Context, client *camera.CameraClient, configType string, section json.RawMessage) configSectionResult {
+	result := configSectionResult{Type: configType}
+	if slices.Contains(configTypesNotBackedUp, configType) {
+		result.Status = configSectionSkipped
+		result.Error = "not restored by config import"
+		return result
+	}
+	if !supportsConfigType(client, configType) {
+		result.Status = configSectionSkipped
+		return result
+	}
+
+	update, err := parseConfigUpdate(configType, bytes.NewReader(section))
+	if err != nil {
+		result.Status = configSectionFailed
+		result.Error = "invalid config: " + err.Error()
+		return result
+	}
+
+	h.recordConfigBaseline(ctx, client, configType, selectorOfUpdate(configType, update))
+
+	err = applyConfigUpdate(ctx, client, configType, update)
+	if err != nil {
+		logger.Warn("Failed to import camera config section",
+			zap.Error(err),
+			zap.String("camera_id", client.Camera.ID),
+			zap.String("config_type", configType))
+		result.Status = configSectionFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	h.cameraService.RecordConfigChange(ctx, client.Camera, configType, update)
+	result.Status = configSectionApplied
+	return result
+}
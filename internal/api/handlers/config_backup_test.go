@@ -0,0 +1,197 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newConfigBackupServer fakes the config API of a camera. Commands listed in
+// failing answer with an API error; every other command succeeds, and the
+// commands received are recorded in order.
+func newConfigBackupServer(t *testing.T, failing ...string) (*httptest.Server, func() []string) {
+	t.Helper()
+	var mu sync.Mutex
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		mu.Lock()
+		received = append(received, cmd)
+		mu.Unlock()
+
+		for _, f := range failing {
+			if cmd == f {
+				w.Write([]byte(`[{"cmd":"` + cmd + `","code":1,"error":{"rspCode":-9,"detail":"not support"}}]`))
+				return
+			}
+		}
+		switch cmd {
+		case "GetDevName":
+			w.Write([]byte(`[{"cmd":"GetDevName","code":0,"value":{"DevName":{"name":"Front Door"}}}]`))
+		case "GetNtp":
+			w.Write([]byte(`[{"cmd":"GetNtp","code":0,"value":{"Ntp":{"enable":1,"server":"pool.ntp.org","port":123,"interval":1440}}}]`))
+		default:
+			if strings.HasPrefix(cmd, "Get") {
+				w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{}}]`))
+				return
+			}
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), received...)
+	}
+}
+
+// newConfigBackupHandler returns a camera handler serving camera-123 from
+// server with the given cached capabilities
+func newConfigBackupHandler(server *httptest.Server, caps models.CameraCapabilities) (*CameraHandler, *MockCameraServiceForConfig) {
+	mockService := new(MockCameraServiceForConfig)
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123", Capabilities: caps},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "camera-999").Return(nil, camera.ErrNotFound)
+	return &CameraHandler{cameraService: mockService}, mockService
+}
+
+func TestSupportsConfigType(t *testing.T) {
+	client := &camera.CameraClient{Camera: &models.Camera{}}
+
+	// Without known abilities every type is attempted
+	assert.True(t, supportsConfigType(client, "wifi"))
+
+	client.Camera.Capabilities = models.CameraCapabilities{"wifi": false, "email": true}
+	assert.False(t, supportsConfigType(client, "wifi"))
+	assert.True(t, supportsConfigType(client, "email"))
+	assert.False(t, supportsConfigType(client, "ftp"), "abilities the camera does not report are unsupported")
+	assert.True(t, supportsConfigType(client, "time"), "types without an ability are always supported")
+}
+
+func TestCameraHandler_ExportCameraConfig(t *testing.T) {
+	server, _ := newConfigBackupServer(t, "GetFtpV20", "GetFtp")
+	caps := models.CameraCapabilities{}
+	for configType, ability := range configTypeAbilities {
+		caps[ability] = configType != "wifi" && configType != "buzzer_alarm"
+	}
+	handler, _ := newConfigBackupHandler(server, caps)
+
+	w := httptest.NewRecorder()
+	handler.ExportCameraConfig(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/config/export", nil, map[string]string{"id": "camera-123"}))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data struct {
+			CameraID string                     `json:"camera_id"`
+			Channel  int                        `json:"channel"`
+			Sections map[string]json.RawMessage `json:"sections"`
+			Skipped  []string                   `json:"skipped"`
+			Failed   map[string]string          `json:"failed"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	export := resp.Data
+
+	// Every supported type is exported, except the one the camera failed to read
+	assert.Equal(t, "camera-123", export.CameraID)
+	assert.Equal(t, []string{"buzzer_alarm", "wifi"}, export.Skipped)
+	assert.Len(t, export.Failed, 1)
+	assert.Contains(t, export.Failed["ftp"], "not support")
+	assert.Len(t, export.Sections, len(updatableConfigTypes)-4)
+	assert.NotContains(t, export.Sections, "ftp")
+	assert.NotContains(t, export.Sections, "time", "the camera clock is not backed up")
+	assert.JSONEq(t, `{"name":"Front Door"}`, string(export.Sections["device_name"]))
+	assert.Contains(t, string(export.Sections["ntp"]), `"server":"pool.ntp.org"`)
+
+	// Invalid channels and unknown cameras are rejected
+	w = httptest.NewRecorder()
+	handler.ExportCameraConfig(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/config/export?channel=x", nil, map[string]string{"id": "camera-123"}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = httptest.NewRecorder()
+	handler.ExportCameraConfig(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-999/config/export", nil, map[string]string{"id": "camera-999"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_ImportCameraConfig(t *testing.T) {
+	server, received := newConfigBackupServer(t, "SetOsd")
+	handler, mockService := newConfigBackupHandler(server, models.CameraCapabilities{"osd": true, "ntp": true, "wifi": false})
+	mockService.On("RecordConfigBaseline", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+	mockService.On("RecordConfigChange", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return()
+
+	body := []byte(`{
+		"camera_id": "camera-123",
+		"sections": {
+			"ntp": {"enable": 1, "server": "time.example.com", "port": 123, "interval": 60},
+			"device_name": {"name": "Back Door"},
+			"osd": {"channel": 0},
+			"wifi": {"ssid": "home"},
+			"time": {"Time": {"year": 2020, "mon": 1, "day": 1}},
+			"system": "not a config",
+			"bogus": {}
+		}
+	}`)
+	w := httptest.NewRecorder()
+	handler.ImportCameraConfig(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/config/import", body, map[string]string{"id": "camera-123"}))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var resp struct {
+		Data struct {
+			Applied  int                   `json:"applied"`
+			Skipped  int                   `json:"skipped"`
+			Failed   int                   `json:"failed"`
+			Sections []configSectionResult `json:"sections"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	// Sections are applied in config type order, past the ones that fail
+	statuses := make(map[string]string)
+	var order []string
+	for _, section := range resp.Data.Sections {
+		statuses[section.Type] = section.Status
+		order = append(order, section.Type)
+	}
+	assert.Equal(t, []string{"time", "device_name", "system", "osd", "ntp", "wifi", "bogus"}, order)
+	assert.Equal(t, map[string]string{
+		"time":        configSectionSkipped,
+		"device_name": configSectionApplied,
+		"system":      configSectionFailed,
+		"osd":         configSectionFailed,
+		"ntp":         configSectionApplied,
+		"wifi":        configSectionSkipped,
+		"bogus":       configSectionFailed,
+	}, statuses)
+	assert.Equal(t, 2, resp.Data.Applied)
+	assert.Equal(t, 2, resp.Data.Skipped)
+	assert.Equal(t, 3, resp.Data.Failed)
+
+	// Clocks of older exports are never written back, only valid, supported
+	// sections reach the camera, and only applied ones are recorded in the
+	// config history
+	assert.Equal(t, []string{"SetDevName", "SetOsd", "SetNtp"}, received())
+	mockService.AssertNumberOfCalls(t, "RecordConfigChange", 2)
+	mockService.AssertNumberOfCalls(t, "RecordConfigBaseline", 3)
+	mockService.AssertCalled(t, "RecordConfigChange", mock.Anything, mock.Anything, "device_name", map[string]string{"name": "Back Door"})
+
+	// Imports without sections are rejected
+	w = httptest.NewRecorder()
+	handler.ImportCameraConfig(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/config/import", []byte(`{"sections":{}}`), map[string]string{"id": "camera-123"}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"archive/zip"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/pkg/utils"
 )
@@ -16,15 +24,20 @@ import (
 // EventServiceInterface defines the interface for event service operations
 type EventServiceInterface interface {
 	GetEvent(ctx context.Context, id string) (*models.Event, error)
-	ListEvents(ctx context.Context, limit, offset int) ([]*models.Event, error)
-	CountEvents(ctx context.Context) (int, error)
+	GetEvents(ctx context.Context, ids []string) ([]*models.Event, []models.BulkFailure)
+	SearchEvents(ctx context.Context, req *models.EventSearchRequest) ([]*models.Event, error)
+	CountSearchEvents(ctx context.Context, req *models.EventSearchRequest) (int, error)
 	AcknowledgeEvent(ctx context.Context, id string) error
+	AcknowledgeEvents(ctx context.Context, ids []string) *models.BulkResult
+	AcknowledgeCameraEvents(ctx context.Context, cameraID string) (int64, error)
+	ListEventActivity(ctx context.Context, cameraID string, startTime, endTime time.Time, gap time.Duration) ([]*models.EventActivity, bool, error)
 }
 
 // EventHandler handles event-related HTTP requests
 type EventHandler struct {
-	eventService  EventServiceInterface
-	cameraService CameraServiceInterface
+	eventService       EventServiceInterface
+	cameraService      CameraServiceInterface
+	defaultMinSeverity models.EventSeverity
 }
 
 // NewEventHandler creates a new event handler
@@ -35,34 +48,178 @@ func NewEventHandler(eventService EventServiceInterface, cameraService CameraSer
 	}
 }
 
-// ListEvents handles GET /api/v1/events
+// SetDefaultMinSeverity sets the minimum severity listed when a request does
+// not specify min_severity. Empty or info lists all events.
+func (h *EventHandler) SetDefaultMinSeverity(severity models.EventSeverity) {
+	h.defaultMinSeverity = severity
+}
+
+// ListEvents handles GET /api/v1/events. Events can be filtered with
+// ?camera_id=, ?type=, ?acknowledged=, and ?severity= (exact) or
+// ?min_severity=, and listed oldest first with ?order=asc.
 func (h *EventHandler) ListEvents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	query := r.URL.Query()
 
 	// Parse query parameters
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
 	if limit <= 0 {
 		limit = 50
 	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	search := &models.EventSearchRequest{Limit: limit, Offset: offset}
+
+	if value := query.Get("camera_id"); value != "" {
+		search.CameraID = &value
+	}
+
+	if value := query.Get("type"); value != "" {
+		eventType, err := models.ParseEventType(value)
+		if err != nil {
+			utils.RespondBadRequest(w, err.Error(), nil)
+			return
+		}
+		search.Type = &eventType
+	}
 
-	events, err := h.eventService.ListEvents(ctx, limit, offset)
+	if value := query.Get("acknowledged"); value != "" {
+		acknowledged, err := strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondBadRequest(w, "acknowledged must be true or false", nil)
+			return
+		}
+		search.Acknowledged = &acknowledged
+	}
+
+	sortOrder, err := models.ParseSortOrder(query.Get("sort"), query.Get("order"), models.EventSortFields, models.DefaultEventSort)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
+	search.Sort = sortOrder
+
+	response := map[string]interface{}{
+		"limit":  limit,
+		"offset": offset,
+	}
+
+	if value := query.Get("severity"); value != "" {
+		if query.Get("min_severity") != "" {
+			utils.RespondBadRequest(w, "severity and min_severity cannot be combined", nil)
+			return
+		}
+		severity, err := models.ParseEventSeverity(value)
+		if err != nil {
+			utils.RespondBadRequest(w, err.Error(), nil)
+			return
+		}
+		search.Severities = []models.EventSeverity{severity}
+		response["severity"] = severity
+	} else {
+		minSeverity := h.defaultMinSeverity
+		if value := query.Get("min_severity"); value != "" {
+			severity, err := models.ParseEventSeverity(value)
+			if err != nil {
+				utils.RespondBadRequest(w, err.Error(), nil)
+				return
+			}
+			minSeverity = severity
+		}
+		if minSeverity == "" {
+			minSeverity = models.SeverityInfo
+		}
+		if minSeverity != models.SeverityInfo {
+			search.Severities = models.SeveritiesAtLeast(minSeverity)
+		}
+		response["min_severity"] = minSeverity
+	}
+
+	events, err := h.eventService.SearchEvents(ctx, search)
 	if err != nil {
 		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to list events", err)
 		return
 	}
 
-	total, err := h.eventService.CountEvents(ctx)
+	total, err := h.eventService.CountSearchEvents(ctx, search)
 	if err != nil {
 		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to count events", err)
 		return
 	}
 
+	response["events"] = events
+	response["total"] = total
+	utils.RespondJSON(w, http.StatusOK, response)
+}
+
+// ListEventTypes handles GET /api/v1/events/types
+// It returns the event types the server emits with their descriptions and
+// default severities, so clients can build filters without hardcoding them.
+func (h *EventHandler) ListEventTypes(w http.ResponseWriter, r *http.Request) {
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"types":      models.EventTypes,
+		"severities": models.SeveritiesAtLeast(models.SeverityInfo),
+	})
+}
+
+// GetEventActivity handles GET /api/v1/events/activity
+func (h *EventHandler) GetEventActivity(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	// Default to the last 24 hours
+	endTime := time.Now()
+	startTime := endTime.Add(-24 * time.Hour)
+
+	if value := query.Get("end_time"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid time format. Use RFC3339 format (e.g., 2025-10-27T10:00:00Z)", nil)
+			return
+		}
+		endTime = parsed
+		startTime = endTime.Add(-24 * time.Hour)
+	}
+	if value := query.Get("start_time"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid time format. Use RFC3339 format (e.g., 2025-10-27T10:00:00Z)", nil)
+			return
+		}
+		startTime = parsed
+	}
+	if !startTime.Before(endTime) {
+		utils.RespondBadRequest(w, "start_time must be before end_time", nil)
+		return
+	}
+
+	gap := service.DefaultActivityGap
+	if value := query.Get("gap"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		if err != nil || parsed <= 0 {
+			utils.RespondBadRequest(w, "Invalid gap. Use a positive duration (e.g., 5m)", map[string]interface{}{"gap": value})
+			return
+		}
+		gap = parsed
+	}
+
+	cameraID := query.Get("camera_id")
+	activity, truncated, err := h.eventService.ListEventActivity(ctx, cameraID, startTime, endTime, gap)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to list event activity", err)
+		return
+	}
+
 	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
-		"events": events,
-		"total":  total,
-		"limit":  limit,
-		"offset": offset,
+		"activity":   activity,
+		"camera_id":  cameraID,
+		"start_time": startTime,
+		"end_time":   endTime,
+		"gap":        gap.String(),
+		"truncated":  truncated,
 	})
 }
 
@@ -95,6 +252,40 @@ func (h *EventHandler) AcknowledgeEvent(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// AcknowledgeEvents handles POST /api/v1/events/acknowledge, acknowledging
+// the events listed in the body. Events that are unknown or fail are
+// reported in the result without failing the others.
+func (h *EventHandler) AcknowledgeEvents(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeBulkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, h.eventService.AcknowledgeEvents(r.Context(), ids))
+}
+
+// AcknowledgeCameraEvents handles POST /api/v1/cameras/{id}/events/acknowledge-all
+func (h *EventHandler) AcknowledgeCameraEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	if _, err := h.cameraService.GetCamera(ctx, cameraID); err != nil {
+		utils.RespondNotFound(w, "Camera not found")
+		return
+	}
+
+	acknowledged, err := h.eventService.AcknowledgeCameraEvents(ctx, cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to acknowledge events", err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id":    cameraID,
+		"acknowledged": acknowledged,
+	})
+}
+
 // GetEventSnapshot handles GET /api/v1/events/{id}/snapshot
 func (h *EventHandler) GetEventSnapshot(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -174,3 +365,160 @@ func (h *EventHandler) GetEventSnapshot(w http.ResponseWriter, r *http.Request)
 	w.WriteHeader(http.StatusOK)
 	w.Write(data)
 }
+
+// ExportEventSnapshots handles POST /api/v1/events/snapshots/export,
+// streaming a ZIP of the stored snapshots of the events selected by ID or by
+// filter. Events without a stored snapshot are left out and listed in the
+// archive's manifest.json with the reason.
+func (h *EventHandler) ExportEventSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req models.SnapshotExportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	var events []*models.Event
+	manifest := models.SnapshotExportManifest{
+		ExportedAt: time.Now().UTC(),
+		Snapshots:  []models.ExportedSnapshot{},
+		Skipped:    []models.BulkFailure{},
+	}
+
+	switch {
+	case len(req.IDs) > 0 && req.Filter != nil:
+		utils.RespondBadRequest(w, "ids and filter cannot be combined", nil)
+		return
+	case len(req.IDs) > models.MaxBulkIDs:
+		utils.RespondBadRequest(w, fmt.Sprintf("ids must not list more than %d IDs", models.MaxBulkIDs), nil)
+		return
+	case len(req.IDs) > 0:
+		var failed []models.BulkFailure
+		events, failed = h.eventService.GetEvents(ctx, req.IDs)
+		manifest.Skipped = append(manifest.Skipped, failed...)
+	case req.Filter != nil:
+		search, err := snapshotExportSearch(req.Filter)
+		if err != nil {
+			utils.RespondBadRequest(w, err.Error(), nil)
+			return
+		}
+		events, err = h.eventService.SearchEvents(ctx, search)
+		if err != nil {
+			utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to list events", err)
+			return
+		}
+	default:
+		utils.RespondBadRequest(w, "ids or filter is required", nil)
+		return
+	}
+
+	// Large exports may outlast the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition",
+		fmt.Sprintf(`attachment; filename="event-snapshots-%s.zip"`, manifest.ExportedAt.Format("20060102T150405Z")))
+	w.WriteHeader(http.StatusOK)
+
+	// The response is committed from here on, so failures can only be
+	// recorded in the manifest or logged
+	archive := zip.NewWriter(w)
+	for _, event := range events {
+		file, reason, err := addSnapshotToArchive(archive, event)
+		if err != nil {
+			logger.Error("Failed to export event snapshot", zap.String("event_id", event.ID), zap.Error(err))
+			if reason == "" {
+				return // the archive itself could not be written, the client is likely gone
+			}
+		}
+		if reason != "" {
+			manifest.Skipped = append(manifest.Skipped, models.BulkFailure{ID: event.ID, Reason: reason})
+			continue
+		}
+		manifest.Snapshots = append(manifest.Snapshots, models.ExportedSnapshot{
+			File:       file,
+			EventID:    event.ID,
+			CameraID:   event.CameraID,
+			CameraName: event.CameraName,
+			Type:       event.Type,
+			Timestamp:  event.Timestamp,
+		})
+	}
+
+	entry, err := archive.Create("manifest.json")
+	if err == nil {
+		encoder := json.NewEncoder(entry)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(manifest)
+	}
+	if err == nil {
+		err = archive.Close()
+	}
+	if err != nil {
+		logger.Error("Failed to finish event snapshot export", zap.Error(err))
+	}
+}
+
+// snapshotExportSearch builds the event search of a snapshot export filter
+func snapshotExportSearch(filter *models.SnapshotExportFilter) (*models.EventSearchRequest, error) {
+	limit := filter.Limit
+	if limit <= 0 || limit > models.MaxBulkIDs {
+		limit = models.MaxBulkIDs
+	}
+
+	search := &models.EventSearchRequest{
+		Acknowledged: filter.Acknowledged,
+		Limit:        limit,
+	}
+	if filter.CameraID != "" {
+		search.CameraID = &filter.CameraID
+	}
+	if filter.Type != "" {
+		eventType, err := models.ParseEventType(filter.Type)
+		if err != nil {
+			return nil, err
+		}
+		search.Type = &eventType
+	}
+	return search, nil
+}
+
+// addSnapshotToArchive copies the stored snapshot of an event into archive
+// and returns its name there. Events whose snapshot cannot be exported return
+// the reason they are skipped; an error without a reason means the archive
+// could not be written.
+func addSnapshotToArchive(archive *zip.Writer, event *models.Event) (string, string, error) {
+	if event.SnapshotPath == "" {
+		return "", models.SnapshotSkipNoSnapshot, nil
+	}
+
+	file, err := os.Open(event.SnapshotPath)
+	if os.IsNotExist(err) {
+		return "", models.SnapshotSkipMissing, nil
+	}
+	if err != nil {
+		return "", models.BulkReasonError, err
+	}
+	defer file.Close()
+
+	ext := filepath.Ext(event.SnapshotPath)
+	if ext == "" {
+		ext = ".jpg"
+	}
+	name := fmt.Sprintf("%s_%s%s", event.Timestamp.UTC().Format("20060102T150405Z"), event.ID, ext)
+
+	// Images are already compressed, so they are stored as they are
+	entry, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Store,
+		Modified: event.Timestamp,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := io.Copy(entry, file); err != nil {
+		return "", "", err
+	}
+	return name, "", nil
+}
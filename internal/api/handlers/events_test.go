@@ -1,16 +1,24 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
@@ -29,24 +37,49 @@ func (m *MockEventService) GetEvent(ctx context.Context, id string) (*models.Eve
 	return args.Get(0).(*models.Event), args.Error(1)
 }
 
-func (m *MockEventService) ListEvents(ctx context.Context, limit, offset int) ([]*models.Event, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *MockEventService) GetEvents(ctx context.Context, ids []string) ([]*models.Event, []models.BulkFailure) {
+	args := m.Called(ctx, ids)
+	events, _ := args.Get(0).([]*models.Event)
+	failed, _ := args.Get(1).([]models.BulkFailure)
+	return events, failed
+}
+
+func (m *MockEventService) SearchEvents(ctx context.Context, req *models.EventSearchRequest) ([]*models.Event, error) {
+	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).([]*models.Event), args.Error(1)
 }
 
-func (m *MockEventService) CountEvents(ctx context.Context) (int, error) {
-	args := m.Called(ctx)
+func (m *MockEventService) CountSearchEvents(ctx context.Context, req *models.EventSearchRequest) (int, error) {
+	args := m.Called(ctx, req)
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockEventService) ListEventActivity(ctx context.Context, cameraID string, startTime, endTime time.Time, gap time.Duration) ([]*models.EventActivity, bool, error) {
+	args := m.Called(ctx, cameraID, startTime, endTime, gap)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]*models.EventActivity), args.Bool(1), args.Error(2)
+}
+
 func (m *MockEventService) AcknowledgeEvent(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockEventService) AcknowledgeEvents(ctx context.Context, ids []string) *models.BulkResult {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(*models.BulkResult)
+}
+
+func (m *MockEventService) AcknowledgeCameraEvents(ctx context.Context, cameraID string) (int64, error) {
+	args := m.Called(ctx, cameraID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
 // MockCameraServiceForEvents is a mock implementation of CameraServiceInterface for event tests
 type MockCameraServiceForEvents struct {
 	mock.Mock
@@ -73,6 +106,14 @@ func (m *MockCameraServiceForEvents) ListCameras(ctx context.Context) ([]*models
 	return args.Get(0).([]*models.Camera), args.Error(1)
 }
 
+func (m *MockCameraServiceForEvents) ListCamerasByGroup(ctx context.Context, groupID string) ([]*models.Camera, error) {
+	args := m.Called(ctx, groupID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Camera), args.Error(1)
+}
+
 func (m *MockCameraServiceForEvents) UpdateCamera(ctx context.Context, camera *models.Camera) error {
 	args := m.Called(ctx, camera)
 	return args.Error(0)
@@ -91,6 +132,14 @@ func (m *MockCameraServiceForEvents) GetCameraStatus(ctx context.Context, id str
 	return args.Get(0).(*models.CameraStatus), args.Error(1)
 }
 
+func (m *MockCameraServiceForEvents) GetDeviceInfo(ctx context.Context, id string) (*models.CameraDeviceInfo, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraDeviceInfo), args.Error(1)
+}
+
 func (m *MockCameraServiceForEvents) GetCameraClient(id string) (*camera.CameraClient, error) {
 	args := m.Called(id)
 	if args.Get(0) == nil {
@@ -112,6 +161,91 @@ func (m *MockCameraServiceForEvents) CountCameraEvents(ctx context.Context, came
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockCameraServiceForEvents) RecordConfigBaseline(ctx context.Context, cam *models.Camera, configType string, current func() (interface{}, error)) {
+	m.Called(ctx, cam, configType, current)
+}
+
+func (m *MockCameraServiceForEvents) RecordConfigChange(ctx context.Context, cam *models.Camera, configType string, changes interface{}) {
+	m.Called(ctx, cam, configType, changes)
+}
+
+func (m *MockCameraServiceForEvents) GetConfigHistory(ctx context.Context, cameraID, configType string, limit, offset int) ([]*models.CameraConfigVersion, error) {
+	args := m.Called(ctx, cameraID, configType, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CameraConfigVersion), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) GetConfigVersion(ctx context.Context, cameraID, configType string, version int) (*models.CameraConfigVersion, error) {
+	args := m.Called(ctx, cameraID, configType, version)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraConfigVersion), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) PurgeCameraHistory(ctx context.Context, cameraID string) (*models.CameraHistoryPurge, error) {
+	args := m.Called(ctx, cameraID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraHistoryPurge), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) ProbeCamera(ctx context.Context, id string, skipVerify *bool) (*camera.ProbeResult, error) {
+	args := m.Called(ctx, id, skipVerify)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.ProbeResult), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) ProbeNewCamera(ctx context.Context, cam *models.Camera) *camera.ProbeResult {
+	args := m.Called(ctx, cam)
+	return args.Get(0).(*camera.ProbeResult)
+}
+
+func (m *MockCameraServiceForEvents) TestConnection(ctx context.Context, cam *models.Camera) (*camera.ConnectionTestResult, error) {
+	args := m.Called(ctx, cam)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.ConnectionTestResult), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) GetSnapshot(ctx context.Context, id string, channel int, fresh bool) ([]byte, bool, error) {
+	args := m.Called(ctx, id, channel, fresh)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]byte), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCameraServiceForEvents) StreamSnapshot(ctx context.Context, id string, channel int, fresh bool) (*camera.SnapshotStream, bool, error) {
+	args := m.Called(ctx, id, channel, fresh)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*camera.SnapshotStream), args.Bool(1), args.Error(2)
+}
+
+func (m *MockCameraServiceForEvents) RebootAndWait(ctx context.Context, id string, timeout time.Duration) (*camera.RebootStatus, error) {
+	args := m.Called(ctx, id, timeout)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.RebootStatus), args.Error(1)
+}
+
+func (m *MockCameraServiceForEvents) GetRebootStatus(id string) (*camera.RebootStatus, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*camera.RebootStatus), args.Error(1)
+}
+
 func TestNewEventHandler(t *testing.T) {
 	mockEventService := new(MockEventService)
 	mockCameraService := new(MockCameraServiceForEvents)
@@ -122,6 +256,17 @@ func TestNewEventHandler(t *testing.T) {
 	assert.Equal(t, mockCameraService, handler.cameraService)
 }
 
+// searchMatching matches event searches with the given filters and the
+// default pagination
+func searchMatching(expected models.EventSearchRequest) interface{} {
+	if expected.Limit == 0 {
+		expected.Limit = 50
+	}
+	return mock.MatchedBy(func(req *models.EventSearchRequest) bool {
+		return assert.ObjectsAreEqual(expected, *req)
+	})
+}
+
 func TestEventHandler_ListEvents(t *testing.T) {
 	mockEventService := new(MockEventService)
 	mockCameraService := new(MockCameraServiceForEvents)
@@ -132,8 +277,8 @@ func TestEventHandler_ListEvents(t *testing.T) {
 		{ID: "evt-2", CameraID: "cam-2", Type: models.EventAIPerson},
 	}
 
-	mockEventService.On("ListEvents", mock.Anything, 50, 0).Return(events, nil)
-	mockEventService.On("CountEvents", mock.Anything).Return(2, nil)
+	mockEventService.On("SearchEvents", mock.Anything, searchMatching(models.EventSearchRequest{})).Return(events, nil)
+	mockEventService.On("CountSearchEvents", mock.Anything, searchMatching(models.EventSearchRequest{})).Return(2, nil)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
 	w := httptest.NewRecorder()
@@ -146,6 +291,137 @@ func TestEventHandler_ListEvents(t *testing.T) {
 	mockEventService.AssertExpectations(t)
 }
 
+func TestEventHandler_ListEvents_DefaultMinSeverity(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+	handler.SetDefaultMinSeverity(models.SeverityWarning)
+
+	events := []*models.Event{
+		{ID: "evt-1", CameraID: "cam-1", Type: models.EventCameraOffline, Severity: models.SeverityWarning},
+	}
+	warningSearch := searchMatching(models.EventSearchRequest{
+		Severities: []models.EventSeverity{models.SeverityWarning, models.SeverityCritical},
+	})
+	mockEventService.On("SearchEvents", mock.Anything, warningSearch).Return(events, nil)
+	mockEventService.On("CountSearchEvents", mock.Anything, warningSearch).Return(1, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	w := httptest.NewRecorder()
+	handler.ListEvents(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"min_severity":"warning"`)
+	mockEventService.AssertExpectations(t)
+}
+
+func TestEventHandler_ListEvents_MinSeverityOverride(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+	handler.SetDefaultMinSeverity(models.SeverityWarning)
+
+	// Requesting info lists every event despite the default
+	allSearch := searchMatching(models.EventSearchRequest{})
+	mockEventService.On("SearchEvents", mock.Anything, allSearch).Return([]*models.Event{{ID: "evt-1"}}, nil).Once()
+	mockEventService.On("CountSearchEvents", mock.Anything, allSearch).Return(1, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events?min_severity=info", nil)
+	w := httptest.NewRecorder()
+	handler.ListEvents(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Requesting critical narrows further
+	criticalSearch := searchMatching(models.EventSearchRequest{
+		Severities: []models.EventSeverity{models.SeverityCritical},
+	})
+	mockEventService.On("SearchEvents", mock.Anything, criticalSearch).Return([]*models.Event{}, nil).Once()
+	mockEventService.On("CountSearchEvents", mock.Anything, criticalSearch).Return(0, nil).Once()
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/events?min_severity=critical", nil)
+	w = httptest.NewRecorder()
+	handler.ListEvents(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/events?min_severity=loud", nil)
+	w = httptest.NewRecorder()
+	handler.ListEvents(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockEventService.AssertExpectations(t)
+}
+
+func TestEventHandler_ListEvents_Sort(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+
+	search := searchMatching(models.EventSearchRequest{
+		Sort:  models.SortOrder{Field: "severity", Descending: true},
+		Limit: 50,
+	})
+	mockEventService.On("SearchEvents", mock.Anything, search).Return([]*models.Event{{ID: "evt-1"}}, nil)
+	mockEventService.On("CountSearchEvents", mock.Anything, search).Return(1, nil)
+
+	w := httptest.NewRecorder()
+	handler.ListEvents(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?sort=severity&order=desc", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockEventService.AssertExpectations(t)
+
+	// Columns outside the allowlist are rejected before reaching the database
+	w = httptest.NewRecorder()
+	handler.ListEvents(w, httptest.NewRequest(http.MethodGet, "/api/v1/events?sort=metadata", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid sort field")
+}
+
+func TestEventHandler_ListEvents_Filters(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+	handler.SetDefaultMinSeverity(models.SeverityCritical)
+
+	cameraID := "cam-1"
+	eventType := models.EventAIPerson
+	acknowledged := false
+	search := searchMatching(models.EventSearchRequest{
+		CameraID:     &cameraID,
+		Type:         &eventType,
+		Severities:   []models.EventSeverity{models.SeverityWarning},
+		Acknowledged: &acknowledged,
+		Sort:         models.SortOrder{Field: "timestamp"},
+		Limit:        10,
+		Offset:       20,
+	})
+	mockEventService.On("SearchEvents", mock.Anything, search).Return([]*models.Event{{ID: "evt-1"}}, nil)
+	mockEventService.On("CountSearchEvents", mock.Anything, search).Return(21, nil)
+
+	// An exact severity replaces the default minimum severity
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/events?camera_id=cam-1&type=ai_person&severity=warning&acknowledged=false&order=asc&limit=10&offset=20", nil)
+	w := httptest.NewRecorder()
+	handler.ListEvents(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"severity":"warning"`)
+	assert.Contains(t, w.Body.String(), `"total":21`)
+	assert.NotContains(t, w.Body.String(), "min_severity")
+	mockEventService.AssertExpectations(t)
+}
+
+func TestEventHandler_ListEvents_InvalidFilters(t *testing.T) {
+	handler := NewEventHandler(new(MockEventService), new(MockCameraServiceForEvents))
+
+	for _, query := range []string{
+		"type=explosion",
+		"severity=loud",
+		"severity=warning&min_severity=info",
+		"acknowledged=maybe",
+		"order=sideways",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/events?"+query, nil)
+		w := httptest.NewRecorder()
+		handler.ListEvents(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 func TestEventHandler_GetEvent(t *testing.T) {
 	mockEventService := new(MockEventService)
 	mockCameraService := new(MockCameraServiceForEvents)
@@ -194,6 +470,74 @@ func TestEventHandler_AcknowledgeEvent(t *testing.T) {
 	mockEventService.AssertExpectations(t)
 }
 
+func TestEventHandler_AcknowledgeEvents(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+
+	ids := []string{"11111111-1111-1111-1111-111111111111", "22222222-2222-2222-2222-222222222222"}
+	result := models.NewBulkResult()
+	result.Succeed(ids[0])
+	result.Fail(ids[1], models.BulkReasonNotFound)
+	mockEventService.On("AcknowledgeEvents", mock.Anything, ids).Return(result)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/events/acknowledge",
+		strings.NewReader(`{"ids": ["`+ids[0]+`", "`+ids[1]+`"]}`))
+	w := httptest.NewRecorder()
+	handler.AcknowledgeEvents(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.JSONEq(t, `{"succeeded": ["`+ids[0]+`"], "failed": [{"id": "`+ids[1]+`", "reason": "not_found"}]}`,
+		string(response.Data))
+	mockEventService.AssertExpectations(t)
+
+	// An empty batch is rejected without calling the service
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/events/acknowledge", strings.NewReader(`{"ids": []}`))
+	w = httptest.NewRecorder()
+	handler.AcknowledgeEvents(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockEventService.AssertNumberOfCalls(t, "AcknowledgeEvents", 1)
+}
+
+func TestEventHandler_AcknowledgeCameraEvents(t *testing.T) {
+	mockEventService := new(MockEventService)
+	mockCameraService := new(MockCameraServiceForEvents)
+	handler := NewEventHandler(mockEventService, mockCameraService)
+
+	mockCameraService.On("GetCamera", mock.Anything, "cam-1").Return(&models.Camera{ID: "cam-1"}, nil)
+	mockCameraService.On("GetCamera", mock.Anything, "missing").Return(nil, errors.New("camera not found"))
+	mockEventService.On("AcknowledgeCameraEvents", mock.Anything, "cam-1").Return(int64(7), nil)
+
+	tests := []struct {
+		name       string
+		cameraID   string
+		wantStatus int
+	}{
+		{"acknowledges events", "cam-1", http.StatusOK},
+		{"unknown camera", "missing", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/"+tt.cameraID+"/events/acknowledge-all", nil)
+			w := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", tt.cameraID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.AcknowledgeCameraEvents(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	mockEventService.AssertNumberOfCalls(t, "AcknowledgeCameraEvents", 1)
+}
+
 func TestEventHandler_GetEventSnapshot_WithStoredSnapshot(t *testing.T) {
 	mockEventService := new(MockEventService)
 	mockCameraService := new(MockCameraServiceForEvents)
@@ -322,3 +666,201 @@ func TestEventHandler_GetEventSnapshot_SnapshotFileNotFound(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Snapshot file not found")
 	mockEventService.AssertExpectations(t)
 }
+
+func TestEventHandler_GetEventActivity(t *testing.T) {
+	mockService := new(MockEventService)
+	handler := NewEventHandler(mockService, nil)
+
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Hour)
+	activity := []*models.EventActivity{
+		{CameraID: "cam-1", Type: models.EventMotionDetected, FirstSeen: start, LastSeen: start.Add(10 * time.Minute), Count: 42},
+	}
+	mockService.On("ListEventActivity", mock.Anything, "cam-1", start, end, 2*time.Minute).Return(activity, true, nil)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/events/activity?camera_id=cam-1&start_time=2025-10-27T10:00:00Z&end_time=2025-10-27T12:00:00Z&gap=2m", nil)
+	w := httptest.NewRecorder()
+	handler.GetEventActivity(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"count":42`)
+	assert.Contains(t, w.Body.String(), `"gap":"2m0s"`)
+	assert.Contains(t, w.Body.String(), `"truncated":true`)
+	mockService.AssertExpectations(t)
+}
+
+func TestEventHandler_GetEventActivity_InvalidParams(t *testing.T) {
+	handler := NewEventHandler(new(MockEventService), nil)
+
+	for _, target := range []string{
+		"/api/v1/events/activity?gap=soon",
+		"/api/v1/events/activity?gap=-1m",
+		"/api/v1/events/activity?start_time=yesterday",
+		"/api/v1/events/activity?start_time=2025-10-27T12:00:00Z&end_time=2025-10-27T10:00:00Z",
+	} {
+		w := httptest.NewRecorder()
+		handler.GetEventActivity(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
+
+func TestEventHandler_ListEventTypes(t *testing.T) {
+	handler := NewEventHandler(new(MockEventService), nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events/types", nil)
+	w := httptest.NewRecorder()
+	handler.ListEventTypes(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data struct {
+			Types      []models.EventTypeInfo `json:"types"`
+			Severities []models.EventSeverity `json:"severities"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	allTypes := []models.EventType{
+		models.EventMotionDetected,
+		models.EventAIPerson,
+		models.EventAIVehicle,
+		models.EventAIPet,
+		models.EventAudioAlarm,
+		models.EventRecordingStart,
+		models.EventRecordingStop,
+		models.EventCameraOnline,
+		models.EventCameraOffline,
+		models.EventConfigChanged,
+		models.EventStorm,
+	}
+	listed := make([]models.EventType, 0, len(response.Data.Types))
+	for _, info := range response.Data.Types {
+		listed = append(listed, info.Type)
+		assert.NotEmpty(t, info.Description, "type %s has no description", info.Type)
+		_, err := models.ParseEventSeverity(string(info.DefaultSeverity))
+		assert.NoError(t, err, "type %s has an invalid default severity", info.Type)
+	}
+	assert.ElementsMatch(t, allTypes, listed)
+	assert.Equal(t, []models.EventSeverity{models.SeverityInfo, models.SeverityWarning, models.SeverityCritical}, response.Data.Severities)
+}
+
+// readExportArchive returns the files of a snapshot export and its manifest
+func readExportArchive(t *testing.T, body []byte) (map[string][]byte, models.SnapshotExportManifest) {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	files := make(map[string][]byte)
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		require.NoError(t, err)
+		files[f.Name] = data
+	}
+
+	var manifest models.SnapshotExportManifest
+	require.Contains(t, files, "manifest.json")
+	require.NoError(t, json.Unmarshal(files["manifest.json"], &manifest))
+	return files, manifest
+}
+
+func TestEventHandler_ExportEventSnapshots(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+
+	tmpDir := t.TempDir()
+	storedPath := filepath.Join(tmpDir, "front.jpg")
+	require.NoError(t, os.WriteFile(storedPath, []byte("front jpeg"), 0644))
+	pngPath := filepath.Join(tmpDir, "back.png")
+	require.NoError(t, os.WriteFile(pngPath, []byte("back png"), 0644))
+
+	timestamp := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	events := []*models.Event{
+		{ID: "evt-1", CameraID: "cam-1", CameraName: "Front", Type: models.EventAIPerson, Timestamp: timestamp, SnapshotPath: storedPath},
+		{ID: "evt-2", CameraID: "cam-1", CameraName: "Front", Type: models.EventMotionDetected, Timestamp: timestamp},
+		{ID: "evt-3", CameraID: "cam-2", CameraName: "Back", Type: models.EventAIVehicle, Timestamp: timestamp.Add(time.Second), SnapshotPath: pngPath},
+		{ID: "evt-4", CameraID: "cam-2", CameraName: "Back", Type: models.EventAIVehicle, Timestamp: timestamp, SnapshotPath: filepath.Join(tmpDir, "deleted.jpg")},
+	}
+	ids := []string{"evt-1", "evt-2", "evt-3", "evt-4", "evt-5"}
+	mockEventService.On("GetEvents", mock.Anything, ids).
+		Return(events, []models.BulkFailure{{ID: "evt-5", Reason: models.BulkReasonNotFound}})
+
+	body, _ := json.Marshal(models.SnapshotExportRequest{IDs: ids})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/events/snapshots/export", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ExportEventSnapshots(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	files, manifest := readExportArchive(t, w.Body.Bytes())
+	assert.Len(t, files, 3)
+	assert.Equal(t, []byte("front jpeg"), files["20261016T120000Z_evt-1.jpg"])
+	assert.Equal(t, []byte("back png"), files["20261016T120001Z_evt-3.png"])
+
+	require.Len(t, manifest.Snapshots, 2)
+	assert.Equal(t, models.ExportedSnapshot{
+		File:       "20261016T120000Z_evt-1.jpg",
+		EventID:    "evt-1",
+		CameraID:   "cam-1",
+		CameraName: "Front",
+		Type:       models.EventAIPerson,
+		Timestamp:  timestamp,
+	}, manifest.Snapshots[0])
+	assert.Equal(t, "evt-3", manifest.Snapshots[1].EventID)
+	assert.Equal(t, []models.BulkFailure{
+		{ID: "evt-5", Reason: models.BulkReasonNotFound},
+		{ID: "evt-2", Reason: models.SnapshotSkipNoSnapshot},
+		{ID: "evt-4", Reason: models.SnapshotSkipMissing},
+	}, manifest.Skipped)
+	mockEventService.AssertExpectations(t)
+}
+
+func TestEventHandler_ExportEventSnapshots_Filter(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+
+	cameraID := "cam-1"
+	eventType := models.EventAIPerson
+	mockEventService.On("SearchEvents", mock.Anything, &models.EventSearchRequest{
+		CameraID: &cameraID,
+		Type:     &eventType,
+		Limit:    models.MaxBulkIDs,
+	}).Return([]*models.Event{{ID: "evt-1", CameraID: cameraID, Type: eventType}}, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/events/snapshots/export",
+		strings.NewReader(`{"filter": {"camera_id": "cam-1", "type": "ai_person", "limit": 100000}}`))
+	w := httptest.NewRecorder()
+	handler.ExportEventSnapshots(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	files, manifest := readExportArchive(t, w.Body.Bytes())
+	assert.Len(t, files, 1)
+	assert.Empty(t, manifest.Snapshots)
+	assert.Equal(t, []models.BulkFailure{{ID: "evt-1", Reason: models.SnapshotSkipNoSnapshot}}, manifest.Skipped)
+	mockEventService.AssertExpectations(t)
+}
+
+func TestEventHandler_ExportEventSnapshots_InvalidRequest(t *testing.T) {
+	mockEventService := new(MockEventService)
+	handler := NewEventHandler(mockEventService, new(MockCameraServiceForEvents))
+
+	for _, body := range []string{
+		`not json`,
+		`{}`,
+		`{"ids": ["evt-1"], "filter": {}}`,
+		`{"filter": {"type": "bogus"}}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/events/snapshots/export", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.ExportEventSnapshots(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, body)
+	}
+	mockEventService.AssertNotCalled(t, "GetEvents", mock.Anything, mock.Anything)
+	mockEventService.AssertNotCalled(t, "SearchEvents", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,294 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+const (
+	// DefaultFirmwareUploadTimeout is how long a firmware upload may go
+	// without receiving data before it is aborted
+	DefaultFirmwareUploadTimeout = 30 * time.Second
+
+	// firmwareTransferTimeout bounds sending uploaded firmware to the camera,
+	// which the upload route does not limit with the request timeout
+	firmwareTransferTimeout = 5 * time.Minute
+
+	// maxFirmwareSize caps firmware uploads, well above Reolink firmware images
+	maxFirmwareSize = 256 << 20
+
+	// defaultFirmwareFileName names uploaded firmware sent without a file name
+	defaultFirmwareFileName = "firmware.pak"
+)
+
+// errFirmwareMissing is returned for firmware uploads without a file
+var errFirmwareMissing = errors.New("a firmware file is required")
+
+// SetFirmwareUploadTimeout sets how long a firmware upload may go without
+// receiving data before it is aborted, independently of the server's read
+// timeout. Zero or negative uses DefaultFirmwareUploadTimeout.
+func (h *CameraHandler) SetFirmwareUploadTimeout(timeout time.Duration) {
+	h.firmwareUploadTimeout = timeout
+}
+
+// respondUpgradeError responds to a firmware upgrade that could not be started
+func respondUpgradeError(w http.ResponseWriter, err error) {
+	if errors.Is(err, camera.ErrUpgradeInProgress) {
+		utils.RespondError(w, http.StatusConflict, "UPGRADE_IN_PROGRESS", "A firmware upgrade of this camera is already in progress", nil)
+		return
+	}
+	respondCameraError(w, err, "UPGRADE_ERROR", "Failed to start firmware upgrade")
+}
+
+// CheckFirmware handles GET /api/v1/cameras/{id}/firmware, asking the camera
+// whether newer firmware is available online
+func (h *CameraHandler) CheckFirmware(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	check, err := client.CheckFirmware(ctx)
+	if err != nil {
+		logger.Error("Failed to check camera firmware", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "FIRMWARE_CHECK_ERROR", "Failed to check firmware")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id":        cameraID,
+		"firmware_version": client.Camera.FirmwareVer,
+		"update_available": check.NewFirmware == 1,
+		"upgrading":        client.Upgrading(),
+	})
+}
+
+// UpgradeFirmware handles POST /api/v1/cameras/{id}/firmware/upgrade, having
+// the camera download and install its latest firmware. Progress is polled
+// with GetFirmwareStatus.
+func (h *CameraHandler) UpgradeFirmware(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	err = client.StartOnlineUpgrade(ctx)
+	if err != nil {
+		if !errors.Is(err, camera.ErrUpgradeInProgress) {
+			logger.Error("Failed to start online firmware upgrade", zap.Error(err), zap.String("id", cameraID))
+		}
+		respondUpgradeError(w, err)
+		return
+	}
+
+	logger.Warn("Camera firmware upgrade started", zap.String("id", cameraID), zap.String("source", "online"))
+	utils.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message":   "Firmware upgrade started",
+		"camera_id": cameraID,
+	})
+}
+
+// UploadFirmware handles POST /api/v1/cameras/{id}/firmware/upload, a
+// multipart form with the firmware as "file" and optionally "restore_config"
+// to reset the camera's configuration. Uploads that receive no data for the
+// firmware upload timeout are aborted; the route is not bounded by the
+// request timeout, so sending the firmware to the camera has its own.
+func (h *CameraHandler) UploadFirmware(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	// Refuse before reading the upload; starting the upgrade checks again
+	if client.Upgrading() {
+		respondUpgradeError(w, camera.ErrUpgradeInProgress)
+		return
+	}
+
+	upload, err := h.readFirmwareUpload(w, r)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		var netErr net.Error
+		switch {
+		case errors.As(err, &tooLarge):
+			utils.RespondError(w, http.StatusRequestEntityTooLarge, "FIRMWARE_TOO_LARGE",
+				fmt.Sprintf("Firmware must not exceed %d MB", maxFirmwareSize>>20), nil)
+		case errors.As(err, &netErr) && netErr.Timeout():
+			logger.Warn("Firmware upload stalled", zap.String("id", cameraID))
+			utils.RespondError(w, http.StatusRequestTimeout, "UPLOAD_STALLED", "Firmware upload stalled", nil)
+		default:
+			utils.RespondBadRequest(w, "Invalid firmware upload: "+err.Error(), nil)
+		}
+		return
+	}
+
+	upgradeCtx, cancel := context.WithTimeout(ctx, firmwareTransferTimeout)
+	defer cancel()
+
+	err = client.StartFileUpgrade(upgradeCtx, upload.fileName, upload.data, upload.restoreConfig)
+	if err != nil {
+		if !errors.Is(err, camera.ErrUpgradeInProgress) {
+			logger.Error("Failed to upgrade camera firmware", zap.Error(err), zap.String("id", cameraID))
+		}
+		respondUpgradeError(w, err)
+		return
+	}
+
+	logger.Warn("Camera firmware upgrade started",
+		zap.String("id", cameraID),
+		zap.String("source", "upload"),
+		zap.String("file", upload.fileName),
+		zap.Int("size", len(upload.data)))
+	utils.RespondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"message":        "Firmware upgrade started",
+		"camera_id":      cameraID,
+		"file_name":      upload.fileName,
+		"size":           len(upload.data),
+		"restore_config": upload.restoreConfig,
+	})
+}
+
+// GetFirmwareStatus handles GET /api/v1/cameras/{id}/firmware/status
+func (h *CameraHandler) GetFirmwareStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	client, err := h.cameraService.GetCameraClient(cameraID)
+	if err != nil {
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+		return
+	}
+
+	status, err := client.UpgradeStatus(ctx)
+	if err != nil {
+		logger.Error("Failed to get firmware upgrade status", zap.Error(err), zap.String("id", cameraID))
+		respondCameraError(w, err, "UPGRADE_STATUS_ERROR", "Failed to get firmware upgrade status")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"camera_id": cameraID,
+		"percent":   status.Percent,
+		"code":      status.Code,
+		"upgrading": client.Upgrading(),
+	})
+}
+
+// firmwareUpload is a firmware image uploaded for a camera
+type firmwareUpload struct {
+	fileName      string
+	data          []byte
+	restoreConfig bool
+}
+
+// readFirmwareUpload reads the multipart firmware upload of r, aborting it
+// once no data arrives for the firmware upload timeout
+func (h *CameraHandler) readFirmwareUpload(w http.ResponseWriter, r *http.Request) (*firmwareUpload, error) {
+	timeout := h.firmwareUploadTimeout
+	if timeout <= 0 {
+		timeout = DefaultFirmwareUploadTimeout
+	}
+	controller := http.NewResponseController(w)
+	defer func() {
+		// The server's deadlines ran from the start of the request, which a
+		// long upload may have outlasted; camera calls have their own timeouts
+		_ = controller.SetReadDeadline(time.Time{})
+		_ = controller.SetWriteDeadline(time.Time{})
+	}()
+
+	r.Body = &stallTimeoutReader{
+		body:       http.MaxBytesReader(w, r.Body, maxFirmwareSize),
+		controller: controller,
+		timeout:    timeout,
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	upload := &firmwareUpload{}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch part.FormName() {
+		case "file":
+			upload.fileName = part.FileName()
+			upload.data, err = io.ReadAll(part)
+			if err != nil {
+				return nil, err
+			}
+		case "restore_config":
+			value, err := io.ReadAll(io.LimitReader(part, 16))
+			if err != nil {
+				return nil, err
+			}
+			upload.restoreConfig, err = strconv.ParseBool(strings.TrimSpace(string(value)))
+			if err != nil {
+				return nil, errors.New("restore_config must be true or false")
+			}
+		}
+		part.Close()
+	}
+
+	if len(upload.data) == 0 {
+		return nil, errFirmwareMissing
+	}
+	if upload.fileName == "" {
+		upload.fileName = defaultFirmwareFileName
+	}
+	return upload, nil
+}
+
+// stallTimeoutReader aborts reading a request body that receives no data for
+// timeout by moving the connection's read deadline forward on every read, so
+// slow but steady uploads are not cut off
+type stallTimeoutReader struct {
+	body       io.ReadCloser
+	controller *http.ResponseController
+	timeout    time.Duration
+}
+
+// Read reads from the body with a fresh read deadline. Connections without
+// deadline support are read without one.
+func (r *stallTimeoutReader) Read(p []byte) (int, error) {
+	_ = r.controller.SetReadDeadline(time.Now().Add(r.timeout))
+	return r.body.Read(p)
+}
+
+// Close closes the body
+func (r *stallTimeoutReader) Close() error {
+	return r.body.Close()
+}
@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newFirmwareCameraServer fakes the camera API of a firmware upgrade. It
+// reports the upgrade progress held by percent and records the parameters of
+// the latest UpgradePrepare in prepared.
+func newFirmwareCameraServer(t *testing.T, percent *atomic.Int32, prepared *map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "UpgradeStatus":
+			w.Write([]byte(`[{"cmd":"UpgradeStatus","code":0,"value":{"Status":{"Persent":` +
+				strconv.Itoa(int(percent.Load())) + `,"code":0}}}]`))
+		case "UpgradePrepare":
+			var req []struct {
+				Param map[string]interface{} `json:"param"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			*prepared = req[0].Param
+			w.Write([]byte(`[{"cmd":"UpgradePrepare","code":0,"value":{"rspCode":200}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newFirmwareHandler returns a camera handler serving camera-123 from server
+// and knowing no other camera
+func newFirmwareHandler(server *httptest.Server) (*CameraHandler, *camera.CameraClient) {
+	mockService := new(MockCameraServiceForConfig)
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "camera-123", FirmwareVer: "v3.1.0.956"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	mockService.On("GetCameraClient", "camera-123").Return(client, nil)
+	mockService.On("GetCameraClient", "camera-999").Return(nil, camera.ErrNotFound)
+	return &CameraHandler{cameraService: mockService}, client
+}
+
+// firmwareForm builds a multipart firmware upload
+func firmwareForm(t *testing.T, fileName string, firmware []byte, fields map[string]string) ([]byte, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for name, value := range fields {
+		require.NoError(t, writer.WriteField(name, value))
+	}
+	if firmware != nil {
+		part, err := writer.CreateFormFile("file", fileName)
+		require.NoError(t, err)
+		part.Write(firmware)
+	}
+	require.NoError(t, writer.Close())
+	return body.Bytes(), writer.FormDataContentType()
+}
+
+func TestReadFirmwareUpload(t *testing.T) {
+	handler := &CameraHandler{}
+
+	tests := []struct {
+		name        string
+		fileName    string
+		firmware    []byte
+		fields      map[string]string
+		wantName    string
+		wantRestore bool
+		wantErr     string
+	}{
+		{"file only", "IPC_523128M8MP.pak", []byte("firmware"), nil, "IPC_523128M8MP.pak", false, ""},
+		{"restore config", "IPC_523128M8MP.pak", []byte("firmware"), map[string]string{"restore_config": "true"}, "IPC_523128M8MP.pak", true, ""},
+		{"path stripped", "../../etc/IPC.pak", []byte("firmware"), nil, "IPC.pak", false, ""},
+		{"no file name", "", []byte("firmware"), nil, defaultFirmwareFileName, false, ""},
+		{"missing file", "", nil, map[string]string{"restore_config": "false"}, "", false, "firmware file is required"},
+		{"empty file", "IPC.pak", []byte{}, nil, "", false, "firmware file is required"},
+		{"invalid restore config", "IPC.pak", []byte("firmware"), map[string]string{"restore_config": "maybe"}, "", false, "restore_config must be true or false"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := firmwareForm(t, tt.fileName, tt.firmware, tt.fields)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/camera-123/firmware/upload", bytes.NewReader(body))
+			req.Header.Set("Content-Type", contentType)
+
+			upload, err := handler.readFirmwareUpload(httptest.NewRecorder(), req)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantName, upload.fileName)
+			assert.Equal(t, tt.firmware, upload.data)
+			assert.Equal(t, tt.wantRestore, upload.restoreConfig)
+		})
+	}
+
+	// Requests that are not multipart are rejected
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/camera-123/firmware/upload", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	_, err := handler.readFirmwareUpload(httptest.NewRecorder(), req)
+	assert.Error(t, err)
+}
+
+func TestCameraHandler_UploadFirmware(t *testing.T) {
+	var percent atomic.Int32
+	var prepared map[string]interface{}
+	server := newFirmwareCameraServer(t, &percent, &prepared)
+	handler, client := newFirmwareHandler(server)
+
+	body, contentType := firmwareForm(t, "IPC_523128M8MP.pak", []byte("firmware"), map[string]string{"restore_config": "true"})
+	req := newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/firmware/upload", body, map[string]string{"id": "camera-123"})
+	req.Header.Set("Content-Type", contentType)
+	w := httptest.NewRecorder()
+	handler.UploadFirmware(w, req)
+
+	// The upload is parsed and the camera prepared; the camera SDK does not
+	// implement uploading the image yet, so the upgrade fails and the camera
+	// is free for another attempt
+	assert.Equal(t, map[string]interface{}{"fileName": "IPC_523128M8MP.pak", "restoreCfg": float64(1)}, prepared)
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"UPGRADE_ERROR"`)
+	assert.False(t, client.Upgrading())
+
+	// Invalid uploads are rejected before reaching the camera
+	prepared = nil
+	body, contentType = firmwareForm(t, "", nil, nil)
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/firmware/upload", body, map[string]string{"id": "camera-123"})
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	handler.UploadFirmware(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Nil(t, prepared)
+
+	// Unknown cameras are 404
+	req = newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-999/firmware/upload", body, map[string]string{"id": "camera-999"})
+	req.Header.Set("Content-Type", contentType)
+	w = httptest.NewRecorder()
+	handler.UploadFirmware(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_UploadFirmware_AbortsStalledUpload(t *testing.T) {
+	var percent atomic.Int32
+	var prepared map[string]interface{}
+	handler, _ := newFirmwareHandler(newFirmwareCameraServer(t, &percent, &prepared))
+	handler.SetFirmwareUploadTimeout(100 * time.Millisecond)
+
+	router := chi.NewRouter()
+	router.Post("/cameras/{id}/firmware/upload", handler.UploadFirmware)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	// The client sends the start of the firmware, then nothing more
+	body, writer := io.Pipe()
+	defer writer.Close()
+	form := multipart.NewWriter(writer)
+	go func() {
+		part, err := form.CreateFormFile("file", "IPC.pak")
+		if err == nil {
+			part.Write([]byte("first chunk of the firmware"))
+		}
+	}()
+
+	req, err := http.NewRequest(http.MethodPost, server.URL+"/cameras/camera-123/firmware/upload", body)
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	start := time.Now()
+	resp, err := server.Client().Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusRequestTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	respBody, _ := io.ReadAll(resp.Body)
+	assert.Contains(t, string(respBody), "UPLOAD_STALLED")
+	assert.Nil(t, prepared, "a stalled upload never reaches the camera")
+}
+
+func TestCameraHandler_FirmwareStatusPolling(t *testing.T) {
+	var percent atomic.Int32
+	var prepared map[string]interface{}
+	handler, _ := newFirmwareHandler(newFirmwareCameraServer(t, &percent, &prepared))
+	params := map[string]string{"id": "camera-123"}
+
+	upgrade := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.UpgradeFirmware(w, newConfigRequest(http.MethodPost, "/api/v1/cameras/camera-123/firmware/upgrade", nil, params))
+		return w
+	}
+	status := func() map[string]interface{} {
+		w := httptest.NewRecorder()
+		handler.GetFirmwareStatus(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/firmware/status", nil, params))
+		require.Equal(t, http.StatusOK, w.Code)
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.Data
+	}
+
+	assert.Equal(t, http.StatusAccepted, upgrade().Code)
+
+	// While the camera installs the firmware, further upgrades are refused
+	percent.Store(40)
+	assert.Equal(t, map[string]interface{}{"camera_id": "camera-123", "percent": float64(40), "code": float64(0), "upgrading": true}, status())
+	w := upgrade()
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), "UPGRADE_IN_PROGRESS")
+
+	// Once the status reports the upgrade complete, the camera can be upgraded again
+	percent.Store(100)
+	assert.Equal(t, false, status()["upgrading"])
+	assert.Equal(t, http.StatusAccepted, upgrade().Code)
+
+	// Unknown cameras are 404
+	w = httptest.NewRecorder()
+	handler.GetFirmwareStatus(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-999/firmware/status", nil, map[string]string{"id": "camera-999"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestCameraHandler_CheckFirmware(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"CheckFirmware","code":0,"value":{"newFirmware":1}}]`))
+	}))
+	defer cameraServer.Close()
+	handler, _ := newFirmwareHandler(cameraServer)
+
+	w := httptest.NewRecorder()
+	handler.CheckFirmware(w, newConfigRequest(http.MethodGet, "/api/v1/cameras/camera-123/firmware", nil, map[string]string{"id": "camera-123"}))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, map[string]interface{}{
+		"camera_id":        "camera-123",
+		"firmware_version": "v3.1.0.956",
+		"update_available": true,
+		"upgrading":        false,
+	}, resp.Data)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// FleetServiceInterface defines the interface for fleet service
+type FleetServiceInterface interface {
+	FirmwareReport(ctx context.Context, checkUpdates bool) (*models.FirmwareReport, error)
+}
+
+// FleetHandler handles requests about all cameras at once
+type FleetHandler struct {
+	fleetService FleetServiceInterface
+}
+
+// NewFleetHandler creates a new fleet handler
+func NewFleetHandler(fleetService FleetServiceInterface) *FleetHandler {
+	return &FleetHandler{fleetService: fleetService}
+}
+
+// GetFirmwareReport handles GET /api/v1/fleet/firmware. With
+// ?check_updates=true every connected camera is also asked whether newer
+// firmware is available.
+func (h *FleetHandler) GetFirmwareReport(w http.ResponseWriter, r *http.Request) {
+	checkUpdates := false
+	if value := r.URL.Query().Get("check_updates"); value != "" {
+		var err error
+		checkUpdates, err = strconv.ParseBool(value)
+		if err != nil {
+			utils.RespondBadRequest(w, "check_updates must be true or false", map[string]interface{}{"check_updates": value})
+			return
+		}
+	}
+
+	report, err := h.fleetService.FirmwareReport(r.Context(), checkUpdates)
+	if err != nil {
+		logger.Error("Failed to build firmware report", zap.Error(err))
+		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to retrieve cameras", nil)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
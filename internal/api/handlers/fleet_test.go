@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// MockFleetService is a mock implementation of FleetServiceInterface
+type MockFleetService struct {
+	mock.Mock
+}
+
+func (m *MockFleetService) FirmwareReport(ctx context.Context, checkUpdates bool) (*models.FirmwareReport, error) {
+	args := m.Called(ctx, checkUpdates)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.FirmwareReport), args.Error(1)
+}
+
+func TestFleetHandler_GetFirmwareReport(t *testing.T) {
+	mockService := new(MockFleetService)
+	handler := NewFleetHandler(mockService)
+
+	report := &models.FirmwareReport{
+		Models: []models.FirmwareModel{{
+			Model:         "RLC-810A",
+			LatestVersion: "v3.1.0.2368_23062700",
+			Versions:      []string{"v3.1.0.2368_23062700", "v3.1.0.956_22041503"},
+			Drift:         true,
+			Cameras: []models.CameraFirmware{
+				{CameraID: "cam-1", Name: "Front Door", FirmwareVer: "v3.1.0.956_22041503", Behind: true},
+				{CameraID: "cam-2", Name: "Back Yard", FirmwareVer: "v3.1.0.2368_23062700"},
+			},
+		}},
+		Total:  2,
+		Behind: 1,
+	}
+	mockService.On("FirmwareReport", mock.Anything, false).Return(report, nil).Once()
+	mockService.On("FirmwareReport", mock.Anything, true).Return(&models.FirmwareReport{UpdatesChecked: true}, nil).Once()
+
+	w := httptest.NewRecorder()
+	handler.GetFirmwareReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/fleet/firmware", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"latest_version":"v3.1.0.2368_23062700"`)
+	assert.Contains(t, w.Body.String(), `"behind":1`)
+	assert.Contains(t, w.Body.String(), `"drift":true`)
+
+	w = httptest.NewRecorder()
+	handler.GetFirmwareReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/fleet/firmware?check_updates=true", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"updates_checked":true`)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestFleetHandler_GetFirmwareReport_Errors(t *testing.T) {
+	mockService := new(MockFleetService)
+	handler := NewFleetHandler(mockService)
+
+	w := httptest.NewRecorder()
+	handler.GetFirmwareReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/fleet/firmware?check_updates=maybe", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	mockService.On("FirmwareReport", mock.Anything, false).Return(nil, errors.New("database down"))
+	w = httptest.NewRecorder()
+	handler.GetFirmwareReport(w, httptest.NewRequest(http.MethodGet, "/api/v1/fleet/firmware", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
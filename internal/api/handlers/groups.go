@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// CameraGroupServiceInterface defines the interface for camera group service
+type CameraGroupServiceInterface interface {
+	CreateGroup(ctx context.Context, req *models.CreateCameraGroupRequest) (*models.CameraGroup, error)
+	GetGroup(ctx context.Context, id string) (*models.CameraGroup, error)
+	ListGroups(ctx context.Context) ([]*models.CameraGroup, error)
+	UpdateGroup(ctx context.Context, id string, req *models.UpdateCameraGroupRequest) (*models.CameraGroup, error)
+	DeleteGroup(ctx context.Context, id string) error
+	ListGroupCameras(ctx context.Context, id string) ([]*models.Camera, error)
+	AssignCamera(ctx context.Context, groupID, cameraID string) error
+	UnassignCamera(ctx context.Context, groupID, cameraID string) error
+}
+
+// GroupHandler handles camera group requests
+type GroupHandler struct {
+	groupService CameraGroupServiceInterface
+}
+
+// NewGroupHandler creates a new camera group handler
+func NewGroupHandler(groupService CameraGroupServiceInterface) *GroupHandler {
+	return &GroupHandler{groupService: groupService}
+}
+
+// ListGroups handles GET /api/v1/groups
+func (h *GroupHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := h.groupService.ListGroups(r.Context())
+	if err != nil {
+		respondGroupError(w, err, "Failed to list camera groups")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"groups": groups,
+		"total":  len(groups),
+	})
+}
+
+// CreateGroup handles POST /api/v1/groups
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateCameraGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	group, err := h.groupService.CreateGroup(r.Context(), &req)
+	if err != nil {
+		respondGroupError(w, err, "Failed to create camera group")
+		return
+	}
+
+	utils.RespondCreated(w, group)
+}
+
+// GetGroup handles GET /api/v1/groups/{id}
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	group, err := h.groupService.GetGroup(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondGroupError(w, err, "Failed to get camera group")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, group)
+}
+
+// UpdateGroup handles PUT /api/v1/groups/{id}
+func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	var req models.UpdateCameraGroupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondError(w, http.StatusBadRequest, "INVALID_JSON", "Invalid request body", nil)
+		return
+	}
+
+	group, err := h.groupService.UpdateGroup(r.Context(), chi.URLParam(r, "id"), &req)
+	if err != nil {
+		respondGroupError(w, err, "Failed to update camera group")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, group)
+}
+
+// DeleteGroup handles DELETE /api/v1/groups/{id}. The cameras of the group
+// are kept, without a group.
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := h.groupService.DeleteGroup(r.Context(), chi.URLParam(r, "id")); err != nil {
+		respondGroupError(w, err, "Failed to delete camera group")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Camera group deleted successfully",
+	})
+}
+
+// ListGroupCameras handles GET /api/v1/groups/{id}/cameras
+func (h *GroupHandler) ListGroupCameras(w http.ResponseWriter, r *http.Request) {
+	cameras, err := h.groupService.ListGroupCameras(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		respondGroupError(w, err, "Failed to list group cameras")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"cameras": cameras,
+		"total":   len(cameras),
+	})
+}
+
+// AssignCamera handles PUT /api/v1/groups/{id}/cameras/{cameraId}, moving
+// the camera into the group
+func (h *GroupHandler) AssignCamera(w http.ResponseWriter, r *http.Request) {
+	if err := h.groupService.AssignCamera(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "cameraId")); err != nil {
+		respondGroupError(w, err, "Failed to assign camera to group")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Camera added to group",
+	})
+}
+
+// UnassignCamera handles DELETE /api/v1/groups/{id}/cameras/{cameraId}
+func (h *GroupHandler) UnassignCamera(w http.ResponseWriter, r *http.Request) {
+	if err := h.groupService.UnassignCamera(r.Context(), chi.URLParam(r, "id"), chi.URLParam(r, "cameraId")); err != nil {
+		respondGroupError(w, err, "Failed to remove camera from group")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]string{
+		"message": "Camera removed from group",
+	})
+}
+
+// respondGroupError responds to a failed camera group operation
+func respondGroupError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrCameraGroupNotFound):
+		utils.RespondNotFound(w, "Camera group not found")
+	case errors.Is(err, service.ErrCameraNotFound):
+		utils.RespondError(w, http.StatusNotFound, "CAMERA_NOT_FOUND", "Camera not found", nil)
+	case errors.Is(err, service.ErrNotGroupMember):
+		utils.RespondNotFound(w, "Camera is not in the group")
+	case errors.Is(err, service.ErrCameraGroupExists):
+		utils.RespondConflict(w, "A camera group with this name already exists", nil)
+	case errors.Is(err, service.ErrInvalidCameraGroup):
+		utils.RespondError(w, http.StatusBadRequest, "VALIDATION_ERROR", "Camera group name is required", nil)
+	default:
+		logger.Error(message, zap.Error(err))
+		utils.RespondInternalError(w, message)
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// MockCameraGroupService is a mock implementation of CameraGroupServiceInterface
+type MockCameraGroupService struct {
+	mock.Mock
+}
+
+func (m *MockCameraGroupService) CreateGroup(ctx context.Context, req *models.CreateCameraGroupRequest) (*models.CameraGroup, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraGroup), args.Error(1)
+}
+
+func (m *MockCameraGroupService) GetGroup(ctx context.Context, id string) (*models.CameraGroup, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraGroup), args.Error(1)
+}
+
+func (m *MockCameraGroupService) ListGroups(ctx context.Context) ([]*models.CameraGroup, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.CameraGroup), args.Error(1)
+}
+
+func (m *MockCameraGroupService) UpdateGroup(ctx context.Context, id string, req *models.UpdateCameraGroupRequest) (*models.CameraGroup, error) {
+	args := m.Called(ctx, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CameraGroup), args.Error(1)
+}
+
+func (m *MockCameraGroupService) DeleteGroup(ctx context.Context, id string) error {
+	return m.Called(ctx, id).Error(0)
+}
+
+func (m *MockCameraGroupService) ListGroupCameras(ctx context.Context, id string) ([]*models.Camera, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Camera), args.Error(1)
+}
+
+func (m *MockCameraGroupService) AssignCamera(ctx context.Context, groupID, cameraID string) error {
+	return m.Called(ctx, groupID, cameraID).Error(0)
+}
+
+func (m *MockCameraGroupService) UnassignCamera(ctx context.Context, groupID, cameraID string) error {
+	return m.Called(ctx, groupID, cameraID).Error(0)
+}
+
+// groupRequest returns a request carrying the chi URL params of group routes
+func groupRequest(method, target, body string, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+func TestGroupHandler_CreateGroup(t *testing.T) {
+	mockService := new(MockCameraGroupService)
+	want := &models.CreateCameraGroupRequest{Name: "Garden", Description: "Backyard"}
+	mockService.On("CreateGroup", mock.Anything, want).Return(&models.CameraGroup{ID: "group-1", Name: "Garden"}, nil)
+
+	w := httptest.NewRecorder()
+	NewGroupHandler(mockService).CreateGroup(w, groupRequest(http.MethodPost, "/api/v1/groups",
+		`{"name":"Garden","description":"Backyard"}`, nil))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Contains(t, w.Body.String(), `"id":"group-1"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestGroupHandler_CreateGroup_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"name taken", service.ErrCameraGroupExists, http.StatusConflict},
+		{"missing name", service.ErrInvalidCameraGroup, http.StatusBadRequest},
+		{"database error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCameraGroupService)
+			mockService.On("CreateGroup", mock.Anything, mock.Anything).Return(nil, tt.err)
+
+			w := httptest.NewRecorder()
+			NewGroupHandler(mockService).CreateGroup(w, groupRequest(http.MethodPost, "/api/v1/groups", `{"name":"Garden"}`, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+
+	w := httptest.NewRecorder()
+	NewGroupHandler(new(MockCameraGroupService)).CreateGroup(w, groupRequest(http.MethodPost, "/api/v1/groups", `{`, nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGroupHandler_UpdateGroup(t *testing.T) {
+	mockService := new(MockCameraGroupService)
+	name := "Front yard"
+	mockService.On("UpdateGroup", mock.Anything, "group-1", &models.UpdateCameraGroupRequest{Name: &name}).
+		Return(&models.CameraGroup{ID: "group-1", Name: name}, nil)
+	mockService.On("UpdateGroup", mock.Anything, "missing", mock.Anything).
+		Return(nil, fmt.Errorf("%w: missing", service.ErrCameraGroupNotFound))
+
+	w := httptest.NewRecorder()
+	NewGroupHandler(mockService).UpdateGroup(w, groupRequest(http.MethodPut, "/api/v1/groups/group-1",
+		`{"name":"Front yard"}`, map[string]string{"id": "group-1"}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Front yard")
+
+	w = httptest.NewRecorder()
+	NewGroupHandler(mockService).UpdateGroup(w, groupRequest(http.MethodPut, "/api/v1/groups/missing",
+		`{"name":"Front yard"}`, map[string]string{"id": "missing"}))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGroupHandler_ListGroupCameras(t *testing.T) {
+	mockService := new(MockCameraGroupService)
+	mockService.On("ListGroupCameras", mock.Anything, "group-1").
+		Return([]*models.Camera{{ID: "cam-1"}, {ID: "cam-2"}}, nil)
+
+	w := httptest.NewRecorder()
+	NewGroupHandler(mockService).ListGroupCameras(w, groupRequest(http.MethodGet, "/api/v1/groups/group-1/cameras",
+		"", map[string]string{"id": "group-1"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":2`)
+}
+
+func TestGroupHandler_AssignAndUnassignCamera(t *testing.T) {
+	tests := []struct {
+		name       string
+		unassign   bool
+		err        error
+		wantStatus int
+	}{
+		{"assign", false, nil, http.StatusOK},
+		{"assign to unknown group", false, fmt.Errorf("%w: group-1", service.ErrCameraGroupNotFound), http.StatusNotFound},
+		{"assign unknown camera", false, fmt.Errorf("%w: cam-1", service.ErrCameraNotFound), http.StatusNotFound},
+		{"unassign", true, nil, http.StatusOK},
+		{"unassign non-member", true, service.ErrNotGroupMember, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockCameraGroupService)
+			handler := NewGroupHandler(mockService)
+			params := map[string]string{"id": "group-1", "cameraId": "cam-1"}
+			w := httptest.NewRecorder()
+
+			if tt.unassign {
+				mockService.On("UnassignCamera", mock.Anything, "group-1", "cam-1").Return(tt.err)
+				handler.UnassignCamera(w, groupRequest(http.MethodDelete, "/api/v1/groups/group-1/cameras/cam-1", "", params))
+			} else {
+				mockService.On("AssignCamera", mock.Anything, "group-1", "cam-1").Return(tt.err)
+				handler.AssignCamera(w, groupRequest(http.MethodPut, "/api/v1/groups/group-1/cameras/cam-1", "", params))
+			}
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGroupHandler_DeleteGroup(t *testing.T) {
+	mockService := new(MockCameraGroupService)
+	mockService.On("DeleteGroup", mock.Anything, "group-1").Return(nil)
+
+	w := httptest.NewRecorder()
+	NewGroupHandler(mockService).DeleteGroup(w, groupRequest(http.MethodDelete, "/api/v1/groups/group-1", "", map[string]string{"id": "group-1"}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestCameraHandler_ListCameras_ByGroup(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	mockService.On("ListCamerasByGroup", mock.Anything, "group-1").Return([]*models.Camera{{ID: "cam-1"}}, nil)
+	handler := &CameraHandler{cameraService: mockService}
+
+	w := httptest.NewRecorder()
+	handler.ListCameras(w, httptest.NewRequest(http.MethodGet, "/api/v1/cameras?group_id=group-1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":1`)
+	mockService.AssertNotCalled(t, "ListCameras", mock.Anything)
+}
+
+func TestCameraHandler_ListCameras_Sort(t *testing.T) {
+	mockService := new(MockCameraServiceForConfig)
+	mockService.On("ListCameras", mock.Anything).Return([]*models.Camera{
+		{ID: "cam-1", Name: "Backyard", Status: "online"},
+		{ID: "cam-2", Name: "Front Door", Status: "offline"},
+		{ID: "cam-3", Name: "Garage", Status: "online"},
+	}, nil)
+	handler := &CameraHandler{cameraService: mockService}
+
+	w := httptest.NewRecorder()
+	handler.ListCameras(w, httptest.NewRequest(http.MethodGet, "/api/v1/cameras?sort=status&order=desc", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Cameras []models.Camera `json:"cameras"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	var ids []string
+	for _, camera := range resp.Data.Cameras {
+		ids = append(ids, camera.ID)
+	}
+	assert.Equal(t, []string{"cam-3", "cam-1", "cam-2"}, ids)
+
+	// Only allowlisted fields sort
+	w = httptest.NewRecorder()
+	handler.ListCameras(w, httptest.NewRequest(http.MethodGet, "/api/v1/cameras?sort=password", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid sort field")
+}
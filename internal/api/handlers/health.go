@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/pkg/utils"
 )
 
@@ -18,7 +19,8 @@ type HealthChecker interface {
 
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db *sql.DB
+	db     *sql.DB
+	ffmpeg *service.FFmpegInfo
 }
 
 // NewHealthHandler creates a new health handler
@@ -28,6 +30,11 @@ func NewHealthHandler(db *sql.DB) *HealthHandler {
 	}
 }
 
+// SetFFmpegInfo sets the FFmpeg probe result reported by the health endpoints
+func (h *HealthHandler) SetFFmpegInfo(info *service.FFmpegInfo) {
+	h.ffmpeg = info
+}
+
 // HealthCheck handles health check requests
 func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	health := map[string]interface{}{
@@ -36,6 +43,10 @@ func (h *HealthHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 		"uptime":  time.Since(startTime).String(),
 	}
 
+	if h.ffmpeg != nil {
+		health["ffmpeg"] = h.ffmpeg
+	}
+
 	utils.RespondJSON(w, http.StatusOK, health)
 }
 
@@ -59,6 +70,15 @@ func (h *HealthHandler) ReadinessCheck(w http.ResponseWriter, r *http.Request) {
 		components["database"] = "not configured"
 	}
 
+	// FFmpeg is only needed for transcoding, so it is reported without affecting readiness
+	if h.ffmpeg != nil {
+		if h.ffmpeg.Available {
+			components["ffmpeg"] = "available: " + h.ffmpeg.Version
+		} else {
+			components["ffmpeg"] = "unavailable: " + h.ffmpeg.Error
+		}
+	}
+
 	status := "ready"
 	statusCode := http.StatusOK
 	if !allHealthy {
@@ -10,6 +10,8 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/api/service"
 )
 
 func TestNewHealthHandler(t *testing.T) {
@@ -204,3 +206,52 @@ func TestHealthHandler_ReadinessCheck_Timeout(t *testing.T) {
 func parseJSON(data []byte, v interface{}) error {
 	return json.Unmarshal(data, v)
 }
+
+func TestHealthHandler_ReportsFFmpeg(t *testing.T) {
+	tests := []struct {
+		name          string
+		info          *service.FFmpegInfo
+		wantComponent string
+	}{
+		{
+			name:          "present",
+			info:          &service.FFmpegInfo{Available: true, Path: "ffmpeg", Version: "6.1.1"},
+			wantComponent: "available: 6.1.1",
+		},
+		{
+			name:          "absent",
+			info:          &service.FFmpegInfo{Path: "ffmpeg", Error: "executable file not found in $PATH"},
+			wantComponent: "unavailable: executable file not found in $PATH",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewHealthHandler(nil)
+			handler.SetFFmpegInfo(tt.info)
+
+			w := httptest.NewRecorder()
+			handler.HealthCheck(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			var health struct {
+				Data struct {
+					FFmpeg service.FFmpegInfo `json:"ffmpeg"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &health))
+			assert.Equal(t, *tt.info, health.Data.FFmpeg)
+
+			w = httptest.NewRecorder()
+			handler.ReadinessCheck(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			var ready struct {
+				Data struct {
+					Components map[string]string `json:"components"`
+				} `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &ready))
+			assert.Equal(t, tt.wantComponent, ready.Data.Components["ffmpeg"])
+			assert.Equal(t, http.StatusOK, w.Code, "ffmpeg does not affect readiness")
+		})
+	}
+}
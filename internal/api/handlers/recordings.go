@@ -3,8 +3,14 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
+	"path"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -26,9 +32,18 @@ type RecordingServiceInterface interface {
 	CountRecordings(ctx context.Context) (int, error)
 	GetTotalSize(ctx context.Context) (int64, error)
 	DeleteRecording(ctx context.Context, id string) error
+	DeleteRecordings(ctx context.Context, ids []string) *models.BulkResult
 	GetRecordingDownloadInfo(ctx context.Context, recording *models.Recording) (*service.RecordingDownloadInfo, error)
+	OpenRecordingStream(ctx context.Context, recording *models.Recording, offset int64) (io.ReadCloser, error)
+	StartClip(ctx context.Context, req *models.CreateClipRequest) (*service.ClipJob, error)
+	GetClipJob(id string) (*service.ClipJob, error)
+	OpenThumbnail(recording *models.Recording) (io.ReadCloser, error)
+	ExtractFrame(ctx context.Context, recording *models.Recording, offset time.Duration) ([]byte, error)
 }
 
+// maxClipDuration is the longest clip that can be exported in one request
+const maxClipDuration = 30 * time.Minute
+
 // RecordingHandler handles recording requests
 type RecordingHandler struct {
 	recordingService RecordingServiceInterface
@@ -44,35 +59,31 @@ func (h *RecordingHandler) ListRecordings(w http.ResponseWriter, r *http.Request
 	ctx := r.Context()
 
 	// Parse query parameters
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
-	cameraID := r.URL.Query().Get("camera_id")
-	startTimeStr := r.URL.Query().Get("start_time")
-	endTimeStr := r.URL.Query().Get("end_time")
-
-	var recordings []*models.Recording
-	var err error
+	query := r.URL.Query()
+	limit, _ := strconv.Atoi(query.Get("limit"))
+	offset, _ := strconv.Atoi(query.Get("offset"))
+	search := &models.RecordingSearchRequest{Limit: limit, Offset: offset}
 
 	// Filter by camera ID and/or time range
-	if cameraID != "" && startTimeStr != "" && endTimeStr != "" {
-		// Filter by both camera ID and time range
-		startTime, err1 := time.Parse(time.RFC3339, startTimeStr)
-		endTime, err2 := time.Parse(time.RFC3339, endTimeStr)
-
-		if err1 != nil || err2 != nil {
-			utils.RespondBadRequest(w, "Invalid time format. Use RFC3339 format (e.g., 2025-10-27T10:00:00Z)", nil)
-			return
-		}
+	if cameraID := query.Get("camera_id"); cameraID != "" {
+		search.CameraID = &cameraID
+	}
+	startTime, err1 := parseOptionalTime(query.Get("start_time"))
+	endTime, err2 := parseOptionalTime(query.Get("end_time"))
+	if err1 != nil || err2 != nil {
+		utils.RespondBadRequest(w, "Invalid time format. Use RFC3339 format (e.g., 2025-10-27T10:00:00Z)", nil)
+		return
+	}
+	search.StartTime, search.EndTime = startTime, endTime
 
-		recordings, err = h.recordingService.ListRecordingsByTimeRange(ctx, cameraID, startTime, endTime, limit, offset)
-	} else if cameraID != "" {
-		// Filter by camera ID only
-		recordings, err = h.recordingService.ListRecordingsByCameraID(ctx, cameraID, limit, offset)
-	} else {
-		// List all recordings
-		recordings, err = h.recordingService.ListRecordings(ctx, limit, offset)
+	sortOrder, err := models.ParseSortOrder(query.Get("sort"), query.Get("order"), models.RecordingSortFields, models.DefaultRecordingSort)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
 	}
+	search.Sort = sortOrder
 
+	recordings, err := h.recordingService.SearchRecordings(ctx, search)
 	if err != nil {
 		utils.RespondInternalError(w, "Failed to list recordings")
 		return
@@ -151,6 +162,244 @@ func (h *RecordingHandler) DownloadRecording(w http.ResponseWriter, r *http.Requ
 	utils.RespondJSON(w, http.StatusOK, downloadInfo)
 }
 
+// StreamRecording handles GET /api/v1/recordings/{id}/stream
+// It proxies the recording file from the camera. A single byte range may be
+// requested with a Range header for seeking.
+func (h *RecordingHandler) StreamRecording(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if id == "" {
+		utils.RespondBadRequest(w, "Recording ID is required", nil)
+		return
+	}
+
+	recording, err := h.recordingService.GetRecording(ctx, id)
+	if err != nil {
+		utils.RespondNotFound(w, "Recording not found")
+		return
+	}
+
+	start, end := int64(0), recording.FileSize-1
+	partial := false
+	if header := r.Header.Get("Range"); header != "" && recording.FileSize > 0 {
+		var ok bool
+		start, end, ok = parseByteRange(header, recording.FileSize)
+		if !ok {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", recording.FileSize))
+			utils.RespondError(w, http.StatusRequestedRangeNotSatisfiable, "INVALID_RANGE", "Requested range not satisfiable", nil)
+			return
+		}
+		partial = true
+	}
+
+	stream, err := h.recordingService.OpenRecordingStream(ctx, recording, start)
+	if err != nil {
+		logger.Error("Failed to open recording stream",
+			zap.Error(err),
+			zap.String("recording_id", id))
+		if errors.Is(err, service.ErrCameraOffline) {
+			utils.RespondError(w, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE", "Camera is offline", nil)
+			return
+		}
+		utils.RespondError(w, http.StatusBadGateway, "STREAM_FAILED", "Failed to stream recording", nil)
+		return
+	}
+	defer stream.Close()
+
+	fileName := path.Base(recording.FileName)
+	if recording.FileName == "" {
+		fileName = recording.ID + ".mp4"
+	}
+
+	w.Header().Set("Content-Type", "video/mp4")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName))
+	w.Header().Set("Accept-Ranges", "bytes")
+
+	var body io.Reader = stream
+	status := http.StatusOK
+	if recording.FileSize > 0 {
+		length := end - start + 1
+		body = io.LimitReader(stream, length)
+		w.Header().Set("Content-Length", strconv.FormatInt(length, 10))
+		if partial {
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, recording.FileSize))
+			status = http.StatusPartialContent
+		}
+	}
+
+	// Large recordings take longer to send than the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	w.WriteHeader(status)
+	if _, err := io.Copy(w, body); err != nil {
+		logger.Debug("Recording stream ended early",
+			zap.String("recording_id", id),
+			zap.Error(err))
+	}
+}
+
+// GetThumbnail handles GET /api/v1/recordings/{id}/thumbnail
+func (h *RecordingHandler) GetThumbnail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if id == "" {
+		utils.RespondBadRequest(w, "Recording ID is required", nil)
+		return
+	}
+
+	recording, err := h.recordingService.GetRecording(ctx, id)
+	if err != nil {
+		utils.RespondNotFound(w, "Recording not found")
+		return
+	}
+
+	thumbnail, err := h.recordingService.OpenThumbnail(recording)
+	if errors.Is(err, service.ErrThumbnailNotFound) {
+		utils.RespondNotFound(w, "Recording has no thumbnail")
+		return
+	}
+	if err != nil {
+		logger.Error("Failed to open recording thumbnail",
+			zap.Error(err),
+			zap.String("recording_id", id))
+		utils.RespondInternalError(w, "Failed to read thumbnail")
+		return
+	}
+	defer thumbnail.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	if _, err := io.Copy(w, thumbnail); err != nil {
+		logger.Debug("Thumbnail response ended early",
+			zap.String("recording_id", id),
+			zap.Error(err))
+	}
+}
+
+// GetFrame handles GET /api/v1/recordings/{id}/frame, returning the frame at
+// ?offset=<seconds> into the recording, or at the time ?at=<RFC 3339>, as a JPEG
+func (h *RecordingHandler) GetFrame(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := chi.URLParam(r, "id")
+
+	if id == "" {
+		utils.RespondBadRequest(w, "Recording ID is required", nil)
+		return
+	}
+
+	query := r.URL.Query()
+	offsetStr, atStr := query.Get("offset"), query.Get("at")
+	if (offsetStr == "") == (atStr == "") {
+		utils.RespondBadRequest(w, "Exactly one of offset or at is required", nil)
+		return
+	}
+
+	recording, err := h.recordingService.GetRecording(ctx, id)
+	if err != nil {
+		utils.RespondNotFound(w, "Recording not found")
+		return
+	}
+
+	var offset time.Duration
+	if offsetStr != "" {
+		seconds, err := strconv.ParseFloat(offsetStr, 64)
+		if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+			utils.RespondBadRequest(w, "offset must be a number of seconds", map[string]interface{}{"offset": offsetStr})
+			return
+		}
+		offset = time.Duration(seconds * float64(time.Second))
+	} else {
+		at, err := time.Parse(time.RFC3339, atStr)
+		if err != nil {
+			utils.RespondBadRequest(w, "at must be an RFC 3339 time", map[string]interface{}{"at": atStr})
+			return
+		}
+		offset = at.Sub(recording.StartTime)
+	}
+
+	frame, err := h.recordingService.ExtractFrame(ctx, recording, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrFrameOutOfRange):
+			utils.RespondError(w, http.StatusBadRequest, "OFFSET_OUT_OF_RANGE", err.Error(), map[string]interface{}{"offset": offset.Seconds()})
+		case errors.Is(err, service.ErrFrameExtractionDisabled):
+			utils.RespondError(w, http.StatusServiceUnavailable, "FRAME_EXTRACTION_DISABLED", "Frame extraction is not configured", nil)
+		case errors.Is(err, service.ErrCameraOffline):
+			utils.RespondError(w, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE", "Camera is offline", nil)
+		default:
+			logger.Error("Failed to extract recording frame",
+				zap.Error(err),
+				zap.String("recording_id", id),
+				zap.Duration("offset", offset))
+			utils.RespondError(w, http.StatusBadGateway, "FRAME_FAILED", "Failed to extract frame", nil)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Content-Length", strconv.Itoa(len(frame)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Write(frame)
+}
+
+// parseOptionalTime parses an RFC 3339 query parameter, nil when empty
+func parseOptionalTime(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// parseByteRange parses a single-range Range header ("bytes=start-end",
+// "bytes=start-" or "bytes=-suffix") against a file of the given size and
+// returns the inclusive byte positions
+func parseByteRange(header string, size int64) (int64, int64, bool) {
+	spec, found := strings.CutPrefix(header, "bytes=")
+	if !found || strings.Contains(spec, ",") {
+		return 0, 0, false
+	}
+
+	first, last, found := strings.Cut(strings.TrimSpace(spec), "-")
+	if !found {
+		return 0, 0, false
+	}
+
+	if first == "" {
+		suffix, err := strconv.ParseInt(last, 10, 64)
+		if err != nil || suffix <= 0 {
+			return 0, 0, false
+		}
+		if suffix > size {
+			suffix = size
+		}
+		return size - suffix, size - 1, true
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil || start < 0 || start >= size {
+		return 0, 0, false
+	}
+
+	end := size - 1
+	if last != "" {
+		end, err = strconv.ParseInt(last, 10, 64)
+		if err != nil || end < start {
+			return 0, 0, false
+		}
+		if end >= size {
+			end = size - 1
+		}
+	}
+
+	return start, end, true
+}
+
 // SearchRecordings handles POST /api/v1/recordings/search
 func (h *RecordingHandler) SearchRecordings(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -161,6 +410,13 @@ func (h *RecordingHandler) SearchRecordings(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	sortOrder, err := models.ParseSortOrder(r.URL.Query().Get("sort"), r.URL.Query().Get("order"), models.RecordingSortFields, models.DefaultRecordingSort)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
+	req.Sort = sortOrder
+
 	recordings, err := h.recordingService.SearchRecordings(ctx, &req)
 	if err != nil {
 		utils.RespondInternalError(w, "Failed to search recordings")
@@ -184,6 +440,66 @@ func (h *RecordingHandler) SearchRecordings(w http.ResponseWriter, r *http.Reque
 	utils.RespondJSON(w, http.StatusOK, response)
 }
 
+// CreateClip handles POST /api/v1/recordings/clips
+// It checks that the recordings of a camera cover a time range and starts
+// joining them into a single clip trimmed to that range. The export runs in
+// the background; it answers 202 with the job, whose state is polled at
+// GET /api/v1/recordings/clips/{id}.
+func (h *RecordingHandler) CreateClip(w http.ResponseWriter, r *http.Request) {
+	var req models.CreateClipRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.RespondBadRequest(w, "Invalid request body", nil)
+		return
+	}
+
+	if req.CameraID == "" {
+		utils.RespondBadRequest(w, "camera_id is required", nil)
+		return
+	}
+	if req.StartTime.IsZero() || !req.EndTime.After(req.StartTime) {
+		utils.RespondBadRequest(w, "end_time must be after start_time", nil)
+		return
+	}
+	if req.EndTime.Sub(req.StartTime) > maxClipDuration {
+		utils.RespondBadRequest(w, fmt.Sprintf("Clips are limited to %s", maxClipDuration), nil)
+		return
+	}
+
+	job, err := h.recordingService.StartClip(r.Context(), &req)
+	if err != nil {
+		logger.Error("Failed to start recording clip",
+			zap.Error(err),
+			zap.String("camera_id", req.CameraID))
+		switch {
+		case errors.Is(err, service.ErrClipExportDisabled):
+			utils.RespondError(w, http.StatusServiceUnavailable, "CLIP_EXPORT_DISABLED", "Clip export is not configured", nil)
+		case errors.Is(err, service.ErrClipNotCovered):
+			utils.RespondError(w, http.StatusUnprocessableEntity, "RECORDINGS_INCOMPLETE", err.Error(), nil)
+		case errors.Is(err, service.ErrCameraOffline):
+			utils.RespondError(w, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE", "Camera is offline", nil)
+		default:
+			utils.RespondError(w, http.StatusBadGateway, "CLIP_FAILED", "Failed to create clip", nil)
+		}
+		return
+	}
+
+	w.Header().Set("Location", "/api/v1/recordings/clips/"+job.ID)
+	utils.RespondJSON(w, http.StatusAccepted, job)
+}
+
+// GetClipJob handles GET /api/v1/recordings/clips/{id}
+// It reports whether a clip export is still running, and the clip recording
+// once it is done.
+func (h *RecordingHandler) GetClipJob(w http.ResponseWriter, r *http.Request) {
+	job, err := h.recordingService.GetClipJob(chi.URLParam(r, "id"))
+	if err != nil {
+		utils.RespondNotFound(w, "Clip job not found")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, job)
+}
+
 // DeleteRecording handles DELETE /api/v1/recordings/{id}
 func (h *RecordingHandler) DeleteRecording(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -203,3 +519,15 @@ func (h *RecordingHandler) DeleteRecording(w http.ResponseWriter, r *http.Reques
 		"message": "Recording deleted successfully",
 	})
 }
+
+// DeleteRecordings handles POST /api/v1/recordings/delete, deleting the
+// recordings listed in the body. Recordings that are unknown or fail are
+// reported in the result without failing the others.
+func (h *RecordingHandler) DeleteRecordings(w http.ResponseWriter, r *http.Request) {
+	ids, ok := decodeBulkRequest(w, r)
+	if !ok {
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, h.recordingService.DeleteRecordings(r.Context(), ids))
+}
@@ -4,14 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
@@ -87,6 +92,11 @@ func (m *MockRecordingService) DeleteRecording(ctx context.Context, id string) e
 	return args.Error(0)
 }
 
+func (m *MockRecordingService) DeleteRecordings(ctx context.Context, ids []string) *models.BulkResult {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(*models.BulkResult)
+}
+
 func (m *MockRecordingService) DeleteOldRecordings(ctx context.Context, olderThan time.Time) (int64, error) {
 	args := m.Called(ctx, olderThan)
 	return args.Get(0).(int64), args.Error(1)
@@ -105,6 +115,46 @@ func (m *MockRecordingService) GetRecordingDownloadInfo(ctx context.Context, rec
 	return args.Get(0).(*service.RecordingDownloadInfo), args.Error(1)
 }
 
+func (m *MockRecordingService) OpenRecordingStream(ctx context.Context, recording *models.Recording, offset int64) (io.ReadCloser, error) {
+	args := m.Called(ctx, recording, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (m *MockRecordingService) StartClip(ctx context.Context, req *models.CreateClipRequest) (*service.ClipJob, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ClipJob), args.Error(1)
+}
+
+func (m *MockRecordingService) GetClipJob(id string) (*service.ClipJob, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.ClipJob), args.Error(1)
+}
+
+func (m *MockRecordingService) OpenThumbnail(recording *models.Recording) (io.ReadCloser, error) {
+	args := m.Called(recording)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(io.ReadCloser), args.Error(1)
+}
+
+func (m *MockRecordingService) ExtractFrame(ctx context.Context, recording *models.Recording, offset time.Duration) ([]byte, error) {
+	args := m.Called(ctx, recording, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]byte), args.Error(1)
+}
+
 func TestNewRecordingHandler(t *testing.T) {
 	mockService := new(MockRecordingService)
 	handler := NewRecordingHandler(mockService)
@@ -122,7 +172,7 @@ func TestRecordingHandler_ListRecordings(t *testing.T) {
 		{ID: "rec-2", CameraID: "cam-2", FileName: "recording2.mp4"},
 	}
 
-	mockService.On("ListRecordings", mock.Anything, mock.Anything, mock.Anything).Return(recordings, nil)
+	mockService.On("SearchRecordings", mock.Anything, &models.RecordingSearchRequest{}).Return(recordings, nil)
 	mockService.On("CountRecordings", mock.Anything).Return(2, nil)
 	mockService.On("GetTotalSize", mock.Anything).Return(int64(2048000), nil)
 
@@ -145,7 +195,8 @@ func TestRecordingHandler_ListRecordingsByCameraID(t *testing.T) {
 		{ID: "rec-1", CameraID: "cam-123", FileName: "recording1.mp4"},
 	}
 
-	mockService.On("ListRecordingsByCameraID", mock.Anything, "cam-123", mock.Anything, mock.Anything).Return(recordings, nil)
+	cameraID := "cam-123"
+	mockService.On("SearchRecordings", mock.Anything, &models.RecordingSearchRequest{CameraID: &cameraID}).Return(recordings, nil)
 	mockService.On("CountRecordings", mock.Anything).Return(1, nil)
 	mockService.On("GetTotalSize", mock.Anything).Return(int64(1024000), nil)
 
@@ -159,6 +210,37 @@ func TestRecordingHandler_ListRecordingsByCameraID(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestRecordingHandler_ListRecordings_Sort(t *testing.T) {
+	mockService := new(MockRecordingService)
+	handler := NewRecordingHandler(mockService)
+
+	cameraID := "cam-123"
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	search := &models.RecordingSearchRequest{
+		CameraID:  &cameraID,
+		StartTime: &start,
+		Sort:      models.SortOrder{Field: "file_size", Descending: true},
+		Limit:     10,
+	}
+	mockService.On("SearchRecordings", mock.Anything, search).Return([]*models.Recording{{ID: "rec-1"}}, nil)
+	mockService.On("CountRecordings", mock.Anything).Return(1, nil)
+	mockService.On("GetTotalSize", mock.Anything).Return(int64(1024), nil)
+
+	req := httptest.NewRequest(http.MethodGet,
+		"/api/v1/recordings?camera_id=cam-123&start_time=2025-10-27T10:00:00Z&sort=file_size&order=desc&limit=10", nil)
+	w := httptest.NewRecorder()
+	handler.ListRecordings(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+
+	for _, query := range []string{"?sort=storage_path", "?sort=start_time&order=sideways", "?start_time=yesterday"} {
+		w := httptest.NewRecorder()
+		handler.ListRecordings(w, httptest.NewRequest(http.MethodGet, "/api/v1/recordings"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
 func TestRecordingHandler_GetRecording(t *testing.T) {
 	mockService := new(MockRecordingService)
 	handler := NewRecordingHandler(mockService)
@@ -235,6 +317,103 @@ func TestRecordingHandler_SearchRecordings(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestRecordingHandler_CreateClip(t *testing.T) {
+	start := time.Date(2025, 10, 27, 12, 0, 30, 0, time.UTC)
+	end := start.Add(105 * time.Second)
+	clipRequest := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recordings/clips", bytes.NewReader([]byte(body)))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+	validBody := `{"camera_id":"cam-123","start_time":"2025-10-27T12:00:30Z","end_time":"2025-10-27T12:02:15Z"}`
+
+	t.Run("accepted", func(t *testing.T) {
+		mockService := new(MockRecordingService)
+		want := &models.CreateClipRequest{CameraID: "cam-123", StartTime: start, EndTime: end}
+		mockService.On("StartClip", mock.Anything, want).
+			Return(&service.ClipJob{ID: "job-1", CameraID: "cam-123", State: service.ClipJobRunning}, nil)
+
+		w := httptest.NewRecorder()
+		NewRecordingHandler(mockService).CreateClip(w, clipRequest(validBody))
+
+		assert.Equal(t, http.StatusAccepted, w.Code)
+		assert.Equal(t, "/api/v1/recordings/clips/job-1", w.Header().Get("Location"))
+		assert.Contains(t, w.Body.String(), `"state":"running"`)
+		mockService.AssertExpectations(t)
+	})
+
+	invalid := []struct {
+		name string
+		body string
+	}{
+		{"invalid json", "{"},
+		{"missing camera", `{"start_time":"2025-10-27T12:00:30Z","end_time":"2025-10-27T12:02:15Z"}`},
+		{"end before start", `{"camera_id":"cam-123","start_time":"2025-10-27T12:02:15Z","end_time":"2025-10-27T12:00:30Z"}`},
+		{"too long", `{"camera_id":"cam-123","start_time":"2025-10-27T12:00:00Z","end_time":"2025-10-27T13:00:00Z"}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			w := httptest.NewRecorder()
+			NewRecordingHandler(mockService).CreateClip(w, clipRequest(tt.body))
+
+			assert.Equal(t, http.StatusBadRequest, w.Code)
+			mockService.AssertNotCalled(t, "StartClip", mock.Anything, mock.Anything)
+		})
+	}
+
+	failures := []struct {
+		err      error
+		wantCode int
+		wantErr  string
+	}{
+		{service.ErrClipExportDisabled, http.StatusServiceUnavailable, "CLIP_EXPORT_DISABLED"},
+		{fmt.Errorf("%w: no recording", service.ErrClipNotCovered), http.StatusUnprocessableEntity, "RECORDINGS_INCOMPLETE"},
+		{service.ErrCameraOffline, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE"},
+		{fmt.Errorf("ffmpeg failed"), http.StatusBadGateway, "CLIP_FAILED"},
+	}
+	for _, tt := range failures {
+		t.Run(tt.wantErr, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			mockService.On("StartClip", mock.Anything, mock.Anything).Return(nil, tt.err)
+
+			w := httptest.NewRecorder()
+			NewRecordingHandler(mockService).CreateClip(w, clipRequest(validBody))
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantErr)
+		})
+	}
+}
+
+func TestRecordingHandler_GetClipJob(t *testing.T) {
+	mockService := new(MockRecordingService)
+	mockService.On("GetClipJob", "job-1").Return(&service.ClipJob{
+		ID:        "job-1",
+		State:     service.ClipJobDone,
+		Recording: &models.Recording{ID: "clip-1", RecordingType: models.RecordingClip},
+	}, nil)
+	mockService.On("GetClipJob", "job-2").Return(nil, service.ErrClipJobNotFound)
+	handler := NewRecordingHandler(mockService)
+
+	getJob := func(id string) *httptest.ResponseRecorder {
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recordings/clips/"+id, nil)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		w := httptest.NewRecorder()
+		handler.GetClipJob(w, req)
+		return w
+	}
+
+	w := getJob("job-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"done"`)
+	assert.Contains(t, w.Body.String(), "clip-1")
+
+	assert.Equal(t, http.StatusNotFound, getJob("job-2").Code)
+}
+
 func TestRecordingHandler_SearchRecordings_InvalidJSON(t *testing.T) {
 	mockService := new(MockRecordingService)
 	handler := NewRecordingHandler(mockService)
@@ -268,6 +447,56 @@ func TestRecordingHandler_DeleteRecording(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestRecordingHandler_DeleteRecordings(t *testing.T) {
+	mockService := new(MockRecordingService)
+	handler := NewRecordingHandler(mockService)
+
+	ids := []string{"11111111-1111-1111-1111-111111111111", "22222222-2222-2222-2222-222222222222", "bogus"}
+	result := models.NewBulkResult()
+	result.Succeed(ids[0])
+	result.Fail(ids[1], models.BulkReasonNotFound)
+	result.Fail(ids[2], models.BulkReasonInvalidID)
+	mockService.On("DeleteRecordings", mock.Anything, ids).Return(result)
+
+	body, _ := json.Marshal(models.BulkRequest{IDs: ids})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/recordings/delete", bytes.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.DeleteRecordings(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response struct {
+		Data models.BulkResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, []string{ids[0]}, response.Data.Succeeded)
+	assert.Equal(t, []models.BulkFailure{
+		{ID: ids[1], Reason: "not_found"},
+		{ID: ids[2], Reason: "invalid_id"},
+	}, response.Data.Failed)
+	mockService.AssertExpectations(t)
+}
+
+func TestRecordingHandler_DeleteRecordings_InvalidRequest(t *testing.T) {
+	handler := NewRecordingHandler(new(MockRecordingService))
+
+	tooMany := make([]string, models.MaxBulkIDs+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("rec-%d", i)
+	}
+	tooManyBody, _ := json.Marshal(models.BulkRequest{IDs: tooMany})
+
+	for name, body := range map[string]string{
+		"invalid json": "{",
+		"no ids":       `{"ids": []}`,
+		"too many ids": string(tooManyBody),
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recordings/delete", strings.NewReader(body))
+		w := httptest.NewRecorder()
+		handler.DeleteRecordings(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code, name)
+	}
+}
+
 func TestRecordingHandler_DownloadRecording(t *testing.T) {
 	mockService := new(MockRecordingService)
 	handler := NewRecordingHandler(mockService)
@@ -367,3 +596,188 @@ func TestRecordingHandler_DownloadRecording_CameraUnavailable(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "Failed to generate download information")
 	mockService.AssertExpectations(t)
 }
+
+func TestRecordingHandler_StreamRecording(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	recording := &models.Recording{
+		ID:       "rec-123",
+		CameraID: "cam-123",
+		FileName: "Mp4Record/2025-10-27/RecM01_20251027_100000_100500.mp4",
+		FileSize: int64(len(data)),
+	}
+
+	tests := []struct {
+		name         string
+		rangeHeader  string
+		offset       int64
+		wantStatus   int
+		wantBody     string
+		contentRange string
+	}{
+		{"full file", "", 0, http.StatusOK, string(data), ""},
+		{"closed range", "bytes=5-9", 5, http.StatusPartialContent, "56789", "bytes 5-9/20"},
+		{"open range", "bytes=15-", 15, http.StatusPartialContent, "fghij", "bytes 15-19/20"},
+		{"suffix range", "bytes=-3", 17, http.StatusPartialContent, "hij", "bytes 17-19/20"},
+		{"range past end is clamped", "bytes=18-100", 18, http.StatusPartialContent, "ij", "bytes 18-19/20"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			handler := NewRecordingHandler(mockService)
+
+			mockService.On("GetRecording", mock.Anything, "rec-123").Return(recording, nil)
+			mockService.On("OpenRecordingStream", mock.Anything, recording, tt.offset).
+				Return(io.NopCloser(bytes.NewReader(data[tt.offset:])), nil)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/recordings/rec-123/stream", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "rec-123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.StreamRecording(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantBody, w.Body.String())
+			assert.Equal(t, tt.contentRange, w.Header().Get("Content-Range"))
+			assert.Equal(t, fmt.Sprint(len(tt.wantBody)), w.Header().Get("Content-Length"))
+			assert.Equal(t, "video/mp4", w.Header().Get("Content-Type"))
+			assert.Equal(t, `attachment; filename="RecM01_20251027_100000_100500.mp4"`, w.Header().Get("Content-Disposition"))
+			mockService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRecordingHandler_StreamRecording_Errors(t *testing.T) {
+	recording := &models.Recording{ID: "rec-123", CameraID: "cam-123", FileName: "rec.mp4", FileSize: 20}
+
+	tests := []struct {
+		name        string
+		rangeHeader string
+		streamErr   error
+		wantStatus  int
+	}{
+		{"unsatisfiable range", "bytes=20-", nil, http.StatusRequestedRangeNotSatisfiable},
+		{"multiple ranges", "bytes=0-1,5-6", nil, http.StatusRequestedRangeNotSatisfiable},
+		{"camera offline", "", fmt.Errorf("%w: camera cam-123", service.ErrCameraOffline), http.StatusServiceUnavailable},
+		{"camera error", "", fmt.Errorf("camera returned status 404"), http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			handler := NewRecordingHandler(mockService)
+
+			mockService.On("GetRecording", mock.Anything, "rec-123").Return(recording, nil)
+			mockService.On("OpenRecordingStream", mock.Anything, recording, int64(0)).Return(nil, tt.streamErr)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/recordings/rec-123/stream", nil)
+			if tt.rangeHeader != "" {
+				req.Header.Set("Range", tt.rangeHeader)
+			}
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "rec-123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			handler.StreamRecording(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestRecordingHandler_GetThumbnail(t *testing.T) {
+	recording := &models.Recording{ID: "rec-123", ThumbnailURL: "/api/v1/recordings/rec-123/thumbnail"}
+
+	tests := []struct {
+		name       string
+		thumbnail  io.ReadCloser
+		err        error
+		wantStatus int
+	}{
+		{"thumbnail", io.NopCloser(strings.NewReader("jpeg")), nil, http.StatusOK},
+		{"no thumbnail", nil, service.ErrThumbnailNotFound, http.StatusNotFound},
+		{"read error", nil, errors.New("permission denied"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			mockService.On("GetRecording", mock.Anything, "rec-123").Return(recording, nil)
+			mockService.On("OpenThumbnail", recording).Return(tt.thumbnail, tt.err)
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/recordings/rec-123/thumbnail", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "rec-123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			NewRecordingHandler(mockService).GetThumbnail(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+				assert.Equal(t, "jpeg", w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRecordingHandler_GetFrame(t *testing.T) {
+	start := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	recording := &models.Recording{ID: "rec-123", CameraID: "cam-123", StartTime: start, EndTime: start.Add(time.Minute), Duration: 60}
+	outOfRange := fmt.Errorf("%w: 1m30s not within 0s-1m0s", service.ErrFrameOutOfRange)
+
+	tests := []struct {
+		name       string
+		query      string
+		offset     time.Duration
+		frame      []byte
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"offset", "?offset=12.5", 12500 * time.Millisecond, []byte("jpeg"), nil, http.StatusOK, ""},
+		{"time", "?at=2025-10-27T12:00:30Z", 30 * time.Second, []byte("jpeg"), nil, http.StatusOK, ""},
+		{"out of range", "?offset=90", 90 * time.Second, nil, outOfRange, http.StatusBadRequest, "OFFSET_OUT_OF_RANGE"},
+		{"camera offline", "?offset=1", time.Second, nil, service.ErrCameraOffline, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE"},
+		{"ffmpeg error", "?offset=1", time.Second, nil, errors.New("ffmpeg failed"), http.StatusBadGateway, "FRAME_FAILED"},
+		{"invalid offset", "?offset=soon", 0, nil, nil, http.StatusBadRequest, ""},
+		{"invalid time", "?at=noon", 0, nil, nil, http.StatusBadRequest, ""},
+		{"no offset", "", 0, nil, nil, http.StatusBadRequest, ""},
+		{"offset and time", "?offset=1&at=2025-10-27T12:00:30Z", 0, nil, nil, http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockRecordingService)
+			mockService.On("GetRecording", mock.Anything, "rec-123").Return(recording, nil).Maybe()
+			if tt.frame != nil || tt.err != nil {
+				mockService.On("ExtractFrame", mock.Anything, recording, tt.offset).Return(tt.frame, tt.err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/recordings/rec-123/frame"+tt.query, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "rec-123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			w := httptest.NewRecorder()
+
+			NewRecordingHandler(mockService).GetFrame(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "image/jpeg", w.Header().Get("Content-Type"))
+				assert.Equal(t, "jpeg", w.Body.String())
+			}
+			if tt.wantCode != "" {
+				assert.Contains(t, w.Body.String(), tt.wantCode)
+			}
+			mockService.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/mosleyit/reolink_server/internal/storage/retention"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// RetentionCleaner defines the interface for retention runs
+type RetentionCleaner interface {
+	Run(ctx context.Context, policy retention.Policy, dryRun bool) (*retention.Report, error)
+}
+
+// RetentionHandler handles data retention requests
+type RetentionHandler struct {
+	cleaner       RetentionCleaner
+	eventDays     int
+	recordingDays int
+}
+
+// NewRetentionHandler creates a new retention handler. eventDays and
+// recordingDays are the retention periods previewed when a request names no cutoff.
+func NewRetentionHandler(cleaner RetentionCleaner, eventDays, recordingDays int) *RetentionHandler {
+	return &RetentionHandler{
+		cleaner:       cleaner,
+		eventDays:     eventDays,
+		recordingDays: recordingDays,
+	}
+}
+
+// PreviewRetention handles GET /api/v1/admin/retention/preview
+// It reports how many events and recordings (and how many bytes) a retention
+// run with the given cutoff would delete, without deleting anything.
+func (h *RetentionHandler) PreviewRetention(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	query := r.URL.Query()
+
+	var policy retention.Policy
+	switch {
+	case query.Get("cutoff") != "":
+		cutoff, err := time.Parse(time.RFC3339, query.Get("cutoff"))
+		if err != nil {
+			utils.RespondBadRequest(w, "Invalid cutoff. Use RFC3339 format (e.g., 2025-10-27T10:00:00Z)", nil)
+			return
+		}
+		policy = retention.Policy{EventsBefore: cutoff, RecordingsBefore: cutoff}
+	case query.Get("older_than_days") != "":
+		days, err := strconv.Atoi(query.Get("older_than_days"))
+		if err != nil || days <= 0 {
+			utils.RespondBadRequest(w, "older_than_days must be a positive number", nil)
+			return
+		}
+		policy = retention.PolicyForDays(time.Now(), days, days)
+	case h.eventDays > 0 || h.recordingDays > 0:
+		policy = retention.PolicyForDays(time.Now(), h.eventDays, h.recordingDays)
+	default:
+		utils.RespondBadRequest(w, "No retention period configured; pass cutoff or older_than_days", nil)
+		return
+	}
+
+	report, err := h.cleaner.Run(ctx, policy, true)
+	if err != nil {
+		utils.RespondError(w, http.StatusInternalServerError, "DATABASE_ERROR", "Failed to preview retention", err)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, report)
+}
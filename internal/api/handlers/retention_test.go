@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/retention"
+)
+
+// MockRetentionCleaner is a mock implementation of RetentionCleaner
+type MockRetentionCleaner struct {
+	mock.Mock
+}
+
+func (m *MockRetentionCleaner) Run(ctx context.Context, policy retention.Policy, dryRun bool) (*retention.Report, error) {
+	args := m.Called(ctx, policy, dryRun)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*retention.Report), args.Error(1)
+}
+
+func TestRetentionHandler_PreviewRetention_Cutoff(t *testing.T) {
+	cleaner := new(MockRetentionCleaner)
+	handler := NewRetentionHandler(cleaner, 90, 90)
+
+	cutoff := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	report := &retention.Report{DryRun: true, Events: 1200, Recordings: 14, RecordingBytes: 7340032}
+	cleaner.On("Run", mock.Anything, retention.Policy{EventsBefore: cutoff, RecordingsBefore: cutoff}, true).Return(report, nil)
+
+	w := httptest.NewRecorder()
+	handler.PreviewRetention(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/retention/preview?cutoff=2025-01-01T00:00:00Z", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"events":1200`)
+	assert.Contains(t, w.Body.String(), `"recording_bytes":7340032`)
+	cleaner.AssertExpectations(t)
+}
+
+func TestRetentionHandler_PreviewRetention_DefaultDays(t *testing.T) {
+	cleaner := new(MockRetentionCleaner)
+	handler := NewRetentionHandler(cleaner, 30, 30)
+
+	cleaner.On("Run", mock.Anything, mock.MatchedBy(func(p retention.Policy) bool {
+		age := time.Since(p.EventsBefore)
+		return age > 29*24*time.Hour && age < 31*24*time.Hour && p.EventsBefore.Equal(p.RecordingsBefore)
+	}), true).Return(&retention.Report{DryRun: true}, nil)
+
+	w := httptest.NewRecorder()
+	handler.PreviewRetention(w, httptest.NewRequest(http.MethodGet, "/api/v1/admin/retention/preview", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	cleaner.AssertExpectations(t)
+}
+
+func TestRetentionHandler_PreviewRetention_InvalidParams(t *testing.T) {
+	handler := NewRetentionHandler(new(MockRetentionCleaner), 0, 0)
+
+	for _, target := range []string{
+		"/api/v1/admin/retention/preview",
+		"/api/v1/admin/retention/preview?cutoff=last-year",
+		"/api/v1/admin/retention/preview?older_than_days=0",
+	} {
+		w := httptest.NewRecorder()
+		handler.PreviewRetention(w, httptest.NewRequest(http.MethodGet, target, nil))
+		assert.Equal(t, http.StatusBadRequest, w.Code, target)
+	}
+}
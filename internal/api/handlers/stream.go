@@ -2,17 +2,23 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/pkg/utils"
 )
@@ -21,11 +27,28 @@ import (
 type StreamServiceInterface interface {
 	ProxyFLVStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, w io.Writer) error
 	StartHLSStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int) (*service.StreamSession, error)
+	StartPreviewStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int) (*service.StreamSession, error)
+	StartWebRTCStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, offer string) (*service.StreamSession, string, error)
 	GetHLSPlaylist(sessionID string) (string, error)
 	GetHLSSegment(sessionID, segmentName string) (string, error)
 	StopSession(sessionID string) error
+	SnapshotStream(ctx context.Context, cameraID string, channel, fps int, onFrame func(jpeg []byte) error) error
+	FindOrphanedFFmpeg() ([]service.FFmpegProcess, error)
+	StopOrphanedFFmpeg() ([]service.FFmpegProcess, error)
 }
 
+// MJPEG stream frame rates
+const (
+	defaultMJPEGFPS = 2
+	maxMJPEGFPS     = 10
+)
+
+// mjpegBoundary separates the frames of an MJPEG response
+const mjpegBoundary = "frame"
+
+// maxSDPSize bounds the SDP offer read from a request
+const maxSDPSize = 64 << 10
+
 // StreamHandler handles streaming-related HTTP requests
 type StreamHandler struct {
 	streamService StreamServiceInterface
@@ -89,6 +112,99 @@ func (h *StreamHandler) ProxyFLV(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// StreamMJPEG handles GET /api/v1/cameras/{id}/stream/mjpeg
+// It streams camera snapshots as multipart/x-mixed-replace, which browsers
+// render directly in an <img> tag. Errors after the first frame end the
+// stream with a text/plain error part.
+func (h *StreamHandler) StreamMJPEG(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	if cameraID == "" {
+		utils.RespondBadRequest(w, "Camera ID is required", nil)
+		return
+	}
+
+	fps := defaultMJPEGFPS
+	if fpsStr := r.URL.Query().Get("fps"); fpsStr != "" {
+		parsed, err := strconv.Atoi(fpsStr)
+		if err != nil || parsed <= 0 {
+			utils.RespondBadRequest(w, "fps must be a positive number", map[string]interface{}{"fps": fpsStr})
+			return
+		}
+		fps = min(parsed, maxMJPEGFPS)
+	}
+
+	// Get channel from query parameter (default to 0)
+	channelStr := r.URL.Query().Get("channel")
+	channel := 0
+	if channelStr != "" {
+		if c, err := strconv.Atoi(channelStr); err == nil {
+			channel = c
+		}
+	}
+
+	logger.Info("Starting MJPEG stream",
+		zap.String("camera_id", cameraID),
+		zap.Int("channel", channel),
+		zap.Int("fps", fps))
+
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	flusher, _ := w.(http.Flusher)
+	started := false
+	err := h.streamService.SnapshotStream(ctx, cameraID, channel, fps, func(jpeg []byte) error {
+		if !started {
+			w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+			w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate")
+			w.Header().Set("Pragma", "no-cache")
+			w.Header().Set("Expires", "0")
+			started = true
+		}
+
+		if _, err := fmt.Fprintf(w, "--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\n\r\n", mjpegBoundary, len(jpeg)); err != nil {
+			return err
+		}
+		if _, err := w.Write(jpeg); err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, "\r\n"); err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	logger.Error("MJPEG stream ended with error",
+		zap.String("camera_id", cameraID),
+		zap.Error(err))
+
+	if !started {
+		switch {
+		case errors.Is(err, service.ErrCameraNotFound):
+			utils.RespondNotFound(w, "Camera not found")
+		case errors.Is(err, camera.ErrCircuitOpen), errors.Is(err, camera.ErrCameraOffline):
+			utils.RespondError(w, http.StatusServiceUnavailable, "CAMERA_UNAVAILABLE", "Camera is unavailable", nil)
+		default:
+			utils.RespondError(w, http.StatusBadGateway, "STREAM_FAILED", "Failed to get snapshot", nil)
+		}
+		return
+	}
+
+	// Headers are already sent; end the stream with an error part the client can show
+	message := "stream ended: " + err.Error()
+	fmt.Fprintf(w, "--%s\r\nContent-Type: text/plain\r\nContent-Length: %d\r\n\r\n%s\r\n--%s--\r\n", mjpegBoundary, len(message), message, mjpegBoundary)
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
 // StartHLS handles POST /api/v1/cameras/{id}/stream/hls
 func (h *StreamHandler) StartHLS(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -144,6 +260,165 @@ func (h *StreamHandler) StartHLS(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// StartPreview handles POST /api/v1/cameras/{id}/stream/preview/start
+func (h *StreamHandler) StartPreview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	if cameraID == "" {
+		utils.RespondBadRequest(w, "Camera ID is required", nil)
+		return
+	}
+
+	// Previews use the sub stream unless the ext stream is explicitly requested
+	streamTypeStr := "sub"
+	streamType := reolink.StreamSub
+	if r.URL.Query().Get("stream") == "ext" {
+		streamTypeStr = "ext"
+		streamType = reolink.StreamExt
+	}
+
+	// Get channel from query parameter (default to 0)
+	channelStr := r.URL.Query().Get("channel")
+	channel := 0
+	if channelStr != "" {
+		if c, err := strconv.Atoi(channelStr); err == nil {
+			channel = c
+		}
+	}
+
+	logger.Info("Starting HLS preview session",
+		zap.String("camera_id", cameraID),
+		zap.String("stream_type", streamTypeStr),
+		zap.Int("channel", channel))
+
+	session, err := h.streamService.StartPreviewStream(ctx, cameraID, streamType, channel)
+	if err != nil {
+		logger.Error("Failed to start HLS preview session",
+			zap.String("camera_id", cameraID),
+			zap.Error(err))
+		utils.RespondInternalError(w, "Failed to start preview stream")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id":   session.ID,
+		"camera_id":    session.CameraID,
+		"stream_type":  streamTypeStr,
+		"channel":      channel,
+		"preview":      true,
+		"playlist_url": "/api/v1/stream/hls/" + session.ID + "/playlist.m3u8",
+		"started_at":   session.StartedAt,
+		"expires_at":   session.ExpiresAt,
+	})
+}
+
+// WebRTCOffer handles POST /api/v1/cameras/{id}/stream/webrtc/offer
+//
+// The offer is either a JSON session description ({"type":"offer","sdp":"..."})
+// answered in kind, or a raw application/sdp body answered with the raw SDP
+// answer and the session URL in the Location header.
+func (h *StreamHandler) WebRTCOffer(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	cameraID := chi.URLParam(r, "id")
+
+	if cameraID == "" {
+		utils.RespondBadRequest(w, "Camera ID is required", nil)
+		return
+	}
+
+	// Get stream type from query parameter (default to main stream)
+	streamTypeStr := r.URL.Query().Get("stream")
+	streamType := reolink.StreamMain
+	switch streamTypeStr {
+	case "sub":
+		streamType = reolink.StreamSub
+	case "ext":
+		streamType = reolink.StreamExt
+	}
+
+	// Get channel from query parameter (default to 0)
+	channelStr := r.URL.Query().Get("channel")
+	channel := 0
+	if channelStr != "" {
+		if c, err := strconv.Atoi(channelStr); err == nil {
+			channel = c
+		}
+	}
+
+	rawSDP := strings.HasPrefix(r.Header.Get("Content-Type"), "application/sdp")
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxSDPSize))
+	if err != nil {
+		utils.RespondBadRequest(w, "Failed to read SDP offer", nil)
+		return
+	}
+
+	offer := string(body)
+	if !rawSDP {
+		var desc struct {
+			Type string `json:"type"`
+			SDP  string `json:"sdp"`
+		}
+		if err := json.Unmarshal(body, &desc); err != nil {
+			utils.RespondBadRequest(w, "Invalid request body", err.Error())
+			return
+		}
+		if desc.Type != "" && desc.Type != "offer" {
+			utils.RespondBadRequest(w, "Session description must be an offer", nil)
+			return
+		}
+		offer = desc.SDP
+	}
+	if strings.TrimSpace(offer) == "" {
+		utils.RespondBadRequest(w, "SDP offer is required", nil)
+		return
+	}
+
+	logger.Info("Starting WebRTC session",
+		zap.String("camera_id", cameraID),
+		zap.String("stream_type", streamTypeStr),
+		zap.Int("channel", channel))
+
+	session, answer, err := h.streamService.StartWebRTCStream(ctx, cameraID, streamType, channel, offer)
+	if err != nil {
+		logger.Error("Failed to start WebRTC session",
+			zap.String("camera_id", cameraID),
+			zap.Error(err))
+		switch {
+		case errors.Is(err, service.ErrCameraNotFound):
+			utils.RespondNotFound(w, "Camera not found")
+		case errors.Is(err, service.ErrWebRTCUnavailable):
+			utils.RespondError(w, http.StatusServiceUnavailable, "WEBRTC_UNAVAILABLE", "WebRTC streaming is not configured", nil)
+		case errors.Is(err, service.ErrSessionStartupFailed):
+			utils.RespondError(w, http.StatusBadGateway, "STREAM_FAILED", "Stream failed to start", nil)
+		default:
+			utils.RespondInternalError(w, "Failed to start WebRTC stream")
+		}
+		return
+	}
+
+	sessionURL := "/api/v1/stream/webrtc/" + session.ID
+	if rawSDP {
+		w.Header().Set("Content-Type", "application/sdp")
+		w.Header().Set("Location", sessionURL)
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, answer)
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"session_id":  session.ID,
+		"camera_id":   session.CameraID,
+		"stream_type": streamTypeStr,
+		"channel":     channel,
+		"type":        "answer",
+		"sdp":         answer,
+		"session_url": sessionURL,
+		"started_at":  session.StartedAt,
+		"expires_at":  session.ExpiresAt,
+	})
+}
+
 // GetHLSPlaylist handles GET /api/v1/stream/hls/{session_id}/playlist.m3u8
 func (h *StreamHandler) GetHLSPlaylist(w http.ResponseWriter, r *http.Request) {
 	sessionID := chi.URLParam(r, "session_id")
@@ -154,6 +429,10 @@ func (h *StreamHandler) GetHLSPlaylist(w http.ResponseWriter, r *http.Request) {
 	}
 
 	playlistPath, err := h.streamService.GetHLSPlaylist(sessionID)
+	if errors.Is(err, service.ErrSessionStartupFailed) {
+		utils.RespondError(w, http.StatusBadGateway, "STREAM_FAILED", "Stream failed to start", nil)
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get HLS playlist",
 			zap.String("session_id", sessionID),
@@ -236,3 +515,60 @@ func (h *StreamHandler) StopHLS(w http.ResponseWriter, r *http.Request) {
 		"message": "Session stopped successfully",
 	})
 }
+
+// StopWebRTC handles DELETE /api/v1/stream/webrtc/{session_id}
+func (h *StreamHandler) StopWebRTC(w http.ResponseWriter, r *http.Request) {
+	sessionID := chi.URLParam(r, "session_id")
+
+	if sessionID == "" {
+		utils.RespondBadRequest(w, "Session ID is required", nil)
+		return
+	}
+
+	if err := h.streamService.StopSession(sessionID); err != nil {
+		logger.Error("Failed to stop WebRTC session",
+			zap.String("session_id", sessionID),
+			zap.Error(err))
+		utils.RespondNotFound(w, "Session not found")
+		return
+	}
+
+	logger.Info("Stopped WebRTC session",
+		zap.String("session_id", sessionID))
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"message": "Session stopped successfully",
+	})
+}
+
+// ListOrphanedFFmpeg handles GET /api/v1/admin/ffmpeg/orphans
+// It lists FFmpeg processes working on HLS session directories that this
+// server did not start, e.g. ones left running by a crashed previous run.
+func (h *StreamHandler) ListOrphanedFFmpeg(w http.ResponseWriter, r *http.Request) {
+	orphans, err := h.streamService.FindOrphanedFFmpeg()
+	if err != nil {
+		logger.Error("Failed to look for orphaned FFmpeg processes", zap.Error(err))
+		utils.RespondInternalError(w, "Failed to look for orphaned FFmpeg processes")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"processes": orphans,
+		"count":     len(orphans),
+	})
+}
+
+// StopOrphanedFFmpeg handles DELETE /api/v1/admin/ffmpeg/orphans
+func (h *StreamHandler) StopOrphanedFFmpeg(w http.ResponseWriter, r *http.Request) {
+	stopped, err := h.streamService.StopOrphanedFFmpeg()
+	if err != nil {
+		logger.Error("Failed to stop orphaned FFmpeg processes", zap.Error(err))
+		utils.RespondInternalError(w, "Failed to stop orphaned FFmpeg processes")
+		return
+	}
+
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"stopped": stopped,
+		"count":   len(stopped),
+	})
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/camera"
 )
 
 // MockStreamService is a mock implementation of StreamServiceInterface
@@ -36,6 +40,22 @@ func (m *MockStreamService) StartHLSStream(ctx context.Context, cameraID string,
 	return args.Get(0).(*service.StreamSession), args.Error(1)
 }
 
+func (m *MockStreamService) StartPreviewStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int) (*service.StreamSession, error) {
+	args := m.Called(ctx, cameraID, streamType, channel)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*service.StreamSession), args.Error(1)
+}
+
+func (m *MockStreamService) StartWebRTCStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, offer string) (*service.StreamSession, string, error) {
+	args := m.Called(ctx, cameraID, streamType, channel, offer)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*service.StreamSession), args.String(1), args.Error(2)
+}
+
 func (m *MockStreamService) GetHLSPlaylist(sessionID string) (string, error) {
 	args := m.Called(sessionID)
 	return args.String(0), args.Error(1)
@@ -51,6 +71,27 @@ func (m *MockStreamService) StopSession(sessionID string) error {
 	return args.Error(0)
 }
 
+func (m *MockStreamService) SnapshotStream(ctx context.Context, cameraID string, channel, fps int, onFrame func(jpeg []byte) error) error {
+	args := m.Called(ctx, cameraID, channel, fps, onFrame)
+	return args.Error(0)
+}
+
+func (m *MockStreamService) FindOrphanedFFmpeg() ([]service.FFmpegProcess, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.FFmpegProcess), args.Error(1)
+}
+
+func (m *MockStreamService) StopOrphanedFFmpeg() ([]service.FFmpegProcess, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]service.FFmpegProcess), args.Error(1)
+}
+
 func TestNewStreamHandler(t *testing.T) {
 	mockService := new(MockStreamService)
 	handler := NewStreamHandler(mockService)
@@ -214,6 +255,26 @@ func TestStreamHandler_GetHLSPlaylist_SessionNotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestStreamHandler_GetHLSPlaylist_StartupFailed(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("GetHLSPlaylist", "session-123").Return("", service.ErrSessionStartupFailed)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/stream/hls/session-123/playlist.m3u8", nil)
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("session_id", "session-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.GetHLSPlaylist(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "STREAM_FAILED")
+	mockService.AssertExpectations(t)
+}
+
 func TestStreamHandler_GetHLSPlaylist_FileNotReady(t *testing.T) {
 	mockService := new(MockStreamService)
 	handler := NewStreamHandler(mockService)
@@ -352,3 +413,332 @@ func TestStreamHandler_StopHLS_SessionNotFound(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+
+func TestStreamHandler_StartPreview_ForcesSubStream(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	session := &service.StreamSession{
+		ID:         "preview-123",
+		CameraID:   "cam-123",
+		StreamType: service.StreamTypeHLS,
+		Preview:    true,
+		StartedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(30 * time.Minute),
+	}
+
+	mockService.On("StartPreviewStream", mock.Anything, "cam-123", reolink.StreamSub, 0).Return(session, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/preview/start?stream=main", nil)
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "cam-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.StartPreview(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "preview-123")
+	assert.Contains(t, w.Body.String(), `"stream_type":"sub"`)
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_StartPreview_ServiceError(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("StartPreviewStream", mock.Anything, "cam-123", reolink.StreamExt, 0).Return(nil, assert.AnError)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/preview/start?stream=ext", nil)
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "cam-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.StartPreview(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+// newMJPEGRequest builds an MJPEG stream request for a camera
+func newMJPEGRequest(cameraID, query string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cameras/"+cameraID+"/stream/mjpeg"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", cameraID)
+	return req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+}
+
+// sendFrames makes a mocked SnapshotStream deliver frames before returning
+func sendFrames(frames ...string) func(args mock.Arguments) {
+	return func(args mock.Arguments) {
+		onFrame := args.Get(4).(func([]byte) error)
+		for _, frame := range frames {
+			onFrame([]byte(frame))
+		}
+	}
+}
+
+func TestStreamHandler_StreamMJPEG_WritesFrames(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("SnapshotStream", mock.Anything, "cam-123", 1, 2, mock.Anything).
+		Run(sendFrames("jpeg-1", "jpeg-2")).
+		Return(nil)
+
+	w := httptest.NewRecorder()
+	handler.StreamMJPEG(w, newMJPEGRequest("cam-123", "?channel=1"))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "multipart/x-mixed-replace; boundary=frame", w.Header().Get("Content-Type"))
+	assert.Equal(t,
+		"--frame\r\nContent-Type: image/jpeg\r\nContent-Length: 6\r\n\r\njpeg-1\r\n"+
+			"--frame\r\nContent-Type: image/jpeg\r\nContent-Length: 6\r\n\r\njpeg-2\r\n",
+		w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_StreamMJPEG_FPS(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantFPS    int
+		wantStatus int
+	}{
+		{"capped", "?fps=30", 10, http.StatusOK},
+		{"custom", "?fps=5", 5, http.StatusOK},
+		{"invalid", "?fps=fast", 0, http.StatusBadRequest},
+		{"zero", "?fps=0", 0, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockStreamService)
+			handler := NewStreamHandler(mockService)
+			mockService.On("SnapshotStream", mock.Anything, "cam-123", 0, tt.wantFPS, mock.Anything).
+				Run(sendFrames("jpeg")).
+				Return(nil)
+
+			w := httptest.NewRecorder()
+			handler.StreamMJPEG(w, newMJPEGRequest("cam-123", tt.query))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				mockService.AssertNotCalled(t, "SnapshotStream", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestStreamHandler_StreamMJPEG_ErrorsBeforeFirstFrame(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+	}{
+		{"camera not found", fmt.Errorf("%w: camera cam-123 not found", service.ErrCameraNotFound), http.StatusNotFound},
+		{"circuit open", fmt.Errorf("%w for camera cam-123", camera.ErrCircuitOpen), http.StatusServiceUnavailable},
+		{"snapshot failed", errors.New("timeout"), http.StatusBadGateway},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockStreamService)
+			handler := NewStreamHandler(mockService)
+			mockService.On("SnapshotStream", mock.Anything, "cam-123", 0, 2, mock.Anything).Return(tt.err)
+
+			w := httptest.NewRecorder()
+			handler.StreamMJPEG(w, newMJPEGRequest("cam-123", ""))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestStreamHandler_StreamMJPEG_CircuitOpenEndsStream(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("SnapshotStream", mock.Anything, "cam-123", 0, 2, mock.Anything).
+		Run(sendFrames("jpeg-1")).
+		Return(fmt.Errorf("%w for camera cam-123", camera.ErrCircuitOpen))
+
+	w := httptest.NewRecorder()
+	handler.StreamMJPEG(w, newMJPEGRequest("cam-123", ""))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "jpeg-1")
+	assert.Contains(t, body, "Content-Type: text/plain")
+	assert.Contains(t, body, "stream ended: circuit open for camera cam-123")
+	assert.True(t, strings.HasSuffix(body, "--frame--\r\n"))
+}
+
+func TestStreamHandler_WebRTCOffer_JSON(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	session := &service.StreamSession{
+		ID:         "webrtc-123",
+		CameraID:   "cam-123",
+		StreamType: service.StreamTypeWebRTC,
+		StartedAt:  time.Now(),
+		ExpiresAt:  time.Now().Add(30 * time.Minute),
+	}
+	mockService.On("StartWebRTCStream", mock.Anything, "cam-123", reolink.StreamSub, 0, "v=0 offer").Return(session, "v=0 answer", nil)
+
+	body := strings.NewReader(`{"type":"offer","sdp":"v=0 offer"}`)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/webrtc/offer?stream=sub", body)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "cam-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.WebRTCOffer(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"sdp":"v=0 answer"`)
+	assert.Contains(t, w.Body.String(), `"type":"answer"`)
+	assert.Contains(t, w.Body.String(), "/api/v1/stream/webrtc/webrtc-123")
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_WebRTCOffer_RawSDP(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	session := &service.StreamSession{ID: "webrtc-123", CameraID: "cam-123", StreamType: service.StreamTypeWebRTC}
+	mockService.On("StartWebRTCStream", mock.Anything, "cam-123", reolink.StreamMain, 0, "v=0 offer").Return(session, "v=0 answer", nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/webrtc/offer", strings.NewReader("v=0 offer"))
+	req.Header.Set("Content-Type", "application/sdp")
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "cam-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.WebRTCOffer(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "application/sdp", w.Header().Get("Content-Type"))
+	assert.Equal(t, "/api/v1/stream/webrtc/webrtc-123", w.Header().Get("Location"))
+	assert.Equal(t, "v=0 answer", w.Body.String())
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_WebRTCOffer_MissingOffer(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/webrtc/offer", strings.NewReader(`{"type":"offer"}`))
+	w := httptest.NewRecorder()
+
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("id", "cam-123")
+	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+	handler.WebRTCOffer(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "SDP offer is required")
+	mockService.AssertNotCalled(t, "StartWebRTCStream")
+}
+
+func TestStreamHandler_WebRTCOffer_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		code int
+	}{
+		{"camera not found", fmt.Errorf("%w: unknown", service.ErrCameraNotFound), http.StatusNotFound},
+		{"not configured", service.ErrWebRTCUnavailable, http.StatusServiceUnavailable},
+		{"startup failed", fmt.Errorf("%w: timeout", service.ErrSessionStartupFailed), http.StatusBadGateway},
+		{"other", assert.AnError, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockStreamService)
+			handler := NewStreamHandler(mockService)
+			mockService.On("StartWebRTCStream", mock.Anything, "cam-123", reolink.StreamMain, 0, "v=0").Return(nil, "", tt.err)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-123/stream/webrtc/offer", strings.NewReader(`{"type":"offer","sdp":"v=0"}`))
+			w := httptest.NewRecorder()
+
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("id", "cam-123")
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+			handler.WebRTCOffer(w, req)
+
+			assert.Equal(t, tt.code, w.Code)
+		})
+	}
+}
+
+func TestStreamHandler_StopWebRTC(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("StopSession", "webrtc-123").Return(nil)
+	mockService.On("StopSession", "webrtc-999").Return(assert.AnError)
+
+	for sessionID, code := range map[string]int{"webrtc-123": http.StatusOK, "webrtc-999": http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/stream/webrtc/"+sessionID, nil)
+		w := httptest.NewRecorder()
+
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("session_id", sessionID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+
+		handler.StopWebRTC(w, req)
+
+		assert.Equal(t, code, w.Code)
+	}
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_ListOrphanedFFmpeg(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("FindOrphanedFFmpeg").Return([]service.FFmpegProcess{{PID: 100, ParentPID: 1, SessionID: "session-123"}}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/ffmpeg/orphans", nil)
+	w := httptest.NewRecorder()
+	handler.ListOrphanedFFmpeg(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"pid":100`)
+	assert.Contains(t, w.Body.String(), `"session_id":"session-123"`)
+	assert.Contains(t, w.Body.String(), `"count":1`)
+
+	mockService.On("FindOrphanedFFmpeg").Return(nil, assert.AnError).Once()
+
+	w = httptest.NewRecorder()
+	handler.ListOrphanedFFmpeg(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestStreamHandler_StopOrphanedFFmpeg(t *testing.T) {
+	mockService := new(MockStreamService)
+	handler := NewStreamHandler(mockService)
+
+	mockService.On("StopOrphanedFFmpeg").Return([]service.FFmpegProcess{{PID: 100, ParentPID: 1, SessionID: "session-123"}}, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/v1/admin/ffmpeg/orphans", nil)
+	w := httptest.NewRecorder()
+	handler.StopOrphanedFFmpeg(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"stopped":[{"pid":100`)
+	mockService.AssertExpectations(t)
+}
@@ -3,8 +3,10 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/coder/websocket"
@@ -14,24 +16,49 @@ import (
 
 	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/pkg/utils"
 )
 
 // EventStreamServiceInterface defines the interface for event streaming
 type EventStreamServiceInterface interface {
-	Subscribe(ctx context.Context, subscriberID string, cameraID string, bufferSize int) *service.EventStreamSubscriber
+	Subscribe(ctx context.Context, subscriberID string, filter service.EventStreamFilter, bufferSize int) *service.EventStreamSubscriber
+	SubscribeWithDelivery(ctx context.Context, subscriberID string, filter service.EventStreamFilter, bufferSize int, delivery service.EventDelivery) *service.EventStreamSubscriber
 	Unsubscribe(subscriberID string)
+	GetSubscriberStats() []service.SubscriberStats
 }
 
+const (
+	// DefaultWebSocketPingInterval is how often WebSocket clients are pinged
+	// to detect dead connections
+	DefaultWebSocketPingInterval = 30 * time.Second
+
+	// webSocketWriteTimeout bounds sending one message to a WebSocket client
+	webSocketWriteTimeout = 10 * time.Second
+
+	// eventStreamBufferSize is how many events a stream client may fall
+	// behind before events are dropped or held up for it
+	eventStreamBufferSize = 100
+
+	// maxEventBlockTimeout caps how long a blocking stream client may hold
+	// up events for every other client
+	maxEventBlockTimeout = 5 * time.Second
+)
+
 // EventStreamHandler handles WebSocket and SSE connections for events
 type EventStreamHandler struct {
 	streamService EventStreamServiceInterface
+
+	// pingInterval is how often WebSocket clients are pinged; a client that
+	// does not answer in time is disconnected
+	pingInterval time.Duration
 }
 
 // NewEventStreamHandler creates a new event stream handler
 func NewEventStreamHandler(streamService EventStreamServiceInterface) *EventStreamHandler {
 	return &EventStreamHandler{
 		streamService: streamService,
+		pingInterval:  DefaultWebSocketPingInterval,
 	}
 }
 
@@ -50,9 +77,21 @@ func (h *EventStreamHandler) WebSocketCameraEvents(w http.ResponseWriter, r *htt
 	h.handleWebSocket(w, r, cameraID)
 }
 
-// handleWebSocket handles WebSocket connections
+// handleWebSocket streams events to a WebSocket client as JSON text
+// messages, optionally only those of one camera. The client is pinged every
+// ping interval and unsubscribed once it disconnects or stops answering.
 func (h *EventStreamHandler) handleWebSocket(w http.ResponseWriter, r *http.Request, cameraID string) {
-	ctx := r.Context()
+	delivery, err := parseEventDelivery(r)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
+
+	// The server's read and write timeouts run from the start of the request
+	// and would otherwise cut off the connection; pings detect dead clients
+	controller := http.NewResponseController(w)
+	_ = controller.SetReadDeadline(time.Time{})
+	_ = controller.SetWriteDeadline(time.Time{})
 
 	// Upgrade to WebSocket
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
@@ -64,15 +103,26 @@ func (h *EventStreamHandler) handleWebSocket(w http.ResponseWriter, r *http.Requ
 	}
 	defer conn.Close(websocket.StatusNormalClosure, "Connection closed")
 
+	// Clients only listen; reading in the background answers their pings and
+	// closes, and cancels ctx once the connection is gone
+	ctx := conn.CloseRead(r.Context())
+
 	// Create subscriber
 	subscriberID := uuid.New().String()
-	subscriber := h.streamService.Subscribe(ctx, subscriberID, cameraID, 100)
+	subscriber := h.subscribe(ctx, subscriberID, service.EventStreamFilter{CameraID: cameraID}, delivery)
 	defer h.streamService.Unsubscribe(subscriberID)
 
 	logger.Info("WebSocket client connected",
 		zap.String("subscriber_id", subscriberID),
 		zap.String("camera_id", cameraID))
 
+	pingInterval := h.pingInterval
+	if pingInterval <= 0 {
+		pingInterval = DefaultWebSocketPingInterval
+	}
+	ticker := time.NewTicker(pingInterval)
+	defer ticker.Stop()
+
 	// Send events to client
 	for {
 		select {
@@ -89,23 +139,53 @@ func (h *EventStreamHandler) handleWebSocket(w http.ResponseWriter, r *http.Requ
 			}
 
 			// Send to client
-			err = conn.Write(ctx, websocket.MessageText, data)
+			writeCtx, cancel := context.WithTimeout(ctx, webSocketWriteTimeout)
+			err = conn.Write(writeCtx, websocket.MessageText, data)
+			cancel()
 			if err != nil {
 				logger.Error("Failed to send WebSocket message", zap.Error(err))
 				return
 			}
 
+		case <-ticker.C:
+			// A client that does not answer within the interval is gone
+			pingCtx, cancel := context.WithTimeout(ctx, pingInterval)
+			err := conn.Ping(pingCtx)
+			cancel()
+			if err != nil {
+				logger.Info("WebSocket client stopped answering pings",
+					zap.String("subscriber_id", subscriberID), zap.Error(err))
+				return
+			}
+
 		case <-ctx.Done():
-			logger.Info("WebSocket context cancelled", zap.String("subscriber_id", subscriberID))
+			logger.Info("WebSocket client disconnected", zap.String("subscriber_id", subscriberID))
 			return
 		}
 	}
 }
 
-// SSEEvents handles Server-Sent Events for all events
+// SSEEvents handles Server-Sent Events for all events. Clients can narrow
+// the stream with ?camera_id= and a comma separated ?types= list, and choose
+// how events are delivered when they fall behind with ?delivery=.
 func (h *EventStreamHandler) SSEEvents(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	filter, err := parseEventStreamFilter(r)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
+	delivery, err := parseEventDelivery(r)
+	if err != nil {
+		utils.RespondBadRequest(w, err.Error(), nil)
+		return
+	}
+
+	// The server's write timeout runs from the start of the request and
+	// would otherwise cut off the stream
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
 	// Set SSE headers
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -114,10 +194,13 @@ func (h *EventStreamHandler) SSEEvents(w http.ResponseWriter, r *http.Request) {
 
 	// Create subscriber
 	subscriberID := uuid.New().String()
-	subscriber := h.streamService.Subscribe(ctx, subscriberID, "", 100)
+	subscriber := h.subscribe(ctx, subscriberID, filter, delivery)
 	defer h.streamService.Unsubscribe(subscriberID)
 
-	logger.Info("SSE client connected", zap.String("subscriber_id", subscriberID))
+	logger.Info("SSE client connected",
+		zap.String("subscriber_id", subscriberID),
+		zap.String("camera_id", filter.CameraID),
+		zap.Any("types", filter.Types))
 
 	// Create flusher
 	flusher, ok := w.(http.Flusher)
@@ -166,6 +249,74 @@ func (h *EventStreamHandler) SSEEvents(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// parseEventStreamFilter reads the camera_id and types query parameters of an
+// event stream request
+func parseEventStreamFilter(r *http.Request) (service.EventStreamFilter, error) {
+	query := r.URL.Query()
+	filter := service.EventStreamFilter{CameraID: query.Get("camera_id")}
+
+	for _, value := range strings.Split(query.Get("types"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		eventType, err := models.ParseEventType(value)
+		if err != nil {
+			return filter, err
+		}
+		filter.Types = append(filter.Types, eventType)
+	}
+	return filter, nil
+}
+
+// ListSubscribers handles GET /api/v1/stream/subscribers, reporting how
+// events are delivered to each connected stream client
+func (h *EventStreamHandler) ListSubscribers(w http.ResponseWriter, r *http.Request) {
+	stats := h.streamService.GetSubscriberStats()
+	utils.RespondJSON(w, http.StatusOK, map[string]interface{}{
+		"subscribers": stats,
+		"total":       len(stats),
+	})
+}
+
+// subscribe subscribes a stream client to the events passing filter
+func (h *EventStreamHandler) subscribe(ctx context.Context, subscriberID string, filter service.EventStreamFilter, delivery service.EventDelivery) *service.EventStreamSubscriber {
+	if delivery == (service.EventDelivery{}) {
+		return h.streamService.Subscribe(ctx, subscriberID, filter, eventStreamBufferSize)
+	}
+	return h.streamService.SubscribeWithDelivery(ctx, subscriberID, filter, eventStreamBufferSize, delivery)
+}
+
+// parseEventDelivery reads the delivery and block_timeout query parameters
+// of an event stream request. Clients falling behind have events dropped
+// unless they ask for delivery=block, which waits up to block_timeout for
+// them to catch up.
+func parseEventDelivery(r *http.Request) (service.EventDelivery, error) {
+	query := r.URL.Query()
+
+	var delivery service.EventDelivery
+	switch query.Get("delivery") {
+	case "", "drop":
+		if query.Get("block_timeout") != "" {
+			return delivery, errors.New("block_timeout requires delivery=block")
+		}
+		return delivery, nil
+	case "block":
+		delivery.Block = true
+	default:
+		return delivery, fmt.Errorf("invalid delivery %q: must be drop or block", query.Get("delivery"))
+	}
+
+	if value := query.Get("block_timeout"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout <= 0 || timeout > maxEventBlockTimeout {
+			return delivery, fmt.Errorf("block_timeout must be a duration up to %s", maxEventBlockTimeout)
+		}
+		delivery.Timeout = timeout
+	}
+	return delivery, nil
+}
+
 // Legacy standalone functions for backward compatibility
 // These will be replaced by the handler methods in the router
 
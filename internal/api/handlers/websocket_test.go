@@ -2,15 +2,19 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/coder/websocket"
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
@@ -21,14 +25,27 @@ type MockEventStreamService struct {
 	mock.Mock
 }
 
-func (m *MockEventStreamService) Subscribe(ctx context.Context, subscriberID string, cameraID string, bufferSize int) *service.EventStreamSubscriber {
-	args := m.Called(ctx, subscriberID, cameraID, bufferSize)
+func (m *MockEventStreamService) Subscribe(ctx context.Context, subscriberID string, filter service.EventStreamFilter, bufferSize int) *service.EventStreamSubscriber {
+	args := m.Called(ctx, subscriberID, filter, bufferSize)
 	if args.Get(0) == nil {
 		return nil
 	}
 	return args.Get(0).(*service.EventStreamSubscriber)
 }
 
+func (m *MockEventStreamService) SubscribeWithDelivery(ctx context.Context, subscriberID string, filter service.EventStreamFilter, bufferSize int, delivery service.EventDelivery) *service.EventStreamSubscriber {
+	args := m.Called(ctx, subscriberID, filter, bufferSize, delivery)
+	if args.Get(0) == nil {
+		return nil
+	}
+	return args.Get(0).(*service.EventStreamSubscriber)
+}
+
+func (m *MockEventStreamService) GetSubscriberStats() []service.SubscriberStats {
+	args := m.Called()
+	return args.Get(0).([]service.SubscriberStats)
+}
+
 func (m *MockEventStreamService) Unsubscribe(subscriberID string) {
 	m.Called(subscriberID)
 }
@@ -61,12 +78,11 @@ func TestEventStreamHandler_SSEEvents(t *testing.T) {
 
 	eventCh := make(chan *models.Event, 10)
 	subscriber := &service.EventStreamSubscriber{
-		ID:       "test-sub",
-		CameraID: "",
-		EventCh:  eventCh,
+		ID:      "test-sub",
+		EventCh: eventCh,
 	}
 
-	mockService.On("Subscribe", mock.Anything, mock.Anything, "", 100).Return(subscriber)
+	mockService.On("Subscribe", mock.Anything, mock.Anything, service.EventStreamFilter{}, 100).Return(subscriber)
 	mockService.On("Unsubscribe", mock.Anything).Return()
 
 	// Create request
@@ -112,12 +128,11 @@ func TestEventStreamHandler_SSEEvents_Heartbeat(t *testing.T) {
 
 	eventCh := make(chan *models.Event, 10)
 	subscriber := &service.EventStreamSubscriber{
-		ID:       "test-sub",
-		CameraID: "",
-		EventCh:  eventCh,
+		ID:      "test-sub",
+		EventCh: eventCh,
 	}
 
-	mockService.On("Subscribe", mock.Anything, mock.Anything, "", 100).Return(subscriber)
+	mockService.On("Subscribe", mock.Anything, mock.Anything, service.EventStreamFilter{}, 100).Return(subscriber)
 	mockService.On("Unsubscribe", mock.Anything).Return()
 
 	req := httptest.NewRequest(http.MethodGet, "/sse/events", nil)
@@ -133,6 +148,133 @@ func TestEventStreamHandler_SSEEvents_Heartbeat(t *testing.T) {
 	mockService.AssertExpectations(t)
 }
 
+func TestEventStreamHandler_SSEEvents_Filter(t *testing.T) {
+	streamService := service.NewEventStreamService(service.NewProcessorAdapter(func(service.EventSubscriber) {}))
+	handler := NewEventStreamHandler(streamService)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/events?camera_id=cam-1&types=ai_person,%20ai_vehicle", nil)
+	req = req.WithContext(ctx)
+	w := httptest.NewRecorder()
+
+	go func() {
+		defer cancel()
+		if !assert.Eventually(t, func() bool { return streamService.GetSubscriberCount() == 1 }, time.Second, 5*time.Millisecond) {
+			return
+		}
+		events := []*models.Event{
+			{ID: "evt-motion", CameraID: "cam-1", Type: models.EventMotionDetected},
+			{ID: "evt-other-camera", CameraID: "cam-2", Type: models.EventAIPerson},
+			{ID: "evt-person", CameraID: "cam-1", Type: models.EventAIPerson},
+			{ID: "evt-vehicle", CameraID: "cam-1", Type: models.EventAIVehicle},
+		}
+		for _, event := range events {
+			streamService.OnEvent(event)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}()
+
+	handler.SSEEvents(w, req)
+
+	body := w.Body.String()
+	assert.Contains(t, body, "evt-person")
+	assert.Contains(t, body, "evt-vehicle")
+	assert.NotContains(t, body, "evt-motion")
+	assert.NotContains(t, body, "evt-other-camera")
+}
+
+func TestEventStreamHandler_SSEEvents_InvalidType(t *testing.T) {
+	mockService := new(MockEventStreamService)
+	handler := NewEventStreamHandler(mockService)
+
+	req := httptest.NewRequest(http.MethodGet, "/sse/events?types=ai_person,motion", nil)
+	w := httptest.NewRecorder()
+	handler.SSEEvents(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), `invalid event type \"motion\"`)
+	assert.Contains(t, w.Body.String(), "motion_detected", "the valid names are listed")
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestParseEventDelivery(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    service.EventDelivery
+		wantErr bool
+	}{
+		{"", service.EventDelivery{}, false},
+		{"?delivery=drop", service.EventDelivery{}, false},
+		{"?delivery=block", service.EventDelivery{Block: true}, false},
+		{"?delivery=block&block_timeout=250ms", service.EventDelivery{Block: true, Timeout: 250 * time.Millisecond}, false},
+		{"?delivery=queue", service.EventDelivery{}, true},
+		{"?delivery=block&block_timeout=soon", service.EventDelivery{}, true},
+		{"?delivery=block&block_timeout=1m", service.EventDelivery{}, true},
+		{"?block_timeout=1s", service.EventDelivery{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			delivery, err := parseEventDelivery(httptest.NewRequest(http.MethodGet, "/sse/events"+tt.query, nil))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, delivery)
+		})
+	}
+}
+
+func TestEventStreamHandler_SSEEvents_BlockingDelivery(t *testing.T) {
+	mockService := new(MockEventStreamService)
+	handler := NewEventStreamHandler(mockService)
+
+	eventCh := make(chan *models.Event)
+	close(eventCh)
+	delivery := service.EventDelivery{Block: true, Timeout: 2 * time.Second}
+	mockService.On("SubscribeWithDelivery", mock.Anything, mock.Anything, service.EventStreamFilter{}, eventStreamBufferSize, delivery).
+		Return(&service.EventStreamSubscriber{EventCh: eventCh})
+	mockService.On("Unsubscribe", mock.Anything).Return()
+
+	w := httptest.NewRecorder()
+	handler.SSEEvents(w, httptest.NewRequest(http.MethodGet, "/sse/events?delivery=block&block_timeout=2s", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockService.AssertExpectations(t)
+	mockService.AssertNotCalled(t, "Subscribe", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+
+	w = httptest.NewRecorder()
+	handler.SSEEvents(w, httptest.NewRequest(http.MethodGet, "/sse/events?delivery=queue", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestEventStreamHandler_ListSubscribers(t *testing.T) {
+	mockService := new(MockEventStreamService)
+	handler := NewEventStreamHandler(mockService)
+
+	mockService.On("GetSubscriberStats").Return([]service.SubscriberStats{
+		{ID: "sub-1", CameraID: "cam-1", Delivery: "drop", BufferSize: 100, Buffered: 100, Dropped: 7},
+	})
+
+	w := httptest.NewRecorder()
+	handler.ListSubscribers(w, httptest.NewRequest(http.MethodGet, "/api/v1/stream/subscribers", nil))
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp struct {
+		Data struct {
+			Subscribers []service.SubscriberStats `json:"subscribers"`
+			Total       int                       `json:"total"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Data.Total)
+	assert.Equal(t, uint64(7), resp.Data.Subscribers[0].Dropped)
+	assert.Equal(t, "cam-1", resp.Data.Subscribers[0].CameraID)
+}
+
 func TestEventStreamHandler_WebSocketCameraEvents_MissingCameraID(t *testing.T) {
 	mockService := new(MockEventStreamService)
 	handler := NewEventStreamHandler(mockService)
@@ -148,37 +290,76 @@ func TestEventStreamHandler_WebSocketCameraEvents_MissingCameraID(t *testing.T)
 }
 
 func TestEventStreamHandler_WebSocketCameraEvents_WithCameraID(t *testing.T) {
-	mockService := new(MockEventStreamService)
-	_ = NewEventStreamHandler(mockService)
+	streamService := service.NewEventStreamService(service.NewProcessorAdapter(func(service.EventSubscriber) {}))
+	handler := NewEventStreamHandler(streamService)
+	handler.pingInterval = 20 * time.Millisecond
 
-	ctx, cancel := context.WithCancel(context.Background())
+	router := chi.NewRouter()
+	router.Get("/ws/cameras/{id}/events", handler.WebSocketCameraEvents)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	eventCh := make(chan *models.Event, 10)
-	subscriber := &service.EventStreamSubscriber{
-		ID:       "test-sub",
-		CameraID: "cam-123",
-		EventCh:  eventCh,
-	}
+	var pings atomic.Int32
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws/cameras/cam-123/events", &websocket.DialOptions{
+		OnPingReceived: func(context.Context, []byte) bool {
+			pings.Add(1)
+			return true
+		},
+	})
+	require.NoError(t, err)
+	defer conn.CloseNow()
+
+	require.Eventually(t, func() bool { return streamService.GetCameraSubscriberCount("cam-123") == 1 },
+		time.Second, 5*time.Millisecond)
+
+	// Only events of the camera in the URL are sent
+	require.NoError(t, streamService.OnEvent(&models.Event{ID: "evt-other", CameraID: "cam-456", Type: models.EventMotionDetected}))
+	require.NoError(t, streamService.OnEvent(&models.Event{ID: "evt-1", CameraID: "cam-123", Type: models.EventMotionDetected}))
+
+	msgType, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, websocket.MessageText, msgType)
+	var event models.Event
+	require.NoError(t, json.Unmarshal(data, &event))
+	assert.Equal(t, "evt-1", event.ID)
+	assert.Equal(t, "cam-123", event.CameraID)
+
+	// Reading answers the server's pings
+	readCtx, cancelRead := context.WithTimeout(ctx, 100*time.Millisecond)
+	_, _, err = conn.Read(readCtx)
+	cancelRead()
+	assert.Error(t, err, "no further events")
+	assert.GreaterOrEqual(t, pings.Load(), int32(2))
+
+	// Disconnecting unsubscribes the client
+	conn.Close(websocket.StatusNormalClosure, "")
+	assert.Eventually(t, func() bool { return streamService.GetSubscriberCount() == 0 },
+		time.Second, 5*time.Millisecond)
+}
 
-	mockService.On("Subscribe", mock.Anything, mock.Anything, "cam-123", 100).Return(subscriber)
-	mockService.On("Unsubscribe", mock.Anything).Return()
+func TestEventStreamHandler_WebSocketEvents_UnresponsiveClient(t *testing.T) {
+	streamService := service.NewEventStreamService(service.NewProcessorAdapter(func(service.EventSubscriber) {}))
+	handler := NewEventStreamHandler(streamService)
+	handler.pingInterval = 20 * time.Millisecond
 
-	req := httptest.NewRequest(http.MethodGet, "/ws/cameras/cam-123/events", nil)
-	req = req.WithContext(ctx)
+	server := httptest.NewServer(http.HandlerFunc(handler.WebSocketEvents))
+	defer server.Close()
 
-	// Add URL params using chi context
-	rctx := chi.NewRouteContext()
-	rctx.URLParams.Add("id", "cam-123")
-	req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
 
-	// Note: We can't fully test WebSocket upgrade without a real WebSocket client
-	// This test verifies the camera ID extraction works correctly
-	// The actual WebSocket functionality would need integration tests
+	// A client that never reads never answers pings
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.CloseNow()
 
-	// For unit testing, we just verify the handler can be created with the mock
-	// In a real scenario, the handler would upgrade to WebSocket
-	assert.NotNil(t, mockService)
+	require.Eventually(t, func() bool { return streamService.GetSubscriberCount() == 1 },
+		time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return streamService.GetSubscriberCount() == 0 },
+		time.Second, 5*time.Millisecond)
 }
 
 func TestLegacyWebSocketEvents(t *testing.T) {
@@ -220,12 +401,11 @@ func TestEventStreamHandler_SSEEvents_MultipleEvents(t *testing.T) {
 
 	eventCh := make(chan *models.Event, 10)
 	subscriber := &service.EventStreamSubscriber{
-		ID:       "test-sub",
-		CameraID: "",
-		EventCh:  eventCh,
+		ID:      "test-sub",
+		EventCh: eventCh,
 	}
 
-	mockService.On("Subscribe", mock.Anything, mock.Anything, "", 100).Return(subscriber)
+	mockService.On("Subscribe", mock.Anything, mock.Anything, service.EventStreamFilter{}, 100).Return(subscriber)
 	mockService.On("Unsubscribe", mock.Anything).Return()
 
 	req := httptest.NewRequest(http.MethodGet, "/sse/events", nil)
@@ -267,12 +447,11 @@ func TestEventStreamHandler_SSEEvents_ChannelClosed(t *testing.T) {
 
 	eventCh := make(chan *models.Event, 10)
 	subscriber := &service.EventStreamSubscriber{
-		ID:       "test-sub",
-		CameraID: "",
-		EventCh:  eventCh,
+		ID:      "test-sub",
+		EventCh: eventCh,
 	}
 
-	mockService.On("Subscribe", mock.Anything, mock.Anything, "", 100).Return(subscriber)
+	mockService.On("Subscribe", mock.Anything, mock.Anything, service.EventStreamFilter{}, 100).Return(subscriber)
 	mockService.On("Unsubscribe", mock.Anything).Return()
 
 	req := httptest.NewRequest(http.MethodGet, "/sse/events", nil)
@@ -2,13 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 
+	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/pkg/utils"
 )
 
@@ -20,12 +23,26 @@ const (
 	UserIDKey contextKey = "user_id"
 	// UsernameKey is the context key for username
 	UsernameKey contextKey = "username"
+	// RoleKey is the context key for the user role
+	RoleKey contextKey = "role"
+	// APIKeyScopeKey is the context key for the scope of the API key a request
+	// was authenticated with; absent for JWT-authenticated requests
+	APIKeyScopeKey contextKey = "api_key_scope"
 )
 
+// apiKeyAuthScheme is the Authorization scheme of API keys
+const apiKeyAuthScheme = "ApiKey"
+
+// APIKeyAuthenticator looks up the API keys presented by clients
+type APIKeyAuthenticator interface {
+	AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error)
+}
+
 // Claims represents JWT claims
 type Claims struct {
 	UserID   string `json:"user_id"`
 	Username string `json:"username"`
+	Role     string `json:"role"`
 	jwt.RegisteredClaims
 }
 
@@ -54,37 +71,186 @@ func Authenticate(jwtSecret string) func(http.Handler) http.Handler {
 				}
 			}
 
-			// Parse and validate token
-			token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-				// Validate signing method
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return []byte(jwtSecret), nil
-			})
+			claims, ok := validateToken(w, tokenString, jwtSecret)
+			if !ok {
+				return
+			}
 
-			if err != nil {
-				logger.Debug("JWT validation failed",
+			// SSE tokens travel in URLs and are only good for the event stream
+			if hasAudience(claims, models.SSETokenAudience) {
+				utils.RespondError(w, http.StatusUnauthorized, "INVALID_TOKEN", "SSE tokens are only valid for event streams", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// AuthenticateSSE is a middleware for SSE routes. Browsers cannot set headers on
+// EventSource requests, so besides the Authorization header it accepts a
+// short-lived SSE token (see AuthService.IssueSSEToken) in the token query parameter.
+// The token is only checked when connecting, so streams outlive it; clients
+// reconnecting after it expired get 401 TOKEN_EXPIRED and need a new token.
+func AuthenticateSSE(jwtSecret string) func(http.Handler) http.Handler {
+	headerAuth := Authenticate(jwtSecret)
+
+	return func(next http.Handler) http.Handler {
+		withHeader := headerAuth(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "" {
+				withHeader.ServeHTTP(w, r)
+				return
+			}
+
+			tokenString := r.URL.Query().Get("token")
+			if tokenString == "" {
+				utils.RespondError(w, http.StatusUnauthorized, "MISSING_TOKEN", "Authorization header or token query parameter is required", nil)
+				return
+			}
+
+			claims, ok := validateToken(w, tokenString, jwtSecret)
+			if !ok {
+				return
+			}
+
+			// Long-lived session tokens must not be exposed in URLs
+			if !hasAudience(claims, models.SSETokenAudience) {
+				utils.RespondError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Query parameter token must be an SSE token", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
+
+// AuthenticateAPIKey is a middleware accepting API keys in the form
+// "Authorization: ApiKey {key}". Requests without an API key are passed to
+// tokenAuth, e.g. Authenticate, so both kinds of credentials work. Unknown and
+// revoked keys are answered with 401; failing to look a key up is a server
+// error, not a bad credential.
+func AuthenticateAPIKey(keys APIKeyAuthenticator, tokenAuth func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		withToken := tokenAuth(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, key, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+			if scheme != apiKeyAuthScheme {
+				withToken.ServeHTTP(w, r)
+				return
+			}
+
+			apiKey, err := keys.AuthenticateAPIKey(r.Context(), key)
+			switch {
+			case errors.Is(err, service.ErrAPIKeyNotFound):
+				logger.Debug("API key authentication failed",
 					zap.Error(err),
-					zap.String("token", tokenString[:min(len(tokenString), 20)]+"..."),
+					zap.String("key", key[:min(len(key), 12)]+"..."),
 				)
-				utils.RespondError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token", nil)
+				utils.RespondError(w, http.StatusUnauthorized, "INVALID_API_KEY", "Invalid or revoked API key", nil)
+				return
+			case errors.Is(err, service.ErrAPIKeysDisabled):
+				utils.RespondError(w, http.StatusServiceUnavailable, "API_KEYS_DISABLED", "API keys are not configured", nil)
+				return
+			case err != nil:
+				logger.Error("Failed to look up API key", zap.Error(err))
+				utils.RespondInternalError(w, "Failed to authenticate API key")
 				return
 			}
 
-			// Extract claims
-			claims, ok := token.Claims.(*Claims)
-			if !ok || !token.Valid {
-				utils.RespondError(w, http.StatusUnauthorized, "INVALID_CLAIMS", "Invalid token claims", nil)
+			ctx := context.WithValue(r.Context(), UserIDKey, apiKey.UserID)
+			ctx = context.WithValue(ctx, UsernameKey, apiKey.Username)
+			ctx = context.WithValue(ctx, RoleKey, string(apiKey.Role))
+			ctx = context.WithValue(ctx, APIKeyScopeKey, string(apiKey.Scope))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// EnforceAPIKeyScope is a middleware that limits requests authenticated with
+// a read-scoped API key to read-only methods. It must run after authentication.
+func EnforceAPIKeyScope(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if models.APIKeyScope(GetAPIKeyScope(r.Context())) == models.APIKeyScopeRead {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+			default:
+				utils.RespondError(w, http.StatusForbidden, "INSUFFICIENT_SCOPE", "API key scope does not allow changes", nil)
 				return
 			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
 
-			// Add user info to context
-			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
-			ctx = context.WithValue(ctx, UsernameKey, claims.Username)
+// validateToken parses and validates a JWT, writing an error response on failure
+func validateToken(w http.ResponseWriter, tokenString, jwtSecret string) (*Claims, bool) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		// Validate signing method
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(jwtSecret), nil
+	})
 
-			// Continue with authenticated request
-			next.ServeHTTP(w, r.WithContext(ctx))
+	if err != nil {
+		logger.Debug("JWT validation failed",
+			zap.Error(err),
+			zap.String("token", tokenString[:min(len(tokenString), 20)]+"..."),
+		)
+		// Clients tell expired tokens apart to fetch a new one, e.g. SSE
+		// clients reconnecting after their token's lifetime
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			utils.RespondError(w, http.StatusUnauthorized, "TOKEN_EXPIRED", "Token expired", nil)
+			return nil, false
+		}
+		utils.RespondError(w, http.StatusUnauthorized, "INVALID_TOKEN", "Invalid or expired token", nil)
+		return nil, false
+	}
+
+	// Extract claims
+	claims, ok := token.Claims.(*Claims)
+	if !ok || !token.Valid {
+		utils.RespondError(w, http.StatusUnauthorized, "INVALID_CLAIMS", "Invalid token claims", nil)
+		return nil, false
+	}
+
+	return claims, true
+}
+
+// hasAudience reports whether the token was issued for the given audience
+func hasAudience(claims *Claims, audience string) bool {
+	for _, aud := range claims.Audience {
+		if aud == audience {
+			return true
+		}
+	}
+	return false
+}
+
+// withClaims adds user info from the token claims to the context
+func withClaims(ctx context.Context, claims *Claims) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, claims.UserID)
+	ctx = context.WithValue(ctx, UsernameKey, claims.Username)
+	return context.WithValue(ctx, RoleKey, claims.Role)
+}
+
+// RequireRole is a middleware that only lets authenticated users with one of
+// the given roles through. It must run after Authenticate.
+func RequireRole(roles ...models.UserRole) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role := models.UserRole(GetUserRole(r.Context()))
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			utils.RespondError(w, http.StatusForbidden, "FORBIDDEN", "Insufficient permissions", nil)
 		})
 	}
 }
@@ -97,6 +263,23 @@ func GetUserID(ctx context.Context) string {
 	return ""
 }
 
+// GetUserRole extracts the user role from context
+func GetUserRole(ctx context.Context) string {
+	if role, ok := ctx.Value(RoleKey).(string); ok {
+		return role
+	}
+	return ""
+}
+
+// GetAPIKeyScope extracts the API key scope from context, or "" when the
+// request was not authenticated with an API key
+func GetAPIKeyScope(ctx context.Context) string {
+	if scope, ok := ctx.Value(APIKeyScopeKey).(string); ok {
+		return scope
+	}
+	return ""
+}
+
 // GetUsername extracts username from context
 func GetUsername(ctx context.Context) string {
 	if username, ok := ctx.Value(UsernameKey).(string); ok {
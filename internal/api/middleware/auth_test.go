@@ -0,0 +1,254 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+const testSecret = "test-secret-with-at-least-32-characters"
+
+// signToken creates a signed token for the test user
+func signToken(t *testing.T, expiresAt time.Time, audience ...string) string {
+	t.Helper()
+	claims := &Claims{
+		UserID:   "user-1",
+		Username: "admin",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  audience,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return token
+}
+
+// serveAuth runs a request through the middleware and returns the recorder and authenticated user ID
+func serveAuth(mw func(http.Handler) http.Handler, req *http.Request) (*httptest.ResponseRecorder, string) {
+	var userID string
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID = GetUserID(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w, userID
+}
+
+func TestAuthenticateSSE(t *testing.T) {
+	authService := service.NewAuthService(nil, testSecret, time.Hour, time.Minute)
+	sseToken, err := authService.IssueSSEToken("user-1", "admin")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		query      string
+		header     string
+		wantStatus int
+		wantCode   string
+	}{
+		{"valid query SSE token", "?token=" + sseToken.Token, "", http.StatusOK, ""},
+		{"expired query SSE token", "?token=" + signToken(t, time.Now().Add(-time.Minute), models.SSETokenAudience), "", http.StatusUnauthorized, "TOKEN_EXPIRED"},
+		{"session token in query", "?token=" + signToken(t, time.Now().Add(time.Hour)), "", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"tampered query token", "?token=" + sseToken.Token + "x", "", http.StatusUnauthorized, "INVALID_TOKEN"},
+		{"missing token", "", "", http.StatusUnauthorized, "MISSING_TOKEN"},
+		{"session token in header", "", "Bearer " + signToken(t, time.Now().Add(time.Hour)), http.StatusOK, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sse/events"+tt.query, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			w, userID := serveAuth(AuthenticateSSE(testSecret), req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantCode != "" {
+				assert.Contains(t, w.Body.String(), tt.wantCode)
+			}
+			if tt.wantStatus == http.StatusOK {
+				assert.Equal(t, "user-1", userID)
+			}
+		})
+	}
+}
+
+func TestAuthenticate_RejectsSSETokens(t *testing.T) {
+	sseToken := signToken(t, time.Now().Add(time.Minute), models.SSETokenAudience)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cameras", nil)
+	req.Header.Set("Authorization", "Bearer "+sseToken)
+	w, _ := serveAuth(Authenticate(testSecret), req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/ws/events?token="+sseToken, nil)
+	w, _ = serveAuth(Authenticate(testSecret), req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// Regular session tokens are still accepted
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/cameras", nil)
+	req.Header.Set("Authorization", "Bearer "+signToken(t, time.Now().Add(time.Hour)))
+	w, _ = serveAuth(Authenticate(testSecret), req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestIssueSSEToken_ExpiresAfterTTL(t *testing.T) {
+	authService := service.NewAuthService(nil, testSecret, time.Hour, 30*time.Second)
+	token, err := authService.IssueSSEToken("user-1", "admin")
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), token.ExpiresAt, 2*time.Second)
+}
+
+func TestRequireRole(t *testing.T) {
+	mw := func(next http.Handler) http.Handler {
+		return Authenticate(testSecret)(RequireRole(models.RoleAdmin)(next))
+	}
+
+	tests := []struct {
+		role       string
+		wantStatus int
+	}{
+		{"admin", http.StatusOK},
+		{"viewer", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run("role "+tt.role, func(t *testing.T) {
+			claims := &Claims{
+				UserID: "user-1",
+				Role:   tt.role,
+				RegisteredClaims: jwt.RegisteredClaims{
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+				},
+			}
+			token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+			require.NoError(t, err)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/cameras/cam-1/history", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w, _ := serveAuth(mw, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+// stubAPIKeys authenticates a fixed set of API keys
+type stubAPIKeys map[string]*models.APIKey
+
+func (s stubAPIKeys) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	switch key {
+	case "rlk_disabled":
+		return nil, service.ErrAPIKeysDisabled
+	case "rlk_dberror":
+		return nil, errors.New("connection refused")
+	}
+	if apiKey, ok := s[key]; ok {
+		return apiKey, nil
+	}
+	return nil, service.ErrAPIKeyNotFound
+}
+
+var testAPIKeys = stubAPIKeys{
+	"rlk_read":    {ID: "key-1", UserID: "user-2", Username: "nvr", Role: models.RoleUser, Scope: models.APIKeyScopeRead},
+	"rlk_control": {ID: "key-2", UserID: "user-2", Username: "nvr", Role: models.RoleUser, Scope: models.APIKeyScopeControl},
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	mw := AuthenticateAPIKey(testAPIKeys, Authenticate(testSecret))
+
+	tests := []struct {
+		name       string
+		header     string
+		wantStatus int
+		wantUser   string
+	}{
+		{"valid key", "ApiKey rlk_control", http.StatusOK, "user-2"},
+		{"unknown key", "ApiKey rlk_unknown", http.StatusUnauthorized, ""},
+		{"empty key", "ApiKey ", http.StatusUnauthorized, ""},
+		{"api keys disabled", "ApiKey rlk_disabled", http.StatusServiceUnavailable, ""},
+		{"lookup failure", "ApiKey rlk_dberror", http.StatusInternalServerError, ""},
+		{"session token", "Bearer " + signToken(t, time.Now().Add(time.Hour)), http.StatusOK, "user-1"},
+		{"missing credentials", "", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/cameras", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			w, userID := serveAuth(mw, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantUser, userID)
+		})
+	}
+}
+
+func TestAuthenticateAPIKey_SetsRoleAndScope(t *testing.T) {
+	var role, scope string
+	handler := AuthenticateAPIKey(testAPIKeys, Authenticate(testSecret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		role = GetUserRole(r.Context())
+		scope = GetAPIKeyScope(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/cameras", nil)
+	req.Header.Set("Authorization", "ApiKey rlk_read")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "user", role)
+	assert.Equal(t, "read", scope)
+}
+
+func TestEnforceAPIKeyScope(t *testing.T) {
+	mw := func(next http.Handler) http.Handler {
+		return AuthenticateAPIKey(testAPIKeys, Authenticate(testSecret))(EnforceAPIKeyScope(next))
+	}
+	sessionToken := signToken(t, time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name       string
+		method     string
+		header     string
+		wantStatus int
+	}{
+		{"read key reads", http.MethodGet, "ApiKey rlk_read", http.StatusOK},
+		{"read key controls", http.MethodPost, "ApiKey rlk_read", http.StatusForbidden},
+		{"read key deletes", http.MethodDelete, "ApiKey rlk_read", http.StatusForbidden},
+		{"control key controls", http.MethodPost, "ApiKey rlk_control", http.StatusOK},
+		{"session token controls", http.MethodPost, "Bearer " + sessionToken, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/cameras/cam-1/ptz/move", nil)
+			req.Header.Set("Authorization", tt.header)
+
+			w, _ := serveAuth(mw, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				assert.Contains(t, w.Body.String(), "INSUFFICIENT_SCOPE")
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// rateLimitPruneInterval is how often buckets of clients that went quiet are dropped
+const rateLimitPruneInterval = time.Minute
+
+// tokenBucket holds the requests a client may still make
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter limits requests per client IP with a token bucket: each client
+// may make burst requests at once, refilled at a steady rate
+type RateLimiter struct {
+	rate  float64 // Tokens added per second
+	burst float64
+	now   func() time.Time // Replaced in tests
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+}
+
+// NewRateLimiter creates a rate limiter allowing perMinute requests a minute
+// per client IP, and bursts of up to burst requests (at least one)
+func NewRateLimiter(perMinute, burst int) *RateLimiter {
+	return &RateLimiter{
+		rate:    float64(perMinute) / 60,
+		burst:   math.Max(float64(burst), 1),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// Handler rejects requests of clients that ran out of tokens with 429 Too
+// Many Requests and a Retry-After header
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if allowed, wait := l.allow(ip); !allowed {
+			logger.Debug("Rate limit exceeded",
+				zap.String("ip", ip),
+				zap.String("path", r.URL.Path))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			utils.RespondError(w, http.StatusTooManyRequests, "RATE_LIMITED", "Too many requests, retry later", nil)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket of key, or reports how long until one
+// is available
+func (l *RateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	bucket, ok := l.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: l.burst, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = l.refill(bucket, now)
+	bucket.updated = now
+
+	if bucket.tokens >= 1 {
+		bucket.tokens--
+		return true, 0
+	}
+	if l.rate <= 0 {
+		return false, time.Minute
+	}
+	return false, time.Duration((1 - bucket.tokens) / l.rate * float64(time.Second))
+}
+
+// refill returns the tokens of bucket at now
+func (l *RateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	return math.Min(l.burst, bucket.tokens+elapsed*l.rate)
+}
+
+// prune drops full buckets, which behave like missing ones, so clients that
+// went quiet do not accumulate
+func (l *RateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	for key, bucket := range l.buckets {
+		if l.refill(bucket, now) >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// clientIP returns the IP of the client, which RealIP takes from the
+// forwarding headers of trusted proxies
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
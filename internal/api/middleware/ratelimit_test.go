@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestRateLimiter returns a rate limiter on a manual clock and a function
+// advancing the clock
+func newTestRateLimiter(perMinute, burst int) (http.Handler, func(time.Duration)) {
+	now := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	limiter := NewRateLimiter(perMinute, burst)
+	limiter.now = func() time.Time { return now }
+
+	handler := limiter.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	return handler, func(d time.Duration) { now = now.Add(d) }
+}
+
+func serveFrom(handler http.Handler, remoteAddr string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/cameras/cam-1/ptz/move", nil)
+	req.RemoteAddr = remoteAddr
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimiter_ExhaustsAndRecovers(t *testing.T) {
+	handler, advance := newTestRateLimiter(6, 3)
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveFrom(handler, "10.0.0.1:1234").Code, "request %d within burst", i)
+	}
+
+	w := serveFrom(handler, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"), "6 a minute refill one token every 10s")
+	assert.Contains(t, w.Body.String(), "RATE_LIMITED")
+
+	advance(5 * time.Second)
+	w = serveFrom(handler, "10.0.0.1:1234")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "5", w.Header().Get("Retry-After"))
+
+	advance(5 * time.Second)
+	assert.Equal(t, http.StatusOK, serveFrom(handler, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(handler, "10.0.0.1:1234").Code)
+
+	advance(time.Minute)
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusOK, serveFrom(handler, "10.0.0.1:1234").Code, "bucket refills up to the burst")
+	}
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(handler, "10.0.0.1:1234").Code)
+}
+
+func TestRateLimiter_PerClientIP(t *testing.T) {
+	handler, _ := newTestRateLimiter(60, 1)
+
+	assert.Equal(t, http.StatusOK, serveFrom(handler, "10.0.0.1:1234").Code)
+	assert.Equal(t, http.StatusTooManyRequests, serveFrom(handler, "10.0.0.1:5678").Code, "ports of one client share a bucket")
+	assert.Equal(t, http.StatusOK, serveFrom(handler, "10.0.0.2:1234").Code)
+}
+
+func TestRateLimiter_PrunesIdleClients(t *testing.T) {
+	limiter := NewRateLimiter(60, 2)
+	now := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+
+	limiter.allow("10.0.0.1")
+	now = now.Add(2 * rateLimitPruneInterval)
+	limiter.allow("10.0.0.2")
+
+	assert.NotContains(t, limiter.buckets, "10.0.0.1")
+	assert.Contains(t, limiter.buckets, "10.0.0.2")
+}
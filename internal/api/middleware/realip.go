@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// RealIP sets the remote address of requests coming through a trusted proxy
+// to the client address the proxy forwarded. Trusted proxies are given as
+// CIDRs or single IPs; invalid entries are ignored. Forwarding headers of
+// other peers are ignored, so clients cannot choose the address that rate
+// limits and logs see.
+//
+// The client is the last address in X-Forwarded-For not of a trusted proxy,
+// as earlier entries may have been sent by the client itself; without
+// X-Forwarded-For, X-Real-IP is used.
+func RealIP(trustedProxies []string) func(http.Handler) http.Handler {
+	trusted := make([]*net.IPNet, 0, len(trustedProxies))
+	for _, value := range trustedProxies {
+		network, ok := parseNetwork(value)
+		if !ok {
+			logger.Warn("Ignoring invalid trusted proxy", zap.String("proxy", value))
+			continue
+		}
+		trusted = append(trusted, network)
+	}
+
+	isTrusted := func(ip net.IP) bool {
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(trusted) > 0 {
+				if peer := net.ParseIP(clientIP(r)); peer != nil && isTrusted(peer) {
+					if ip := forwardedIP(r, isTrusted); ip != "" {
+						r.RemoteAddr = ip
+					}
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedIP returns the client address forwarded by a trusted proxy, or an
+// empty string when the headers name none
+func forwardedIP(r *http.Request, isTrusted func(net.IP) bool) string {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			return ""
+		}
+		if !isTrusted(ip) {
+			return ip.String()
+		}
+	}
+
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
+
+// parseNetwork parses a CIDR, or a single IP as a network of one address
+func parseNetwork(value string) (*net.IPNet, bool) {
+	value = strings.TrimSpace(value)
+	if _, network, err := net.ParseCIDR(value); err == nil {
+		return network, true
+	}
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return nil, false
+	}
+	bits := 8 * net.IPv6len
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 8*net.IPv4len
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, true
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/pkg/utils"
+)
+
+// Recoverer is a middleware that recovers from panics, logs them and responds
+// with the standard error envelope
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				// Let net/http abort the response as intended
+				panic(rvr)
+			}
+
+			logger.Error("Panic while handling request",
+				zap.Any("panic", rvr),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("request_id", middleware.GetReqID(r.Context())),
+				zap.ByteString("stack", debug.Stack()),
+			)
+
+			if r.Header.Get("Connection") != "Upgrade" {
+				utils.RespondInternalError(w, "Internal server error")
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
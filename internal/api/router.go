@@ -8,26 +8,42 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"go.uber.org/zap"
 
 	"github.com/mosleyit/reolink_server/internal/api/handlers"
 	apimiddleware "github.com/mosleyit/reolink_server/internal/api/middleware"
 	"github.com/mosleyit/reolink_server/internal/api/service"
 	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/config"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/internal/storage/repository"
+	"github.com/mosleyit/reolink_server/internal/storage/retention"
+	"github.com/mosleyit/reolink_server/pkg/utils"
 )
 
+// defaultRequestTimeout bounds API requests when api.request_timeout is unset
+const defaultRequestTimeout = 60 * time.Second
+
 // Router holds the HTTP router and dependencies
 type Router struct {
 	config             *config.Config
 	mux                *chi.Mux
+	authService        *service.AuthService
 	authHandler        *handlers.AuthHandler
 	cameraHandler      *handlers.CameraHandler
 	eventHandler       *handlers.EventHandler
 	recordingHandler   *handlers.RecordingHandler
+	groupHandler       *handlers.GroupHandler
+	fleetHandler       *handlers.FleetHandler
 	eventStreamHandler *handlers.EventStreamHandler
 	streamHandler      *handlers.StreamHandler
+	streamService      *service.StreamService
 	healthHandler      *handlers.HealthHandler
+	retentionHandler   *handlers.RetentionHandler
+
+	// controlMiddleware guards the camera-control routes (rate limiting)
+	controlMiddleware []func(http.Handler) http.Handler
 }
 
 // RouterDependencies holds all dependencies needed by the router
@@ -41,16 +57,40 @@ type RouterDependencies struct {
 	EventRepo         *repository.EventRepository
 	RecordingRepo     *repository.RecordingRepository
 	UserRepo          *repository.UserRepository
+	ConfigHistoryRepo *repository.ConfigHistoryRepository
+	APIKeyRepo        *repository.APIKeyRepository
+	CameraGroupRepo   *repository.CameraGroupRepository
 }
 
 // NewRouter creates a new HTTP router
 func NewRouter(deps *RouterDependencies) *Router {
 	// Create services
-	authService := service.NewAuthService(deps.UserRepo, deps.Config.Auth.JWTSecret, deps.Config.Auth.JWTExpiration)
-	cameraService := service.NewCameraService(deps.CameraManager, deps.CameraRepo, deps.EventRepo, deps.RawEventProcessor)
+	authService := service.NewAuthService(deps.UserRepo, deps.Config.Auth.JWTSecret, deps.Config.Auth.JWTExpiration, deps.Config.Auth.SSETokenTTL)
+	if deps.APIKeyRepo != nil {
+		authService.SetAPIKeys(deps.APIKeyRepo)
+	}
+	cameraService := service.NewCameraService(deps.CameraManager, deps.CameraRepo, deps.EventRepo, deps.ConfigHistoryRepo, deps.RawEventProcessor)
+	if deps.Config.Cameras.SnapshotCacheTTL != 0 {
+		cameraService.SetSnapshotCacheTTL(deps.Config.Cameras.SnapshotCacheTTL)
+	}
+	cameraService.SetUniqueNames(deps.Config.Cameras.UniqueNames)
 	eventService := service.NewEventService(deps.EventRepo)
 	recordingService := service.NewRecordingService(deps.RecordingRepo, deps.CameraManager)
-	streamService := service.NewStreamService(deps.CameraManager, nil) // Use default config
+	streamConfig := service.DefaultStreamServiceConfig()
+	if deps.Config.Streams.HLSOutputDir != "" {
+		streamConfig.HLSOutputDir = deps.Config.Streams.HLSOutputDir
+	}
+	streamConfig.RTSPTransport = deps.Config.Streams.RTSPTransport
+	streamConfig.Go2RTCURL = deps.Config.Streams.Go2RTCURL
+	streamConfig.Go2RTCRTSPURL = deps.Config.Streams.Go2RTCRTSPURL
+	streamService := service.NewStreamService(deps.CameraManager, streamConfig)
+	if deps.Config.Streams.ClipDir != "" {
+		recordingService.SetClipExport(streamConfig.FFmpegPath, deps.Config.Streams.ClipDir)
+	}
+	if deps.Config.Streams.ThumbnailDir != "" {
+		recordingService.SetThumbnails(streamConfig.FFmpegPath, deps.Config.Streams.ThumbnailDir)
+	}
+	recordingService.SetFrameExtraction(streamConfig.FFmpegPath)
 
 	// Create event stream service if processor is provided
 	var eventStreamService *service.EventStreamService
@@ -60,30 +100,60 @@ func NewRouter(deps *RouterDependencies) *Router {
 			deps.EventProcessor.Subscribe(sub)
 		})
 		eventStreamService = service.NewEventStreamService(adapter)
+		cameraService.SetSubscriberCounter(eventStreamService)
 	}
 
 	// Create handlers
 	authHandler := handlers.NewAuthHandler(authService)
 	cameraHandler := handlers.NewCameraHandler(cameraService)
+	cameraHandler.SetFirmwareUploadTimeout(deps.Config.API.FirmwareUploadTimeout)
+	cameraHandler.SetSnapshotStreaming(deps.Config.Cameras.SnapshotStreaming)
 	eventHandler := handlers.NewEventHandler(eventService, cameraService)
+	eventHandler.SetDefaultMinSeverity(models.EventSeverity(deps.Config.Events.DefaultMinSeverity))
 	recordingHandler := handlers.NewRecordingHandler(recordingService)
+	groupHandler := handlers.NewGroupHandler(service.NewCameraGroupService(deps.CameraGroupRepo, deps.CameraRepo))
+	fleetHandler := handlers.NewFleetHandler(service.NewFleetService(deps.CameraRepo, deps.CameraManager))
 	streamHandler := handlers.NewStreamHandler(streamService)
 	var eventStreamHandler *handlers.EventStreamHandler
 	if eventStreamService != nil {
 		eventStreamHandler = handlers.NewEventStreamHandler(eventStreamService)
 	}
 	healthHandler := handlers.NewHealthHandler(deps.DB)
+	retentionCleaner := retention.NewCleaner(deps.EventRepo, deps.RecordingRepo)
+	retentionCleaner.SetOwnedDirs(deps.Config.Streams.ClipDir, deps.Config.Cameras.SnapshotDir)
+	retentionCleaner.SetThumbnailDir(deps.Config.Streams.ThumbnailDir)
+	cameraService.SetHistoryFileRemover(retentionCleaner)
+	retentionHandler := handlers.NewRetentionHandler(retentionCleaner, deps.Config.Retention.EventDays, deps.Config.Retention.RecordingDays)
+
+	// Probe FFmpeg once at startup; HLS and preview streaming depend on it
+	ffmpegInfo := streamService.ProbeFFmpeg()
+	if !ffmpegInfo.Available {
+		logger.Warn("FFmpeg not available, HLS and preview streaming will fail",
+			zap.String("path", ffmpegInfo.Path),
+			zap.String("error", ffmpegInfo.Error))
+	}
+	healthHandler.SetFFmpegInfo(ffmpegInfo)
 
 	r := &Router{
 		config:             deps.Config,
 		mux:                chi.NewRouter(),
+		authService:        authService,
 		authHandler:        authHandler,
 		cameraHandler:      cameraHandler,
 		eventHandler:       eventHandler,
 		recordingHandler:   recordingHandler,
+		groupHandler:       groupHandler,
+		fleetHandler:       fleetHandler,
 		eventStreamHandler: eventStreamHandler,
 		streamHandler:      streamHandler,
+		streamService:      streamService,
 		healthHandler:      healthHandler,
+		retentionHandler:   retentionHandler,
+	}
+
+	if deps.Config.API.ControlRatePerMinute > 0 {
+		limiter := apimiddleware.NewRateLimiter(deps.Config.API.ControlRatePerMinute, deps.Config.API.ControlBurst)
+		r.controlMiddleware = append(r.controlMiddleware, limiter.Handler)
 	}
 
 	r.setupMiddleware()
@@ -92,6 +162,11 @@ func NewRouter(deps *RouterDependencies) *Router {
 	return r
 }
 
+// IsStreaming reports whether a camera is currently being streamed to clients
+func (r *Router) IsStreaming(cameraID string) bool {
+	return r.streamService.IsStreaming(cameraID)
+}
+
 // ServeHTTP implements http.Handler
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	r.mux.ServeHTTP(w, req)
@@ -102,17 +177,14 @@ func (r *Router) setupMiddleware() {
 	// Request ID
 	r.mux.Use(middleware.RequestID)
 
-	// Real IP
-	r.mux.Use(middleware.RealIP)
+	// Real IP, forwarded only by trusted proxies
+	r.mux.Use(apimiddleware.RealIP(r.config.API.TrustedProxies))
 
 	// Logging
 	r.mux.Use(apimiddleware.Logger)
 
 	// Recovery from panics
-	r.mux.Use(middleware.Recoverer)
-
-	// Timeout
-	r.mux.Use(middleware.Timeout(60 * time.Second))
+	r.mux.Use(apimiddleware.Recoverer)
 
 	// CORS
 	if r.config.API.EnableCORS {
@@ -132,109 +204,231 @@ func (r *Router) setupMiddleware() {
 
 // setupRoutes configures all API routes
 func (r *Router) setupRoutes() {
+	// Unmatched routes get the same error envelope as handler errors
+	r.mux.NotFound(func(w http.ResponseWriter, req *http.Request) {
+		utils.RespondNotFound(w, "Route not found")
+	})
+	r.mux.MethodNotAllowed(func(w http.ResponseWriter, req *http.Request) {
+		utils.RespondError(w, http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED", "Method not allowed", nil)
+	})
+
+	// Requests are cancelled after the request timeout, except long-lived
+	// connections such as event streams, which are registered without it
+	requestTimeout := r.config.API.RequestTimeout
+	if requestTimeout <= 0 {
+		requestTimeout = defaultRequestTimeout
+	}
+	timeout := middleware.Timeout(requestTimeout)
+
 	// Health check (no auth required)
-	r.mux.Get("/health", r.healthHandler.HealthCheck)
-	r.mux.Get("/ready", r.healthHandler.ReadinessCheck)
+	r.mux.With(timeout).Get("/health", r.healthHandler.HealthCheck)
+	r.mux.With(timeout).Get("/ready", r.healthHandler.ReadinessCheck)
 
 	// API v1 routes
 	r.mux.Route("/api/v1", func(rt chi.Router) {
 		// Public routes
 		rt.Group(func(pub chi.Router) {
+			pub.Use(timeout)
 			pub.Post("/auth/login", r.authHandler.Login)
 		})
 
 		// Protected routes (require authentication)
 		rt.Group(func(protected chi.Router) {
 			// Apply JWT authentication middleware
-			protected.Use(apimiddleware.Authenticate(r.config.Auth.JWTSecret))
-
-			// Camera management
-			protected.Route("/cameras", func(cam chi.Router) {
-				cam.Get("/", r.cameraHandler.ListCameras)
-				cam.Post("/", r.cameraHandler.AddCamera)
-				cam.Get("/{id}", r.cameraHandler.GetCamera)
-				cam.Put("/{id}", r.cameraHandler.UpdateCamera)
-				cam.Delete("/{id}", r.cameraHandler.DeleteCamera)
-				cam.Get("/{id}/status", r.cameraHandler.GetCameraStatus)
-				cam.Post("/{id}/reboot", r.cameraHandler.RebootCamera)
-				cam.Get("/{id}/snapshot", r.cameraHandler.GetSnapshot)
-
-				// PTZ control
-				cam.Post("/{id}/ptz/move", r.cameraHandler.PTZMove)
-				cam.Post("/{id}/ptz/preset", r.cameraHandler.PTZPreset)
-
-				// LED/Siren control
-				cam.Post("/{id}/led", r.cameraHandler.ControlLED)
-				cam.Post("/{id}/siren", r.cameraHandler.TriggerSiren)
-
-				// Configuration
-				cam.Get("/{id}/config/{type}", r.cameraHandler.GetCameraConfig)
-				cam.Put("/{id}/config/{type}", r.cameraHandler.UpdateCameraConfig)
-
-				// Events for specific camera
-				cam.Get("/{id}/events", r.cameraHandler.GetCameraEvents)
-
-				// Stream URLs (direct camera URLs)
-				cam.Get("/{id}/stream/rtsp", r.cameraHandler.GetRTSPURL)
-				cam.Get("/{id}/stream/flv", r.cameraHandler.GetFLVURL)
-				cam.Get("/{id}/stream/hls", r.cameraHandler.GetHLSURL)
-
-				// Stream Proxy (proxied through server)
-				cam.Get("/{id}/stream/flv/proxy", r.streamHandler.ProxyFLV)
-				cam.Post("/{id}/stream/hls/start", r.streamHandler.StartHLS)
-			})
+			protected.Use(apimiddleware.AuthenticateAPIKey(r.authService, apimiddleware.Authenticate(r.config.Auth.JWTSecret)))
+			protected.Use(apimiddleware.EnforceAPIKeyScope)
 
-			// HLS Stream Management (session-based)
-			protected.Route("/stream/hls", func(hls chi.Router) {
-				hls.Get("/{session_id}/playlist.m3u8", r.streamHandler.GetHLSPlaylist)
-				hls.Get("/{session_id}/{segment}", r.streamHandler.GetHLSSegment)
-				hls.Delete("/{session_id}", r.streamHandler.StopHLS)
-			})
-
-			// Events
-			protected.Route("/events", func(evt chi.Router) {
-				evt.Get("/", r.eventHandler.ListEvents)
-				evt.Get("/{id}", r.eventHandler.GetEvent)
-				evt.Put("/{id}/acknowledge", r.eventHandler.AcknowledgeEvent)
-				evt.Get("/{id}/snapshot", r.eventHandler.GetEventSnapshot)
-			})
-
-			// Recordings
-			protected.Route("/recordings", func(rec chi.Router) {
-				rec.Get("/", r.recordingHandler.ListRecordings)
-				rec.Get("/{id}", r.recordingHandler.GetRecording)
-				rec.Get("/{id}/download", r.recordingHandler.DownloadRecording)
-				rec.Post("/search", r.recordingHandler.SearchRecordings)
-				rec.Delete("/{id}", r.recordingHandler.DeleteRecording)
-			})
-
-			// WebSocket for real-time events
+			// WebSocket for real-time events, not bounded by the request timeout
 			if r.eventStreamHandler != nil {
 				protected.Get("/ws/events", r.eventStreamHandler.WebSocketEvents)
 				protected.Get("/ws/cameras/{id}/events", r.eventStreamHandler.WebSocketCameraEvents)
-
-				// SSE alternative
-				protected.Get("/sse/events", r.eventStreamHandler.SSEEvents)
 			} else {
 				// Fallback to legacy handlers if event stream not available
 				protected.Get("/ws/events", handlers.WebSocketEvents)
 				protected.Get("/ws/cameras/{id}/events", handlers.WebSocketCameraEvents)
-				protected.Get("/sse/events", handlers.SSEEvents)
+			}
+
+			// Firmware uploads may take longer than the request timeout; the
+			// handler aborts stalled uploads and bounds the camera transfer
+			protected.With(apimiddleware.RequireRole(models.RoleAdmin)).Post("/cameras/{id}/firmware/upload", r.cameraHandler.UploadFirmware)
+
+			// MJPEG streams run until the client disconnects, recordings and
+			// snapshot exports until they are sent
+			protected.Get("/cameras/{id}/stream/mjpeg", r.streamHandler.StreamMJPEG)
+			protected.Get("/recordings/{id}/stream", r.recordingHandler.StreamRecording)
+			protected.Post("/events/snapshots/export", r.eventHandler.ExportEventSnapshots)
+
+			protected.Group(func(api chi.Router) {
+				api.Use(timeout)
+
+				// Short-lived token for browser SSE clients
+				api.Post("/auth/sse-token", r.authHandler.SSEToken)
+
+				// API keys of the current user
+				api.Get("/auth/keys", r.authHandler.ListAPIKeys)
+				api.Post("/auth/keys", r.authHandler.CreateAPIKey)
+				api.Delete("/auth/keys/{id}", r.authHandler.RevokeAPIKey)
+
+				// Camera management
+				api.Route("/cameras", func(cam chi.Router) {
+					cam.Get("/", r.cameraHandler.ListCameras)
+					cam.Post("/", r.cameraHandler.AddCamera)
+					cam.Post("/probe", r.cameraHandler.ProbeNewCamera)
+					cam.Post("/test", r.cameraHandler.TestConnection)
+					cam.Get("/{id}", r.cameraHandler.GetCamera)
+					cam.Put("/{id}", r.cameraHandler.UpdateCamera)
+					cam.Delete("/{id}", r.cameraHandler.DeleteCamera)
+					cam.With(apimiddleware.RequireRole(models.RoleAdmin)).Delete("/{id}/history", r.cameraHandler.PurgeCameraHistory)
+					cam.Get("/{id}/status", r.cameraHandler.GetCameraStatus)
+					cam.Get("/{id}/info", r.cameraHandler.GetDeviceInfo)
+					cam.Get("/{id}/capabilities", r.cameraHandler.GetCameraCapabilities)
+					cam.Get("/{id}/probe", r.cameraHandler.ProbeCamera)
+					cam.With(r.controlMiddleware...).Post("/{id}/reboot", r.cameraHandler.RebootCamera)
+					cam.Get("/{id}/reboot", r.cameraHandler.GetRebootStatus)
+					cam.With(r.controlMiddleware...).Get("/{id}/snapshot", r.cameraHandler.GetSnapshot)
+					cam.With(r.controlMiddleware...).Post("/{id}/commands", r.cameraHandler.RunCameraCommand)
+
+					// PTZ control
+					cam.Group(func(ptz chi.Router) {
+						ptz.Use(r.controlMiddleware...)
+						ptz.Post("/{id}/ptz/move", r.cameraHandler.PTZMove)
+						ptz.Post("/{id}/ptz/preset", r.cameraHandler.PTZPreset)
+						ptz.Get("/{id}/ptz/presets", r.cameraHandler.ListPTZPresets)
+						ptz.Post("/{id}/ptz/presets", r.cameraHandler.SavePTZPreset)
+						ptz.Post("/{id}/ptz/home", r.cameraHandler.PTZHome)
+						ptz.Post("/{id}/ptz/patrol/start", r.cameraHandler.PTZPatrolStart)
+						ptz.Post("/{id}/ptz/patrol/stop", r.cameraHandler.PTZPatrolStop)
+					})
+
+					// LED/Siren control
+					cam.With(r.controlMiddleware...).Post("/{id}/led", r.cameraHandler.ControlLED)
+					cam.With(r.controlMiddleware...).Post("/{id}/siren", r.cameraHandler.TriggerSiren)
+
+					// Storage
+					cam.Get("/{id}/storage", r.cameraHandler.GetCameraStorage)
+					cam.With(apimiddleware.RequireRole(models.RoleAdmin)).Post("/{id}/storage/{hddId}/format", r.cameraHandler.FormatCameraStorage)
+
+					// Firmware
+					cam.Get("/{id}/firmware", r.cameraHandler.CheckFirmware)
+					cam.Get("/{id}/firmware/status", r.cameraHandler.GetFirmwareStatus)
+					cam.With(apimiddleware.RequireRole(models.RoleAdmin)).Post("/{id}/firmware/upgrade", r.cameraHandler.UpgradeFirmware)
+
+					// Configuration
+					cam.Get("/{id}/config/export", r.cameraHandler.ExportCameraConfig)
+					cam.Post("/{id}/config/import", r.cameraHandler.ImportCameraConfig)
+					cam.Get("/{id}/config/{type}", r.cameraHandler.GetCameraConfig)
+					cam.Put("/{id}/config/{type}", r.cameraHandler.UpdateCameraConfig)
+					cam.Patch("/{id}/config/{type}", r.cameraHandler.PatchCameraConfig)
+					cam.Get("/{id}/config/{type}/history", r.cameraHandler.GetCameraConfigHistory)
+					cam.Post("/{id}/config/{type}/rollback/{version}", r.cameraHandler.RollbackCameraConfig)
+
+					// Events for specific camera
+					cam.Get("/{id}/events", r.cameraHandler.GetCameraEvents)
+					cam.Post("/{id}/events/acknowledge-all", r.eventHandler.AcknowledgeCameraEvents)
+
+					// Stream URLs (direct camera URLs)
+					cam.Get("/{id}/stream/rtsp", r.cameraHandler.GetRTSPURL)
+					cam.Get("/{id}/stream/flv", r.cameraHandler.GetFLVURL)
+					cam.Get("/{id}/stream/hls", r.cameraHandler.GetHLSURL)
+
+					// Stream Proxy (proxied through server)
+					cam.Get("/{id}/stream/flv/proxy", r.streamHandler.ProxyFLV)
+					cam.Post("/{id}/stream/hls/start", r.streamHandler.StartHLS)
+					cam.Post("/{id}/stream/preview/start", r.streamHandler.StartPreview)
+					cam.Post("/{id}/stream/webrtc/offer", r.streamHandler.WebRTCOffer)
+				})
+
+				// Camera groups
+				api.Route("/groups", func(grp chi.Router) {
+					grp.Get("/", r.groupHandler.ListGroups)
+					grp.Post("/", r.groupHandler.CreateGroup)
+					grp.Get("/{id}", r.groupHandler.GetGroup)
+					grp.Put("/{id}", r.groupHandler.UpdateGroup)
+					grp.Delete("/{id}", r.groupHandler.DeleteGroup)
+					grp.Get("/{id}/cameras", r.groupHandler.ListGroupCameras)
+					grp.Put("/{id}/cameras/{cameraId}", r.groupHandler.AssignCamera)
+					grp.Delete("/{id}/cameras/{cameraId}", r.groupHandler.UnassignCamera)
+				})
+
+				// Fleet-wide reports
+				api.Get("/fleet/firmware", r.fleetHandler.GetFirmwareReport)
+
+				// HLS Stream Management (session-based)
+				api.Route("/stream/hls", func(hls chi.Router) {
+					hls.Get("/{session_id}/playlist.m3u8", r.streamHandler.GetHLSPlaylist)
+					hls.Get("/{session_id}/{segment}", r.streamHandler.GetHLSSegment)
+					hls.Delete("/{session_id}", r.streamHandler.StopHLS)
+				})
+
+				// WebRTC Stream Management (session-based)
+				api.Delete("/stream/webrtc/{session_id}", r.streamHandler.StopWebRTC)
+
+				// Event stream clients and their backpressure
+				if r.eventStreamHandler != nil {
+					api.Get("/stream/subscribers", r.eventStreamHandler.ListSubscribers)
+				}
+
+				// Events
+				api.Route("/events", func(evt chi.Router) {
+					evt.Get("/", r.eventHandler.ListEvents)
+					evt.Get("/activity", r.eventHandler.GetEventActivity)
+					evt.Get("/types", r.eventHandler.ListEventTypes)
+					evt.Get("/{id}", r.eventHandler.GetEvent)
+					evt.Put("/{id}/acknowledge", r.eventHandler.AcknowledgeEvent)
+					evt.Post("/acknowledge", r.eventHandler.AcknowledgeEvents)
+					evt.Get("/{id}/snapshot", r.eventHandler.GetEventSnapshot)
+				})
+
+				// Recordings
+				api.Route("/recordings", func(rec chi.Router) {
+					rec.Get("/", r.recordingHandler.ListRecordings)
+					rec.Get("/{id}", r.recordingHandler.GetRecording)
+					rec.Get("/{id}/download", r.recordingHandler.DownloadRecording)
+					rec.Get("/{id}/thumbnail", r.recordingHandler.GetThumbnail)
+					rec.Get("/{id}/frame", r.recordingHandler.GetFrame)
+					rec.Post("/search", r.recordingHandler.SearchRecordings)
+					rec.Post("/clips", r.recordingHandler.CreateClip)
+					rec.Get("/clips/{id}", r.recordingHandler.GetClipJob)
+					rec.Delete("/{id}", r.recordingHandler.DeleteRecording)
+					rec.Post("/delete", r.recordingHandler.DeleteRecordings)
+				})
+
+				// Administration
+				api.Route("/admin", func(adm chi.Router) {
+					adm.Use(apimiddleware.RequireRole(models.RoleAdmin))
+					adm.Get("/retention/preview", r.retentionHandler.PreviewRetention)
+					adm.Get("/ffmpeg/orphans", r.streamHandler.ListOrphanedFFmpeg)
+					adm.Delete("/ffmpeg/orphans", r.streamHandler.StopOrphanedFFmpeg)
+				})
+			})
+		})
+
+		// SSE routes also accept short-lived SSE tokens in the query string,
+		// since EventSource cannot send an Authorization header. Like the
+		// WebSocket routes they are not bounded by the request timeout.
+		rt.Group(func(sse chi.Router) {
+			sse.Use(apimiddleware.AuthenticateAPIKey(r.authService, apimiddleware.AuthenticateSSE(r.config.Auth.JWTSecret)))
+			sse.Use(apimiddleware.EnforceAPIKeyScope)
+
+			if r.eventStreamHandler != nil {
+				sse.Get("/sse/events", r.eventStreamHandler.SSEEvents)
+			} else {
+				sse.Get("/sse/events", handlers.SSEEvents)
 			}
 		})
 	})
 
 	// Serve static files for frontend
 	fileServer := http.FileServer(http.Dir("./web/static"))
-	r.mux.Handle("/static/*", http.StripPrefix("/static/", fileServer))
+	r.mux.With(timeout).Handle("/static/*", http.StripPrefix("/static/", fileServer))
 
 	// Serve common files at root
-	r.mux.Get("/favicon.ico", func(w http.ResponseWriter, req *http.Request) {
+	r.mux.With(timeout).Get("/favicon.ico", func(w http.ResponseWriter, req *http.Request) {
 		http.ServeFile(w, req, "./web/static/favicon.svg")
 	})
-	r.mux.Get("/robots.txt", func(w http.ResponseWriter, req *http.Request) {
+	r.mux.With(timeout).Get("/robots.txt", func(w http.ResponseWriter, req *http.Request) {
 		http.ServeFile(w, req, "./web/static/robots.txt")
 	})
 
-	r.mux.Get("/", handlers.ServeIndex)
+	r.mux.With(timeout).Get("/", handlers.ServeIndex)
 }
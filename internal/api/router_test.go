@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/coder/websocket"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	apimiddleware "github.com/mosleyit/reolink_server/internal/api/middleware"
+	"github.com/mosleyit/reolink_server/internal/api/service"
+	"github.com/mosleyit/reolink_server/internal/config"
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
+)
+
+func newTestRouter(t *testing.T) *Router {
+	cfg := &config.Config{}
+	cfg.Auth.JWTSecret = "test-secret-with-at-least-32-characters"
+	cfg.Streams.HLSOutputDir = t.TempDir()
+	return NewRouter(&RouterDependencies{Config: cfg})
+}
+
+func TestRouter_ResponseEnvelope(t *testing.T) {
+	router := newTestRouter(t)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantCode   string
+	}{
+		{"success", http.MethodGet, "/health", http.StatusOK, ""},
+		{"unknown route", http.MethodGet, "/api/v1/does-not-exist", http.StatusNotFound, "NOT_FOUND"},
+		{"wrong method", http.MethodPatch, "/api/v1/auth/login", http.StatusMethodNotAllowed, "METHOD_NOT_ALLOWED"},
+		{"missing token", http.MethodGet, "/api/v1/cameras", http.StatusUnauthorized, "MISSING_TOKEN"},
+		{"invalid body", http.MethodPost, "/api/v1/auth/login", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), w.Body.String())
+			assert.Contains(t, body, "timestamp")
+
+			success := tt.wantStatus < 300
+			assert.Equal(t, success, body["success"])
+			if success {
+				assert.Contains(t, body, "data")
+				assert.NotContains(t, body, "error")
+				return
+			}
+
+			require.Contains(t, body, "error")
+			assert.NotContains(t, body, "data")
+			if tt.wantCode != "" {
+				assert.Equal(t, tt.wantCode, body["error"].(map[string]interface{})["code"])
+			}
+		})
+	}
+}
+
+// fakeEventProcessor hands events to the subscribers of the event stream service
+type fakeEventProcessor struct {
+	mu          sync.Mutex
+	subscribers []service.EventSubscriber
+}
+
+func (p *fakeEventProcessor) Subscribe(subscriber service.EventSubscriber) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.subscribers = append(p.subscribers, subscriber)
+}
+
+func (p *fakeEventProcessor) publish(event *models.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, subscriber := range p.subscribers {
+		_ = subscriber.OnEvent(event)
+	}
+}
+
+func TestRouter_WebSocketOutlivesRequestTimeout(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.JWTSecret = "test-secret-with-at-least-32-characters"
+	cfg.Streams.HLSOutputDir = t.TempDir()
+	cfg.API.RequestTimeout = 50 * time.Millisecond
+	processor := &fakeEventProcessor{}
+	server := httptest.NewServer(NewRouter(&RouterDependencies{Config: cfg, EventProcessor: processor}))
+	defer server.Close()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &apimiddleware.Claims{
+		UserID: "user-1",
+		Role:   string(models.RoleViewer),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(cfg.Auth.JWTSecret))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, _, err := websocket.Dial(ctx, "ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/ws/events", &websocket.DialOptions{
+		HTTPHeader: http.Header{"Authorization": []string{"Bearer " + token}},
+	})
+	require.NoError(t, err)
+	defer conn.Close(websocket.StatusNormalClosure, "")
+
+	// Ordinary requests are cancelled after the timeout, the event stream is not
+	time.Sleep(4 * cfg.API.RequestTimeout)
+	processor.publish(&models.Event{ID: "evt-1", CameraID: "cam-1", Type: models.EventMotionDetected})
+
+	_, data, err := conn.Read(ctx)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "evt-1")
+}
+
+func TestRouter_SSEStreamOutlivesToken(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Auth.JWTSecret = "test-secret-with-at-least-32-characters"
+	cfg.Streams.HLSOutputDir = t.TempDir()
+	processor := &fakeEventProcessor{}
+	server := httptest.NewServer(NewRouter(&RouterDependencies{Config: cfg, EventProcessor: processor}))
+	defer server.Close()
+
+	token, err := service.NewAuthService(nil, cfg.Auth.JWTSecret, time.Hour, time.Second).IssueSSEToken("user-1", "admin")
+	require.NoError(t, err)
+	url := server.URL + "/api/v1/sse/events?token=" + token.Token
+
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The token is checked only when connecting: the open stream keeps
+	// delivering, while reconnecting with it is refused
+	time.Sleep(time.Until(token.ExpiresAt) + time.Second)
+	processor.publish(&models.Event{ID: "evt-1", CameraID: "cam-1", Type: models.EventMotionDetected})
+
+	buf := make([]byte, 4096)
+	var received string
+	for !strings.Contains(received, "evt-1") {
+		n, err := resp.Body.Read(buf)
+		require.NoError(t, err)
+		received += string(buf[:n])
+	}
+
+	reconnect, err := http.Get(url)
+	require.NoError(t, err)
+	defer reconnect.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, reconnect.StatusCode)
+}
+
+func TestRouter_SSEAcceptsAPIKey(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	cfg := &config.Config{}
+	cfg.Auth.JWTSecret = "test-secret-with-at-least-32-characters"
+	cfg.Streams.HLSOutputDir = t.TempDir()
+	server := httptest.NewServer(NewRouter(&RouterDependencies{
+		Config:         cfg,
+		EventProcessor: &fakeEventProcessor{},
+		APIKeyRepo:     repository.NewAPIKeyRepository(&db.DB{DB: sqlDB}),
+	}))
+	defer server.Close()
+
+	key := "rlk_" + strings.Repeat("a", 64)
+	sum := sha256.Sum256([]byte(key))
+	mock.ExpectQuery(`SELECT .+ FROM api_keys k`).
+		WithArgs(hex.EncodeToString(sum[:])).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "key_prefix", "key_hash", "scope",
+			"created_at", "last_used_at", "username", "role"}).
+			AddRow("key-1", "user-1", "dashboard", key[:12], hex.EncodeToString(sum[:]), "read",
+				time.Now(), time.Now(), "viewer", "viewer"))
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/api/v1/sse/events", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "ApiKey "+key)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
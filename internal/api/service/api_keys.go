@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
+)
+
+const (
+	// apiKeyPrefix starts every API key, so leaked keys are easy to recognize
+	apiKeyPrefix = "rlk_"
+
+	// apiKeyTouchInterval limits how often the last use of a key is written
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	// ErrAPIKeysDisabled is returned when no API key repository is configured
+	ErrAPIKeysDisabled = errors.New("api keys are not configured")
+
+	// ErrAPIKeyNotFound is returned for unknown and revoked API keys. It is
+	// the repository's error, so handlers match either.
+	ErrAPIKeyNotFound = repository.ErrAPIKeyNotFound
+)
+
+// APIKeyRepository interface for dependency injection
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListByUserID(ctx context.Context, userID string) ([]*models.APIKey, error)
+	Revoke(ctx context.Context, id, userID string) error
+	TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
+
+// SetAPIKeys enables API key authentication backed by repo
+func (s *AuthService) SetAPIKeys(repo APIKeyRepository) {
+	s.apiKeys = repo
+}
+
+// CreateAPIKey mints an API key for a user. The key is only returned here;
+// just its hash is stored.
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID string, req *models.CreateAPIKeyRequest) (*models.CreateAPIKeyResponse, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+
+	apiKey := &models.APIKey{
+		UserID:  userID,
+		Name:    req.Name,
+		Prefix:  key[:len(apiKeyPrefix)+8],
+		KeyHash: hashAPIKey(key),
+		Scope:   req.Scope,
+	}
+	if err := s.apiKeys.Create(ctx, apiKey); err != nil {
+		return nil, err
+	}
+
+	return &models.CreateAPIKeyResponse{Key: key, APIKey: apiKey}, nil
+}
+
+// ListAPIKeys returns the API keys of a user
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+	return s.apiKeys.ListByUserID(ctx, userID)
+}
+
+// RevokeAPIKey revokes an API key of a user
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	if s.apiKeys == nil {
+		return ErrAPIKeysDisabled
+	}
+	return s.apiKeys.Revoke(ctx, id, userID)
+}
+
+// AuthenticateAPIKey returns the active API key matching key, with the
+// username and role of its owner
+func (s *AuthService) AuthenticateAPIKey(ctx context.Context, key string) (*models.APIKey, error) {
+	if s.apiKeys == nil {
+		return nil, ErrAPIKeysDisabled
+	}
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	apiKey, err := s.apiKeys.GetActiveByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= apiKeyTouchInterval {
+		if err := s.apiKeys.TouchLastUsed(ctx, apiKey.ID, now); err != nil {
+			logger.Warn("Failed to record API key use",
+				zap.String("api_key_id", apiKey.ID),
+				zap.Error(err))
+		}
+	}
+
+	return apiKey, nil
+}
+
+// hashAPIKey returns the stored hash of an API key. Keys are random, so a
+// plain SHA-256 suffices and allows looking them up by hash.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
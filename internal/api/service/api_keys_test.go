@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// fakeAPIKeyRepository keeps API keys in memory, like the database would
+type fakeAPIKeyRepository struct {
+	mu      sync.Mutex
+	keys    map[string]*models.APIKey
+	touches int
+}
+
+func newFakeAPIKeyRepository() *fakeAPIKeyRepository {
+	return &fakeAPIKeyRepository{keys: make(map[string]*models.APIKey)}
+}
+
+func (r *fakeAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key.ID = "key-" + key.Name
+	key.CreatedAt = time.Now()
+	stored := *key
+	r.keys[key.ID] = &stored
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) GetActiveByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash && key.RevokedAt == nil {
+			found := *key
+			found.Username = "admin"
+			found.Role = models.RoleAdmin
+			return &found, nil
+		}
+	}
+	return nil, ErrAPIKeyNotFound
+}
+
+func (r *fakeAPIKeyRepository) ListByUserID(ctx context.Context, userID string) ([]*models.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	keys := []*models.APIKey{}
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) Revoke(ctx context.Context, id, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key, ok := r.keys[id]
+	if !ok || key.UserID != userID || key.RevokedAt != nil {
+		return ErrAPIKeyNotFound
+	}
+	now := time.Now()
+	key.RevokedAt = &now
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) TouchLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.touches++
+	r.keys[id].LastUsedAt = &usedAt
+	return nil
+}
+
+func newAPIKeyTestService() (*AuthService, *fakeAPIKeyRepository) {
+	repo := newFakeAPIKeyRepository()
+	authService := NewAuthService(nil, "test-secret-with-at-least-32-characters", time.Hour, time.Minute)
+	authService.SetAPIKeys(repo)
+	return authService, repo
+}
+
+func TestAuthService_CreateAPIKey(t *testing.T) {
+	authService, repo := newAPIKeyTestService()
+
+	resp, err := authService.CreateAPIKey(context.Background(), "user-1",
+		&models.CreateAPIKeyRequest{Name: "home-assistant", Scope: models.APIKeyScopeControl})
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(resp.Key, apiKeyPrefix))
+	assert.Len(t, resp.Key, len(apiKeyPrefix)+64)
+	assert.True(t, strings.HasPrefix(resp.Key, resp.APIKey.Prefix))
+	assert.Equal(t, "user-1", resp.APIKey.UserID)
+	assert.Equal(t, models.APIKeyScopeControl, resp.APIKey.Scope)
+
+	stored := repo.keys[resp.APIKey.ID]
+	require.NotNil(t, stored)
+	assert.Equal(t, hashAPIKey(resp.Key), stored.KeyHash)
+	assert.NotContains(t, stored.KeyHash, resp.Key[len(apiKeyPrefix):], "only the hash is stored")
+
+	other, err := authService.CreateAPIKey(context.Background(), "user-1",
+		&models.CreateAPIKeyRequest{Name: "other", Scope: models.APIKeyScopeRead})
+	require.NoError(t, err)
+	assert.NotEqual(t, resp.Key, other.Key)
+}
+
+func TestAuthService_AuthenticateAPIKey(t *testing.T) {
+	authService, repo := newAPIKeyTestService()
+	ctx := context.Background()
+
+	resp, err := authService.CreateAPIKey(ctx, "user-1", &models.CreateAPIKeyRequest{Name: "nvr", Scope: models.APIKeyScopeRead})
+	require.NoError(t, err)
+
+	apiKey, err := authService.AuthenticateAPIKey(ctx, resp.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", apiKey.UserID)
+	assert.Equal(t, "admin", apiKey.Username)
+	assert.Equal(t, models.APIKeyScopeRead, apiKey.Scope)
+
+	_, err = authService.AuthenticateAPIKey(ctx, resp.Key)
+	require.NoError(t, err)
+	assert.Equal(t, 1, repo.touches, "last use is recorded at most once a minute")
+
+	_, err = authService.AuthenticateAPIKey(ctx, resp.Key+"x")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+	_, err = authService.AuthenticateAPIKey(ctx, "not-an-api-key")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestAuthService_RevokeAPIKey(t *testing.T) {
+	authService, _ := newAPIKeyTestService()
+	ctx := context.Background()
+
+	resp, err := authService.CreateAPIKey(ctx, "user-1", &models.CreateAPIKeyRequest{Name: "nvr", Scope: models.APIKeyScopeControl})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, authService.RevokeAPIKey(ctx, "user-2", resp.APIKey.ID), ErrAPIKeyNotFound,
+		"users cannot revoke keys of others")
+	require.NoError(t, authService.RevokeAPIKey(ctx, "user-1", resp.APIKey.ID))
+
+	_, err = authService.AuthenticateAPIKey(ctx, resp.Key)
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound, "revoked keys no longer authenticate")
+	assert.ErrorIs(t, authService.RevokeAPIKey(ctx, "user-1", resp.APIKey.ID), ErrAPIKeyNotFound)
+
+	keys, err := authService.ListAPIKeys(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	assert.NotNil(t, keys[0].RevokedAt)
+}
+
+func TestAuthService_APIKeysDisabled(t *testing.T) {
+	authService := NewAuthService(nil, "test-secret-with-at-least-32-characters", time.Hour, time.Minute)
+
+	_, err := authService.CreateAPIKey(context.Background(), "user-1", &models.CreateAPIKeyRequest{Name: "nvr"})
+	assert.ErrorIs(t, err, ErrAPIKeysDisabled)
+	_, err = authService.AuthenticateAPIKey(context.Background(), apiKeyPrefix+"abc")
+	assert.ErrorIs(t, err, ErrAPIKeysDisabled)
+}
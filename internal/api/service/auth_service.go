@@ -11,19 +11,29 @@ import (
 	"github.com/mosleyit/reolink_server/internal/storage/repository"
 )
 
+// defaultSSETokenTTL is used when no SSE token lifetime is configured
+const defaultSSETokenTTL = time.Minute
+
 // AuthService handles authentication operations
 type AuthService struct {
 	userRepo      *repository.UserRepository
 	jwtSecret     string
 	jwtExpiration time.Duration
+	sseTokenTTL   time.Duration
+	apiKeys       APIKeyRepository // Nil disables API keys, see SetAPIKeys
 }
 
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, jwtExpiration time.Duration) *AuthService {
+func NewAuthService(userRepo *repository.UserRepository, jwtSecret string, jwtExpiration, sseTokenTTL time.Duration) *AuthService {
+	if sseTokenTTL <= 0 {
+		sseTokenTTL = defaultSSETokenTTL
+	}
+
 	return &AuthService{
 		userRepo:      userRepo,
 		jwtSecret:     jwtSecret,
 		jwtExpiration: jwtExpiration,
+		sseTokenTTL:   sseTokenTTL,
 	}
 }
 
@@ -75,3 +85,30 @@ func (s *AuthService) Login(ctx context.Context, username, password string) (*mo
 	}, nil
 }
 
+// IssueSSEToken returns a short-lived token that is only accepted by the
+// SSE endpoints, where browsers have to pass it in the query string
+func (s *AuthService) IssueSSEToken(userID, username string) (*models.SSETokenResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.sseTokenTTL)
+	claims := &Claims{
+		UserID:   userID,
+		Username: username,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{models.SSETokenAudience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString([]byte(s.jwtSecret))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SSETokenResponse{
+		Token:     tokenString,
+		ExpiresAt: expiresAt,
+	}, nil
+}
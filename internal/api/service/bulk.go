@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// runBulk applies apply to each distinct ID, one at a time, so a missing or
+// failing ID does not stop the others. IDs that are not UUIDs are rejected
+// without a query; apply returning notFound marks an ID not found.
+func runBulk(ctx context.Context, operation string, ids []string, notFound error, apply func(ctx context.Context, id string) error) *models.BulkResult {
+	result := models.NewBulkResult()
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		if _, err := uuid.Parse(id); err != nil {
+			result.Fail(id, models.BulkReasonInvalidID)
+			continue
+		}
+
+		err := apply(ctx, id)
+		switch {
+		case err == nil:
+			result.Succeed(id)
+		case errors.Is(err, notFound):
+			result.Fail(id, models.BulkReasonNotFound)
+		default:
+			logger.Error("Bulk operation failed",
+				zap.String("operation", operation),
+				zap.String("id", id),
+				zap.Error(err))
+			result.Fail(id, models.BulkReasonError)
+		}
+	}
+	return result
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
+)
+
+// Camera group errors are the repository's, so handlers match either
+var (
+	ErrCameraGroupNotFound = repository.ErrCameraGroupNotFound
+	ErrCameraGroupExists   = repository.ErrCameraGroupExists
+	ErrNotGroupMember      = repository.ErrNotGroupMember
+)
+
+// ErrInvalidCameraGroup is returned for groups without a name
+var ErrInvalidCameraGroup = errors.New("camera group name is required")
+
+// CameraGroupRepository interface for dependency injection
+type CameraGroupRepository interface {
+	Create(ctx context.Context, group *models.CameraGroup) error
+	GetByID(ctx context.Context, id string) (*models.CameraGroup, error)
+	List(ctx context.Context) ([]*models.CameraGroup, error)
+	Update(ctx context.Context, group *models.CameraGroup) error
+	Delete(ctx context.Context, id string) error
+}
+
+// GroupMemberRepository assigns cameras to groups
+type GroupMemberRepository interface {
+	ListByGroup(ctx context.Context, groupID string) ([]*models.Camera, error)
+	AssignGroup(ctx context.Context, cameraID, groupID string) error
+	UnassignGroup(ctx context.Context, cameraID, groupID string) error
+}
+
+// CameraGroupService manages camera groups and their members
+type CameraGroupService struct {
+	groupRepo  CameraGroupRepository
+	memberRepo GroupMemberRepository
+}
+
+// NewCameraGroupService creates a new camera group service
+func NewCameraGroupService(groupRepo CameraGroupRepository, memberRepo GroupMemberRepository) *CameraGroupService {
+	return &CameraGroupService{
+		groupRepo:  groupRepo,
+		memberRepo: memberRepo,
+	}
+}
+
+// CreateGroup creates a camera group
+func (s *CameraGroupService) CreateGroup(ctx context.Context, req *models.CreateCameraGroupRequest) (*models.CameraGroup, error) {
+	group := &models.CameraGroup{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+	}
+	if group.Name == "" {
+		return nil, ErrInvalidCameraGroup
+	}
+
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// GetGroup retrieves a camera group by ID
+func (s *CameraGroupService) GetGroup(ctx context.Context, id string) (*models.CameraGroup, error) {
+	return s.groupRepo.GetByID(ctx, id)
+}
+
+// ListGroups retrieves all camera groups
+func (s *CameraGroupService) ListGroups(ctx context.Context) ([]*models.CameraGroup, error) {
+	return s.groupRepo.List(ctx)
+}
+
+// UpdateGroup changes the name and/or description of a camera group
+func (s *CameraGroupService) UpdateGroup(ctx context.Context, id string, req *models.UpdateCameraGroupRequest) (*models.CameraGroup, error) {
+	group, err := s.groupRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		group.Name = strings.TrimSpace(*req.Name)
+		if group.Name == "" {
+			return nil, ErrInvalidCameraGroup
+		}
+	}
+	if req.Description != nil {
+		group.Description = *req.Description
+	}
+
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		return nil, err
+	}
+	return s.groupRepo.GetByID(ctx, id)
+}
+
+// DeleteGroup deletes a camera group. Its cameras are kept, without a group.
+func (s *CameraGroupService) DeleteGroup(ctx context.Context, id string) error {
+	return s.groupRepo.Delete(ctx, id)
+}
+
+// ListGroupCameras retrieves the cameras of a camera group
+func (s *CameraGroupService) ListGroupCameras(ctx context.Context, id string) ([]*models.Camera, error) {
+	if _, err := s.groupRepo.GetByID(ctx, id); err != nil {
+		return nil, err
+	}
+	return s.memberRepo.ListByGroup(ctx, id)
+}
+
+// AssignCamera moves a camera into a camera group
+func (s *CameraGroupService) AssignCamera(ctx context.Context, groupID, cameraID string) error {
+	if _, err := s.groupRepo.GetByID(ctx, groupID); err != nil {
+		return err
+	}
+
+	err := s.memberRepo.AssignGroup(ctx, cameraID, groupID)
+	if errors.Is(err, repository.ErrCameraNotFound) {
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, cameraID)
+	}
+	return err
+}
+
+// UnassignCamera removes a camera from a camera group
+func (s *CameraGroupService) UnassignCamera(ctx context.Context, groupID, cameraID string) error {
+	return s.memberRepo.UnassignGroup(ctx, cameraID, groupID)
+}
@@ -2,9 +2,15 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"time"
+
+	"go.uber.org/zap"
 
 	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/internal/storage/repository"
 )
@@ -12,14 +18,35 @@ import (
 // EventProcessorInterface defines the interface for event processor operations
 type EventProcessorInterface interface {
 	AddCamera(ctx context.Context, cameraClient *camera.CameraClient)
+	PublishConfigChangedEvent(cameraID, cameraName, configType string, changes interface{})
 }
 
+// ErrCameraNameTaken is returned when unique camera names are enforced and
+// another camera already uses the requested name
+var ErrCameraNameTaken = errors.New("camera name already in use")
+
 // CameraService coordinates camera operations between the camera manager and database
 type CameraService struct {
-	cameraManager  *camera.Manager
-	cameraRepo     *repository.CameraRepository
-	eventRepo      *repository.EventRepository
-	eventProcessor EventProcessorInterface
+	cameraManager     *camera.Manager
+	cameraRepo        *repository.CameraRepository
+	eventRepo         *repository.EventRepository
+	configHistoryRepo *repository.ConfigHistoryRepository
+	eventProcessor    EventProcessorInterface
+	snapshots         *snapshotCache
+	uniqueNames       bool
+	subscribers       SubscriberCounter
+	files             HistoryFileRemover
+}
+
+// SubscriberCounter counts the live event stream subscribers of a camera
+type SubscriberCounter interface {
+	GetCameraSubscriberCount(cameraID string) int
+}
+
+// HistoryFileRemover removes the recording files, thumbnails and event
+// snapshots of a purged camera history, reporting how many were removed
+type HistoryFileRemover interface {
+	RemoveFiles(recordings []*models.Recording, snapshotPaths []string) int
 }
 
 // NewCameraService creates a new camera service
@@ -27,30 +54,72 @@ func NewCameraService(
 	cameraManager *camera.Manager,
 	cameraRepo *repository.CameraRepository,
 	eventRepo *repository.EventRepository,
+	configHistoryRepo *repository.ConfigHistoryRepository,
 	eventProcessor EventProcessorInterface,
 ) *CameraService {
 	return &CameraService{
-		cameraManager:  cameraManager,
-		cameraRepo:     cameraRepo,
-		eventRepo:      eventRepo,
-		eventProcessor: eventProcessor,
+		cameraManager:     cameraManager,
+		cameraRepo:        cameraRepo,
+		eventRepo:         eventRepo,
+		configHistoryRepo: configHistoryRepo,
+		eventProcessor:    eventProcessor,
+		snapshots:         newSnapshotCache(DefaultSnapshotCacheTTL, defaultSnapshotCacheLimit),
 	}
 }
 
+// SetUniqueNames enables rejecting cameras whose name is already used by another camera
+func (s *CameraService) SetUniqueNames(enabled bool) {
+	s.uniqueNames = enabled
+}
+
+// SetSubscriberCounter sets where camera status reads the number of live
+// event stream subscribers from
+func (s *CameraService) SetSubscriberCounter(counter SubscriberCounter) {
+	s.subscribers = counter
+}
+
+// SetHistoryFileRemover sets what removes the files of purged camera history.
+// Without one, purging a camera's history leaves its files on disk.
+func (s *CameraService) SetHistoryFileRemover(files HistoryFileRemover) {
+	s.files = files
+}
+
+// SetSnapshotCacheTTL sets how long snapshots are served from the cache.
+// A zero or negative TTL disables snapshot caching.
+func (s *CameraService) SetSnapshotCacheTTL(ttl time.Duration) {
+	s.snapshots = newSnapshotCache(ttl, defaultSnapshotCacheLimit)
+}
+
 // AddCamera adds a new camera to both the database and camera manager
 func (s *CameraService) AddCamera(ctx context.Context, camera *models.Camera) error {
+	if err := s.checkConnectionProfile(camera); err != nil {
+		return err
+	}
+	if err := s.checkNameAvailable(ctx, camera); err != nil {
+		return err
+	}
+
 	// Save to database first
 	if err := s.cameraRepo.Create(ctx, camera); err != nil {
+		if errors.Is(err, repository.ErrCameraNameExists) {
+			return fmt.Errorf("%w: %s", ErrCameraNameTaken, camera.Name)
+		}
 		return fmt.Errorf("failed to save camera to database: %w", err)
 	}
 
 	// Add to camera manager
 	if err := s.cameraManager.AddCamera(ctx, camera); err != nil {
+		if s.addedByReconciliation(ctx, camera.ID, err) {
+			return nil
+		}
 		// Rollback: delete from database
 		_ = s.cameraRepo.Delete(ctx, camera.ID)
 		return fmt.Errorf("failed to add camera to manager: %w", err)
 	}
 
+	// Persist device info and capabilities discovered while connecting
+	s.storeDeviceInfo(ctx, camera)
+
 	// Add to event processor for polling (if available)
 	if s.eventProcessor != nil {
 		cameraClient, err := s.cameraManager.GetCamera(camera.ID)
@@ -62,6 +131,57 @@ func (s *CameraService) AddCamera(ctx context.Context, camera *models.Camera) er
 	return nil
 }
 
+// addedByReconciliation reports whether the manager refused to add a camera
+// just saved because a reconciliation pass saw the new row first and added
+// it, along with its event polling. The device info that pass discovered is
+// stored then, which is all that is left of adding the camera.
+func (s *CameraService) addedByReconciliation(ctx context.Context, cameraID string, err error) bool {
+	if !errors.Is(err, camera.ErrCameraExists) {
+		return false
+	}
+	if client, err := s.cameraManager.GetCamera(cameraID); err == nil {
+		s.storeDeviceInfo(ctx, client.Camera)
+	}
+	return true
+}
+
+// storeDeviceInfo persists the device info and capabilities discovered while
+// connecting to a camera
+func (s *CameraService) storeDeviceInfo(ctx context.Context, cam *models.Camera) {
+	if err := s.cameraRepo.Update(ctx, cam); err != nil {
+		logger.Warn("Failed to store camera device info",
+			zap.String("camera_id", cam.ID),
+			zap.Error(err))
+	}
+}
+
+// checkConnectionProfile returns camera.ErrUnknownConnectionProfile if the
+// camera names a connection profile the manager does not know
+func (s *CameraService) checkConnectionProfile(cam *models.Camera) error {
+	if cam.ConnectionProfile == "" {
+		return nil
+	}
+	_, err := s.cameraManager.ConnectionProfile(cam.ConnectionProfile)
+	return err
+}
+
+// checkNameAvailable returns ErrCameraNameTaken if unique names are enforced
+// and another camera already uses the camera's name
+func (s *CameraService) checkNameAvailable(ctx context.Context, camera *models.Camera) error {
+	if !s.uniqueNames {
+		return nil
+	}
+
+	exists, err := s.cameraRepo.NameExists(ctx, camera.Name, camera.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", ErrCameraNameTaken, camera.Name)
+	}
+	return nil
+}
+
 // GetCamera retrieves a camera by ID
 func (s *CameraService) GetCamera(ctx context.Context, id string) (*models.Camera, error) {
 	return s.cameraRepo.GetByID(ctx, id)
@@ -72,19 +192,43 @@ func (s *CameraService) ListCameras(ctx context.Context) ([]*models.Camera, erro
 	return s.cameraRepo.List(ctx)
 }
 
+// ListCamerasByGroup retrieves the cameras of a camera group
+func (s *CameraService) ListCamerasByGroup(ctx context.Context, groupID string) ([]*models.Camera, error) {
+	return s.cameraRepo.ListByGroup(ctx, groupID)
+}
+
 // UpdateCamera updates a camera in both database and manager
 func (s *CameraService) UpdateCamera(ctx context.Context, camera *models.Camera) error {
+	if err := s.checkConnectionProfile(camera); err != nil {
+		return err
+	}
+	if err := s.checkNameAvailable(ctx, camera); err != nil {
+		return err
+	}
+
 	// Update in database
 	if err := s.cameraRepo.Update(ctx, camera); err != nil {
+		if errors.Is(err, repository.ErrCameraNameExists) {
+			return fmt.Errorf("%w: %s", ErrCameraNameTaken, camera.Name)
+		}
 		return fmt.Errorf("failed to update camera in database: %w", err)
 	}
 
 	// Remove from manager and re-add with new config
 	s.cameraManager.RemoveCamera(camera.ID)
+	s.snapshots.forget(camera.ID)
 	if err := s.cameraManager.AddCamera(ctx, camera); err != nil {
 		return fmt.Errorf("failed to update camera in manager: %w", err)
 	}
 
+	// Removing the camera stopped its poller; poll the new client
+	if s.eventProcessor != nil {
+		cameraClient, err := s.cameraManager.GetCamera(camera.ID)
+		if err == nil && cameraClient != nil {
+			s.eventProcessor.AddCamera(ctx, cameraClient)
+		}
+	}
+
 	return nil
 }
 
@@ -92,6 +236,7 @@ func (s *CameraService) UpdateCamera(ctx context.Context, camera *models.Camera)
 func (s *CameraService) DeleteCamera(ctx context.Context, id string) error {
 	// Remove from manager first
 	s.cameraManager.RemoveCamera(id)
+	s.snapshots.forget(id)
 
 	// Delete from database
 	if err := s.cameraRepo.Delete(ctx, id); err != nil {
@@ -104,7 +249,43 @@ func (s *CameraService) DeleteCamera(ctx context.Context, id string) error {
 // GetCameraStatus retrieves the current status of a camera
 func (s *CameraService) GetCameraStatus(ctx context.Context, id string) (*models.CameraStatus, error) {
 	// Use the manager's GetCameraStatus method which already handles this
-	return s.cameraManager.GetCameraStatus(id)
+	status, err := s.cameraManager.GetCameraStatus(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.subscribers != nil {
+		status.EventSubscribers = s.subscribers.GetCameraSubscriberCount(id)
+	}
+	return status, nil
+}
+
+// GetDeviceInfo queries a camera for its model, versions and channel count
+func (s *CameraService) GetDeviceInfo(ctx context.Context, id string) (*models.CameraDeviceInfo, error) {
+	client, err := s.cameraManager.GetCamera(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCameraNotFound, err)
+	}
+
+	info, err := client.GetDeviceInfo(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deviceInfo := &models.CameraDeviceInfo{
+		CameraID:     id,
+		Name:         info.Name,
+		Model:        info.Model,
+		FirmwareVer:  info.FirmVer,
+		HardwareVer:  info.HardVer,
+		ChannelCount: info.ChannelNum,
+		Detail:       info.Detail,
+		Serial:       info.Serial,
+	}
+	if status, err := s.cameraManager.GetCameraStatus(id); err == nil {
+		deviceInfo.Uptime = status.Uptime
+	}
+	return deviceInfo, nil
 }
 
 // GetCameraClient retrieves the camera client for direct SDK operations
@@ -112,6 +293,179 @@ func (s *CameraService) GetCameraClient(id string) (*camera.CameraClient, error)
 	return s.cameraManager.GetCamera(id)
 }
 
+// RebootAndWait reboots a camera and waits in the background for it to come
+// back online within timeout
+func (s *CameraService) RebootAndWait(ctx context.Context, id string, timeout time.Duration) (*camera.RebootStatus, error) {
+	return s.cameraManager.RebootAndWait(ctx, id, timeout)
+}
+
+// GetRebootStatus returns the outcome of a camera's latest reboot-and-wait
+func (s *CameraService) GetRebootStatus(id string) (*camera.RebootStatus, error) {
+	return s.cameraManager.RebootStatus(id)
+}
+
+// GetSnapshot returns a JPEG snapshot of a camera channel. Recent snapshots
+// are served from the cache unless fresh is set, and concurrent requests for
+// an uncached snapshot share one camera request; the returned bool reports
+// whether the snapshot came from the cache or another request.
+func (s *CameraService) GetSnapshot(ctx context.Context, id string, channel int, fresh bool) ([]byte, bool, error) {
+	take := func(ctx context.Context) ([]byte, error) {
+		client, err := s.cameraManager.GetCamera(id)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrCameraNotFound, err)
+		}
+
+		data, err := client.GetSnapshot(ctx, channel)
+		return data, err
+	}
+
+	if !fresh {
+		return s.snapshots.load(ctx, id, channel, take)
+	}
+
+	data, err := take(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	s.snapshots.put(id, channel, data)
+	return data, false, nil
+}
+
+// StreamSnapshot is GetSnapshot for large snapshots: a cached snapshot is
+// served from memory, anything else is streamed from the camera. Streamed
+// snapshots are not cached. The caller closes the stream.
+func (s *CameraService) StreamSnapshot(ctx context.Context, id string, channel int, fresh bool) (*camera.SnapshotStream, bool, error) {
+	if !fresh {
+		if data, ok := s.snapshots.get(id, channel); ok {
+			return camera.NewBufferedSnapshotStream(data), true, nil
+		}
+	}
+
+	client, err := s.cameraManager.GetCamera(id)
+	if err != nil {
+		return nil, false, fmt.Errorf("%w: %v", ErrCameraNotFound, err)
+	}
+
+	stream, err := client.StreamSnapshot(ctx, channel)
+	if err != nil {
+		return nil, false, err
+	}
+	return stream, false, nil
+}
+
+// RecordConfigChange stores a snapshot of the new config in the camera's
+// config history and emits a config_changed event. The history keeps the
+// passwords the config carries so rollbacks restore them; the event has
+// them masked.
+func (s *CameraService) RecordConfigChange(ctx context.Context, cam *models.Camera, configType string, changes interface{}) {
+	if cam == nil {
+		return
+	}
+
+	if s.configHistoryRepo != nil {
+		if err := s.saveConfigSnapshot(ctx, cam.ID, configType, changes); err != nil {
+			logger.Error("Failed to save config snapshot",
+				zap.String("camera_id", cam.ID),
+				zap.String("config_type", configType),
+				zap.Error(err))
+		}
+	}
+
+	if s.eventProcessor != nil {
+		redacted, err := redactConfig(changes)
+		if err != nil {
+			logger.Error("Failed to redact config change",
+				zap.String("camera_id", cam.ID),
+				zap.String("config_type", configType),
+				zap.Error(err))
+			return
+		}
+		s.eventProcessor.PublishConfigChangedEvent(cam.ID, cam.Name, configType, redacted)
+	}
+}
+
+// RecordConfigBaseline stores the config a camera has before the first
+// recorded change of a type, read with current, so the change can be rolled
+// back. Types with a history already are left alone. Failures are logged and
+// do not stop the change.
+func (s *CameraService) RecordConfigBaseline(ctx context.Context, cam *models.Camera, configType string, current func() (interface{}, error)) {
+	if cam == nil || s.configHistoryRepo == nil {
+		return
+	}
+
+	versions, err := s.configHistoryRepo.ListByCameraAndType(ctx, cam.ID, configType, 1, 0)
+	if err != nil || len(versions) > 0 {
+		if err != nil {
+			logger.Error("Failed to read config history",
+				zap.String("camera_id", cam.ID),
+				zap.String("config_type", configType),
+				zap.Error(err))
+		}
+		return
+	}
+
+	config, err := current()
+	if err != nil {
+		logger.Warn("Failed to read config before its first change",
+			zap.String("camera_id", cam.ID),
+			zap.String("config_type", configType),
+			zap.Error(err))
+		return
+	}
+	if err := s.saveConfigSnapshot(ctx, cam.ID, configType, config); err != nil {
+		logger.Error("Failed to save config snapshot",
+			zap.String("camera_id", cam.ID),
+			zap.String("config_type", configType),
+			zap.Error(err))
+	}
+}
+
+// saveConfigSnapshot stores a config value as the next version in the config history
+func (s *CameraService) saveConfigSnapshot(ctx context.Context, cameraID, configType string, config interface{}) error {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	return s.configHistoryRepo.Create(ctx, &models.CameraConfigVersion{
+		CameraID:   cameraID,
+		ConfigType: configType,
+		Config:     data,
+	})
+}
+
+// GetConfigHistory retrieves the config history of a camera for a config
+// type, newest first, with the passwords of the configs masked
+func (s *CameraService) GetConfigHistory(ctx context.Context, cameraID, configType string, limit, offset int) ([]*models.CameraConfigVersion, error) {
+	if s.configHistoryRepo == nil {
+		return nil, fmt.Errorf("config history not available")
+	}
+	history, err := s.configHistoryRepo.ListByCameraAndType(ctx, cameraID, configType, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, entry := range history {
+		redacted, err := redactConfigJSON(entry.Config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to redact config version %d: %w", entry.Version, err)
+		}
+		if entry.Config, err = json.Marshal(redacted); err != nil {
+			return nil, fmt.Errorf("failed to redact config version %d: %w", entry.Version, err)
+		}
+	}
+	return history, nil
+}
+
+// GetConfigVersion retrieves a specific version from a camera's config
+// history, passwords included, for rolling back to it
+func (s *CameraService) GetConfigVersion(ctx context.Context, cameraID, configType string, version int) (*models.CameraConfigVersion, error) {
+	if s.configHistoryRepo == nil {
+		return nil, fmt.Errorf("config history not available")
+	}
+	return s.configHistoryRepo.GetVersion(ctx, cameraID, configType, version)
+}
+
 // GetCameraEvents retrieves events for a specific camera
 func (s *CameraService) GetCameraEvents(ctx context.Context, cameraID string, limit, offset int) ([]*models.Event, error) {
 	return s.eventRepo.ListByCameraID(ctx, cameraID, limit, offset)
@@ -121,3 +475,59 @@ func (s *CameraService) GetCameraEvents(ctx context.Context, cameraID string, li
 func (s *CameraService) CountCameraEvents(ctx context.Context, cameraID string) (int, error) {
 	return s.eventRepo.CountByCameraID(ctx, cameraID)
 }
+
+// PurgeCameraHistory deletes all events and recordings of a camera along with
+// the recording files, thumbnails and event snapshots the server stored on disk
+func (s *CameraService) PurgeCameraHistory(ctx context.Context, cameraID string) (*models.CameraHistoryPurge, error) {
+	purge, err := s.cameraRepo.PurgeHistory(ctx, cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.files != nil {
+		purge.FilesDeleted = s.files.RemoveFiles(purge.Recordings, purge.SnapshotPaths)
+	}
+
+	logger.Info("Camera history purged",
+		zap.String("camera_id", cameraID),
+		zap.Int64("events", purge.EventsDeleted),
+		zap.Int64("recordings", purge.RecordingsDeleted),
+		zap.Int("files", purge.FilesDeleted))
+
+	return purge, nil
+}
+
+// ProbeCamera checks the reachability of a stored camera layer by layer. The
+// camera is loaded from the database, so cameras the manager failed to connect
+// to can be diagnosed too. A non-nil skipVerify overrides the stored TLS
+// verification setting for this probe only.
+func (s *CameraService) ProbeCamera(ctx context.Context, id string, skipVerify *bool) (*camera.ProbeResult, error) {
+	cam, err := s.cameraRepo.GetByID(ctx, id)
+	if errors.Is(err, repository.ErrCameraNotFound) {
+		return nil, fmt.Errorf("%w: %s", ErrCameraNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if skipVerify != nil {
+		cam.SkipVerify = *skipVerify
+	}
+
+	return s.cameraManager.ProbeCamera(ctx, cam), nil
+}
+
+// ProbeNewCamera checks the reachability of a camera that has not been added
+// yet, using one-off clients built from the given connection settings
+func (s *CameraService) ProbeNewCamera(ctx context.Context, cam *models.Camera) *camera.ProbeResult {
+	return s.cameraManager.ProbeCamera(ctx, cam)
+}
+
+// TestConnection logs into a camera that has not been added yet and reports
+// what it found. Neither the database nor the camera manager is changed.
+func (s *CameraService) TestConnection(ctx context.Context, cam *models.Camera) (*camera.ConnectionTestResult, error) {
+	if err := s.checkConnectionProfile(cam); err != nil {
+		return nil, err
+	}
+	return s.cameraManager.TestConnection(ctx, cam), nil
+}
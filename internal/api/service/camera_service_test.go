@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
+	"github.com/mosleyit/reolink_server/internal/storage/retention"
+)
+
+func TestCameraService_PurgeCameraHistory(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	snapshotDir := t.TempDir()
+	clipDir := t.TempDir()
+	thumbnailDir := t.TempDir()
+	outside := t.TempDir()
+
+	snapshot := filepath.Join(snapshotDir, "event-1.jpg")
+	require.NoError(t, os.WriteFile(snapshot, []byte("jpeg"), 0o644))
+	otherCamera := filepath.Join(snapshotDir, "other-camera.jpg")
+	require.NoError(t, os.WriteFile(otherCamera, []byte("jpeg"), 0o644))
+	missing := filepath.Join(snapshotDir, "already-gone.jpg")
+	notOwned := filepath.Join(outside, "event-2.jpg")
+	require.NoError(t, os.WriteFile(notOwned, []byte("jpeg"), 0o644))
+
+	clip := filepath.Join(clipDir, "rec-1.mp4")
+	require.NoError(t, os.WriteFile(clip, []byte("mp4"), 0o644))
+	thumbnail := filepath.Join(thumbnailDir, "rec-1.jpg")
+	require.NoError(t, os.WriteFile(thumbnail, []byte("jpeg"), 0o644))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM events WHERE camera_id = \$1`).
+		WithArgs("cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot_path"}).AddRow(snapshot).AddRow(missing).AddRow(notOwned))
+	mock.ExpectQuery(`DELETE FROM recordings WHERE camera_id = \$1 RETURNING`).
+		WithArgs("cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage_path", "thumbnail_url"}).
+			AddRow("rec-1", clip, "/api/v1/recordings/rec-1/thumbnail").
+			AddRow("rec-2", "/mnt/sd/Mp4Record/rec-2.mp4", nil))
+	mock.ExpectCommit()
+
+	cleaner := retention.NewCleaner(nil, nil)
+	cleaner.SetOwnedDirs(clipDir, snapshotDir)
+	cleaner.SetThumbnailDir(thumbnailDir)
+	svc := NewCameraService(nil, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+	svc.SetHistoryFileRemover(cleaner)
+	purge, err := svc.PurgeCameraHistory(context.Background(), "cam-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), purge.EventsDeleted)
+	assert.Equal(t, int64(2), purge.RecordingsDeleted)
+	assert.Equal(t, 3, purge.FilesDeleted)
+	assert.NoFileExists(t, snapshot)
+	assert.NoFileExists(t, clip)
+	assert.NoFileExists(t, thumbnail)
+	assert.FileExists(t, otherCamera)
+	// Files outside the directories the server writes to are left alone
+	assert.FileExists(t, notOwned)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_UniqueNames(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(nil, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+	svc.SetUniqueNames(true)
+
+	// New cameras have no ID yet, so none is excluded
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM cameras WHERE LOWER\(name\) = LOWER\(\$1\)\)`).
+		WithArgs("Front Door").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	err = svc.AddCamera(context.Background(), &models.Camera{Name: "Front Door", Host: "192.168.1.100"})
+	assert.ErrorIs(t, err, ErrCameraNameTaken)
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM cameras WHERE LOWER\(name\) = LOWER\(\$1\) AND id <> \$2\)`).
+		WithArgs("Front Door", "cam-2").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	err = svc.UpdateCamera(context.Background(), &models.Camera{ID: "cam-2", Name: "Front Door"})
+	assert.ErrorIs(t, err, ErrCameraNameTaken)
+
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("Front Door", "cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	assert.NoError(t, svc.checkNameAvailable(context.Background(), &models.Camera{ID: "cam-1", Name: "Front Door"}))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_UniqueNamesIndexViolation(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(nil, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+	svc.SetUniqueNames(true)
+
+	// A concurrent add took the name between the check and the insert
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("Front Door").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectExec(`INSERT INTO cameras`).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "idx_cameras_name_lower"})
+
+	err = svc.AddCamera(context.Background(), &models.Camera{Name: "Front Door", Host: "192.168.1.100"})
+
+	assert.ErrorIs(t, err, ErrCameraNameTaken)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_UniqueNamesDisabled(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(nil, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+
+	assert.NoError(t, svc.checkNameAvailable(context.Background(), &models.Camera{Name: "Front Door"}))
+	assert.NoError(t, mock.ExpectationsWereMet(), "no name lookup when the check is disabled")
+}
+
+func TestCameraService_AddCameraAlreadyAddedByReconciliation(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "Login":
+			w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"leaseTime":3600,"name":"test-token"}}}]`))
+		case "GetDevInfo":
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"model":"RLC-810A"}}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	defer server.Close()
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, _ := strconv.Atoi(u.Port())
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	manager := camera.NewManager(nil, nil)
+	svc := NewCameraService(manager, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+	cam := &models.Camera{ID: "cam-1", Name: "Barn", Host: u.Hostname(), Port: port, Username: "admin", Password: "password"}
+
+	// A reconciliation pass picked up the new row before the service added the camera
+	mock.ExpectExec(`INSERT INTO cameras`).WillReturnResult(sqlmock.NewResult(0, 1))
+	reconciled := *cam
+	require.NoError(t, manager.AddCamera(context.Background(), &reconciled))
+
+	// The row is kept and the device info the pass discovered is stored
+	mock.ExpectExec(`UPDATE cameras`).
+		WithArgs("cam-1", "Barn", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			"online", "RLC-810A", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, svc.AddCamera(context.Background(), cam))
+
+	_, err = manager.GetCamera("cam-1")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_ConnectionProfile(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(camera.NewManager(nil, nil), repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+
+	// Unknown profiles are refused before the camera is stored
+	err = svc.AddCamera(context.Background(), &models.Camera{Name: "Barn", ConnectionProfile: "satellite"})
+	assert.ErrorIs(t, err, camera.ErrUnknownConnectionProfile)
+	err = svc.UpdateCamera(context.Background(), &models.Camera{ID: "cam-1", Name: "Barn", ConnectionProfile: "satellite"})
+	assert.ErrorIs(t, err, camera.ErrUnknownConnectionProfile)
+	_, err = svc.TestConnection(context.Background(), &models.Camera{Host: "192.168.1.50", ConnectionProfile: "satellite"})
+	assert.ErrorIs(t, err, camera.ErrUnknownConnectionProfile)
+
+	assert.NoError(t, svc.checkConnectionProfile(&models.Camera{ConnectionProfile: camera.ProfileCellular}))
+	assert.NoError(t, svc.checkConnectionProfile(&models.Camera{}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_RecordConfigBaseline(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(nil, nil, nil, repository.NewConfigHistoryRepository(&db.DB{DB: sqlDB}), nil)
+	cam := &models.Camera{ID: "cam-1"}
+	historyColumns := []string{"id", "camera_id", "config_type", "version", "config", "created_at"}
+	reads := 0
+	current := func() (interface{}, error) {
+		reads++
+		return map[string]string{"name": "Front Door"}, nil
+	}
+
+	// The first change of a type stores the value it replaces
+	mock.ExpectQuery(`SELECT .+ FROM camera_config_history`).
+		WithArgs("cam-1", "device_name", 1, 0).
+		WillReturnRows(sqlmock.NewRows(historyColumns))
+	mock.ExpectQuery(`INSERT INTO camera_config_history`).
+		WithArgs(sqlmock.AnyArg(), "cam-1", "device_name", []byte(`{"name":"Front Door"}`), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	svc.RecordConfigBaseline(context.Background(), cam, "device_name", current)
+
+	// Later changes already have it
+	mock.ExpectQuery(`SELECT .+ FROM camera_config_history`).
+		WithArgs("cam-1", "device_name", 1, 0).
+		WillReturnRows(sqlmock.NewRows(historyColumns).AddRow("v1", "cam-1", "device_name", 1, []byte(`{"name":"Front Door"}`), time.Now()))
+	svc.RecordConfigBaseline(context.Background(), cam, "device_name", current)
+
+	assert.Equal(t, 1, reads)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraService_ProbeCamera_NotFound(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	svc := NewCameraService(nil, repository.NewCameraRepository(&db.DB{DB: sqlDB}), nil, nil, nil)
+
+	mock.ExpectQuery(`SELECT .+ FROM cameras`).WithArgs("missing").WillReturnError(sql.ErrNoRows)
+	_, err = svc.ProbeCamera(context.Background(), "missing", nil)
+	assert.ErrorIs(t, err, ErrCameraNotFound)
+
+	mock.ExpectQuery(`SELECT .+ FROM cameras`).WithArgs("cam-1").WillReturnError(errors.New("connection refused"))
+	_, err = svc.ProbeCamera(context.Background(), "cam-1", nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCameraNotFound)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// configEventRecorder records the config_changed events published by the camera service
+type configEventRecorder struct {
+	changes []interface{}
+}
+
+func (r *configEventRecorder) AddCamera(ctx context.Context, cameraClient *camera.CameraClient) {}
+
+func (r *configEventRecorder) PublishConfigChangedEvent(cameraID, cameraName, configType string, changes interface{}) {
+	r.changes = append(r.changes, changes)
+}
+
+func TestCameraService_ConfigChangesMaskPasswords(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	events := &configEventRecorder{}
+	svc := NewCameraService(nil, nil, nil, repository.NewConfigHistoryRepository(&db.DB{DB: sqlDB}), events)
+	cam := &models.Camera{ID: "cam-1"}
+
+	changes := map[string]interface{}{
+		"wifi":  reolink.Wifi{SSID: "home", Password: "wifi-secret"},
+		"email": reolink.Email{SMTPServer: "smtp.example.com", UserName: "cam", Password: "email-secret"},
+		"ftp":   reolink.Ftp{Server: "ftp.example.com", UserName: "cam", Password: "ftp-secret"},
+	}
+	for _, configType := range []string{"wifi", "email", "ftp"} {
+		// The history keeps the password for rollbacks
+		mock.ExpectQuery(`INSERT INTO camera_config_history`).
+			WithArgs(sqlmock.AnyArg(), "cam-1", configType, sqlmock.AnyArg(), sqlmock.AnyArg()).
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+		svc.RecordConfigChange(context.Background(), cam, configType, changes[configType])
+	}
+
+	// Events never carry it
+	require.Len(t, events.changes, 3)
+	for _, published := range events.changes {
+		data, err := json.Marshal(published)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "secret")
+		assert.Contains(t, string(data), `"password":"***"`)
+	}
+
+	// Nor does the history returned to clients
+	mock.ExpectQuery(`SELECT .+ FROM camera_config_history`).
+		WithArgs("cam-1", "wifi", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "config_type", "version", "config", "created_at"}).
+			AddRow("v1", "cam-1", "wifi", 1, []byte(`{"ssid":"home","password":"wifi-secret"}`), time.Now()))
+	history, err := svc.GetConfigHistory(context.Background(), "cam-1", "wifi", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.JSONEq(t, `{"ssid":"home","password":"***"}`, string(history[0].Config))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
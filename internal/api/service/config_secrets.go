@@ -0,0 +1,53 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// configSecretMask replaces the passwords of config values leaving the server
+const configSecretMask = "***"
+
+// redactConfig returns config as a JSON value with the passwords it carries,
+// e.g. of email, FTP and Wi-Fi settings, masked
+func redactConfig(config interface{}) (interface{}, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	return redactConfigJSON(data)
+}
+
+// redactConfigJSON decodes a stored config with its passwords masked
+func redactConfigJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return maskSecrets(value), nil
+}
+
+// maskSecrets masks the non-empty password fields of a decoded JSON value
+func maskSecrets(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if strings.EqualFold(key, "password") {
+				if secret, ok := field.(string); ok && secret != "" {
+					v[key] = configSecretMask
+				}
+				continue
+			}
+			v[key] = maskSecrets(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = maskSecrets(v[i])
+		}
+	}
+	return value
+}
@@ -2,12 +2,21 @@ package service
 
 import (
 	"context"
+	"sort"
 	"time"
 
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/mosleyit/reolink_server/internal/storage/repository"
 )
 
+const (
+	// DefaultActivityGap is the quiet period after which recurring events start a new activity window
+	DefaultActivityGap = 5 * time.Minute
+
+	// maxActivityEvents caps how many events are grouped into activity windows per request
+	maxActivityEvents = 50000
+)
+
 // EventService handles event-related operations
 type EventService struct {
 	eventRepo *repository.EventRepository
@@ -25,11 +34,46 @@ func (s *EventService) GetEvent(ctx context.Context, id string) (*models.Event,
 	return s.eventRepo.GetByID(ctx, id)
 }
 
+// GetEvents retrieves the events with the given IDs in order, skipping
+// duplicates, and reports the IDs that could not be retrieved
+func (s *EventService) GetEvents(ctx context.Context, ids []string) ([]*models.Event, []models.BulkFailure) {
+	var events []*models.Event
+	result := runBulk(ctx, "get_events", ids, repository.ErrEventNotFound, func(ctx context.Context, id string) error {
+		event, err := s.eventRepo.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		events = append(events, event)
+		return nil
+	})
+	return events, result.Failed
+}
+
 // ListEvents retrieves all events with pagination
 func (s *EventService) ListEvents(ctx context.Context, limit, offset int) ([]*models.Event, error) {
 	return s.eventRepo.List(ctx, limit, offset)
 }
 
+// ListEventsBySeverity retrieves events at or above a minimum severity with pagination
+func (s *EventService) ListEventsBySeverity(ctx context.Context, minSeverity models.EventSeverity, limit, offset int) ([]*models.Event, error) {
+	return s.eventRepo.ListBySeverity(ctx, models.SeveritiesAtLeast(minSeverity), limit, offset)
+}
+
+// CountEventsBySeverity returns the number of events at or above a minimum severity
+func (s *EventService) CountEventsBySeverity(ctx context.Context, minSeverity models.EventSeverity) (int, error) {
+	return s.eventRepo.CountBySeverity(ctx, models.SeveritiesAtLeast(minSeverity))
+}
+
+// SearchEvents retrieves events matching the filters of a search
+func (s *EventService) SearchEvents(ctx context.Context, req *models.EventSearchRequest) ([]*models.Event, error) {
+	return s.eventRepo.Search(ctx, req)
+}
+
+// CountSearchEvents returns the number of events matching the filters of a search
+func (s *EventService) CountSearchEvents(ctx context.Context, req *models.EventSearchRequest) (int, error) {
+	return s.eventRepo.CountSearch(ctx, req)
+}
+
 // ListEventsByTimeRange retrieves events within a time range
 func (s *EventService) ListEventsByTimeRange(ctx context.Context, startTime, endTime time.Time, limit, offset int) ([]*models.Event, error) {
 	return s.eventRepo.ListByTimeRange(ctx, startTime, endTime, limit, offset)
@@ -50,8 +94,68 @@ func (s *EventService) AcknowledgeEvent(ctx context.Context, id string) error {
 	return s.eventRepo.Acknowledge(ctx, id)
 }
 
+// AcknowledgeEvents marks several events as acknowledged, reporting which
+// were acknowledged and why the others were not
+func (s *EventService) AcknowledgeEvents(ctx context.Context, ids []string) *models.BulkResult {
+	return runBulk(ctx, "acknowledge_events", ids, repository.ErrEventNotFound, s.eventRepo.Acknowledge)
+}
+
+// AcknowledgeCameraEvents marks all unacknowledged events of a camera as
+// acknowledged and returns how many were updated
+func (s *EventService) AcknowledgeCameraEvents(ctx context.Context, cameraID string) (int64, error) {
+	return s.eventRepo.AcknowledgeByCamera(ctx, cameraID)
+}
+
 // CountEvents returns the total number of events
 func (s *EventService) CountEvents(ctx context.Context) (int, error) {
 	return s.eventRepo.Count(ctx)
 }
 
+// ListEventActivity groups the events of a time range into activity windows.
+// Events of the same type on the same camera that are at most gap apart belong
+// to one window. Windows are returned most recently active first. At most
+// maxActivityEvents events are grouped; truncated reports that the range held more.
+func (s *EventService) ListEventActivity(ctx context.Context, cameraID string, startTime, endTime time.Time, gap time.Duration) ([]*models.EventActivity, bool, error) {
+	events, err := s.eventRepo.ListForActivity(ctx, cameraID, startTime, endTime, maxActivityEvents+1)
+	if err != nil {
+		return nil, false, err
+	}
+
+	truncated := len(events) > maxActivityEvents
+	if truncated {
+		events = events[:maxActivityEvents]
+	}
+
+	return groupEventActivity(events, gap), truncated, nil
+}
+
+// groupEventActivity builds activity windows from events ordered by camera, type and time
+func groupEventActivity(events []*models.Event, gap time.Duration) []*models.EventActivity {
+	activity := make([]*models.EventActivity, 0)
+
+	var current *models.EventActivity
+	for _, event := range events {
+		if current != nil && current.CameraID == event.CameraID && current.Type == event.Type &&
+			event.Timestamp.Sub(current.LastSeen) <= gap {
+			current.LastSeen = event.Timestamp
+			current.Count++
+			continue
+		}
+
+		current = &models.EventActivity{
+			CameraID:   event.CameraID,
+			CameraName: event.CameraName,
+			Type:       event.Type,
+			FirstSeen:  event.Timestamp,
+			LastSeen:   event.Timestamp,
+			Count:      1,
+		}
+		activity = append(activity, current)
+	}
+
+	sort.SliceStable(activity, func(i, j int) bool {
+		return activity[i].LastSeen.After(activity[j].LastSeen)
+	})
+
+	return activity
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
+)
+
+func TestEventService_ListEventActivity_WindowsDenseEvents(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	base := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	columns := []string{"id", "camera_id", "camera_name", "type", "severity", "timestamp", "acknowledged",
+		"acknowledged_at", "metadata", "snapshot_path", "video_clip_url", "created_at"}
+	rows := sqlmock.NewRows(columns)
+	add := func(cameraID string, eventType models.EventType, at time.Time) {
+		rows.AddRow("evt", cameraID, "Camera "+cameraID, string(eventType), "info", at, false, nil, "", "", "", at)
+	}
+
+	// cam-1 motion: a dense burst every 30s for 10 minutes, then a second burst after a 20 minute lull
+	for i := 0; i <= 20; i++ {
+		add("cam-1", models.EventMotionDetected, base.Add(time.Duration(i)*30*time.Second))
+	}
+	for i := 0; i < 3; i++ {
+		add("cam-1", models.EventMotionDetected, base.Add(30*time.Minute+time.Duration(i)*time.Minute))
+	}
+	// cam-1 person detections overlap the first burst but form their own window
+	add("cam-1", models.EventAIPerson, base.Add(time.Minute))
+	add("cam-1", models.EventAIPerson, base.Add(3*time.Minute))
+	// cam-2 motion at the same time as cam-1 is kept separate
+	add("cam-2", models.EventMotionDetected, base.Add(2*time.Minute))
+
+	start, end := base, base.Add(time.Hour)
+	mock.ExpectQuery(`SELECT .* FROM events`).
+		WithArgs(start, end, maxActivityEvents+1).
+		WillReturnRows(rows)
+
+	svc := NewEventService(repository.NewEventRepository(&db.DB{DB: sqlDB}))
+	activity, truncated, err := svc.ListEventActivity(context.Background(), "", start, end, 5*time.Minute)
+
+	require.NoError(t, err)
+	assert.False(t, truncated)
+	require.Len(t, activity, 4)
+
+	// Most recently active window first
+	assert.Equal(t, "cam-1", activity[0].CameraID)
+	assert.Equal(t, models.EventMotionDetected, activity[0].Type)
+	assert.Equal(t, 3, activity[0].Count)
+	assert.Equal(t, base.Add(30*time.Minute), activity[0].FirstSeen)
+	assert.Equal(t, base.Add(32*time.Minute), activity[0].LastSeen)
+
+	assert.Equal(t, models.EventMotionDetected, activity[1].Type)
+	assert.Equal(t, 21, activity[1].Count)
+	assert.Equal(t, base, activity[1].FirstSeen)
+	assert.Equal(t, base.Add(10*time.Minute), activity[1].LastSeen)
+
+	assert.Equal(t, models.EventAIPerson, activity[2].Type)
+	assert.Equal(t, 2, activity[2].Count)
+
+	assert.Equal(t, "cam-2", activity[3].CameraID)
+	assert.Equal(t, 1, activity[3].Count)
+	assert.Equal(t, "Camera cam-2", activity[3].CameraName)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGroupEventActivity_GapBoundary(t *testing.T) {
+	base := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	events := []*models.Event{
+		{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: base},
+		{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: base.Add(time.Minute)},
+		{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: base.Add(2*time.Minute + time.Second)},
+	}
+
+	activity := groupEventActivity(events, time.Minute)
+
+	require.Len(t, activity, 2)
+	assert.Equal(t, 1, activity[0].Count, "an event just past the gap starts a new window")
+	assert.Equal(t, 2, activity[1].Count, "an event exactly at the gap extends the window")
+	assert.Empty(t, groupEventActivity(nil, time.Minute))
+}
+
+func TestEventService_AcknowledgeEvents_MixedBatch(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	acknowledged := "11111111-1111-1111-1111-111111111111"
+	missing := "22222222-2222-2222-2222-222222222222"
+	mock.ExpectExec(`UPDATE events`).WithArgs(acknowledged).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE events`).WithArgs(missing).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	svc := NewEventService(repository.NewEventRepository(&db.DB{DB: sqlDB}))
+	result := svc.AcknowledgeEvents(context.Background(), []string{acknowledged, missing, "not-a-uuid"})
+
+	assert.Equal(t, []string{acknowledged}, result.Succeeded)
+	assert.Equal(t, []models.BulkFailure{
+		{ID: missing, Reason: models.BulkReasonNotFound},
+		{ID: "not-a-uuid", Reason: models.BulkReasonInvalidID},
+	}, result.Failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventService_GetEvents(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	found := "11111111-1111-1111-1111-111111111111"
+	missing := "22222222-2222-2222-2222-222222222222"
+	now := time.Now()
+	mock.ExpectQuery(`SELECT .* FROM events\s+WHERE id = \$1`).WithArgs(found).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "camera_name", "type", "severity", "timestamp",
+			"acknowledged", "acknowledged_at", "metadata", "snapshot_path", "video_clip_url", "created_at"}).
+			AddRow(found, "cam-1", "Front", "ai_person", "warning", now, false, nil, "", "/snapshots/1.jpg", "", now))
+	mock.ExpectQuery(`SELECT .* FROM events\s+WHERE id = \$1`).WithArgs(missing).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	svc := NewEventService(repository.NewEventRepository(&db.DB{DB: sqlDB}))
+	events, failed := svc.GetEvents(context.Background(), []string{found, missing, found, "not-a-uuid"})
+
+	require.Len(t, events, 1)
+	assert.Equal(t, "/snapshots/1.jpg", events[0].SnapshotPath)
+	assert.Equal(t, []models.BulkFailure{
+		{ID: missing, Reason: models.BulkReasonNotFound},
+		{ID: "not-a-uuid", Reason: models.BulkReasonInvalidID},
+	}, failed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
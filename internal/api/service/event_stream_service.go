@@ -2,11 +2,22 @@ package service
 
 import (
 	"context"
+	"slices"
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
+// DefaultBlockTimeout is how long a blocking subscriber's full buffer may
+// hold up an event before it is dropped, when no timeout is given
+const DefaultBlockTimeout = time.Second
+
 // EventSubscriber interface for event subscription
 type EventSubscriber interface {
 	OnEvent(event *models.Event) error
@@ -21,16 +32,71 @@ type EventProcessor interface {
 type EventStreamService struct {
 	processor   EventProcessor
 	subscribers map[string]*EventStreamSubscriber
-	mu          sync.RWMutex
+
+	// cameraSubscribers counts the subscribers of each camera, with
+	// subscribers to all cameras under the empty ID
+	cameraSubscribers map[string]int
+	mu                sync.RWMutex
 }
 
 // EventStreamSubscriber represents a client subscribed to events
 type EventStreamSubscriber struct {
 	ID       string
-	CameraID string // Empty string means all cameras
+	Filter   EventStreamFilter
+	Delivery EventDelivery
 	EventCh  chan *models.Event
 	ctx      context.Context
 	cancel   context.CancelFunc
+
+	// pending queues the events of blocking subscribers for their own
+	// goroutine, which waits for buffer space; nil for dropping subscribers
+	pending chan *models.Event
+
+	// dropped counts the events not delivered because the buffer was full
+	dropped atomic.Uint64
+}
+
+// Dropped returns the number of events the subscriber missed because its
+// buffer was full
+func (s *EventStreamSubscriber) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// EventDelivery is how events reach a subscriber whose buffer is full. The
+// zero value drops them immediately, so a slow client never holds up others.
+type EventDelivery struct {
+	// Block waits up to Timeout for buffer space before dropping an event.
+	// The wait happens on the subscriber's own goroutine, so other
+	// subscribers are never held up; events arriving while a further buffer
+	// of the same size is full are dropped.
+	Block   bool
+	Timeout time.Duration // Zero uses DefaultBlockTimeout
+}
+
+// SubscriberStats describes the delivery of events to one subscriber
+type SubscriberStats struct {
+	ID         string             `json:"id"`
+	CameraID   string             `json:"camera_id,omitempty"`
+	Types      []models.EventType `json:"types,omitempty"`
+	Delivery   string             `json:"delivery"`
+	BufferSize int                `json:"buffer_size"`
+	Buffered   int                `json:"buffered"`
+	Dropped    uint64             `json:"dropped"`
+}
+
+// EventStreamFilter selects the events sent to a subscriber; the zero filter
+// passes every event
+type EventStreamFilter struct {
+	CameraID string             // Empty string means all cameras
+	Types    []models.EventType // Empty means all types
+}
+
+// Matches reports whether an event passes the filter
+func (f EventStreamFilter) Matches(event *models.Event) bool {
+	if f.CameraID != "" && f.CameraID != event.CameraID {
+		return false
+	}
+	return len(f.Types) == 0 || slices.Contains(f.Types, event.Type)
 }
 
 // ProcessorAdapter adapts any processor with a Subscribe method to EventProcessor
@@ -50,28 +116,51 @@ func NewProcessorAdapter(subscribe func(EventSubscriber)) *ProcessorAdapter {
 // NewEventStreamService creates a new event stream service
 func NewEventStreamService(processor EventProcessor) *EventStreamService {
 	return &EventStreamService{
-		processor:   processor,
-		subscribers: make(map[string]*EventStreamSubscriber),
+		processor:         processor,
+		subscribers:       make(map[string]*EventStreamSubscriber),
+		cameraSubscribers: make(map[string]int),
 	}
 }
 
-// Subscribe creates a new event subscription
-func (s *EventStreamService) Subscribe(ctx context.Context, subscriberID string, cameraID string, bufferSize int) *EventStreamSubscriber {
+// Subscribe creates a new event subscription receiving the events passing
+// filter. Events arriving while its buffer is full are dropped.
+func (s *EventStreamService) Subscribe(ctx context.Context, subscriberID string, filter EventStreamFilter, bufferSize int) *EventStreamSubscriber {
+	return s.SubscribeWithDelivery(ctx, subscriberID, filter, bufferSize, EventDelivery{})
+}
+
+// SubscribeWithDelivery creates a new event subscription receiving the events
+// passing filter, delivered as chosen by delivery
+func (s *EventStreamService) SubscribeWithDelivery(ctx context.Context, subscriberID string, filter EventStreamFilter, bufferSize int, delivery EventDelivery) *EventStreamSubscriber {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	// Create subscriber context
 	subCtx, cancel := context.WithCancel(ctx)
 
+	if delivery.Block && delivery.Timeout <= 0 {
+		delivery.Timeout = DefaultBlockTimeout
+	}
 	subscriber := &EventStreamSubscriber{
 		ID:       subscriberID,
-		CameraID: cameraID,
+		Filter:   filter,
+		Delivery: delivery,
 		EventCh:  make(chan *models.Event, bufferSize),
 		ctx:      subCtx,
 		cancel:   cancel,
 	}
+	if delivery.Block {
+		subscriber.pending = make(chan *models.Event, bufferSize)
+		go subscriber.forward()
+	}
 
+	if existing, exists := s.subscribers[subscriberID]; exists {
+		s.removeCameraSubscriber(existing.Filter.CameraID)
+		if existing.pending != nil {
+			existing.cancel()
+		}
+	}
 	s.subscribers[subscriberID] = subscriber
+	s.cameraSubscribers[filter.CameraID]++
 
 	// Register with event processor if this is the first subscriber
 	if len(s.subscribers) == 1 {
@@ -88,8 +177,21 @@ func (s *EventStreamService) Unsubscribe(subscriberID string) {
 
 	if subscriber, exists := s.subscribers[subscriberID]; exists {
 		subscriber.cancel()
-		close(subscriber.EventCh)
+		// Blocking subscribers' goroutines close their channel on their own
+		if subscriber.pending == nil {
+			close(subscriber.EventCh)
+		}
 		delete(s.subscribers, subscriberID)
+		s.removeCameraSubscriber(subscriber.Filter.CameraID)
+	}
+}
+
+// removeCameraSubscriber decrements a camera's subscriber count, dropping
+// cameras without subscribers. The caller must hold the lock.
+func (s *EventStreamService) removeCameraSubscriber(cameraID string) {
+	s.cameraSubscribers[cameraID]--
+	if s.cameraSubscribers[cameraID] <= 0 {
+		delete(s.cameraSubscribers, cameraID)
 	}
 }
 
@@ -100,24 +202,74 @@ func (s *EventStreamService) OnEvent(event *models.Event) error {
 
 	// Broadcast to all subscribers
 	for _, subscriber := range s.subscribers {
-		// Filter by camera ID if specified
-		if subscriber.CameraID != "" && subscriber.CameraID != event.CameraID {
+		if !subscriber.Filter.Matches(event) {
 			continue
 		}
+		subscriber.deliver(event)
+	}
 
-		// Non-blocking send
+	return nil
+}
+
+// deliver hands an event to the subscriber without waiting, counting it as
+// dropped when the buffer is full. Blocking subscribers get the event queued
+// for their goroutine.
+func (s *EventStreamSubscriber) deliver(event *models.Event) {
+	ch := s.EventCh
+	if s.pending != nil {
+		ch = s.pending
+	}
+
+	// Non-blocking send
+	select {
+	case ch <- event:
+		return
+	case <-s.ctx.Done():
+		// Subscriber context cancelled, skip
+		return
+	default:
+	}
+
+	// Channel full, skip this event for this subscriber
+	s.drop(event)
+}
+
+// forward moves the queued events of a blocking subscriber to its buffer,
+// waiting up to the delivery timeout for space. It closes the buffer once
+// the subscriber is cancelled.
+func (s *EventStreamSubscriber) forward() {
+	defer close(s.EventCh)
+
+	timer := time.NewTimer(s.Delivery.Timeout)
+	timer.Stop()
+	for {
+		var event *models.Event
 		select {
-		case subscriber.EventCh <- event:
-			// Event sent successfully
-		case <-subscriber.ctx.Done():
-			// Subscriber context cancelled, skip
-		default:
-			// Channel full, skip this event for this subscriber
-			// In production, you might want to log this
+		case event = <-s.pending:
+		case <-s.ctx.Done():
+			return
 		}
+
+		timer.Reset(s.Delivery.Timeout)
+		select {
+		case s.EventCh <- event:
+		case <-s.ctx.Done():
+			return
+		case <-timer.C:
+			s.drop(event)
+		}
+		timer.Stop()
 	}
+}
 
-	return nil
+// drop counts an event the subscriber missed, logging as the count grows
+func (s *EventStreamSubscriber) drop(event *models.Event) {
+	if dropped := s.dropped.Add(1); dropped == 1 || dropped%100 == 0 {
+		logger.Warn("Event stream subscriber is falling behind, dropping events",
+			zap.String("subscriber_id", s.ID),
+			zap.String("event_id", event.ID),
+			zap.Uint64("dropped", dropped))
+	}
 }
 
 // GetSubscriberCount returns the number of active subscribers
@@ -126,3 +278,37 @@ func (s *EventStreamService) GetSubscriberCount() int {
 	defer s.mu.RUnlock()
 	return len(s.subscribers)
 }
+
+// GetCameraSubscriberCount returns the number of active subscribers to a
+// camera's events, not counting subscribers to all cameras
+func (s *EventStreamService) GetCameraSubscriberCount(cameraID string) int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.cameraSubscribers[cameraID]
+}
+
+// GetSubscriberStats returns the delivery statistics of every active
+// subscriber, ordered by ID
+func (s *EventStreamService) GetSubscriberStats() []SubscriberStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	stats := make([]SubscriberStats, 0, len(s.subscribers))
+	for _, subscriber := range s.subscribers {
+		delivery := "drop"
+		if subscriber.Delivery.Block {
+			delivery = "block"
+		}
+		stats = append(stats, SubscriberStats{
+			ID:         subscriber.ID,
+			CameraID:   subscriber.Filter.CameraID,
+			Types:      subscriber.Filter.Types,
+			Delivery:   delivery,
+			BufferSize: cap(subscriber.EventCh),
+			Buffered:   len(subscriber.EventCh) + len(subscriber.pending),
+			Dropped:    subscriber.Dropped(),
+		})
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].ID < stats[j].ID })
+	return stats
+}
@@ -41,11 +41,11 @@ func TestEventStreamService_Subscribe(t *testing.T) {
 	service := NewEventStreamService(mockProcessor)
 	ctx := context.Background()
 
-	subscriber := service.Subscribe(ctx, "sub-1", "", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
 
 	assert.NotNil(t, subscriber)
 	assert.Equal(t, "sub-1", subscriber.ID)
-	assert.Equal(t, "", subscriber.CameraID)
+	assert.Equal(t, "", subscriber.Filter.CameraID)
 	assert.NotNil(t, subscriber.EventCh)
 	assert.Equal(t, 1, len(service.subscribers))
 	assert.Equal(t, 1, len(mockProcessor.subscribers))
@@ -56,11 +56,11 @@ func TestEventStreamService_Subscribe_WithCameraID(t *testing.T) {
 	service := NewEventStreamService(mockProcessor)
 	ctx := context.Background()
 
-	subscriber := service.Subscribe(ctx, "sub-1", "cam-123", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{CameraID: "cam-123"}, 10)
 
 	assert.NotNil(t, subscriber)
 	assert.Equal(t, "sub-1", subscriber.ID)
-	assert.Equal(t, "cam-123", subscriber.CameraID)
+	assert.Equal(t, "cam-123", subscriber.Filter.CameraID)
 }
 
 func TestEventStreamService_Unsubscribe(t *testing.T) {
@@ -68,7 +68,7 @@ func TestEventStreamService_Unsubscribe(t *testing.T) {
 	service := NewEventStreamService(mockProcessor)
 	ctx := context.Background()
 
-	subscriber := service.Subscribe(ctx, "sub-1", "", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
 	assert.Equal(t, 1, len(service.subscribers))
 
 	service.Unsubscribe("sub-1")
@@ -84,7 +84,7 @@ func TestEventStreamService_OnEvent(t *testing.T) {
 	service := NewEventStreamService(mockProcessor)
 	ctx := context.Background()
 
-	subscriber := service.Subscribe(ctx, "sub-1", "", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
 
 	event := &models.Event{
 		ID:       "evt-1",
@@ -111,7 +111,7 @@ func TestEventStreamService_OnEvent_FilterByCameraID(t *testing.T) {
 	ctx := context.Background()
 
 	// Subscribe to specific camera
-	subscriber := service.Subscribe(ctx, "sub-1", "cam-123", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{CameraID: "cam-123"}, 10)
 
 	// Send event from different camera
 	event1 := &models.Event{
@@ -155,8 +155,8 @@ func TestEventStreamService_OnEvent_MultipleSubscribers(t *testing.T) {
 	service := NewEventStreamService(mockProcessor)
 	ctx := context.Background()
 
-	subscriber1 := service.Subscribe(ctx, "sub-1", "", 10)
-	subscriber2 := service.Subscribe(ctx, "sub-2", "", 10)
+	subscriber1 := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
+	subscriber2 := service.Subscribe(ctx, "sub-2", EventStreamFilter{}, 10)
 
 	event := &models.Event{
 		ID:       "evt-1",
@@ -189,7 +189,7 @@ func TestEventStreamService_OnEvent_ChannelFull(t *testing.T) {
 	ctx := context.Background()
 
 	// Create subscriber with small buffer
-	subscriber := service.Subscribe(ctx, "sub-1", "", 2)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 2)
 
 	// Fill the channel
 	for i := 0; i < 2; i++ {
@@ -224,12 +224,112 @@ func TestEventStreamService_OnEvent_ChannelFull(t *testing.T) {
 	}
 }
 
+func TestEventStreamService_OnEvent_CountsDropped(t *testing.T) {
+	service := NewEventStreamService(&MockEventProcessor{})
+	ctx := context.Background()
+
+	full := service.Subscribe(ctx, "sub-full", EventStreamFilter{}, 2)
+	other := service.Subscribe(ctx, "sub-other", EventStreamFilter{CameraID: "cam-2"}, 2)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, service.OnEvent(&models.Event{ID: "evt", CameraID: "cam-1", Type: models.EventMotionDetected}))
+	}
+
+	// The two buffered events are delivered, the three after them dropped;
+	// subscribers the events do not pass the filter of drop nothing
+	assert.Equal(t, uint64(3), full.Dropped())
+	assert.Equal(t, uint64(0), other.Dropped())
+
+	// Once the client catches up, events are delivered again
+	<-full.EventCh
+	require.NoError(t, service.OnEvent(&models.Event{ID: "evt", CameraID: "cam-1", Type: models.EventMotionDetected}))
+	assert.Equal(t, uint64(3), full.Dropped())
+	assert.Len(t, full.EventCh, 2)
+}
+
+func TestEventStreamService_OnEvent_BlockingDelivery(t *testing.T) {
+	service := NewEventStreamService(&MockEventProcessor{})
+	ctx := context.Background()
+
+	subscriber := service.SubscribeWithDelivery(ctx, "sub-1", EventStreamFilter{}, 1, EventDelivery{Block: true, Timeout: 500 * time.Millisecond})
+	require.NoError(t, service.OnEvent(&models.Event{ID: "evt-1"}))
+	require.Eventually(t, func() bool { return len(subscriber.EventCh) == 1 }, time.Second, time.Millisecond)
+
+	// A client catching up within the timeout receives the event that arrived
+	// while its buffer was full
+	require.NoError(t, service.OnEvent(&models.Event{ID: "evt-2"}))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, "evt-1", (<-subscriber.EventCh).ID)
+	assert.Equal(t, "evt-2", (<-subscriber.EventCh).ID)
+	assert.Equal(t, uint64(0), subscriber.Dropped())
+
+	// A client that stays behind has the event dropped after the timeout,
+	// without holding up the event source
+	blocking := service.SubscribeWithDelivery(ctx, "sub-2", EventStreamFilter{}, 1, EventDelivery{Block: true, Timeout: 20 * time.Millisecond})
+	require.NoError(t, service.OnEvent(&models.Event{ID: "evt-3"}))
+	require.Eventually(t, func() bool { return len(blocking.EventCh) == 1 }, time.Second, time.Millisecond)
+	start := time.Now()
+	require.NoError(t, service.OnEvent(&models.Event{ID: "evt-4"}))
+	assert.Less(t, time.Since(start), 20*time.Millisecond)
+	require.Eventually(t, func() bool { return blocking.Dropped() == 1 }, time.Second, time.Millisecond)
+
+	// Blocking subscribers close their channel once unsubscribed
+	service.Unsubscribe("sub-2")
+	<-blocking.EventCh
+	_, ok := <-blocking.EventCh
+	assert.False(t, ok)
+}
+
+func TestEventStreamService_OnEvent_BlockingSubscriberDoesNotHoldUpOthers(t *testing.T) {
+	service := NewEventStreamService(&MockEventProcessor{})
+	ctx := context.Background()
+
+	// A client that never reads, asking for the longest wait
+	stalled := service.SubscribeWithDelivery(ctx, "stalled", EventStreamFilter{}, 1, EventDelivery{Block: true, Timeout: 5 * time.Second})
+	other := service.Subscribe(ctx, "other", EventStreamFilter{}, 10)
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		require.NoError(t, service.OnEvent(&models.Event{ID: "evt"}))
+	}
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, other.EventCh, 5)
+
+	// Subscribing and unsubscribing is not held up either
+	service.Subscribe(ctx, "late", EventStreamFilter{}, 1)
+	service.Unsubscribe("late")
+	service.Unsubscribe("stalled")
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, uint64(0), other.Dropped())
+	assert.NotZero(t, stalled.Dropped())
+}
+
+func TestEventStreamService_GetSubscriberStats(t *testing.T) {
+	service := NewEventStreamService(&MockEventProcessor{})
+	ctx := context.Background()
+
+	service.Subscribe(ctx, "sub-b", EventStreamFilter{CameraID: "cam-1"}, 1)
+	service.SubscribeWithDelivery(ctx, "sub-a", EventStreamFilter{Types: []models.EventType{models.EventAIPerson}}, 10, EventDelivery{Block: true})
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, service.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected}))
+	}
+
+	assert.Equal(t, []SubscriberStats{
+		{ID: "sub-a", Types: []models.EventType{models.EventAIPerson}, Delivery: "block", BufferSize: 10},
+		{ID: "sub-b", CameraID: "cam-1", Delivery: "drop", BufferSize: 1, Buffered: 1, Dropped: 2},
+	}, service.GetSubscriberStats())
+
+	service.Unsubscribe("sub-b")
+	assert.Len(t, service.GetSubscriberStats(), 1)
+}
+
 func TestEventStreamService_OnEvent_CancelledContext(t *testing.T) {
 	mockProcessor := &MockEventProcessor{}
 	service := NewEventStreamService(mockProcessor)
 	ctx, cancel := context.WithCancel(context.Background())
 
-	subscriber := service.Subscribe(ctx, "sub-1", "", 10)
+	subscriber := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
 
 	// Cancel the context
 	cancel()
@@ -260,10 +360,10 @@ func TestEventStreamService_GetSubscriberCount(t *testing.T) {
 
 	assert.Equal(t, 0, service.GetSubscriberCount())
 
-	service.Subscribe(ctx, "sub-1", "", 10)
+	service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
 	assert.Equal(t, 1, service.GetSubscriberCount())
 
-	service.Subscribe(ctx, "sub-2", "", 10)
+	service.Subscribe(ctx, "sub-2", EventStreamFilter{}, 10)
 	assert.Equal(t, 2, service.GetSubscriberCount())
 
 	service.Unsubscribe("sub-1")
@@ -273,6 +373,40 @@ func TestEventStreamService_GetSubscriberCount(t *testing.T) {
 	assert.Equal(t, 0, service.GetSubscriberCount())
 }
 
+func TestEventStreamService_GetCameraSubscriberCount(t *testing.T) {
+	mockProcessor := &MockEventProcessor{}
+	service := NewEventStreamService(mockProcessor)
+	ctx := context.Background()
+
+	assert.Equal(t, 0, service.GetCameraSubscriberCount("cam-1"))
+
+	service.Subscribe(ctx, "sub-1", EventStreamFilter{CameraID: "cam-1"}, 10)
+	service.Subscribe(ctx, "sub-2", EventStreamFilter{CameraID: "cam-1"}, 10)
+	service.Subscribe(ctx, "sub-3", EventStreamFilter{CameraID: "cam-2"}, 10)
+	service.Subscribe(ctx, "sub-4", EventStreamFilter{}, 10)
+	assert.Equal(t, 2, service.GetCameraSubscriberCount("cam-1"))
+	assert.Equal(t, 1, service.GetCameraSubscriberCount("cam-2"))
+	assert.Equal(t, 1, service.GetCameraSubscriberCount(""), "subscribers to all cameras are counted separately")
+
+	// Resubscribing under the same ID moves the subscriber
+	service.Subscribe(ctx, "sub-2", EventStreamFilter{CameraID: "cam-2"}, 10)
+	assert.Equal(t, 1, service.GetCameraSubscriberCount("cam-1"))
+	assert.Equal(t, 2, service.GetCameraSubscriberCount("cam-2"))
+
+	service.Unsubscribe("sub-1")
+	assert.Equal(t, 0, service.GetCameraSubscriberCount("cam-1"))
+	assert.NotContains(t, service.cameraSubscribers, "cam-1")
+
+	// Unknown subscribers leave the counts alone
+	service.Unsubscribe("sub-1")
+	assert.Equal(t, 2, service.GetCameraSubscriberCount("cam-2"))
+
+	service.Unsubscribe("sub-2")
+	service.Unsubscribe("sub-3")
+	service.Unsubscribe("sub-4")
+	assert.Empty(t, service.cameraSubscribers)
+}
+
 // MockSubscriber implements EventSubscriber for testing
 type MockSubscriber struct {
 	events []*models.Event
@@ -305,9 +439,9 @@ func TestEventStreamService_Integration(t *testing.T) {
 	ctx := context.Background()
 
 	// Create multiple subscribers
-	sub1 := service.Subscribe(ctx, "sub-1", "", 10)
-	sub2 := service.Subscribe(ctx, "sub-2", "cam-123", 10)
-	sub3 := service.Subscribe(ctx, "sub-3", "cam-456", 10)
+	sub1 := service.Subscribe(ctx, "sub-1", EventStreamFilter{}, 10)
+	sub2 := service.Subscribe(ctx, "sub-2", EventStreamFilter{CameraID: "cam-123"}, 10)
+	sub3 := service.Subscribe(ctx, "sub-3", EventStreamFilter{CameraID: "cam-456"}, 10)
 
 	require.Equal(t, 1, len(mockProcessor.subscribers))
 
@@ -342,3 +476,25 @@ func TestEventStreamService_Integration(t *testing.T) {
 		// Expected
 	}
 }
+
+func TestEventStreamFilter_Matches(t *testing.T) {
+	person := &models.Event{CameraID: "cam-1", Type: models.EventAIPerson}
+	motion := &models.Event{CameraID: "cam-2", Type: models.EventMotionDetected}
+
+	tests := []struct {
+		name   string
+		filter EventStreamFilter
+		want   []bool // person, motion
+	}{
+		{"no filter", EventStreamFilter{}, []bool{true, true}},
+		{"camera", EventStreamFilter{CameraID: "cam-1"}, []bool{true, false}},
+		{"types", EventStreamFilter{Types: []models.EventType{models.EventMotionDetected, models.EventAIVehicle}}, []bool{false, true}},
+		{"camera and types", EventStreamFilter{CameraID: "cam-2", Types: []models.EventType{models.EventAIPerson}}, []bool{false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, []bool{tt.filter.Matches(person), tt.filter.Matches(motion)})
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ffmpegProbeTimeout bounds how long `ffmpeg -version` may take
+const ffmpegProbeTimeout = 5 * time.Second
+
+// FFmpegInfo describes the FFmpeg binary used for HLS and preview transcoding
+type FFmpegInfo struct {
+	Available bool   `json:"available"`
+	Path      string `json:"path"`
+	Version   string `json:"version,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// ProbeFFmpeg runs `ffmpeg -version` and reports whether FFmpeg can be used
+func ProbeFFmpeg(path string) *FFmpegInfo {
+	info := &FFmpegInfo{Path: path}
+
+	ctx, cancel := context.WithTimeout(context.Background(), ffmpegProbeTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "-version").Output()
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Available = true
+	info.Version = parseFFmpegVersion(string(output))
+	return info
+}
+
+// parseFFmpegVersion extracts the version from the first line of `ffmpeg -version`,
+// e.g. "ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers"
+func parseFFmpegVersion(output string) string {
+	firstLine, _, _ := strings.Cut(output, "\n")
+	fields := strings.Fields(firstLine)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] == "version" {
+			return fields[i+1]
+		}
+	}
+	return strings.TrimSpace(firstLine)
+}
+
+// ProbeFFmpeg reports availability and version of the FFmpeg binary the service uses
+func (s *StreamService) ProbeFFmpeg() *FFmpegInfo {
+	return ProbeFFmpeg(s.ffmpegPath)
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// FFmpegProcess is an FFmpeg process working on a session directory of the
+// HLS output directory. Arguments are not reported since they carry camera
+// credentials in the RTSP URL.
+type FFmpegProcess struct {
+	PID       int    `json:"pid"`
+	ParentPID int    `json:"parent_pid"`
+	SessionID string `json:"session_id"`
+}
+
+// processInfo is an entry of the process table
+type processInfo struct {
+	pid  int
+	ppid int
+	args []string
+	cwd  string // Empty if it could not be read
+}
+
+// processTable lists and kills processes (replaced in tests)
+type processTable interface {
+	list() ([]processInfo, error)
+	kill(pid int) error
+}
+
+// procfsTable is the process table of /proc
+type procfsTable struct{}
+
+func (procfsTable) list() ([]processInfo, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read process table: %w", err)
+	}
+
+	var processes []processInfo
+	for _, entry := range entries {
+		pid, err := strconv.Atoi(entry.Name())
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join("/proc", entry.Name())
+
+		// Processes may exit while the table is read; they are skipped
+		cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			continue
+		}
+		stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+		if err != nil {
+			continue
+		}
+		ppid, ok := parseProcStatPPID(string(stat))
+		if !ok {
+			continue
+		}
+		cwd, _ := os.Readlink(filepath.Join(dir, "cwd"))
+
+		processes = append(processes, processInfo{
+			pid:  pid,
+			ppid: ppid,
+			args: strings.Split(string(bytes.TrimRight(cmdline, "\x00")), "\x00"),
+			cwd:  strings.TrimSuffix(cwd, " (deleted)"),
+		})
+	}
+	return processes, nil
+}
+
+func (procfsTable) kill(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+	return process.Kill()
+}
+
+// parseProcStatPPID returns the parent PID from the contents of /proc/<pid>/stat,
+// e.g. "1234 (ffmpeg) S 1 ...". The command name may contain spaces and
+// parentheses, so fields are counted from the last ")".
+func parseProcStatPPID(stat string) (int, bool) {
+	end := strings.LastIndex(stat, ")")
+	if end < 0 {
+		return 0, false
+	}
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 2 {
+		return 0, false
+	}
+	ppid, err := strconv.Atoi(fields[1])
+	return ppid, err == nil
+}
+
+// FindOrphanedFFmpeg returns the FFmpeg processes working on a session
+// directory of the HLS output directory that were not started by this server
+// process, e.g. ones that survived a crash of a previous run
+func (s *StreamService) FindOrphanedFFmpeg() ([]FFmpegProcess, error) {
+	processes, err := s.processes.list()
+	if err != nil {
+		return nil, err
+	}
+
+	outputDir, err := filepath.Abs(s.hlsOutputDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve HLS output directory: %w", err)
+	}
+
+	self := os.Getpid()
+	ffmpegName := filepath.Base(s.ffmpegPath)
+
+	orphans := []FFmpegProcess{}
+	for _, process := range processes {
+		if process.ppid == self || filepath.Base(process.args[0]) != ffmpegName {
+			continue
+		}
+		sessionID := ffmpegSessionID(outputDir, process)
+		if sessionID == "" {
+			continue
+		}
+		orphans = append(orphans, FFmpegProcess{
+			PID:       process.pid,
+			ParentPID: process.ppid,
+			SessionID: sessionID,
+		})
+	}
+	return orphans, nil
+}
+
+// StopOrphanedFFmpeg kills the processes reported by FindOrphanedFFmpeg and
+// returns the ones it stopped
+func (s *StreamService) StopOrphanedFFmpeg() ([]FFmpegProcess, error) {
+	orphans, err := s.FindOrphanedFFmpeg()
+	if err != nil {
+		return nil, err
+	}
+
+	stopped := []FFmpegProcess{}
+	for _, orphan := range orphans {
+		if err := s.processes.kill(orphan.PID); err != nil {
+			logger.Warn("Failed to stop orphaned FFmpeg process",
+				zap.Int("pid", orphan.PID),
+				zap.String("session_id", orphan.SessionID),
+				zap.Error(err))
+			continue
+		}
+		stopped = append(stopped, orphan)
+	}
+
+	if len(stopped) > 0 {
+		logger.Info("Stopped orphaned FFmpeg processes",
+			zap.String("dir", s.hlsOutputDir),
+			zap.Int("count", len(stopped)))
+	}
+	return stopped, nil
+}
+
+// ffmpegSessionID returns the ID of the session whose directory in outputDir
+// a process works on, or "" if it works on none. HLS sessions name the
+// directory in their output arguments; WebRTC sessions run inside it.
+func ffmpegSessionID(outputDir string, process processInfo) string {
+	candidates := append([]string{process.cwd}, process.args[1:]...)
+	for _, candidate := range candidates {
+		if candidate == "" {
+			continue
+		}
+		path, err := filepath.Abs(candidate)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(outputDir, path)
+		if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		sessionID, _, _ := strings.Cut(rel, string(filepath.Separator))
+		if _, err := uuid.Parse(sessionID); err == nil {
+			return sessionID
+		}
+	}
+	return ""
+}
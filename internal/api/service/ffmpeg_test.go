@@ -0,0 +1,38 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProbeFFmpeg_Present(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "ffmpeg")
+	err := os.WriteFile(script, []byte("#!/bin/sh\n"+
+		"echo 'ffmpeg version 6.1.1-3ubuntu5 Copyright (c) 2000-2023 the FFmpeg developers'\n"+
+		"echo 'built with gcc 13'\n"), 0755)
+	require.NoError(t, err)
+
+	info := ProbeFFmpeg(script)
+
+	assert.True(t, info.Available)
+	assert.Equal(t, "6.1.1-3ubuntu5", info.Version)
+	assert.Equal(t, script, info.Path)
+	assert.Empty(t, info.Error)
+}
+
+func TestProbeFFmpeg_Absent(t *testing.T) {
+	info := ProbeFFmpeg(filepath.Join(t.TempDir(), "missing-ffmpeg"))
+
+	assert.False(t, info.Available)
+	assert.Empty(t, info.Version)
+	assert.NotEmpty(t, info.Error)
+}
+
+func TestParseFFmpegVersion(t *testing.T) {
+	assert.Equal(t, "n7.0", parseFFmpegVersion("ffmpeg version n7.0 Copyright (c) 2000-2024\nmore"))
+	assert.Equal(t, "custom build", parseFFmpegVersion("custom build\n"))
+}
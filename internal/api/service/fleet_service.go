@@ -0,0 +1,208 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+const (
+	// firmwareCheckConcurrency caps how many cameras are asked for firmware updates at once
+	firmwareCheckConcurrency = 4
+
+	// firmwareCheckTimeout bounds asking a single camera for firmware updates
+	firmwareCheckTimeout = 10 * time.Second
+)
+
+// CameraLister lists the configured cameras
+type CameraLister interface {
+	List(ctx context.Context) ([]*models.Camera, error)
+}
+
+// FleetService reports on the cameras as a whole
+type FleetService struct {
+	cameraRepo    CameraLister
+	cameraManager CameraManager
+}
+
+// NewFleetService creates a new fleet service
+func NewFleetService(cameraRepo CameraLister, cameraManager CameraManager) *FleetService {
+	return &FleetService{
+		cameraRepo:    cameraRepo,
+		cameraManager: cameraManager,
+	}
+}
+
+// FirmwareReport lists the firmware of every camera, grouped by model. The
+// versions are those stored for the cameras; with checkUpdates every
+// connected camera is also asked whether newer firmware is available.
+func (s *FleetService) FirmwareReport(ctx context.Context, checkUpdates bool) (*models.FirmwareReport, error) {
+	cameras, err := s.cameraRepo.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]models.CameraFirmware, len(cameras))
+	for i, cam := range cameras {
+		entries[i] = models.CameraFirmware{
+			CameraID:    cam.ID,
+			Name:        cam.Name,
+			FirmwareVer: cam.FirmwareVer,
+		}
+	}
+	if checkUpdates {
+		s.checkUpdates(ctx, entries)
+	}
+
+	report := &models.FirmwareReport{
+		Models:         []models.FirmwareModel{},
+		Total:          len(cameras),
+		UpdatesChecked: checkUpdates,
+	}
+
+	byModel := make(map[string]*models.FirmwareModel)
+	var order []string
+	for i, cam := range cameras {
+		group, ok := byModel[cam.Model]
+		if !ok {
+			group = &models.FirmwareModel{Model: cam.Model, Versions: []string{}}
+			byModel[cam.Model] = group
+			order = append(order, cam.Model)
+		}
+		group.Cameras = append(group.Cameras, entries[i])
+	}
+	sort.Strings(order)
+
+	for _, model := range order {
+		group := byModel[model]
+		summarizeFirmware(group)
+		for _, entry := range group.Cameras {
+			if entry.Behind {
+				report.Behind++
+			}
+			if entry.UpdateAvailable != nil && *entry.UpdateAvailable {
+				report.UpdatesAvailable++
+			}
+		}
+		report.Models = append(report.Models, *group)
+	}
+	return report, nil
+}
+
+// summarizeFirmware fills in the versions of a model and marks its cameras
+// running older firmware than the newest one seen
+func summarizeFirmware(group *models.FirmwareModel) {
+	seen := make(map[string]bool)
+	for _, entry := range group.Cameras {
+		if entry.FirmwareVer != "" && !seen[entry.FirmwareVer] {
+			seen[entry.FirmwareVer] = true
+			group.Versions = append(group.Versions, entry.FirmwareVer)
+		}
+	}
+	sort.SliceStable(group.Versions, func(i, j int) bool {
+		return compareFirmware(group.Versions[i], group.Versions[j]) > 0
+	})
+	if len(group.Versions) > 0 {
+		group.LatestVersion = group.Versions[0]
+	}
+	group.Drift = len(group.Versions) > 1
+
+	for i := range group.Cameras {
+		entry := &group.Cameras[i]
+		entry.Behind = entry.FirmwareVer != "" && compareFirmware(entry.FirmwareVer, group.LatestVersion) < 0
+	}
+	sort.SliceStable(group.Cameras, func(i, j int) bool {
+		if group.Cameras[i].Name != group.Cameras[j].Name {
+			return group.Cameras[i].Name < group.Cameras[j].Name
+		}
+		return group.Cameras[i].CameraID < group.Cameras[j].CameraID
+	})
+}
+
+// checkUpdates asks the cameras of entries whether newer firmware is
+// available, a few at a time
+func (s *FleetService) checkUpdates(ctx context.Context, entries []models.CameraFirmware) {
+	sem := make(chan struct{}, firmwareCheckConcurrency)
+	var wg sync.WaitGroup
+	for i := range entries {
+		wg.Add(1)
+		go func(entry *models.CameraFirmware) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			available, err := s.checkUpdate(ctx, entry.CameraID)
+			if err != nil {
+				entry.CheckError = err.Error()
+				return
+			}
+			entry.UpdateAvailable = &available
+		}(&entries[i])
+	}
+	wg.Wait()
+}
+
+// checkUpdate asks a camera whether newer firmware is available
+func (s *FleetService) checkUpdate(ctx context.Context, cameraID string) (bool, error) {
+	client, err := s.cameraManager.GetCamera(cameraID)
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, firmwareCheckTimeout)
+	defer cancel()
+
+	check, err := client.CheckFirmware(ctx)
+	if err != nil {
+		return false, err
+	}
+	return check.NewFirmware == 1, nil
+}
+
+// compareFirmware compares firmware versions such as "v3.1.0.956_22041503"
+// by the numbers they contain, returning -1, 0 or 1
+func compareFirmware(a, b string) int {
+	na, nb := firmwareNumbers(a), firmwareNumbers(b)
+	for i := 0; i < len(na) && i < len(nb); i++ {
+		switch {
+		case na[i] < nb[i]:
+			return -1
+		case na[i] > nb[i]:
+			return 1
+		}
+	}
+	switch {
+	case len(na) < len(nb):
+		return -1
+	case len(na) > len(nb):
+		return 1
+	}
+	return 0
+}
+
+// firmwareNumbers returns the runs of digits in a firmware version
+func firmwareNumbers(version string) []int {
+	var numbers []int
+	start := -1
+	for i, r := range version + " " {
+		if r >= '0' && r <= '9' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			n, err := strconv.Atoi(version[start:i])
+			if err != nil {
+				n = int(^uint(0) >> 1) // too long to parse, treat as newest
+			}
+			numbers = append(numbers, n)
+			start = -1
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// stubCameraLister lists a fixed set of cameras
+type stubCameraLister struct {
+	cameras []*models.Camera
+	err     error
+}
+
+func (s *stubCameraLister) List(ctx context.Context) ([]*models.Camera, error) {
+	return s.cameras, s.err
+}
+
+// newFirmwareCameraClient returns a client for a fake camera answering
+// CheckFirmware with newFirmware
+func newFirmwareCameraClient(t *testing.T, id string, newFirmware int) *camera.CameraClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `[{"cmd":"CheckFirmware","code":0,"value":{"newFirmware":%d}}]`, newFirmware)
+	}))
+	t.Cleanup(server.Close)
+
+	return &camera.CameraClient{
+		Camera: &models.Camera{ID: id},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+}
+
+func fleetCameras() []*models.Camera {
+	return []*models.Camera{
+		{ID: "cam-1", Name: "Front Door", Model: "RLC-810A", FirmwareVer: "v3.1.0.956_22041503"},
+		{ID: "cam-2", Name: "Back Yard", Model: "RLC-810A", FirmwareVer: "v3.1.0.2368_23062700"},
+		{ID: "cam-3", Name: "Garage", Model: "RLC-810A", FirmwareVer: "v3.1.0.2368_23062700"},
+		{ID: "cam-4", Name: "Doorbell", Model: "Reolink Video Doorbell PoE", FirmwareVer: "v3.0.0.2033_23041302"},
+		{ID: "cam-5", Name: "New Camera"},
+	}
+}
+
+func TestFleetService_FirmwareReport(t *testing.T) {
+	service := NewFleetService(&stubCameraLister{cameras: fleetCameras()}, new(MockCameraManager))
+
+	report, err := service.FirmwareReport(context.Background(), false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 1, report.Behind)
+	assert.False(t, report.UpdatesChecked)
+	require.Len(t, report.Models, 3)
+
+	// Models are sorted by name, cameras without a model first
+	unknown := report.Models[0]
+	assert.Equal(t, "", unknown.Model)
+	assert.Empty(t, unknown.Versions)
+	assert.False(t, unknown.Drift)
+	require.Len(t, unknown.Cameras, 1)
+	assert.False(t, unknown.Cameras[0].Behind)
+
+	rlc := report.Models[1]
+	assert.Equal(t, "RLC-810A", rlc.Model)
+	assert.Equal(t, "v3.1.0.2368_23062700", rlc.LatestVersion)
+	assert.Equal(t, []string{"v3.1.0.2368_23062700", "v3.1.0.956_22041503"}, rlc.Versions)
+	assert.True(t, rlc.Drift)
+	require.Len(t, rlc.Cameras, 3)
+	behind := map[string]bool{}
+	for _, entry := range rlc.Cameras {
+		behind[entry.CameraID] = entry.Behind
+		assert.Nil(t, entry.UpdateAvailable)
+	}
+	assert.Equal(t, map[string]bool{"cam-1": true, "cam-2": false, "cam-3": false}, behind)
+	assert.Equal(t, "Back Yard", rlc.Cameras[0].Name, "cameras are sorted by name")
+
+	doorbell := report.Models[2]
+	assert.Equal(t, "v3.0.0.2033_23041302", doorbell.LatestVersion)
+	assert.False(t, doorbell.Drift)
+}
+
+func TestFleetService_FirmwareReport_CheckUpdates(t *testing.T) {
+	cameras := fleetCameras()
+	manager := new(MockCameraManager)
+	manager.On("GetCamera", "cam-1").Return(newFirmwareCameraClient(t, "cam-1", 1), nil)
+	manager.On("GetCamera", "cam-2").Return(newFirmwareCameraClient(t, "cam-2", 0), nil)
+	manager.On("GetCamera", "cam-3").Return(newFirmwareCameraClient(t, "cam-3", 0), nil)
+	manager.On("GetCamera", "cam-4").Return(newFirmwareCameraClient(t, "cam-4", 1), nil)
+	manager.On("GetCamera", "cam-5").Return(nil, camera.ErrNotFound)
+
+	service := NewFleetService(&stubCameraLister{cameras: cameras}, manager)
+	report, err := service.FirmwareReport(context.Background(), true)
+	require.NoError(t, err)
+
+	assert.True(t, report.UpdatesChecked)
+	assert.Equal(t, 2, report.UpdatesAvailable)
+
+	checked := map[string]*bool{}
+	checkErrors := map[string]string{}
+	for _, model := range report.Models {
+		for _, entry := range model.Cameras {
+			checked[entry.CameraID] = entry.UpdateAvailable
+			checkErrors[entry.CameraID] = entry.CheckError
+		}
+	}
+	require.NotNil(t, checked["cam-1"])
+	assert.True(t, *checked["cam-1"])
+	require.NotNil(t, checked["cam-2"])
+	assert.False(t, *checked["cam-2"])
+	require.NotNil(t, checked["cam-4"])
+	assert.True(t, *checked["cam-4"])
+
+	// Cameras that cannot be asked report why
+	assert.Nil(t, checked["cam-5"])
+	assert.Contains(t, checkErrors["cam-5"], "camera not found")
+	manager.AssertExpectations(t)
+}
+
+func TestFleetService_FirmwareReport_ListError(t *testing.T) {
+	service := NewFleetService(&stubCameraLister{err: errors.New("database down")}, new(MockCameraManager))
+
+	_, err := service.FirmwareReport(context.Background(), false)
+	assert.Error(t, err)
+}
+
+func TestCompareFirmware(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v3.1.0.956_22041503", "v3.1.0.2368_23062700", -1},
+		{"v3.1.0.2368_23062700", "v3.1.0.956_22041503", 1},
+		{"v3.1.0.956_22041503", "v3.1.0.956_22041503", 0},
+		{"v3.0.0.136_20121102", "v2.0.0.1441_19032101", 1},
+		{"v3.1.0.956", "v3.1.0.956_22041503", -1},
+		{"", "v1", -1},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, compareFirmware(tt.a, tt.b), "%s vs %s", tt.a, tt.b)
+	}
+}
@@ -0,0 +1,353 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// clipMaxGap is the largest gap between adjacent recordings that are still
+// joined into one clip; cameras leave short gaps between segment files
+const clipMaxGap = 2 * time.Second
+
+const (
+	// clipExportTimeout bounds one clip export, which runs in the background
+	// since re-encoding a long clip outlasts any request
+	clipExportTimeout = time.Hour
+
+	// clipJobRetention is how long a finished clip job can still be looked up
+	clipJobRetention = time.Hour
+)
+
+// Clip job states
+const (
+	ClipJobRunning = "running"
+	ClipJobDone    = "done"
+	ClipJobFailed  = "failed"
+)
+
+var (
+	// ErrClipExportDisabled is returned when no clip directory is configured
+	ErrClipExportDisabled = errors.New("clip export disabled")
+
+	// ErrClipNotCovered is returned when the recordings of the camera do not
+	// cover the requested range without gaps
+	ErrClipNotCovered = errors.New("recordings do not cover the clip range")
+
+	// ErrClipJobNotFound is returned for unknown or expired clip jobs
+	ErrClipJobNotFound = errors.New("clip job not found")
+)
+
+// ClipJob is a clip export running in the background
+type ClipJob struct {
+	ID         string            `json:"id"`
+	CameraID   string            `json:"camera_id"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	State      string            `json:"state"`
+	Recording  *models.Recording `json:"recording,omitempty"` // The clip, once done
+	Error      string            `json:"error,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+}
+
+// clipSegment is a recording contributing to a clip. In and out points are
+// offsets into the recording; zero means its start and end respectively.
+type clipSegment struct {
+	recording *models.Recording
+	url       string
+	inPoint   time.Duration
+	outPoint  time.Duration
+}
+
+// SetClipExport enables exporting clips with FFmpeg into clipDir
+func (s *RecordingService) SetClipExport(ffmpegPath, clipDir string) {
+	s.ffmpegPath = ffmpegPath
+	s.clipDir = clipDir
+}
+
+// StartClip checks that the recordings of a camera cover a time range and
+// starts joining them into a single clip, trimmed to exactly that range, in
+// the background. The clip is stored as a new recording; GetClipJob reports
+// when it is done.
+func (s *RecordingService) StartClip(ctx context.Context, req *models.CreateClipRequest) (*ClipJob, error) {
+	if s.clipDir == "" {
+		return nil, ErrClipExportDisabled
+	}
+
+	recordings, err := s.recordingRepo.ListOverlapping(ctx, req.CameraID, req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+	segments, err := planClip(recordings, req.StartTime, req.EndTime)
+	if err != nil {
+		return nil, err
+	}
+
+	cameraClient, err := s.cameraManager.GetCamera(req.CameraID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCameraOffline, err)
+	}
+	if cameraClient.Camera.Status == "offline" {
+		return nil, fmt.Errorf("%w: camera %s", ErrCameraOffline, req.CameraID)
+	}
+	for i := range segments {
+		segments[i].url = cameraClient.Download(segments[i].recording.StoragePath, segments[i].recording.FileName)
+	}
+
+	job := &ClipJob{
+		ID:        uuid.New().String(),
+		CameraID:  req.CameraID,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		State:     ClipJobRunning,
+		CreatedAt: time.Now(),
+	}
+
+	s.clipMu.Lock()
+	s.pruneClipJobs(job.CreatedAt)
+	s.clipJobs[job.ID] = job
+	snapshot := *job
+	s.clipMu.Unlock()
+
+	go s.runClipJob(job, req, segments)
+
+	return &snapshot, nil
+}
+
+// GetClipJob returns the state of a clip export started by StartClip
+func (s *RecordingService) GetClipJob(id string) (*ClipJob, error) {
+	s.clipMu.Lock()
+	defer s.clipMu.Unlock()
+
+	job, ok := s.clipJobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrClipJobNotFound, id)
+	}
+	snapshot := *job
+	return &snapshot, nil
+}
+
+// runClipJob exports a clip and records the outcome in its job
+func (s *RecordingService) runClipJob(job *ClipJob, req *models.CreateClipRequest, segments []clipSegment) {
+	ctx, cancel := context.WithTimeout(context.Background(), clipExportTimeout)
+	defer cancel()
+
+	clip, err := s.exportClip(ctx, req, segments)
+	if err != nil {
+		logger.Error("Failed to create recording clip",
+			zap.String("job_id", job.ID),
+			zap.String("camera_id", req.CameraID),
+			zap.Error(err))
+	}
+
+	s.clipMu.Lock()
+	defer s.clipMu.Unlock()
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	if err != nil {
+		job.State = ClipJobFailed
+		job.Error = err.Error()
+		return
+	}
+	job.State = ClipJobDone
+	job.Recording = clip
+}
+
+// pruneClipJobs forgets finished clip jobs past their retention. The caller
+// must hold clipMu.
+func (s *RecordingService) pruneClipJobs(now time.Time) {
+	for id, job := range s.clipJobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > clipJobRetention {
+			delete(s.clipJobs, id)
+		}
+	}
+}
+
+// exportClip joins the segments of a clip with FFmpeg and stores the clip as
+// a new recording
+func (s *RecordingService) exportClip(ctx context.Context, req *models.CreateClipRequest, segments []clipSegment) (*models.Recording, error) {
+	// The list holds download URLs carrying the camera session token
+	list, err := os.CreateTemp(s.clipDir, "clip-*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create clip list: %w", err)
+	}
+	defer os.Remove(list.Name())
+	_, err = io.WriteString(list, buildConcatList(segments))
+	if closeErr := list.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to write clip list: %w", err)
+	}
+
+	id := uuid.New().String()
+	outputPath := filepath.Join(s.clipDir, id+".mp4")
+	args := buildClipArgs(list.Name(), outputPath, clipTrimmed(segments))
+
+	output, err := exec.CommandContext(ctx, s.ffmpegPath, args...).CombinedOutput()
+	if err != nil {
+		os.Remove(outputPath)
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, lastLine(string(output)))
+	}
+
+	info, err := os.Stat(outputPath)
+	if err != nil {
+		return nil, fmt.Errorf("clip not written: %w", err)
+	}
+
+	clip := &models.Recording{
+		ID:            id,
+		CameraID:      req.CameraID,
+		FileName:      id + ".mp4",
+		FileSize:      info.Size(),
+		StartTime:     req.StartTime,
+		EndTime:       req.EndTime,
+		Duration:      int(req.EndTime.Sub(req.StartTime).Round(time.Second) / time.Second),
+		StreamType:    segments[0].recording.StreamType,
+		RecordingType: models.RecordingClip,
+		StoragePath:   outputPath,
+	}
+	s.addThumbnail(ctx, clip)
+	if err := s.recordingRepo.Create(ctx, clip); err != nil {
+		os.Remove(outputPath)
+		s.removeThumbnail(clip)
+		return nil, err
+	}
+
+	logger.Info("Recording clip created",
+		zap.String("recording_id", clip.ID),
+		zap.String("camera_id", clip.CameraID),
+		zap.Int("segments", len(segments)),
+		zap.Int64("size", clip.FileSize))
+
+	return clip, nil
+}
+
+// removeClipFile deletes the file of a clip recording. Only files inside the
+// clip directory are removed; other recordings live on their camera.
+func (s *RecordingService) removeClipFile(recording *models.Recording) {
+	if recording.RecordingType != models.RecordingClip || s.clipDir == "" || recording.StoragePath == "" {
+		return
+	}
+	if filepath.Dir(filepath.Clean(recording.StoragePath)) != filepath.Clean(s.clipDir) {
+		return
+	}
+	if err := os.Remove(recording.StoragePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove clip file",
+			zap.String("recording_id", recording.ID),
+			zap.String("path", recording.StoragePath),
+			zap.Error(err))
+	}
+}
+
+// planClip picks the recordings, sorted by start time, making up the range
+// from start to end and where to cut them. Clips are not cut from other clips.
+func planClip(recordings []*models.Recording, start, end time.Time) ([]clipSegment, error) {
+	var segments []clipSegment
+	covered := start
+	for _, recording := range recordings {
+		if recording.RecordingType == models.RecordingClip || !recording.EndTime.After(covered) {
+			continue
+		}
+		if recording.StartTime.Sub(covered) > clipMaxGap {
+			return nil, fmt.Errorf("%w: no recording from %s to %s", ErrClipNotCovered,
+				covered.Format(time.RFC3339), recording.StartTime.Format(time.RFC3339))
+		}
+
+		segment := clipSegment{recording: recording}
+		if covered.After(recording.StartTime) {
+			segment.inPoint = covered.Sub(recording.StartTime)
+		}
+		if end.Before(recording.EndTime) {
+			segment.outPoint = end.Sub(recording.StartTime)
+		}
+		segments = append(segments, segment)
+
+		covered = recording.EndTime
+		if !covered.Before(end) {
+			break
+		}
+	}
+
+	if len(segments) == 0 || end.Sub(covered) > clipMaxGap {
+		return nil, fmt.Errorf("%w: no recording from %s to %s", ErrClipNotCovered,
+			covered.Format(time.RFC3339), end.Format(time.RFC3339))
+	}
+	return segments, nil
+}
+
+// clipTrimmed reports whether any segment is cut rather than used whole
+func clipTrimmed(segments []clipSegment) bool {
+	for _, segment := range segments {
+		if segment.inPoint > 0 || segment.outPoint > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// buildConcatList returns the FFmpeg concat demuxer script joining segments
+func buildConcatList(segments []clipSegment) string {
+	var b strings.Builder
+	b.WriteString("ffconcat version 1.0\n")
+	for _, segment := range segments {
+		fmt.Fprintf(&b, "file '%s'\n", strings.ReplaceAll(segment.url, "'", `'\''`))
+		if segment.inPoint > 0 {
+			fmt.Fprintf(&b, "inpoint %.3f\n", segment.inPoint.Seconds())
+		}
+		if segment.outPoint > 0 {
+			fmt.Fprintf(&b, "outpoint %.3f\n", segment.outPoint.Seconds())
+		}
+	}
+	return b.String()
+}
+
+// buildClipArgs returns the FFmpeg arguments writing the segments of a concat
+// list to an MP4 file. Whole segments are copied; trimmed clips are
+// re-encoded, since copying can only cut at keyframes.
+func buildClipArgs(listPath, outputPath string, trimmed bool) []string {
+	args := []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-f", "concat",
+		"-safe", "0",
+		"-protocol_whitelist", "file,http,https,tcp,tls",
+		"-i", listPath,
+	}
+
+	if trimmed {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-c:a", "aac",
+		)
+	} else {
+		args = append(args, "-c", "copy")
+	}
+
+	return append(args,
+		"-movflags", "+faststart",
+		"-y", outputPath,
+	)
+}
+
+// lastLine returns the last non-empty line of output, where FFmpeg reports
+// the error that made it fail
+func lastLine(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
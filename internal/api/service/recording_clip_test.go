@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// clipRecordings returns three adjacent one-minute recordings from 12:00,
+// the second starting a second late like cameras do between segment files
+func clipRecordings() []*models.Recording {
+	base := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	return []*models.Recording{
+		{ID: "rec-1", CameraID: "cam-123", FileName: "a.mp4", StoragePath: "Mp4Record/a.mp4", StartTime: base, EndTime: base.Add(time.Minute), StreamType: models.StreamMain},
+		{ID: "rec-2", CameraID: "cam-123", FileName: "b.mp4", StoragePath: "Mp4Record/b.mp4", StartTime: base.Add(61 * time.Second), EndTime: base.Add(2 * time.Minute), StreamType: models.StreamMain},
+		{ID: "rec-3", CameraID: "cam-123", FileName: "c.mp4", StoragePath: "Mp4Record/c.mp4", StartTime: base.Add(2 * time.Minute), EndTime: base.Add(3 * time.Minute), StreamType: models.StreamMain},
+	}
+}
+
+func TestPlanClip_TrimsAcrossSegments(t *testing.T) {
+	recordings := clipRecordings()
+	start := recordings[0].StartTime.Add(30 * time.Second)
+	end := recordings[2].StartTime.Add(15 * time.Second)
+
+	segments, err := planClip(recordings, start, end)
+	require.NoError(t, err)
+	require.Len(t, segments, 3)
+
+	assert.Equal(t, "rec-1", segments[0].recording.ID)
+	assert.Equal(t, 30*time.Second, segments[0].inPoint)
+	assert.Zero(t, segments[0].outPoint)
+
+	assert.Zero(t, segments[1].inPoint, "middle segments are used whole")
+	assert.Zero(t, segments[1].outPoint)
+
+	assert.Zero(t, segments[2].inPoint)
+	assert.Equal(t, 15*time.Second, segments[2].outPoint)
+	assert.True(t, clipTrimmed(segments))
+}
+
+func TestPlanClip_WithinOneRecording(t *testing.T) {
+	recordings := clipRecordings()
+	start := recordings[1].StartTime.Add(10 * time.Second)
+
+	segments, err := planClip(recordings, start, start.Add(20*time.Second))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+	assert.Equal(t, "rec-2", segments[0].recording.ID)
+	assert.Equal(t, 10*time.Second, segments[0].inPoint)
+	assert.Equal(t, 30*time.Second, segments[0].outPoint)
+}
+
+func TestPlanClip_WholeSegments(t *testing.T) {
+	recordings := clipRecordings()
+
+	segments, err := planClip(recordings, recordings[0].StartTime, recordings[1].EndTime)
+	require.NoError(t, err)
+	assert.Len(t, segments, 2)
+	assert.False(t, clipTrimmed(segments), "segment boundaries are joined without cutting")
+}
+
+func TestPlanClip_NotCovered(t *testing.T) {
+	recordings := clipRecordings()
+	start := recordings[0].StartTime
+
+	// A recording missing in the middle
+	_, err := planClip([]*models.Recording{recordings[0], recordings[2]}, start, recordings[2].EndTime)
+	assert.ErrorIs(t, err, ErrClipNotCovered)
+
+	// Range starting before the first recording
+	_, err = planClip(recordings, start.Add(-time.Minute), recordings[0].EndTime)
+	assert.ErrorIs(t, err, ErrClipNotCovered)
+
+	// Range ending after the last recording
+	_, err = planClip(recordings, start, recordings[2].EndTime.Add(time.Minute))
+	assert.ErrorIs(t, err, ErrClipNotCovered)
+
+	// Clips are not joined into other clips
+	clip := *recordings[0]
+	clip.RecordingType = models.RecordingClip
+	_, err = planClip([]*models.Recording{&clip}, start, clip.EndTime)
+	assert.ErrorIs(t, err, ErrClipNotCovered)
+}
+
+func TestBuildConcatList(t *testing.T) {
+	recordings := clipRecordings()
+	segments := []clipSegment{
+		{recording: recordings[0], url: "http://cam/cgi-bin/api.cgi?cmd=Download&source=a.mp4", inPoint: 30500 * time.Millisecond},
+		{recording: recordings[1], url: "http://cam/it's.mp4"},
+		{recording: recordings[2], url: "http://cam/c.mp4", outPoint: 15 * time.Second},
+	}
+
+	assert.Equal(t, "ffconcat version 1.0\n"+
+		"file 'http://cam/cgi-bin/api.cgi?cmd=Download&source=a.mp4'\n"+
+		"inpoint 30.500\n"+
+		"file 'http://cam/it'\\''s.mp4'\n"+
+		"file 'http://cam/c.mp4'\n"+
+		"outpoint 15.000\n", buildConcatList(segments))
+}
+
+func TestBuildClipArgs_Concat(t *testing.T) {
+	args := buildClipArgs("/clips/clip-1.txt", "/clips/out.mp4", false)
+	joined := strings.Join(args, " ")
+
+	assert.Contains(t, joined, "-f concat -safe 0")
+	assert.Contains(t, joined, "-protocol_whitelist file,http,https,tcp,tls -i /clips/clip-1.txt")
+	assert.Contains(t, joined, "-c copy")
+	assert.NotContains(t, joined, "libx264")
+	assert.Equal(t, "/clips/out.mp4", args[len(args)-1])
+}
+
+func TestBuildClipArgs_Trim(t *testing.T) {
+	args := buildClipArgs("/clips/clip-1.txt", "/clips/out.mp4", true)
+	joined := strings.Join(args, " ")
+
+	assert.Contains(t, joined, "-i /clips/clip-1.txt")
+	assert.Contains(t, joined, "-c:v libx264")
+	assert.Contains(t, joined, "-c:a aac")
+	assert.NotContains(t, joined, "-c copy")
+	assert.Equal(t, "/clips/out.mp4", args[len(args)-1])
+}
+
+func TestRecordingService_CreateClip(t *testing.T) {
+	clipDir := t.TempDir()
+	listCopy := filepath.Join(t.TempDir(), "list.txt")
+
+	// Fake ffmpeg keeping the concat list and writing the output file
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+while [ "$1" != "-i" ]; do shift; done
+cp "$2" `+listCopy+`
+for out; do :; done
+printf clip > "$out"
+`), 0755)
+	require.NoError(t, err)
+
+	recordings := clipRecordings()
+	start := recordings[0].StartTime.Add(30 * time.Second)
+	end := recordings[1].EndTime
+
+	mockRepo := new(MockRecordingRepository)
+	mockRepo.On("ListOverlapping", mock.Anything, "cam-123", start, end).Return(recordings[:2], nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.Recording")).Return(nil)
+	mockCameraManager := new(MockCameraManager)
+	mockCameraManager.On("GetCamera", "cam-123").Return(newRecordingCamera(t, nil, false), nil)
+
+	service := NewRecordingService(mockRepo, mockCameraManager)
+	service.SetClipExport(script, clipDir)
+
+	job, err := service.StartClip(context.Background(), &models.CreateClipRequest{CameraID: "cam-123", StartTime: start, EndTime: end})
+	require.NoError(t, err)
+	assert.Equal(t, ClipJobRunning, job.State)
+
+	job = waitForClipJob(t, service, job.ID)
+	require.Equal(t, ClipJobDone, job.State, job.Error)
+	require.NotNil(t, job.FinishedAt)
+	clip := job.Recording
+
+	assert.Equal(t, models.RecordingClip, clip.RecordingType)
+	assert.Equal(t, start, clip.StartTime)
+	assert.Equal(t, end, clip.EndTime)
+	assert.Equal(t, 90, clip.Duration)
+	assert.Equal(t, int64(len("clip")), clip.FileSize)
+	assert.Equal(t, filepath.Join(clipDir, clip.ID+".mp4"), clip.StoragePath)
+	mockRepo.AssertCalled(t, "Create", mock.Anything, clip)
+
+	list, err := os.ReadFile(listCopy)
+	require.NoError(t, err)
+	assert.Contains(t, string(list), "source=Mp4Record/a.mp4")
+	assert.Contains(t, string(list), "inpoint 30.000")
+	assert.NotContains(t, string(list), "outpoint")
+
+	entries, err := os.ReadDir(clipDir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the concat list is removed")
+
+	// The clip is streamed from the clip directory
+	stream, err := service.OpenRecordingStream(context.Background(), clip, 2)
+	require.NoError(t, err)
+	defer stream.Close()
+	body, err := io.ReadAll(stream)
+	require.NoError(t, err)
+	assert.Equal(t, "ip", string(body))
+}
+
+func TestRecordingService_CreateClip_Disabled(t *testing.T) {
+	service := NewRecordingService(new(MockRecordingRepository), new(MockCameraManager))
+
+	_, err := service.StartClip(context.Background(), &models.CreateClipRequest{CameraID: "cam-123"})
+	assert.ErrorIs(t, err, ErrClipExportDisabled)
+
+	_, err = service.GetClipJob("missing")
+	assert.ErrorIs(t, err, ErrClipJobNotFound)
+}
+
+func TestRecordingService_CreateClip_FFmpegFails(t *testing.T) {
+	clipDir := t.TempDir()
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho 'Connection refused' >&2\nexit 1\n"), 0755))
+
+	recordings := clipRecordings()
+	mockRepo := new(MockRecordingRepository)
+	mockRepo.On("ListOverlapping", mock.Anything, "cam-123", mock.Anything, mock.Anything).Return(recordings, nil)
+	mockCameraManager := new(MockCameraManager)
+	mockCameraManager.On("GetCamera", "cam-123").Return(newRecordingCamera(t, nil, false), nil)
+
+	service := NewRecordingService(mockRepo, mockCameraManager)
+	service.SetClipExport(script, clipDir)
+
+	job, err := service.StartClip(context.Background(), &models.CreateClipRequest{
+		CameraID: "cam-123", StartTime: recordings[0].StartTime, EndTime: recordings[2].EndTime,
+	})
+	require.NoError(t, err)
+
+	job = waitForClipJob(t, service, job.ID)
+	assert.Equal(t, ClipJobFailed, job.State)
+	assert.Contains(t, job.Error, "Connection refused")
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	entries, err := os.ReadDir(clipDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+// waitForClipJob waits for a clip job to finish and returns its final state
+func waitForClipJob(t *testing.T, service *RecordingService, id string) *ClipJob {
+	t.Helper()
+	var job *ClipJob
+	require.Eventually(t, func() bool {
+		var err error
+		job, err = service.GetClipJob(id)
+		require.NoError(t, err)
+		return job.State != ClipJobRunning
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
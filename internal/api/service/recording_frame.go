@@ -0,0 +1,81 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// frameTimeout bounds extracting one frame, which seeks into the recording
+// on its camera
+const frameTimeout = 30 * time.Second
+
+var (
+	// ErrFrameExtractionDisabled is returned when no FFmpeg is configured for
+	// extracting frames
+	ErrFrameExtractionDisabled = errors.New("frame extraction disabled")
+
+	// ErrFrameOutOfRange is returned for frame offsets outside the recording
+	ErrFrameOutOfRange = errors.New("offset is outside the recording")
+)
+
+// SetFrameExtraction enables extracting single frames of stored recordings
+// with FFmpeg
+func (s *RecordingService) SetFrameExtraction(ffmpegPath string) {
+	s.frameFFmpegPath = ffmpegPath
+}
+
+// ExtractFrame returns the frame at offset into a recording as a full size
+// JPEG. The offset must lie within the recording's length; recordings of
+// unknown length only accept offsets from their start.
+func (s *RecordingService) ExtractFrame(ctx context.Context, recording *models.Recording, offset time.Duration) ([]byte, error) {
+	if s.frameFFmpegPath == "" {
+		return nil, ErrFrameExtractionDisabled
+	}
+	if length := recordingLength(recording); offset < 0 || (length > 0 && offset >= length) {
+		return nil, fmt.Errorf("%w: %s not within 0s-%s", ErrFrameOutOfRange, offset, length)
+	}
+
+	source, err := s.thumbnailSource(recording)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, frameTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.frameFFmpegPath, buildFrameArgs(source, offset)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %w: %s", err, lastLine(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		// FFmpeg succeeds without output when seeking past the last frame
+		return nil, fmt.Errorf("%w: no frame at %s", ErrFrameOutOfRange, offset)
+	}
+	return stdout.Bytes(), nil
+}
+
+// buildFrameArgs returns the FFmpeg arguments writing the frame at offset of
+// source to stdout as a JPEG. Like thumbnails, seeking before the input lets
+// FFmpeg skip to the offset with range requests.
+func buildFrameArgs(source string, offset time.Duration) []string {
+	return []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", fmt.Sprintf("%.3f", offset.Seconds()),
+		"-i", source,
+		"-frames:v", "1",
+		"-f", "image2",
+		"-c:v", "mjpeg",
+		"-q:v", "2",
+		"pipe:1",
+	}
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestBuildFrameArgs(t *testing.T) {
+	args := buildFrameArgs("http://cam/a.mp4", 12500*time.Millisecond)
+	joined := strings.Join(args, " ")
+
+	assert.Contains(t, joined, "-ss 12.500 -i http://cam/a.mp4", "seeks before opening the input")
+	assert.Contains(t, joined, "-frames:v 1")
+	assert.NotContains(t, joined, "scale", "frames keep the recording's resolution")
+	assert.Equal(t, "pipe:1", args[len(args)-1])
+}
+
+func TestRecordingService_ExtractFrame(t *testing.T) {
+	argsCopy := filepath.Join(t.TempDir(), "args.txt")
+
+	// Fake ffmpeg recording its arguments and writing the frame to stdout
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+argsCopy+`
+printf jpeg
+`), 0755))
+
+	start := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	recording := &models.Recording{
+		ID: "rec-1", CameraID: "cam-123", FileName: "a.mp4", StoragePath: "Mp4Record/a.mp4",
+		StartTime: start, EndTime: start.Add(time.Minute),
+	}
+
+	mockCameraManager := new(MockCameraManager)
+	mockCameraManager.On("GetCamera", "cam-123").Return(newRecordingCamera(t, nil, false), nil)
+	service := NewRecordingService(new(MockRecordingRepository), mockCameraManager)
+	service.SetFrameExtraction(script)
+
+	frame, err := service.ExtractFrame(context.Background(), recording, 12500*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(frame))
+
+	args, err := os.ReadFile(argsCopy)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-ss 12.500")
+	assert.Contains(t, string(args), "source=Mp4Record/a.mp4")
+}
+
+func TestRecordingService_ExtractFrame_OutOfRange(t *testing.T) {
+	// Out of range offsets never reach FFmpeg
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nexit 1\n"), 0755))
+
+	service := NewRecordingService(new(MockRecordingRepository), new(MockCameraManager))
+	service.SetFrameExtraction(script)
+	ctx := context.Background()
+
+	start := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	recording := &models.Recording{ID: "rec-1", CameraID: "cam-123", StartTime: start, EndTime: start.Add(time.Minute)}
+	for _, offset := range []time.Duration{-time.Second, time.Minute, 2 * time.Minute} {
+		_, err := service.ExtractFrame(ctx, recording, offset)
+		assert.ErrorIs(t, err, ErrFrameOutOfRange, "offset %s", offset)
+	}
+
+	// Without a time range the duration bounds the offset
+	_, err := service.ExtractFrame(ctx, &models.Recording{ID: "rec-2", Duration: 30}, 45*time.Second)
+	assert.ErrorIs(t, err, ErrFrameOutOfRange)
+
+	// Seeking past the last frame yields no frame
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	clip := &models.Recording{ID: "clip-1", RecordingType: models.RecordingClip, StoragePath: "/clips/clip-1.mp4", Duration: 60}
+	_, err = service.ExtractFrame(ctx, clip, 59*time.Second)
+	assert.ErrorIs(t, err, ErrFrameOutOfRange)
+}
+
+func TestRecordingService_ExtractFrame_Disabled(t *testing.T) {
+	service := NewRecordingService(new(MockRecordingRepository), new(MockCameraManager))
+
+	_, err := service.ExtractFrame(context.Background(), &models.Recording{ID: "rec-1", Duration: 60}, time.Second)
+	assert.ErrorIs(t, err, ErrFrameExtractionDisabled)
+}
@@ -3,17 +3,28 @@ package service
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
 )
 
+// ErrCameraOffline is returned when the camera holding a recording cannot be
+// reached. It is the camera package's error, so handlers match either.
+var ErrCameraOffline = camera.ErrCameraOffline
+
 // RecordingRepository interface for dependency injection
 type RecordingRepository interface {
 	GetByID(ctx context.Context, id string) (*models.Recording, error)
 	ListByCameraID(ctx context.Context, cameraID string, limit, offset int) ([]*models.Recording, error)
 	ListByTimeRange(ctx context.Context, cameraID string, startTime, endTime time.Time, limit, offset int) ([]*models.Recording, error)
+	ListOverlapping(ctx context.Context, cameraID string, startTime, endTime time.Time) ([]*models.Recording, error)
 	Search(ctx context.Context, req *models.RecordingSearchRequest) ([]*models.Recording, error)
 	Count(ctx context.Context) (int, error)
 	CountByCameraID(ctx context.Context, cameraID string) (int, error)
@@ -33,6 +44,19 @@ type CameraManager interface {
 type RecordingService struct {
 	recordingRepo RecordingRepository
 	cameraManager CameraManager
+
+	// Clip export, see SetClipExport
+	ffmpegPath string
+	clipDir    string
+	clipJobs   map[string]*ClipJob
+	clipMu     sync.Mutex
+
+	// Thumbnails of new clips, see SetThumbnails
+	thumbnailFFmpegPath string
+	thumbnailDir        string
+
+	// Frames of stored recordings, see SetFrameExtraction
+	frameFFmpegPath string
 }
 
 // NewRecordingService creates a new recording service
@@ -40,6 +64,7 @@ func NewRecordingService(recordingRepo RecordingRepository, cameraManager Camera
 	return &RecordingService{
 		recordingRepo: recordingRepo,
 		cameraManager: cameraManager,
+		clipJobs:      make(map[string]*ClipJob),
 	}
 }
 
@@ -112,9 +137,26 @@ func (s *RecordingService) GetTotalSizeByCameraID(ctx context.Context, cameraID
 	return s.recordingRepo.GetTotalSizeByCameraID(ctx, cameraID)
 }
 
-// DeleteRecording deletes a recording by ID
+// DeleteRecording deletes a recording by ID, along with its thumbnail and the
+// file of a clip
 func (s *RecordingService) DeleteRecording(ctx context.Context, id string) error {
-	return s.recordingRepo.Delete(ctx, id)
+	recording, err := s.recordingRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.recordingRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.removeClipFile(recording)
+	s.removeThumbnail(recording)
+	return nil
+}
+
+// DeleteRecordings deletes several recordings, reporting which were deleted
+// and why the others were not
+func (s *RecordingService) DeleteRecordings(ctx context.Context, ids []string) *models.BulkResult {
+	return runBulk(ctx, "delete_recordings", ids, repository.ErrRecordingNotFound, s.DeleteRecording)
 }
 
 // DeleteOldRecordings deletes recordings older than the specified time
@@ -137,6 +179,15 @@ type RecordingDownloadInfo struct {
 
 // GetRecordingDownloadInfo generates download information for a recording
 func (s *RecordingService) GetRecordingDownloadInfo(ctx context.Context, recording *models.Recording) (*RecordingDownloadInfo, error) {
+	if recording.RecordingType == models.RecordingClip {
+		return &RecordingDownloadInfo{
+			Recording:   recording,
+			DownloadURL: "/api/v1/recordings/" + recording.ID + "/stream",
+			Method:      "GET",
+			Note:        "Clips are stored on the server and downloaded through the stream endpoint",
+		}, nil
+	}
+
 	// Get camera client to generate download URL
 	cameraClient, err := s.cameraManager.GetCamera(recording.CameraID)
 	if err != nil {
@@ -154,3 +205,62 @@ func (s *RecordingService) GetRecordingDownloadInfo(ctx context.Context, recordi
 		Note:        "Use this URL to download the recording file directly from the camera",
 	}, nil
 }
+
+// OpenRecordingStream opens a recording file on its camera for reading,
+// starting offset bytes into the file. The caller must close the returned reader.
+func (s *RecordingService) OpenRecordingStream(ctx context.Context, recording *models.Recording, offset int64) (io.ReadCloser, error) {
+	if recording.RecordingType == models.RecordingClip {
+		return openClip(recording.StoragePath, offset)
+	}
+
+	cameraClient, err := s.cameraManager.GetCamera(recording.CameraID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCameraOffline, err)
+	}
+	if cameraClient.Camera.Status == "offline" {
+		return nil, fmt.Errorf("%w: camera %s", ErrCameraOffline, recording.CameraID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, cameraClient.Download(recording.StoragePath, recording.FileName), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", "bytes="+strconv.FormatInt(offset, 10)+"-")
+	}
+
+	resp, err := cameraClient.HTTPClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCameraOffline, err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent && offset > 0:
+		return resp.Body, nil
+	case resp.StatusCode == http.StatusOK:
+		// The camera ignored the range, skip to the offset ourselves
+		if offset > 0 {
+			if _, err := io.CopyN(io.Discard, resp.Body, offset); err != nil {
+				resp.Body.Close()
+				return nil, fmt.Errorf("failed to seek recording: %w", err)
+			}
+		}
+		return resp.Body, nil
+	default:
+		resp.Body.Close()
+		return nil, fmt.Errorf("camera returned status %d", resp.StatusCode)
+	}
+}
+
+// openClip opens a clip stored on the server at offset
+func openClip(path string, offset int64) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open clip: %w", err)
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to seek clip: %w", err)
+	}
+	return file, nil
+}
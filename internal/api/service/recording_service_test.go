@@ -1,15 +1,27 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	"github.com/mosleyit/reolink_server/internal/camera"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/mosleyit/reolink_server/internal/storage/repository"
 )
 
 // MockRecordingRepository is a mock implementation of RecordingRepository
@@ -41,6 +53,14 @@ func (m *MockRecordingRepository) ListByTimeRange(ctx context.Context, cameraID
 	return args.Get(0).([]*models.Recording), args.Error(1)
 }
 
+func (m *MockRecordingRepository) ListOverlapping(ctx context.Context, cameraID string, startTime, endTime time.Time) ([]*models.Recording, error) {
+	args := m.Called(ctx, cameraID, startTime, endTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Recording), args.Error(1)
+}
+
 func (m *MockRecordingRepository) Search(ctx context.Context, req *models.RecordingSearchRequest) ([]*models.Recording, error) {
 	args := m.Called(ctx, req)
 	if args.Get(0) == nil {
@@ -257,6 +277,7 @@ func TestRecordingService_DeleteRecording(t *testing.T) {
 	service := NewRecordingService(mockRepo, mockCameraManager)
 	ctx := context.Background()
 
+	mockRepo.On("GetByID", ctx, "rec-123").Return(&models.Recording{ID: "rec-123", StoragePath: "Mp4Record/a.mp4"}, nil)
 	mockRepo.On("Delete", ctx, "rec-123").Return(nil)
 
 	err := service.DeleteRecording(ctx, "rec-123")
@@ -265,6 +286,51 @@ func TestRecordingService_DeleteRecording(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+func TestRecordingService_DeleteRecording_RemovesClipFile(t *testing.T) {
+	mockRepo := new(MockRecordingRepository)
+	service := NewRecordingService(mockRepo, new(MockCameraManager))
+	clipDir := t.TempDir()
+	service.SetClipExport("ffmpeg", clipDir)
+	ctx := context.Background()
+
+	clipPath := filepath.Join(clipDir, "clip-1.mp4")
+	require.NoError(t, os.WriteFile(clipPath, []byte("clip"), 0644))
+	mockRepo.On("GetByID", ctx, "clip-1").
+		Return(&models.Recording{ID: "clip-1", RecordingType: models.RecordingClip, StoragePath: clipPath}, nil)
+	mockRepo.On("Delete", ctx, "clip-1").Return(nil)
+
+	require.NoError(t, service.DeleteRecording(ctx, "clip-1"))
+
+	assert.NoFileExists(t, clipPath)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestRecordingService_DeleteRecordings_MixedBatch(t *testing.T) {
+	mockRepo := new(MockRecordingRepository)
+	service := NewRecordingService(mockRepo, new(MockCameraManager))
+	ctx := context.Background()
+
+	deleted := "11111111-1111-1111-1111-111111111111"
+	missing := "22222222-2222-2222-2222-222222222222"
+	broken := "33333333-3333-3333-3333-333333333333"
+	mockRepo.On("GetByID", ctx, deleted).Return(&models.Recording{ID: deleted}, nil).Once()
+	mockRepo.On("Delete", ctx, deleted).Return(nil).Once()
+	mockRepo.On("GetByID", ctx, missing).Return(nil, fmt.Errorf("%w: %s", repository.ErrRecordingNotFound, missing))
+	mockRepo.On("GetByID", ctx, broken).Return(&models.Recording{ID: broken}, nil)
+	mockRepo.On("Delete", ctx, broken).Return(errors.New("connection reset"))
+
+	// Duplicates are applied once, and IDs that are not UUIDs never reach the repository
+	result := service.DeleteRecordings(ctx, []string{deleted, "rec-bogus", missing, deleted, broken})
+
+	assert.Equal(t, []string{deleted}, result.Succeeded)
+	assert.Equal(t, []models.BulkFailure{
+		{ID: "rec-bogus", Reason: models.BulkReasonInvalidID},
+		{ID: missing, Reason: models.BulkReasonNotFound},
+		{ID: broken, Reason: models.BulkReasonError},
+	}, result.Failed)
+	mockRepo.AssertExpectations(t)
+}
+
 func TestRecordingService_DeleteOldRecordings(t *testing.T) {
 	mockRepo := new(MockRecordingRepository)
 	mockCameraManager := new(MockCameraManager)
@@ -328,3 +394,64 @@ func TestRecordingService_GetRecordingDownloadInfo_CameraNotFound(t *testing.T)
 	assert.Contains(t, err.Error(), "camera not found or unavailable")
 	mockCameraManager.AssertExpectations(t)
 }
+
+// newRecordingCamera returns a camera client backed by a fake camera serving
+// data for Download requests, honoring Range headers when honorRange is set
+func newRecordingCamera(t *testing.T, data []byte, honorRange bool) *camera.CameraClient {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cmd") != "Download" {
+			http.NotFound(w, r)
+			return
+		}
+		if honorRange && r.Header.Get("Range") != "" {
+			http.ServeContent(w, r, "rec.mp4", time.Time{}, bytes.NewReader(data))
+			return
+		}
+		w.Write(data)
+	}))
+	t.Cleanup(server.Close)
+
+	cam := &models.Camera{ID: "cam-123", Status: "online"}
+	return &camera.CameraClient{
+		Camera: cam,
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+}
+
+func TestRecordingService_OpenRecordingStream(t *testing.T) {
+	data := []byte("0123456789abcdefghij")
+	recording := &models.Recording{ID: "rec-123", CameraID: "cam-123", FileName: "rec.mp4", StoragePath: "Mp4Record/rec.mp4"}
+
+	for _, honorRange := range []bool{true, false} {
+		t.Run(fmt.Sprintf("camera honors range %v", honorRange), func(t *testing.T) {
+			mockCameraManager := new(MockCameraManager)
+			mockCameraManager.On("GetCamera", "cam-123").Return(newRecordingCamera(t, data, honorRange), nil)
+			service := NewRecordingService(new(MockRecordingRepository), mockCameraManager)
+
+			stream, err := service.OpenRecordingStream(context.Background(), recording, 12)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			body, err := io.ReadAll(stream)
+			require.NoError(t, err)
+			assert.Equal(t, "cdefghij", string(body))
+		})
+	}
+}
+
+func TestRecordingService_OpenRecordingStream_Offline(t *testing.T) {
+	recording := &models.Recording{ID: "rec-123", CameraID: "cam-123"}
+
+	mockCameraManager := new(MockCameraManager)
+	client := newRecordingCamera(t, nil, false)
+	client.Camera.Status = "offline"
+	mockCameraManager.On("GetCamera", "cam-123").Return(client, nil)
+	mockCameraManager.On("GetCamera", "cam-999").Return(nil, assert.AnError)
+	service := NewRecordingService(new(MockRecordingRepository), mockCameraManager)
+
+	_, err := service.OpenRecordingStream(context.Background(), recording, 0)
+	assert.ErrorIs(t, err, ErrCameraOffline)
+
+	_, err = service.OpenRecordingStream(context.Background(), &models.Recording{ID: "rec-1", CameraID: "cam-999"}, 0)
+	assert.ErrorIs(t, err, ErrCameraOffline)
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// thumbnailTimeout bounds extracting one thumbnail, which seeks into the
+// recording on its camera
+const thumbnailTimeout = 30 * time.Second
+
+// thumbnailWidth is the width thumbnails are scaled to, keeping the aspect ratio
+const thumbnailWidth = 320
+
+// ErrThumbnailNotFound is returned for recordings without a thumbnail
+var ErrThumbnailNotFound = errors.New("thumbnail not found")
+
+// SetThumbnails enables extracting JPEG thumbnails of new clips with FFmpeg
+// into thumbnailDir
+func (s *RecordingService) SetThumbnails(ffmpegPath, thumbnailDir string) {
+	s.thumbnailFFmpegPath = ffmpegPath
+	s.thumbnailDir = thumbnailDir
+}
+
+// OpenThumbnail opens the JPEG thumbnail of a recording. The caller must
+// close the returned reader.
+func (s *RecordingService) OpenThumbnail(recording *models.Recording) (io.ReadCloser, error) {
+	if s.thumbnailDir == "" || recording.ThumbnailURL == "" {
+		return nil, ErrThumbnailNotFound
+	}
+
+	file, err := os.Open(s.thumbnailPath(recording.ID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrThumbnailNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open thumbnail: %w", err)
+	}
+	return file, nil
+}
+
+// addThumbnail extracts the frame at the middle of a clip into the thumbnail
+// directory and sets its ThumbnailURL. Failures are logged and leave
+// ThumbnailURL empty; a clip is stored without a thumbnail rather than not
+// at all.
+func (s *RecordingService) addThumbnail(ctx context.Context, clip *models.Recording) {
+	if s.thumbnailDir == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, thumbnailTimeout)
+	defer cancel()
+
+	outputPath := s.thumbnailPath(clip.ID)
+	args := buildThumbnailArgs(clip.StoragePath, outputPath, recordingMidpoint(clip))
+	output, err := exec.CommandContext(ctx, s.thumbnailFFmpegPath, args...).CombinedOutput()
+	if err == nil {
+		_, err = os.Stat(outputPath)
+	}
+	if err != nil {
+		os.Remove(outputPath)
+		logger.Warn("Failed to generate recording thumbnail",
+			zap.String("recording_id", clip.ID),
+			zap.Error(err),
+			zap.String("output", lastLine(string(output))))
+		return
+	}
+
+	clip.ThumbnailURL = "/api/v1/recordings/" + clip.ID + "/thumbnail"
+}
+
+// removeThumbnail deletes the thumbnail of a recording
+func (s *RecordingService) removeThumbnail(recording *models.Recording) {
+	if s.thumbnailDir == "" || recording.ThumbnailURL == "" {
+		return
+	}
+	if err := os.Remove(s.thumbnailPath(recording.ID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		logger.Warn("Failed to remove recording thumbnail",
+			zap.String("recording_id", recording.ID),
+			zap.Error(err))
+	}
+}
+
+// thumbnailSource returns where FFmpeg reads a recording from: the file of a
+// clip, or the download URL of the recording on its camera
+func (s *RecordingService) thumbnailSource(recording *models.Recording) (string, error) {
+	if recording.RecordingType == models.RecordingClip {
+		return recording.StoragePath, nil
+	}
+
+	cameraClient, err := s.cameraManager.GetCamera(recording.CameraID)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrCameraOffline, err)
+	}
+	if cameraClient.Camera.Status == "offline" {
+		return "", fmt.Errorf("%w: camera %s", ErrCameraOffline, recording.CameraID)
+	}
+	return cameraClient.Download(recording.StoragePath, recording.FileName), nil
+}
+
+// thumbnailPath returns where the thumbnail of a recording is stored. The
+// retention cleaner removes expired thumbnails by the same name.
+func (s *RecordingService) thumbnailPath(recordingID string) string {
+	return filepath.Join(s.thumbnailDir, recordingID+".jpg")
+}
+
+// recordingMidpoint returns the offset of the middle of a recording
+func recordingMidpoint(recording *models.Recording) time.Duration {
+	return recordingLength(recording) / 2
+}
+
+// recordingLength returns how long a recording runs, from its time range or
+// else its duration
+func recordingLength(recording *models.Recording) time.Duration {
+	if length := recording.EndTime.Sub(recording.StartTime); length > 0 {
+		return length
+	}
+	return time.Duration(recording.Duration) * time.Second
+}
+
+// buildThumbnailArgs returns the FFmpeg arguments writing the frame at offset
+// of source to a scaled JPEG. Seeking before the input lets FFmpeg skip to
+// the offset with range requests instead of reading the whole recording.
+func buildThumbnailArgs(source, outputPath string, offset time.Duration) []string {
+	return []string{
+		"-hide_banner",
+		"-loglevel", "error",
+		"-ss", fmt.Sprintf("%.3f", offset.Seconds()),
+		"-i", source,
+		"-frames:v", "1",
+		"-vf", fmt.Sprintf("scale=%d:-2", thumbnailWidth),
+		"-q:v", "4",
+		"-y", outputPath,
+	}
+}
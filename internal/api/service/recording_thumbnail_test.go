@@ -0,0 +1,110 @@
+package service
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestBuildThumbnailArgs(t *testing.T) {
+	args := buildThumbnailArgs("http://cam/a.mp4", "/thumbs/rec-1.jpg", 30500*time.Millisecond)
+	joined := strings.Join(args, " ")
+
+	assert.Contains(t, joined, "-ss 30.500 -i http://cam/a.mp4", "seeks before opening the input")
+	assert.Contains(t, joined, "-frames:v 1")
+	assert.Contains(t, joined, "-vf scale=320:-2")
+	assert.Equal(t, "/thumbs/rec-1.jpg", args[len(args)-1])
+}
+
+func TestRecordingMidpoint(t *testing.T) {
+	start := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, 30*time.Second, recordingMidpoint(&models.Recording{StartTime: start, EndTime: start.Add(time.Minute)}))
+	assert.Equal(t, 45*time.Second, recordingMidpoint(&models.Recording{Duration: 90}), "falls back to the duration")
+}
+
+func TestRecordingService_AddThumbnail(t *testing.T) {
+	thumbnailDir := t.TempDir()
+	argsCopy := filepath.Join(t.TempDir(), "args.txt")
+
+	// Fake ffmpeg recording its arguments and writing the output file
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+echo "$@" > `+argsCopy+`
+for out; do :; done
+printf jpeg > "$out"
+`), 0755)
+	require.NoError(t, err)
+
+	start := time.Date(2025, 10, 27, 12, 0, 0, 0, time.UTC)
+	clip := &models.Recording{
+		ID: "clip-1", CameraID: "cam-123", RecordingType: models.RecordingClip, StoragePath: "/clips/clip-1.mp4",
+		StartTime: start, EndTime: start.Add(time.Minute),
+	}
+
+	service := NewRecordingService(new(MockRecordingRepository), new(MockCameraManager))
+	service.SetThumbnails(script, thumbnailDir)
+
+	service.addThumbnail(context.Background(), clip)
+	assert.Equal(t, "/api/v1/recordings/clip-1/thumbnail", clip.ThumbnailURL)
+
+	args, err := os.ReadFile(argsCopy)
+	require.NoError(t, err)
+	assert.Contains(t, string(args), "-ss 30.000 -i /clips/clip-1.mp4")
+
+	thumbnail, err := service.OpenThumbnail(clip)
+	require.NoError(t, err)
+	defer thumbnail.Close()
+	data, err := io.ReadAll(thumbnail)
+	require.NoError(t, err)
+	assert.Equal(t, "jpeg", string(data))
+}
+
+func TestRecordingService_AddThumbnailFails(t *testing.T) {
+	thumbnailDir := t.TempDir()
+	script := filepath.Join(t.TempDir(), "ffmpeg.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho 'Invalid data found' >&2\nexit 1\n"), 0755))
+
+	clip := &models.Recording{ID: "clip-1", RecordingType: models.RecordingClip, StoragePath: "/clips/clip-1.mp4", Duration: 60}
+
+	service := NewRecordingService(new(MockRecordingRepository), new(MockCameraManager))
+	service.SetThumbnails(script, thumbnailDir)
+
+	service.addThumbnail(context.Background(), clip)
+	assert.Empty(t, clip.ThumbnailURL, "the clip is stored without a thumbnail")
+
+	entries, err := os.ReadDir(thumbnailDir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	_, err = service.OpenThumbnail(clip)
+	assert.ErrorIs(t, err, ErrThumbnailNotFound)
+}
+
+func TestRecordingService_DeleteRecording_RemovesThumbnail(t *testing.T) {
+	mockRepo := new(MockRecordingRepository)
+	service := NewRecordingService(mockRepo, new(MockCameraManager))
+	thumbnailDir := t.TempDir()
+	service.SetThumbnails("ffmpeg", thumbnailDir)
+	ctx := context.Background()
+
+	thumbnailPath := filepath.Join(thumbnailDir, "clip-1.jpg")
+	require.NoError(t, os.WriteFile(thumbnailPath, []byte("jpeg"), 0644))
+	mockRepo.On("GetByID", ctx, "clip-1").
+		Return(&models.Recording{ID: "clip-1", RecordingType: models.RecordingClip, ThumbnailURL: "/api/v1/recordings/clip-1/thumbnail"}, nil)
+	mockRepo.On("Delete", ctx, "clip-1").Return(nil)
+
+	require.NoError(t, service.DeleteRecording(ctx, "clip-1"))
+
+	assert.NoFileExists(t, thumbnailPath)
+	mockRepo.AssertExpectations(t)
+}
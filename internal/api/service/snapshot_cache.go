@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Snapshot cache defaults
+const (
+	DefaultSnapshotCacheTTL   = time.Second
+	defaultSnapshotCacheLimit = 64
+)
+
+// snapshotKey identifies a cached snapshot
+type snapshotKey struct {
+	cameraID string
+	channel  int
+}
+
+// snapshotEntry is a cached snapshot and the time it was taken
+type snapshotEntry struct {
+	data    []byte
+	takenAt time.Time
+}
+
+// snapshotCache keeps the latest snapshot of each camera channel for a short
+// time, so dashboards polling the same camera share one camera request. It
+// holds at most limit snapshots.
+type snapshotCache struct {
+	ttl     time.Duration
+	limit   int
+	now     func() time.Time
+	entries map[snapshotKey]snapshotEntry
+	mu      sync.Mutex
+	takes   singleflight.Group
+}
+
+// newSnapshotCache creates a snapshot cache
+func newSnapshotCache(ttl time.Duration, limit int) *snapshotCache {
+	return &snapshotCache{
+		ttl:     ttl,
+		limit:   limit,
+		now:     time.Now,
+		entries: make(map[snapshotKey]snapshotEntry),
+	}
+}
+
+// get returns a snapshot taken less than ttl ago
+func (c *snapshotCache) get(cameraID string, channel int) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := snapshotKey{cameraID, channel}
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if c.now().Sub(entry.takenAt) >= c.ttl {
+		delete(c.entries, key)
+		return nil, false
+	}
+	return entry.data, true
+}
+
+// load returns a snapshot taken less than ttl ago, or takes and caches a new
+// one with take. Concurrent misses of a camera channel share one take, which
+// is not cancelled when the caller that started it goes away; each caller
+// still stops waiting once its ctx is done. The returned bool reports whether
+// the snapshot was taken for another caller. A disabled cache calls take
+// every time.
+func (c *snapshotCache) load(ctx context.Context, cameraID string, channel int, take func(context.Context) ([]byte, error)) ([]byte, bool, error) {
+	if c.ttl <= 0 || c.limit <= 0 {
+		data, err := take(ctx)
+		return data, false, err
+	}
+	if data, ok := c.get(cameraID, channel); ok {
+		return data, true, nil
+	}
+
+	taken := false
+	results := c.takes.DoChan(fmt.Sprintf("%s/%d", cameraID, channel), func() (interface{}, error) {
+		taken = true
+		data, err := take(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.put(cameraID, channel, data)
+		return data, nil
+	})
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return nil, false, result.Err
+		}
+		return result.Val.([]byte), !taken, nil
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+}
+
+// put stores a snapshot, making room by dropping expired snapshots first and
+// the oldest one after that
+func (c *snapshotCache) put(cameraID string, channel int, data []byte) {
+	if c.ttl <= 0 || c.limit <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	key := snapshotKey{cameraID, channel}
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.limit {
+		var oldest snapshotKey
+		var oldestAt time.Time
+		for k, entry := range c.entries {
+			if now.Sub(entry.takenAt) >= c.ttl {
+				delete(c.entries, k)
+				continue
+			}
+			if oldestAt.IsZero() || entry.takenAt.Before(oldestAt) {
+				oldest, oldestAt = k, entry.takenAt
+			}
+		}
+		if len(c.entries) >= c.limit {
+			delete(c.entries, oldest)
+		}
+	}
+
+	c.entries[key] = snapshotEntry{data: data, takenAt: now}
+}
+
+// forget drops all cached snapshots of a camera
+func (c *snapshotCache) forget(cameraID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.entries {
+		if key.cameraID == cameraID {
+			delete(c.entries, key)
+		}
+	}
+}
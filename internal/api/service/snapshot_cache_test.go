@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestSnapshotCache returns a snapshot cache with a controllable clock
+func newTestSnapshotCache(ttl time.Duration, limit int) (*snapshotCache, *time.Time) {
+	now := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	cache := newSnapshotCache(ttl, limit)
+	cache.now = func() time.Time { return now }
+	return cache, &now
+}
+
+func TestSnapshotCache_HitAndMiss(t *testing.T) {
+	cache, _ := newTestSnapshotCache(time.Second, 8)
+
+	_, ok := cache.get("cam-1", 0)
+	assert.False(t, ok)
+
+	cache.put("cam-1", 0, []byte("jpeg"))
+
+	data, ok := cache.get("cam-1", 0)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("jpeg"), data)
+
+	_, ok = cache.get("cam-1", 1)
+	assert.False(t, ok, "channels are cached separately")
+	_, ok = cache.get("cam-2", 0)
+	assert.False(t, ok)
+}
+
+func TestSnapshotCache_Expiry(t *testing.T) {
+	cache, now := newTestSnapshotCache(time.Second, 8)
+
+	cache.put("cam-1", 0, []byte("jpeg"))
+
+	*now = now.Add(999 * time.Millisecond)
+	_, ok := cache.get("cam-1", 0)
+	assert.True(t, ok)
+
+	*now = now.Add(time.Millisecond)
+	_, ok = cache.get("cam-1", 0)
+	assert.False(t, ok)
+	assert.Empty(t, cache.entries)
+}
+
+func TestSnapshotCache_Bounded(t *testing.T) {
+	cache, now := newTestSnapshotCache(time.Minute, 2)
+
+	cache.put("cam-1", 0, []byte("1"))
+	*now = now.Add(time.Second)
+	cache.put("cam-2", 0, []byte("2"))
+	*now = now.Add(time.Second)
+	cache.put("cam-3", 0, []byte("3"))
+
+	assert.Len(t, cache.entries, 2)
+	_, ok := cache.get("cam-1", 0)
+	assert.False(t, ok, "oldest snapshot is evicted")
+	_, ok = cache.get("cam-3", 0)
+	assert.True(t, ok)
+
+	// Replacing a cached snapshot does not evict another one
+	cache.put("cam-2", 0, []byte("2b"))
+	assert.Len(t, cache.entries, 2)
+	_, ok = cache.get("cam-3", 0)
+	assert.True(t, ok)
+}
+
+func TestSnapshotCache_Disabled(t *testing.T) {
+	cache, _ := newTestSnapshotCache(0, 8)
+
+	cache.put("cam-1", 0, []byte("jpeg"))
+	_, ok := cache.get("cam-1", 0)
+	assert.False(t, ok)
+}
+
+func TestSnapshotCache_Forget(t *testing.T) {
+	cache, _ := newTestSnapshotCache(time.Second, 8)
+
+	cache.put("cam-1", 0, []byte("a"))
+	cache.put("cam-1", 1, []byte("b"))
+	cache.put("cam-2", 0, []byte("c"))
+
+	cache.forget("cam-1")
+
+	assert.Len(t, cache.entries, 1)
+	_, ok := cache.get("cam-2", 0)
+	assert.True(t, ok)
+}
+
+func TestSnapshotCache_LoadSharesConcurrentMisses(t *testing.T) {
+	cache, _ := newTestSnapshotCache(time.Second, 8)
+
+	var takes atomic.Int32
+	release := make(chan struct{})
+	take := func(ctx context.Context) ([]byte, error) {
+		takes.Add(1)
+		<-release
+		return []byte("jpeg"), nil
+	}
+
+	const callers = 5
+	var wg sync.WaitGroup
+	var shared atomic.Int32
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, cached, err := cache.load(context.Background(), "cam-1", 0, take)
+			assert.NoError(t, err)
+			assert.Equal(t, []byte("jpeg"), data)
+			if cached {
+				shared.Add(1)
+			}
+		}()
+	}
+
+	// Let every caller join the take before it finishes
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), takes.Load())
+	assert.Equal(t, int32(callers-1), shared.Load(), "only the caller that took the snapshot reports a miss")
+
+	// The snapshot taken is cached
+	data, cached, err := cache.load(context.Background(), "cam-1", 0, take)
+	require.NoError(t, err)
+	assert.True(t, cached)
+	assert.Equal(t, []byte("jpeg"), data)
+	assert.Equal(t, int32(1), takes.Load())
+}
+
+func TestSnapshotCache_LoadOutlivesCaller(t *testing.T) {
+	cache, _ := newTestSnapshotCache(time.Second, 8)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	take := func(ctx context.Context) ([]byte, error) {
+		close(started)
+		<-release
+		return []byte("jpeg"), ctx.Err()
+	}
+
+	// The caller that started the take goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, _, err := cache.load(ctx, "cam-1", 0, take)
+		done <- err
+	}()
+	<-started
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// The take still completes for everyone else
+	close(release)
+	assert.Eventually(t, func() bool {
+		_, ok := cache.get("cam-1", 0)
+		return ok
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestSnapshotCache_LoadErrors(t *testing.T) {
+	cache, _ := newTestSnapshotCache(time.Second, 8)
+
+	_, _, err := cache.load(context.Background(), "cam-1", 0, func(context.Context) ([]byte, error) {
+		return nil, errors.New("camera offline")
+	})
+	assert.EqualError(t, err, "camera offline")
+
+	_, ok := cache.get("cam-1", 0)
+	assert.False(t, ok, "failures are not cached")
+}
+
+func TestSnapshotCache_LoadDisabled(t *testing.T) {
+	cache, _ := newTestSnapshotCache(0, 8)
+
+	takes := 0
+	take := func(context.Context) ([]byte, error) {
+		takes++
+		return []byte("jpeg"), nil
+	}
+	for i := 0; i < 2; i++ {
+		_, cached, err := cache.load(context.Background(), "cam-1", 0, take)
+		require.NoError(t, err)
+		assert.False(t, cached)
+	}
+	assert.Equal(t, 2, takes)
+}
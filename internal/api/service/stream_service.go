@@ -2,12 +2,15 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +19,7 @@ import (
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/logger"
 )
 
@@ -23,23 +27,61 @@ import (
 type StreamType string
 
 const (
-	StreamTypeFLV  StreamType = "flv"
-	StreamTypeHLS  StreamType = "hls"
-	StreamTypeRTSP StreamType = "rtsp"
-	StreamTypeRTMP StreamType = "rtmp"
+	StreamTypeFLV    StreamType = "flv"
+	StreamTypeHLS    StreamType = "hls"
+	StreamTypeRTSP   StreamType = "rtsp"
+	StreamTypeRTMP   StreamType = "rtmp"
+	StreamTypeWebRTC StreamType = "webrtc"
 )
 
+// SessionStatus represents the lifecycle state of a streaming session
+type SessionStatus string
+
+const (
+	SessionStatusStarting SessionStatus = "starting"
+	SessionStatusRunning  SessionStatus = "running"
+	SessionStatusFailed   SessionStatus = "failed"
+)
+
+// ErrCameraNotFound is returned for streams of cameras the camera manager does not know
+var ErrCameraNotFound = errors.New("camera not found")
+
+// ErrSessionStartupFailed is returned for sessions whose FFmpeg process never produced a playlist
+var ErrSessionStartupFailed = errors.New("stream session failed to start")
+
 // StreamSession represents an active streaming session
 type StreamSession struct {
 	ID         string
 	CameraID   string
 	StreamType StreamType
+	Preview    bool
+	Status     SessionStatus
 	StartedAt  time.Time
 	LastAccess time.Time
 	ExpiresAt  time.Time
 	cancel     context.CancelFunc
 }
 
+// Preview sessions are tuned for multi-camera grids
+const (
+	previewFrameRate = 5
+	previewHeight    = 360
+)
+
+const (
+	// defaultStartupTimeout is how long FFmpeg gets to write the first playlist
+	defaultStartupTimeout = 15 * time.Second
+
+	// playlistPollInterval is how often the startup watchdog checks for the playlist
+	playlistPollInterval = 250 * time.Millisecond
+
+	// failedSessionRetention keeps failed sessions visible to polling clients
+	failedSessionRetention = time.Minute
+
+	// snapshotStreamMaxFailures is how many snapshots in a row may fail before an MJPEG stream ends
+	snapshotStreamMaxFailures = 5
+)
+
 // CameraManagerInterface defines the interface for camera manager operations
 type CameraManagerInterface interface {
 	GetCamera(id string) (*camera.CameraClient, error)
@@ -47,11 +89,26 @@ type CameraManagerInterface interface {
 
 // StreamService manages video streaming sessions
 type StreamService struct {
-	cameraManager CameraManagerInterface
-	sessions      map[string]*StreamSession
-	sessionsMu    sync.RWMutex
-	hlsOutputDir  string
-	ffmpegPath    string
+	cameraManager  CameraManagerInterface
+	sessions       map[string]*StreamSession
+	sessionsMu     sync.RWMutex
+	liveStreams    map[string]int // Running FLV proxies and MJPEG streams per camera, guarded by sessionsMu
+	hlsOutputDir   string
+	ffmpegPath     string
+	startupTimeout time.Duration
+	rtspTransport  string
+	go2rtcURL      string
+	go2rtcRTSPURL  string
+	httpClient     *http.Client
+
+	// removeAll deletes session directories (replaced in tests)
+	removeAll func(path string) error
+
+	// processes is searched for orphaned FFmpeg processes
+	processes processTable
+
+	// clock times session access and expiry
+	clock clock.Clock
 }
 
 // StreamServiceConfig holds configuration for the stream service
@@ -60,17 +117,32 @@ type StreamServiceConfig struct {
 	FFmpegPath      string
 	SessionTimeout  time.Duration
 	CleanupInterval time.Duration
+	StartupTimeout  time.Duration
+	RTSPTransport   string // tcp or udp for cameras without their own setting; empty leaves ffmpeg's default
+	Go2RTCURL       string // go2rtc API answering WebRTC offers, e.g. http://localhost:1984; empty disables WebRTC
+	Go2RTCRTSPURL   string // go2rtc RTSP server WebRTC sessions publish to, e.g. rtsp://localhost:8554
+}
+
+// DefaultStreamServiceConfig returns default stream service configuration
+func DefaultStreamServiceConfig() *StreamServiceConfig {
+	return &StreamServiceConfig{
+		HLSOutputDir:    "/tmp/hls",
+		FFmpegPath:      "ffmpeg",
+		SessionTimeout:  30 * time.Minute,
+		CleanupInterval: 5 * time.Minute,
+		StartupTimeout:  defaultStartupTimeout,
+	}
 }
 
 // NewStreamService creates a new stream service
 func NewStreamService(cameraManager CameraManagerInterface, config *StreamServiceConfig) *StreamService {
 	if config == nil {
-		config = &StreamServiceConfig{
-			HLSOutputDir:    "/tmp/hls",
-			FFmpegPath:      "ffmpeg",
-			SessionTimeout:  30 * time.Minute,
-			CleanupInterval: 5 * time.Minute,
-		}
+		config = DefaultStreamServiceConfig()
+	}
+
+	startupTimeout := config.StartupTimeout
+	if startupTimeout <= 0 {
+		startupTimeout = defaultStartupTimeout
 	}
 
 	// Create HLS output directory if it doesn't exist
@@ -79,11 +151,26 @@ func NewStreamService(cameraManager CameraManagerInterface, config *StreamServic
 	}
 
 	service := &StreamService{
-		cameraManager: cameraManager,
-		sessions:      make(map[string]*StreamSession),
-		hlsOutputDir:  config.HLSOutputDir,
-		ffmpegPath:    config.FFmpegPath,
+		cameraManager:  cameraManager,
+		sessions:       make(map[string]*StreamSession),
+		liveStreams:    make(map[string]int),
+		hlsOutputDir:   config.HLSOutputDir,
+		ffmpegPath:     config.FFmpegPath,
+		startupTimeout: startupTimeout,
+		rtspTransport:  config.RTSPTransport,
+		go2rtcURL:      strings.TrimSuffix(config.Go2RTCURL, "/"),
+		go2rtcRTSPURL:  strings.TrimSuffix(config.Go2RTCRTSPURL, "/"),
+		httpClient:     &http.Client{Timeout: webrtcRequestTimeout},
+		removeAll:      os.RemoveAll,
+		processes:      procfsTable{},
+		clock:          clock.Real,
+	}
+
+	// Stop FFmpeg processes and remove output left behind by sessions of a previous run
+	if _, err := service.StopOrphanedFFmpeg(); err != nil {
+		logger.Warn("Failed to look for orphaned FFmpeg processes", zap.Error(err))
 	}
+	service.removeOrphanedSessionDirs()
 
 	// Start session cleanup goroutine
 	go service.cleanupExpiredSessions(config.CleanupInterval)
@@ -91,6 +178,12 @@ func NewStreamService(cameraManager CameraManagerInterface, config *StreamServic
 	return service
 }
 
+// SetClock sets the time source of session expiry, e.g. a fake clock in tests.
+// It must be called before sessions are started.
+func (s *StreamService) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 // ProxyFLVStream proxies an FLV stream from the camera
 func (s *StreamService) ProxyFLVStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, w io.Writer) error {
 	client, err := s.cameraManager.GetCamera(cameraID)
@@ -98,6 +191,8 @@ func (s *StreamService) ProxyFLVStream(ctx context.Context, cameraID string, str
 		return fmt.Errorf("camera not found: %w", err)
 	}
 
+	defer s.trackLiveStream(cameraID)()
+
 	// Get FLV URL from camera
 	flvURL := client.GetFLVURL(streamType, channel)
 	if flvURL == "" {
@@ -115,7 +210,9 @@ func (s *StreamService) ProxyFLVStream(ctx context.Context, cameraID string, str
 	}
 
 	// Execute request
-	resp, err := http.DefaultClient.Do(req)
+	httpClient := client.HTTPClient()
+	defer httpClient.CloseIdleConnections()
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to connect to camera stream: %w", err)
 	}
@@ -134,8 +231,88 @@ func (s *StreamService) ProxyFLVStream(ctx context.Context, cameraID string, str
 	return nil
 }
 
+// SnapshotStream fetches snapshots from a camera at fps frames per second and
+// hands each JPEG to onFrame until ctx is done. A few failed snapshots in a
+// row are tolerated; the stream ends with an error once the camera's circuit
+// opens, snapshots keep failing or onFrame fails.
+func (s *StreamService) SnapshotStream(ctx context.Context, cameraID string, channel, fps int, onFrame func(jpeg []byte) error) error {
+	client, err := s.cameraManager.GetCamera(cameraID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrCameraNotFound, err)
+	}
+	if fps <= 0 {
+		fps = 1
+	}
+
+	defer s.trackLiveStream(cameraID)()
+
+	ticker := time.NewTicker(time.Second / time.Duration(fps))
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		snapshot, err := client.GetSnapshot(ctx, channel)
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case errors.Is(err, camera.ErrCircuitOpen):
+			return err
+		case err != nil:
+			failures++
+			if failures >= snapshotStreamMaxFailures {
+				return fmt.Errorf("failed to get snapshot: %w", err)
+			}
+			logger.Warn("Skipping MJPEG frame, snapshot failed",
+				zap.String("camera_id", cameraID),
+				zap.Error(err))
+		default:
+			failures = 0
+			if err := onFrame(snapshot); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// trackLiveStream counts a running FLV proxy or MJPEG stream of a camera
+// and returns the function ending it
+func (s *StreamService) trackLiveStream(cameraID string) func() {
+	s.sessionsMu.Lock()
+	s.liveStreams[cameraID]++
+	s.sessionsMu.Unlock()
+
+	return func() {
+		s.sessionsMu.Lock()
+		if s.liveStreams[cameraID]--; s.liveStreams[cameraID] <= 0 {
+			delete(s.liveStreams, cameraID)
+		}
+		s.sessionsMu.Unlock()
+	}
+}
+
 // StartHLSStream starts an HLS transcoding session
 func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int) (*StreamSession, error) {
+	return s.startHLSSession(ctx, cameraID, streamType, channel, false)
+}
+
+// StartPreviewStream starts a low-resolution HLS session for camera grids.
+// Previews always read the sub stream (or ext when requested) and are
+// scaled down to a low frame rate so many can run side by side.
+func (s *StreamService) StartPreviewStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int) (*StreamSession, error) {
+	if streamType != reolink.StreamExt {
+		streamType = reolink.StreamSub
+	}
+	return s.startHLSSession(ctx, cameraID, streamType, channel, true)
+}
+
+// startHLSSession starts an FFmpeg process writing HLS output for a camera
+func (s *StreamService) startHLSSession(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, preview bool) (*StreamSession, error) {
 	client, err := s.cameraManager.GetCamera(cameraID)
 	if err != nil {
 		return nil, fmt.Errorf("camera not found: %w", err)
@@ -147,6 +324,11 @@ func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, str
 		return nil, fmt.Errorf("failed to get RTSP URL for camera %s", cameraID)
 	}
 
+	transport := client.RTSPTransport()
+	if transport == "" {
+		transport = s.rtspTransport
+	}
+
 	// Create session
 	sessionID := uuid.New().String()
 	sessionDir := filepath.Join(s.hlsOutputDir, sessionID)
@@ -154,26 +336,11 @@ func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, str
 		return nil, fmt.Errorf("failed to create session directory: %w", err)
 	}
 
-	playlistPath := filepath.Join(sessionDir, "playlist.m3u8")
-
 	// Create context for FFmpeg process
 	ffmpegCtx, cancel := context.WithCancel(ctx)
 
 	// Build FFmpeg command
-	// ffmpeg -i rtsp://camera/stream -c:v copy -c:a aac -f hls \
-	//        -hls_time 2 -hls_list_size 5 -hls_flags delete_segments \
-	//        -hls_segment_filename 'segment_%03d.ts' playlist.m3u8
-	cmd := exec.CommandContext(ffmpegCtx, s.ffmpegPath,
-		"-i", rtspURL,
-		"-c:v", "copy",
-		"-c:a", "aac",
-		"-f", "hls",
-		"-hls_time", "2",
-		"-hls_list_size", "5",
-		"-hls_flags", "delete_segments",
-		"-hls_segment_filename", filepath.Join(sessionDir, "segment_%03d.ts"),
-		playlistPath,
-	)
+	cmd := exec.CommandContext(ffmpegCtx, s.ffmpegPath, buildHLSArgs(rtspURL, transport, sessionDir, preview)...)
 
 	// Capture stderr for error logging
 	stderrPipe, err := cmd.StderrPipe()
@@ -191,16 +358,21 @@ func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, str
 	logger.Info("Started HLS transcoding session",
 		zap.String("session_id", sessionID),
 		zap.String("camera_id", cameraID),
-		zap.String("rtsp_url", rtspURL))
+		zap.String("rtsp_url", rtspURL),
+		zap.String("rtsp_transport", transport),
+		zap.Bool("preview", preview))
 
 	// Create session
+	now := s.clock.Now()
 	session := &StreamSession{
 		ID:         sessionID,
 		CameraID:   cameraID,
 		StreamType: StreamTypeHLS,
-		StartedAt:  time.Now(),
-		LastAccess: time.Now(),
-		ExpiresAt:  time.Now().Add(30 * time.Minute),
+		Preview:    preview,
+		Status:     SessionStatusStarting,
+		StartedAt:  now,
+		LastAccess: now,
+		ExpiresAt:  now.Add(30 * time.Minute),
 		cancel:     cancel,
 	}
 
@@ -209,6 +381,17 @@ func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, str
 	s.sessions[sessionID] = session
 	s.sessionsMu.Unlock()
 
+	s.superviseFFmpeg(ffmpegCtx, sessionID, cmd, stderrPipe)
+
+	// Fail the session if FFmpeg never produces a playlist
+	go s.watchStartup(ffmpegCtx, session)
+
+	return session, nil
+}
+
+// superviseFFmpeg logs the FFmpeg output of a session and stops the session
+// once the process exits
+func (s *StreamService) superviseFFmpeg(ffmpegCtx context.Context, sessionID string, cmd *exec.Cmd, stderrPipe io.Reader) {
 	// Read FFmpeg stderr in background
 	go func() {
 		buf := make([]byte, 4096)
@@ -234,11 +417,125 @@ func (s *StreamService) StartHLSStream(ctx context.Context, cameraID string, str
 				zap.Error(err))
 		}
 
+		// Failed sessions are left for the cleanup loop so clients can see the failure
+		if s.sessionStatus(sessionID) == SessionStatusFailed {
+			return
+		}
+
 		// Cleanup session
 		s.StopSession(sessionID)
 	}()
+}
 
-	return session, nil
+// watchStartup waits for the session playlist to appear and marks the session
+// failed if it does not show up within the startup timeout
+func (s *StreamService) watchStartup(ctx context.Context, session *StreamSession) {
+	playlistPath := filepath.Join(s.hlsOutputDir, session.ID, "playlist.m3u8")
+
+	deadline := time.NewTimer(s.startupTimeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(playlistPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := os.Stat(playlistPath); err == nil {
+				s.sessionsMu.Lock()
+				session.Status = SessionStatusRunning
+				s.sessionsMu.Unlock()
+				return
+			}
+		case <-deadline.C:
+			s.failSession(session)
+			return
+		}
+	}
+}
+
+// failSession stops the FFmpeg process of a session that never started and
+// keeps the session around briefly in the failed state
+func (s *StreamService) failSession(session *StreamSession) {
+	s.sessionsMu.Lock()
+	if _, exists := s.sessions[session.ID]; !exists {
+		s.sessionsMu.Unlock()
+		return
+	}
+	session.Status = SessionStatusFailed
+	session.ExpiresAt = s.clock.Now().Add(failedSessionRetention)
+	s.sessionsMu.Unlock()
+
+	logger.Warn("HLS session produced no playlist, stopping",
+		zap.String("session_id", session.ID),
+		zap.String("camera_id", session.CameraID),
+		zap.Duration("timeout", s.startupTimeout))
+
+	if session.cancel != nil {
+		session.cancel()
+	}
+
+	sessionDir := filepath.Join(s.hlsOutputDir, session.ID)
+	if err := os.RemoveAll(sessionDir); err != nil {
+		logger.Error("Failed to cleanup session directory",
+			zap.String("session_id", session.ID),
+			zap.Error(err))
+	}
+}
+
+// sessionStatus returns the status of a session, or an empty status if it does not exist
+func (s *StreamService) sessionStatus(sessionID string) SessionStatus {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	if session, exists := s.sessions[sessionID]; exists {
+		return session.Status
+	}
+	return ""
+}
+
+// buildHLSArgs builds the FFmpeg arguments for an HLS session.
+// Full sessions copy the video stream as-is:
+//
+//	ffmpeg -i rtsp://camera/stream -c:v copy -c:a aac -f hls \
+//	       -hls_time 2 -hls_list_size 5 -hls_flags delete_segments \
+//	       -hls_segment_filename 'segment_%03d.ts' playlist.m3u8
+//
+// Preview sessions drop audio and re-encode to a small, low frame rate output.
+// A non-empty transport is passed as -rtsp_transport ahead of the input.
+func buildHLSArgs(rtspURL, transport, sessionDir string, preview bool) []string {
+	var args []string
+	if transport != "" {
+		args = append(args, "-rtsp_transport", transport)
+	}
+	args = append(args, "-i", rtspURL)
+
+	if preview {
+		args = append(args,
+			"-an",
+			"-vf", fmt.Sprintf("scale=-2:%d", previewHeight),
+			"-r", strconv.Itoa(previewFrameRate),
+			"-c:v", "libx264",
+			"-preset", "ultrafast",
+			"-tune", "zerolatency",
+		)
+	} else {
+		args = append(args,
+			"-c:v", "copy",
+			"-c:a", "aac",
+		)
+	}
+
+	return append(args,
+		"-f", "hls",
+		"-hls_time", "2",
+		"-hls_list_size", "5",
+		"-hls_flags", "delete_segments",
+		"-hls_segment_filename", filepath.Join(sessionDir, "segment_%03d.ts"),
+		filepath.Join(sessionDir, "playlist.m3u8"),
+	)
 }
 
 // GetHLSPlaylist returns the path to the HLS playlist for a session
@@ -253,8 +550,13 @@ func (s *StreamService) GetHLSPlaylist(sessionID string) (string, error) {
 
 	// Update last access time
 	s.sessionsMu.Lock()
-	session.LastAccess = time.Now()
-	session.ExpiresAt = time.Now().Add(30 * time.Minute)
+	if session.Status == SessionStatusFailed {
+		s.sessionsMu.Unlock()
+		return "", ErrSessionStartupFailed
+	}
+	now := s.clock.Now()
+	session.LastAccess = now
+	session.ExpiresAt = now.Add(30 * time.Minute)
 	s.sessionsMu.Unlock()
 
 	playlistPath := filepath.Join(s.hlsOutputDir, sessionID, "playlist.m3u8")
@@ -273,8 +575,9 @@ func (s *StreamService) GetHLSSegment(sessionID, segmentName string) (string, er
 
 	// Update last access time
 	s.sessionsMu.Lock()
-	session.LastAccess = time.Now()
-	session.ExpiresAt = time.Now().Add(30 * time.Minute)
+	now := s.clock.Now()
+	session.LastAccess = now
+	session.ExpiresAt = now.Add(30 * time.Minute)
 	s.sessionsMu.Unlock()
 
 	segmentPath := filepath.Join(s.hlsOutputDir, sessionID, segmentName)
@@ -287,6 +590,23 @@ func (s *StreamService) GetHLSSegment(sessionID, segmentName string) (string, er
 	return segmentPath, nil
 }
 
+// IsStreaming reports whether a camera has a running FLV proxy or MJPEG
+// stream, or an HLS session that has not failed
+func (s *StreamService) IsStreaming(cameraID string) bool {
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	if s.liveStreams[cameraID] > 0 {
+		return true
+	}
+	for _, session := range s.sessions {
+		if session.CameraID == cameraID && session.Status != SessionStatusFailed {
+			return true
+		}
+	}
+	return false
+}
+
 // StopSession stops a streaming session
 func (s *StreamService) StopSession(sessionID string) error {
 	s.sessionsMu.Lock()
@@ -305,7 +625,7 @@ func (s *StreamService) StopSession(sessionID string) error {
 
 	// Cleanup session directory
 	sessionDir := filepath.Join(s.hlsOutputDir, sessionID)
-	if err := os.RemoveAll(sessionDir); err != nil {
+	if err := s.removeAll(sessionDir); err != nil {
 		logger.Error("Failed to cleanup session directory",
 			zap.String("session_id", sessionID),
 			zap.Error(err))
@@ -324,27 +644,85 @@ func (s *StreamService) cleanupExpiredSessions(interval time.Duration) {
 	defer ticker.Stop()
 
 	for range ticker.C {
-		now := time.Now()
-		s.sessionsMu.Lock()
-		for sessionID, session := range s.sessions {
-			if now.After(session.ExpiresAt) {
-				logger.Info("Cleaning up expired session",
-					zap.String("session_id", sessionID),
-					zap.String("camera_id", session.CameraID))
+		s.removeExpiredSessions(s.clock.Now())
+	}
+}
 
-				// Cancel context
-				if session.cancel != nil {
-					session.cancel()
-				}
+// removeExpiredSessions stops the sessions expired at now and returns their
+// IDs. Sessions are taken out of the map under the lock and their directories
+// deleted after releasing it, so slow disk I/O does not block session lookups.
+func (s *StreamService) removeExpiredSessions(now time.Time) []string {
+	var expired []*StreamSession
+	s.sessionsMu.Lock()
+	for sessionID, session := range s.sessions {
+		if now.After(session.ExpiresAt) {
+			delete(s.sessions, sessionID)
+			expired = append(expired, session)
+		}
+	}
+	s.sessionsMu.Unlock()
 
-				// Remove from map
-				delete(s.sessions, sessionID)
+	removed := make([]string, 0, len(expired))
+	for _, session := range expired {
+		logger.Info("Cleaning up expired session",
+			zap.String("session_id", session.ID),
+			zap.String("camera_id", session.CameraID))
 
-				// Cleanup directory
-				sessionDir := filepath.Join(s.hlsOutputDir, sessionID)
-				os.RemoveAll(sessionDir)
-			}
+		// Cancel context
+		if session.cancel != nil {
+			session.cancel()
 		}
-		s.sessionsMu.Unlock()
+
+		// Cleanup directory
+		if err := s.removeAll(filepath.Join(s.hlsOutputDir, session.ID)); err != nil {
+			logger.Error("Failed to cleanup session directory",
+				zap.String("session_id", session.ID),
+				zap.Error(err))
+		}
+		removed = append(removed, session.ID)
+	}
+
+	return removed
+}
+
+// removeOrphanedSessionDirs deletes session directories in the HLS output
+// directory that do not belong to a live session, e.g. after a crash. Only
+// directories named like session IDs are touched, so other content of a shared
+// output directory is left alone.
+func (s *StreamService) removeOrphanedSessionDirs() {
+	entries, err := os.ReadDir(s.hlsOutputDir)
+	if err != nil {
+		logger.Warn("Failed to scan HLS output directory", zap.String("dir", s.hlsOutputDir), zap.Error(err))
+		return
+	}
+
+	s.sessionsMu.RLock()
+	defer s.sessionsMu.RUnlock()
+
+	removed := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, err := uuid.Parse(entry.Name()); err != nil {
+			continue
+		}
+		if _, live := s.sessions[entry.Name()]; live {
+			continue
+		}
+
+		if err := os.RemoveAll(filepath.Join(s.hlsOutputDir, entry.Name())); err != nil {
+			logger.Warn("Failed to remove orphaned HLS session directory",
+				zap.String("session_id", entry.Name()),
+				zap.Error(err))
+			continue
+		}
+		removed++
+	}
+
+	if removed > 0 {
+		logger.Info("Removed orphaned HLS session directories",
+			zap.String("dir", s.hlsOutputDir),
+			zap.Int("count", removed))
 	}
 }
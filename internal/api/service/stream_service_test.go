@@ -2,16 +2,23 @@ package service
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
@@ -52,6 +59,32 @@ func TestNewStreamService(t *testing.T) {
 	assert.Equal(t, "/usr/bin/ffmpeg", service.ffmpegPath)
 }
 
+func TestNewStreamService_RemovesOrphanedSessionDirs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	orphan := filepath.Join(tmpDir, uuid.New().String())
+	require.NoError(t, os.MkdirAll(orphan, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(orphan, "segment_000.ts"), []byte("ts"), 0644))
+	unrelated := filepath.Join(tmpDir, "keep-me")
+	require.NoError(t, os.MkdirAll(unrelated, 0755))
+
+	config := DefaultStreamServiceConfig()
+	config.HLSOutputDir = tmpDir
+	service := NewStreamService(new(MockCameraManagerForStream), config)
+
+	assert.NoDirExists(t, orphan)
+	assert.DirExists(t, unrelated, "directories not named like sessions are left alone")
+
+	// Directories of live sessions survive a later sweep
+	liveID := uuid.New().String()
+	live := filepath.Join(tmpDir, liveID)
+	require.NoError(t, os.MkdirAll(live, 0755))
+	service.sessions[liveID] = &StreamSession{ID: liveID, CameraID: "cam-123"}
+
+	service.removeOrphanedSessionDirs()
+	assert.DirExists(t, live)
+}
+
 func TestStreamService_ProxyFLVStream_CameraNotFound(t *testing.T) {
 	mockCameraManager := new(MockCameraManagerForStream)
 	service := NewStreamService(mockCameraManager, nil)
@@ -218,6 +251,98 @@ func TestStreamService_StopSession_Success(t *testing.T) {
 	assert.Error(t, ctx.Err())
 }
 
+func TestStreamService_RemoveExpiredSessions(t *testing.T) {
+	service := NewStreamService(new(MockCameraManagerForStream), &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+	now := time.Now()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.sessions["expired"] = &StreamSession{ID: "expired", CameraID: "cam-1", ExpiresAt: now.Add(-time.Second), cancel: cancel}
+	service.sessions["live"] = &StreamSession{ID: "live", CameraID: "cam-2", ExpiresAt: now.Add(time.Minute)}
+
+	assert.Equal(t, []string{"expired"}, service.removeExpiredSessions(now))
+	assert.NotContains(t, service.sessions, "expired")
+	assert.Contains(t, service.sessions, "live")
+	assert.Error(t, ctx.Err(), "expired sessions are stopped")
+}
+
+func TestStreamService_SessionExpiryWithClock(t *testing.T) {
+	service := NewStreamService(new(MockCameraManagerForStream), &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	service.SetClock(fake)
+
+	service.sessions["session-1"] = &StreamSession{ID: "session-1", CameraID: "cam-1", Status: SessionStatusRunning}
+
+	// Fetching the playlist keeps the session alive for another 30 minutes
+	_, err := service.GetHLSPlaylist("session-1")
+	require.NoError(t, err)
+	assert.Equal(t, fake.Now(), service.sessions["session-1"].LastAccess)
+
+	fake.Advance(29 * time.Minute)
+	assert.Empty(t, service.removeExpiredSessions(fake.Now()))
+
+	// So does fetching a segment
+	_, err = service.GetHLSSegment("session-1", "segment_000.ts")
+	require.NoError(t, err)
+	fake.Advance(29 * time.Minute)
+	assert.Empty(t, service.removeExpiredSessions(fake.Now()))
+
+	fake.Advance(2 * time.Minute)
+	assert.Equal(t, []string{"session-1"}, service.removeExpiredSessions(fake.Now()))
+	assert.NotContains(t, service.sessions, "session-1")
+}
+
+func TestStreamService_RemoveExpiredSessions_DoesNotBlockLookups(t *testing.T) {
+	service := NewStreamService(new(MockCameraManagerForStream), &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+	now := time.Now()
+
+	service.sessions["expired"] = &StreamSession{ID: "expired", CameraID: "cam-1", ExpiresAt: now.Add(-time.Second)}
+	service.sessions["live"] = &StreamSession{ID: "live", CameraID: "cam-2", Status: SessionStatusRunning, ExpiresAt: now.Add(time.Minute)}
+
+	// Directory removal blocks until released, like a slow disk
+	removing := make(chan struct{})
+	release := make(chan struct{})
+	service.removeAll = func(path string) error {
+		close(removing)
+		<-release
+		return nil
+	}
+
+	cleanupDone := make(chan []string)
+	go func() { cleanupDone <- service.removeExpiredSessions(now) }()
+	<-removing
+
+	lookupDone := make(chan struct{})
+	go func() {
+		defer close(lookupDone)
+		_, err := service.GetHLSPlaylist("live")
+		assert.NoError(t, err)
+		assert.True(t, service.IsStreaming("cam-2"))
+	}()
+
+	select {
+	case <-lookupDone:
+	case <-time.After(time.Second):
+		t.Fatal("session lookups blocked while an expired session directory is removed")
+	}
+
+	close(release)
+	assert.Equal(t, []string{"expired"}, <-cleanupDone)
+}
+
+func TestStreamService_IsStreaming(t *testing.T) {
+	mockCameraManager := new(MockCameraManagerForStream)
+	service := NewStreamService(mockCameraManager, &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+
+	service.sessions["running"] = &StreamSession{ID: "running", CameraID: "cam-1", Status: SessionStatusRunning}
+	service.sessions["failed"] = &StreamSession{ID: "failed", CameraID: "cam-2", Status: SessionStatusFailed}
+	service.liveStreams["cam-3"] = 1
+
+	assert.True(t, service.IsStreaming("cam-1"))
+	assert.False(t, service.IsStreaming("cam-2"), "failed sessions do not count")
+	assert.True(t, service.IsStreaming("cam-3"))
+	assert.False(t, service.IsStreaming("cam-4"))
+}
+
 func TestStreamService_GetHLSPlaylist_UpdatesLastAccess(t *testing.T) {
 	mockCameraManager := new(MockCameraManagerForStream)
 	tmpDir := t.TempDir()
@@ -288,3 +413,266 @@ func TestStreamService_GetHLSSegment_UpdatesLastAccess(t *testing.T) {
 	assert.True(t, session.ExpiresAt.After(oldTime.Add(30*time.Minute)))
 }
 
+
+func TestBuildHLSArgs_Full(t *testing.T) {
+	args := buildHLSArgs("rtsp://cam/Preview_01_main", "", "/tmp/hls/session", false)
+
+	assert.Equal(t, []string{"-i", "rtsp://cam/Preview_01_main"}, args[:2])
+	assert.Contains(t, strings.Join(args, " "), "-c:v copy")
+	assert.Contains(t, strings.Join(args, " "), "-c:a aac")
+	assert.NotContains(t, args, "-vf")
+	assert.Equal(t, filepath.Join("/tmp/hls/session", "playlist.m3u8"), args[len(args)-1])
+}
+
+func TestBuildHLSArgs_RTSPTransport(t *testing.T) {
+	args := buildHLSArgs("rtsp://cam/Preview_01_main", models.RTSPTransportTCP, "/tmp/hls/session", false)
+	assert.Equal(t, []string{"-rtsp_transport", "tcp", "-i", "rtsp://cam/Preview_01_main"}, args[:4])
+
+	args = buildHLSArgs("rtsp://cam/Preview_01_main", "", "/tmp/hls/session", false)
+	assert.NotContains(t, args, "-rtsp_transport")
+}
+
+func TestBuildHLSArgs_Preview(t *testing.T) {
+	args := buildHLSArgs("rtsp://cam/Preview_01_sub", "", "/tmp/hls/session", true)
+	joined := strings.Join(args, " ")
+
+	assert.Contains(t, joined, "-an")
+	assert.Contains(t, joined, "-vf scale=-2:360")
+	assert.Contains(t, joined, "-r 5")
+	assert.Contains(t, joined, "-c:v libx264")
+	assert.NotContains(t, joined, "-c:v copy")
+	assert.NotContains(t, joined, "-c:a aac")
+	assert.Equal(t, filepath.Join("/tmp/hls/session", "playlist.m3u8"), args[len(args)-1])
+}
+
+func TestStreamService_StartPreviewStream_UsesSubStream(t *testing.T) {
+	tmpDir := t.TempDir()
+	argsFile := filepath.Join(tmpDir, "args.txt")
+
+	// Fake ffmpeg that records its arguments
+	script := filepath.Join(tmpDir, "ffmpeg.sh")
+	err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\n"), 0755)
+	assert.NoError(t, err)
+
+	mockCameraManager := new(MockCameraManagerForStream)
+	service := NewStreamService(mockCameraManager, &StreamServiceConfig{
+		HLSOutputDir:    filepath.Join(tmpDir, "hls"),
+		FFmpegPath:      script,
+		SessionTimeout:  30 * time.Minute,
+		CleanupInterval: 5 * time.Minute,
+	})
+
+	cameraClient := &camera.CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Host: "192.168.1.100"},
+		Client: reolink.NewClient("192.168.1.100", reolink.WithCredentials("admin", "password")),
+	}
+	mockCameraManager.On("GetCamera", "cam-123").Return(cameraClient, nil)
+
+	// Main stream requests are forced down to the sub stream
+	session, err := service.StartPreviewStream(context.Background(), "cam-123", reolink.StreamMain, 0)
+	assert.NoError(t, err)
+	assert.NotNil(t, session)
+	assert.True(t, session.Preview)
+
+	assert.Eventually(t, func() bool {
+		data, err := os.ReadFile(argsFile)
+		return err == nil && len(data) > 0
+	}, 2*time.Second, 20*time.Millisecond)
+
+	data, _ := os.ReadFile(argsFile)
+	assert.Contains(t, string(data), "Preview_00_sub")
+	assert.NotContains(t, string(data), "Preview_00_main")
+	assert.Contains(t, string(data), "-r 5")
+	mockCameraManager.AssertExpectations(t)
+}
+
+func TestStreamService_StartHLSStream_RTSPTransport(t *testing.T) {
+	tests := []struct {
+		name             string
+		defaultTransport string
+		profileTransport string
+		cameraTransport  string
+		want             string
+	}{
+		{"server default", models.RTSPTransportTCP, "", "", "-rtsp_transport tcp -i "},
+		{"camera overrides default", models.RTSPTransportTCP, "", models.RTSPTransportUDP, "-rtsp_transport udp -i "},
+		{"profile overrides default", models.RTSPTransportUDP, models.RTSPTransportTCP, "", "-rtsp_transport tcp -i "},
+		{"camera overrides profile", "", models.RTSPTransportTCP, models.RTSPTransportUDP, "-rtsp_transport udp -i "},
+		{"ffmpeg default", "", "", "", "-i "},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpDir := t.TempDir()
+			argsFile := filepath.Join(tmpDir, "args.txt")
+
+			// Fake ffmpeg that records its arguments
+			script := filepath.Join(tmpDir, "ffmpeg.sh")
+			err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$@\" > "+argsFile+"\n"), 0755)
+			assert.NoError(t, err)
+
+			mockCameraManager := new(MockCameraManagerForStream)
+			service := NewStreamService(mockCameraManager, &StreamServiceConfig{
+				HLSOutputDir:    filepath.Join(tmpDir, "hls"),
+				FFmpegPath:      script,
+				SessionTimeout:  30 * time.Minute,
+				CleanupInterval: 5 * time.Minute,
+				RTSPTransport:   tt.defaultTransport,
+			})
+
+			cameraClient := &camera.CameraClient{
+				Camera: &models.Camera{ID: "cam-123", Host: "192.168.1.100", RTSPTransport: tt.cameraTransport},
+				Client: reolink.NewClient("192.168.1.100", reolink.WithCredentials("admin", "password")),
+
+				ConnectionProfile: camera.ConnectionProfile{RTSPTransport: tt.profileTransport},
+			}
+			mockCameraManager.On("GetCamera", "cam-123").Return(cameraClient, nil)
+
+			_, err = service.StartHLSStream(context.Background(), "cam-123", reolink.StreamMain, 0)
+			assert.NoError(t, err)
+
+			assert.Eventually(t, func() bool {
+				data, err := os.ReadFile(argsFile)
+				return err == nil && len(data) > 0
+			}, 2*time.Second, 20*time.Millisecond)
+
+			data, _ := os.ReadFile(argsFile)
+			assert.True(t, strings.HasPrefix(string(data), tt.want), "ffmpeg args: %s", data)
+		})
+	}
+}
+
+// newWatchdogTestService creates a stream service backed by a fake ffmpeg script
+func newWatchdogTestService(t *testing.T, script string) (*StreamService, *MockCameraManagerForStream) {
+	tmpDir := t.TempDir()
+	scriptPath := filepath.Join(tmpDir, "ffmpeg.sh")
+	err := os.WriteFile(scriptPath, []byte("#!/bin/sh\n"+script+"\n"), 0755)
+	assert.NoError(t, err)
+
+	mockCameraManager := new(MockCameraManagerForStream)
+	service := NewStreamService(mockCameraManager, &StreamServiceConfig{
+		HLSOutputDir:    filepath.Join(tmpDir, "hls"),
+		FFmpegPath:      scriptPath,
+		SessionTimeout:  30 * time.Minute,
+		CleanupInterval: 5 * time.Minute,
+		StartupTimeout:  300 * time.Millisecond,
+	})
+
+	cameraClient := &camera.CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Host: "192.168.1.100"},
+		Client: reolink.NewClient("192.168.1.100", reolink.WithCredentials("admin", "password")),
+	}
+	mockCameraManager.On("GetCamera", "cam-123").Return(cameraClient, nil)
+
+	return service, mockCameraManager
+}
+
+func TestStreamService_StartHLSStream_NoPlaylistTimesOut(t *testing.T) {
+	// FFmpeg that keeps running but never writes a playlist
+	service, mockCameraManager := newWatchdogTestService(t, "exec sleep 10")
+
+	session, err := service.StartHLSStream(context.Background(), "cam-123", reolink.StreamMain, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, SessionStatusStarting, service.sessionStatus(session.ID))
+
+	assert.Eventually(t, func() bool {
+		return service.sessionStatus(session.ID) == SessionStatusFailed
+	}, 3*time.Second, 20*time.Millisecond)
+
+	_, err = service.GetHLSPlaylist(session.ID)
+	assert.ErrorIs(t, err, ErrSessionStartupFailed)
+
+	_, err = os.Stat(filepath.Join(service.hlsOutputDir, session.ID))
+	assert.True(t, os.IsNotExist(err))
+
+	// The session stays visible after FFmpeg is killed
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, SessionStatusFailed, service.sessionStatus(session.ID))
+	mockCameraManager.AssertExpectations(t)
+}
+
+func TestStreamService_StartHLSStream_PlaylistMarksRunning(t *testing.T) {
+	// FFmpeg that writes its playlist (the last argument) and keeps running
+	service, mockCameraManager := newWatchdogTestService(t,
+		"for last; do :; done\ntouch \"$last\"\nexec sleep 10")
+
+	session, err := service.StartHLSStream(context.Background(), "cam-123", reolink.StreamMain, 0)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return service.sessionStatus(session.ID) == SessionStatusRunning
+	}, 3*time.Second, 20*time.Millisecond)
+
+	// Status does not flip to failed once the timeout passes
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, SessionStatusRunning, service.sessionStatus(session.ID))
+
+	_, err = service.GetHLSPlaylist(session.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, service.StopSession(session.ID))
+	mockCameraManager.AssertExpectations(t)
+}
+
+func TestStreamService_SnapshotStream(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte{0xff, 0xd8, 0xff, 0xd9})
+	}))
+	defer cameraServer.Close()
+
+	mockCameraManager := new(MockCameraManagerForStream)
+	mockCameraManager.On("GetCamera", "cam-123").Return(&camera.CameraClient{
+		Camera: &models.Camera{ID: "cam-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}, nil)
+	mockCameraManager.On("GetCamera", "missing").Return(nil, errors.New("camera missing not found"))
+	service := NewStreamService(mockCameraManager, &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+
+	t.Run("delivers frames until the client disconnects", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		frames := 0
+		err := service.SnapshotStream(ctx, "cam-123", 0, 10, func(jpeg []byte) error {
+			assert.Equal(t, []byte{0xff, 0xd8, 0xff, 0xd9}, jpeg)
+			assert.True(t, service.IsStreaming("cam-123"))
+			if frames++; frames == 3 {
+				cancel()
+			}
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, frames)
+		assert.False(t, service.IsStreaming("cam-123"))
+	})
+
+	t.Run("unknown camera", func(t *testing.T) {
+		err := service.SnapshotStream(context.Background(), "missing", 0, 2, func([]byte) error { return nil })
+		assert.ErrorIs(t, err, ErrCameraNotFound)
+	})
+
+	t.Run("write failure ends the stream", func(t *testing.T) {
+		writeErr := errors.New("broken pipe")
+		err := service.SnapshotStream(context.Background(), "cam-123", 0, 10, func([]byte) error { return writeErr })
+		assert.ErrorIs(t, err, writeErr)
+	})
+}
+
+func TestStreamService_SnapshotStream_CircuitOpen(t *testing.T) {
+	mockCameraManager := new(MockCameraManagerForStream)
+	mockCameraManager.On("GetCamera", "cam-123").Return(&camera.CameraClient{
+		Camera:      &models.Camera{ID: "cam-123"},
+		Client:      reolink.NewClient("192.168.1.100"),
+		CircuitOpen: true,
+	}, nil)
+	service := NewStreamService(mockCameraManager, &StreamServiceConfig{HLSOutputDir: t.TempDir(), CleanupInterval: time.Minute})
+
+	err := service.SnapshotStream(context.Background(), "cam-123", 0, 2, func([]byte) error {
+		t.Fatal("no frame expected")
+		return nil
+	})
+
+	assert.ErrorIs(t, err, camera.ErrCircuitOpen)
+}
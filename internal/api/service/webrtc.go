@@ -0,0 +1,283 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// ErrWebRTCUnavailable is returned for WebRTC streams when no go2rtc instance is configured
+var ErrWebRTCUnavailable = errors.New("webrtc streaming is not configured")
+
+const (
+	// webrtcRequestTimeout bounds a single request to the go2rtc API
+	webrtcRequestTimeout = 10 * time.Second
+
+	// webrtcSessionTimeout is how long a WebRTC session lives without viewers
+	webrtcSessionTimeout = 30 * time.Minute
+)
+
+// sessionDescription is an SDP offer or answer as exchanged with go2rtc
+type sessionDescription struct {
+	Type string `json:"type"`
+	SDP  string `json:"sdp"`
+}
+
+// StartWebRTCStream starts a WebRTC session for a camera and returns the SDP
+// answer to the client's offer.
+//
+// FFmpeg cannot answer SDP offers itself, so the camera's RTSP stream is
+// repackaged by FFmpeg and published to a go2rtc instance, which terminates
+// WebRTC. The offer is forwarded to go2rtc once the published stream is
+// available; a session whose stream does not show up within the startup
+// timeout is stopped and ErrSessionStartupFailed is returned.
+func (s *StreamService) StartWebRTCStream(ctx context.Context, cameraID string, streamType reolink.StreamType, channel int, offer string) (*StreamSession, string, error) {
+	if s.go2rtcURL == "" || s.go2rtcRTSPURL == "" {
+		return nil, "", ErrWebRTCUnavailable
+	}
+
+	client, err := s.cameraManager.GetCamera(cameraID)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCameraNotFound, err)
+	}
+
+	rtspURL := client.GetRTSPURL(streamType, channel)
+	if rtspURL == "" {
+		return nil, "", fmt.Errorf("failed to get RTSP URL for camera %s", cameraID)
+	}
+
+	transport := client.RTSPTransport()
+	if transport == "" {
+		transport = s.rtspTransport
+	}
+
+	// Sessions share the HLS output directory so StopSession and the
+	// orphan sweep clean them up the same way
+	sessionID := uuid.New().String()
+	sessionDir := filepath.Join(s.hlsOutputDir, sessionID)
+	if err := os.MkdirAll(sessionDir, 0755); err != nil {
+		return nil, "", fmt.Errorf("failed to create session directory: %w", err)
+	}
+
+	// FFmpeg outlives the request that starts the session
+	ffmpegCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+
+	publishURL := s.go2rtcRTSPURL + "/" + sessionID
+	cmd := exec.CommandContext(ffmpegCtx, s.ffmpegPath, buildWebRTCArgs(rtspURL, transport, publishURL)...)
+	cmd.Dir = sessionDir
+
+	stderrPipe, err := cmd.StderrPipe()
+	if err != nil {
+		cancel()
+		os.RemoveAll(sessionDir)
+		return nil, "", fmt.Errorf("failed to create stderr pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		os.RemoveAll(sessionDir)
+		return nil, "", fmt.Errorf("failed to start FFmpeg: %w", err)
+	}
+
+	logger.Info("Started WebRTC session",
+		zap.String("session_id", sessionID),
+		zap.String("camera_id", cameraID),
+		zap.String("rtsp_url", rtspURL),
+		zap.String("rtsp_transport", transport),
+		zap.String("publish_url", publishURL))
+
+	now := s.clock.Now()
+	session := &StreamSession{
+		ID:         sessionID,
+		CameraID:   cameraID,
+		StreamType: StreamTypeWebRTC,
+		Status:     SessionStatusStarting,
+		StartedAt:  now,
+		LastAccess: now,
+		ExpiresAt:  now.Add(webrtcSessionTimeout),
+		cancel:     cancel,
+	}
+
+	s.sessionsMu.Lock()
+	s.sessions[sessionID] = session
+	s.sessionsMu.Unlock()
+
+	s.superviseFFmpeg(ffmpegCtx, sessionID, cmd, stderrPipe)
+
+	answer, err := s.exchangeOffer(ctx, ffmpegCtx, sessionID, offer)
+	if err != nil {
+		s.StopSession(sessionID)
+		return nil, "", err
+	}
+
+	s.sessionsMu.Lock()
+	session.Status = SessionStatusRunning
+	s.sessionsMu.Unlock()
+
+	go s.watchWebRTCViewers(ffmpegCtx, session)
+
+	return session, answer, nil
+}
+
+// buildWebRTCArgs builds the FFmpeg arguments for a WebRTC session. Video is
+// copied as-is and audio re-encoded to Opus, the codec WebRTC requires:
+//
+//	ffmpeg -i rtsp://camera/stream -c:v copy -c:a libopus -ar 48000 \
+//	       -f rtsp -rtsp_transport tcp rtsp://go2rtc:8554/<session>
+//
+// A non-empty transport is passed as -rtsp_transport ahead of the input.
+func buildWebRTCArgs(rtspURL, transport, publishURL string) []string {
+	var args []string
+	if transport != "" {
+		args = append(args, "-rtsp_transport", transport)
+	}
+
+	return append(args,
+		"-i", rtspURL,
+		"-c:v", "copy",
+		"-c:a", "libopus",
+		"-ar", "48000",
+		"-f", "rtsp",
+		"-rtsp_transport", "tcp",
+		publishURL,
+	)
+}
+
+// exchangeOffer forwards an SDP offer to go2rtc until the session's stream is
+// published there and returns go2rtc's answer. It gives up when the startup
+// timeout passes, FFmpeg exits or the request is cancelled.
+func (s *StreamService) exchangeOffer(ctx, ffmpegCtx context.Context, sessionID, offer string) (string, error) {
+	deadline := time.NewTimer(s.startupTimeout)
+	defer deadline.Stop()
+
+	ticker := time.NewTicker(playlistPollInterval)
+	defer ticker.Stop()
+
+	for {
+		answer, err := s.postOffer(ctx, sessionID, offer)
+		if err == nil {
+			return answer, nil
+		}
+		logger.Debug("go2rtc did not answer WebRTC offer yet",
+			zap.String("session_id", sessionID),
+			zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ffmpegCtx.Done():
+			return "", fmt.Errorf("%w: FFmpeg exited", ErrSessionStartupFailed)
+		case <-deadline.C:
+			return "", fmt.Errorf("%w: %v", ErrSessionStartupFailed, err)
+		case <-ticker.C:
+		}
+	}
+}
+
+// postOffer sends an SDP offer for a session's stream to the go2rtc API
+func (s *StreamService) postOffer(ctx context.Context, sessionID, offer string) (string, error) {
+	body, err := json.Marshal(sessionDescription{Type: "offer", SDP: offer})
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := s.go2rtcURL + "/api/webrtc?src=" + url.QueryEscape(sessionID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("go2rtc request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("go2rtc returned status %d: %s", resp.StatusCode, bytes.TrimSpace(message))
+	}
+
+	var answer sessionDescription
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", fmt.Errorf("failed to decode go2rtc answer: %w", err)
+	}
+	if answer.Type != "answer" || answer.SDP == "" {
+		return "", fmt.Errorf("go2rtc returned no SDP answer")
+	}
+
+	return answer.SDP, nil
+}
+
+// watchWebRTCViewers keeps a WebRTC session alive while go2rtc reports
+// viewers of its stream. Sessions closed without a teardown request expire
+// once their last viewer is gone.
+func (s *StreamService) watchWebRTCViewers(ctx context.Context, session *StreamSession) {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			viewers, err := s.countViewers(ctx, session.ID)
+			if err != nil {
+				logger.Debug("Failed to query go2rtc viewers",
+					zap.String("session_id", session.ID),
+					zap.Error(err))
+				continue
+			}
+			if viewers > 0 {
+				s.sessionsMu.Lock()
+				now := s.clock.Now()
+				session.LastAccess = now
+				session.ExpiresAt = now.Add(webrtcSessionTimeout)
+				s.sessionsMu.Unlock()
+			}
+		}
+	}
+}
+
+// countViewers returns how many consumers go2rtc has for a session's stream
+func (s *StreamService) countViewers(ctx context.Context, sessionID string) (int, error) {
+	endpoint := s.go2rtcURL + "/api/streams?src=" + url.QueryEscape(sessionID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("go2rtc returned status %d", resp.StatusCode)
+	}
+
+	var stream struct {
+		Consumers []json.RawMessage `json:"consumers"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&stream); err != nil {
+		return 0, err
+	}
+	return len(stream.Consumers), nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+)
+
+// newWebRTCTestService creates a stream service backed by a fake ffmpeg script
+// that keeps running and a fake go2rtc answering offers once ready is set
+func newWebRTCTestService(t *testing.T, ready *atomic.Bool) (*StreamService, *MockCameraManagerForStream) {
+	go2rtc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/webrtc" || !ready.Load() {
+			http.Error(w, "stream not found", http.StatusNotFound)
+			return
+		}
+		var offer sessionDescription
+		if err := json.NewDecoder(r.Body).Decode(&offer); err != nil || offer.Type != "offer" {
+			http.Error(w, "bad offer", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(sessionDescription{Type: "answer", SDP: "answer for " + r.URL.Query().Get("src")})
+	}))
+	t.Cleanup(go2rtc.Close)
+
+	service, mockCameraManager := newWatchdogTestService(t, "exec sleep 10")
+	service.go2rtcURL = go2rtc.URL
+	service.go2rtcRTSPURL = "rtsp://127.0.0.1:8554"
+	return service, mockCameraManager
+}
+
+func TestBuildWebRTCArgs(t *testing.T) {
+	args := buildWebRTCArgs("rtsp://camera/stream", "udp", "rtsp://go2rtc:8554/session")
+	joined := strings.Join(args, " ")
+
+	assert.True(t, strings.HasPrefix(joined, "-rtsp_transport udp -i rtsp://camera/stream"))
+	assert.Contains(t, joined, "-c:v copy")
+	assert.Contains(t, joined, "-c:a libopus")
+	assert.Contains(t, joined, "-f rtsp -rtsp_transport tcp")
+	assert.Equal(t, "rtsp://go2rtc:8554/session", args[len(args)-1])
+}
+
+func TestStreamService_StartWebRTCStream_NotConfigured(t *testing.T) {
+	service := NewStreamService(new(MockCameraManagerForStream), &StreamServiceConfig{
+		HLSOutputDir:    t.TempDir(),
+		FFmpegPath:      "ffmpeg",
+		CleanupInterval: 5 * time.Minute,
+	})
+
+	_, _, err := service.StartWebRTCStream(context.Background(), "cam-123", reolink.StreamMain, 0, "v=0")
+	assert.ErrorIs(t, err, ErrWebRTCUnavailable)
+}
+
+func TestStreamService_StartWebRTCStream(t *testing.T) {
+	var ready atomic.Bool
+	service, mockCameraManager := newWebRTCTestService(t, &ready)
+
+	// go2rtc only answers once FFmpeg has published the stream
+	time.AfterFunc(100*time.Millisecond, func() { ready.Store(true) })
+
+	// The session must outlive the request starting it
+	ctx, cancel := context.WithCancel(context.Background())
+	session, answer, err := service.StartWebRTCStream(ctx, "cam-123", reolink.StreamMain, 0, "v=0 offer")
+	cancel()
+	require.NoError(t, err)
+
+	assert.Equal(t, "answer for "+session.ID, answer)
+	assert.Equal(t, StreamTypeWebRTC, session.StreamType)
+	assert.Equal(t, SessionStatusRunning, service.sessionStatus(session.ID))
+	assert.True(t, service.IsStreaming("cam-123"))
+
+	sessionDir := filepath.Join(service.hlsOutputDir, session.ID)
+	_, err = os.Stat(sessionDir)
+	assert.NoError(t, err)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, SessionStatusRunning, service.sessionStatus(session.ID))
+
+	assert.NoError(t, service.StopSession(session.ID))
+	_, err = os.Stat(sessionDir)
+	assert.True(t, os.IsNotExist(err))
+	assert.False(t, service.IsStreaming("cam-123"))
+	mockCameraManager.AssertExpectations(t)
+}
+
+func TestStreamService_StartWebRTCStream_NoStreamTimesOut(t *testing.T) {
+	var ready atomic.Bool
+	service, mockCameraManager := newWebRTCTestService(t, &ready)
+
+	_, _, err := service.StartWebRTCStream(context.Background(), "cam-123", reolink.StreamMain, 0, "v=0 offer")
+	assert.ErrorIs(t, err, ErrSessionStartupFailed)
+
+	// The session and its directory are gone
+	assert.False(t, service.IsStreaming("cam-123"))
+	entries, err := os.ReadDir(service.hlsOutputDir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+	mockCameraManager.AssertExpectations(t)
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/mosleyit/reolink_server/internal/config"
+)
+
+// ConfigureTLS prepares server for HTTPS according to the server TLS settings.
+// It returns a plain HTTP server redirecting to HTTPS when redirect_http is
+// enabled (which also answers ACME challenges when autocert is used).
+func ConfigureTLS(server *http.Server, cfg config.ServerConfig) (*http.Server, error) {
+	if !cfg.TLS.Enabled {
+		return nil, nil
+	}
+
+	var manager *autocert.Manager
+	if cfg.TLS.AutoCert {
+		manager = &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.AutoCertHosts...),
+		}
+		if cfg.TLS.AutoCertCacheDir != "" {
+			manager.Cache = autocert.DirCache(cfg.TLS.AutoCertCacheDir)
+		}
+		server.TLSConfig = manager.TLSConfig()
+	} else {
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+		}
+		server.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}
+	}
+
+	if !cfg.TLS.RedirectHTTP {
+		return nil, nil
+	}
+
+	httpPort := cfg.TLS.HTTPPort
+	if httpPort == 0 {
+		httpPort = 80
+	}
+
+	var handler http.Handler = redirectToHTTPS(cfg.Port)
+	if manager != nil {
+		handler = manager.HTTPHandler(handler)
+	}
+
+	return &http.Server{
+		Addr:         net.JoinHostPort(cfg.Host, strconv.Itoa(httpPort)),
+		Handler:      handler,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+		IdleTimeout:  cfg.IdleTimeout,
+	}, nil
+}
+
+// ListenAndServe starts server over HTTPS when ConfigureTLS set it up, and
+// over plain HTTP otherwise
+func ListenAndServe(server *http.Server) error {
+	ln, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		return err
+	}
+	return serve(server, ln)
+}
+
+// serve serves connections from ln, using TLS if configured
+func serve(server *http.Server, ln net.Listener) error {
+	if server.TLSConfig != nil {
+		return server.ServeTLS(ln, "", "")
+	}
+	return server.Serve(ln)
+}
+
+// redirectToHTTPS returns a handler redirecting every request to the HTTPS port
+func redirectToHTTPS(httpsPort int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if httpsPort != 0 && httpsPort != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/config"
+)
+
+// writeSelfSignedCert writes a self-signed certificate for 127.0.0.1 and returns the cert and key paths
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "reolink-test"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "server.crt")
+	keyFile := filepath.Join(dir, "server.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+	return certFile, keyFile
+}
+
+func TestConfigureTLS_Disabled(t *testing.T) {
+	server := &http.Server{}
+
+	redirect, err := ConfigureTLS(server, config.ServerConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, redirect)
+	assert.Nil(t, server.TLSConfig)
+}
+
+func TestConfigureTLS_ServesHTTPSWithProvidedCerts(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}),
+	}
+	redirect, err := ConfigureTLS(server, config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: certFile, KeyFile: keyFile},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, redirect)
+	require.NotNil(t, server.TLSConfig)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go serve(server, ln)
+	defer server.Close()
+
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		Timeout:   5 * time.Second,
+	}
+	resp, err := client.Get("https://" + ln.Addr().String() + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, resp.TLS)
+	assert.Equal(t, "reolink-test", resp.TLS.PeerCertificates[0].Subject.CommonName)
+}
+
+func TestConfigureTLS_InvalidCertFiles(t *testing.T) {
+	_, err := ConfigureTLS(&http.Server{}, config.ServerConfig{
+		TLS: config.TLSConfig{Enabled: true, CertFile: "/nonexistent.crt", KeyFile: "/nonexistent.key"},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load TLS certificate")
+}
+
+func TestConfigureTLS_RedirectServer(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	redirect, err := ConfigureTLS(&http.Server{}, config.ServerConfig{
+		Host: "0.0.0.0",
+		Port: 8443,
+		TLS: config.TLSConfig{
+			Enabled:      true,
+			CertFile:     certFile,
+			KeyFile:      keyFile,
+			RedirectHTTP: true,
+			HTTPPort:     8080,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, redirect)
+	assert.Equal(t, "0.0.0.0:8080", redirect.Addr)
+
+	req := httptest.NewRequest(http.MethodGet, "http://cams.example.com:8080/api/v1/cameras?limit=5", nil)
+	w := httptest.NewRecorder()
+	redirect.Handler.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "https://cams.example.com:8443/api/v1/cameras?limit=5", w.Header().Get("Location"))
+}
+
+func TestRedirectToHTTPS_DefaultPort(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "http://cams.example.com/", nil)
+	w := httptest.NewRecorder()
+	redirectToHTTPS(443).ServeHTTP(w, req)
+
+	assert.Equal(t, "https://cams.example.com/", w.Header().Get("Location"))
+}
+
+func TestConfigureTLS_AutoCert(t *testing.T) {
+	server := &http.Server{}
+
+	redirect, err := ConfigureTLS(server, config.ServerConfig{
+		Port: 443,
+		TLS: config.TLSConfig{
+			Enabled:          true,
+			AutoCert:         true,
+			AutoCertHosts:    []string{"cams.example.com"},
+			AutoCertCacheDir: t.TempDir(),
+			RedirectHTTP:     true,
+		},
+	})
+	require.NoError(t, err)
+	require.NotNil(t, server.TLSConfig)
+	assert.NotNil(t, server.TLSConfig.GetCertificate)
+	require.NotNil(t, redirect)
+	assert.Equal(t, ":80", redirect.Addr)
+}
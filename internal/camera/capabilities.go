@@ -0,0 +1,41 @@
+package camera
+
+import (
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// capabilitiesFromAbility flattens a GetAbility response into capability flags.
+// Reolink reports each ability as {"ver": N, "permit": M}, where a non-zero
+// version means the feature is supported. Channel abilities are taken from
+// channel 0 and merged with the device-level ones.
+func capabilitiesFromAbility(ability *reolink.Ability) models.CameraCapabilities {
+	caps := make(models.CameraCapabilities)
+	if ability == nil {
+		return caps
+	}
+
+	addAbilities(caps, ability.AbilityInfo)
+
+	if channels, ok := ability.AbilityInfo["abilityChn"].([]interface{}); ok && len(channels) > 0 {
+		if chn, ok := channels[0].(map[string]interface{}); ok {
+			addAbilities(caps, chn)
+		}
+	}
+
+	return caps
+}
+
+// addAbilities adds every {"ver": N} entry of abilities to caps
+func addAbilities(caps models.CameraCapabilities, abilities map[string]interface{}) {
+	for name, raw := range abilities {
+		entry, ok := raw.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if ver, ok := entry["ver"].(float64); ok {
+			caps[name] = ver > 0
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// channelRefreshInterval is how long the discovered channels of a camera are
+// reused before asking the device again, so NVR channels coming online are
+// picked up without querying on every poll
+const channelRefreshInterval = 10 * time.Minute
+
+// ActiveChannels returns the channels to poll for events. NVRs report the
+// online channels of their channel status; cameras without channel status
+// support are polled on channel 0.
+func (c *CameraClient) ActiveChannels(ctx context.Context) []int {
+	c.channelsMu.Lock()
+	defer c.channelsMu.Unlock()
+
+	if c.channels != nil && time.Since(c.channelsAt) < channelRefreshInterval {
+		return c.channels
+	}
+
+	status, err := c.GetChannelStatus(ctx)
+	if err != nil {
+		// An open circuit says nothing about the channels; ask again later
+		if errors.Is(err, ErrCircuitOpen) {
+			if c.channels != nil {
+				return c.channels
+			}
+			return []int{0}
+		}
+		logger.Debug("Channel status unavailable, polling channel 0",
+			zap.String("camera_id", c.Camera.ID),
+			zap.Error(err))
+		c.channels, c.channelsAt = []int{0}, time.Now()
+		return c.channels
+	}
+
+	channels := []int{}
+	switch {
+	case len(status.Status) > 0:
+		for _, ch := range status.Status {
+			if ch.Online == 1 {
+				channels = append(channels, ch.Channel)
+			}
+		}
+	case status.Count > 0:
+		for ch := 0; ch < status.Count; ch++ {
+			channels = append(channels, ch)
+		}
+	default:
+		channels = append(channels, 0)
+	}
+
+	if !slices.Equal(channels, c.channels) {
+		logger.Info("Discovered camera channels",
+			zap.String("camera_id", c.Camera.ID),
+			zap.Ints("channels", channels))
+	}
+	c.channels, c.channelsAt = channels, time.Now()
+	return c.channels
+}
@@ -0,0 +1,132 @@
+package camera
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// Built-in connection profiles
+const (
+	ProfileLAN       = "lan"        // Cameras on the local network
+	ProfileRemoteP2P = "remote-p2p" // Cameras reached over the internet or a relay
+	ProfileCellular  = "cellular"   // Cameras on LTE routers with slow, lossy links
+)
+
+// ErrUnknownConnectionProfile is returned for cameras naming a connection
+// profile the server does not know
+var ErrUnknownConnectionProfile = errors.New("unknown connection profile")
+
+// ConnectionProfile bundles the connection settings suited to one kind of
+// link, so cameras on slow links need not be tuned field by field. Zero
+// fields use the manager's defaults.
+type ConnectionProfile struct {
+	Timeout       time.Duration // Timeout of each camera request
+	MaxRetries    int           // Failed health checks before the camera's circuit opens
+	KeepAlive     bool          // Keep the connection warm like always-ready cameras
+	RTSPTransport string        // tcp or udp for cameras without their own setting
+}
+
+// DefaultConnectionProfiles returns the built-in connection profiles
+func DefaultConnectionProfiles() map[string]ConnectionProfile {
+	return map[string]ConnectionProfile{
+		ProfileLAN: {
+			Timeout:       5 * time.Second,
+			MaxRetries:    3,
+			RTSPTransport: models.RTSPTransportUDP,
+		},
+		ProfileRemoteP2P: {
+			Timeout:       30 * time.Second,
+			MaxRetries:    5,
+			KeepAlive:     true,
+			RTSPTransport: models.RTSPTransportTCP,
+		},
+		ProfileCellular: {
+			Timeout:       45 * time.Second,
+			MaxRetries:    8,
+			KeepAlive:     true,
+			RTSPTransport: models.RTSPTransportTCP,
+		},
+	}
+}
+
+// SetConnectionProfiles adds connection profiles for cameras added later,
+// replacing built-in profiles of the same name
+func (m *Manager) SetConnectionProfiles(profiles map[string]ConnectionProfile) {
+	for name, profile := range profiles {
+		m.profiles[name] = profile
+	}
+}
+
+// ConnectionProfile returns the named connection profile. The empty name is
+// the manager's defaults.
+func (m *Manager) ConnectionProfile(name string) (ConnectionProfile, error) {
+	profile := ConnectionProfile{}
+	if name != "" {
+		var ok bool
+		profile, ok = m.profiles[name]
+		if !ok {
+			return ConnectionProfile{}, fmt.Errorf("%w: %s", ErrUnknownConnectionProfile, name)
+		}
+	}
+
+	if profile.Timeout <= 0 {
+		profile.Timeout = m.config.ConnectionTimeout
+	}
+	if profile.MaxRetries <= 0 {
+		profile.MaxRetries = m.config.MaxRetries
+	}
+	return profile, nil
+}
+
+// resolveProfile returns the connection profile of a camera. Cameras naming
+// an unknown profile, e.g. one removed from the configuration, use the
+// manager's defaults.
+func (m *Manager) resolveProfile(camera *models.Camera) ConnectionProfile {
+	profile, err := m.ConnectionProfile(camera.ConnectionProfile)
+	if err != nil {
+		logger.Warn("Camera uses an unknown connection profile, using defaults",
+			zap.String("camera_id", camera.ID),
+			zap.String("profile", camera.ConnectionProfile))
+		profile, _ = m.ConnectionProfile("")
+	}
+	return profile
+}
+
+// maxRetries returns how many health checks of the camera may fail before its
+// circuit opens
+func (m *Manager) maxRetries(client *CameraClient) int {
+	if client.ConnectionProfile.MaxRetries > 0 {
+		return client.ConnectionProfile.MaxRetries
+	}
+	return m.config.MaxRetries
+}
+
+// requestTimeout returns the timeout of requests the manager makes to the camera
+func (m *Manager) requestTimeout(client *CameraClient) time.Duration {
+	if client.ConnectionProfile.Timeout > 0 {
+		return client.ConnectionProfile.Timeout
+	}
+	return m.config.ConnectionTimeout
+}
+
+// keptAlive reports whether the camera's connection is kept warm, because
+// it is always ready or its connection profile asks for keep-alive
+func (c *CameraClient) keptAlive() bool {
+	return c.Camera.AlwaysReady || c.ConnectionProfile.KeepAlive
+}
+
+// RTSPTransport returns the RTSP transport of the camera's streams: its own
+// setting, else its connection profile's. Empty leaves the choice to the
+// server default.
+func (c *CameraClient) RTSPTransport() string {
+	if c.Camera.RTSPTransport != "" {
+		return c.Camera.RTSPTransport
+	}
+	return c.ConnectionProfile.RTSPTransport
+}
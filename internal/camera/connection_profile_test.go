@@ -0,0 +1,134 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestManager_ConnectionProfile(t *testing.T) {
+	m := NewManager(&Config{ConnectionTimeout: 10 * time.Second, MaxRetries: 3}, nil)
+	m.SetConnectionProfiles(map[string]ConnectionProfile{
+		"starlink":      {Timeout: 20 * time.Second, KeepAlive: true},
+		ProfileCellular: {Timeout: time.Minute, MaxRetries: 10, RTSPTransport: models.RTSPTransportTCP},
+	})
+
+	tests := []struct {
+		name    string
+		want    ConnectionProfile
+		wantErr error
+	}{
+		{"", ConnectionProfile{Timeout: 10 * time.Second, MaxRetries: 3}, nil},
+		{ProfileLAN, ConnectionProfile{Timeout: 5 * time.Second, MaxRetries: 3, RTSPTransport: models.RTSPTransportUDP}, nil},
+		{ProfileRemoteP2P, ConnectionProfile{Timeout: 30 * time.Second, MaxRetries: 5, KeepAlive: true, RTSPTransport: models.RTSPTransportTCP}, nil},
+		// Configured profiles replace built-in ones and fill unset values from the defaults
+		{ProfileCellular, ConnectionProfile{Timeout: time.Minute, MaxRetries: 10, RTSPTransport: models.RTSPTransportTCP}, nil},
+		{"starlink", ConnectionProfile{Timeout: 20 * time.Second, MaxRetries: 3, KeepAlive: true}, nil},
+		{"satellite", ConnectionProfile{}, ErrUnknownConnectionProfile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			profile, err := m.ConnectionProfile(tt.name)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, profile)
+		})
+	}
+}
+
+func TestManager_AddCamera_ConnectionProfile(t *testing.T) {
+	server := newFakeCameraServer(t)
+	m := NewManager(&Config{ConnectionTimeout: 10 * time.Second, MaxRetries: 3}, nil)
+
+	cam := fakeCamera(t, server, "cam-1")
+	cam.ConnectionProfile = ProfileCellular
+	require.NoError(t, m.AddCamera(context.Background(), cam))
+
+	client, err := m.GetCamera("cam-1")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultConnectionProfiles()[ProfileCellular], client.ConnectionProfile)
+	assert.Equal(t, 8, m.maxRetries(client))
+	assert.Equal(t, 45*time.Second, m.requestTimeout(client))
+	assert.True(t, client.keptAlive())
+	assert.Equal(t, models.RTSPTransportTCP, client.RTSPTransport())
+
+	// The camera's own RTSP transport wins over its profile's
+	client.Camera.RTSPTransport = models.RTSPTransportUDP
+	assert.Equal(t, models.RTSPTransportUDP, client.RTSPTransport())
+
+	// Cameras naming a profile that no longer exists use the defaults
+	stale := fakeCamera(t, server, "cam-2")
+	stale.ConnectionProfile = "removed"
+	require.NoError(t, m.AddCamera(context.Background(), stale))
+	client, err = m.GetCamera("cam-2")
+	require.NoError(t, err)
+	assert.Equal(t, ConnectionProfile{Timeout: 10 * time.Second, MaxRetries: 3}, client.ConnectionProfile)
+	assert.False(t, client.keptAlive())
+	assert.Empty(t, client.RTSPTransport())
+}
+
+func TestManager_AddCamera_ProfileTimeout(t *testing.T) {
+	fake := newFakeCameraServer(t)
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+		fake.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(slow.Close)
+
+	m := NewManager(&Config{ConnectionTimeout: 50 * time.Millisecond, MaxRetries: 3}, nil)
+	m.SetConnectionProfiles(map[string]ConnectionProfile{"slow-link": {Timeout: 5 * time.Second}})
+
+	// The manager's default timeout gives up on the slow camera
+	cam := fakeCamera(t, slow, "cam-default")
+	assert.Error(t, m.AddCamera(context.Background(), cam))
+
+	// A profile with a longer timeout waits for it
+	cam = fakeCamera(t, slow, "cam-slow-link")
+	cam.ConnectionProfile = "slow-link"
+	assert.NoError(t, m.AddCamera(context.Background(), cam))
+}
+
+func TestManager_CheckCameraHealth_ProfileMaxRetries(t *testing.T) {
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 1}, nil)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(failing.Close)
+
+	cam := fakeCamera(t, failing, "cam-1")
+	client, err := m.createClient(cam, ConnectionProfile{Timeout: time.Second, MaxRetries: 3})
+	require.NoError(t, err)
+	cameraClient := &CameraClient{
+		Camera:            cam,
+		Client:            client,
+		ConnectionProfile: ConnectionProfile{Timeout: time.Second, MaxRetries: 3},
+	}
+
+	// The profile's retries apply instead of the manager's single one
+	for i := 0; i < 2; i++ {
+		m.checkCameraHealth(context.Background(), cameraClient)
+		assert.False(t, cameraClient.CircuitOpen, "circuit opened after %d failures", i+1)
+	}
+	m.checkCameraHealth(context.Background(), cameraClient)
+	assert.True(t, cameraClient.CircuitOpen)
+}
+
+func TestManager_RunKeepAlive_ProfileKeepAlive(t *testing.T) {
+	m, pings := newKeepAliveTestManager(t)
+	m.cameras["cam-idle"].ConnectionProfile = DefaultConnectionProfiles()[ProfileRemoteP2P]
+
+	assert.ElementsMatch(t, []string{"cam-ready", "cam-idle"}, m.runKeepAlive(context.Background()))
+	assert.Equal(t, 1, pings("cam-idle"))
+}
@@ -0,0 +1,82 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+)
+
+// UpgradeTimeout is how long a started firmware upgrade blocks further
+// upgrades of the camera when its completion is never observed
+const UpgradeTimeout = 15 * time.Minute
+
+// ErrUpgradeInProgress is returned when a firmware upgrade of the camera is
+// already running
+var ErrUpgradeInProgress = errors.New("firmware upgrade already in progress")
+
+// beginUpgrade claims the camera for a firmware upgrade
+func (c *CameraClient) beginUpgrade() error {
+	c.upgradeMu.Lock()
+	defer c.upgradeMu.Unlock()
+
+	if !c.upgradeStarted.IsZero() && time.Since(c.upgradeStarted) < UpgradeTimeout {
+		return ErrUpgradeInProgress
+	}
+	c.upgradeStarted = time.Now()
+	return nil
+}
+
+// endUpgrade releases the camera for further firmware upgrades
+func (c *CameraClient) endUpgrade() {
+	c.upgradeMu.Lock()
+	defer c.upgradeMu.Unlock()
+
+	c.upgradeStarted = time.Time{}
+}
+
+// Upgrading reports whether a firmware upgrade of the camera is running
+func (c *CameraClient) Upgrading() bool {
+	c.upgradeMu.Lock()
+	defer c.upgradeMu.Unlock()
+
+	return !c.upgradeStarted.IsZero() && time.Since(c.upgradeStarted) < UpgradeTimeout
+}
+
+// StartOnlineUpgrade has the camera download and install its latest firmware.
+// It fails with ErrUpgradeInProgress while another upgrade is running; the
+// upgrade counts as running until UpgradeStatus reports it complete.
+func (c *CameraClient) StartOnlineUpgrade(ctx context.Context) error {
+	if err := c.beginUpgrade(); err != nil {
+		return err
+	}
+	if err := c.UpgradeOnline(ctx); err != nil {
+		c.endUpgrade()
+		return err
+	}
+	return nil
+}
+
+// StartFileUpgrade uploads firmware to the camera and installs it, keeping the
+// camera's configuration unless restoreConfig is set. Like StartOnlineUpgrade
+// it fails with ErrUpgradeInProgress while another upgrade is running.
+func (c *CameraClient) StartFileUpgrade(ctx context.Context, fileName string, firmware []byte, restoreConfig bool) error {
+	if err := c.beginUpgrade(); err != nil {
+		return err
+	}
+	err := c.UpgradePrepare(ctx, restoreConfig, fileName)
+	if err == nil {
+		err = c.Upgrade(ctx, firmware)
+	}
+	if err != nil {
+		c.endUpgrade()
+		return err
+	}
+	return nil
+}
+
+// upgradeComplete reports whether an upgrade status shows the upgrade finished
+func upgradeComplete(status *reolink.UpgradeStatusInfo) bool {
+	return status.Percent >= 100
+}
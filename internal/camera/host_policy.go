@@ -0,0 +1,171 @@
+package camera
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ErrHostNotAllowed is returned when a connection to a camera is refused by
+// the host policy
+var ErrHostNotAllowed = errors.New("camera host not allowed")
+
+// defaultDeniedNetworks are refused unless explicitly allowed: link-local
+// ranges, which hold cloud metadata services such as 169.254.169.254, and
+// addresses no camera can have
+var defaultDeniedNetworks = []string{
+	"0.0.0.0/8",
+	"169.254.0.0/16",
+	"224.0.0.0/4",
+	"::/128",
+	"fe80::/10",
+	"ff00::/8",
+}
+
+// HostPolicy decides which addresses the server may connect to on behalf of
+// a camera, so camera records cannot point requests at internal services.
+// Addresses are checked when connecting, after DNS resolution. A nil policy
+// allows every address.
+type HostPolicy struct {
+	allowed       []*net.IPNet
+	denied        []*net.IPNet
+	defaultDenied []*net.IPNet
+}
+
+// DefaultHostPolicy returns the policy refusing the default denied networks
+func DefaultHostPolicy() *HostPolicy {
+	policy, err := NewHostPolicy(nil, nil)
+	if err != nil {
+		panic(err)
+	}
+	return policy
+}
+
+// NewHostPolicy creates a host policy from networks in CIDR notation or
+// single IP addresses. Denied networks are always refused. When allowed
+// networks are given, only they may be reached; they also lift the default
+// denial of link-local and metadata addresses.
+func NewHostPolicy(allowed, denied []string) (*HostPolicy, error) {
+	policy := &HostPolicy{}
+	var err error
+	if policy.allowed, err = parseNetworks(allowed); err != nil {
+		return nil, err
+	}
+	if policy.denied, err = parseNetworks(denied); err != nil {
+		return nil, err
+	}
+	if policy.defaultDenied, err = parseNetworks(defaultDeniedNetworks); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// parseNetworks parses CIDR networks and single IP addresses
+func parseNetworks(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if !strings.Contains(value, "/") {
+			ip := net.ParseIP(value)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid network %q", value)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", value, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// CheckIP returns ErrHostNotAllowed when the policy refuses connections to ip
+func (p *HostPolicy) CheckIP(ip net.IP) error {
+	if p == nil {
+		return nil
+	}
+
+	if network := findNetwork(p.denied, ip); network != nil {
+		return fmt.Errorf("%w: %s is in denied network %s", ErrHostNotAllowed, ip, network)
+	}
+	if findNetwork(p.allowed, ip) != nil {
+		return nil
+	}
+	if len(p.allowed) > 0 {
+		return fmt.Errorf("%w: %s is not in an allowed network", ErrHostNotAllowed, ip)
+	}
+	if network := findNetwork(p.defaultDenied, ip); network != nil {
+		return fmt.Errorf("%w: %s is in denied network %s", ErrHostNotAllowed, ip, network)
+	}
+	return nil
+}
+
+// findNetwork returns the first of networks containing ip
+func findNetwork(networks []*net.IPNet, ip net.IP) *net.IPNet {
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return network
+		}
+	}
+	return nil
+}
+
+// Dialer returns a dialer refusing, before connecting, the addresses the
+// policy does not allow. Checking the resolved address rather than the host
+// name also catches names resolving to internal addresses.
+func (p *HostPolicy) Dialer(timeout time.Duration) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if p == nil {
+		return dialer
+	}
+
+	dialer.Control = func(network, address string, _ syscall.RawConn) error {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return err
+		}
+		ip := net.ParseIP(host)
+		if ip == nil {
+			return fmt.Errorf("%w: unresolved address %s", ErrHostNotAllowed, address)
+		}
+		return p.CheckIP(ip)
+	}
+	return dialer
+}
+
+// Transport returns an HTTP transport connecting through Dialer
+func (p *HostPolicy) Transport(skipVerify bool) *http.Transport {
+	return &http.Transport{
+		DialContext:     p.Dialer(30 * time.Second).DialContext,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: skipVerify},
+		IdleConnTimeout: 90 * time.Second,
+	}
+}
+
+// SetHostPolicy sets which addresses the server may connect to for cameras
+// added later. The default refuses link-local and metadata addresses.
+func (m *Manager) SetHostPolicy(policy *HostPolicy) {
+	m.hostPolicy = policy
+}
+
+// HTTPClient returns an HTTP client for requests to the camera made outside
+// the SDK, such as stream proxying, honouring the host policy and the
+// camera's TLS verification setting. It has no overall timeout, so it suits
+// long-running streams; callers bound requests with their context.
+func (c *CameraClient) HTTPClient() *http.Client {
+	skipVerify := c.Camera != nil && c.Camera.SkipVerify
+	return &http.Client{Transport: c.hostPolicy.Transport(skipVerify)}
+}
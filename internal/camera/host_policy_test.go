@@ -0,0 +1,112 @@
+package camera
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestHostPolicy_CheckIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		ip      string
+		wantErr bool
+	}{
+		{"private camera network", nil, nil, "192.168.1.10", false},
+		{"loopback", nil, nil, "127.0.0.1", false},
+		{"cloud metadata", nil, nil, "169.254.169.254", true},
+		{"ipv6 link-local", nil, nil, "fe80::1", true},
+		{"unspecified", nil, nil, "0.0.0.0", true},
+		{"metadata explicitly allowed", []string{"169.254.169.254"}, nil, "169.254.169.254", false},
+		{"outside allow-list", []string{"192.168.1.0/24"}, nil, "192.168.2.10", true},
+		{"inside allow-list", []string{"192.168.1.0/24"}, nil, "192.168.1.10", false},
+		{"denied network", nil, []string{"10.0.0.0/8"}, "10.1.2.3", true},
+		{"deny wins over allow", []string{"10.0.0.0/8"}, []string{"10.0.5.0/24"}, "10.0.5.1", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewHostPolicy(tt.allowed, tt.denied)
+			require.NoError(t, err)
+
+			err = policy.CheckIP(net.ParseIP(tt.ip))
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrHostNotAllowed)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestNewHostPolicy_InvalidNetwork(t *testing.T) {
+	_, err := NewHostPolicy([]string{"192.168.1.0/33"}, nil)
+	assert.Error(t, err)
+
+	_, err = NewHostPolicy(nil, []string{"camera.local"})
+	assert.Error(t, err)
+}
+
+func TestHostPolicy_NilAllowsAll(t *testing.T) {
+	var policy *HostPolicy
+	assert.NoError(t, policy.CheckIP(net.ParseIP("169.254.169.254")))
+}
+
+func TestManager_ProbeCamera_BlockedHost(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	result := m.ProbeCamera(context.Background(), &models.Camera{ID: "cam-1", Host: "169.254.169.254", Port: 80})
+
+	assert.False(t, result.Reachable)
+	assert.Equal(t, ProbeLayerTCP, result.FailedLayer)
+	assert.Contains(t, result.Steps[0].Error, ErrHostNotAllowed.Error())
+}
+
+func TestCameraClient_HTTPClient_BlockedHost(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	// Only the camera LAN is allowed, the test server on loopback is not
+	policy, err := NewHostPolicy([]string{"192.168.1.0/24"}, nil)
+	require.NoError(t, err)
+	client := &CameraClient{Camera: &models.Camera{ID: "cam-1"}, hostPolicy: policy}
+
+	_, err = client.HTTPClient().Get(server.URL + "/flv")
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+	assert.Zero(t, requests, "blocked targets are never contacted")
+
+	client.hostPolicy = DefaultHostPolicy()
+	resp, err := client.HTTPClient().Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, 1, requests)
+}
+
+func TestManager_AddCamera_BlockedHost(t *testing.T) {
+	server := newFakeCameraServer(t)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, _ := strconv.Atoi(u.Port())
+
+	m := NewManager(nil, nil)
+	policy, err := NewHostPolicy(nil, []string{"127.0.0.0/8"})
+	require.NoError(t, err)
+	m.SetHostPolicy(policy)
+
+	err = m.AddCamera(context.Background(), &models.Camera{ID: "cam-1", Host: "127.0.0.1", Port: port, Username: "admin", Password: "password"})
+	assert.ErrorIs(t, err, ErrHostNotAllowed)
+}
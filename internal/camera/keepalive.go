@@ -0,0 +1,85 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// StartKeepAlive sends a cheap request to every always-ready camera, and every
+// camera whose connection profile asks for keep-alive, on each interval, so
+// their connections and login sessions stay warm and the first request after
+// idle is fast. A non-positive interval disables keep-alive.
+func (m *Manager) StartKeepAlive(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	logger.Info("Camera keep-alive started",
+		zap.Duration("interval", interval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Camera keep-alive stopped")
+			return
+		case <-ticker.C:
+			m.runKeepAlive(ctx)
+		}
+	}
+}
+
+// runKeepAlive pings every kept-alive camera and returns the IDs of the
+// cameras it pinged
+func (m *Manager) runKeepAlive(ctx context.Context) []string {
+	m.mu.RLock()
+	cameras := make([]*CameraClient, 0, len(m.cameras))
+	for _, client := range m.cameras {
+		if client.keptAlive() {
+			cameras = append(cameras, client)
+		}
+	}
+	m.mu.RUnlock()
+
+	var wg sync.WaitGroup
+	pinged := make([]string, 0, len(cameras))
+	for _, client := range cameras {
+		pinged = append(pinged, client.Camera.ID)
+		wg.Add(1)
+		go func(client *CameraClient) {
+			defer wg.Done()
+			m.keepAlive(ctx, client)
+		}(client)
+	}
+	wg.Wait()
+
+	return pinged
+}
+
+// keepAlive asks a camera for its time, logging in again when the session has
+// expired. Cameras with an open circuit are left to the health check.
+func (m *Manager) keepAlive(ctx context.Context, client *CameraClient) {
+	pingCtx, cancel := context.WithTimeout(ctx, m.requestTimeout(client))
+	defer cancel()
+
+	_, err := client.GetTime(pingCtx)
+	if err != nil && !errors.Is(err, ErrCircuitOpen) {
+		if loginErr := m.login(pingCtx, client.Camera, client.Client); loginErr == nil {
+			_, err = client.GetTime(pingCtx)
+		}
+	}
+	if err != nil && !errors.Is(err, ErrCircuitOpen) {
+		logger.Debug("Camera keep-alive failed",
+			zap.String("camera_id", client.Camera.ID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,101 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newKeepAliveTestManager returns a manager with an always-ready and a regular
+// camera and a counter of the GetTime requests each camera received
+func newKeepAliveTestManager(t *testing.T) (*Manager, func(cameraID string) int) {
+	var mu sync.Mutex
+	pings := make(map[string]int)
+
+	newCamera := func(cameraID string, alwaysReady bool) *CameraClient {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cmd := r.URL.Query().Get("cmd")
+			if cmd == "GetTime" {
+				mu.Lock()
+				pings[cameraID]++
+				mu.Unlock()
+			}
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{}}]`))
+		}))
+		t.Cleanup(server.Close)
+
+		return &CameraClient{
+			Camera: &models.Camera{ID: cameraID, AlwaysReady: alwaysReady},
+			Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+		}
+	}
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.cameras["cam-ready"] = newCamera("cam-ready", true)
+	m.cameras["cam-idle"] = newCamera("cam-idle", false)
+
+	return m, func(cameraID string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return pings[cameraID]
+	}
+}
+
+func TestManager_RunKeepAlive_PingsAlwaysReadyCameras(t *testing.T) {
+	m, pings := newKeepAliveTestManager(t)
+
+	assert.Equal(t, []string{"cam-ready"}, m.runKeepAlive(context.Background()))
+	assert.Equal(t, 1, pings("cam-ready"))
+	assert.Equal(t, 0, pings("cam-idle"))
+}
+
+func TestManager_RunKeepAlive_SkipsOpenCircuit(t *testing.T) {
+	m, pings := newKeepAliveTestManager(t)
+	m.cameras["cam-ready"].CircuitOpen = true
+
+	m.runKeepAlive(context.Background())
+	assert.Equal(t, 0, pings("cam-ready"))
+}
+
+func TestManager_StartKeepAlive_PingsAtInterval(t *testing.T) {
+	m, pings := newKeepAliveTestManager(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const interval = 50 * time.Millisecond
+	done := make(chan struct{})
+	start := time.Now()
+	go func() {
+		m.StartKeepAlive(ctx, interval)
+		close(done)
+	}()
+
+	// Nothing is sent before the first interval has passed
+	time.Sleep(interval / 2)
+	assert.Equal(t, 0, pings("cam-ready"))
+
+	assert.Eventually(t, func() bool { return pings("cam-ready") >= 3 }, time.Second, interval/5)
+	cancel()
+	<-done
+
+	// Three pings take at least three intervals
+	assert.GreaterOrEqual(t, time.Since(start), 3*interval)
+	assert.Equal(t, 0, pings("cam-idle"))
+}
+
+func TestManager_StartKeepAlive_Disabled(t *testing.T) {
+	m, pings := newKeepAliveTestManager(t)
+
+	// A zero interval returns right away instead of blocking
+	m.StartKeepAlive(context.Background(), 0)
+	assert.Equal(t, 0, pings("cam-ready"))
+}
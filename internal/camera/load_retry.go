@@ -0,0 +1,161 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// DefaultLoadRetryInterval is how often cameras that failed to load are retried
+const DefaultLoadRetryInterval = time.Minute
+
+// failedLoad is a camera that could not be added to the manager. Health
+// checks retry it until it loads or is removed; until then it is reported
+// offline.
+type failedLoad struct {
+	camera      *models.Camera
+	err         string
+	attempts    int
+	lastAttempt time.Time
+}
+
+// SetLoadRetryInterval sets how often cameras that failed to load are
+// retried. A zero or negative interval disables retrying, leaving such
+// cameras out of the manager until they are added again.
+func (m *Manager) SetLoadRetryInterval(interval time.Duration) {
+	m.config.LoadRetryInterval = interval
+}
+
+// SetOnLoaded sets the function called for every camera that loads when
+// retried after failing to. It must be called before health checks start.
+func (m *Manager) SetOnLoaded(onLoaded func(*CameraClient)) {
+	m.onLoaded = onLoaded
+}
+
+// LoadCamera adds a camera stored in the database. Unless retrying is
+// disabled, a camera that cannot be added is marked offline and retried by the
+// health checks instead of missing until the next restart. The error of the
+// attempt is returned either way.
+func (m *Manager) LoadCamera(ctx context.Context, camera *models.Camera) error {
+	err := m.AddCamera(ctx, camera)
+	if err != nil {
+		m.recordFailedLoad(ctx, camera, err)
+	}
+	return err
+}
+
+// recordFailedLoad queues a camera that could not be added for retrying and
+// marks it offline. Cameras missing settings are not queued, as retrying
+// cannot fix them.
+func (m *Manager) recordFailedLoad(ctx context.Context, camera *models.Camera, err error) {
+	if m.config.LoadRetryInterval <= 0 || errors.Is(err, ErrInvalidCamera) {
+		return
+	}
+
+	m.mu.Lock()
+	if _, exists := m.cameras[camera.ID]; exists {
+		// Added concurrently, e.g. by a reconciliation pass
+		m.mu.Unlock()
+		return
+	}
+	load, queued := m.failedLoads[camera.ID]
+	if !queued {
+		load = &failedLoad{}
+		m.failedLoads[camera.ID] = load
+	}
+	wasOffline := camera.Status == "offline"
+	camera.Status = "offline"
+	load.camera = camera
+	load.err = err.Error()
+	load.attempts++
+	load.lastAttempt = m.clock.Now()
+	m.mu.Unlock()
+
+	if !queued {
+		logger.Warn("Camera failed to load, retrying",
+			zap.String("camera_id", camera.ID),
+			zap.Duration("interval", m.config.LoadRetryInterval),
+			zap.Error(err))
+	}
+
+	if m.repo != nil && !wasOffline {
+		if err := m.repo.UpdateStatus(ctx, camera.ID, "offline", camera.LastSeen); err != nil {
+			logger.Error("Failed to update camera status in database",
+				zap.String("camera_id", camera.ID),
+				zap.Error(err))
+		}
+	}
+}
+
+// retryFailedLoads tries again to add the cameras that failed to load and
+// were last tried at least LoadRetryInterval ago
+func (m *Manager) retryFailedLoads(ctx context.Context) {
+	now := m.clock.Now()
+
+	// Claim the due cameras so overlapping health checks skip them
+	m.mu.Lock()
+	var due []*models.Camera
+	for _, load := range m.failedLoads {
+		if now.Sub(load.lastAttempt) >= m.config.LoadRetryInterval {
+			load.lastAttempt = now
+			due = append(due, load.camera)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, queued := range due {
+		// Connect with a copy: the queued camera is read under the lock by
+		// status lookups while the connection is attempted without it
+		camera := new(models.Camera)
+		*camera = *queued
+		if err := m.AddCamera(ctx, camera); err != nil {
+			logger.Debug("Camera still fails to load",
+				zap.String("camera_id", camera.ID),
+				zap.Error(err))
+			m.recordFailedLoad(ctx, queued, err)
+			continue
+		}
+
+		logger.Info("Camera loaded after failing to",
+			zap.String("camera_id", camera.ID),
+			zap.String("name", camera.Name))
+
+		if m.repo != nil {
+			if err := m.repo.UpdateStatus(ctx, camera.ID, camera.Status, camera.LastSeen); err != nil {
+				logger.Error("Failed to update camera status in database",
+					zap.String("camera_id", camera.ID),
+					zap.Error(err))
+			}
+		}
+
+		if client, err := m.GetCamera(camera.ID); err == nil && m.onLoaded != nil {
+			m.onLoaded(client)
+		}
+	}
+}
+
+// failedLoadStatus reports a camera that failed to load as offline, or
+// returns nil if the camera is not waiting to be retried
+func (m *Manager) failedLoadStatus(cameraID string) *models.CameraStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	load, ok := m.failedLoads[cameraID]
+	if !ok {
+		return nil
+	}
+	return &models.CameraStatus{
+		CameraID:     cameraID,
+		Status:       "offline",
+		Model:        load.camera.Model,
+		FirmwareVer:  load.camera.FirmwareVer,
+		LastSeen:     load.camera.LastSeen,
+		FailureCount: load.attempts,
+		Error:        load.err,
+	}
+}
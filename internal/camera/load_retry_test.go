@@ -0,0 +1,202 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/clock"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newFlakyCameraServer starts a fake camera API that refuses every request
+// until up is set
+func newFlakyCameraServer(t *testing.T, up *atomic.Bool) *httptest.Server {
+	camera := newFakeCameraServer(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		camera.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestManager_LoadCamera_RetriesFailedLoads(t *testing.T) {
+	var up atomic.Bool
+	server := newFlakyCameraServer(t, &up)
+
+	repo := new(MockCameraRepository)
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewManager(nil, repo)
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	var loaded []string
+	m.SetOnLoaded(func(client *CameraClient) {
+		loaded = append(loaded, client.Camera.ID)
+	})
+
+	cam := fakeCamera(t, server, "cam-1")
+	cam.Status = "online"
+	repo.On("UpdateStatus", ctx, "cam-1", "offline", mock.Anything).Return(nil).Once()
+
+	// The camera fails to load at startup but is reported offline, not missing
+	require.Error(t, m.LoadCamera(ctx, cam))
+	_, err := m.GetCamera("cam-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	status, err := m.GetCameraStatus("cam-1")
+	require.NoError(t, err)
+	assert.Equal(t, "offline", status.Status)
+	assert.Equal(t, 1, status.FailureCount)
+	assert.NotEmpty(t, status.Error)
+
+	// Retries wait for the interval and count further failures without
+	// writing the status again
+	m.retryFailedLoads(ctx)
+	fake.Advance(DefaultLoadRetryInterval)
+	m.retryFailedLoads(ctx)
+	status, err = m.GetCameraStatus("cam-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, status.FailureCount)
+	assert.Empty(t, loaded)
+
+	// Once the camera answers, the next retry picks it up
+	up.Store(true)
+	fake.Advance(DefaultLoadRetryInterval)
+	repo.On("UpdateStatus", ctx, "cam-1", "online", fake.Now()).Return(nil).Once()
+	m.retryFailedLoads(ctx)
+
+	client, err := m.GetCamera("cam-1")
+	require.NoError(t, err)
+	assert.Equal(t, "RLC-810A", client.Camera.Model)
+	assert.Equal(t, []string{"cam-1"}, loaded)
+
+	status, err = m.GetCameraStatus("cam-1")
+	require.NoError(t, err)
+	assert.Equal(t, "online", status.Status)
+	assert.Empty(t, m.failedLoads)
+	repo.AssertExpectations(t)
+}
+
+func TestManager_LoadCamera_RetryDisabled(t *testing.T) {
+	m := NewManager(nil, nil)
+	m.SetLoadRetryInterval(-1)
+
+	// Without retrying, a camera that fails to load stays missing
+	var up atomic.Bool
+	server := newFlakyCameraServer(t, &up)
+	require.Error(t, m.LoadCamera(context.Background(), fakeCamera(t, server, "cam-bad")))
+	_, err := m.GetCameraStatus("cam-bad")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Empty(t, m.failedLoads)
+}
+
+func TestManager_RemoveCamera_ForgetsFailedLoad(t *testing.T) {
+	m := NewManager(nil, nil)
+	ctx := context.Background()
+
+	var up atomic.Bool
+	server := newFlakyCameraServer(t, &up)
+	require.Error(t, m.LoadCamera(ctx, fakeCamera(t, server, "cam-1")))
+	require.Error(t, m.LoadCamera(ctx, fakeCamera(t, server, "cam-2")))
+	require.Len(t, m.failedLoads, 2)
+
+	// Deleting a camera waiting to be retried stops the retries; it was never
+	// loaded, so there is nothing else to tear down
+	var removed []string
+	m.SetOnRemoved(func(cameraID string) { removed = append(removed, cameraID) })
+	assert.NoError(t, m.RemoveCamera("cam-1"))
+	assert.Empty(t, removed)
+	_, err := m.GetCameraStatus("cam-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// And so does reconciling with a database that no longer has it
+	m.Reconcile(ctx, nil)
+	assert.Empty(t, m.failedLoads)
+}
+
+func TestManager_LoadCamera_InvalidCameraNotRetried(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	// Retrying cannot fill in missing settings, so the camera is not queued
+	err := m.LoadCamera(context.Background(), &models.Camera{ID: "cam-1", Port: 80, Username: "admin"})
+	assert.ErrorIs(t, err, ErrInvalidCamera)
+	assert.Empty(t, m.failedLoads)
+	_, err = m.GetCameraStatus("cam-1")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestManager_RetryFailedLoads_DoesNotBlockLookups(t *testing.T) {
+	camera := newFakeCameraServer(t)
+	var up atomic.Bool
+	release := make(chan struct{})
+	arrived := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		<-release
+		camera.Config.Handler.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-release:
+		default:
+			close(release)
+		}
+	})
+
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewManager(nil, nil)
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	require.NoError(t, m.AddCamera(ctx, fakeCamera(t, camera, "cam-1")))
+	require.Error(t, m.LoadCamera(ctx, fakeCamera(t, server, "cam-2")))
+
+	// The retry hangs on the slow camera while other cameras stay reachable
+	up.Store(true)
+	fake.Advance(DefaultLoadRetryInterval)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.retryFailedLoads(ctx)
+	}()
+	<-arrived
+
+	lookup := make(chan error, 1)
+	go func() {
+		_, err := m.GetCamera("cam-1")
+		m.ListCameras()
+		_, _ = m.GetCameraStatus("cam-2")
+		lookup <- err
+	}()
+	select {
+	case err := <-lookup:
+		assert.NoError(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("lookups blocked while a camera was being loaded")
+	}
+
+	close(release)
+	<-done
+	_, err := m.GetCamera("cam-2")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,101 @@
+package camera
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/clock"
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// loginGuard serializes logins per camera. Concurrent callers share the result
+// of the login already in flight, and after a failed login further attempts are
+// rejected until the cooldown has passed, so a flapping camera is not flooded.
+type loginGuard struct {
+	cooldown time.Duration
+	calls    map[string]*loginCall
+	failures map[string]loginFailure
+	clock    clock.Clock
+	mu       sync.Mutex
+}
+
+// loginCall is a login in progress; done is closed once err is set
+type loginCall struct {
+	done chan struct{}
+	err  error
+}
+
+// loginFailure records the last failed login of a camera
+type loginFailure struct {
+	at  time.Time
+	err error
+}
+
+// newLoginGuard creates a login guard; a zero cooldown disables throttling
+func newLoginGuard(cooldown time.Duration) *loginGuard {
+	return &loginGuard{
+		cooldown: cooldown,
+		calls:    make(map[string]*loginCall),
+		failures: make(map[string]loginFailure),
+		clock:    clock.Real,
+	}
+}
+
+// Do runs login for the camera unless one is already in flight, in which case
+// it waits for that login and returns its result
+func (g *loginGuard) Do(ctx context.Context, cameraID string, login func(context.Context) error) error {
+	g.mu.Lock()
+
+	if call, ok := g.calls[cameraID]; ok {
+		g.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	if failure, ok := g.failures[cameraID]; ok && g.cooldown > 0 {
+		if wait := g.cooldown - g.clock.Now().Sub(failure.at); wait > 0 {
+			g.mu.Unlock()
+			return fmt.Errorf("login throttled for %s after recent failure: %w", wait.Round(time.Millisecond), failure.err)
+		}
+	}
+
+	call := &loginCall{done: make(chan struct{})}
+	g.calls[cameraID] = call
+	g.mu.Unlock()
+
+	call.err = login(ctx)
+
+	g.mu.Lock()
+	delete(g.calls, cameraID)
+	if call.err != nil {
+		g.failures[cameraID] = loginFailure{at: g.clock.Now(), err: call.err}
+	} else {
+		delete(g.failures, cameraID)
+	}
+	g.mu.Unlock()
+	close(call.done)
+
+	if call.err != nil {
+		logger.Debug("Camera login failed",
+			zap.String("camera_id", cameraID),
+			zap.Error(call.err),
+		)
+	}
+
+	return call.err
+}
+
+// Forget drops the login state of a camera
+func (g *loginGuard) Forget(cameraID string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.failures, cameraID)
+}
@@ -0,0 +1,138 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginGuard_ConcurrentLoginsCollapse(t *testing.T) {
+	g := newLoginGuard(0)
+
+	var calls int32
+	release := make(chan struct{})
+	login := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return nil
+	}
+
+	const callers = 20
+	var started, wg sync.WaitGroup
+	errs := make(chan error, callers)
+	started.Add(callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			errs <- g.Do(context.Background(), "cam-1", login)
+		}()
+	}
+
+	// Give every caller the chance to join the in-flight login
+	started.Wait()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+}
+
+func TestLoginGuard_WaitersShareFailure(t *testing.T) {
+	g := newLoginGuard(0)
+	loginErr := errors.New("bad credentials")
+
+	var calls int32
+	release := make(chan struct{})
+	login := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return loginErr
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, 5)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = g.Do(context.Background(), "cam-1", login)
+		}(i)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	for _, err := range errs {
+		assert.ErrorIs(t, err, loginErr)
+	}
+}
+
+func TestLoginGuard_CamerasAreIndependent(t *testing.T) {
+	g := newLoginGuard(0)
+
+	var calls int32
+	login := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return nil
+	}
+
+	require.NoError(t, g.Do(context.Background(), "cam-1", login))
+	require.NoError(t, g.Do(context.Background(), "cam-2", login))
+	require.NoError(t, g.Do(context.Background(), "cam-1", login))
+
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+}
+
+func TestLoginGuard_ThrottlesAfterFailure(t *testing.T) {
+	g := newLoginGuard(time.Hour)
+	loginErr := errors.New("connection refused")
+
+	var calls int32
+	failing := func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return loginErr
+	}
+
+	assert.ErrorIs(t, g.Do(context.Background(), "cam-1", failing), loginErr)
+
+	err := g.Do(context.Background(), "cam-1", failing)
+	assert.ErrorIs(t, err, loginErr)
+	assert.Contains(t, err.Error(), "throttled")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// Forgetting the camera lifts the throttle
+	g.Forget("cam-1")
+	assert.ErrorIs(t, g.Do(context.Background(), "cam-1", failing), loginErr)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestLoginGuard_WaiterHonoursContext(t *testing.T) {
+	g := newLoginGuard(0)
+
+	release := make(chan struct{})
+	defer close(release)
+	go g.Do(context.Background(), "cam-1", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := g.Do(ctx, "cam-1", func(ctx context.Context) error { return nil })
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
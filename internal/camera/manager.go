@@ -2,20 +2,25 @@ package camera
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"sync"
 	"time"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
 	"go.uber.org/zap"
 
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
 // CameraRepository interface for database operations
 type CameraRepository interface {
+	List(ctx context.Context) ([]*models.Camera, error)
 	UpdateStatus(ctx context.Context, id string, status string, lastSeen time.Time) error
+	UpdateDeviceInfo(ctx context.Context, id, model, firmwareVer, hardwareVer string) error
 }
 
 // Manager manages all camera connections and operations
@@ -24,6 +29,42 @@ type Manager struct {
 	mu      sync.RWMutex
 	config  *Config
 	repo    CameraRepository
+	logins  *loginGuard
+	tokens  TokenCache // Nil disables token caching
+
+	// hostPolicy refuses connections to addresses cameras must not point at
+	hostPolicy *HostPolicy
+
+	// clock times health checks, circuit backoff and login throttling
+	clock clock.Clock
+
+	// rebootedOn holds the day ("2006-01-02") of each camera's last scheduled reboot
+	rebootedOn map[string]string
+	rebootMu   sync.Mutex
+
+	// rebootWaits holds the latest reboot-and-wait of each camera, guarded by rebootMu
+	rebootWaits map[string]*RebootStatus
+
+	// rebootPoll overrides how often and how long after the reboot command
+	// waiting cameras are polled (used in tests)
+	rebootPoll *rebootPollTiming
+
+	// probeSteps overrides the default reachability checks (used in tests)
+	probeSteps []ProbeStep
+
+	// testClient overrides how connection tests build their client (used in tests)
+	testClient func(camera *models.Camera) (*reolink.Client, error)
+
+	// failedLoads holds the cameras waiting to be retried after failing to
+	// load, guarded by mu; onLoaded is called for each that loads later
+	failedLoads map[string]*failedLoad
+	onLoaded    func(*CameraClient)
+
+	// onRemoved is called with the ID of every loaded camera that is removed
+	onRemoved func(cameraID string)
+
+	// profiles holds the connection profiles cameras may name
+	profiles map[string]ConnectionProfile
 }
 
 // Config holds camera manager configuration
@@ -31,17 +72,105 @@ type Config struct {
 	HealthCheckInterval time.Duration
 	ConnectionTimeout   time.Duration
 	MaxRetries          int
-	RetryBackoff        time.Duration
+	RetryBackoff        time.Duration // Minimum wait before retrying a failed login or probing a camera with an open circuit
+	ReconcileInterval   time.Duration // Zero disables periodic DB reconciliation
+	LoadRetryInterval   time.Duration // How often cameras that failed to load are retried; zero disables retrying
+	HealthCheckWorkers  int           // Cameras health checked concurrently; zero uses DefaultHealthCheckWorkers
+
+	// Health checks also probe the disks and PTZ of cameras this often,
+	// keeping the reasons found in between; zero uses DefaultFeatureCheckInterval
+	FeatureCheckInterval time.Duration
+	HddFullPercent       int // Disks at least this full degrade the camera; zero ignores disk usage
+}
+
+// DefaultHealthCheckWorkers is how many cameras are health checked at once
+// when the configuration does not say
+const DefaultHealthCheckWorkers = 10
+
+// DefaultFeatureCheckInterval is how often health checks probe the disks and
+// PTZ of cameras when the configuration does not say
+const DefaultFeatureCheckInterval = 5 * time.Minute
+
+// lastSeenPersistInterval is how far the last-seen time of a camera moves on
+// before health checks write it to the database without a status change
+const lastSeenPersistInterval = time.Minute
+
+// ReconcileResult describes the changes applied by a reconciliation pass
+type ReconcileResult struct {
+	Added   []string
+	Removed []string
+	Failed  []string
 }
 
 // CameraClient wraps a Reolink API client with additional metadata
 type CameraClient struct {
-	Camera       *models.Camera
-	Client       *reolink.Client
-	LastHealthy  time.Time
-	FailureCount int
-	CircuitOpen  bool
-	mu           sync.RWMutex
+	Camera          *models.Camera
+	Client          *reolink.Client
+	LastHealthy     time.Time
+	OnlineSince     time.Time // Since when health checks have passed without interruption, zero while offline
+	FailureCount    int
+	CircuitOpen     bool
+	CircuitOpenedAt time.Time // When the circuit last (re)opened; a probe is allowed after RetryBackoff
+	LastHealthError string    // Error of the latest health check, empty once a check succeeds
+	StatusReasons   []string  // Why the camera is degraded, empty unless Camera.Status is "degraded"
+	featuresChecked time.Time // When StatusReasons were last probed, zero to probe on the next health check
+	seenPersisted   time.Time // Camera.LastSeen as last written to the database
+	mu              sync.RWMutex
+	seenMu          sync.Mutex // Guards Camera.LastSeen while operations hold only the read lock
+
+	// ConnectionProfile holds the connection settings the client was built
+	// with, resolved from the camera's connection profile
+	ConnectionProfile ConnectionProfile
+
+	// login logs in again when the session expired; nil uses Client.Login
+	login     func(ctx context.Context) error
+	relogins  int        // Logins after expired sessions, guarded by reloginMu
+	reloginMu sync.Mutex // Serializes re-logins so concurrent failures share one
+
+	// hostPolicy guards the requests made outside the SDK, see HTTPClient
+	hostPolicy *HostPolicy
+
+	// clock stamps last-seen times and times operations; nil uses the real clock
+	clock clock.Clock
+
+	// commands tracks the latency of the operations run through call
+	commands commandMetrics
+
+	// channels caches the channels polled for events, see ActiveChannels
+	channels   []int
+	channelsAt time.Time
+	channelsMu sync.Mutex
+
+	// upgradeStarted is when the running firmware upgrade started, zero
+	// without one, guarded by upgradeMu
+	upgradeStarted time.Time
+	upgradeMu      sync.Mutex
+}
+
+// markSeen records that the camera answered at t. Callers hold at least the read lock.
+func (c *CameraClient) markSeen(t time.Time) {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+
+	if t.After(c.Camera.LastSeen) {
+		c.Camera.LastSeen = t
+	}
+}
+
+// now returns the current time of the client's clock
+func (c *CameraClient) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// lastSeen returns when the camera last answered. Callers hold at least the read lock.
+func (c *CameraClient) lastSeen() time.Time {
+	c.seenMu.Lock()
+	defer c.seenMu.Unlock()
+
+	return c.Camera.LastSeen
 }
 
 // NewManager creates a new camera manager
@@ -52,52 +181,63 @@ func NewManager(config *Config, repo CameraRepository) *Manager {
 			ConnectionTimeout:   10 * time.Second,
 			MaxRetries:          3,
 			RetryBackoff:        5 * time.Second,
+			ReconcileInterval:   5 * time.Minute,
+			LoadRetryInterval:   DefaultLoadRetryInterval,
+			HealthCheckWorkers:  DefaultHealthCheckWorkers,
+
+			FeatureCheckInterval: DefaultFeatureCheckInterval,
 		}
 	}
 
 	return &Manager{
-		cameras: make(map[string]*CameraClient),
-		config:  config,
-		repo:    repo,
+		cameras:     make(map[string]*CameraClient),
+		config:      config,
+		repo:        repo,
+		logins:      newLoginGuard(config.RetryBackoff),
+		rebootedOn:  make(map[string]string),
+		rebootWaits: make(map[string]*RebootStatus),
+		failedLoads: make(map[string]*failedLoad),
+		profiles:    DefaultConnectionProfiles(),
+		hostPolicy:  DefaultHostPolicy(),
+		clock:       clock.Real,
 	}
 }
 
-// AddCamera adds a new camera to the manager
-func (m *Manager) AddCamera(ctx context.Context, camera *models.Camera) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
+// SetClock sets the time source of the manager, e.g. a fake clock in tests.
+// It must be called before cameras are added or health checks start.
+func (m *Manager) SetClock(c clock.Clock) {
+	m.clock = c
+	m.logins.clock = c
+}
 
-	// Validate input
-	if camera == nil {
-		return fmt.Errorf("camera cannot be nil")
-	}
-	if camera.ID == "" {
-		return fmt.Errorf("camera ID cannot be empty")
-	}
-	if camera.Host == "" {
-		return fmt.Errorf("camera host cannot be empty")
-	}
-	if camera.Port <= 0 {
-		return fmt.Errorf("camera port must be positive")
-	}
-	if camera.Username == "" {
-		return fmt.Errorf("camera username cannot be empty")
+// AddCamera adds a new camera to the manager. The camera is logged in and
+// queried without holding the manager lock, so lookups of other cameras are
+// not held up by a slow or unreachable camera.
+func (m *Manager) AddCamera(ctx context.Context, camera *models.Camera) error {
+	if err := validateCamera(camera); err != nil {
+		return err
 	}
 
 	// Check if camera already exists
-	if _, exists := m.cameras[camera.ID]; exists {
-		return fmt.Errorf("camera %s already exists", camera.ID)
+	m.mu.RLock()
+	_, exists := m.cameras[camera.ID]
+	m.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("%w: %s", ErrCameraExists, camera.ID)
 	}
 
 	// Create Reolink client
-	client, err := m.createClient(camera)
+	profile := m.resolveProfile(camera)
+	client, err := m.createClient(camera, profile)
 	if err != nil {
 		return fmt.Errorf("failed to create client: %w", err)
 	}
 
-	// Test connection
-	if err := client.Login(ctx); err != nil {
-		return fmt.Errorf("failed to connect to camera: %w", err)
+	// Test connection, reusing a cached session if the camera still accepts it
+	if !m.restoreToken(ctx, camera, client) {
+		if err := m.login(ctx, camera, client); err != nil {
+			return fmt.Errorf("failed to connect to camera: %w", err)
+		}
 	}
 
 	// Get device info
@@ -110,15 +250,43 @@ func (m *Manager) AddCamera(ctx context.Context, camera *models.Camera) error {
 		camera.HardwareVer = info.HardVer // SDK uses HardVer field
 	}
 
+	// Cache capabilities so later checks don't need to query the device
+	ability, err := client.System.GetAbility(ctx)
+	if err != nil {
+		logger.Warn("Failed to get camera abilities", zap.String("camera_id", camera.ID), zap.Error(err))
+	} else {
+		camera.Capabilities = capabilitiesFromAbility(ability)
+	}
+
+	m.mu.Lock()
+	// Another add may have won the race while this one was connecting
+	if _, exists := m.cameras[camera.ID]; exists {
+		m.mu.Unlock()
+		logoutCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Logout(logoutCtx)
+		return fmt.Errorf("%w: %s", ErrCameraExists, camera.ID)
+	}
+
 	// Add to manager
 	m.cameras[camera.ID] = &CameraClient{
 		Camera:      camera,
 		Client:      client,
-		LastHealthy: time.Now(),
+		LastHealthy: m.clock.Now(),
+		OnlineSince: m.clock.Now(),
+		login: func(ctx context.Context) error {
+			return m.login(ctx, camera, client)
+		},
+		hostPolicy: m.hostPolicy,
+		clock:      m.clock,
+
+		ConnectionProfile: profile,
 	}
+	delete(m.failedLoads, camera.ID)
 
 	camera.Status = "online"
-	camera.LastSeen = time.Now()
+	camera.LastSeen = m.clock.Now()
+	m.mu.Unlock()
 
 	logger.Info("Camera added",
 		zap.String("camera_id", camera.ID),
@@ -129,14 +297,46 @@ func (m *Manager) AddCamera(ctx context.Context, camera *models.Camera) error {
 	return nil
 }
 
+// validateCamera checks that a camera has the settings needed to connect to
+// it. Its errors wrap ErrInvalidCamera.
+func validateCamera(camera *models.Camera) error {
+	if camera == nil {
+		return fmt.Errorf("%w: camera cannot be nil", ErrInvalidCamera)
+	}
+	if camera.ID == "" {
+		return fmt.Errorf("%w: camera ID cannot be empty", ErrInvalidCamera)
+	}
+	if camera.Host == "" {
+		return fmt.Errorf("%w: camera host cannot be empty", ErrInvalidCamera)
+	}
+	if camera.Port <= 0 {
+		return fmt.Errorf("%w: camera port must be positive", ErrInvalidCamera)
+	}
+	if camera.Username == "" {
+		return fmt.Errorf("%w: camera username cannot be empty", ErrInvalidCamera)
+	}
+	return nil
+}
+
+// SetOnRemoved sets the function called with the ID of every loaded camera
+// that is removed, whether deleted, updated or dropped by reconciliation. It
+// must be called before cameras are removed.
+func (m *Manager) SetOnRemoved(onRemoved func(cameraID string)) {
+	m.onRemoved = onRemoved
+}
+
 // RemoveCamera removes a camera from the manager
 func (m *Manager) RemoveCamera(cameraID string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	client, exists := m.cameras[cameraID]
 	if !exists {
-		return fmt.Errorf("camera %s not found", cameraID)
+		defer m.mu.Unlock()
+		if _, queued := m.failedLoads[cameraID]; queued {
+			delete(m.failedLoads, cameraID)
+			logger.Info("Camera removed", zap.String("camera_id", cameraID))
+			return nil
+		}
+		return fmt.Errorf("%w: %s", ErrNotFound, cameraID)
 	}
 
 	// Logout from camera
@@ -145,6 +345,13 @@ func (m *Manager) RemoveCamera(cameraID string) error {
 	_ = client.Client.Logout(ctx)
 
 	delete(m.cameras, cameraID)
+	m.logins.Forget(cameraID)
+	m.forgetToken(cameraID)
+	m.mu.Unlock()
+
+	if m.onRemoved != nil {
+		m.onRemoved(cameraID)
+	}
 
 	logger.Info("Camera removed", zap.String("camera_id", cameraID))
 	return nil
@@ -157,7 +364,7 @@ func (m *Manager) GetCamera(cameraID string) (*CameraClient, error) {
 
 	client, exists := m.cameras[cameraID]
 	if !exists {
-		return nil, fmt.Errorf("camera %s not found", cameraID)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, cameraID)
 	}
 
 	return client, nil
@@ -180,6 +387,9 @@ func (m *Manager) ListCameras() []*models.Camera {
 func (m *Manager) GetCameraStatus(cameraID string) (*models.CameraStatus, error) {
 	client, err := m.GetCamera(cameraID)
 	if err != nil {
+		if status := m.failedLoadStatus(cameraID); status != nil {
+			return status, nil
+		}
 		return nil, err
 	}
 
@@ -187,47 +397,118 @@ func (m *Manager) GetCameraStatus(cameraID string) (*models.CameraStatus, error)
 	defer client.mu.RUnlock()
 
 	status := &models.CameraStatus{
-		CameraID:    client.Camera.ID,
-		Status:      client.Camera.Status,
-		Model:       client.Camera.Model,
-		FirmwareVer: client.Camera.FirmwareVer,
-		LastSeen:    client.Camera.LastSeen,
+		CameraID:     client.Camera.ID,
+		Status:       client.Camera.Status,
+		Model:        client.Camera.Model,
+		FirmwareVer:  client.Camera.FirmwareVer,
+		LastSeen:     client.lastSeen(),
+		LastHealthy:  client.LastHealthy,
+		FailureCount: client.FailureCount,
+		CircuitOpen:  client.CircuitOpen,
+		Relogins:     client.Relogins(),
+		Reasons:      client.StatusReasons,
+		Error:        client.LastHealthError,
+		Commands:     client.CommandMetrics(),
+	}
+	if !client.OnlineSince.IsZero() {
+		status.Uptime = int64(m.clock.Now().Sub(client.OnlineSince).Seconds())
 	}
 
 	return status, nil
 }
 
-// HealthCheck performs health checks on all cameras
+// SetHealthCheckWorkers sets how many cameras are health checked at once. Zero
+// or negative uses DefaultHealthCheckWorkers.
+func (m *Manager) SetHealthCheckWorkers(workers int) {
+	m.config.HealthCheckWorkers = workers
+}
+
+// HealthCheck performs health checks on all cameras, a bounded number at a
+// time, and retries the cameras that failed to load. It returns once every
+// camera has been checked.
 func (m *Manager) HealthCheck(ctx context.Context) {
 	m.mu.RLock()
 	cameras := make([]*CameraClient, 0, len(m.cameras))
 	for _, client := range m.cameras {
 		cameras = append(cameras, client)
 	}
+	retryLoads := len(m.failedLoads) > 0
 	m.mu.RUnlock()
 
+	if retryLoads {
+		go m.retryFailedLoads(ctx)
+	}
+
+	workers := m.config.HealthCheckWorkers
+	if workers <= 0 {
+		workers = DefaultHealthCheckWorkers
+	}
+	workers = min(workers, len(cameras))
+
+	queue := make(chan *CameraClient)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for client := range queue {
+				m.checkCameraHealth(ctx, client)
+			}
+		}()
+	}
+
 	for _, client := range cameras {
-		go m.checkCameraHealth(ctx, client)
+		select {
+		case queue <- client:
+		case <-ctx.Done():
+		}
 	}
+	close(queue)
+	wg.Wait()
 }
 
-// checkCameraHealth checks the health of a single camera
+// checkCameraHealth checks the health of a single camera. The camera's
+// requests are bounded by its request timeout, so a hung camera cannot hold
+// the client's lock or a health check worker indefinitely.
 func (m *Manager) checkCameraHealth(ctx context.Context, client *CameraClient) {
 	client.mu.Lock()
 	defer client.mu.Unlock()
 
-	// Skip if circuit is open
+	checkCtx := ctx
+	if timeout := m.requestTimeout(client); timeout > 0 {
+		var cancel context.CancelFunc
+		checkCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// While the circuit is open, skip health checks until the backoff has
+	// elapsed, then let this one through as the half-open probe
 	if client.CircuitOpen {
-		logger.Debug("Circuit open, skipping health check",
+		if m.clock.Now().Sub(client.CircuitOpenedAt) < m.config.RetryBackoff {
+			logger.Debug("Circuit open, skipping health check",
+				zap.String("camera_id", client.Camera.ID),
+			)
+			return
+		}
+		logger.Info("Circuit half-open, probing camera",
 			zap.String("camera_id", client.Camera.ID),
 		)
-		return
 	}
 
 	// Perform health check
-	_, err := client.Client.System.GetDeviceInfo(ctx)
+	info, err := client.Client.System.GetDeviceInfo(checkCtx)
+	if err != nil {
+		// The session may have expired; log in again and retry once
+		if loginErr := m.login(checkCtx, client.Camera, client.Client); loginErr == nil {
+			info, err = client.Client.System.GetDeviceInfo(checkCtx)
+		}
+	}
 	if err != nil {
 		client.FailureCount++
+		client.OnlineSince = time.Time{}
+		client.LastHealthError = err.Error()
+		client.StatusReasons = nil
+		client.featuresChecked = time.Time{}
 		oldStatus := client.Camera.Status
 		client.Camera.Status = "offline"
 
@@ -237,38 +518,172 @@ func (m *Manager) checkCameraHealth(ctx context.Context, client *CameraClient) {
 			zap.Error(err),
 		)
 
-		// Update database if status changed
-		if m.repo != nil && oldStatus != "offline" {
-			if err := m.repo.UpdateStatus(ctx, client.Camera.ID, "offline", time.Now()); err != nil {
-				logger.Error("Failed to update camera status in database",
-					zap.String("camera_id", client.Camera.ID),
-					zap.Error(err))
-			}
+		// Update database if status changed, or operations succeeding
+		// meanwhile moved last-seen on
+		if oldStatus != "offline" || client.Camera.LastSeen.Sub(client.seenPersisted) >= lastSeenPersistInterval {
+			m.persistStatus(ctx, client)
 		}
 
-		// Open circuit if too many failures
-		if client.FailureCount >= m.config.MaxRetries {
+		// Open circuit if too many failures; a failed half-open probe
+		// re-opens it and restarts the backoff
+		if client.FailureCount >= m.maxRetries(client) {
+			if !client.CircuitOpen {
+				logger.Error("Circuit opened for camera",
+					zap.String("camera_id", client.Camera.ID),
+				)
+			}
 			client.CircuitOpen = true
-			logger.Error("Circuit opened for camera",
-				zap.String("camera_id", client.Camera.ID),
-			)
+			client.CircuitOpenedAt = m.clock.Now()
 		}
 	} else {
 		// Reset on success
+		if client.CircuitOpen {
+			logger.Info("Circuit closed for camera",
+				zap.String("camera_id", client.Camera.ID),
+			)
+		}
 		client.FailureCount = 0
 		client.CircuitOpen = false
-		client.LastHealthy = time.Now()
+		client.CircuitOpenedAt = time.Time{}
+		client.LastHealthError = ""
+		client.LastHealthy = m.clock.Now()
+		if client.OnlineSince.IsZero() {
+			client.OnlineSince = client.LastHealthy
+		}
 		oldStatus := client.Camera.Status
+		if now := m.clock.Now(); now.Sub(client.featuresChecked) >= m.featureCheckInterval() {
+			client.StatusReasons = m.featureProblems(checkCtx, client)
+			client.featuresChecked = now
+		}
 		client.Camera.Status = "online"
-		client.Camera.LastSeen = time.Now()
+		if len(client.StatusReasons) > 0 {
+			client.Camera.Status = "degraded"
+		}
+		client.Camera.LastSeen = m.clock.Now()
 
-		// Update database if status changed
-		if m.repo != nil && oldStatus != "online" {
-			if err := m.repo.UpdateStatus(ctx, client.Camera.ID, "online", time.Now()); err != nil {
-				logger.Error("Failed to update camera status in database",
-					zap.String("camera_id", client.Camera.ID),
-					zap.Error(err))
-			}
+		if client.Camera.Status == "degraded" && oldStatus != "degraded" {
+			logger.Warn("Camera degraded",
+				zap.String("camera_id", client.Camera.ID),
+				zap.Strings("reasons", client.StatusReasons),
+			)
+		}
+
+		// Update database if status changed or last-seen moved on
+		if oldStatus != client.Camera.Status || client.Camera.LastSeen.Sub(client.seenPersisted) >= lastSeenPersistInterval {
+			m.persistStatus(ctx, client)
+		}
+
+		m.backfillDeviceInfo(ctx, client, info)
+	}
+}
+
+// persistStatus writes the status and last-seen time of a camera to the
+// database. Callers hold the client's write lock.
+func (m *Manager) persistStatus(ctx context.Context, client *CameraClient) {
+	if m.repo == nil {
+		return
+	}
+	if err := m.repo.UpdateStatus(ctx, client.Camera.ID, client.Camera.Status, client.Camera.LastSeen); err != nil {
+		logger.Error("Failed to update camera status in database",
+			zap.String("camera_id", client.Camera.ID),
+			zap.Error(err))
+		return
+	}
+	client.seenPersisted = client.Camera.LastSeen
+}
+
+// SetFeatureCheckInterval sets how often health checks probe the disks and
+// PTZ of cameras. Zero or negative uses DefaultFeatureCheckInterval.
+func (m *Manager) SetFeatureCheckInterval(interval time.Duration) {
+	m.config.FeatureCheckInterval = interval
+}
+
+// SetHddFullPercent sets the share of a disk in use from which the camera is
+// degraded. Zero or negative ignores disk usage, as cameras recording
+// continuously keep their disks full by overwriting the oldest recordings.
+func (m *Manager) SetHddFullPercent(percent int) {
+	m.config.HddFullPercent = percent
+}
+
+// featureCheckInterval returns how often health checks probe camera features
+func (m *Manager) featureCheckInterval() time.Duration {
+	if m.config.FeatureCheckInterval > 0 {
+		return m.config.FeatureCheckInterval
+	}
+	return DefaultFeatureCheckInterval
+}
+
+// featureProblems returns what is wrong with the disks and PTZ of a camera
+// that answered its health check. Callers hold the client's write lock.
+func (m *Manager) featureProblems(ctx context.Context, client *CameraClient) []string {
+	reasons := storageProblems(ctx, client, m.config.HddFullPercent)
+	return append(reasons, ptzProblems(ctx, client)...)
+}
+
+// ptzProblems returns why the PTZ of a camera reporting PTZ control in its
+// abilities cannot be read. Other cameras report none.
+func ptzProblems(ctx context.Context, client *CameraClient) []string {
+	if !client.Camera.Capabilities["ptzCtrl"] {
+		return nil
+	}
+	if _, err := client.Client.PTZ.GetPtzPreset(ctx, 0); err != nil {
+		return []string{"ptz: " + err.Error()}
+	}
+	return nil
+}
+
+// storageProblems returns what is wrong with the disks of a camera that
+// answered its health check, counting disks at least fullPercent used as
+// full unless fullPercent is zero. Cameras without disks, or whose disks
+// cannot be read, report none. Callers hold the client's write lock.
+func storageProblems(ctx context.Context, client *CameraClient, fullPercent int) []string {
+	disks, err := client.Client.System.GetHddInfo(ctx)
+	if err != nil {
+		logger.Debug("Failed to read camera disks during health check",
+			zap.String("camera_id", client.Camera.ID),
+			zap.Error(err))
+		return nil
+	}
+
+	var reasons []string
+	for i, disk := range disks {
+		switch {
+		case disk.Status != "" && disk.Status != "ok":
+			reasons = append(reasons, fmt.Sprintf("hdd %d: status %s", i, disk.Status))
+		case disk.Capacity > 0 && disk.Mount == 0:
+			reasons = append(reasons, fmt.Sprintf("hdd %d: not mounted", i))
+		case fullPercent > 0 && disk.Capacity > 0 && disk.Size*100 >= disk.Capacity*fullPercent:
+			reasons = append(reasons, fmt.Sprintf("hdd %d: full (%d of %d MB used)", i, disk.Size, disk.Capacity))
+		}
+	}
+	return reasons
+}
+
+// backfillDeviceInfo fills in the model and versions of a camera whose device
+// info could not be read when it was added, and persists them. Callers hold
+// the client's write lock.
+func (m *Manager) backfillDeviceInfo(ctx context.Context, client *CameraClient, info *reolink.DeviceInfo) {
+	if info == nil || (client.Camera.Model != "" && client.Camera.FirmwareVer != "") {
+		return
+	}
+	if info.Model == "" && info.FirmVer == "" {
+		return
+	}
+
+	client.Camera.Model = info.Model
+	client.Camera.FirmwareVer = info.FirmVer
+	client.Camera.HardwareVer = info.HardVer
+
+	logger.Info("Backfilled camera device info",
+		zap.String("camera_id", client.Camera.ID),
+		zap.String("model", info.Model),
+		zap.String("firmware_version", info.FirmVer))
+
+	if m.repo != nil {
+		if err := m.repo.UpdateDeviceInfo(ctx, client.Camera.ID, info.Model, info.FirmVer, info.HardVer); err != nil {
+			logger.Error("Failed to update camera device info in database",
+				zap.String("camera_id", client.Camera.ID),
+				zap.Error(err))
 		}
 	}
 }
@@ -293,11 +708,126 @@ func (m *Manager) StartHealthMonitoring(ctx context.Context) {
 	}
 }
 
-// createClient creates a new Reolink API client
-func (m *Manager) createClient(camera *models.Camera) (*reolink.Client, error) {
+// Reconcile aligns the managed cameras with the desired set, adding cameras
+// that are missing and removing cameras that are no longer present
+func (m *Manager) Reconcile(ctx context.Context, desired []*models.Camera) *ReconcileResult {
+	result := &ReconcileResult{}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, cam := range desired {
+		wanted[cam.ID] = true
+	}
+
+	m.mu.RLock()
+	current := make(map[string]bool, len(m.cameras))
+	for id := range m.cameras {
+		current[id] = true
+	}
+	m.mu.RUnlock()
+
+	for _, cam := range desired {
+		if current[cam.ID] {
+			continue
+		}
+		err := m.LoadCamera(ctx, cam)
+		if errors.Is(err, ErrCameraExists) {
+			// Added meanwhile, e.g. by the API request that created the row
+			continue
+		}
+		if err != nil {
+			logger.Warn("Failed to add camera during reconciliation",
+				zap.String("camera_id", cam.ID),
+				zap.Error(err))
+			result.Failed = append(result.Failed, cam.ID)
+			continue
+		}
+		result.Added = append(result.Added, cam.ID)
+	}
+
+	for id := range current {
+		if wanted[id] {
+			continue
+		}
+		if err := m.RemoveCamera(id); err != nil {
+			// Removed concurrently, nothing left to do
+			continue
+		}
+		result.Removed = append(result.Removed, id)
+	}
+
+	// Cameras deleted while waiting to be retried are no longer loaded
+	m.mu.Lock()
+	for id := range m.failedLoads {
+		if !wanted[id] {
+			delete(m.failedLoads, id)
+		}
+	}
+	m.mu.Unlock()
+
+	if len(result.Added) > 0 || len(result.Removed) > 0 || len(result.Failed) > 0 {
+		logger.Info("Camera reconciliation applied",
+			zap.Strings("added", result.Added),
+			zap.Strings("removed", result.Removed),
+			zap.Strings("failed", result.Failed))
+	}
+
+	return result
+}
+
+// StartReconciliation periodically reconciles the managed cameras with the
+// database. onAdded is called for every camera added by a reconciliation pass.
+func (m *Manager) StartReconciliation(ctx context.Context, onAdded func(*CameraClient)) {
+	if m.repo == nil || m.config.ReconcileInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(m.config.ReconcileInterval)
+	defer ticker.Stop()
+
+	logger.Info("Camera reconciliation started",
+		zap.Duration("interval", m.config.ReconcileInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Camera reconciliation stopped")
+			return
+		case <-ticker.C:
+			m.reconcileFromRepo(ctx, onAdded)
+		}
+	}
+}
+
+// reconcileFromRepo runs a single reconciliation pass against the database
+func (m *Manager) reconcileFromRepo(ctx context.Context, onAdded func(*CameraClient)) *ReconcileResult {
+	cameras, err := m.repo.List(ctx)
+	if err != nil {
+		logger.Error("Failed to list cameras for reconciliation", zap.Error(err))
+		return nil
+	}
+
+	result := m.Reconcile(ctx, cameras)
+
+	if onAdded != nil {
+		for _, id := range result.Added {
+			if client, err := m.GetCamera(id); err == nil {
+				onAdded(client)
+			}
+		}
+	}
+
+	return result
+}
+
+// createClient creates a new Reolink API client using the timeout of the
+// camera's connection profile
+func (m *Manager) createClient(camera *models.Camera, profile ConnectionProfile) (*reolink.Client, error) {
 	opts := []reolink.Option{
+		// Same defaults as the SDK's own client, connecting through the host policy
+		reolink.WithHTTPClient(&http.Client{Timeout: 30 * time.Second, Transport: m.hostPolicy.Transport(true)}),
 		reolink.WithCredentials(camera.Username, camera.Password),
-		reolink.WithTimeout(m.config.ConnectionTimeout),
+		reolink.WithTimeout(profile.Timeout),
 	}
 
 	if camera.UseHTTPS {
@@ -324,12 +854,15 @@ func (m *Manager) Shutdown(ctx context.Context) error {
 
 	logger.Info("Shutting down camera manager")
 
-	for id, client := range m.cameras {
-		if err := client.Client.Logout(ctx); err != nil {
-			logger.Warn("Failed to logout from camera",
-				zap.String("camera_id", id),
-				zap.Error(err),
-			)
+	// With a token cache, sessions stay open so the next start can reuse them
+	if m.tokens == nil {
+		for id, client := range m.cameras {
+			if err := client.Client.Logout(ctx); err != nil {
+				logger.Warn("Failed to logout from camera",
+					zap.String("camera_id", id),
+					zap.Error(err),
+				)
+			}
 		}
 	}
 
@@ -2,14 +2,46 @@ package camera
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+// MockCameraRepository is a mock implementation of CameraRepository
+type MockCameraRepository struct {
+	mock.Mock
+}
+
+func (m *MockCameraRepository) List(ctx context.Context) ([]*models.Camera, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Camera), args.Error(1)
+}
+
+func (m *MockCameraRepository) UpdateStatus(ctx context.Context, id string, status string, lastSeen time.Time) error {
+	args := m.Called(ctx, id, status, lastSeen)
+	return args.Error(0)
+}
+
+func (m *MockCameraRepository) UpdateDeviceInfo(ctx context.Context, id, model, firmwareVer, hardwareVer string) error {
+	args := m.Called(ctx, id, model, firmwareVer, hardwareVer)
+	return args.Error(0)
+}
+
 func TestNewManager(t *testing.T) {
 	tests := []struct {
 		name   string
@@ -146,7 +178,7 @@ func TestManager_GetCamera(t *testing.T) {
 
 	// Test getting non-existent camera
 	client, err := m.GetCamera("nonexistent")
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrNotFound)
 	assert.Nil(t, client)
 }
 
@@ -249,6 +281,656 @@ func TestCameraClient_CircuitBreaker(t *testing.T) {
 	})
 }
 
+func TestManager_CheckCameraHealth_PersistsStatusTransitions(t *testing.T) {
+	// Fake camera answering GetDevInfo
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+	}))
+
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "offline"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	ctx := context.Background()
+
+	// offline -> online
+	repo.On("UpdateStatus", ctx, "cam-123", "online", mock.AnythingOfType("time.Time")).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, "online", client.Camera.Status)
+
+	// online -> online does not touch the database
+	m.checkCameraHealth(ctx, client)
+
+	// online -> offline
+	cameraServer.Close()
+	repo.On("UpdateStatus", ctx, "cam-123", "offline", mock.AnythingOfType("time.Time")).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, "offline", client.Camera.Status)
+
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "UpdateStatus", 2)
+}
+
+func TestManager_CheckCameraHealth_PersistsLastSeen(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":1,"error":{"rspCode":-1,"detail":"not ready"}}]`))
+			return
+		}
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+	}))
+	defer cameraServer.Close()
+
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "offline"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	repo.On("UpdateStatus", ctx, "cam-123", "online", fake.Now()).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	// Last-seen is written once it moved on by lastSeenPersistInterval
+	fake.Advance(lastSeenPersistInterval / 2)
+	m.checkCameraHealth(ctx, client)
+	fake.Advance(lastSeenPersistInterval / 2)
+	repo.On("UpdateStatus", ctx, "cam-123", "online", fake.Now()).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	// Going offline keeps the time the camera was last seen
+	seen := fake.Now()
+	fake.Advance(10 * time.Minute)
+	healthy.Store(false)
+	repo.On("UpdateStatus", ctx, "cam-123", "offline", seen).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	// Operations succeeding while health checks fail are persisted too
+	client.markSeen(fake.Now())
+	repo.On("UpdateStatus", ctx, "cam-123", "offline", fake.Now()).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "UpdateStatus", 4)
+}
+
+func TestManager_CheckCameraHealth_HalfOpenRecovery(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+	}))
+	defer cameraServer.Close()
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3, RetryBackoff: time.Minute}, nil)
+	ctx := context.Background()
+
+	openedAt := time.Now()
+	client := &CameraClient{
+		Camera:          &models.Camera{ID: "cam-123", Status: "offline"},
+		Client:          reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+		FailureCount:    3,
+		CircuitOpen:     true,
+		CircuitOpenedAt: openedAt,
+	}
+
+	// Within the backoff the camera is not probed
+	m.checkCameraHealth(ctx, client)
+	assert.True(t, client.CircuitOpen)
+	assert.Equal(t, "offline", client.Camera.Status)
+	assert.Equal(t, openedAt, client.CircuitOpenedAt)
+
+	// After the backoff a successful probe closes the circuit
+	client.CircuitOpenedAt = time.Now().Add(-2 * time.Minute)
+	m.checkCameraHealth(ctx, client)
+	assert.False(t, client.CircuitOpen)
+	assert.True(t, client.CircuitOpenedAt.IsZero())
+	assert.Equal(t, 0, client.FailureCount)
+	assert.Equal(t, "online", client.Camera.Status)
+}
+
+func TestManager_CheckCameraHealth_HalfOpenProbeFails(t *testing.T) {
+	// Closed server: every request fails
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cameraServer.Close()
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3, RetryBackoff: time.Minute}, nil)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera:          &models.Camera{ID: "cam-123", Status: "offline"},
+		Client:          reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+		FailureCount:    3,
+		CircuitOpen:     true,
+		CircuitOpenedAt: time.Now().Add(-2 * time.Minute),
+	}
+
+	before := time.Now()
+	m.checkCameraHealth(ctx, client)
+
+	assert.True(t, client.CircuitOpen)
+	assert.Equal(t, 4, client.FailureCount)
+	assert.False(t, client.CircuitOpenedAt.Before(before), "failed probe should restart the backoff")
+
+	// The restarted backoff skips the next check
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, 4, client.FailureCount)
+}
+
+func TestManager_CheckCameraHealth_OpensCircuit(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cameraServer.Close()
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 2, RetryBackoff: time.Minute}, nil)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	m.checkCameraHealth(ctx, client)
+	assert.False(t, client.CircuitOpen)
+	assert.True(t, client.CircuitOpenedAt.IsZero())
+
+	before := time.Now()
+	m.checkCameraHealth(ctx, client)
+	assert.True(t, client.CircuitOpen)
+	assert.False(t, client.CircuitOpenedAt.Before(before))
+}
+
+func TestManager_HealthCheck_BoundedWorkers(t *testing.T) {
+	var inFlight, maxInFlight, checks atomic.Int32
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		if cmd == "GetDevInfo" {
+			current := inFlight.Add(1)
+			defer inFlight.Add(-1)
+			for {
+				peak := maxInFlight.Load()
+				if current <= peak || maxInFlight.CompareAndSwap(peak, current) {
+					break
+				}
+			}
+			checks.Add(1)
+			time.Sleep(20 * time.Millisecond)
+		}
+		w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{}}]`))
+	}))
+	defer cameraServer.Close()
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3, HealthCheckWorkers: 3}, nil)
+	for i := 0; i < 30; i++ {
+		id := "cam-" + strconv.Itoa(i)
+		m.cameras[id] = &CameraClient{
+			Camera: &models.Camera{ID: id, Status: "online"},
+			Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+		}
+	}
+
+	// HealthCheck returns once every camera has been checked, never more
+	// than the configured number at a time
+	m.HealthCheck(context.Background())
+
+	assert.Equal(t, int32(30), checks.Load())
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+	assert.Greater(t, maxInFlight.Load(), int32(1), "cameras are checked concurrently")
+	for _, client := range m.cameras {
+		assert.False(t, client.LastHealthy.IsZero(), client.Camera.ID)
+	}
+}
+
+func TestManager_HealthCheck_HungCameraTimesOut(t *testing.T) {
+	release := make(chan struct{})
+	hung := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hung.Close()
+	defer close(release)
+	healthy := newFakeCameraServer(t)
+
+	m := NewManager(&Config{ConnectionTimeout: 100 * time.Millisecond, MaxRetries: 3, HealthCheckWorkers: 1}, nil)
+	m.cameras["cam-hung"] = &CameraClient{
+		Camera: &models.Camera{ID: "cam-hung", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(hung.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	m.cameras["cam-ok"] = &CameraClient{
+		Camera: &models.Camera{ID: "cam-ok", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(healthy.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	// The single worker gives up on the hung camera and goes on to the next
+	start := time.Now()
+	m.HealthCheck(context.Background())
+	assert.Less(t, time.Since(start), 2*time.Second)
+
+	assert.Equal(t, "offline", m.cameras["cam-hung"].Camera.Status)
+	assert.Equal(t, 1, m.cameras["cam-hung"].FailureCount)
+	assert.Equal(t, "online", m.cameras["cam-ok"].Camera.Status)
+	assert.Zero(t, m.cameras["cam-ok"].FailureCount)
+}
+
+func TestManager_CheckCameraHealth_CircuitResetWithClock(t *testing.T) {
+	var healthy atomic.Bool
+	var requests atomic.Int32
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if !healthy.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+	}))
+	defer cameraServer.Close()
+
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 2, RetryBackoff: time.Minute}, nil)
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	// Two failures open the circuit at the current time
+	m.checkCameraHealth(ctx, client)
+	m.checkCameraHealth(ctx, client)
+	require.True(t, client.CircuitOpen)
+	assert.Equal(t, fake.Now(), client.CircuitOpenedAt)
+
+	// Until the backoff has passed the camera is not probed
+	healthy.Store(true)
+	fake.Advance(time.Minute - time.Second)
+	probed := requests.Load()
+	m.checkCameraHealth(ctx, client)
+	assert.True(t, client.CircuitOpen)
+	assert.Equal(t, probed, requests.Load())
+
+	// Once it has, a successful probe closes the circuit
+	fake.Advance(time.Second)
+	m.checkCameraHealth(ctx, client)
+	assert.False(t, client.CircuitOpen)
+	assert.Equal(t, 0, client.FailureCount)
+	assert.Equal(t, fake.Now(), client.LastHealthy)
+	assert.Equal(t, fake.Now(), client.Camera.LastSeen)
+}
+
+func TestManager_GetCameraStatus_Uptime(t *testing.T) {
+	var healthy atomic.Bool
+	healthy.Store(true)
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+	}))
+	defer cameraServer.Close()
+
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "offline"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	m.cameras["cam-123"] = client
+
+	status, err := m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Zero(t, status.Uptime, "never healthy")
+
+	m.checkCameraHealth(ctx, client)
+	fake.Advance(90 * time.Second)
+	m.checkCameraHealth(ctx, client)
+	status, err = m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(90), status.Uptime)
+
+	// A failed check resets the uptime
+	healthy.Store(false)
+	m.checkCameraHealth(ctx, client)
+	status, err = m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Zero(t, status.Uptime)
+
+	healthy.Store(true)
+	m.checkCameraHealth(ctx, client)
+	fake.Advance(10 * time.Second)
+	status, err = m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Equal(t, int64(10), status.Uptime)
+}
+
+func TestManager_CheckCameraHealth_BackfillsDeviceInfo(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"model":"RLC-810A","firmVer":"v3.1.0","hardVer":"IPC_523"}}}]`))
+	}))
+	defer cameraServer.Close()
+
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+	ctx := context.Background()
+
+	// Device info could not be read when the camera was added
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	repo.On("UpdateDeviceInfo", ctx, "cam-123", "RLC-810A", "v3.1.0", "IPC_523").Return(nil).Once()
+	repo.On("UpdateStatus", ctx, "cam-123", "online", mock.AnythingOfType("time.Time")).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	assert.Equal(t, "RLC-810A", client.Camera.Model)
+	assert.Equal(t, "v3.1.0", client.Camera.FirmwareVer)
+	assert.Equal(t, "IPC_523", client.Camera.HardwareVer)
+
+	// Known device info is not written again
+	m.checkCameraHealth(ctx, client)
+
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "UpdateDeviceInfo", 1)
+}
+
+// newHddCameraServer starts a fake camera answering device info and the given disks
+func newHddCameraServer(t *testing.T, hddInfo string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "GetHddInfo":
+			w.Write([]byte(`[{"cmd":"GetHddInfo","code":0,"value":{"HddInfo":` + hddInfo + `}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestManager_CheckCameraHealth_DegradedWithFullHdd(t *testing.T) {
+	cameraServer := newHddCameraServer(t, `[{"capacity":61440,"format":1,"mount":1,"size":61000,"status":"ok"}]`)
+
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+	m.SetHddFullPercent(95)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	m.cameras["cam-123"] = client
+
+	repo.On("UpdateStatus", ctx, "cam-123", "degraded", mock.AnythingOfType("time.Time")).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	status, err := m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Equal(t, "degraded", status.Status)
+	assert.Equal(t, []string{"hdd 0: full (61000 of 61440 MB used)"}, status.Reasons)
+	assert.Zero(t, status.FailureCount, "a degraded camera passed its health check")
+
+	// Still degraded: the database is not touched again
+	m.checkCameraHealth(ctx, client)
+
+	repo.AssertExpectations(t)
+	repo.AssertNumberOfCalls(t, "UpdateStatus", 1)
+}
+
+func TestManager_CheckCameraHealth_RecoversFromDegraded(t *testing.T) {
+	cameraServer := newHddCameraServer(t, `[{"capacity":61440,"format":1,"mount":1,"size":1200,"status":"ok"}]`)
+
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera:        &models.Camera{ID: "cam-123", Status: "degraded"},
+		Client:        reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+		StatusReasons: []string{"hdd 0: full (61000 of 61440 MB used)"},
+	}
+
+	repo.On("UpdateStatus", ctx, "cam-123", "online", mock.AnythingOfType("time.Time")).Return(nil).Once()
+	m.checkCameraHealth(ctx, client)
+
+	assert.Equal(t, "online", client.Camera.Status)
+	assert.Empty(t, client.StatusReasons)
+	repo.AssertExpectations(t)
+}
+
+func TestStorageProblems(t *testing.T) {
+	cameraServer := newHddCameraServer(t, `[`+
+		`{"capacity":61440,"format":1,"mount":1,"size":100,"status":"ok"},`+
+		`{"capacity":61440,"format":1,"mount":1,"size":100,"status":"error"},`+
+		`{"capacity":61440,"format":0,"mount":0,"size":0,"status":"ok"},`+
+		`{"capacity":1000,"format":1,"mount":1,"size":950,"status":"ok"}]`)
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123"},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	assert.Equal(t, []string{
+		"hdd 1: status error",
+		"hdd 2: not mounted",
+		"hdd 3: full (950 of 1000 MB used)",
+	}, storageProblems(context.Background(), client, 95))
+
+	// Without a threshold full disks are fine, as recording cameras keep them full
+	assert.Equal(t, []string{
+		"hdd 1: status error",
+		"hdd 2: not mounted",
+	}, storageProblems(context.Background(), client, 0))
+}
+
+func TestManager_CheckCameraHealth_DegradedWithFailingPTZ(t *testing.T) {
+	var hddReads atomic.Int32
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "GetHddInfo":
+			hddReads.Add(1)
+			w.Write([]byte(`[{"cmd":"GetHddInfo","code":0,"value":{"HddInfo":[]}}]`))
+		case "GetPtzPreset":
+			w.Write([]byte(`[{"cmd":"GetPtzPreset","code":1,"error":{"rspCode":-17,"detail":"rcv failed"}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"name":"Test"}}}]`))
+		}
+	}))
+	defer cameraServer.Close()
+
+	fake := clock.NewFake(time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC))
+	m := NewManager(nil, nil)
+	m.SetClock(fake)
+	ctx := context.Background()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-123", Status: "online", Capabilities: models.CameraCapabilities{"ptzCtrl": true}},
+		Client: reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, "degraded", client.Camera.Status)
+	require.Len(t, client.StatusReasons, 1)
+	assert.Contains(t, client.StatusReasons[0], "ptz: ")
+
+	// Features are probed again only after the feature check interval
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, "degraded", client.Camera.Status)
+	assert.Equal(t, int32(1), hddReads.Load())
+
+	fake.Advance(DefaultFeatureCheckInterval)
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, int32(2), hddReads.Load())
+
+	// Cameras without PTZ are not asked for it
+	client.Camera.Capabilities = models.CameraCapabilities{"ptzCtrl": false}
+	fake.Advance(DefaultFeatureCheckInterval)
+	m.checkCameraHealth(ctx, client)
+	assert.Equal(t, "online", client.Camera.Status)
+	assert.Empty(t, client.StatusReasons)
+}
+
+func TestManager_GetCameraStatus_ReportsHealth(t *testing.T) {
+	cameraServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	cameraServer.Close()
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 2, RetryBackoff: time.Minute}, nil)
+	ctx := context.Background()
+
+	lastHealthy := time.Now().Add(-time.Hour)
+	client := &CameraClient{
+		Camera:      &models.Camera{ID: "cam-123", Status: "online"},
+		Client:      reolink.NewClient(strings.TrimPrefix(cameraServer.URL, "http://"), reolink.WithToken("test-token")),
+		LastHealthy: lastHealthy,
+	}
+	m.cameras["cam-123"] = client
+
+	m.checkCameraHealth(ctx, client)
+	m.checkCameraHealth(ctx, client)
+
+	status, err := m.GetCameraStatus("cam-123")
+	require.NoError(t, err)
+	assert.Equal(t, "offline", status.Status)
+	assert.True(t, status.CircuitOpen)
+	assert.Equal(t, 2, status.FailureCount)
+	assert.Equal(t, lastHealthy, status.LastHealthy)
+	assert.NotEmpty(t, status.Error, "the failed health check is reported")
+}
+
+// newFakeCameraServer starts a fake camera API answering login, logout and device info
+func newFakeCameraServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "Login":
+			w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"leaseTime":3600,"name":"test-token"}}}]`))
+		case "GetDevInfo":
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"model":"RLC-810A"}}}]`))
+		case "GetAbility":
+			w.Write([]byte(`[{"cmd":"GetAbility","code":0,"value":{"Ability":{"Ability":{` +
+				`"push":{"permit":6,"ver":1},"supportFtp":{"permit":0,"ver":0},` +
+				`"abilityChn":[{"ptzCtrl":{"permit":6,"ver":1},"supportAiPeople":{"permit":4,"ver":1}}]}}}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// fakeCamera returns a camera model pointing at the fake camera server
+func fakeCamera(t *testing.T, server *httptest.Server, id string) *models.Camera {
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(u.Port())
+	return &models.Camera{ID: id, Name: id, Host: u.Hostname(), Port: port, Username: "admin", Password: "password"}
+}
+
+func TestManager_AddCamera_StoresCapabilities(t *testing.T) {
+	server := newFakeCameraServer(t)
+	m := NewManager(nil, nil)
+
+	cam := fakeCamera(t, server, "cam-1")
+	assert.NoError(t, m.AddCamera(context.Background(), cam))
+
+	assert.Equal(t, models.CameraCapabilities{
+		"push":            true,
+		"supportFtp":      false,
+		"ptzCtrl":         true,
+		"supportAiPeople": true,
+	}, cam.Capabilities)
+
+	client, err := m.GetCamera("cam-1")
+	assert.NoError(t, err)
+	assert.True(t, client.Camera.Capabilities["ptzCtrl"])
+}
+
+func TestManager_Reconcile_AddsMissingCameras(t *testing.T) {
+	server := newFakeCameraServer(t)
+	m := NewManager(nil, nil)
+
+	result := m.Reconcile(context.Background(), []*models.Camera{fakeCamera(t, server, "cam-1")})
+
+	assert.Equal(t, []string{"cam-1"}, result.Added)
+	assert.Empty(t, result.Removed)
+	assert.Empty(t, result.Failed)
+
+	client, err := m.GetCamera("cam-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "RLC-810A", client.Camera.Model)
+}
+
+func TestManager_Reconcile_RemovesStaleCameras(t *testing.T) {
+	server := newFakeCameraServer(t)
+	m := NewManager(nil, nil)
+	ctx := context.Background()
+
+	assert.NoError(t, m.AddCamera(ctx, fakeCamera(t, server, "cam-1")))
+	assert.NoError(t, m.AddCamera(ctx, fakeCamera(t, server, "cam-2")))
+
+	var removed []string
+	m.SetOnRemoved(func(cameraID string) { removed = append(removed, cameraID) })
+
+	// cam-2 was deleted from the database behind our back
+	result := m.Reconcile(ctx, []*models.Camera{fakeCamera(t, server, "cam-1")})
+
+	assert.Empty(t, result.Added)
+	assert.Equal(t, []string{"cam-2"}, result.Removed)
+	assert.Equal(t, []string{"cam-2"}, removed, "removed cameras are reported, e.g. to stop their event poller")
+
+	_, err := m.GetCamera("cam-1")
+	assert.NoError(t, err)
+	_, err = m.GetCamera("cam-2")
+	assert.Error(t, err)
+}
+
+func TestManager_Reconcile_ReportsFailedAdds(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	// Invalid camera rows stay out of the manager and are reported
+	result := m.Reconcile(context.Background(), []*models.Camera{{ID: "cam-bad"}})
+
+	assert.Empty(t, result.Added)
+	assert.Equal(t, []string{"cam-bad"}, result.Failed)
+	assert.Empty(t, m.ListCameras())
+}
+
+func TestManager_ReconcileFromRepo_NotifiesAddedCameras(t *testing.T) {
+	server := newFakeCameraServer(t)
+	repo := new(MockCameraRepository)
+	m := NewManager(nil, repo)
+	ctx := context.Background()
+
+	repo.On("List", ctx).Return([]*models.Camera{fakeCamera(t, server, "cam-1")}, nil).Once()
+
+	var added []string
+	result := m.reconcileFromRepo(ctx, func(client *CameraClient) {
+		added = append(added, client.Camera.ID)
+	})
+
+	assert.Equal(t, []string{"cam-1"}, result.Added)
+	assert.Equal(t, []string{"cam-1"}, added)
+
+	// Database errors leave the manager untouched
+	repo.On("List", ctx).Return(nil, assert.AnError).Once()
+	assert.Nil(t, m.reconcileFromRepo(ctx, nil))
+	assert.Len(t, m.ListCameras(), 1)
+
+	repo.AssertExpectations(t)
+}
+
 func TestManager_Shutdown(t *testing.T) {
 	m := NewManager(nil, nil)
 
@@ -0,0 +1,98 @@
+package camera
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// commandStats holds aggregated latency data for a camera operation
+type commandStats struct {
+	count    int64
+	failures int64
+	total    time.Duration
+	max      time.Duration
+}
+
+// commandMetrics tracks the latency of the operations run on a camera. The
+// zero value is ready to use; the metrics go away with the camera's client.
+type commandMetrics struct {
+	mu    sync.Mutex
+	stats map[string]*commandStats
+}
+
+// observe records the outcome and duration of an operation
+func (m *commandMetrics) observe(operation string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.stats == nil {
+		m.stats = make(map[string]*commandStats)
+	}
+	stats, ok := m.stats[operation]
+	if !ok {
+		stats = &commandStats{}
+		m.stats[operation] = stats
+	}
+	stats.count++
+	stats.total += duration
+	if duration > stats.max {
+		stats.max = duration
+	}
+	if err != nil {
+		stats.failures++
+	}
+}
+
+// snapshot returns the recorded metrics by operation, nil without any
+func (m *commandMetrics) snapshot() map[string]models.CommandStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if len(m.stats) == 0 {
+		return nil
+	}
+	snapshot := make(map[string]models.CommandStats, len(m.stats))
+	for operation, stats := range m.stats {
+		snapshot[operation] = models.CommandStats{
+			Count:    stats.count,
+			Failures: stats.failures,
+			AvgMs:    milliseconds(stats.total / time.Duration(stats.count)),
+			MaxMs:    milliseconds(stats.max),
+		}
+	}
+	return snapshot
+}
+
+// milliseconds converts d to fractional milliseconds
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// observeCommand records the outcome and duration of an SDK operation in the
+// camera's command metrics and logs it. Failures are logged at debug level
+// only, since callers report the error themselves.
+func (c *CameraClient) observeCommand(operation string, duration time.Duration, err error) {
+	c.commands.observe(operation, duration, err)
+
+	fields := []zap.Field{
+		zap.String("camera_id", c.Camera.ID),
+		zap.String("operation", operation),
+		zap.Duration("duration", duration),
+	}
+	if err != nil {
+		logger.Debug("Camera command failed", append(fields, zap.Error(err))...)
+		return
+	}
+	logger.Debug("Camera command completed", fields...)
+}
+
+// CommandMetrics returns the count, failures and latency of the operations
+// run on the camera by operation, e.g. "reboot" or "get_snapshot"
+func (c *CameraClient) CommandMetrics() map[string]models.CommandStats {
+	return c.commands.snapshot()
+}
@@ -0,0 +1,83 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func observeLogs(t *testing.T) *observer.ObservedLogs {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = prev })
+	return logs
+}
+
+func TestCameraClient_CallRecordsCommandMetrics(t *testing.T) {
+	var fail atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		if fail.Load() {
+			w.Write([]byte(`[{"cmd":"GetTime","code":1,"error":{"rspCode":-4,"detail":"param error"}}]`))
+			return
+		}
+		w.Write([]byte(`[{"cmd":"GetTime","code":0,"value":{"Time":{"year":2026}}}]`))
+	}))
+	defer server.Close()
+
+	logs := observeLogs(t)
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	assert.Nil(t, client.CommandMetrics())
+
+	_, err := client.GetTime(context.Background())
+	require.NoError(t, err)
+	fail.Store(true)
+	_, err = client.GetTime(context.Background())
+	require.Error(t, err)
+
+	stats := client.CommandMetrics()["get_time"]
+	assert.Equal(t, int64(2), stats.Count)
+	assert.Equal(t, int64(1), stats.Failures)
+	assert.GreaterOrEqual(t, stats.AvgMs, 20.0)
+	assert.GreaterOrEqual(t, stats.MaxMs, stats.AvgMs)
+
+	entries := logs.FilterMessage("Camera command completed").All()
+	require.Len(t, entries, 1)
+	fields := entries[0].ContextMap()
+	assert.Equal(t, "cam-1", fields["camera_id"])
+	assert.Equal(t, "get_time", fields["operation"])
+	assert.GreaterOrEqual(t, fields["duration"].(time.Duration), 20*time.Millisecond)
+
+	// Callers report failures, so they are not logged above debug level again
+	entries = logs.FilterMessage("Camera command failed").All()
+	require.Len(t, entries, 1)
+	assert.Equal(t, zapcore.DebugLevel, entries[0].Level)
+	assert.Contains(t, entries[0].ContextMap(), "duration")
+}
+
+func TestCameraClient_CallSkipsMetricsWhenCircuitOpen(t *testing.T) {
+	client := createTestCameraClientWithCircuitOpen()
+
+	err := client.Reboot(context.Background())
+
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.Nil(t, client.CommandMetrics())
+}
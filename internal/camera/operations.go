@@ -2,306 +2,337 @@ package camera
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"strings"
 	"time"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
 )
 
-// ============================================================================
-// System API Methods
-// ============================================================================
+var (
+	// ErrCircuitOpen is returned by operations on a camera whose circuit breaker is open
+	ErrCircuitOpen = errors.New("circuit open")
 
-// Reboot reboots the camera
-func (c *CameraClient) Reboot(ctx context.Context) error {
+	// ErrCameraOffline is returned by operations that could not reach the camera
+	ErrCameraOffline = errors.New("camera offline")
+
+	// ErrNotFound is returned for cameras the manager does not know
+	ErrNotFound = errors.New("camera not found")
+
+	// ErrCameraExists is returned when adding a camera the manager already has
+	ErrCameraExists = errors.New("camera already exists")
+
+	// ErrInvalidCamera is returned when adding a camera missing the settings
+	// needed to connect to it
+	ErrInvalidCamera = errors.New("invalid camera")
+)
+
+// call runs an SDK operation on the camera unless its circuit is open. A
+// successful operation shows the camera is reachable and updates its last-seen time.
+// An operation rejected because the camera session expired is retried once
+// after logging in again. Failures to reach the camera are wrapped in
+// ErrCameraOffline; errors returned by the camera's API are passed through.
+// The outcome and duration of operations that ran are recorded under the
+// operation's name in the camera's command metrics.
+func (c *CameraClient) call(ctx context.Context, operation string, op func() error) error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
+		return fmt.Errorf("%w for camera %s", ErrCircuitOpen, c.Camera.ID)
+	}
+
+	start := c.now()
+	token := c.Client.GetToken()
+	err := op()
+	if err != nil && isSessionExpired(err) && c.relogin(ctx, token) == nil {
+		err = op()
+	}
+	c.observeCommand(operation, c.now().Sub(start), err)
+	if err != nil {
+		if isUnreachable(err) {
+			return fmt.Errorf("%w: camera %s: %w", ErrCameraOffline, c.Camera.ID, err)
+		}
+		return err
 	}
 
-	return c.Client.System.Reboot(ctx)
+	c.markSeen(c.now())
+	return nil
 }
 
-// GetTime gets the camera's time configuration
-func (c *CameraClient) GetTime(ctx context.Context) (*reolink.TimeConfig, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// isSessionExpired reports whether err is the camera rejecting the session
+// token, which a new login fixes
+func isSessionExpired(err error) bool {
+	var apiErr *reolink.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return apiErr.RspCode == reolink.ErrCodeLoginRequired || apiErr.RspCode == reolink.ErrCodeTokenError
+}
 
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
+// relogin logs in to the camera again after an operation using staleToken
+// was rejected. Operations failing together share a single login: those
+// finding the token already replaced by another one's login just retry.
+func (c *CameraClient) relogin(ctx context.Context, staleToken string) error {
+	c.reloginMu.Lock()
+	defer c.reloginMu.Unlock()
+
+	if c.Client.GetToken() != staleToken {
+		return nil
 	}
 
-	return c.Client.System.GetTime(ctx)
+	c.relogins++
+	login := c.login
+	if login == nil {
+		login = c.Client.Login
+	}
+	if err := login(ctx); err != nil {
+		logger.Warn("Camera re-login failed",
+			zap.String("camera_id", c.Camera.ID),
+			zap.Int("relogins", c.relogins),
+			zap.Error(err))
+		return err
+	}
+
+	logger.Info("Logged in to camera again after session expired",
+		zap.String("camera_id", c.Camera.ID),
+		zap.Int("relogins", c.relogins))
+	return nil
 }
 
-// SetTime sets the camera's time configuration
-func (c *CameraClient) SetTime(ctx context.Context, timeConfig *reolink.TimeConfig) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// Relogins returns how often operations logged in to the camera again after
+// its session expired
+func (c *CameraClient) Relogins() int {
+	c.reloginMu.Lock()
+	defer c.reloginMu.Unlock()
+	return c.relogins
+}
 
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
+// isUnreachable reports whether err means the camera could not be reached,
+// as opposed to the camera answering with an error. Cancelled requests are
+// not the camera's fault and do not count.
+func isUnreachable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
 	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
 
-	return c.Client.System.SetTime(ctx, timeConfig)
+// callValue is call for SDK operations returning a value
+func callValue[T any](ctx context.Context, c *CameraClient, operation string, op func() (T, error)) (T, error) {
+	var result T
+	err := c.call(ctx, operation, func() error {
+		var err error
+		result, err = op()
+		return err
+	})
+	return result, err
 }
 
-// GetHddInfo gets HDD/SD card information
-func (c *CameraClient) GetHddInfo(ctx context.Context) ([]reolink.HddInfo, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// ============================================================================
+// System API Methods
+// ============================================================================
 
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
+// Reboot reboots the camera
+func (c *CameraClient) Reboot(ctx context.Context) error {
+	return c.call(ctx, "reboot", func() error {
+		return c.Client.System.Reboot(ctx)
+	})
+}
 
-	return c.Client.System.GetHddInfo(ctx)
+// GetTime gets the camera's time configuration
+func (c *CameraClient) GetTime(ctx context.Context) (*reolink.TimeConfig, error) {
+	return callValue(ctx, c, "get_time", func() (*reolink.TimeConfig, error) {
+		return c.Client.System.GetTime(ctx)
+	})
 }
 
-// GetChannelStatus gets the channel status
-func (c *CameraClient) GetChannelStatus(ctx context.Context) (*reolink.ChannelStatusValue, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+// SetTime sets the camera's time configuration
+func (c *CameraClient) SetTime(ctx context.Context, timeConfig *reolink.TimeConfig) error {
+	return c.call(ctx, "set_time", func() error {
+		return c.Client.System.SetTime(ctx, timeConfig)
+	})
+}
 
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
+// GetHddInfo gets HDD/SD card information
+func (c *CameraClient) GetHddInfo(ctx context.Context) ([]reolink.HddInfo, error) {
+	return callValue(ctx, c, "get_hdd_info", func() ([]reolink.HddInfo, error) {
+		return c.Client.System.GetHddInfo(ctx)
+	})
+}
 
-	return c.Client.System.GetChannelStatus(ctx)
+// GetChannelStatus gets the channel status
+func (c *CameraClient) GetChannelStatus(ctx context.Context) (*reolink.ChannelStatusValue, error) {
+	return callValue(ctx, c, "get_channel_status", func() (*reolink.ChannelStatusValue, error) {
+		return c.Client.System.GetChannelStatus(ctx)
+	})
 }
 
 // GetAbility gets the camera's capabilities
 func (c *CameraClient) GetAbility(ctx context.Context) (*reolink.Ability, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
+	return callValue(ctx, c, "get_ability", func() (*reolink.Ability, error) {
+		return c.Client.System.GetAbility(ctx)
+	})
+}
 
-	return c.Client.System.GetAbility(ctx)
+// GetDeviceInfo gets the camera's model, versions and channel count
+func (c *CameraClient) GetDeviceInfo(ctx context.Context) (*reolink.DeviceInfo, error) {
+	return callValue(ctx, c, "get_device_info", func() (*reolink.DeviceInfo, error) {
+		return c.Client.System.GetDeviceInfo(ctx)
+	})
 }
 
 // GetDeviceName gets the camera's device name
 func (c *CameraClient) GetDeviceName(ctx context.Context) (string, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return "", fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.GetDeviceName(ctx)
+	return callValue(ctx, c, "get_device_name", func() (string, error) {
+		return c.Client.System.GetDeviceName(ctx)
+	})
 }
 
 // SetDeviceName sets the camera's device name
 func (c *CameraClient) SetDeviceName(ctx context.Context, name string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.SetDeviceName(ctx, name)
+	return c.call(ctx, "set_device_name", func() error {
+		return c.Client.System.SetDeviceName(ctx, name)
+	})
 }
 
 // GetAutoMaint gets auto maintenance configuration
 func (c *CameraClient) GetAutoMaint(ctx context.Context) (*reolink.AutoMaint, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.GetAutoMaint(ctx)
+	return callValue(ctx, c, "get_auto_maint", func() (*reolink.AutoMaint, error) {
+		return c.Client.System.GetAutoMaint(ctx)
+	})
 }
 
 // SetAutoMaint sets auto maintenance configuration
 func (c *CameraClient) SetAutoMaint(ctx context.Context, config reolink.AutoMaint) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.SetAutoMaint(ctx, config)
+	return c.call(ctx, "set_auto_maint", func() error {
+		return c.Client.System.SetAutoMaint(ctx, config)
+	})
 }
 
 // GetAutoUpgrade gets auto upgrade configuration
 func (c *CameraClient) GetAutoUpgrade(ctx context.Context) (*reolink.AutoUpgrade, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.GetAutoUpgrade(ctx)
+	return callValue(ctx, c, "get_auto_upgrade", func() (*reolink.AutoUpgrade, error) {
+		return c.Client.System.GetAutoUpgrade(ctx)
+	})
 }
 
 // SetAutoUpgrade sets auto upgrade configuration
 func (c *CameraClient) SetAutoUpgrade(ctx context.Context, enable bool) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.SetAutoUpgrade(ctx, enable)
+	return c.call(ctx, "set_auto_upgrade", func() error {
+		return c.Client.System.SetAutoUpgrade(ctx, enable)
+	})
 }
 
 // CheckFirmware checks for firmware updates
 func (c *CameraClient) CheckFirmware(ctx context.Context) (*reolink.FirmwareCheck, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.CheckFirmware(ctx)
+	return callValue(ctx, c, "check_firmware", func() (*reolink.FirmwareCheck, error) {
+		return c.Client.System.CheckFirmware(ctx)
+	})
 }
 
 // Upgrade upgrades the camera firmware from a file
 func (c *CameraClient) Upgrade(ctx context.Context, firmware []byte) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.Upgrade(ctx, firmware)
+	return c.call(ctx, "upgrade", func() error {
+		return c.Client.System.Upgrade(ctx, firmware)
+	})
 }
 
 // UpgradeOnline upgrades the camera firmware from online source
 func (c *CameraClient) UpgradeOnline(ctx context.Context) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.UpgradeOnline(ctx)
+	return c.call(ctx, "upgrade_online", func() error {
+		return c.Client.System.UpgradeOnline(ctx)
+	})
 }
 
 // UpgradePrepare prepares for firmware upgrade
 func (c *CameraClient) UpgradePrepare(ctx context.Context, restoreCfg bool, fileName string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.UpgradePrepare(ctx, restoreCfg, fileName)
+	return c.call(ctx, "upgrade_prepare", func() error {
+		return c.Client.System.UpgradePrepare(ctx, restoreCfg, fileName)
+	})
 }
 
-// UpgradeStatus gets the firmware upgrade status
+// UpgradeStatus gets the firmware upgrade status. A complete upgrade
+// releases the camera for further upgrades.
 func (c *CameraClient) UpgradeStatus(ctx context.Context) (*reolink.UpgradeStatusInfo, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
+	status, err := callValue(ctx, c, "upgrade_status", func() (*reolink.UpgradeStatusInfo, error) {
+		return c.Client.System.UpgradeStatus(ctx)
+	})
+	if err == nil && upgradeComplete(status) {
+		c.endUpgrade()
 	}
-
-	return c.Client.System.UpgradeStatus(ctx)
+	return status, err
 }
 
 // Format formats the HDD/SD card
 func (c *CameraClient) Format(ctx context.Context, hddID int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.Format(ctx, hddID)
+	return c.call(ctx, "format", func() error {
+		return c.Client.System.Format(ctx, hddID)
+	})
 }
 
 // Restore performs a factory reset
 func (c *CameraClient) Restore(ctx context.Context) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.Restore(ctx)
+	return c.call(ctx, "restore", func() error {
+		return c.Client.System.Restore(ctx)
+	})
 }
 
 // GetSysCfg gets system configuration
 func (c *CameraClient) GetSysCfg(ctx context.Context) (*reolink.SysCfg, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.GetSysCfg(ctx)
+	return callValue(ctx, c, "get_sys_cfg", func() (*reolink.SysCfg, error) {
+		return c.Client.System.GetSysCfg(ctx)
+	})
 }
 
 // SetSysCfg sets system configuration
 func (c *CameraClient) SetSysCfg(ctx context.Context, cfg reolink.SysCfg) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.System.SetSysCfg(ctx, cfg)
+	return c.call(ctx, "set_sys_cfg", func() error {
+		return c.Client.System.SetSysCfg(ctx, cfg)
+	})
 }
 
 // ============================================================================
 // Encoding API Methods
 // ============================================================================
 
-// GetSnapshot takes a snapshot from the camera
+// GetSnapshot takes a snapshot from the camera. Some firmwares wrap the JPEG
+// in a multipart response, which the SDK rejects; those snapshots are fetched
+// directly and the JPEG frame is extracted.
 func (c *CameraClient) GetSnapshot(ctx context.Context, channel int) ([]byte, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Encoding.Snap(ctx, channel)
+	return callValue(ctx, c, "get_snapshot", func() ([]byte, error) {
+		data, err := c.Client.Encoding.Snap(ctx, channel)
+		if err != nil {
+			if strings.Contains(err.Error(), "unexpected content type") {
+				return c.fetchSnapshot(ctx, channel)
+			}
+			return nil, err
+		}
+		return extractJPEG("image/jpeg", data)
+	})
 }
 
 // GetEnc gets encoding configuration
 func (c *CameraClient) GetEnc(ctx context.Context, channel int) (*reolink.EncConfig, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Encoding.GetEnc(ctx, channel)
+	return callValue(ctx, c, "get_enc", func() (*reolink.EncConfig, error) {
+		return c.Client.Encoding.GetEnc(ctx, channel)
+	})
 }
 
 // SetEnc sets encoding configuration
 func (c *CameraClient) SetEnc(ctx context.Context, config reolink.EncConfig) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Encoding.SetEnc(ctx, config)
+	return c.call(ctx, "set_enc", func() error {
+		return c.Client.Encoding.SetEnc(ctx, config)
+	})
 }
 
 // ============================================================================
@@ -310,199 +341,153 @@ func (c *CameraClient) SetEnc(ctx context.Context, config reolink.EncConfig) err
 
 // PTZMove moves the camera PTZ
 func (c *CameraClient) PTZMove(ctx context.Context, operation string, speed int, channel int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	// Use PtzCtrl with PtzCtrlParam
-	param := reolink.PtzCtrlParam{
-		Channel: channel,
-		Op:      operation,
-		Speed:   speed,
-	}
-	return c.Client.PTZ.PtzCtrl(ctx, param)
+	return c.call(ctx, "ptz_move", func() error {
+		// Use PtzCtrl with PtzCtrlParam
+		param := reolink.PtzCtrlParam{
+			Channel: channel,
+			Op:      operation,
+			Speed:   speed,
+		}
+		return c.Client.PTZ.PtzCtrl(ctx, param)
+	})
 }
 
 // PTZStop stops PTZ movement
 func (c *CameraClient) PTZStop(ctx context.Context, channel int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	// Use PtzCtrl with "Stop" operation
-	param := reolink.PtzCtrlParam{
-		Channel: channel,
-		Op:      "Stop",
-	}
-	return c.Client.PTZ.PtzCtrl(ctx, param)
+	return c.call(ctx, "ptz_stop", func() error {
+		// Use PtzCtrl with "Stop" operation
+		param := reolink.PtzCtrlParam{
+			Channel: channel,
+			Op:      "Stop",
+		}
+		return c.Client.PTZ.PtzCtrl(ctx, param)
+	})
 }
 
 // PTZGotoPreset moves to a PTZ preset
 func (c *CameraClient) PTZGotoPreset(ctx context.Context, channel int, presetID int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	// Use PtzCtrl with "ToPos" operation and preset ID
-	param := reolink.PtzCtrlParam{
-		Channel: channel,
-		Op:      "ToPos",
-		ID:      presetID,
-	}
-	return c.Client.PTZ.PtzCtrl(ctx, param)
+	return c.call(ctx, "ptz_goto_preset", func() error {
+		// Use PtzCtrl with "ToPos" operation and preset ID
+		param := reolink.PtzCtrlParam{
+			Channel: channel,
+			Op:      "ToPos",
+			ID:      presetID,
+		}
+		return c.Client.PTZ.PtzCtrl(ctx, param)
+	})
 }
 
 // GetPtzPreset gets PTZ presets
 func (c *CameraClient) GetPtzPreset(ctx context.Context, channel int) ([]reolink.PtzPreset, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetPtzPreset(ctx, channel)
+	return callValue(ctx, c, "get_ptz_preset", func() ([]reolink.PtzPreset, error) {
+		return c.Client.PTZ.GetPtzPreset(ctx, channel)
+	})
 }
 
 // SetPtzPreset sets a PTZ preset
 func (c *CameraClient) SetPtzPreset(ctx context.Context, preset reolink.PtzPreset) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.SetPtzPreset(ctx, preset)
+	return c.call(ctx, "set_ptz_preset", func() error {
+		return c.Client.PTZ.SetPtzPreset(ctx, preset)
+	})
 }
 
 // GetPtzPatrol gets PTZ patrol configuration
 func (c *CameraClient) GetPtzPatrol(ctx context.Context, channel int) (*reolink.PtzPatrol, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetPtzPatrol(ctx, channel)
+	return callValue(ctx, c, "get_ptz_patrol", func() (*reolink.PtzPatrol, error) {
+		return c.Client.PTZ.GetPtzPatrol(ctx, channel)
+	})
 }
 
 // SetPtzPatrol sets PTZ patrol configuration
 func (c *CameraClient) SetPtzPatrol(ctx context.Context, patrol reolink.PtzPatrol) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.SetPtzPatrol(ctx, patrol)
+	return c.call(ctx, "set_ptz_patrol", func() error {
+		return c.Client.PTZ.SetPtzPatrol(ctx, patrol)
+	})
+}
+
+// PTZPatrolControl starts or stops a saved PTZ patrol
+func (c *CameraClient) PTZPatrolControl(ctx context.Context, channel int, patrolID int, start bool) error {
+	return c.call(ctx, "ptz_patrol_control", func() error {
+		op := reolink.PTZOpStopPatrol
+		if start {
+			op = reolink.PTZOpStartPatrol
+		}
+		param := reolink.PtzCtrlParam{
+			Channel: channel,
+			Op:      op,
+			ID:      patrolID,
+		}
+		return c.Client.PTZ.PtzCtrl(ctx, param)
+	})
 }
 
 // GetPtzGuard gets PTZ guard configuration
 func (c *CameraClient) GetPtzGuard(ctx context.Context, channel int) (*reolink.PtzGuard, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetPtzGuard(ctx, channel)
+	return callValue(ctx, c, "get_ptz_guard", func() (*reolink.PtzGuard, error) {
+		return c.Client.PTZ.GetPtzGuard(ctx, channel)
+	})
 }
 
 // SetPtzGuard sets PTZ guard configuration
 func (c *CameraClient) SetPtzGuard(ctx context.Context, guard reolink.PtzGuard) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
+	return c.call(ctx, "set_ptz_guard", func() error {
+		return c.Client.PTZ.SetPtzGuard(ctx, guard)
+	})
+}
 
-	return c.Client.PTZ.SetPtzGuard(ctx, guard)
+// PTZGotoGuard moves the camera to its guard (home) position
+func (c *CameraClient) PTZGotoGuard(ctx context.Context, channel int) error {
+	return c.call(ctx, "ptz_goto_guard", func() error {
+		// SetPtzGuard with cmdStr "toPos" goes to the guard position instead of changing it
+		guard := reolink.PtzGuard{
+			Channel:   channel,
+			CmdStr:    "toPos",
+			BExistPos: 1,
+		}
+		return c.Client.PTZ.SetPtzGuard(ctx, guard)
+	})
 }
 
 // GetAutoFocus gets auto focus configuration
 func (c *CameraClient) GetAutoFocus(ctx context.Context, channel int) (*reolink.AutoFocus, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetAutoFocus(ctx, channel)
+	return callValue(ctx, c, "get_auto_focus", func() (*reolink.AutoFocus, error) {
+		return c.Client.PTZ.GetAutoFocus(ctx, channel)
+	})
 }
 
 // SetAutoFocus sets auto focus configuration
 func (c *CameraClient) SetAutoFocus(ctx context.Context, autoFocus reolink.AutoFocus) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.SetAutoFocus(ctx, autoFocus)
+	return c.call(ctx, "set_auto_focus", func() error {
+		return c.Client.PTZ.SetAutoFocus(ctx, autoFocus)
+	})
 }
 
 // GetZoomFocus gets zoom focus configuration
 func (c *CameraClient) GetZoomFocus(ctx context.Context, channel int) (*reolink.ZoomFocus, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetZoomFocus(ctx, channel)
+	return callValue(ctx, c, "get_zoom_focus", func() (*reolink.ZoomFocus, error) {
+		return c.Client.PTZ.GetZoomFocus(ctx, channel)
+	})
 }
 
 // StartZoomFocus starts zoom/focus operation
 func (c *CameraClient) StartZoomFocus(ctx context.Context, channel int, op string, pos int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.StartZoomFocus(ctx, channel, op, pos)
+	return c.call(ctx, "start_zoom_focus", func() error {
+		return c.Client.PTZ.StartZoomFocus(ctx, channel, op, pos)
+	})
 }
 
 // GetPtzCheckState gets PTZ check state
 func (c *CameraClient) GetPtzCheckState(ctx context.Context, channel int) (*reolink.PtzCheckState, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.GetPtzCheckState(ctx, channel)
+	return callValue(ctx, c, "get_ptz_check_state", func() (*reolink.PtzCheckState, error) {
+		return c.Client.PTZ.GetPtzCheckState(ctx, channel)
+	})
 }
 
 // PtzCheck performs PTZ check
 func (c *CameraClient) PtzCheck(ctx context.Context, channel int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.PTZ.PtzCheck(ctx, channel)
+	return c.call(ctx, "ptz_check", func() error {
+		return c.Client.PTZ.PtzCheck(ctx, channel)
+	})
 }
 
 // ============================================================================
@@ -511,110 +496,65 @@ func (c *CameraClient) PtzCheck(ctx context.Context, channel int) error {
 
 // SetIRLights controls the IR lights
 func (c *CameraClient) SetIRLights(ctx context.Context, channel int, state string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.SetIrLights(ctx, channel, state)
+	return c.call(ctx, "set_ir_lights", func() error {
+		return c.Client.LED.SetIrLights(ctx, channel, state)
+	})
 }
 
 // SetWhiteLED controls the white LED
 func (c *CameraClient) SetWhiteLED(ctx context.Context, config *reolink.WhiteLed) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.SetWhiteLed(ctx, *config)
+	return c.call(ctx, "set_white_led", func() error {
+		return c.Client.LED.SetWhiteLed(ctx, *config)
+	})
 }
 
 // GetIrLights gets IR lights configuration
 func (c *CameraClient) GetIrLights(ctx context.Context) (*reolink.IrLights, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.GetIrLights(ctx)
+	return callValue(ctx, c, "get_ir_lights", func() (*reolink.IrLights, error) {
+		return c.Client.LED.GetIrLights(ctx)
+	})
 }
 
 // GetWhiteLed gets white LED configuration
 func (c *CameraClient) GetWhiteLed(ctx context.Context, channel int) (*reolink.WhiteLed, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.GetWhiteLed(ctx, channel)
+	return callValue(ctx, c, "get_white_led", func() (*reolink.WhiteLed, error) {
+		return c.Client.LED.GetWhiteLed(ctx, channel)
+	})
 }
 
 // GetPowerLed gets power LED configuration
 func (c *CameraClient) GetPowerLed(ctx context.Context, channel int) (*reolink.PowerLed, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.GetPowerLed(ctx, channel)
+	return callValue(ctx, c, "get_power_led", func() (*reolink.PowerLed, error) {
+		return c.Client.LED.GetPowerLed(ctx, channel)
+	})
 }
 
 // SetPowerLed sets power LED configuration
 func (c *CameraClient) SetPowerLed(ctx context.Context, channel int, state string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.SetPowerLed(ctx, channel, state)
+	return c.call(ctx, "set_power_led", func() error {
+		return c.Client.LED.SetPowerLed(ctx, channel, state)
+	})
 }
 
 // SetAlarmArea sets alarm detection area/zone
 func (c *CameraClient) SetAlarmArea(ctx context.Context, params map[string]interface{}) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.SetAlarmArea(ctx, params)
+	return c.call(ctx, "set_alarm_area", func() error {
+		return c.Client.LED.SetAlarmArea(ctx, params)
+	})
 }
 
 // GetAiAlarm gets AI alarm configuration
 func (c *CameraClient) GetAiAlarm(ctx context.Context, channel int, aiType string) (*reolink.AiAlarm, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.GetAiAlarm(ctx, channel, aiType)
+	return callValue(ctx, c, "get_ai_alarm", func() (*reolink.AiAlarm, error) {
+		return c.Client.LED.GetAiAlarm(ctx, channel, aiType)
+	})
 }
 
 // SetAiAlarm sets AI alarm configuration
 func (c *CameraClient) SetAiAlarm(ctx context.Context, channel int, alarm reolink.AiAlarm) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.LED.SetAiAlarm(ctx, channel, alarm)
+	return c.call(ctx, "set_ai_alarm", func() error {
+		return c.Client.LED.SetAiAlarm(ctx, channel, alarm)
+	})
 }
 
 // ============================================================================
@@ -623,131 +563,81 @@ func (c *CameraClient) SetAiAlarm(ctx context.Context, channel int, alarm reolin
 
 // TriggerSiren triggers the camera siren
 func (c *CameraClient) TriggerSiren(ctx context.Context, channel int, duration int) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	return c.call(ctx, "trigger_siren", func() error {
+		// AudioAlarmPlayParam fields: Channel, AlarmMode, ManualSwitch, Times
+		param := reolink.AudioAlarmPlayParam{
+			Channel:      channel,
+			AlarmMode:    "manul", // Manual mode (note: API uses "manul" typo)
+			ManualSwitch: 1,       // Enable
+			Times:        duration,
+		}
 
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	// AudioAlarmPlayParam fields: Channel, AlarmMode, ManualSwitch, Times
-	param := reolink.AudioAlarmPlayParam{
-		Channel:      channel,
-		AlarmMode:    "manul", // Manual mode (note: API uses "manul" typo)
-		ManualSwitch: 1,       // Enable
-		Times:        duration,
-	}
-
-	return c.Client.Alarm.AudioAlarmPlay(ctx, param)
+		return c.Client.Alarm.AudioAlarmPlay(ctx, param)
+	})
 }
 
 // GetMotionState gets the current motion detection state
 func (c *CameraClient) GetMotionState(ctx context.Context, channel int) (int, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return 0, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	// GetMdState returns (int, error) not (*MdStateValue, error)
-	return c.Client.Alarm.GetMdState(ctx, channel)
+	return callValue(ctx, c, "get_motion_state", func() (int, error) {
+		// GetMdState returns (int, error) not (*MdStateValue, error)
+		return c.Client.Alarm.GetMdState(ctx, channel)
+	})
 }
 
 // GetMdAlarm gets motion detection alarm configuration
 func (c *CameraClient) GetMdAlarm(ctx context.Context, channel int) (*reolink.MdAlarm, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.GetMdAlarm(ctx, channel)
+	return callValue(ctx, c, "get_md_alarm", func() (*reolink.MdAlarm, error) {
+		return c.Client.Alarm.GetMdAlarm(ctx, channel)
+	})
 }
 
 // SetMdAlarm sets motion detection alarm configuration
 func (c *CameraClient) SetMdAlarm(ctx context.Context, config reolink.MdAlarm) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.SetMdAlarm(ctx, config)
+	return c.call(ctx, "set_md_alarm", func() error {
+		return c.Client.Alarm.SetMdAlarm(ctx, config)
+	})
 }
 
 // GetAlarm gets alarm configuration
 func (c *CameraClient) GetAlarm(ctx context.Context, channel int, alarmType string) (*reolink.Alarm, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.GetAlarm(ctx, channel, alarmType)
+	return callValue(ctx, c, "get_alarm", func() (*reolink.Alarm, error) {
+		return c.Client.Alarm.GetAlarm(ctx, channel, alarmType)
+	})
 }
 
 // SetAlarm sets alarm configuration
 func (c *CameraClient) SetAlarm(ctx context.Context, alarm reolink.Alarm) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.SetAlarm(ctx, alarm)
+	return c.call(ctx, "set_alarm", func() error {
+		return c.Client.Alarm.SetAlarm(ctx, alarm)
+	})
 }
 
 // GetAudioAlarm gets audio alarm configuration
 func (c *CameraClient) GetAudioAlarm(ctx context.Context, channel int) (*reolink.AudioAlarm, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.GetAudioAlarm(ctx, channel)
+	return callValue(ctx, c, "get_audio_alarm", func() (*reolink.AudioAlarm, error) {
+		return c.Client.Alarm.GetAudioAlarm(ctx, channel)
+	})
 }
 
 // SetAudioAlarm sets audio alarm configuration
 func (c *CameraClient) SetAudioAlarm(ctx context.Context, audioAlarm reolink.AudioAlarm) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.SetAudioAlarm(ctx, audioAlarm)
+	return c.call(ctx, "set_audio_alarm", func() error {
+		return c.Client.Alarm.SetAudioAlarm(ctx, audioAlarm)
+	})
 }
 
 // GetBuzzerAlarmV20 gets buzzer alarm configuration (V20 API)
 func (c *CameraClient) GetBuzzerAlarmV20(ctx context.Context, channel int) (*reolink.BuzzerAlarm, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.GetBuzzerAlarmV20(ctx, channel)
+	return callValue(ctx, c, "get_buzzer_alarm_v20", func() (*reolink.BuzzerAlarm, error) {
+		return c.Client.Alarm.GetBuzzerAlarmV20(ctx, channel)
+	})
 }
 
 // SetBuzzerAlarmV20 sets buzzer alarm configuration (V20 API)
 func (c *CameraClient) SetBuzzerAlarmV20(ctx context.Context, buzzerAlarm reolink.BuzzerAlarm) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Alarm.SetBuzzerAlarmV20(ctx, buzzerAlarm)
+	return c.call(ctx, "set_buzzer_alarm_v20", func() error {
+		return c.Client.Alarm.SetBuzzerAlarmV20(ctx, buzzerAlarm)
+	})
 }
 
 // ============================================================================
@@ -756,38 +646,23 @@ func (c *CameraClient) SetBuzzerAlarmV20(ctx context.Context, buzzerAlarm reolin
 
 // GetAIState gets the current AI detection state
 func (c *CameraClient) GetAIState(ctx context.Context, channel int) (*reolink.AiState, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.AI.GetAiState(ctx, channel)
+	return callValue(ctx, c, "get_ai_state", func() (*reolink.AiState, error) {
+		return c.Client.AI.GetAiState(ctx, channel)
+	})
 }
 
 // GetAiCfg gets AI detection configuration
 func (c *CameraClient) GetAiCfg(ctx context.Context, channel int) (*reolink.AiCfg, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.AI.GetAiCfg(ctx, channel)
+	return callValue(ctx, c, "get_ai_cfg", func() (*reolink.AiCfg, error) {
+		return c.Client.AI.GetAiCfg(ctx, channel)
+	})
 }
 
 // SetAiCfg sets AI detection configuration
 func (c *CameraClient) SetAiCfg(ctx context.Context, config reolink.AiCfg) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.AI.SetAiCfg(ctx, config)
+	return c.call(ctx, "set_ai_cfg", func() error {
+		return c.Client.AI.SetAiCfg(ctx, config)
+	})
 }
 
 // ============================================================================
@@ -824,62 +699,37 @@ func (c *CameraClient) GetRTMPURL(streamType reolink.StreamType, channelID int)
 
 // GetRec gets recording configuration (v1.0)
 func (c *CameraClient) GetRec(ctx context.Context, channel int) (*reolink.Rec, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.GetRec(ctx, channel)
+	return callValue(ctx, c, "get_rec", func() (*reolink.Rec, error) {
+		return c.Client.Recording.GetRec(ctx, channel)
+	})
 }
 
 // SetRec sets recording configuration (v1.0)
 func (c *CameraClient) SetRec(ctx context.Context, rec reolink.Rec) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.SetRec(ctx, rec)
+	return c.call(ctx, "set_rec", func() error {
+		return c.Client.Recording.SetRec(ctx, rec)
+	})
 }
 
 // GetRecV20 gets recording configuration (v2.0)
 func (c *CameraClient) GetRecV20(ctx context.Context, channel int) (*reolink.Rec, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.GetRecV20(ctx, channel)
+	return callValue(ctx, c, "get_rec_v20", func() (*reolink.Rec, error) {
+		return c.Client.Recording.GetRecV20(ctx, channel)
+	})
 }
 
 // SetRecV20 sets recording configuration (v2.0)
 func (c *CameraClient) SetRecV20(ctx context.Context, rec reolink.Rec) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.SetRecV20(ctx, rec)
+	return c.call(ctx, "set_rec_v20", func() error {
+		return c.Client.Recording.SetRecV20(ctx, rec)
+	})
 }
 
 // Search searches for recordings within a time range
 func (c *CameraClient) Search(ctx context.Context, channel int, startTime, endTime time.Time, streamType string) ([]reolink.SearchResult, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.Search(ctx, channel, startTime, endTime, streamType)
+	return callValue(ctx, c, "search", func() ([]reolink.SearchResult, error) {
+		return c.Client.Recording.Search(ctx, channel, startTime, endTime, streamType)
+	})
 }
 
 // Download downloads a recording
@@ -900,14 +750,9 @@ func (c *CameraClient) Playback(source, output string) string {
 
 // NvrDownload downloads a recording from NVR
 func (c *CameraClient) NvrDownload(ctx context.Context, params map[string]interface{}) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Recording.NvrDownload(ctx, params)
+	return c.call(ctx, "nvr_download", func() error {
+		return c.Client.Recording.NvrDownload(ctx, params)
+	})
 }
 
 // ============================================================================
@@ -916,146 +761,86 @@ func (c *CameraClient) NvrDownload(ctx context.Context, params map[string]interf
 
 // GetOsd gets OSD (On-Screen Display) configuration
 func (c *CameraClient) GetOsd(ctx context.Context, channel int) (*reolink.Osd, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.GetOsd(ctx, channel)
+	return callValue(ctx, c, "get_osd", func() (*reolink.Osd, error) {
+		return c.Client.Video.GetOsd(ctx, channel)
+	})
 }
 
 // SetOsd sets OSD (On-Screen Display) configuration
 func (c *CameraClient) SetOsd(ctx context.Context, osd reolink.Osd) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetOsd(ctx, osd)
+	return c.call(ctx, "set_osd", func() error {
+		return c.Client.Video.SetOsd(ctx, osd)
+	})
 }
 
 // GetImage gets image settings (brightness, contrast, saturation, etc.)
 func (c *CameraClient) GetImage(ctx context.Context, channel int) (*reolink.Image, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.GetImage(ctx, channel)
+	return callValue(ctx, c, "get_image", func() (*reolink.Image, error) {
+		return c.Client.Video.GetImage(ctx, channel)
+	})
 }
 
 // SetImage sets image settings (brightness, contrast, saturation, etc.)
 func (c *CameraClient) SetImage(ctx context.Context, image reolink.Image) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetImage(ctx, image)
+	return c.call(ctx, "set_image", func() error {
+		return c.Client.Video.SetImage(ctx, image)
+	})
 }
 
 // GetIsp gets ISP (Image Signal Processing) settings
 func (c *CameraClient) GetIsp(ctx context.Context, channel int) (*reolink.Isp, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.GetIsp(ctx, channel)
+	return callValue(ctx, c, "get_isp", func() (*reolink.Isp, error) {
+		return c.Client.Video.GetIsp(ctx, channel)
+	})
 }
 
 // SetIsp sets ISP (Image Signal Processing) settings
 func (c *CameraClient) SetIsp(ctx context.Context, isp reolink.Isp) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetIsp(ctx, isp)
+	return c.call(ctx, "set_isp", func() error {
+		return c.Client.Video.SetIsp(ctx, isp)
+	})
 }
 
 // GetMask gets privacy mask configuration
 func (c *CameraClient) GetMask(ctx context.Context, channel int) (*reolink.Mask, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.GetMask(ctx, channel)
+	return callValue(ctx, c, "get_mask", func() (*reolink.Mask, error) {
+		return c.Client.Video.GetMask(ctx, channel)
+	})
 }
 
 // SetMask sets privacy mask configuration
-func (c *CameraClient) SetMask(ctx context.Context, mask reolink.Mask) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetMask(ctx, mask)
-}
-
-// GetCrop gets video crop configuration
-func (c *CameraClient) GetCrop(ctx context.Context, channel int) (*reolink.Crop, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
+func (c *CameraClient) SetMask(ctx context.Context, mask reolink.Mask) error {
+	return c.call(ctx, "set_mask", func() error {
+		return c.Client.Video.SetMask(ctx, mask)
+	})
+}
 
-	return c.Client.Video.GetCrop(ctx, channel)
+// GetCrop gets video crop configuration
+func (c *CameraClient) GetCrop(ctx context.Context, channel int) (*reolink.Crop, error) {
+	return callValue(ctx, c, "get_crop", func() (*reolink.Crop, error) {
+		return c.Client.Video.GetCrop(ctx, channel)
+	})
 }
 
 // SetCrop sets video crop configuration
 func (c *CameraClient) SetCrop(ctx context.Context, crop reolink.Crop) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetCrop(ctx, crop)
+	return c.call(ctx, "set_crop", func() error {
+		return c.Client.Video.SetCrop(ctx, crop)
+	})
 }
 
 // GetStitch gets panoramic stitching configuration
 func (c *CameraClient) GetStitch(ctx context.Context) (*reolink.Stitch, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.GetStitch(ctx)
+	return callValue(ctx, c, "get_stitch", func() (*reolink.Stitch, error) {
+		return c.Client.Video.GetStitch(ctx)
+	})
 }
 
 // SetStitch sets panoramic stitching configuration
 func (c *CameraClient) SetStitch(ctx context.Context, stitch reolink.Stitch) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Video.SetStitch(ctx, stitch)
+	return c.call(ctx, "set_stitch", func() error {
+		return c.Client.Video.SetStitch(ctx, stitch)
+	})
 }
 
 // ============================================================================
@@ -1064,374 +849,219 @@ func (c *CameraClient) SetStitch(ctx context.Context, stitch reolink.Stitch) err
 
 // GetNetPort gets network port configuration (HTTP, RTSP, RTMP, ONVIF)
 func (c *CameraClient) GetNetPort(ctx context.Context) (*reolink.NetPort, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetNetPort(ctx)
+	return callValue(ctx, c, "get_net_port", func() (*reolink.NetPort, error) {
+		return c.Client.Network.GetNetPort(ctx)
+	})
 }
 
 // SetNetPort sets network port configuration
 func (c *CameraClient) SetNetPort(ctx context.Context, netPort reolink.NetPort) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetNetPort(ctx, netPort)
+	return c.call(ctx, "set_net_port", func() error {
+		return c.Client.Network.SetNetPort(ctx, netPort)
+	})
 }
 
 // GetLocalLink gets local link configuration
 func (c *CameraClient) GetLocalLink(ctx context.Context) (*reolink.LocalLink, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetLocalLink(ctx)
+	return callValue(ctx, c, "get_local_link", func() (*reolink.LocalLink, error) {
+		return c.Client.Network.GetLocalLink(ctx)
+	})
 }
 
 // SetLocalLink sets local link configuration
 func (c *CameraClient) SetLocalLink(ctx context.Context, localLink reolink.LocalLink) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetLocalLink(ctx, localLink)
+	return c.call(ctx, "set_local_link", func() error {
+		return c.Client.Network.SetLocalLink(ctx, localLink)
+	})
 }
 
 // GetNtp gets NTP configuration
 func (c *CameraClient) GetNtp(ctx context.Context) (*reolink.Ntp, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetNtp(ctx)
+	return callValue(ctx, c, "get_ntp", func() (*reolink.Ntp, error) {
+		return c.Client.Network.GetNtp(ctx)
+	})
 }
 
 // SetNtp sets NTP configuration
 func (c *CameraClient) SetNtp(ctx context.Context, ntp reolink.Ntp) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetNtp(ctx, ntp)
+	return c.call(ctx, "set_ntp", func() error {
+		return c.Client.Network.SetNtp(ctx, ntp)
+	})
 }
 
 // GetWifi gets WiFi configuration
 func (c *CameraClient) GetWifi(ctx context.Context) (*reolink.Wifi, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetWifi(ctx)
+	return callValue(ctx, c, "get_wifi", func() (*reolink.Wifi, error) {
+		return c.Client.Network.GetWifi(ctx)
+	})
 }
 
 // SetWifi sets WiFi configuration
 func (c *CameraClient) SetWifi(ctx context.Context, wifi reolink.Wifi) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetWifi(ctx, wifi)
+	return c.call(ctx, "set_wifi", func() error {
+		return c.Client.Network.SetWifi(ctx, wifi)
+	})
 }
 
 // ScanWifi scans for available WiFi networks
 func (c *CameraClient) ScanWifi(ctx context.Context) ([]reolink.WifiNetwork, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.ScanWifi(ctx)
+	return callValue(ctx, c, "scan_wifi", func() ([]reolink.WifiNetwork, error) {
+		return c.Client.Network.ScanWifi(ctx)
+	})
 }
 
 // GetWifiSignal gets WiFi signal strength
 func (c *CameraClient) GetWifiSignal(ctx context.Context) (*reolink.WifiSignal, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetWifiSignal(ctx)
+	return callValue(ctx, c, "get_wifi_signal", func() (*reolink.WifiSignal, error) {
+		return c.Client.Network.GetWifiSignal(ctx)
+	})
 }
 
 // GetDdns gets DDNS configuration
 func (c *CameraClient) GetDdns(ctx context.Context) (*reolink.Ddns, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetDdns(ctx)
+	return callValue(ctx, c, "get_ddns", func() (*reolink.Ddns, error) {
+		return c.Client.Network.GetDdns(ctx)
+	})
 }
 
 // SetDdns sets DDNS configuration
 func (c *CameraClient) SetDdns(ctx context.Context, ddns reolink.Ddns) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetDdns(ctx, ddns)
+	return c.call(ctx, "set_ddns", func() error {
+		return c.Client.Network.SetDdns(ctx, ddns)
+	})
 }
 
 // GetEmail gets email notification configuration
 func (c *CameraClient) GetEmail(ctx context.Context) (*reolink.Email, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetEmail(ctx)
+	return callValue(ctx, c, "get_email", func() (*reolink.Email, error) {
+		return c.Client.Network.GetEmail(ctx)
+	})
 }
 
 // SetEmail sets email notification configuration
 func (c *CameraClient) SetEmail(ctx context.Context, email reolink.Email) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetEmail(ctx, email)
+	return c.call(ctx, "set_email", func() error {
+		return c.Client.Network.SetEmail(ctx, email)
+	})
 }
 
 // GetEmailV20 gets email notification configuration (v2.0)
 func (c *CameraClient) GetEmailV20(ctx context.Context, channel int) (*reolink.Email, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetEmailV20(ctx, channel)
+	return callValue(ctx, c, "get_email_v20", func() (*reolink.Email, error) {
+		return c.Client.Network.GetEmailV20(ctx, channel)
+	})
 }
 
 // SetEmailV20 sets email notification configuration (v2.0)
 func (c *CameraClient) SetEmailV20(ctx context.Context, channel int, email reolink.Email) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetEmailV20(ctx, channel, email)
+	return c.call(ctx, "set_email_v20", func() error {
+		return c.Client.Network.SetEmailV20(ctx, channel, email)
+	})
 }
 
 // GetFtp gets FTP configuration
 func (c *CameraClient) GetFtp(ctx context.Context) (*reolink.Ftp, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetFtp(ctx)
+	return callValue(ctx, c, "get_ftp", func() (*reolink.Ftp, error) {
+		return c.Client.Network.GetFtp(ctx)
+	})
 }
 
 // SetFtp sets FTP configuration
 func (c *CameraClient) SetFtp(ctx context.Context, ftp reolink.Ftp) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetFtp(ctx, ftp)
+	return c.call(ctx, "set_ftp", func() error {
+		return c.Client.Network.SetFtp(ctx, ftp)
+	})
 }
 
 // GetFtpV20 gets FTP configuration (v2.0)
 func (c *CameraClient) GetFtpV20(ctx context.Context, channel int) (*reolink.Ftp, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetFtpV20(ctx, channel)
+	return callValue(ctx, c, "get_ftp_v20", func() (*reolink.Ftp, error) {
+		return c.Client.Network.GetFtpV20(ctx, channel)
+	})
 }
 
 // SetFtpV20 sets FTP configuration (v2.0)
 func (c *CameraClient) SetFtpV20(ctx context.Context, channel int, ftp reolink.Ftp) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetFtpV20(ctx, channel, ftp)
+	return c.call(ctx, "set_ftp_v20", func() error {
+		return c.Client.Network.SetFtpV20(ctx, channel, ftp)
+	})
 }
 
 // GetPush gets push notification configuration
 func (c *CameraClient) GetPush(ctx context.Context) (*reolink.Push, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetPush(ctx)
+	return callValue(ctx, c, "get_push", func() (*reolink.Push, error) {
+		return c.Client.Network.GetPush(ctx)
+	})
 }
 
 // SetPush sets push notification configuration
 func (c *CameraClient) SetPush(ctx context.Context, push reolink.Push) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetPush(ctx, push)
+	return c.call(ctx, "set_push", func() error {
+		return c.Client.Network.SetPush(ctx, push)
+	})
 }
 
 // GetPushV20 gets push notification configuration (v2.0)
 func (c *CameraClient) GetPushV20(ctx context.Context, channel int) (*reolink.Push, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetPushV20(ctx, channel)
+	return callValue(ctx, c, "get_push_v20", func() (*reolink.Push, error) {
+		return c.Client.Network.GetPushV20(ctx, channel)
+	})
 }
 
 // SetPushV20 sets push notification configuration (v2.0)
 func (c *CameraClient) SetPushV20(ctx context.Context, channel int, push reolink.Push) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetPushV20(ctx, channel, push)
+	return c.call(ctx, "set_push_v20", func() error {
+		return c.Client.Network.SetPushV20(ctx, channel, push)
+	})
 }
 
 // GetPushCfg gets push configuration
 func (c *CameraClient) GetPushCfg(ctx context.Context) (*reolink.PushCfg, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetPushCfg(ctx)
+	return callValue(ctx, c, "get_push_cfg", func() (*reolink.PushCfg, error) {
+		return c.Client.Network.GetPushCfg(ctx)
+	})
 }
 
 // SetPushCfg sets push configuration
 func (c *CameraClient) SetPushCfg(ctx context.Context, pushCfg reolink.PushCfg) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetPushCfg(ctx, pushCfg)
+	return c.call(ctx, "set_push_cfg", func() error {
+		return c.Client.Network.SetPushCfg(ctx, pushCfg)
+	})
 }
 
 // GetP2p gets P2P configuration
 func (c *CameraClient) GetP2p(ctx context.Context) (*reolink.P2p, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetP2p(ctx)
+	return callValue(ctx, c, "get_p2p", func() (*reolink.P2p, error) {
+		return c.Client.Network.GetP2p(ctx)
+	})
 }
 
 // SetP2p sets P2P configuration
 func (c *CameraClient) SetP2p(ctx context.Context, p2p reolink.P2p) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetP2p(ctx, p2p)
+	return c.call(ctx, "set_p2p", func() error {
+		return c.Client.Network.SetP2p(ctx, p2p)
+	})
 }
 
 // GetUpnp gets UPnP configuration
 func (c *CameraClient) GetUpnp(ctx context.Context) (*reolink.Upnp, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetUpnp(ctx)
+	return callValue(ctx, c, "get_upnp", func() (*reolink.Upnp, error) {
+		return c.Client.Network.GetUpnp(ctx)
+	})
 }
 
 // SetUpnp sets UPnP configuration
 func (c *CameraClient) SetUpnp(ctx context.Context, upnp reolink.Upnp) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.SetUpnp(ctx, upnp)
+	return c.call(ctx, "set_upnp", func() error {
+		return c.Client.Network.SetUpnp(ctx, upnp)
+	})
 }
 
 // GetRtspUrl gets RTSP URL configuration
 func (c *CameraClient) GetRtspUrl(ctx context.Context, channel int) (*reolink.RtspUrl, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Network.GetRtspUrl(ctx, channel)
+	return callValue(ctx, c, "get_rtsp_url", func() (*reolink.RtspUrl, error) {
+		return c.Client.Network.GetRtspUrl(ctx, channel)
+	})
 }
 
 // ============================================================================
@@ -1440,96 +1070,56 @@ func (c *CameraClient) GetRtspUrl(ctx context.Context, channel int) (*reolink.Rt
 
 // GetUsers gets list of users
 func (c *CameraClient) GetUsers(ctx context.Context) ([]reolink.User, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.GetUsers(ctx)
+	return callValue(ctx, c, "get_users", func() ([]reolink.User, error) {
+		return c.Client.Security.GetUsers(ctx)
+	})
 }
 
 // AddUser adds a new user
 func (c *CameraClient) AddUser(ctx context.Context, user reolink.User) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.AddUser(ctx, user)
+	return c.call(ctx, "add_user", func() error {
+		return c.Client.Security.AddUser(ctx, user)
+	})
 }
 
 // ModifyUser modifies an existing user
 func (c *CameraClient) ModifyUser(ctx context.Context, user reolink.User) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.ModifyUser(ctx, user)
+	return c.call(ctx, "modify_user", func() error {
+		return c.Client.Security.ModifyUser(ctx, user)
+	})
 }
 
 // DeleteUser deletes a user
 func (c *CameraClient) DeleteUser(ctx context.Context, username string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.DeleteUser(ctx, username)
+	return c.call(ctx, "delete_user", func() error {
+		return c.Client.Security.DeleteUser(ctx, username)
+	})
 }
 
 // GetOnlineUsers gets list of currently online users
 func (c *CameraClient) GetOnlineUsers(ctx context.Context) ([]reolink.OnlineUser, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.GetOnlineUsers(ctx)
+	return callValue(ctx, c, "get_online_users", func() ([]reolink.OnlineUser, error) {
+		return c.Client.Security.GetOnlineUsers(ctx)
+	})
 }
 
 // DisconnectUser disconnects a user session
 func (c *CameraClient) DisconnectUser(ctx context.Context, username string) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.DisconnectUser(ctx, username)
+	return c.call(ctx, "disconnect_user", func() error {
+		return c.Client.Security.DisconnectUser(ctx, username)
+	})
 }
 
 // GetCertificateInfo gets SSL certificate information
 func (c *CameraClient) GetCertificateInfo(ctx context.Context) (*reolink.CertificateInfo, error) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return nil, fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.GetCertificateInfo(ctx)
+	return callValue(ctx, c, "get_certificate_info", func() (*reolink.CertificateInfo, error) {
+		return c.Client.Security.GetCertificateInfo(ctx)
+	})
 }
 
 // CertificateClear clears SSL certificate
 func (c *CameraClient) CertificateClear(ctx context.Context) error {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-
-	if c.CircuitOpen {
-		return fmt.Errorf("circuit open for camera %s", c.Camera.ID)
-	}
-
-	return c.Client.Security.CertificateClear(ctx)
+	return c.call(ctx, "certificate_clear", func() error {
+		return c.Client.Security.CertificateClear(ctx)
+	})
 }
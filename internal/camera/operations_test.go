@@ -2,10 +2,16 @@ package camera
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/stretchr/testify/assert"
 )
@@ -189,6 +195,16 @@ func TestCameraClient_GetAbility_CircuitOpen(t *testing.T) {
 	assert.Contains(t, err.Error(), "circuit open")
 }
 
+func TestCameraClient_GetDeviceInfo_CircuitOpen(t *testing.T) {
+	client := createTestCameraClientWithCircuitOpen()
+	ctx := context.Background()
+
+	info, err := client.GetDeviceInfo(ctx)
+	assert.Error(t, err)
+	assert.Nil(t, info)
+	assert.Contains(t, err.Error(), "circuit open")
+}
+
 func TestCameraClient_GetDeviceName_CircuitOpen(t *testing.T) {
 	client := createTestCameraClientWithCircuitOpen()
 	ctx := context.Background()
@@ -376,6 +392,15 @@ func TestCameraClient_SetPtzPatrol_CircuitOpen(t *testing.T) {
 	assert.Contains(t, err.Error(), "circuit open")
 }
 
+func TestCameraClient_PTZPatrolControl_CircuitOpen(t *testing.T) {
+	client := createTestCameraClientWithCircuitOpen()
+	ctx := context.Background()
+
+	err := client.PTZPatrolControl(ctx, 0, 1, true)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "circuit open")
+}
+
 func TestCameraClient_GetPtzGuard_CircuitOpen(t *testing.T) {
 	client := createTestCameraClientWithCircuitOpen()
 	ctx := context.Background()
@@ -1326,3 +1351,231 @@ func BenchmarkCameraClient_GetRTSPURL(b *testing.B) {
 		_ = cameraClient.GetRTSPURL(reolink.StreamMain, 0)
 	}
 }
+
+func TestCameraClient_GetSnapshot_UpdatesLastSeen(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("channel") != "0" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write([]byte{0xff, 0xd8, 0xff, 0xd9})
+	}))
+	defer server.Close()
+
+	lastSeen := time.Now().Add(-1 * time.Hour)
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "test-camera", LastSeen: lastSeen},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://")),
+	}
+	ctx := context.Background()
+
+	// Failed operations leave last-seen alone
+	_, err := client.GetSnapshot(ctx, 1)
+	assert.Error(t, err)
+	assert.Equal(t, lastSeen, client.Camera.LastSeen)
+
+	before := time.Now()
+	data, err := client.GetSnapshot(ctx, 0)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, data)
+	assert.False(t, client.Camera.LastSeen.Before(before), "successful snapshot should update last-seen")
+}
+
+func TestCameraClient_LastSeenUsesClientClock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"GetTime","code":0,"value":{"Time":{"year":2026}}}]`))
+	}))
+	defer server.Close()
+
+	fake := clock.NewFake(time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC))
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "test-camera"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+		clock:  fake,
+	}
+
+	_, err := client.GetTime(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, fake.Now(), client.Camera.LastSeen)
+}
+
+func TestCameraClient_ActiveChannels_WithoutChannelStatus(t *testing.T) {
+	queries := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries++
+		w.Write([]byte(`[{"cmd":"Getchannelstatus","code":1,"error":{"rspCode":-9,"detail":"not support"}}]`))
+	}))
+	defer server.Close()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	// Single-channel cameras fall back to channel 0 and are not asked again
+	assert.Equal(t, []int{0}, client.ActiveChannels(context.Background()))
+	assert.Equal(t, []int{0}, client.ActiveChannels(context.Background()))
+	assert.Equal(t, 1, queries)
+}
+
+func TestCameraClient_Call_TypedErrors(t *testing.T) {
+	apiServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"Reboot","code":1,"error":{"rspCode":-6,"detail":"please login first"}}]`))
+	}))
+	defer apiServer.Close()
+
+	// A closed server refuses connections like a camera that is switched off
+	offlineServer := httptest.NewServer(http.NotFoundHandler())
+	offlineServer.Close()
+
+	newClient := func(server *httptest.Server) *CameraClient {
+		return &CameraClient{
+			Camera: &models.Camera{ID: "test-camera"},
+			Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+		}
+	}
+	ctx := context.Background()
+
+	err := createTestCameraClientWithCircuitOpen().Reboot(ctx)
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	assert.NotErrorIs(t, err, ErrCameraOffline)
+
+	err = newClient(offlineServer).Reboot(ctx)
+	assert.ErrorIs(t, err, ErrCameraOffline)
+	assert.Contains(t, err.Error(), "test-camera")
+
+	err = newClient(apiServer).Reboot(ctx)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrCameraOffline)
+	assert.NotErrorIs(t, err, ErrCircuitOpen)
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	err = newClient(apiServer).Reboot(cancelled)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.NotErrorIs(t, err, ErrCameraOffline, "cancelled requests do not mark the camera offline")
+}
+
+func TestCameraClient_PTZPatrolControl(t *testing.T) {
+	tests := []struct {
+		name     string
+		channel  int
+		patrolID int
+		start    bool
+		wantOp   string
+	}{
+		{"start", 0, 1, true, "StartPatrol"},
+		{"stop", 0, 1, false, "StopPatrol"},
+		{"nvr channel", 3, 2, true, "StartPatrol"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var param reolink.PtzCtrlParam
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "PtzCtrl", r.URL.Query().Get("cmd"))
+				var body []struct {
+					Param reolink.PtzCtrlParam `json:"param"`
+				}
+				if assert.NoError(t, json.NewDecoder(r.Body).Decode(&body)) && assert.Len(t, body, 1) {
+					param = body[0].Param
+				}
+				w.Write([]byte(`[{"cmd":"PtzCtrl","code":0,"value":{"rspCode":200}}]`))
+			}))
+			defer server.Close()
+
+			client := &CameraClient{
+				Camera: &models.Camera{ID: "cam-1"},
+				Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+			}
+
+			assert.NoError(t, client.PTZPatrolControl(context.Background(), tt.channel, tt.patrolID, tt.start))
+			assert.Equal(t, reolink.PtzCtrlParam{Channel: tt.channel, Op: tt.wantOp, ID: tt.patrolID}, param)
+		})
+	}
+}
+
+// newSessionTestClient returns a client of a camera whose session expired,
+// and the number of logins it received. The camera accepts a fresh token for
+// operations unless rejectFresh is set.
+func newSessionTestClient(t *testing.T, rejectFresh bool) (*CameraClient, func() int) {
+	var mu sync.Mutex
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch {
+		case r.URL.Query().Get("cmd") == "Login":
+			logins++
+			w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"name":"fresh-token","leaseTime":3600}}}]`))
+		case r.URL.Query().Get("token") == "fresh-token" && !rejectFresh:
+			w.Write([]byte(`[{"cmd":"Reboot","code":0,"value":{"rspCode":200}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"Reboot","code":1,"error":{"rspCode":-6,"detail":"please login first"}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"),
+			reolink.WithCredentials("admin", "password"), reolink.WithToken("expired-token")),
+	}
+	return client, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return logins
+	}
+}
+
+func TestCameraClient_Call_ReloginOnExpiredSession(t *testing.T) {
+	client, logins := newSessionTestClient(t, false)
+
+	assert.NoError(t, client.Reboot(context.Background()))
+	assert.Equal(t, 1, logins())
+	assert.Equal(t, 1, client.Relogins())
+	assert.Equal(t, "fresh-token", client.Client.GetToken())
+}
+
+func TestCameraClient_Call_ReloginRetriesOnce(t *testing.T) {
+	client, logins := newSessionTestClient(t, true)
+
+	err := client.Reboot(context.Background())
+	var apiErr *reolink.APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, reolink.ErrCodeLoginRequired, apiErr.RspCode)
+	}
+	assert.NotErrorIs(t, err, ErrCameraOffline)
+	assert.Equal(t, 1, logins(), "the operation is retried once after a single login")
+}
+
+func TestCameraClient_Call_ConcurrentExpiredSessionsShareLogin(t *testing.T) {
+	client, logins := newSessionTestClient(t, false)
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, client.Reboot(context.Background()))
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, logins())
+	assert.Equal(t, 1, client.Relogins())
+}
+
+func TestCameraClient_Call_UsesManagerLogin(t *testing.T) {
+	client, _ := newSessionTestClient(t, false)
+	managerLogins := 0
+	client.login = func(ctx context.Context) error {
+		managerLogins++
+		return client.Client.Login(ctx)
+	}
+
+	assert.NoError(t, client.Reboot(context.Background()))
+	assert.Equal(t, 1, managerLogins)
+}
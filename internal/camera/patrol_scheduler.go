@@ -0,0 +1,156 @@
+package camera
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// patrolCheckInterval is how often the patrol scheduler looks for patrols to start or stop
+const patrolCheckInterval = 30 * time.Second
+
+// patrolState is the patrol command last sent to a camera by the scheduler
+type patrolState struct {
+	schedule string
+	patrolID int
+	channel  int
+	running  bool
+}
+
+// patrolScheduler starts and stops the PTZ patrols of cameras with a patrol schedule
+type patrolScheduler struct {
+	manager *Manager
+
+	// sent holds the patrol state of each camera as of its latest successful command
+	sent map[string]patrolState
+	mu   sync.Mutex
+}
+
+// StartPatrolScheduler starts the PTZ patrol of every camera with a patrol
+// schedule when its daily window opens and stops it when the window closes.
+// Failed commands and cameras whose circuit is open are retried on later
+// checks. A camera first seen outside its window is left alone, so a patrol
+// started by hand is not stopped when the server starts.
+func (m *Manager) StartPatrolScheduler(ctx context.Context) {
+	scheduler := &patrolScheduler{
+		manager: m,
+		sent:    make(map[string]patrolState),
+	}
+
+	ticker := time.NewTicker(patrolCheckInterval)
+	defer ticker.Stop()
+
+	logger.Info("Patrol scheduler started",
+		zap.Duration("interval", patrolCheckInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Patrol scheduler stopped")
+			return
+		case now := <-ticker.C:
+			scheduler.run(ctx, now)
+		}
+	}
+}
+
+// run starts or stops every patrol whose window opened or closed by now and
+// returns the IDs of the cameras it sent a patrol command to
+func (s *patrolScheduler) run(ctx context.Context, now time.Time) []string {
+	s.manager.mu.RLock()
+	cameras := make([]*CameraClient, 0, len(s.manager.cameras))
+	for _, client := range s.manager.cameras {
+		cameras = append(cameras, client)
+	}
+	s.manager.mu.RUnlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	present := make(map[string]bool, len(cameras))
+	for _, client := range cameras {
+		present[client.Camera.ID] = true
+	}
+	for cameraID := range s.sent {
+		if !present[cameraID] {
+			delete(s.sent, cameraID)
+		}
+	}
+
+	var controlled []string
+	for _, client := range cameras {
+		cameraID := client.Camera.ID
+
+		client.mu.RLock()
+		want := patrolState{
+			schedule: client.Camera.PatrolSchedule,
+			patrolID: client.Camera.PatrolID,
+			channel:  client.Camera.PatrolChannel,
+		}
+		circuitOpen := client.CircuitOpen
+		client.mu.RUnlock()
+
+		if want.schedule == "" {
+			delete(s.sent, cameraID)
+			continue
+		}
+		start, stop, err := models.ParsePatrolSchedule(want.schedule)
+		if err != nil {
+			continue
+		}
+		want.running = patrolActive(start, stop, now)
+
+		last, seen := s.sent[cameraID]
+		if last == want {
+			continue
+		}
+		if !seen && !want.running {
+			s.sent[cameraID] = want
+			continue
+		}
+		if circuitOpen {
+			continue
+		}
+
+		controlCtx, cancel := context.WithTimeout(ctx, s.manager.config.ConnectionTimeout)
+		err = client.PTZPatrolControl(controlCtx, want.channel, want.patrolID, want.running)
+		cancel()
+		controlled = append(controlled, cameraID)
+
+		if err != nil {
+			logger.Error("Scheduled patrol command failed",
+				zap.String("camera_id", cameraID),
+				zap.Bool("start", want.running),
+				zap.Error(err))
+			continue
+		}
+
+		s.sent[cameraID] = want
+		logger.Info("Scheduled patrol command sent",
+			zap.String("camera_id", cameraID),
+			zap.Bool("start", want.running),
+			zap.Int("patrol_id", want.patrolID),
+			zap.String("patrol_schedule", want.schedule))
+	}
+
+	return controlled
+}
+
+// patrolActive reports whether now falls within the daily window from start
+// to stop, which runs over midnight when stop is before start
+func patrolActive(start, stop, now time.Time) bool {
+	minute := now.Hour()*60 + now.Minute()
+	from := start.Hour()*60 + start.Minute()
+	to := stop.Hour()*60 + stop.Minute()
+
+	if from < to {
+		return minute >= from && minute < to
+	}
+	return minute >= from || minute < to
+}
@@ -0,0 +1,145 @@
+package camera
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newPatrolTestScheduler returns a scheduler for a manager with one camera
+// patrolling from 08:00 to 18:00, and the PtzCtrl ops the camera received
+func newPatrolTestScheduler(t *testing.T) (*patrolScheduler, *CameraClient, func() []string) {
+	var mu sync.Mutex
+	var ops []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body []struct {
+			Param reolink.PtzCtrlParam `json:"param"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		for _, cmd := range body {
+			ops = append(ops, cmd.Param.Op)
+		}
+		mu.Unlock()
+		w.Write([]byte(`[{"cmd":"PtzCtrl","code":0,"value":{"rspCode":200}}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1", PatrolSchedule: "08:00-18:00", PatrolID: 1},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.cameras["cam-1"] = client
+
+	scheduler := &patrolScheduler{manager: m, sent: make(map[string]patrolState)}
+	sentOps := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), ops...)
+	}
+	return scheduler, client, sentOps
+}
+
+// patrolTime returns hour:minute of a fixed day in local time
+func patrolTime(hour, minute int) time.Time {
+	return time.Date(2025, 10, 27, hour, minute, 0, 0, time.Local)
+}
+
+func TestPatrolActive(t *testing.T) {
+	tests := []struct {
+		schedule string
+		now      time.Time
+		want     bool
+	}{
+		{"08:00-18:00", patrolTime(7, 59), false},
+		{"08:00-18:00", patrolTime(8, 0), true},
+		{"08:00-18:00", patrolTime(17, 59), true},
+		{"08:00-18:00", patrolTime(18, 0), false},
+		{"22:00-06:00", patrolTime(23, 0), true},
+		{"22:00-06:00", patrolTime(5, 59), true},
+		{"22:00-06:00", patrolTime(6, 0), false},
+		{"22:00-06:00", patrolTime(12, 0), false},
+	}
+
+	for _, tt := range tests {
+		start, stop, err := models.ParsePatrolSchedule(tt.schedule)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, patrolActive(start, stop, tt.now), "%s at %s", tt.schedule, tt.now.Format("15:04"))
+	}
+}
+
+func TestPatrolScheduler_Run_StartsAndStopsWithWindow(t *testing.T) {
+	scheduler, _, ops := newPatrolTestScheduler(t)
+	ctx := context.Background()
+
+	assert.Empty(t, scheduler.run(ctx, patrolTime(7, 0)), "a camera first seen outside its window is left alone")
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(ctx, patrolTime(8, 0)))
+	assert.Empty(t, scheduler.run(ctx, patrolTime(12, 0)), "a running patrol is not started again")
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(ctx, patrolTime(18, 0)))
+	assert.Empty(t, scheduler.run(ctx, patrolTime(19, 0)))
+
+	assert.Equal(t, []string{"StartPatrol", "StopPatrol"}, ops())
+}
+
+func TestPatrolScheduler_Run_StartsInsideWindow(t *testing.T) {
+	scheduler, _, ops := newPatrolTestScheduler(t)
+
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(context.Background(), patrolTime(12, 0)),
+		"the patrol is started when the server comes up during the window")
+	assert.Equal(t, []string{"StartPatrol"}, ops())
+}
+
+func TestPatrolScheduler_Run_RestartsChangedPatrol(t *testing.T) {
+	scheduler, client, ops := newPatrolTestScheduler(t)
+	ctx := context.Background()
+
+	scheduler.run(ctx, patrolTime(9, 0))
+	client.Camera.PatrolID = 2
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(ctx, patrolTime(9, 1)))
+
+	assert.Equal(t, []string{"StartPatrol", "StartPatrol"}, ops())
+}
+
+func TestPatrolScheduler_Run_SkipsCameras(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(client *CameraClient)
+	}{
+		{"no schedule", func(client *CameraClient) { client.Camera.PatrolSchedule = "" }},
+		{"invalid schedule", func(client *CameraClient) { client.Camera.PatrolSchedule = "8-18" }},
+		{"circuit open", func(client *CameraClient) { client.CircuitOpen = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, client, ops := newPatrolTestScheduler(t)
+			tt.setup(client)
+
+			assert.Empty(t, scheduler.run(context.Background(), patrolTime(12, 0)))
+			assert.Empty(t, ops())
+		})
+	}
+}
+
+func TestPatrolScheduler_Run_RetriesAfterCircuitCloses(t *testing.T) {
+	scheduler, client, ops := newPatrolTestScheduler(t)
+	ctx := context.Background()
+
+	client.CircuitOpen = true
+	assert.Empty(t, scheduler.run(ctx, patrolTime(12, 0)))
+
+	client.CircuitOpen = false
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(ctx, patrolTime(12, 1)))
+	assert.Equal(t, []string{"StartPatrol"}, ops())
+}
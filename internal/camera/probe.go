@@ -0,0 +1,231 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// Probe layers, checked in order
+const (
+	ProbeLayerTCP  = "tcp"
+	ProbeLayerHTTP = "http"
+	ProbeLayerAuth = "auth"
+)
+
+// ProbeStep checks a single connectivity layer of a camera
+type ProbeStep struct {
+	Layer string
+	Run   func(ctx context.Context, camera *models.Camera) error
+}
+
+// ProbeStepResult is the outcome of one probe layer
+type ProbeStepResult struct {
+	Layer    string        `json:"layer"`
+	Status   string        `json:"status"` // ok, failed, skipped
+	Duration time.Duration `json:"duration_ns"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// ProbeResult reports how far a camera could be reached
+type ProbeResult struct {
+	CameraID    string            `json:"camera_id"`
+	Reachable   bool              `json:"reachable"`
+	FailedLayer string            `json:"failed_layer,omitempty"`
+	Steps       []ProbeStepResult `json:"steps"`
+}
+
+// Probe checks the reachability of a managed camera layer by layer
+func (m *Manager) Probe(ctx context.Context, cameraID string) (*ProbeResult, error) {
+	client, err := m.GetCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.ProbeCamera(ctx, client.Camera), nil
+}
+
+// ProbeCamera checks TCP connectivity, the HTTP API and authentication of a
+// camera in that order. Once a layer fails, the layers above it are skipped.
+func (m *Manager) ProbeCamera(ctx context.Context, camera *models.Camera) *ProbeResult {
+	steps := m.probeSteps
+	if steps == nil {
+		steps = m.defaultProbeSteps()
+	}
+
+	result := &ProbeResult{CameraID: camera.ID, Reachable: true}
+	for _, step := range steps {
+		if result.FailedLayer != "" {
+			result.Steps = append(result.Steps, ProbeStepResult{Layer: step.Layer, Status: "skipped"})
+			continue
+		}
+
+		stepCtx, cancel := context.WithTimeout(ctx, m.config.ConnectionTimeout)
+		start := time.Now()
+		err := step.Run(stepCtx, camera)
+		cancel()
+
+		stepResult := ProbeStepResult{Layer: step.Layer, Status: "ok", Duration: time.Since(start)}
+		if err != nil {
+			stepResult.Status = "failed"
+			stepResult.Error = err.Error()
+			result.Reachable = false
+			result.FailedLayer = step.Layer
+		}
+		result.Steps = append(result.Steps, stepResult)
+	}
+
+	return result
+}
+
+// defaultProbeSteps returns the TCP, HTTP and auth checks against the real camera
+func (m *Manager) defaultProbeSteps() []ProbeStep {
+	return []ProbeStep{
+		{Layer: ProbeLayerTCP, Run: m.probeTCP},
+		{Layer: ProbeLayerHTTP, Run: m.probeHTTP},
+		{Layer: ProbeLayerAuth, Run: m.probeAuth},
+	}
+}
+
+// cameraAddress returns the host:port the camera API listens on
+func cameraAddress(camera *models.Camera) string {
+	port := camera.Port
+	if port <= 0 {
+		port = 80
+		if camera.UseHTTPS {
+			port = 443
+		}
+	}
+	return net.JoinHostPort(camera.Host, strconv.Itoa(port))
+}
+
+// probeTCP checks that the camera accepts TCP connections
+func (m *Manager) probeTCP(ctx context.Context, camera *models.Camera) error {
+	conn, err := m.hostPolicy.Dialer(0).DialContext(ctx, "tcp", cameraAddress(camera))
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// probeHTTP checks that the camera answers HTTP requests on its API endpoint.
+// Any response counts, since unauthenticated requests are expected to be rejected.
+func (m *Manager) probeHTTP(ctx context.Context, camera *models.Camera) error {
+	scheme := "http"
+	if camera.UseHTTPS {
+		scheme = "https"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, scheme+"://"+cameraAddress(camera)+"/cgi-bin/api.cgi", nil)
+	if err != nil {
+		return err
+	}
+
+	client := &http.Client{Transport: m.hostPolicy.Transport(camera.SkipVerify)}
+	defer client.CloseIdleConnections()
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("camera API returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// probeAuth logs in with the camera's credentials on a fresh session
+func (m *Manager) probeAuth(ctx context.Context, camera *models.Camera) error {
+	client, err := m.createClient(camera, m.resolveProfile(camera))
+	if err != nil {
+		return err
+	}
+
+	if err := client.Login(ctx); err != nil {
+		return err
+	}
+	_ = client.Logout(ctx)
+	return nil
+}
+
+// ConnectionTestTimeout bounds a connection test as a whole, so a camera that
+// does not answer fails the test quickly instead of holding the request
+const ConnectionTestTimeout = 10 * time.Second
+
+// ConnectionTestResult reports whether a camera could be logged into with the
+// given settings and what it reported about itself
+type ConnectionTestResult struct {
+	Reachable       bool          `json:"reachable"`
+	Authenticated   bool          `json:"authenticated"`
+	Model           string        `json:"model,omitempty"`
+	FirmwareVersion string        `json:"firmware_version,omitempty"`
+	HardwareVersion string        `json:"hardware_version,omitempty"`
+	ChannelCount    int           `json:"channel_count,omitempty"`
+	Duration        time.Duration `json:"duration_ns"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// TestConnection logs into a camera that is not managed and reads its device
+// info, using a one-off client that is logged out afterwards. Nothing is added
+// to the manager. Cameras rejecting the login are reachable but not
+// authenticated.
+func (m *Manager) TestConnection(ctx context.Context, camera *models.Camera) *ConnectionTestResult {
+	ctx, cancel := context.WithTimeout(ctx, ConnectionTestTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result := &ConnectionTestResult{}
+	defer func() { result.Duration = time.Since(start) }()
+
+	newClient := m.testClient
+	if newClient == nil {
+		newClient = m.newTestClient
+	}
+	client, err := newClient(camera)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if err := client.Login(ctx); err != nil {
+		// An API error means the camera answered but refused the credentials
+		var apiErr *reolink.APIError
+		result.Reachable = errors.As(err, &apiErr)
+		result.Error = err.Error()
+		return result
+	}
+	defer client.Logout(context.WithoutCancel(ctx))
+	result.Reachable = true
+	result.Authenticated = true
+
+	info, err := client.System.GetDeviceInfo(ctx)
+	if err != nil {
+		result.Error = fmt.Sprintf("failed to get device info: %v", err)
+		return result
+	}
+	result.Model = info.Model
+	result.FirmwareVersion = info.FirmVer
+	result.HardwareVersion = info.HardVer
+	result.ChannelCount = info.ChannelNum
+	return result
+}
+
+// newTestClient builds the client of a connection test from the camera's
+// connection profile, with requests timing out no later than the test
+func (m *Manager) newTestClient(camera *models.Camera) (*reolink.Client, error) {
+	profile := m.resolveProfile(camera)
+	if profile.Timeout <= 0 || profile.Timeout > ConnectionTestTimeout {
+		profile.Timeout = ConnectionTestTimeout
+	}
+	return m.createClient(camera, profile)
+}
@@ -0,0 +1,216 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// stubProbeSteps returns probe steps failing at the given layer ("" for none)
+func stubProbeSteps(failAt string) []ProbeStep {
+	steps := make([]ProbeStep, 0, 3)
+	for _, layer := range []string{ProbeLayerTCP, ProbeLayerHTTP, ProbeLayerAuth} {
+		layer := layer
+		steps = append(steps, ProbeStep{Layer: layer, Run: func(ctx context.Context, camera *models.Camera) error {
+			if layer == failAt {
+				return errors.New(layer + " failure")
+			}
+			return nil
+		}})
+	}
+	return steps
+}
+
+func TestManager_ProbeCamera_Layers(t *testing.T) {
+	tests := []struct {
+		failAt   string
+		statuses []string
+	}{
+		{"", []string{"ok", "ok", "ok"}},
+		{ProbeLayerTCP, []string{"failed", "skipped", "skipped"}},
+		{ProbeLayerHTTP, []string{"ok", "failed", "skipped"}},
+		{ProbeLayerAuth, []string{"ok", "ok", "failed"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("fail at "+tt.failAt, func(t *testing.T) {
+			m := NewManager(nil, nil)
+			m.probeSteps = stubProbeSteps(tt.failAt)
+
+			result := m.ProbeCamera(context.Background(), &models.Camera{ID: "cam-1"})
+
+			assert.Equal(t, "cam-1", result.CameraID)
+			assert.Equal(t, tt.failAt == "", result.Reachable)
+			assert.Equal(t, tt.failAt, result.FailedLayer)
+			require.Len(t, result.Steps, 3)
+			for i, step := range result.Steps {
+				assert.Equal(t, tt.statuses[i], step.Status, step.Layer)
+				if step.Status == "failed" {
+					assert.Equal(t, tt.failAt+" failure", step.Error)
+				}
+			}
+		})
+	}
+}
+
+func TestManager_Probe_UnknownCamera(t *testing.T) {
+	m := NewManager(nil, nil)
+	_, err := m.Probe(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+// cameraAt returns a camera pointing at addr
+func cameraAt(t *testing.T, addr string) *models.Camera {
+	host, portStr, err := net.SplitHostPort(addr)
+	require.NoError(t, err)
+	port, _ := strconv.Atoi(portStr)
+	return &models.Camera{ID: "cam-1", Host: host, Port: port, Username: "admin", Password: "password"}
+}
+
+func TestManager_ProbeCamera_DefaultSteps(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	t.Run("reachable", func(t *testing.T) {
+		server := newFakeCameraServer(t)
+		result := m.ProbeCamera(context.Background(), cameraAt(t, server.Listener.Addr().String()))
+		assert.True(t, result.Reachable, "%+v", result.Steps)
+	})
+
+	t.Run("tcp unreachable", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		result := m.ProbeCamera(context.Background(), cameraAt(t, addr))
+		assert.Equal(t, ProbeLayerTCP, result.FailedLayer)
+	})
+
+	t.Run("not speaking http", func(t *testing.T) {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		defer ln.Close()
+		go func() {
+			for {
+				conn, err := ln.Accept()
+				if err != nil {
+					return
+				}
+				conn.Close()
+			}
+		}()
+
+		result := m.ProbeCamera(context.Background(), cameraAt(t, ln.Addr().String()))
+		assert.Equal(t, ProbeLayerHTTP, result.FailedLayer)
+	})
+
+	t.Run("auth rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"cmd":"Login","code":1,"error":{"rspCode":-7,"detail":"login failed"}}]`))
+		}))
+		defer server.Close()
+
+		result := m.ProbeCamera(context.Background(), cameraAt(t, server.Listener.Addr().String()))
+		assert.Equal(t, ProbeLayerAuth, result.FailedLayer)
+	})
+}
+
+func TestManager_ProbeCamera_SkipVerify(t *testing.T) {
+	m := NewManager(nil, nil)
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"leaseTime":3600,"name":"test-token"}}}]`))
+	}))
+	defer server.Close()
+
+	cam := cameraAt(t, server.Listener.Addr().String())
+	cam.UseHTTPS = true
+
+	result := m.ProbeCamera(context.Background(), cam)
+	assert.Equal(t, ProbeLayerHTTP, result.FailedLayer, "self-signed certificate must be rejected")
+
+	cam.SkipVerify = true
+	result = m.ProbeCamera(context.Background(), cam)
+	assert.True(t, result.Reachable, "%+v", result.Steps)
+}
+
+func TestManager_TestConnection(t *testing.T) {
+	var loggedOut bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch cmd := r.URL.Query().Get("cmd"); cmd {
+		case "Login":
+			w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"leaseTime":3600,"name":"test-token"}}}]`))
+		case "GetDevInfo":
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":` +
+				`{"model":"RLC-810A","firmVer":"v3.1.0.956","hardVer":"IPC_523128M8MP","channelNum":1}}}]`))
+		case "Logout":
+			loggedOut = true
+			w.Write([]byte(`[{"cmd":"Logout","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	defer server.Close()
+
+	m := NewManager(nil, nil)
+	var built *models.Camera
+	m.testClient = func(camera *models.Camera) (*reolink.Client, error) {
+		built = camera
+		return reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithCredentials(camera.Username, camera.Password)), nil
+	}
+
+	cam := &models.Camera{Host: "192.168.1.50", Port: 80, Username: "admin", Password: "secret"}
+	result := m.TestConnection(context.Background(), cam)
+
+	assert.Same(t, cam, built)
+	assert.True(t, result.Reachable)
+	assert.True(t, result.Authenticated)
+	assert.Equal(t, "RLC-810A", result.Model)
+	assert.Equal(t, "v3.1.0.956", result.FirmwareVersion)
+	assert.Equal(t, "IPC_523128M8MP", result.HardwareVersion)
+	assert.Equal(t, 1, result.ChannelCount)
+	assert.Empty(t, result.Error)
+	assert.True(t, loggedOut, "the test session is logged out")
+	assert.Empty(t, m.ListCameras(), "tested cameras are not added")
+}
+
+func TestManager_TestConnection_Failures(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	t.Run("auth rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`[{"cmd":"Login","code":1,"error":{"rspCode":-7,"detail":"login failed"}}]`))
+		}))
+		defer server.Close()
+		m.testClient = func(camera *models.Camera) (*reolink.Client, error) {
+			return reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithCredentials(camera.Username, camera.Password)), nil
+		}
+
+		result := m.TestConnection(context.Background(), &models.Camera{Host: "192.168.1.50", Username: "admin", Password: "wrong"})
+		assert.True(t, result.Reachable)
+		assert.False(t, result.Authenticated)
+		assert.Empty(t, result.Model)
+		assert.Contains(t, result.Error, "login failed")
+	})
+
+	t.Run("unreachable", func(t *testing.T) {
+		m.testClient = nil
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := ln.Addr().String()
+		ln.Close()
+
+		result := m.TestConnection(context.Background(), cameraAt(t, addr))
+		assert.False(t, result.Reachable)
+		assert.False(t, result.Authenticated)
+		assert.NotEmpty(t, result.Error)
+	})
+}
@@ -0,0 +1,284 @@
+package camera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+const (
+	// rebootCheckInterval is how often the scheduler looks for due reboots
+	rebootCheckInterval = 30 * time.Second
+
+	// rebootWindow is how long after its scheduled time a reboot may still run,
+	// so a reboot postponed by an active stream is retried on later checks
+	rebootWindow = 15 * time.Minute
+
+	// DefaultRebootWaitTimeout is how long a rebooted camera gets to come back online
+	DefaultRebootWaitTimeout = 5 * time.Minute
+
+	// rebootPollInterval is how often a rebooting camera is polled
+	rebootPollInterval = 5 * time.Second
+
+	// rebootSettleDelay is how long after the reboot command a camera that
+	// never stopped answering is taken to be back, since cameras keep
+	// answering for a moment before they go down
+	rebootSettleDelay = 30 * time.Second
+)
+
+// Reboot wait states
+const (
+	RebootStateWaiting = "waiting"
+	RebootStateOnline  = "online"
+	RebootStateTimeout = "timeout"
+)
+
+// ErrRebootInProgress is returned when a camera is already being waited on after a reboot
+var ErrRebootInProgress = errors.New("camera reboot already in progress")
+
+// RebootStatus is the outcome of a reboot-and-wait
+type RebootStatus struct {
+	CameraID   string     `json:"camera_id"`
+	State      string     `json:"state"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	Timeout    string     `json:"timeout"`
+}
+
+// rebootPollTiming controls how a rebooting camera is polled
+type rebootPollTiming struct {
+	interval    time.Duration
+	settleDelay time.Duration
+}
+
+// StartRebootScheduler reboots cameras daily at their configured reboot time.
+// Cameras for which streaming reports an active stream are skipped until the
+// stream ends or the reboot window passes; streaming may be nil.
+func (m *Manager) StartRebootScheduler(ctx context.Context, streaming func(cameraID string) bool) {
+	ticker := time.NewTicker(rebootCheckInterval)
+	defer ticker.Stop()
+
+	logger.Info("Reboot scheduler started",
+		zap.Duration("interval", rebootCheckInterval),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Reboot scheduler stopped")
+			return
+		case now := <-ticker.C:
+			m.runScheduledReboots(ctx, now, streaming)
+		}
+	}
+}
+
+// runScheduledReboots reboots every camera whose reboot is due at now and
+// returns the IDs of the cameras it attempted to reboot
+func (m *Manager) runScheduledReboots(ctx context.Context, now time.Time, streaming func(cameraID string) bool) []string {
+	m.mu.RLock()
+	cameras := make([]*CameraClient, 0, len(m.cameras))
+	for _, client := range m.cameras {
+		cameras = append(cameras, client)
+	}
+	m.mu.RUnlock()
+
+	var rebooted []string
+	for _, client := range cameras {
+		cameraID := client.Camera.ID
+		scheduled, ok := rebootDue(client.Camera.RebootTime, now)
+		if !ok {
+			continue
+		}
+
+		day := scheduled.Format("2006-01-02")
+		m.rebootMu.Lock()
+		done := m.rebootedOn[cameraID] == day
+		m.rebootMu.Unlock()
+		if done {
+			continue
+		}
+
+		if streaming != nil && streaming(cameraID) {
+			logger.Info("Postponing scheduled reboot, camera is streaming",
+				zap.String("camera_id", cameraID),
+				zap.String("reboot_time", client.Camera.RebootTime))
+			continue
+		}
+
+		// Record the attempt first so a failing camera is not rebooted again on every check
+		m.rebootMu.Lock()
+		m.rebootedOn[cameraID] = day
+		m.rebootMu.Unlock()
+
+		rebootCtx, cancel := context.WithTimeout(ctx, m.config.ConnectionTimeout)
+		err := client.Reboot(rebootCtx)
+		cancel()
+
+		if err != nil {
+			logger.Error("Scheduled reboot failed",
+				zap.String("camera_id", cameraID),
+				zap.Error(err))
+		} else {
+			logger.Info("Scheduled reboot triggered",
+				zap.String("camera_id", cameraID),
+				zap.String("reboot_time", client.Camera.RebootTime))
+		}
+		rebooted = append(rebooted, cameraID)
+	}
+
+	return rebooted
+}
+
+// rebootDue returns the most recent scheduled reboot at or before now and
+// whether it is still within the reboot window. An empty or invalid reboot
+// time is never due.
+func rebootDue(rebootTime string, now time.Time) (time.Time, bool) {
+	if rebootTime == "" {
+		return time.Time{}, false
+	}
+	clock, err := time.Parse("15:04", rebootTime)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	scheduled := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, now.Location())
+	if scheduled.After(now) {
+		scheduled = scheduled.AddDate(0, 0, -1)
+	}
+
+	return scheduled, now.Sub(scheduled) < rebootWindow
+}
+
+// RebootAndWait reboots a camera and then waits in the background until it
+// answers again or timeout passes. The reboot command itself is sent before
+// returning; the outcome of the wait is reported by RebootStatus.
+func (m *Manager) RebootAndWait(ctx context.Context, cameraID string, timeout time.Duration) (*RebootStatus, error) {
+	client, err := m.GetCamera(cameraID)
+	if err != nil {
+		return nil, err
+	}
+	if timeout <= 0 {
+		timeout = DefaultRebootWaitTimeout
+	}
+
+	m.rebootMu.Lock()
+	if current, ok := m.rebootWaits[cameraID]; ok && current.State == RebootStateWaiting {
+		m.rebootMu.Unlock()
+		return nil, ErrRebootInProgress
+	}
+	status := &RebootStatus{
+		CameraID:  cameraID,
+		State:     RebootStateWaiting,
+		StartedAt: time.Now(),
+		Timeout:   timeout.String(),
+	}
+	m.rebootWaits[cameraID] = status
+	m.rebootMu.Unlock()
+
+	err = client.Reboot(ctx)
+	if err != nil {
+		m.rebootMu.Lock()
+		delete(m.rebootWaits, cameraID)
+		m.rebootMu.Unlock()
+		return nil, fmt.Errorf("failed to reboot camera: %w", err)
+	}
+
+	logger.Info("Camera rebooted, waiting for it to come back online",
+		zap.String("camera_id", cameraID),
+		zap.Duration("timeout", timeout))
+
+	// The wait outlives the request that started it
+	waitCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	go func() {
+		defer cancel()
+		m.waitForOnline(waitCtx, client, status)
+	}()
+
+	return m.RebootStatus(cameraID)
+}
+
+// RebootStatus returns the latest reboot-and-wait of a camera
+func (m *Manager) RebootStatus(cameraID string) (*RebootStatus, error) {
+	m.rebootMu.Lock()
+	defer m.rebootMu.Unlock()
+
+	status, ok := m.rebootWaits[cameraID]
+	if !ok {
+		return nil, fmt.Errorf("no reboot recorded for camera %s", cameraID)
+	}
+	copied := *status
+	return &copied, nil
+}
+
+// waitForOnline polls a rebooted camera until it answers again, once it has
+// gone down or the settle delay has passed, and records the outcome
+func (m *Manager) waitForOnline(ctx context.Context, client *CameraClient, status *RebootStatus) {
+	timing := rebootPollTiming{interval: rebootPollInterval, settleDelay: rebootSettleDelay}
+	if m.rebootPoll != nil {
+		timing = *m.rebootPoll
+	}
+
+	ticker := time.NewTicker(timing.interval)
+	defer ticker.Stop()
+
+	wentDown := false
+	for {
+		select {
+		case <-ctx.Done():
+			m.finishRebootWait(status, RebootStateTimeout)
+			logger.Warn("Camera did not come back online after reboot",
+				zap.String("camera_id", status.CameraID),
+				zap.String("timeout", status.Timeout))
+			return
+		case <-ticker.C:
+		}
+
+		if !m.answers(ctx, client) {
+			wentDown = true
+			continue
+		}
+		if !wentDown && time.Since(status.StartedAt) < timing.settleDelay {
+			continue
+		}
+
+		// Refresh status and close the circuit opened while the camera was down
+		m.checkCameraHealth(ctx, client)
+		m.finishRebootWait(status, RebootStateOnline)
+		logger.Info("Camera back online after reboot",
+			zap.String("camera_id", status.CameraID),
+			zap.Duration("downtime", time.Since(status.StartedAt)))
+		return
+	}
+}
+
+// answers reports whether a camera responds to a device info request,
+// logging in again first if its session did not survive the reboot
+func (m *Manager) answers(ctx context.Context, client *CameraClient) bool {
+	pollCtx, cancel := context.WithTimeout(ctx, m.config.ConnectionTimeout)
+	defer cancel()
+
+	if _, err := client.Client.System.GetDeviceInfo(pollCtx); err == nil {
+		return true
+	}
+	if err := m.login(pollCtx, client.Camera, client.Client); err != nil {
+		return false
+	}
+	_, err := client.Client.System.GetDeviceInfo(pollCtx)
+	return err == nil
+}
+
+// finishRebootWait records the final state of a reboot-and-wait
+func (m *Manager) finishRebootWait(status *RebootStatus, state string) {
+	now := time.Now()
+
+	m.rebootMu.Lock()
+	status.State = state
+	status.FinishedAt = &now
+	m.rebootMu.Unlock()
+}
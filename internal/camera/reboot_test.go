@@ -0,0 +1,194 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newRebootTestManager returns a manager with one camera rebooting at rebootTime
+// and a counter of the reboot commands the camera received
+func newRebootTestManager(t *testing.T, rebootTime string) (*Manager, func() int) {
+	var mu sync.Mutex
+	reboots := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		if cmd == "Reboot" {
+			mu.Lock()
+			reboots++
+			mu.Unlock()
+		}
+		w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.cameras["cam-1"] = &CameraClient{
+		Camera: &models.Camera{ID: "cam-1", RebootTime: rebootTime},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	return m, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return reboots
+	}
+}
+
+func TestManager_RunScheduledReboots_FiresAtConfiguredTime(t *testing.T) {
+	m, reboots := newRebootTestManager(t, "03:30")
+	ctx := context.Background()
+	day := time.Date(2025, 10, 27, 0, 0, 0, 0, time.Local)
+
+	// Before the reboot time nothing happens
+	assert.Empty(t, m.runScheduledReboots(ctx, day.Add(3*time.Hour+29*time.Minute), nil))
+	assert.Equal(t, 0, reboots())
+
+	// At the reboot time the camera is rebooted once
+	assert.Equal(t, []string{"cam-1"}, m.runScheduledReboots(ctx, day.Add(3*time.Hour+30*time.Minute), nil))
+	assert.Empty(t, m.runScheduledReboots(ctx, day.Add(3*time.Hour+31*time.Minute), nil))
+	assert.Equal(t, 1, reboots())
+
+	// The next day it is rebooted again
+	assert.Equal(t, []string{"cam-1"}, m.runScheduledReboots(ctx, day.AddDate(0, 0, 1).Add(3*time.Hour+30*time.Minute), nil))
+	assert.Equal(t, 2, reboots())
+}
+
+func TestManager_RunScheduledReboots_SkipsStreamingCamera(t *testing.T) {
+	m, reboots := newRebootTestManager(t, "03:30")
+	ctx := context.Background()
+	at := time.Date(2025, 10, 27, 3, 30, 0, 0, time.Local)
+
+	streaming := true
+	isStreaming := func(cameraID string) bool { return streaming }
+
+	assert.Empty(t, m.runScheduledReboots(ctx, at, isStreaming))
+	assert.Equal(t, 0, reboots())
+
+	// Once the stream ends the postponed reboot runs within the window
+	streaming = false
+	assert.Equal(t, []string{"cam-1"}, m.runScheduledReboots(ctx, at.Add(5*time.Minute), isStreaming))
+	assert.Equal(t, 1, reboots())
+}
+
+func TestManager_RunScheduledReboots_MissedWindow(t *testing.T) {
+	m, reboots := newRebootTestManager(t, "03:30")
+
+	assert.Empty(t, m.runScheduledReboots(context.Background(), time.Date(2025, 10, 27, 4, 0, 0, 0, time.Local), nil))
+	assert.Equal(t, 0, reboots())
+}
+
+func TestRebootDue(t *testing.T) {
+	now := time.Date(2025, 10, 27, 0, 5, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		rebootTime string
+		wantDue    bool
+		wantDay    int
+	}{
+		{"disabled", "", false, 0},
+		{"invalid", "3am", false, 0},
+		{"window spanning midnight", "23:55", true, 26},
+		{"just started", "00:05", true, 27},
+		{"later today", "00:06", false, 26},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduled, due := rebootDue(tt.rebootTime, now)
+			assert.Equal(t, tt.wantDue, due)
+			if tt.wantDay != 0 {
+				assert.Equal(t, tt.wantDay, scheduled.Day())
+			}
+		})
+	}
+}
+
+// newRebootWaitTestManager returns a manager with one camera that stops
+// answering for downtime after a reboot command, or for good if downtime is negative
+func newRebootWaitTestManager(t *testing.T, downtime time.Duration) *Manager {
+	var mu sync.Mutex
+	var rebootedAt time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cmd := r.URL.Query().Get("cmd")
+		mu.Lock()
+		if cmd == "Reboot" {
+			rebootedAt = time.Now()
+		}
+		down := !rebootedAt.IsZero() && cmd != "Reboot" && (downtime < 0 || time.Since(rebootedAt) < downtime)
+		mu.Unlock()
+
+		if down {
+			http.Error(w, "rebooting", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+	}))
+	t.Cleanup(server.Close)
+
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.rebootPoll = &rebootPollTiming{interval: 20 * time.Millisecond, settleDelay: time.Minute}
+	m.cameras["cam-1"] = &CameraClient{
+		Camera: &models.Camera{ID: "cam-1", Status: "online"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	return m
+}
+
+func TestManager_RebootAndWait_ReturnsOnline(t *testing.T) {
+	m := newRebootWaitTestManager(t, 200*time.Millisecond)
+
+	status, err := m.RebootAndWait(context.Background(), "cam-1", 5*time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, RebootStateWaiting, status.State)
+	assert.Nil(t, status.FinishedAt)
+
+	// A second reboot is refused while waiting
+	_, err = m.RebootAndWait(context.Background(), "cam-1", 5*time.Second)
+	assert.ErrorIs(t, err, ErrRebootInProgress)
+
+	assert.Eventually(t, func() bool {
+		status, err := m.RebootStatus("cam-1")
+		return err == nil && status.State == RebootStateOnline
+	}, 3*time.Second, 20*time.Millisecond)
+
+	status, _ = m.RebootStatus("cam-1")
+	assert.NotNil(t, status.FinishedAt)
+	assert.GreaterOrEqual(t, status.FinishedAt.Sub(status.StartedAt), 200*time.Millisecond)
+	assert.Equal(t, "online", m.cameras["cam-1"].Camera.Status)
+}
+
+func TestManager_RebootAndWait_Timeout(t *testing.T) {
+	m := newRebootWaitTestManager(t, -1)
+
+	_, err := m.RebootAndWait(context.Background(), "cam-1", 200*time.Millisecond)
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		status, err := m.RebootStatus("cam-1")
+		return err == nil && status.State == RebootStateTimeout
+	}, 3*time.Second, 20*time.Millisecond)
+
+	// Once the wait is over the camera may be rebooted again
+	_, err = m.RebootAndWait(context.Background(), "cam-1", 200*time.Millisecond)
+	assert.NoError(t, err)
+}
+
+func TestManager_RebootAndWait_UnknownCamera(t *testing.T) {
+	m := newRebootWaitTestManager(t, 0)
+
+	_, err := m.RebootAndWait(context.Background(), "cam-2", time.Second)
+	assert.Error(t, err)
+	_, err = m.RebootStatus("cam-2")
+	assert.Error(t, err)
+}
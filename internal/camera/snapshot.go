@@ -0,0 +1,196 @@
+package camera
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"strings"
+)
+
+// maxSnapshotSize bounds how much of a snapshot response is read
+const maxSnapshotSize = 16 << 20
+
+var (
+	jpegStart = []byte{0xFF, 0xD8, 0xFF}
+	jpegEnd   = []byte{0xFF, 0xD9}
+)
+
+// fetchSnapshot requests a snapshot without the SDK, for firmwares answering
+// with a multipart response the SDK rejects, and extracts the first JPEG frame.
+// Chunked transfer encoding is undone by net/http.
+func (c *CameraClient) fetchSnapshot(ctx context.Context, channel int) ([]byte, error) {
+	resp, httpClient, err := c.requestSnapshot(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	defer httpClient.CloseIdleConnections()
+	defer resp.Body.Close()
+
+	return readSnapshot(resp)
+}
+
+// requestSnapshot sends a snapshot request to the camera. The caller closes
+// the response body and then the client's idle connections.
+func (c *CameraClient) requestSnapshot(ctx context.Context, channel int) (*http.Response, *http.Client, error) {
+	url := fmt.Sprintf("%s?cmd=Snap&channel=%d&rs=snapshot", c.Client.BaseURL(), channel)
+	if token := c.Client.GetToken(); token != "" {
+		url += "&token=" + token
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpClient := c.HTTPClient()
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		httpClient.CloseIdleConnections()
+		return nil, nil, fmt.Errorf("request failed: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		httpClient.CloseIdleConnections()
+		return nil, nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return resp, httpClient, nil
+}
+
+// readSnapshot reads a snapshot response and extracts its JPEG frame
+func readSnapshot(resp *http.Response) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxSnapshotSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+
+	return extractJPEG(resp.Header.Get("Content-Type"), data)
+}
+
+// SnapshotStream is a snapshot read from the camera as it arrives. Close
+// releases the camera connection.
+type SnapshotStream struct {
+	io.Reader
+
+	// Size is the snapshot's length in bytes, or -1 when the camera did not
+	// announce it
+	Size int64
+
+	close func() error
+}
+
+// Close releases the camera connection of the stream
+func (s *SnapshotStream) Close() error {
+	if s.close == nil {
+		return nil
+	}
+	return s.close()
+}
+
+// NewBufferedSnapshotStream returns a stream of a snapshot already in memory
+func NewBufferedSnapshotStream(data []byte) *SnapshotStream {
+	return &SnapshotStream{Reader: bytes.NewReader(data), Size: int64(len(data))}
+}
+
+// StreamSnapshot takes a snapshot from the camera without holding it in
+// memory: plain JPEG responses are passed on as they arrive, with the size the
+// camera announced if any. Multipart responses have to be buffered to extract
+// their frame. When the camera will not answer the direct request, for
+// instance because its session expired, the snapshot is taken buffered
+// through GetSnapshot instead.
+func (c *CameraClient) StreamSnapshot(ctx context.Context, channel int) (*SnapshotStream, error) {
+	stream, err := callValue(ctx, c, "stream_snapshot", func() (*SnapshotStream, error) {
+		return c.openSnapshot(ctx, channel)
+	})
+	if err == nil || errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrCameraOffline) || ctx.Err() != nil {
+		return stream, err
+	}
+
+	data, err := c.GetSnapshot(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	return NewBufferedSnapshotStream(data), nil
+}
+
+// openSnapshot requests a snapshot and streams it if it is a plain JPEG
+func (c *CameraClient) openSnapshot(ctx context.Context, channel int) (*SnapshotStream, error) {
+	resp, httpClient, err := c.requestSnapshot(ctx, channel)
+	if err != nil {
+		return nil, err
+	}
+	closeResp := func() error {
+		err := resp.Body.Close()
+		httpClient.CloseIdleConnections()
+		return err
+	}
+
+	if resp.ContentLength > maxSnapshotSize {
+		closeResp()
+		return nil, fmt.Errorf("snapshot of %d bytes exceeds the limit of %d", resp.ContentLength, maxSnapshotSize)
+	}
+
+	body := bufio.NewReader(io.LimitReader(resp.Body, maxSnapshotSize))
+	if start, _ := body.Peek(len(jpegStart)); bytes.Equal(start, jpegStart) {
+		return &SnapshotStream{Reader: body, Size: resp.ContentLength, close: closeResp}, nil
+	}
+
+	defer closeResp()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read image data: %w", err)
+	}
+	frame, err := extractJPEG(resp.Header.Get("Content-Type"), data)
+	if err != nil {
+		return nil, err
+	}
+	return NewBufferedSnapshotStream(frame), nil
+}
+
+// extractJPEG returns the first JPEG frame of a snapshot response body.
+// Plain JPEG bodies are returned unchanged; multipart bodies are split on
+// their boundary, taken from the content type or the body's first line.
+func extractJPEG(contentType string, data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, jpegStart) {
+		return data, nil
+	}
+
+	boundary := ""
+	if mediaType, params, err := mime.ParseMediaType(contentType); err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		boundary = params["boundary"]
+	}
+	if boundary == "" && bytes.HasPrefix(data, []byte("--")) {
+		line, _, _ := bytes.Cut(data, []byte("\n"))
+		boundary = strings.TrimSpace(string(line[2:]))
+	}
+
+	if boundary != "" {
+		reader := multipart.NewReader(bufio.NewReader(bytes.NewReader(data)), strings.TrimPrefix(boundary, "--"))
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+			frame, err := io.ReadAll(part)
+			if err == nil && bytes.HasPrefix(frame, jpegStart) {
+				return frame, nil
+			}
+		}
+	}
+
+	// Last resort: cut the frame out between the JPEG start and end markers
+	start := bytes.Index(data, jpegStart)
+	if start >= 0 {
+		if end := bytes.LastIndex(data, jpegEnd); end > start {
+			return data[start : end+len(jpegEnd)], nil
+		}
+	}
+
+	return nil, errors.New("no JPEG frame in snapshot response")
+}
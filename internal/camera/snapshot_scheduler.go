@@ -0,0 +1,185 @@
+package camera
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// snapshotCheckInterval is how often the snapshot scheduler looks for due snapshots
+const snapshotCheckInterval = time.Second
+
+// SnapshotRepository records the snapshots written by the snapshot scheduler
+type SnapshotRepository interface {
+	Create(ctx context.Context, snapshot *models.Snapshot) error
+}
+
+// snapshotScheduler captures stills from cameras with a snapshot schedule
+type snapshotScheduler struct {
+	manager *Manager
+	repo    SnapshotRepository
+	dir     string
+
+	// lastCapture holds when each camera's latest snapshot was attempted
+	lastCapture map[string]time.Time
+	mu          sync.Mutex
+}
+
+// StartSnapshotScheduler captures a snapshot of every camera with scheduled
+// snapshots enabled each time its interval passes, writing the JPEGs below
+// dir and recording them in repo. Cameras whose circuit is open are skipped
+// until it closes. An empty dir disables scheduled snapshots.
+func (m *Manager) StartSnapshotScheduler(ctx context.Context, repo SnapshotRepository, dir string) {
+	if dir == "" {
+		return
+	}
+
+	scheduler := &snapshotScheduler{
+		manager:     m,
+		repo:        repo,
+		dir:         dir,
+		lastCapture: make(map[string]time.Time),
+	}
+
+	ticker := time.NewTicker(snapshotCheckInterval)
+	defer ticker.Stop()
+
+	logger.Info("Snapshot scheduler started",
+		zap.String("dir", dir),
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Snapshot scheduler stopped")
+			return
+		case now := <-ticker.C:
+			scheduler.run(ctx, now)
+		}
+	}
+}
+
+// run captures every snapshot due at now and returns the IDs of the cameras
+// it captured from
+func (s *snapshotScheduler) run(ctx context.Context, now time.Time) []string {
+	s.manager.mu.RLock()
+	cameras := make([]*CameraClient, 0, len(s.manager.cameras))
+	for _, client := range s.manager.cameras {
+		cameras = append(cameras, client)
+	}
+	s.manager.mu.RUnlock()
+
+	due := make([]*CameraClient, 0, len(cameras))
+	s.mu.Lock()
+	present := make(map[string]bool, len(cameras))
+	for _, client := range cameras {
+		present[client.Camera.ID] = true
+	}
+	for cameraID := range s.lastCapture {
+		if !present[cameraID] {
+			delete(s.lastCapture, cameraID)
+		}
+	}
+	for _, client := range cameras {
+		if !s.due(client, now) {
+			continue
+		}
+		// Record the attempt first so a failing camera is retried on its
+		// next interval rather than on every check
+		s.lastCapture[client.Camera.ID] = now
+		due = append(due, client)
+	}
+	s.mu.Unlock()
+
+	var wg sync.WaitGroup
+	captured := make([]string, 0, len(due))
+	for _, client := range due {
+		captured = append(captured, client.Camera.ID)
+		wg.Add(1)
+		go func(client *CameraClient) {
+			defer wg.Done()
+			s.capture(ctx, client, now)
+		}(client)
+	}
+	wg.Wait()
+
+	return captured
+}
+
+// due reports whether a camera's next scheduled snapshot is due at now.
+// Callers hold s.mu.
+func (s *snapshotScheduler) due(client *CameraClient, now time.Time) bool {
+	client.mu.RLock()
+	enabled := client.Camera.SnapshotEnabled
+	interval := time.Duration(client.Camera.SnapshotInterval) * time.Second
+	circuitOpen := client.CircuitOpen
+	client.mu.RUnlock()
+
+	if !enabled || interval <= 0 || circuitOpen {
+		return false
+	}
+
+	last, ok := s.lastCapture[client.Camera.ID]
+	return !ok || now.Sub(last) >= interval
+}
+
+// capture takes a snapshot from a camera, writes it to disk and records it
+func (s *snapshotScheduler) capture(ctx context.Context, client *CameraClient, now time.Time) {
+	cameraID := client.Camera.ID
+	channel := client.Camera.SnapshotChannel
+
+	captureCtx, cancel := context.WithTimeout(ctx, s.manager.config.ConnectionTimeout)
+	data, err := client.GetSnapshot(captureCtx, channel)
+	cancel()
+	if err != nil {
+		logger.Warn("Scheduled snapshot failed",
+			zap.String("camera_id", cameraID),
+			zap.Int("channel", channel),
+			zap.Error(err))
+		return
+	}
+
+	path := filepath.Join(s.dir, snapshotFileName(cameraID, channel, now))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		logger.Error("Failed to create snapshot directory", zap.String("camera_id", cameraID), zap.Error(err))
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		logger.Error("Failed to write snapshot", zap.String("camera_id", cameraID), zap.Error(err))
+		return
+	}
+
+	if s.repo == nil {
+		return
+	}
+	snapshot := &models.Snapshot{
+		CameraID:   cameraID,
+		Channel:    channel,
+		FilePath:   path,
+		FileSize:   int64(len(data)),
+		CapturedAt: now,
+	}
+	if err := s.repo.Create(ctx, snapshot); err != nil {
+		logger.Error("Failed to record snapshot",
+			zap.String("camera_id", cameraID),
+			zap.String("path", path),
+			zap.Error(err))
+	}
+}
+
+// snapshotFileName returns where a snapshot taken at t is stored, relative to
+// the snapshot directory. Each camera gets its own directory and names sort
+// by capture time, so a directory listing is a ready-made timelapse:
+//
+//	<camera_id>/ch0_20251027T100000Z.jpg
+func snapshotFileName(cameraID string, channel int, t time.Time) string {
+	return filepath.Join(cameraID, fmt.Sprintf("ch%d_%s.jpg", channel, t.UTC().Format("20060102T150405Z")))
+}
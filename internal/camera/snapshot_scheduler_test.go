@@ -0,0 +1,153 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// mockSnapshotRepository collects the snapshots recorded by the scheduler
+type mockSnapshotRepository struct {
+	mu        sync.Mutex
+	snapshots []*models.Snapshot
+}
+
+func (r *mockSnapshotRepository) Create(ctx context.Context, snapshot *models.Snapshot) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.snapshots = append(r.snapshots, snapshot)
+	return nil
+}
+
+// newSnapshotTestScheduler returns a scheduler writing to a temporary
+// directory for a manager with one camera taking a snapshot every minute
+func newSnapshotTestScheduler(t *testing.T) (*snapshotScheduler, *CameraClient, *mockSnapshotRepository) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Snap", r.URL.Query().Get("cmd"))
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(sampleJPEG)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1", SnapshotEnabled: true, SnapshotInterval: 60, SnapshotChannel: 1},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+	m := NewManager(&Config{ConnectionTimeout: time.Second, MaxRetries: 3}, nil)
+	m.cameras["cam-1"] = client
+
+	repo := &mockSnapshotRepository{}
+	scheduler := &snapshotScheduler{
+		manager:     m,
+		repo:        repo,
+		dir:         t.TempDir(),
+		lastCapture: make(map[string]time.Time),
+	}
+	return scheduler, client, repo
+}
+
+func TestSnapshotFileName(t *testing.T) {
+	at := time.Date(2025, 10, 27, 10, 0, 5, 0, time.FixedZone("CET", 3600))
+
+	assert.Equal(t, filepath.Join("cam-1", "ch0_20251027T090005Z.jpg"), snapshotFileName("cam-1", 0, at))
+	assert.Equal(t, filepath.Join("cam-1", "ch2_20251027T090005Z.jpg"), snapshotFileName("cam-1", 2, at))
+	assert.Less(t, snapshotFileName("cam-1", 0, at), snapshotFileName("cam-1", 0, at.Add(time.Second)),
+		"names sort by capture time")
+}
+
+func TestSnapshotScheduler_Run_WritesAndRecordsSnapshot(t *testing.T) {
+	scheduler, _, repo := newSnapshotTestScheduler(t)
+	now := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(context.Background(), now))
+
+	path := filepath.Join(scheduler.dir, "cam-1", "ch1_20251027T100000Z.jpg")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, sampleJPEG, data)
+
+	require.Len(t, repo.snapshots, 1)
+	snapshot := repo.snapshots[0]
+	assert.Equal(t, "cam-1", snapshot.CameraID)
+	assert.Equal(t, 1, snapshot.Channel)
+	assert.Equal(t, path, snapshot.FilePath)
+	assert.Equal(t, int64(len(sampleJPEG)), snapshot.FileSize)
+	assert.Equal(t, now, snapshot.CapturedAt)
+}
+
+func TestSnapshotScheduler_Run_FollowsInterval(t *testing.T) {
+	scheduler, _, repo := newSnapshotTestScheduler(t)
+	ctx := context.Background()
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+
+	assert.NotEmpty(t, scheduler.run(ctx, start), "the first snapshot is taken right away")
+	assert.Empty(t, scheduler.run(ctx, start.Add(30*time.Second)))
+	assert.Empty(t, scheduler.run(ctx, start.Add(59*time.Second)))
+	assert.NotEmpty(t, scheduler.run(ctx, start.Add(60*time.Second)))
+	assert.Empty(t, scheduler.run(ctx, start.Add(90*time.Second)))
+	assert.NotEmpty(t, scheduler.run(ctx, start.Add(125*time.Second)))
+
+	assert.Len(t, repo.snapshots, 3)
+}
+
+func TestSnapshotScheduler_Run_SkipsCameras(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(client *CameraClient)
+	}{
+		{"disabled", func(client *CameraClient) { client.Camera.SnapshotEnabled = false }},
+		{"no interval", func(client *CameraClient) { client.Camera.SnapshotInterval = 0 }},
+		{"circuit open", func(client *CameraClient) { client.CircuitOpen = true }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scheduler, client, repo := newSnapshotTestScheduler(t)
+			tt.setup(client)
+
+			assert.Empty(t, scheduler.run(context.Background(), time.Now()))
+			assert.Empty(t, repo.snapshots)
+		})
+	}
+}
+
+func TestSnapshotScheduler_Run_ResumesAfterCircuitCloses(t *testing.T) {
+	scheduler, client, _ := newSnapshotTestScheduler(t)
+	ctx := context.Background()
+	start := time.Now()
+
+	client.CircuitOpen = true
+	assert.Empty(t, scheduler.run(ctx, start))
+
+	client.CircuitOpen = false
+	assert.Equal(t, []string{"cam-1"}, scheduler.run(ctx, start.Add(time.Second)),
+		"a skipped camera is captured as soon as its circuit closes")
+}
+
+func TestManager_StartSnapshotScheduler_DisabledWithoutDir(t *testing.T) {
+	m := NewManager(nil, nil)
+
+	done := make(chan struct{})
+	go func() {
+		m.StartSnapshotScheduler(context.Background(), nil, "")
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("scheduler without a snapshot directory should return immediately")
+	}
+}
@@ -0,0 +1,142 @@
+package camera
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+var sampleJPEG = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00, 0xFF, 0xD9}
+
+// multipartSnapshot wraps frames in a multipart body as some firmwares send them
+func multipartSnapshot(boundary string, frames ...[]byte) []byte {
+	var body []byte
+	for _, frame := range frames {
+		body = append(body, "--"+boundary+"\r\nContent-Type: image/jpeg\r\n\r\n"...)
+		body = append(body, frame...)
+		body = append(body, "\r\n"...)
+	}
+	return append(body, "--"+boundary+"--\r\n"...)
+}
+
+func TestExtractJPEG(t *testing.T) {
+	second := []byte{0xFF, 0xD8, 0xFF, 0xDB, 0x01, 0xFF, 0xD9}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        []byte
+	}{
+		{"plain jpeg", "image/jpeg", sampleJPEG},
+		{"multipart with boundary header", "multipart/x-mixed-replace; boundary=myboundary", multipartSnapshot("myboundary", sampleJPEG, second)},
+		{"multipart without boundary header", "text/plain", multipartSnapshot("frame", sampleJPEG)},
+		{"jpeg with leading garbage", "application/octet-stream", append([]byte("\r\n\r\n"), sampleJPEG...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := extractJPEG(tt.contentType, tt.body)
+			require.NoError(t, err)
+			assert.Equal(t, sampleJPEG, frame)
+		})
+	}
+
+	_, err := extractJPEG("text/html", []byte("<html>error</html>"))
+	assert.Error(t, err)
+}
+
+func TestCameraClient_GetSnapshot_Multipart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Snap", r.URL.Query().Get("cmd"))
+		assert.Equal(t, "test-token", r.URL.Query().Get("token"))
+		w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=myboundary")
+		// Flushing forces a chunked transfer encoding
+		w.Write(multipartSnapshot("myboundary", sampleJPEG)[:20])
+		w.(http.Flusher).Flush()
+		w.Write(multipartSnapshot("myboundary", sampleJPEG)[20:])
+	}))
+	defer server.Close()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	data, err := client.GetSnapshot(context.Background(), 0)
+	require.NoError(t, err)
+	assert.Equal(t, sampleJPEG, data)
+}
+
+func TestCameraClient_StreamSnapshot(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Query().Get("channel") {
+		case "0":
+			// Sized plain JPEG
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Header().Set("Content-Length", strconv.Itoa(len(sampleJPEG)))
+			w.Write(sampleJPEG)
+		case "1":
+			// Flushing forces a chunked transfer encoding without a length
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(sampleJPEG[:4])
+			w.(http.Flusher).Flush()
+			w.Write(sampleJPEG[4:])
+		case "2":
+			w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary=myboundary")
+			w.Write(multipartSnapshot("myboundary", sampleJPEG))
+		case "3":
+			// The direct request is refused, the SDK's is answered
+			if requests.Load()%2 == 1 {
+				http.Error(w, "busy", http.StatusServiceUnavailable)
+				return
+			}
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(sampleJPEG)
+		}
+	}))
+	defer server.Close()
+
+	client := &CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	tests := []struct {
+		name     string
+		channel  int
+		wantSize int64
+	}{
+		{"sized jpeg", 0, int64(len(sampleJPEG))},
+		{"chunked jpeg", 1, -1},
+		{"multipart buffered", 2, int64(len(sampleJPEG))},
+		{"buffered fallback", 3, int64(len(sampleJPEG))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests.Store(0)
+			stream, err := client.StreamSnapshot(context.Background(), tt.channel)
+			require.NoError(t, err)
+			defer stream.Close()
+
+			assert.Equal(t, tt.wantSize, stream.Size)
+			data, err := io.ReadAll(stream)
+			require.NoError(t, err)
+			assert.Equal(t, sampleJPEG, data)
+		})
+	}
+	assert.Equal(t, int32(2), requests.Load(), "the fallback asks the camera again")
+}
@@ -0,0 +1,208 @@
+package camera
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// tokenLease is how long a cached login token is reused. Cameras lease tokens
+// for an hour by default; cached tokens are dropped a little earlier.
+const tokenLease = 55 * time.Minute
+
+// CachedToken is a camera login token saved for reuse after a restart
+type CachedToken struct {
+	Token       string    `json:"token"`
+	Fingerprint string    `json:"fingerprint"` // Hash of the connection settings the token was issued for
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// TokenCache stores camera login tokens across restarts
+type TokenCache interface {
+	Get(cameraID string) (CachedToken, bool)
+	Put(cameraID string, token CachedToken) error
+	Delete(cameraID string) error
+}
+
+// FileTokenCache is a TokenCache kept in a JSON file readable only by its owner
+type FileTokenCache struct {
+	path   string
+	tokens map[string]CachedToken
+	mu     sync.Mutex
+}
+
+// NewFileTokenCache loads the token cache at path. A missing file is an empty cache.
+func NewFileTokenCache(path string) (*FileTokenCache, error) {
+	cache := &FileTokenCache{
+		path:   path,
+		tokens: make(map[string]CachedToken),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read token cache: %w", err)
+	}
+	if err := json.Unmarshal(data, &cache.tokens); err != nil {
+		return nil, fmt.Errorf("failed to parse token cache %s: %w", path, err)
+	}
+
+	return cache, nil
+}
+
+// Get returns the cached token of a camera
+func (c *FileTokenCache) Get(cameraID string) (CachedToken, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	token, ok := c.tokens[cameraID]
+	return token, ok
+}
+
+// Put caches the token of a camera
+func (c *FileTokenCache) Put(cameraID string, token CachedToken) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.tokens[cameraID] = token
+	return c.save()
+}
+
+// Delete removes the cached token of a camera
+func (c *FileTokenCache) Delete(cameraID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.tokens[cameraID]; !ok {
+		return nil
+	}
+	delete(c.tokens, cameraID)
+	return c.save()
+}
+
+// save atomically rewrites the cache file. Callers hold the lock.
+func (c *FileTokenCache) save() error {
+	data, err := json.Marshal(c.tokens)
+	if err != nil {
+		return fmt.Errorf("failed to encode token cache: %w", err)
+	}
+
+	dir := filepath.Dir(c.path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("failed to create token cache directory: %w", err)
+	}
+
+	// CreateTemp creates the file with mode 0600, so tokens are never world-readable
+	tmp, err := os.CreateTemp(dir, ".token-cache-*")
+	if err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("failed to write token cache: %w", err)
+	}
+	return nil
+}
+
+// tokenFingerprint identifies the connection settings of a camera, so a
+// cached token is not reused after its host or credentials changed
+func tokenFingerprint(camera *models.Camera) string {
+	sum := sha256.Sum256([]byte(camera.Host + "\x00" + strconv.Itoa(camera.Port) + "\x00" +
+		strconv.FormatBool(camera.UseHTTPS) + "\x00" + camera.Username + "\x00" + camera.Password))
+	return hex.EncodeToString(sum[:])
+}
+
+// SetTokenCache enables caching login tokens so cameras added later, e.g.
+// after a restart, reuse their sessions instead of logging in again
+func (m *Manager) SetTokenCache(cache TokenCache) {
+	m.tokens = cache
+}
+
+// restoreToken sets a camera's cached token on client if the camera still
+// accepts it and reports whether it did
+func (m *Manager) restoreToken(ctx context.Context, camera *models.Camera, client *reolink.Client) bool {
+	if m.tokens == nil {
+		return false
+	}
+
+	cached, ok := m.tokens.Get(camera.ID)
+	if !ok {
+		return false
+	}
+	if cached.Fingerprint != tokenFingerprint(camera) || time.Now().After(cached.ExpiresAt) {
+		m.forgetToken(camera.ID)
+		return false
+	}
+
+	client.SetToken(cached.Token)
+	if _, err := client.System.GetDeviceInfo(ctx); err != nil {
+		logger.Info("Cached camera token rejected, logging in",
+			zap.String("camera_id", camera.ID),
+			zap.Error(err))
+		client.SetToken("")
+		m.forgetToken(camera.ID)
+		return false
+	}
+
+	logger.Debug("Reusing cached camera token", zap.String("camera_id", camera.ID))
+	return true
+}
+
+// login logs in to a camera and caches the new token
+func (m *Manager) login(ctx context.Context, camera *models.Camera, client *reolink.Client) error {
+	return m.logins.Do(ctx, camera.ID, func(ctx context.Context) error {
+		if err := client.Login(ctx); err != nil {
+			return err
+		}
+
+		if m.tokens != nil {
+			err := m.tokens.Put(camera.ID, CachedToken{
+				Token:       client.GetToken(),
+				Fingerprint: tokenFingerprint(camera),
+				ExpiresAt:   time.Now().Add(tokenLease),
+			})
+			if err != nil {
+				logger.Warn("Failed to cache camera token",
+					zap.String("camera_id", camera.ID),
+					zap.Error(err))
+			}
+		}
+		return nil
+	})
+}
+
+// forgetToken drops the cached token of a camera
+func (m *Manager) forgetToken(cameraID string) {
+	if m.tokens == nil {
+		return
+	}
+	if err := m.tokens.Delete(cameraID); err != nil {
+		logger.Warn("Failed to remove cached camera token",
+			zap.String("camera_id", cameraID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,122 @@
+package camera
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTokenCameraServer starts a fake camera accepting only validToken and
+// issuing loginToken on login. It returns the number of logins so far.
+func newTokenCameraServer(t *testing.T, validToken, loginToken string) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	logins := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch cmd := r.URL.Query().Get("cmd"); {
+		case cmd == "Login":
+			logins++
+			validToken = loginToken
+			w.Write([]byte(`[{"cmd":"Login","code":0,"value":{"Token":{"leaseTime":3600,"name":"` + loginToken + `"}}}]`))
+		case r.URL.Query().Get("token") != validToken:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":1,"error":{"detail":"please login first","rspCode":-6}}]`))
+		case cmd == "GetDevInfo":
+			w.Write([]byte(`[{"cmd":"GetDevInfo","code":0,"value":{"DevInfo":{"model":"RLC-810A"}}}]`))
+		default:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{"rspCode":200}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return logins
+	}
+}
+
+func TestFileTokenCache_PersistsTokens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "tokens.json")
+	expires := time.Now().Add(time.Hour).Round(time.Second)
+
+	cache, err := NewFileTokenCache(path)
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("cam-1", CachedToken{Token: "abc", Fingerprint: "fp", ExpiresAt: expires}))
+	require.NoError(t, cache.Put("cam-2", CachedToken{Token: "def"}))
+	require.NoError(t, cache.Delete("cam-2"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	reloaded, err := NewFileTokenCache(path)
+	require.NoError(t, err)
+	token, ok := reloaded.Get("cam-1")
+	assert.True(t, ok)
+	assert.Equal(t, "abc", token.Token)
+	assert.True(t, expires.Equal(token.ExpiresAt))
+	_, ok = reloaded.Get("cam-2")
+	assert.False(t, ok)
+}
+
+func TestManager_AddCamera_ReusesCachedToken(t *testing.T) {
+	server, logins := newTokenCameraServer(t, "cached-token", "fresh-token")
+	cam := fakeCamera(t, server, "cam-1")
+
+	cache, err := NewFileTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("cam-1", CachedToken{Token: "cached-token", Fingerprint: tokenFingerprint(cam), ExpiresAt: time.Now().Add(time.Hour)}))
+
+	m := NewManager(nil, nil)
+	m.SetTokenCache(cache)
+
+	require.NoError(t, m.AddCamera(context.Background(), cam))
+
+	assert.Equal(t, 0, logins(), "a valid cached token needs no login")
+	assert.Equal(t, "RLC-810A", cam.Model)
+}
+
+func TestManager_AddCamera_LogsInWhenCachedTokenRejected(t *testing.T) {
+	server, logins := newTokenCameraServer(t, "", "fresh-token")
+	cam := fakeCamera(t, server, "cam-1")
+
+	cache, err := NewFileTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("cam-1", CachedToken{Token: "stale-token", Fingerprint: tokenFingerprint(cam), ExpiresAt: time.Now().Add(time.Hour)}))
+
+	m := NewManager(nil, nil)
+	m.SetTokenCache(cache)
+
+	require.NoError(t, m.AddCamera(context.Background(), cam))
+
+	assert.Equal(t, 1, logins())
+	token, ok := cache.Get("cam-1")
+	assert.True(t, ok)
+	assert.Equal(t, "fresh-token", token.Token, "the new token replaces the rejected one")
+}
+
+func TestManager_AddCamera_IgnoresTokenForChangedCredentials(t *testing.T) {
+	server, logins := newTokenCameraServer(t, "cached-token", "fresh-token")
+	cam := fakeCamera(t, server, "cam-1")
+
+	cache, err := NewFileTokenCache(filepath.Join(t.TempDir(), "tokens.json"))
+	require.NoError(t, err)
+	require.NoError(t, cache.Put("cam-1", CachedToken{Token: "cached-token", Fingerprint: "other-settings", ExpiresAt: time.Now().Add(time.Hour)}))
+
+	m := NewManager(nil, nil)
+	m.SetTokenCache(cache)
+
+	require.NoError(t, m.AddCamera(context.Background(), cam))
+
+	assert.Equal(t, 1, logins())
+}
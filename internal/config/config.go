@@ -2,24 +2,35 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/spf13/viper"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	Cameras  CamerasConfig  `mapstructure:"cameras"`
-	Events   EventsConfig   `mapstructure:"events"`
-	Streams  StreamsConfig  `mapstructure:"streams"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Auth     AuthConfig     `mapstructure:"auth"`
-	API      APIConfig      `mapstructure:"api"`
-	Metrics  MetricsConfig  `mapstructure:"metrics"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	Redis     RedisConfig     `mapstructure:"redis"`
+	Cameras   CamerasConfig   `mapstructure:"cameras"`
+	Events    EventsConfig    `mapstructure:"events"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Streams   StreamsConfig   `mapstructure:"streams"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	API       APIConfig       `mapstructure:"api"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	MQTT      MQTTConfig      `mapstructure:"mqtt"`
+
+	// Deprecations describes deprecated settings found while loading, to be
+	// logged once logging is set up
+	Deprecations []string `mapstructure:"-"`
 }
 
 // ServerConfig holds HTTP server configuration
@@ -30,6 +41,19 @@ type ServerConfig struct {
 	WriteTimeout    time.Duration `mapstructure:"write_timeout"`
 	IdleTimeout     time.Duration `mapstructure:"idle_timeout"`
 	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+	TLS             TLSConfig     `mapstructure:"tls"`
+}
+
+// TLSConfig holds HTTPS configuration for the API server
+type TLSConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	CertFile         string   `mapstructure:"cert_file"`
+	KeyFile          string   `mapstructure:"key_file"`
+	AutoCert         bool     `mapstructure:"autocert"`
+	AutoCertHosts    []string `mapstructure:"autocert_hosts"`
+	AutoCertCacheDir string   `mapstructure:"autocert_cache_dir"`
+	RedirectHTTP     bool     `mapstructure:"redirect_http"`
+	HTTPPort         int      `mapstructure:"http_port"`
 }
 
 // DatabaseConfig holds PostgreSQL configuration
@@ -61,16 +85,86 @@ type CamerasConfig struct {
 	ReconnectInterval   time.Duration `mapstructure:"reconnect_interval"`
 	MaxRetries          int           `mapstructure:"max_retries"`
 	RequestTimeout      time.Duration `mapstructure:"request_timeout"`
-	WorkerPoolSize      int           `mapstructure:"worker_pool_size"`
+	WorkerPoolSize      int           `mapstructure:"worker_pool_size"`    // Cameras health checked concurrently
+	TokenCacheFile      string        `mapstructure:"token_cache_file"`    // Empty disables reusing login tokens across restarts
+	SnapshotCacheTTL    time.Duration `mapstructure:"snapshot_cache_ttl"`  // 0 uses the default of 1s, negative disables caching
+	SnapshotStreaming   bool          `mapstructure:"snapshot_streaming"`  // Stream snapshots from the camera instead of buffering them
+	UniqueNames         bool          `mapstructure:"unique_names"`        // Reject cameras named like an existing camera
+	KeepAliveInterval   time.Duration `mapstructure:"keep_alive_interval"` // How often always-ready cameras are pinged; 0 disables
+	SnapshotDir         string        `mapstructure:"snapshot_dir"`        // Where scheduled snapshots are written; empty disables them
+	LoadRetryInterval   time.Duration `mapstructure:"load_retry_interval"` // How often cameras that failed to load are retried; 0 uses the default of 1m, negative disables
+
+	// Health checks probe the disks and PTZ of cameras every
+	// FeatureCheckInterval (0 uses the default of 5m). Disks at least
+	// HddFullPercent used degrade the camera; 0 ignores disk usage.
+	FeatureCheckInterval time.Duration `mapstructure:"feature_check_interval"`
+	HddFullPercent       int           `mapstructure:"hdd_full_percent"`
+
+	// Networks (CIDR or single IPs) the server may connect to for cameras.
+	// Denied networks always win; a non-empty allow-list admits only its
+	// networks. Link-local and metadata addresses are denied unless allowed.
+	AllowedNetworks []string `mapstructure:"allowed_networks"`
+	DeniedNetworks  []string `mapstructure:"denied_networks"`
+
+	// Named connection profiles cameras can be assigned, added to or
+	// replacing the built-in lan, remote-p2p and cellular profiles
+	ConnectionProfiles map[string]ConnectionProfileConfig `mapstructure:"connection_profiles"`
+}
+
+// ConnectionProfileConfig holds the settings of a camera connection profile.
+// Zero values use the camera manager's defaults.
+type ConnectionProfileConfig struct {
+	Timeout       time.Duration `mapstructure:"timeout"`        // Timeout of each camera request
+	MaxRetries    int           `mapstructure:"max_retries"`    // Failed health checks before the camera's circuit opens
+	KeepAlive     bool          `mapstructure:"keep_alive"`     // Keep connections warm like always_ready cameras
+	RTSPTransport string        `mapstructure:"rtsp_transport"` // tcp or udp for cameras without their own setting
 }
 
 // EventsConfig holds event processing configuration
 type EventsConfig struct {
 	PollInterval  time.Duration `mapstructure:"poll_interval"`
-	RetentionDays int           `mapstructure:"retention_days"`
+	RetentionDays int           `mapstructure:"retention_days"` // Deprecated: use retention.event_days
 	BatchSize     int           `mapstructure:"batch_size"`
 	BatchInterval time.Duration `mapstructure:"batch_interval"`
 	BufferSize    int           `mapstructure:"buffer_size"`
+	Workers       int           `mapstructure:"workers"` // Subscribers served concurrently by the event dispatcher
+
+	SnapshotMotion SnapshotMotionConfig `mapstructure:"snapshot_motion"`
+
+	CameraQuota       int           `mapstructure:"camera_quota"` // Max events per camera per quota window
+	CameraQuotaWindow time.Duration `mapstructure:"camera_quota_window"`
+
+	Cooldown  time.Duration            `mapstructure:"cooldown"`  // Repeated detections within this window are suppressed
+	Cooldowns map[string]time.Duration `mapstructure:"cooldowns"` // Per event type overrides of cooldown
+
+	DefaultMinSeverity string `mapstructure:"default_min_severity"` // Lowest severity listed by default
+
+	Rules []AutomationRuleConfig `mapstructure:"rules"`
+}
+
+// AutomationRuleConfig holds an automation rule run against incoming events
+type AutomationRuleConfig struct {
+	Name        string                   `mapstructure:"name"`
+	Tags        []string                 `mapstructure:"tags"`         // Cameras with any of these tags; empty matches all
+	EventTypes  []string                 `mapstructure:"event_types"`  // Empty matches all event types
+	ActiveFrom  string                   `mapstructure:"active_from"`  // Local time HH:MM
+	ActiveUntil string                   `mapstructure:"active_until"` // Local time HH:MM, may wrap past midnight
+	Cooldown    time.Duration            `mapstructure:"cooldown"`
+	Actions     []AutomationActionConfig `mapstructure:"actions"`
+}
+
+// AutomationActionConfig holds a camera action of an automation rule
+type AutomationActionConfig struct {
+	Type     string `mapstructure:"type"`     // siren or white_led
+	Duration int    `mapstructure:"duration"` // Siren plays, or seconds the white LED stays on
+}
+
+// SnapshotMotionConfig holds the snapshot differencing motion fallback configuration
+type SnapshotMotionConfig struct {
+	Enabled   bool          `mapstructure:"enabled"`
+	Interval  time.Duration `mapstructure:"interval"`
+	Threshold float64       `mapstructure:"threshold"`
+	Timeout   time.Duration `mapstructure:"timeout"` // Max time a single snapshot capture may take, defaults to 5s
 }
 
 // StreamsConfig holds stream management configuration
@@ -80,6 +174,12 @@ type StreamsConfig struct {
 	EnableHLSTranscoding bool          `mapstructure:"enable_hls_transcoding"`
 	HLSSegmentDuration   time.Duration `mapstructure:"hls_segment_duration"`
 	HLSPlaylistSize      int           `mapstructure:"hls_playlist_size"`
+	HLSOutputDir         string        `mapstructure:"hls_output_dir"`  // Per-session HLS output; orphaned sessions are removed on start
+	RTSPTransport        string        `mapstructure:"rtsp_transport"`  // tcp or udp for cameras without their own setting
+	Go2RTCURL            string        `mapstructure:"go2rtc_url"`      // go2rtc API for WebRTC; empty disables WebRTC
+	Go2RTCRTSPURL        string        `mapstructure:"go2rtc_rtsp_url"` // go2rtc RTSP server WebRTC sessions publish to
+	ClipDir              string        `mapstructure:"clip_dir"`        // Where exported recording clips are written; empty disables export
+	ThumbnailDir         string        `mapstructure:"thumbnail_dir"`   // Where recording thumbnails are written; empty disables thumbnails
 }
 
 // LoggingConfig holds logging configuration
@@ -96,6 +196,7 @@ type AuthConfig struct {
 	JWTSecret     string        `mapstructure:"jwt_secret"`
 	JWTExpiration time.Duration `mapstructure:"jwt_expiration"`
 	BcryptCost    int           `mapstructure:"bcrypt_cost"`
+	SSETokenTTL   time.Duration `mapstructure:"sse_token_ttl"` // Lifetime of query-string tokens for SSE
 }
 
 // APIConfig holds API configuration
@@ -106,6 +207,44 @@ type APIConfig struct {
 	CORSAllowedOrigins  []string `mapstructure:"cors_allowed_origins"`
 	CORSAllowedMethods  []string `mapstructure:"cors_allowed_methods"`
 	CORSAllowedHeaders  []string `mapstructure:"cors_allowed_headers"`
+
+	// Camera-control routes (PTZ, reboot, snapshot, siren, LED, commands)
+	// allow this many requests a minute per client IP, in bursts of up to
+	// ControlBurst; 0 disables
+	ControlRatePerMinute int `mapstructure:"control_rate_per_minute"`
+	ControlBurst         int `mapstructure:"control_burst"`
+
+	// Proxies (CIDRs or single IPs) whose X-Forwarded-For and X-Real-IP
+	// headers name the client; the headers of other peers are ignored
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+
+	// Firmware uploads that receive no data for this long are aborted, even
+	// when the server's read timeout has not passed; 0 uses the default of 30s
+	FirmwareUploadTimeout time.Duration `mapstructure:"firmware_upload_timeout"`
+
+	// API requests still running after this long are cancelled with 504;
+	// event streams are not bounded. 0 uses the default of 60s.
+	RequestTimeout time.Duration `mapstructure:"request_timeout"`
+}
+
+// RetentionConfig holds the periodic cleanup of old events and recordings.
+// A zero day count keeps that kind of data forever.
+type RetentionConfig struct {
+	Interval      time.Duration `mapstructure:"interval"` // Time between cleanups, defaults to 24h
+	EventDays     int           `mapstructure:"event_days"`
+	RecordingDays int           `mapstructure:"recording_days"` // Recording files on disk are removed too
+}
+
+// MQTTConfig holds the MQTT broker events are published to. An empty host
+// disables MQTT publishing.
+type MQTTConfig struct {
+	Host            string `mapstructure:"host"`
+	Port            int    `mapstructure:"port"` // Defaults to 1883
+	Username        string `mapstructure:"username"`
+	Password        string `mapstructure:"password"`
+	ClientID        string `mapstructure:"client_id"`
+	TopicPrefix     string `mapstructure:"topic_prefix"`     // Events go to <prefix>/<camera_id>/<type>
+	DiscoveryPrefix string `mapstructure:"discovery_prefix"` // Home Assistant discovery prefix; empty disables discovery
 }
 
 // MetricsConfig holds metrics configuration
@@ -115,27 +254,52 @@ type MetricsConfig struct {
 	Port    int    `mapstructure:"port"`
 }
 
-// Load loads configuration from file and environment variables
+// Load loads configuration from file and environment variables.
+//
+// Configuration is layered, later sources overriding earlier ones:
+//  1. the base config file (YAML, JSON or TOML, detected by extension)
+//  2. an environment-specific override next to it, e.g. config.production.yaml
+//     when CONFIG_ENV=production (skipped if the file does not exist)
+//  3. environment variables
+//
+// A config file may list other files under a top-level "include" key,
+// resolved relative to it (useful for keeping secrets in a separate file).
+// Included files are merged right after the file including them, so they
+// override it but not the layers that follow: the base file's includes are
+// overridden by the environment-specific file and its includes.
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
 
-	// Set config file
-	if configPath != "" {
-		v.SetConfigFile(configPath)
-	} else {
-		v.SetConfigName("config")
-		v.SetConfigType("yaml")
-		v.AddConfigPath("./configs")
-		v.AddConfigPath(".")
+	// Locate the base config file
+	if configPath == "" {
+		finder := viper.New()
+		finder.SetConfigName("config")
+		finder.SetConfigType("yaml")
+		finder.AddConfigPath("./configs")
+		finder.AddConfigPath(".")
+		if err := finder.ReadInConfig(); err != nil {
+			return nil, fmt.Errorf("failed to read config file: %w", err)
+		}
+		configPath = finder.ConfigFileUsed()
 	}
 
 	// Read environment variables
 	v.AutomaticEnv()
 	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	// Read config file
-	if err := v.ReadInConfig(); err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+	// Read base config file
+	if err := mergeConfigFile(v, configPath, nil); err != nil {
+		return nil, err
+	}
+
+	// Layer environment-specific overrides on top
+	if env := os.Getenv("CONFIG_ENV"); env != "" {
+		overridePath := envConfigPath(configPath, env)
+		if _, err := os.Stat(overridePath); err == nil {
+			if err := mergeConfigFile(v, overridePath, nil); err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	// Unmarshal config
@@ -144,6 +308,14 @@ func Load(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Honor settings that moved, unless their new home is set
+	if v.IsSet("events.retention_days") {
+		if !v.IsSet("retention.event_days") {
+			cfg.Retention.EventDays = cfg.Events.RetentionDays
+		}
+		cfg.Deprecations = append(cfg.Deprecations, "events.retention_days is deprecated, use retention.event_days")
+	}
+
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
@@ -152,12 +324,135 @@ func Load(configPath string) (*Config, error) {
 	return &cfg, nil
 }
 
+// envConfigPath returns the environment-specific override path for a config
+// file, e.g. configs/config.yaml -> configs/config.production.yaml
+func envConfigPath(configPath, env string) string {
+	ext := filepath.Ext(configPath)
+	return strings.TrimSuffix(configPath, ext) + "." + env + ext
+}
+
+// mergeConfigFile merges a config file and the files it includes into v.
+// including holds the files whose includes led to path; a file may be
+// included from several places, but not from a file it includes itself.
+func mergeConfigFile(v *viper.Viper, path string, including map[string]bool) error {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config path %s: %w", path, err)
+	}
+	if including[absPath] {
+		return fmt.Errorf("config file %s includes itself", path)
+	}
+	if including == nil {
+		including = make(map[string]bool)
+	}
+	including[absPath] = true
+	defer delete(including, absPath)
+
+	fv := viper.New()
+	fv.SetConfigFile(path)
+	if err := fv.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	includes := fv.GetStringSlice("include")
+	settings := fv.AllSettings()
+	delete(settings, "include")
+
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to merge config file %s: %w", path, err)
+	}
+
+	for _, include := range includes {
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		if err := mergeConfigFile(v, include, including); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // Validate validates the configuration
 func (c *Config) Validate() error {
 	if c.Server.Port <= 0 || c.Server.Port > 65535 {
 		return fmt.Errorf("invalid server port: %d", c.Server.Port)
 	}
 
+	if c.Server.TLS.Enabled {
+		if c.Server.TLS.AutoCert {
+			if len(c.Server.TLS.AutoCertHosts) == 0 {
+				return fmt.Errorf("tls autocert requires at least one host")
+			}
+		} else if c.Server.TLS.CertFile == "" || c.Server.TLS.KeyFile == "" {
+			return fmt.Errorf("tls cert_file and key_file are required")
+		}
+	}
+
+	for _, network := range append(append([]string{}, c.Cameras.AllowedNetworks...), c.Cameras.DeniedNetworks...) {
+		if !validNetwork(network) {
+			return fmt.Errorf("invalid cameras network %q, use CIDR notation or an IP address", network)
+		}
+	}
+
+	for name, profile := range c.Cameras.ConnectionProfiles {
+		if profile.Timeout < 0 || profile.MaxRetries < 0 {
+			return fmt.Errorf("cameras connection profile %q: timeout and max_retries must not be negative", name)
+		}
+		if !models.ValidRTSPTransport(profile.RTSPTransport) {
+			return fmt.Errorf("cameras connection profile %q: invalid rtsp_transport %q, use tcp or udp", name, profile.RTSPTransport)
+		}
+	}
+
+	if c.Events.DefaultMinSeverity != "" {
+		if _, err := models.ParseEventSeverity(c.Events.DefaultMinSeverity); err != nil {
+			return fmt.Errorf("events default_min_severity: %w", err)
+		}
+	}
+
+	if c.Events.Cooldown < 0 {
+		return fmt.Errorf("events cooldown must not be negative")
+	}
+	for eventType, cooldown := range c.Events.Cooldowns {
+		if _, err := models.ParseEventType(eventType); err != nil {
+			return fmt.Errorf("events cooldowns: %w", err)
+		}
+		if cooldown < 0 {
+			return fmt.Errorf("events cooldowns %s must not be negative", eventType)
+		}
+	}
+
+	if !models.ValidRTSPTransport(c.Streams.RTSPTransport) {
+		return fmt.Errorf("invalid streams rtsp_transport %q, use tcp or udp", c.Streams.RTSPTransport)
+	}
+
+	if c.Streams.Go2RTCURL != "" && c.Streams.Go2RTCRTSPURL == "" {
+		return fmt.Errorf("streams go2rtc_rtsp_url is required when go2rtc_url is set")
+	}
+
+	if c.MQTT.Host != "" && (c.MQTT.Port < 0 || c.MQTT.Port > 65535) {
+		return fmt.Errorf("invalid mqtt port: %d", c.MQTT.Port)
+	}
+
+	if c.Cameras.HddFullPercent < 0 || c.Cameras.HddFullPercent > 100 {
+		return fmt.Errorf("cameras hdd_full_percent must be between 0 and 100")
+	}
+
+	if c.API.ControlRatePerMinute < 0 || c.API.ControlBurst < 0 {
+		return fmt.Errorf("api control_rate_per_minute and control_burst must not be negative")
+	}
+
+	for _, proxy := range c.API.TrustedProxies {
+		if !validNetwork(proxy) {
+			return fmt.Errorf("invalid api trusted proxy %q, use CIDR notation or an IP address", proxy)
+		}
+	}
+
+	if c.Retention.EventDays < 0 || c.Retention.RecordingDays < 0 {
+		return fmt.Errorf("retention event_days and recording_days must not be negative")
+	}
+
 	if c.Database.Host == "" {
 		return fmt.Errorf("database host is required")
 	}
@@ -185,6 +480,61 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// CheckOutputDirs verifies that every configured output directory exists, or
+// can be created, and is writable, so permission problems surface at startup
+// instead of failing each stream or certificate write
+func (c *Config) CheckOutputDirs() error {
+	type outputDir struct {
+		key  string
+		path string
+	}
+
+	dirs := []outputDir{
+		{"streams hls_output_dir", c.Streams.HLSOutputDir},
+	}
+	if c.Server.TLS.Enabled && c.Server.TLS.AutoCert {
+		dirs = append(dirs, outputDir{"server tls autocert_cache_dir", c.Server.TLS.AutoCertCacheDir})
+	}
+	if c.Cameras.TokenCacheFile != "" {
+		dirs = append(dirs, outputDir{"cameras token_cache_file", filepath.Dir(c.Cameras.TokenCacheFile)})
+	}
+	if c.Cameras.SnapshotDir != "" {
+		dirs = append(dirs, outputDir{"cameras snapshot_dir", c.Cameras.SnapshotDir})
+	}
+	if c.Streams.ClipDir != "" {
+		dirs = append(dirs, outputDir{"streams clip_dir", c.Streams.ClipDir})
+	}
+	if c.Streams.ThumbnailDir != "" {
+		dirs = append(dirs, outputDir{"streams thumbnail_dir", c.Streams.ThumbnailDir})
+	}
+
+	for _, dir := range dirs {
+		if dir.path == "" {
+			continue
+		}
+		if err := CheckWritableDir(dir.path); err != nil {
+			return fmt.Errorf("%s: %w", dir.key, err)
+		}
+	}
+
+	return nil
+}
+
+// CheckWritableDir creates dir if needed and checks that files can be written to it
+func CheckWritableDir(dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("cannot create directory %s: %w", dir, err)
+	}
+
+	f, err := os.CreateTemp(dir, ".write-check-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	name := f.Name()
+	f.Close()
+	return os.Remove(name)
+}
+
 // GetDSN returns the PostgreSQL connection string
 func (c *DatabaseConfig) GetDSN() string {
 	return fmt.Sprintf(
@@ -198,8 +548,25 @@ func (c *RedisConfig) GetRedisAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// GetBrokerURL returns the MQTT broker URL
+func (c *MQTTConfig) GetBrokerURL() string {
+	port := c.Port
+	if port == 0 {
+		port = 1883
+	}
+	return fmt.Sprintf("tcp://%s:%d", c.Host, port)
+}
+
 // GetServerAddr returns the server address
 func (c *ServerConfig) GetServerAddr() string {
 	return fmt.Sprintf("%s:%d", c.Host, c.Port)
 }
 
+// validNetwork reports whether value is a network in CIDR notation or an IP address
+func validNetwork(value string) bool {
+	value = strings.TrimSpace(value)
+	if _, _, err := net.ParseCIDR(value); err == nil {
+		return true
+	}
+	return net.ParseIP(value) != nil
+}
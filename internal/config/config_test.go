@@ -0,0 +1,405 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const baseConfig = `
+server:
+  host: 0.0.0.0
+  port: 8080
+database:
+  host: localhost
+  port: 5432
+  name: reolink
+  user: reolink
+  password: base-password
+redis:
+  host: localhost
+  port: 6379
+logging:
+  level: info
+auth:
+  jwt_secret: base-secret-that-is-at-least-32-characters
+`
+
+func writeConfigFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestLoad_BaseConfig(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, "base-password", cfg.Database.Password)
+	assert.Equal(t, "info", cfg.Logging.Level)
+}
+
+func TestLoad_EnvironmentOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+	writeConfigFile(t, dir, "config.production.yaml", `
+server:
+  port: 9000
+logging:
+  level: warn
+`)
+
+	t.Setenv("CONFIG_ENV", "production")
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 9000, cfg.Server.Port)
+	assert.Equal(t, "warn", cfg.Logging.Level)
+
+	// Keys not overridden keep their base values
+	assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+}
+
+func TestLoad_MissingEnvironmentOverrideIsIgnored(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	t.Setenv("CONFIG_ENV", "staging")
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+}
+
+func TestLoad_IncludeSecretsFile(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+include:
+  - secrets/secrets.json
+`)
+	writeConfigFile(t, dir, "secrets/secrets.json", `{
+  "database": {"password": "included-password"},
+  "auth": {"jwt_secret": "included-secret-that-is-at-least-32-chars"}
+}`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "included-password", cfg.Database.Password)
+	assert.Equal(t, "included-secret-that-is-at-least-32-chars", cfg.Auth.JWTSecret)
+	assert.Equal(t, "localhost", cfg.Database.Host)
+}
+
+func TestLoad_MergePrecedence(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+	writeConfigFile(t, dir, "config.production.yaml", `
+database:
+  password: override-password
+  name: reolink_prod
+logging:
+  level: warn
+include:
+  - secrets.toml
+`)
+	writeConfigFile(t, dir, "secrets.toml", `
+[database]
+password = "secret-password"
+`)
+
+	t.Setenv("CONFIG_ENV", "production")
+	t.Setenv("LOGGING_LEVEL", "debug")
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	// include > environment override > base
+	assert.Equal(t, "secret-password", cfg.Database.Password)
+	assert.Equal(t, "reolink_prod", cfg.Database.Name)
+
+	// environment variables win over every file
+	assert.Equal(t, "debug", cfg.Logging.Level)
+}
+
+func TestLoad_IncludeErrors(t *testing.T) {
+	t.Run("missing include", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+include:
+  - missing.yaml
+`)
+
+		_, err := Load(path)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "missing.yaml")
+	})
+
+	t.Run("include cycle", func(t *testing.T) {
+		dir := t.TempDir()
+		path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+include:
+  - other.yaml
+`)
+		writeConfigFile(t, dir, "other.yaml", `
+include:
+  - config.yaml
+`)
+
+		_, err := Load(path)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "includes itself")
+	})
+}
+
+func TestLoad_SharedInclude(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+include:
+  - secrets.yaml
+`)
+	writeConfigFile(t, dir, "config.production.yaml", `
+database:
+  password: override-password
+  name: reolink_prod
+include:
+  - secrets.yaml
+  - common.yaml
+`)
+	writeConfigFile(t, dir, "common.yaml", `
+include:
+  - secrets.yaml
+`)
+	writeConfigFile(t, dir, "secrets.yaml", `
+database:
+  password: secret-password
+`)
+
+	t.Setenv("CONFIG_ENV", "production")
+
+	// A file included by several files is not a cycle
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-password", cfg.Database.Password)
+	assert.Equal(t, "reolink_prod", cfg.Database.Name)
+}
+
+func TestEnvConfigPath(t *testing.T) {
+	assert.Equal(t, "configs/config.production.yaml", envConfigPath("configs/config.yaml", "production"))
+	assert.Equal(t, "/etc/reolink/server.dev.json", envConfigPath("/etc/reolink/server.json", "dev"))
+}
+
+func TestValidate_TLS(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	cfg.Server.TLS = TLSConfig{Enabled: true}
+	assert.ErrorContains(t, cfg.Validate(), "cert_file and key_file")
+
+	cfg.Server.TLS = TLSConfig{Enabled: true, AutoCert: true}
+	assert.ErrorContains(t, cfg.Validate(), "autocert requires")
+
+	cfg.Server.TLS = TLSConfig{Enabled: true, CertFile: "server.crt", KeyFile: "server.key"}
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_ControlRateLimit(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	cfg.API.ControlRatePerMinute = -1
+	assert.ErrorContains(t, cfg.Validate(), "control_rate_per_minute")
+
+	cfg.API.ControlRatePerMinute = 60
+	cfg.API.ControlBurst = 10
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	cfg.API.TrustedProxies = []string{"127.0.0.1", "10.0.0.0/8"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.API.TrustedProxies = []string{"proxy.local"}
+	assert.ErrorContains(t, cfg.Validate(), `invalid api trusted proxy "proxy.local"`)
+}
+
+func TestValidate_CameraNetworks(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+
+	cfg.Cameras.AllowedNetworks = []string{"192.168.1.0/24", "10.0.0.5"}
+	cfg.Cameras.DeniedNetworks = []string{"fd00::/8"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Cameras.DeniedNetworks = []string{"192.168.1"}
+	assert.ErrorContains(t, cfg.Validate(), `invalid cameras network "192.168.1"`)
+}
+
+func TestValidate_EventCooldowns(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+events:
+  cooldown: 30s
+  cooldowns:
+    ai_person: 30s
+    motion_detected: 5s
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"ai_person": 30 * time.Second, "motion_detected": 5 * time.Second}, cfg.Events.Cooldowns)
+	assert.NoError(t, cfg.Validate())
+
+	// Misspelled event types would silently use the default cooldown
+	cfg.Events.Cooldowns["person"] = time.Minute
+	assert.ErrorContains(t, cfg.Validate(), `invalid event type "person"`)
+
+	delete(cfg.Events.Cooldowns, "person")
+	cfg.Events.Cooldowns["ai_vehicle"] = -time.Second
+	assert.ErrorContains(t, cfg.Validate(), "cooldowns ai_vehicle must not be negative")
+}
+
+func TestLoad_ConnectionProfiles(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig+`
+cameras:
+  connection_profiles:
+    starlink:
+      timeout: 20s
+      max_retries: 5
+      keep_alive: true
+      rtsp_transport: tcp
+`)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]ConnectionProfileConfig{
+		"starlink": {Timeout: 20 * time.Second, MaxRetries: 5, KeepAlive: true, RTSPTransport: "tcp"},
+	}, cfg.Cameras.ConnectionProfiles)
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Cameras.ConnectionProfiles["starlink"] = ConnectionProfileConfig{RTSPTransport: "quic"}
+	assert.ErrorContains(t, cfg.Validate(), `connection profile "starlink": invalid rtsp_transport "quic"`)
+
+	cfg.Cameras.ConnectionProfiles["starlink"] = ConnectionProfileConfig{Timeout: -time.Second}
+	assert.ErrorContains(t, cfg.Validate(), "must not be negative")
+}
+
+func TestCheckWritableDir(t *testing.T) {
+	dir := t.TempDir()
+
+	// Missing directories are created
+	nested := filepath.Join(dir, "hls", "sessions")
+	require.NoError(t, CheckWritableDir(nested))
+	assert.DirExists(t, nested)
+
+	entries, err := os.ReadDir(nested)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "write check must not leave files behind")
+
+	// A path below a regular file can never be a directory
+	file := writeConfigFile(t, dir, "not-a-dir", "x")
+	assert.ErrorContains(t, CheckWritableDir(filepath.Join(file, "hls")), "cannot create directory")
+}
+
+func TestCheckWritableDir_ReadOnly(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("root bypasses directory permissions")
+	}
+
+	dir := filepath.Join(t.TempDir(), "readonly")
+	require.NoError(t, os.Mkdir(dir, 0555))
+
+	assert.ErrorContains(t, CheckWritableDir(dir), "is not writable")
+}
+
+func TestCheckOutputDirs(t *testing.T) {
+	dir := t.TempDir()
+	file := writeConfigFile(t, dir, "not-a-dir", "x")
+
+	cfg := &Config{}
+	assert.NoError(t, cfg.CheckOutputDirs(), "unset directories are skipped")
+
+	cfg.Streams.HLSOutputDir = filepath.Join(dir, "hls")
+	assert.NoError(t, cfg.CheckOutputDirs())
+
+	cfg.Streams.HLSOutputDir = filepath.Join(file, "hls")
+	assert.ErrorContains(t, cfg.CheckOutputDirs(), "streams hls_output_dir")
+
+	cfg.Streams.HLSOutputDir = ""
+	cfg.Server.TLS = TLSConfig{AutoCertCacheDir: filepath.Join(file, "certs")}
+	assert.NoError(t, cfg.CheckOutputDirs(), "cache dir is only checked with autocert enabled")
+
+	cfg.Server.TLS.Enabled = true
+	cfg.Server.TLS.AutoCert = true
+	assert.ErrorContains(t, cfg.CheckOutputDirs(), "server tls autocert_cache_dir")
+
+	cfg.Server.TLS = TLSConfig{}
+	cfg.Cameras.TokenCacheFile = filepath.Join(file, "tokens.json")
+	assert.ErrorContains(t, cfg.CheckOutputDirs(), "cameras token_cache_file")
+
+	cfg.Cameras.TokenCacheFile = ""
+	cfg.Cameras.SnapshotDir = filepath.Join(file, "snapshots")
+	assert.ErrorContains(t, cfg.CheckOutputDirs(), "cameras snapshot_dir")
+}
+
+func TestValidate_HddFullPercent(t *testing.T) {
+	dir := t.TempDir()
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig)
+
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Zero(t, cfg.Cameras.HddFullPercent, "disk usage is ignored unless configured")
+
+	cfg.Cameras.HddFullPercent = 95
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Cameras.HddFullPercent = 101
+	assert.ErrorContains(t, cfg.Validate(), "hdd_full_percent")
+}
+
+func TestLoad_DeprecatedEventRetentionDays(t *testing.T) {
+	dir := t.TempDir()
+
+	// The old setting still applies while the new one is unset
+	path := writeConfigFile(t, dir, "config.yaml", baseConfig+"events:\n  retention_days: 14\n")
+	cfg, err := Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 14, cfg.Retention.EventDays)
+	require.Len(t, cfg.Deprecations, 1)
+	assert.Contains(t, cfg.Deprecations[0], "events.retention_days")
+
+	// The new setting wins when both are set
+	path = writeConfigFile(t, dir, "both.yaml", baseConfig+"events:\n  retention_days: 14\nretention:\n  event_days: 30\n")
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, 30, cfg.Retention.EventDays)
+	assert.Len(t, cfg.Deprecations, 1)
+
+	path = writeConfigFile(t, dir, "new.yaml", baseConfig+"retention:\n  event_days: 30\n")
+	cfg, err = Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Deprecations)
+}
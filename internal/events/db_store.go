@@ -0,0 +1,48 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// dbStoreTimeout bounds how long persisting a single event may take
+const dbStoreTimeout = 5 * time.Second
+
+// EventRepository is the database storage used by DBStore
+type EventRepository interface {
+	CreateIfNotExists(ctx context.Context, event *models.Event) (bool, error)
+}
+
+// DBStore is a subscriber persisting events to the events table, where the
+// events API reads them from
+type DBStore struct {
+	repo EventRepository
+}
+
+// NewDBStore creates a new database event store
+func NewDBStore(repo EventRepository) *DBStore {
+	return &DBStore{repo: repo}
+}
+
+// OnEvent implements the Subscriber interface. Events that are already stored
+// are skipped.
+func (s *DBStore) OnEvent(event *models.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbStoreTimeout)
+	defer cancel()
+
+	inserted, err := s.repo.CreateIfNotExists(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to persist event %s: %w", event.ID, err)
+	}
+
+	if !inserted {
+		logger.Debug("Event already persisted",
+			correlation(event))
+	}
+
+	return nil
+}
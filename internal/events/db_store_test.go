@@ -0,0 +1,63 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// fakeEventRepository stores events in memory, optionally failing every insert
+type fakeEventRepository struct {
+	events map[string]*models.Event
+	err    error
+}
+
+func (f *fakeEventRepository) CreateIfNotExists(ctx context.Context, event *models.Event) (bool, error) {
+	if f.err != nil {
+		return false, f.err
+	}
+	if _, ok := f.events[event.ID]; ok {
+		return false, nil
+	}
+	f.events[event.ID] = event
+	return true, nil
+}
+
+func TestDBStore_OnEvent_PersistsEvent(t *testing.T) {
+	repo := &fakeEventRepository{events: make(map[string]*models.Event)}
+	store := NewDBStore(repo)
+
+	event := &models.Event{ID: "evt-1", CameraID: "cam-1", Type: models.EventMotionDetected}
+
+	assert.NoError(t, store.OnEvent(event))
+	assert.Same(t, event, repo.events["evt-1"])
+
+	// Duplicates are skipped without an error
+	assert.NoError(t, store.OnEvent(event))
+	assert.Len(t, repo.events, 1)
+}
+
+func TestDBStore_OnEvent_ReportsFailure(t *testing.T) {
+	store := NewDBStore(&fakeEventRepository{err: errors.New("connection refused")})
+
+	err := store.OnEvent(&models.Event{ID: "evt-1"})
+
+	assert.ErrorContains(t, err, "evt-1")
+}
+
+func TestDBStore_ProcessorKeepsDispatchingOnFailure(t *testing.T) {
+	processor := NewProcessor(camera.NewManager(nil, nil), nil)
+	processor.Subscribe(NewDBStore(&fakeEventRepository{err: errors.New("connection refused")}))
+
+	received := &MockSubscriber{}
+	processor.Subscribe(received)
+
+	dispatch(t, processor, &models.Event{ID: "evt-1"})
+
+	assert.Len(t, received.events, 1)
+}
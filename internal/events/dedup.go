@@ -0,0 +1,114 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// DefaultEventCooldown is how long repeats of an event are suppressed by default
+const DefaultEventCooldown = 30 * time.Second
+
+// dedupKey identifies a stream of repeated events
+type dedupKey struct {
+	cameraID  string
+	eventType models.EventType
+	channel   int
+}
+
+// dedupEntry tracks the latest occurrence of an event
+type dedupEntry struct {
+	lastSeen   time.Time
+	suppressed int
+}
+
+// eventDedup suppresses events repeating an event of the same type, camera
+// and channel seen less than a cooldown ago, such as motion reported on every
+// poll while it lasts. Suppressed events only move the last seen time, so the
+// first event after a quiet period of one cooldown fires again.
+type eventDedup struct {
+	cooldown  time.Duration
+	cooldowns map[models.EventType]time.Duration
+	entries   map[dedupKey]*dedupEntry
+	mu        sync.Mutex
+}
+
+// newEventDedup creates a dedup layer with a default cooldown and per-type
+// overrides; a zero cooldown disables suppression for the types it applies to
+func newEventDedup(cooldown time.Duration, cooldowns map[models.EventType]time.Duration) *eventDedup {
+	return &eventDedup{
+		cooldown:  cooldown,
+		cooldowns: cooldowns,
+		entries:   make(map[dedupKey]*dedupEntry),
+	}
+}
+
+// cooldownFor returns the cooldown of an event type
+func (d *eventDedup) cooldownFor(eventType models.EventType) time.Duration {
+	if cooldown, ok := d.cooldowns[eventType]; ok {
+		return cooldown
+	}
+	return d.cooldown
+}
+
+// admit reports whether event may be published at now, recording it as the
+// latest occurrence either way
+func (d *eventDedup) admit(event *models.Event, now time.Time) bool {
+	cooldown := d.cooldownFor(event.Type)
+	if cooldown <= 0 {
+		return true
+	}
+
+	key := dedupKey{cameraID: event.CameraID, eventType: event.Type, channel: eventChannel(event)}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, ok := d.entries[key]
+	if ok && now.Sub(entry.lastSeen) < cooldown {
+		entry.lastSeen = now
+		entry.suppressed++
+		return false
+	}
+
+	if ok && entry.suppressed > 0 {
+		logger.Debug("Repeated event ended",
+			zap.String("camera_id", key.cameraID),
+			zap.String("type", string(key.eventType)),
+			zap.Int("channel", key.channel),
+			zap.Int("suppressed", entry.suppressed))
+	}
+	d.entries[key] = &dedupEntry{lastSeen: now}
+	d.prune(now)
+	return true
+}
+
+// prune drops entries that are past their cooldown, so cameras that stopped
+// reporting do not accumulate. Callers hold the lock.
+func (d *eventDedup) prune(now time.Time) {
+	for key, entry := range d.entries {
+		if now.Sub(entry.lastSeen) >= d.cooldownFor(key.eventType) {
+			delete(d.entries, key)
+		}
+	}
+}
+
+// eventChannel returns the channel recorded in an event's metadata, 0 if none
+func eventChannel(event *models.Event) int {
+	if event.Metadata == "" {
+		return 0
+	}
+
+	var metadata struct {
+		Channel int `json:"channel"`
+	}
+	if err := json.Unmarshal([]byte(event.Metadata), &metadata); err != nil {
+		return 0
+	}
+	return metadata.Channel
+}
@@ -0,0 +1,184 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/mosleyit/reolink_server/internal/clock"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// dedupEvent builds an event of a type on a camera channel
+func dedupEvent(cameraID string, eventType models.EventType, channel int) *models.Event {
+	return &models.Event{
+		CameraID: cameraID,
+		Type:     eventType,
+		Metadata: fmt.Sprintf(`{"channel":%d}`, channel),
+	}
+}
+
+func TestEventDedup_Admit(t *testing.T) {
+	motion := func(channel int) *models.Event { return dedupEvent("cam-1", models.EventMotionDetected, channel) }
+
+	type step struct {
+		after time.Duration // Advance of the mock clock before the event
+		event *models.Event
+		want  bool
+	}
+
+	tests := []struct {
+		name      string
+		cooldowns map[models.EventType]time.Duration
+		steps     []step
+	}{
+		{
+			name: "persistent motion is suppressed",
+			steps: []step{
+				{0, motion(0), true},
+				{5 * time.Second, motion(0), false},
+				{5 * time.Second, motion(0), false},
+				// Each repeat moves the last seen time, so motion lasting longer than
+				// the cooldown stays suppressed
+				{25 * time.Second, motion(0), false},
+			},
+		},
+		{
+			name: "first event after a quiet period fires",
+			steps: []step{
+				{0, motion(0), true},
+				{5 * time.Second, motion(0), false},
+				{30 * time.Second, motion(0), true},
+				{29 * time.Second, motion(0), false},
+			},
+		},
+		{
+			name: "cameras, types and channels are separate",
+			steps: []step{
+				{0, motion(0), true},
+				{0, motion(1), true},
+				{0, dedupEvent("cam-2", models.EventMotionDetected, 0), true},
+				{0, dedupEvent("cam-1", models.EventAIPerson, 0), true},
+				{time.Second, motion(1), false},
+			},
+		},
+		{
+			name:      "per-type cooldown",
+			cooldowns: map[models.EventType]time.Duration{models.EventAIPerson: 2 * time.Minute},
+			steps: []step{
+				{0, dedupEvent("cam-1", models.EventAIPerson, 0), true},
+				{0, motion(0), true},
+				{time.Minute, dedupEvent("cam-1", models.EventAIPerson, 0), false},
+				{0, motion(0), true},
+				{2 * time.Minute, dedupEvent("cam-1", models.EventAIPerson, 0), true},
+			},
+		},
+		{
+			name:      "zero cooldown disables suppression for a type",
+			cooldowns: map[models.EventType]time.Duration{models.EventMotionDetected: 0},
+			steps: []step{
+				{0, motion(0), true},
+				{time.Second, motion(0), true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dedup := newEventDedup(30*time.Second, tt.cooldowns)
+			now := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+
+			for i, s := range tt.steps {
+				now = now.Add(s.after)
+				assert.Equal(t, s.want, dedup.admit(s.event, now), "step %d", i)
+			}
+		})
+	}
+}
+
+func TestEventDedup_LastSeen(t *testing.T) {
+	dedup := newEventDedup(30*time.Second, nil)
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+
+	dedup.admit(dedupEvent("cam-1", models.EventMotionDetected, 2), start)
+	dedup.admit(dedupEvent("cam-1", models.EventMotionDetected, 2), start.Add(10*time.Second))
+
+	entry, ok := dedup.entries[dedupKey{cameraID: "cam-1", eventType: models.EventMotionDetected, channel: 2}]
+	assert.True(t, ok)
+	assert.Equal(t, start.Add(10*time.Second), entry.lastSeen)
+	assert.Equal(t, 1, entry.suppressed)
+
+	// Entries past their cooldown are pruned
+	dedup.admit(dedupEvent("cam-2", models.EventMotionDetected, 0), start.Add(time.Minute))
+	assert.Len(t, dedup.entries, 1)
+}
+
+func TestProcessor_PublishDetection_Dedup(t *testing.T) {
+	processor := NewProcessor(nil, nil)
+
+	for i := 0; i < 10; i++ {
+		processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0))
+	}
+	processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 1))
+
+	assert.Len(t, processor.eventCh, 2)
+}
+
+func TestProcessor_PublishDetection_CooldownWithClock(t *testing.T) {
+	processor := NewProcessor(nil, nil)
+	fake := clock.NewFake(time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC))
+	processor.SetClock(fake)
+
+	processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0))
+
+	// A repeat within the cooldown is suppressed and extends it
+	fake.Advance(10 * time.Second)
+	processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0))
+	fake.Advance(DefaultEventCooldown - time.Second)
+	processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0))
+	assert.Len(t, processor.eventCh, 1)
+
+	// After a quiet cooldown the detection fires again
+	fake.Advance(DefaultEventCooldown)
+	processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0))
+	assert.Len(t, processor.eventCh, 2)
+}
+
+func TestProcessor_PublishDetection_PerTypeCooldowns(t *testing.T) {
+	config := DefaultConfig()
+	config.EventCooldowns = map[models.EventType]time.Duration{
+		models.EventAIPerson:       30 * time.Second,
+		models.EventMotionDetected: 5 * time.Second,
+	}
+	processor := NewProcessor(nil, config)
+	fake := clock.NewFake(time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC))
+	processor.SetClock(fake)
+
+	person := func() { processor.publishDetection(dedupEvent("cam-1", models.EventAIPerson, 0)) }
+	motion := func() { processor.publishDetection(dedupEvent("cam-1", models.EventMotionDetected, 0)) }
+
+	person()
+	motion()
+	assert.Len(t, processor.eventCh, 2)
+
+	// Motion fires again after its own quiet window while person stays
+	// within its longer one
+	fake.Advance(6 * time.Second)
+	motion()
+	person()
+	assert.Len(t, processor.eventCh, 3)
+
+	// Each repeat only extends the window of its own type
+	fake.Advance(6 * time.Second)
+	motion()
+	assert.Len(t, processor.eventCh, 4)
+	fake.Advance(20 * time.Second)
+	person()
+	assert.Len(t, processor.eventCh, 4, "person repeated within 30s of its last detection")
+	fake.Advance(30 * time.Second)
+	person()
+	motion()
+	assert.Len(t, processor.eventCh, 6)
+}
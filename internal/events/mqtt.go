@@ -0,0 +1,254 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+const (
+	// mqttTimeout bounds connecting to the broker and publishing a single message
+	mqttTimeout = 5 * time.Second
+
+	// mqttQoS is the quality of service of every published message
+	mqttQoS = 1
+
+	// Availability payloads published to <prefix>/status
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+)
+
+// errMQTTDisconnected is returned for messages published while the broker
+// connection is down
+var errMQTTDisconnected = errors.New("not connected to MQTT broker")
+
+// MQTTClient is the part of an MQTT client used by MQTTPublisher, so the
+// broker connection can be replaced in tests
+type MQTTClient interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+	Disconnect()
+}
+
+// MQTTConfig holds MQTT publisher configuration
+type MQTTConfig struct {
+	BrokerURL       string // e.g. tcp://localhost:1883
+	ClientID        string
+	Username        string
+	Password        string
+	TopicPrefix     string // Events go to <prefix>/<camera_id>/<type>
+	DiscoveryPrefix string // Home Assistant discovery prefix; empty disables discovery
+}
+
+// MQTTPublisher is a subscriber publishing events to an MQTT broker as JSON,
+// for Home Assistant and other home automation systems. The server's
+// availability is published, retained, to <prefix>/status; the broker
+// announces it offline when the connection drops.
+type MQTTPublisher struct {
+	client          MQTTClient
+	prefix          string
+	discoveryPrefix string
+
+	// discovered holds the discovery topics already announced
+	discovered map[string]bool
+	mu         sync.Mutex
+}
+
+// NewMQTTPublisher connects to the broker and returns a publisher. The
+// connection is re-established automatically when it drops; a broker that
+// cannot be reached at startup is retried in the background.
+func NewMQTTPublisher(config *MQTTConfig) (*MQTTPublisher, error) {
+	prefix := strings.TrimSuffix(config.TopicPrefix, "/")
+	if prefix == "" {
+		prefix = "reolink"
+	}
+	statusTopic := prefix + "/status"
+
+	clientID := config.ClientID
+	if clientID == "" {
+		clientID = "reolink-server"
+	}
+
+	opts := mqtt.NewClientOptions().
+		AddBroker(config.BrokerURL).
+		SetClientID(clientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetConnectTimeout(mqttTimeout).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetWill(statusTopic, mqttOffline, mqttQoS, true).
+		SetOnConnectHandler(func(c mqtt.Client) {
+			logger.Info("Connected to MQTT broker", zap.String("broker", config.BrokerURL))
+			c.Publish(statusTopic, mqttQoS, true, mqttOnline)
+		}).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			logger.Warn("Lost connection to MQTT broker, reconnecting",
+				zap.String("broker", config.BrokerURL),
+				zap.Error(err))
+		})
+
+	client := mqtt.NewClient(opts)
+	token := client.Connect()
+	if !token.WaitTimeout(mqttTimeout) {
+		logger.Warn("MQTT broker not reachable yet, retrying in the background",
+			zap.String("broker", config.BrokerURL))
+	} else if err := token.Error(); err != nil {
+		return nil, fmt.Errorf("failed to connect to MQTT broker: %w", err)
+	}
+
+	return newMQTTPublisher(&pahoClient{client: client, statusTopic: statusTopic}, prefix, config.DiscoveryPrefix), nil
+}
+
+// newMQTTPublisher creates a publisher on an already configured client
+func newMQTTPublisher(client MQTTClient, prefix, discoveryPrefix string) *MQTTPublisher {
+	return &MQTTPublisher{
+		client:          client,
+		prefix:          prefix,
+		discoveryPrefix: strings.TrimSuffix(discoveryPrefix, "/"),
+		discovered:      make(map[string]bool),
+	}
+}
+
+// OnEvent implements the Subscriber interface, publishing the event to
+// <prefix>/<camera_id>/<type>
+func (p *MQTTPublisher) OnEvent(event *models.Event) error {
+	if err := p.announce(event); err != nil {
+		logger.Warn("Failed to publish Home Assistant discovery config",
+			correlation(event),
+			zap.Error(err))
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event %s: %w", event.ID, err)
+	}
+
+	if err := p.client.Publish(p.eventTopic(event), mqttQoS, false, payload); err != nil {
+		return fmt.Errorf("failed to publish event %s to MQTT: %w", event.ID, err)
+	}
+	return nil
+}
+
+// eventTopic returns the topic an event is published to
+func (p *MQTTPublisher) eventTopic(event *models.Event) string {
+	return fmt.Sprintf("%s/%s/%s", p.prefix, event.CameraID, event.Type)
+}
+
+// announce publishes a Home Assistant discovery config the first time an
+// event type is seen on a camera, so the pair shows up as an event entity
+// of the camera's device
+func (p *MQTTPublisher) announce(event *models.Event) error {
+	if p.discoveryPrefix == "" {
+		return nil
+	}
+
+	objectID := discoveryID(p.prefix + "_" + event.CameraID + "_" + string(event.Type))
+	topic := fmt.Sprintf("%s/event/%s/config", p.discoveryPrefix, objectID)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered[topic] {
+		return nil
+	}
+
+	name := event.CameraName
+	if name == "" {
+		name = event.CameraID
+	}
+
+	config := map[string]interface{}{
+		"name":               strings.ReplaceAll(string(event.Type), "_", " "),
+		"unique_id":          objectID,
+		"state_topic":        p.eventTopic(event),
+		"availability_topic": p.prefix + "/status",
+		"event_types":        []string{string(event.Type)},
+		"value_template":     "{{ {'event_type': value_json.type} | to_json }}",
+		"device": map[string]interface{}{
+			"identifiers":  []string{discoveryID(p.prefix + "_" + event.CameraID)},
+			"name":         name,
+			"manufacturer": "Reolink",
+		},
+	}
+
+	payload, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	if err := p.client.Publish(topic, mqttQoS, true, payload); err != nil {
+		return err
+	}
+
+	p.discovered[topic] = true
+	return nil
+}
+
+// discoveryID turns s into an ID Home Assistant accepts in discovery topics
+func discoveryID(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// Close announces the server offline and disconnects from the broker
+func (p *MQTTPublisher) Close() error {
+	p.client.Disconnect()
+	return nil
+}
+
+// pahoConnection is the part of a paho MQTT client used by pahoClient
+type pahoConnection interface {
+	Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token
+	IsConnected() bool
+	IsConnectionOpen() bool
+	Disconnect(quiesce uint)
+}
+
+// pahoClient adapts a paho MQTT client to MQTTClient
+type pahoClient struct {
+	client      pahoConnection
+	statusTopic string
+}
+
+// Publish hands a message to the broker connection without waiting for the
+// broker to acknowledge it, so a slow or unreachable broker cannot hold up
+// event delivery. Messages published while the connection is down fail
+// right away; acknowledgements that fail or time out are logged.
+func (c *pahoClient) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	if !c.client.IsConnectionOpen() {
+		return fmt.Errorf("%w, dropping message to %s", errMQTTDisconnected, topic)
+	}
+
+	token := c.client.Publish(topic, qos, retained, payload)
+	go func() {
+		if !token.WaitTimeout(mqttTimeout) {
+			logger.Warn("Timed out publishing to MQTT broker", zap.String("topic", topic))
+			return
+		}
+		if err := token.Error(); err != nil {
+			logger.Warn("Failed to publish to MQTT broker", zap.String("topic", topic), zap.Error(err))
+		}
+	}()
+	return nil
+}
+
+// Disconnect announces the server offline, since a clean disconnect does not
+// trigger the will, and closes the connection
+func (c *pahoClient) Disconnect() {
+	if c.client.IsConnected() {
+		c.client.Publish(c.statusTopic, mqttQoS, true, mqttOffline).WaitTimeout(mqttTimeout)
+	}
+	c.client.Disconnect(250)
+}
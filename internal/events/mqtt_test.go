@@ -0,0 +1,185 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// mqttMessage is a message published through mockMQTTClient
+type mqttMessage struct {
+	topic    string
+	retained bool
+	payload  []byte
+}
+
+// mockMQTTClient records published messages instead of talking to a broker
+type mockMQTTClient struct {
+	mu           sync.Mutex
+	messages     []mqttMessage
+	err          error
+	disconnected bool
+}
+
+func (c *mockMQTTClient) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.messages = append(c.messages, mqttMessage{topic: topic, retained: retained, payload: payload})
+	return nil
+}
+
+func (c *mockMQTTClient) Disconnect() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.disconnected = true
+}
+
+func (c *mockMQTTClient) topics() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	topics := make([]string, 0, len(c.messages))
+	for _, msg := range c.messages {
+		topics = append(topics, msg.topic)
+	}
+	return topics
+}
+
+func newMQTTTestEvent(eventType models.EventType) *models.Event {
+	return &models.Event{
+		ID:         "evt-1",
+		CameraID:   "cam-1",
+		CameraName: "Front Door",
+		Type:       eventType,
+		Severity:   models.SeverityInfo,
+		Timestamp:  time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestMQTTPublisher_PublishesEventAsJSON(t *testing.T) {
+	client := &mockMQTTClient{}
+	publisher := newMQTTPublisher(client, "reolink", "")
+
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected)))
+
+	require.Len(t, client.messages, 1)
+	msg := client.messages[0]
+	assert.Equal(t, "reolink/cam-1/motion_detected", msg.topic)
+	assert.False(t, msg.retained)
+
+	var event models.Event
+	require.NoError(t, json.Unmarshal(msg.payload, &event))
+	assert.Equal(t, "evt-1", event.ID)
+	assert.Equal(t, models.EventMotionDetected, event.Type)
+}
+
+func TestMQTTPublisher_AnnouncesDiscoveryOnce(t *testing.T) {
+	client := &mockMQTTClient{}
+	publisher := newMQTTPublisher(client, "reolink", "homeassistant/")
+
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected)))
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected)))
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventAIPerson)))
+
+	assert.Equal(t, []string{
+		"homeassistant/event/reolink_cam-1_motion_detected/config",
+		"reolink/cam-1/motion_detected",
+		"reolink/cam-1/motion_detected",
+		"homeassistant/event/reolink_cam-1_ai_person/config",
+		"reolink/cam-1/ai_person",
+	}, client.topics())
+
+	discovery := client.messages[0]
+	assert.True(t, discovery.retained)
+
+	var config map[string]interface{}
+	require.NoError(t, json.Unmarshal(discovery.payload, &config))
+	assert.Equal(t, "reolink/cam-1/motion_detected", config["state_topic"])
+	assert.Equal(t, "reolink/status", config["availability_topic"])
+	assert.Equal(t, "Front Door", config["device"].(map[string]interface{})["name"])
+}
+
+func TestMQTTPublisher_PublishError(t *testing.T) {
+	client := &mockMQTTClient{err: errors.New("not connected")}
+	publisher := newMQTTPublisher(client, "reolink", "homeassistant")
+
+	err := publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected))
+	assert.ErrorContains(t, err, "not connected")
+
+	// Discovery is retried with the next event once the broker is back
+	client.err = nil
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected)))
+	assert.Equal(t, []string{
+		"homeassistant/event/reolink_cam-1_motion_detected/config",
+		"reolink/cam-1/motion_detected",
+	}, client.topics())
+}
+
+func TestMQTTPublisher_Close(t *testing.T) {
+	client := &mockMQTTClient{}
+	publisher := newMQTTPublisher(client, "reolink", "")
+
+	require.NoError(t, publisher.Close())
+	assert.True(t, client.disconnected)
+}
+
+func TestDiscoveryID(t *testing.T) {
+	assert.Equal(t, "reolink_cam-1_motion", discoveryID("reolink_cam-1_motion"))
+	assert.Equal(t, "home_reolink_cam_1", discoveryID("home/reolink cam.1"))
+}
+
+// pendingToken is a publish the broker never acknowledges
+type pendingToken struct{ done chan struct{} }
+
+func (t *pendingToken) Wait() bool { <-t.done; return true }
+func (t *pendingToken) WaitTimeout(d time.Duration) bool {
+	select {
+	case <-t.done:
+		return true
+	case <-time.After(d):
+		return false
+	}
+}
+func (t *pendingToken) Done() <-chan struct{} { return t.done }
+func (t *pendingToken) Error() error          { return nil }
+
+// stalledConnection is a paho connection whose broker never acknowledges publishes
+type stalledConnection struct {
+	open      bool
+	published int
+}
+
+func (c *stalledConnection) Publish(topic string, qos byte, retained bool, payload interface{}) mqtt.Token {
+	c.published++
+	return &pendingToken{done: make(chan struct{})}
+}
+func (c *stalledConnection) IsConnected() bool      { return c.open }
+func (c *stalledConnection) IsConnectionOpen() bool { return c.open }
+func (c *stalledConnection) Disconnect(uint)        {}
+
+func TestPahoClient_PublishDoesNotWaitForBroker(t *testing.T) {
+	conn := &stalledConnection{open: true}
+	publisher := newMQTTPublisher(&pahoClient{client: conn, statusTopic: "reolink/status"}, "reolink", "")
+
+	// A broker that does not acknowledge cannot hold up event delivery
+	start := time.Now()
+	require.NoError(t, publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected)))
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, 1, conn.published)
+
+	// Without a connection events fail right away instead of queueing
+	conn.open = false
+	err := publisher.OnEvent(newMQTTTestEvent(models.EventMotionDetected))
+	assert.ErrorIs(t, err, errMQTTDisconnected)
+	assert.Equal(t, 1, conn.published)
+}
@@ -0,0 +1,97 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// newNVRClient returns a client for a fake NVR with channels 0-2, of which
+// channel 1 is offline, reporting motion and people on every channel asked.
+// The returned counter reports how often the channel status was queried.
+func newNVRClient(t *testing.T) (*camera.CameraClient, *atomic.Int32) {
+	var statusQueries atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req []struct {
+			Cmd   string `json:"cmd"`
+			Param struct {
+				Channel int `json:"channel"`
+			} `json:"param"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		switch cmd, channel := req[0].Cmd, req[0].Param.Channel; cmd {
+		case "Getchannelstatus":
+			statusQueries.Add(1)
+			w.Write([]byte(`[{"cmd":"Getchannelstatus","code":0,"value":{"count":3,"status":[` +
+				`{"channel":0,"online":1},{"channel":1,"online":0},{"channel":2,"online":1}]}}]`))
+		case "GetMdState":
+			w.Write([]byte(`[{"cmd":"GetMdState","code":0,"value":{"state":1}}]`))
+		case "GetAiState":
+			fmt.Fprintf(w, `[{"cmd":"GetAiState","code":0,"value":{"channel":%d,"people":{"alarm_state":1,"support":1}}}]`, channel)
+		default:
+			w.Write([]byte(`[{"cmd":"` + cmd + `","code":0,"value":{}}]`))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return &camera.CameraClient{
+		Camera: &models.Camera{ID: "nvr-1", Name: "NVR"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}, &statusQueries
+}
+
+// drainChannels returns the metadata channels of the buffered events of a type
+func drainChannels(t *testing.T, processor *Processor, eventType models.EventType) []int {
+	var channels []int
+	for len(processor.eventCh) > 0 {
+		event := <-processor.eventCh
+		assert.Equal(t, "nvr-1", event.CameraID)
+		if event.Type != eventType {
+			continue
+		}
+
+		var metadata models.EventMetadata
+		require.NoError(t, json.Unmarshal([]byte(event.Metadata), &metadata))
+		channels = append(channels, metadata.Channel)
+	}
+	return channels
+}
+
+func TestProcessor_CheckMotionDetection_PerChannel(t *testing.T) {
+	client, statusQueries := newNVRClient(t)
+	config := DefaultConfig()
+	config.EventCooldown = 0 // report motion on every poll
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+
+	processor.checkMotionDetection(context.Background(), client)
+	assert.Equal(t, []int{0, 2}, drainChannels(t, processor, models.EventMotionDetected))
+
+	// The channel list is cached between polls
+	processor.checkMotionDetection(context.Background(), client)
+	assert.Equal(t, []int{0, 2}, drainChannels(t, processor, models.EventMotionDetected))
+	assert.Equal(t, int32(1), statusQueries.Load())
+}
+
+func TestProcessor_CheckAIDetection_PerChannel(t *testing.T) {
+	client, _ := newNVRClient(t)
+	processor := NewProcessor(camera.NewManager(nil, nil), nil)
+
+	processor.checkAIDetection(context.Background(), client)
+	assert.Equal(t, []int{0, 2}, drainChannels(t, processor, models.EventAIPerson))
+}
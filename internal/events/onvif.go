@@ -0,0 +1,497 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+const (
+	// defaultONVIFPort is the ONVIF port of Reolink cameras not reporting one
+	defaultONVIFPort = 8000
+
+	// onvifEventServicePath is where Reolink cameras serve the ONVIF event service
+	onvifEventServicePath = "/onvif/event_service"
+
+	// onvifPullTimeout is how long a PullMessages request waits for events
+	onvifPullTimeout = 10 * time.Second
+
+	// onvifMessageLimit caps the notifications returned by one PullMessages request
+	onvifMessageLimit = 16
+
+	// onvifSubscriptionTime is how long a subscription lives without renewal;
+	// it is renewed once half of it has passed
+	onvifSubscriptionTime = time.Minute
+
+	// onvifRequestTimeout bounds requests other than PullMessages, which may
+	// take up to onvifPullTimeout longer
+	onvifRequestTimeout = 10 * time.Second
+
+	// DefaultONVIFRetryInterval is how long a camera is polled after its ONVIF
+	// subscription failed before subscribing is tried again
+	DefaultONVIFRetryInterval = time.Minute
+)
+
+var (
+	// errONVIFDisabled is returned for cameras whose event mode or ports rule out ONVIF
+	errONVIFDisabled = errors.New("onvif disabled on camera")
+
+	// errONVIFFault is returned for SOAP faults sent by a camera
+	errONVIFFault = errors.New("onvif fault")
+)
+
+// SOAP actions of the ONVIF event service and WS-BaseNotification
+const (
+	onvifActionCreatePullPoint = "http://www.onvif.org/ver10/events/wsdl/EventPortType/CreatePullPointSubscriptionRequest"
+	onvifActionPullMessages    = "http://www.onvif.org/ver10/events/wsdl/PullPointSubscription/PullMessagesRequest"
+	onvifActionRenew           = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/RenewRequest"
+	onvifActionUnsubscribe     = "http://docs.oasis-open.org/wsn/bw-2/SubscriptionManager/UnsubscribeRequest"
+)
+
+// onvifTopics maps the topics of ONVIF notifications, without their namespace
+// prefix, to the events they report and the data item holding their state
+var onvifTopics = map[string]struct {
+	eventType models.EventType
+	item      string
+}{
+	"RuleEngine/CellMotionDetector/Motion":    {models.EventMotionDetected, "IsMotion"},
+	"VideoSource/MotionAlarm":                 {models.EventMotionDetected, "State"},
+	"RuleEngine/MyRuleDetector/PeopleDetect":  {models.EventAIPerson, "State"},
+	"RuleEngine/MyRuleDetector/VehicleDetect": {models.EventAIVehicle, "State"},
+	"RuleEngine/MyRuleDetector/DogCatDetect":  {models.EventAIPet, "State"},
+}
+
+// onvifNotification is an event notification pulled from an ONVIF camera
+type onvifNotification struct {
+	Topic     string
+	Time      time.Time // Zero when the camera sent none
+	Operation string    // Initialized, Changed or Deleted
+	Source    map[string]string
+	Data      map[string]string
+}
+
+// onvifSimpleItem is a name/value pair of a notification's source or data
+type onvifSimpleItem struct {
+	Name  string `xml:"Name,attr"`
+	Value string `xml:"Value,attr"`
+}
+
+// onvifEnvelope holds the parts of SOAP responses the subscriber reads.
+// Elements are matched by local name, whatever namespace prefix the camera uses.
+type onvifEnvelope struct {
+	Body struct {
+		Fault *struct {
+			Reason string `xml:"Reason>Text"`
+		} `xml:"Fault"`
+		CreatePullPointSubscriptionResponse *struct {
+			Address string `xml:"SubscriptionReference>Address"`
+		} `xml:"CreatePullPointSubscriptionResponse"`
+		PullMessagesResponse *struct {
+			NotificationMessages []struct {
+				Topic   string `xml:"Topic"`
+				Message struct {
+					UtcTime           string            `xml:"UtcTime,attr"`
+					PropertyOperation string            `xml:"PropertyOperation,attr"`
+					Source            []onvifSimpleItem `xml:"Source>SimpleItem"`
+					Data              []onvifSimpleItem `xml:"Data>SimpleItem"`
+				} `xml:"Message>Message"`
+			} `xml:"NotificationMessage"`
+		} `xml:"PullMessagesResponse"`
+	} `xml:"Body"`
+}
+
+// parseONVIFEnvelope decodes a SOAP response, returning SOAP faults as errors
+func parseONVIFEnvelope(data []byte) (*onvifEnvelope, error) {
+	var envelope onvifEnvelope
+	if err := xml.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("invalid onvif response: %w", err)
+	}
+	if fault := envelope.Body.Fault; fault != nil {
+		return nil, fmt.Errorf("%w: %s", errONVIFFault, strings.TrimSpace(fault.Reason))
+	}
+	return &envelope, nil
+}
+
+// parseONVIFNotifications decodes the notifications of a PullMessages response
+func parseONVIFNotifications(data []byte) ([]onvifNotification, error) {
+	envelope, err := parseONVIFEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	response := envelope.Body.PullMessagesResponse
+	if response == nil {
+		return nil, errors.New("invalid onvif response: no PullMessagesResponse")
+	}
+
+	notifications := make([]onvifNotification, 0, len(response.NotificationMessages))
+	for _, message := range response.NotificationMessages {
+		notification := onvifNotification{
+			Topic:     strings.TrimSpace(message.Topic),
+			Operation: message.Message.PropertyOperation,
+			Source:    simpleItems(message.Message.Source),
+			Data:      simpleItems(message.Message.Data),
+		}
+		if at, err := time.Parse(time.RFC3339, message.Message.UtcTime); err == nil {
+			notification.Time = at
+		}
+		notifications = append(notifications, notification)
+	}
+	return notifications, nil
+}
+
+// simpleItems converts simple items to a map by name
+func simpleItems(items []onvifSimpleItem) map[string]string {
+	values := make(map[string]string, len(items))
+	for _, item := range items {
+		values[item.Name] = item.Value
+	}
+	return values
+}
+
+// detection returns the event a notification reports. Only notifications of
+// a detection starting report one; the end of a detection does not.
+func (n onvifNotification) detection() (models.EventType, bool) {
+	topic := n.Topic
+	if i := strings.Index(topic, ":"); i >= 0 {
+		topic = topic[i+1:]
+	}
+
+	mapping, ok := onvifTopics[topic]
+	if !ok || n.Operation == "Deleted" {
+		return "", false
+	}
+	active, err := strconv.ParseBool(n.Data[mapping.item])
+	if err != nil || !active {
+		return "", false
+	}
+	return mapping.eventType, true
+}
+
+// channel returns the camera channel of a notification, taken from the
+// trailing digits of its video source token ("000" or "VideoSource_1")
+func (n onvifNotification) channel() int {
+	for _, name := range []string{"VideoSourceConfigurationToken", "VideoSourceToken", "Source"} {
+		token, ok := n.Source[name]
+		if !ok {
+			continue
+		}
+		digits := token[len(strings.TrimRight(token, "0123456789")):]
+		if channel, err := strconv.Atoi(digits); err == nil {
+			return channel
+		}
+	}
+	return 0
+}
+
+// onvifEndpoint returns the URL of a camera's ONVIF event service. In the
+// default event mode a camera is only subscribed to when its ports report
+// ONVIF enabled; in onvif mode the default port is assumed when they cannot
+// be read.
+func onvifEndpoint(cam *models.Camera, netPort *reolink.NetPort, netPortErr error) (string, error) {
+	port := defaultONVIFPort
+	switch cam.EventMode {
+	case models.EventModePoll:
+		return "", errONVIFDisabled
+	case models.EventModeONVIF:
+		if netPortErr == nil && netPort.OnvifPort > 0 {
+			port = netPort.OnvifPort
+		}
+	default:
+		if netPortErr != nil {
+			return "", fmt.Errorf("failed to get camera ports: %w", netPortErr)
+		}
+		if netPort.OnvifEnable != 1 {
+			return "", errONVIFDisabled
+		}
+		if netPort.OnvifPort > 0 {
+			port = netPort.OnvifPort
+		}
+	}
+
+	return "http://" + net.JoinHostPort(cam.Host, strconv.Itoa(port)) + onvifEventServicePath, nil
+}
+
+// onvifClient calls the ONVIF event service of a camera, authenticating with
+// a WS-Security username token
+type onvifClient struct {
+	httpClient *http.Client
+	username   string
+	password   string
+}
+
+// call sends a SOAP request to address and returns the response body
+func (c *onvifClient) call(ctx context.Context, address, action, body string) ([]byte, error) {
+	envelope, err := c.envelope(address, action, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, address, bytes.NewReader(envelope))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create onvif request: %w", err)
+	}
+	req.Header.Set("Content-Type", `application/soap+xml; charset=utf-8; action="`+action+`"`)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("onvif request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read onvif response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		// SOAP faults come with an error status and explain it better
+		if _, err := parseONVIFEnvelope(data); errors.Is(err, errONVIFFault) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("onvif request failed with status %d", resp.StatusCode)
+	}
+	return data, nil
+}
+
+// envelope wraps a SOAP body with WS-Addressing and WS-Security headers
+func (c *onvifClient) envelope(address, action, body string) ([]byte, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to create onvif nonce: %w", err)
+	}
+	created := time.Now().UTC().Format(time.RFC3339)
+
+	// PasswordDigest = Base64(SHA1(nonce + created + password))
+	digest := sha1.New()
+	digest.Write(nonce)
+	digest.Write([]byte(created))
+	digest.Write([]byte(c.password))
+
+	var buf bytes.Buffer
+	buf.WriteString(`<?xml version="1.0" encoding="UTF-8"?>`)
+	buf.WriteString(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope" xmlns:a="http://www.w3.org/2005/08/addressing">`)
+	buf.WriteString(`<s:Header>`)
+	fmt.Fprintf(&buf, `<a:Action s:mustUnderstand="1">%s</a:Action>`, action)
+	fmt.Fprintf(&buf, `<a:To s:mustUnderstand="1">%s</a:To>`, xmlEscape(address))
+	buf.WriteString(`<Security s:mustUnderstand="1" xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-secext-1.0.xsd">`)
+	buf.WriteString(`<UsernameToken>`)
+	fmt.Fprintf(&buf, `<Username>%s</Username>`, xmlEscape(c.username))
+	fmt.Fprintf(&buf, `<Password Type="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-username-token-profile-1.0#PasswordDigest">%s</Password>`,
+		base64.StdEncoding.EncodeToString(digest.Sum(nil)))
+	fmt.Fprintf(&buf, `<Nonce EncodingType="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-soap-message-security-1.0#Base64Binary">%s</Nonce>`,
+		base64.StdEncoding.EncodeToString(nonce))
+	fmt.Fprintf(&buf, `<Created xmlns="http://docs.oasis-open.org/wss/2004/01/oasis-200401-wss-wssecurity-utility-1.0.xsd">%s</Created>`, created)
+	buf.WriteString(`</UsernameToken></Security></s:Header>`)
+	fmt.Fprintf(&buf, `<s:Body>%s</s:Body></s:Envelope>`, body)
+	return buf.Bytes(), nil
+}
+
+// xmlEscape escapes text for use in XML
+func xmlEscape(text string) string {
+	var buf strings.Builder
+	xml.EscapeText(&buf, []byte(text))
+	return buf.String()
+}
+
+// onvifDuration formats a duration as an XML schema duration
+func onvifDuration(d time.Duration) string {
+	return fmt.Sprintf("PT%dS", int(d.Seconds()))
+}
+
+// createPullPoint subscribes to the events of the camera and returns the
+// address of the subscription
+func (c *onvifClient) createPullPoint(ctx context.Context, endpoint string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, onvifRequestTimeout)
+	defer cancel()
+
+	body := `<CreatePullPointSubscription xmlns="http://www.onvif.org/ver10/events/wsdl">` +
+		`<InitialTerminationTime>` + onvifDuration(onvifSubscriptionTime) + `</InitialTerminationTime>` +
+		`</CreatePullPointSubscription>`
+	data, err := c.call(ctx, endpoint, onvifActionCreatePullPoint, body)
+	if err != nil {
+		return "", err
+	}
+
+	envelope, err := parseONVIFEnvelope(data)
+	if err != nil {
+		return "", err
+	}
+	response := envelope.Body.CreatePullPointSubscriptionResponse
+	if response == nil || strings.TrimSpace(response.Address) == "" {
+		return "", errors.New("invalid onvif response: no subscription address")
+	}
+	return strings.TrimSpace(response.Address), nil
+}
+
+// pullMessages waits up to onvifPullTimeout for notifications of a subscription
+func (c *onvifClient) pullMessages(ctx context.Context, address string) ([]onvifNotification, error) {
+	ctx, cancel := context.WithTimeout(ctx, onvifPullTimeout+onvifRequestTimeout)
+	defer cancel()
+
+	body := `<PullMessages xmlns="http://www.onvif.org/ver10/events/wsdl">` +
+		`<Timeout>` + onvifDuration(onvifPullTimeout) + `</Timeout>` +
+		`<MessageLimit>` + strconv.Itoa(onvifMessageLimit) + `</MessageLimit>` +
+		`</PullMessages>`
+	data, err := c.call(ctx, address, onvifActionPullMessages, body)
+	if err != nil {
+		return nil, err
+	}
+	return parseONVIFNotifications(data)
+}
+
+// renew extends a subscription by onvifSubscriptionTime
+func (c *onvifClient) renew(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, onvifRequestTimeout)
+	defer cancel()
+
+	body := `<Renew xmlns="http://docs.oasis-open.org/wsn/b-2">` +
+		`<TerminationTime>` + onvifDuration(onvifSubscriptionTime) + `</TerminationTime>` +
+		`</Renew>`
+	_, err := c.call(ctx, address, onvifActionRenew, body)
+	return err
+}
+
+// unsubscribe ends a subscription
+func (c *onvifClient) unsubscribe(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, onvifRequestTimeout)
+	defer cancel()
+
+	_, err := c.call(ctx, address, onvifActionUnsubscribe, `<Unsubscribe xmlns="http://docs.oasis-open.org/wsn/b-2"/>`)
+	return err
+}
+
+// startONVIF subscribes to the ONVIF events of a camera in the background,
+// unless its event mode is poll. The returned channel receives why the
+// subscription ended; it is nil when the camera is only polled.
+func (p *Processor) startONVIF(ctx context.Context, cameraClient *camera.CameraClient) <-chan error {
+	if cameraClient.Camera.EventMode == models.EventModePoll {
+		return nil
+	}
+
+	done := make(chan error, 1)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		done <- p.runONVIF(ctx, cameraClient)
+	}()
+	return done
+}
+
+// runONVIF subscribes to the ONVIF events of a camera and publishes its
+// detections until ctx is done or the subscription fails
+func (p *Processor) runONVIF(ctx context.Context, cameraClient *camera.CameraClient) error {
+	netPort, netPortErr := cameraClient.GetNetPort(ctx)
+	endpoint, err := onvifEndpoint(cameraClient.Camera, netPort, netPortErr)
+	if err != nil {
+		return err
+	}
+
+	client := &onvifClient{
+		httpClient: cameraClient.HTTPClient(),
+		username:   cameraClient.Camera.Username,
+		password:   cameraClient.Camera.Password,
+	}
+	defer client.httpClient.CloseIdleConnections()
+
+	return p.pullONVIF(ctx, cameraClient.Camera, client, endpoint)
+}
+
+// pullONVIF creates a PullPoint subscription at endpoint and publishes the
+// detections pulled from it until ctx is done or pulling fails
+func (p *Processor) pullONVIF(ctx context.Context, cam *models.Camera, client *onvifClient, endpoint string) error {
+	address, err := client.createPullPoint(ctx, endpoint)
+	if err != nil {
+		return err
+	}
+	renewAt := time.Now().Add(onvifSubscriptionTime / 2)
+
+	logger.Info("Subscribed to ONVIF events",
+		zap.String("camera_id", cam.ID),
+		zap.String("subscription", address))
+
+	defer func() {
+		// The poller's context is done by now, so unsubscribing gets its own
+		unsubscribeCtx, cancel := context.WithTimeout(context.Background(), onvifRequestTimeout)
+		defer cancel()
+		if err := client.unsubscribe(unsubscribeCtx, address); err != nil {
+			logger.Debug("Failed to unsubscribe from ONVIF events",
+				zap.String("camera_id", cam.ID),
+				zap.Error(err))
+		}
+	}()
+
+	for {
+		notifications, err := client.pullMessages(ctx, address)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		received := p.clock.Now()
+		for _, notification := range notifications {
+			p.publishONVIFDetection(cam, notification, received)
+		}
+
+		if time.Now().After(renewAt) {
+			if err := client.renew(ctx, address); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return fmt.Errorf("failed to renew onvif subscription: %w", err)
+			}
+			renewAt = time.Now().Add(onvifSubscriptionTime / 2)
+		}
+	}
+}
+
+// publishONVIFDetection publishes the detection an ONVIF notification reports, if any
+func (p *Processor) publishONVIFDetection(cam *models.Camera, notification onvifNotification, received time.Time) {
+	eventType, ok := notification.detection()
+	if !ok {
+		return
+	}
+
+	event := &models.Event{
+		ID:         uuid.New().String(),
+		CameraID:   cam.ID,
+		CameraName: cam.Name,
+		Type:       eventType,
+	}
+	stampEvent(event, notification.Time, received)
+
+	metadata := models.EventMetadata{
+		Channel: notification.channel(),
+		Extra: map[string]interface{}{
+			"source": "onvif",
+			"topic":  notification.Topic,
+		},
+	}
+
+	if metadataJSON, err := json.Marshal(metadata); err == nil {
+		event.Metadata = string(metadataJSON)
+	}
+
+	p.publishDetection(event)
+}
@@ -0,0 +1,239 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// samplePullMessagesResponse is a PullMessages response as sent by a Reolink
+// camera: motion starting on channel 0, a person on channel 1 and a vehicle
+// detection ending
+const samplePullMessagesResponse = `<?xml version="1.0" encoding="UTF-8"?>
+<SOAP-ENV:Envelope xmlns:SOAP-ENV="http://www.w3.org/2003/05/soap-envelope"
+  xmlns:wsnt="http://docs.oasis-open.org/wsn/b-2"
+  xmlns:tev="http://www.onvif.org/ver10/events/wsdl"
+  xmlns:tt="http://www.onvif.org/ver10/schema"
+  xmlns:tns1="http://www.onvif.org/ver10/topics">
+  <SOAP-ENV:Body>
+    <tev:PullMessagesResponse>
+      <tev:CurrentTime>2026-10-16T12:00:05Z</tev:CurrentTime>
+      <tev:TerminationTime>2026-10-16T12:01:00Z</tev:TerminationTime>
+      <wsnt:NotificationMessage>
+        <wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:RuleEngine/CellMotionDetector/Motion</wsnt:Topic>
+        <wsnt:Message>
+          <tt:Message UtcTime="2026-10-16T12:00:03Z" PropertyOperation="Changed">
+            <tt:Source>
+              <tt:SimpleItem Name="VideoSourceConfigurationToken" Value="000"/>
+              <tt:SimpleItem Name="VideoAnalyticsConfigurationToken" Value="000"/>
+              <tt:SimpleItem Name="Rule" Value="MyMotionDetectorRule"/>
+            </tt:Source>
+            <tt:Data>
+              <tt:SimpleItem Name="IsMotion" Value="true"/>
+            </tt:Data>
+          </tt:Message>
+        </wsnt:Message>
+      </wsnt:NotificationMessage>
+      <wsnt:NotificationMessage>
+        <wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:RuleEngine/MyRuleDetector/PeopleDetect</wsnt:Topic>
+        <wsnt:Message>
+          <tt:Message UtcTime="2026-10-16T12:00:04Z" PropertyOperation="Changed">
+            <tt:Source>
+              <tt:SimpleItem Name="Source" Value="VideoSource_1"/>
+            </tt:Source>
+            <tt:Data>
+              <tt:SimpleItem Name="State" Value="true"/>
+            </tt:Data>
+          </tt:Message>
+        </wsnt:Message>
+      </wsnt:NotificationMessage>
+      <wsnt:NotificationMessage>
+        <wsnt:Topic Dialect="http://www.onvif.org/ver10/tev/topicExpression/ConcreteSet">tns1:RuleEngine/MyRuleDetector/VehicleDetect</wsnt:Topic>
+        <wsnt:Message>
+          <tt:Message UtcTime="2026-10-16T12:00:04Z" PropertyOperation="Changed">
+            <tt:Source>
+              <tt:SimpleItem Name="Source" Value="000"/>
+            </tt:Source>
+            <tt:Data>
+              <tt:SimpleItem Name="State" Value="false"/>
+            </tt:Data>
+          </tt:Message>
+        </wsnt:Message>
+      </wsnt:NotificationMessage>
+    </tev:PullMessagesResponse>
+  </SOAP-ENV:Body>
+</SOAP-ENV:Envelope>`
+
+func TestParseONVIFNotifications(t *testing.T) {
+	notifications, err := parseONVIFNotifications([]byte(samplePullMessagesResponse))
+	require.NoError(t, err)
+	require.Len(t, notifications, 3)
+
+	motion := notifications[0]
+	assert.Equal(t, "tns1:RuleEngine/CellMotionDetector/Motion", motion.Topic)
+	assert.Equal(t, time.Date(2026, 10, 16, 12, 0, 3, 0, time.UTC), motion.Time)
+	assert.Equal(t, "Changed", motion.Operation)
+	assert.Equal(t, "000", motion.Source["VideoSourceConfigurationToken"])
+	assert.Equal(t, "MyMotionDetectorRule", motion.Source["Rule"])
+	assert.Equal(t, map[string]string{"IsMotion": "true"}, motion.Data)
+
+	eventType, ok := motion.detection()
+	assert.True(t, ok)
+	assert.Equal(t, models.EventMotionDetected, eventType)
+	assert.Equal(t, 0, motion.channel())
+
+	person := notifications[1]
+	eventType, ok = person.detection()
+	assert.True(t, ok)
+	assert.Equal(t, models.EventAIPerson, eventType)
+	assert.Equal(t, 1, person.channel())
+
+	// A detection ending reports no event
+	_, ok = notifications[2].detection()
+	assert.False(t, ok)
+}
+
+func TestParseONVIFNotifications_Errors(t *testing.T) {
+	_, err := parseONVIFNotifications([]byte("not xml"))
+	assert.Error(t, err)
+
+	_, err = parseONVIFNotifications([]byte(`<Envelope><Body><Other/></Body></Envelope>`))
+	assert.Error(t, err)
+
+	_, err = parseONVIFNotifications([]byte(`<s:Envelope xmlns:s="http://www.w3.org/2003/05/soap-envelope"><s:Body>` +
+		`<s:Fault><s:Reason><s:Text xml:lang="en">Subscription expired</s:Text></s:Reason></s:Fault>` +
+		`</s:Body></s:Envelope>`))
+	assert.ErrorIs(t, err, errONVIFFault)
+	assert.Contains(t, err.Error(), "Subscription expired")
+}
+
+func TestONVIFNotification_Detection(t *testing.T) {
+	tests := []struct {
+		name         string
+		notification onvifNotification
+		want         models.EventType
+		wantOK       bool
+	}{
+		{"motion alarm", onvifNotification{Topic: "tns1:VideoSource/MotionAlarm", Data: map[string]string{"State": "true"}}, models.EventMotionDetected, true},
+		{"pet", onvifNotification{Topic: "tns1:RuleEngine/MyRuleDetector/DogCatDetect", Data: map[string]string{"State": "true"}}, models.EventAIPet, true},
+		{"vehicle", onvifNotification{Topic: "tns1:RuleEngine/MyRuleDetector/VehicleDetect", Data: map[string]string{"State": "true"}}, models.EventAIVehicle, true},
+		{"motion ended", onvifNotification{Topic: "tns1:RuleEngine/CellMotionDetector/Motion", Data: map[string]string{"IsMotion": "false"}}, "", false},
+		{"deleted", onvifNotification{Topic: "tns1:VideoSource/MotionAlarm", Operation: "Deleted", Data: map[string]string{"State": "true"}}, "", false},
+		{"wrong item", onvifNotification{Topic: "tns1:RuleEngine/CellMotionDetector/Motion", Data: map[string]string{"State": "true"}}, "", false},
+		{"unknown topic", onvifNotification{Topic: "tns1:Device/Trigger/DigitalInput", Data: map[string]string{"LogicalState": "true"}}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.notification.detection()
+			assert.Equal(t, tt.wantOK, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestONVIFEndpoint(t *testing.T) {
+	netPortErr := errors.New("unsupported")
+	tests := []struct {
+		name       string
+		mode       string
+		netPort    *reolink.NetPort
+		netPortErr error
+		want       string
+		wantErr    error
+	}{
+		{"auto enabled", models.EventModeAuto, &reolink.NetPort{OnvifEnable: 1, OnvifPort: 8000}, nil, "http://192.168.1.10:8000/onvif/event_service", nil},
+		{"auto custom port", models.EventModeAuto, &reolink.NetPort{OnvifEnable: 1, OnvifPort: 8999}, nil, "http://192.168.1.10:8999/onvif/event_service", nil},
+		{"auto disabled", models.EventModeAuto, &reolink.NetPort{OnvifEnable: 0, OnvifPort: 8000}, nil, "", errONVIFDisabled},
+		{"auto ports unknown", models.EventModeAuto, nil, netPortErr, "", netPortErr},
+		{"onvif ports unknown", models.EventModeONVIF, nil, netPortErr, "http://192.168.1.10:8000/onvif/event_service", nil},
+		{"onvif reported disabled", models.EventModeONVIF, &reolink.NetPort{OnvifEnable: 0, OnvifPort: 8080}, nil, "http://192.168.1.10:8080/onvif/event_service", nil},
+		{"poll", models.EventModePoll, &reolink.NetPort{OnvifEnable: 1, OnvifPort: 8000}, nil, "", errONVIFDisabled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cam := &models.Camera{Host: "192.168.1.10", EventMode: tt.mode}
+			got, err := onvifEndpoint(cam, tt.netPort, tt.netPortErr)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestProcessor_PullONVIF(t *testing.T) {
+	var pulls, unsubscribes atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), "<Username>admin</Username>") ||
+			!strings.Contains(string(body), "#PasswordDigest") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		switch {
+		case strings.Contains(string(body), "<CreatePullPointSubscription"):
+			assert.Equal(t, onvifEventServicePath, r.URL.Path)
+			w.Write([]byte(`<Envelope><Body><CreatePullPointSubscriptionResponse><SubscriptionReference>` +
+				`<Address>` + server.URL + `/onvif/subscription?id=1</Address>` +
+				`</SubscriptionReference></CreatePullPointSubscriptionResponse></Body></Envelope>`))
+		case strings.Contains(string(body), "<PullMessages"):
+			assert.Equal(t, "/onvif/subscription", r.URL.Path)
+			if pulls.Add(1) == 1 {
+				w.Write([]byte(samplePullMessagesResponse))
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`<Envelope><Body><Fault><Reason><Text>Subscription expired</Text></Reason></Fault></Body></Envelope>`))
+		case strings.Contains(string(body), "<Unsubscribe"):
+			unsubscribes.Add(1)
+			w.Write([]byte(`<Envelope><Body><UnsubscribeResponse/></Body></Envelope>`))
+		default:
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	config := DefaultConfig()
+	config.EventCooldown = 0
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+	cam := &models.Camera{ID: "cam-1", Name: "Front Door"}
+	client := &onvifClient{httpClient: server.Client(), username: "admin", password: "secret"}
+
+	err := processor.pullONVIF(t.Context(), cam, client, server.URL+onvifEventServicePath)
+	assert.ErrorIs(t, err, errONVIFFault)
+	assert.Contains(t, err.Error(), "Subscription expired")
+	assert.Equal(t, int32(2), pulls.Load())
+	assert.Equal(t, int32(1), unsubscribes.Load())
+
+	require.Len(t, processor.eventCh, 2)
+	motion := <-processor.eventCh
+	assert.Equal(t, models.EventMotionDetected, motion.Type)
+	assert.Equal(t, "cam-1", motion.CameraID)
+	assert.Equal(t, "Front Door", motion.CameraName)
+
+	person := <-processor.eventCh
+	assert.Equal(t, models.EventAIPerson, person.Type)
+	var metadata models.EventMetadata
+	require.NoError(t, json.Unmarshal([]byte(person.Metadata), &metadata))
+	assert.Equal(t, 1, metadata.Channel)
+	assert.Equal(t, "onvif", metadata.Extra["source"])
+}
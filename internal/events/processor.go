@@ -1,13 +1,19 @@
 package events
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // snapshot decoding
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/clock"
 	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"go.uber.org/zap"
@@ -18,19 +24,79 @@ type Processor struct {
 	cameraManager *camera.Manager
 	config        *Config
 	subscribers   []Subscriber
+	queues        []*subscriberQueue // One per subscriber, in subscription order
 	mu            sync.RWMutex
+	queueMu       sync.Mutex // Guards the contents of queues
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
 	eventCh       chan *models.Event
+	motionDiff    *SnapshotMotionDetector
+	quota         *eventQuota
+	dedup         *eventDedup
+	clock         clock.Clock // Stamps events and times cooldowns and quotas
+
+	// pollers holds the running poller of each camera, guarded by pollersMu
+	pollers   map[string]*poller
+	pollersMu sync.Mutex
+
+	// closed is set once eventCh is closed so late publishers drop instead of panicking
+	closed       bool
+	closeMu      sync.RWMutex
+	dispatching  bool
+	dispatchDone chan struct{}
+	abortCh      chan struct{}
 }
 
+// correlationKey is the log field carrying the ID of the event a log entry
+// belongs to, so a detection can be traced from publishing through every subscriber
+const correlationKey = "correlation_id"
+
+// correlation returns the correlation log field of an event
+func correlation(event *models.Event) zap.Field {
+	return zap.String(correlationKey, event.ID)
+}
+
+// correlations returns the correlation log field of a batch of events
+func correlations(events []*models.Event) zap.Field {
+	ids := make([]string, len(events))
+	for i, event := range events {
+		ids[i] = event.ID
+	}
+	return zap.Strings(correlationKey, ids)
+}
+
+// defaultDrainTimeout bounds how long Stop waits for buffered events to be delivered
+const defaultDrainTimeout = 10 * time.Second
+
 // Config holds processor configuration
 type Config struct {
 	PollInterval      time.Duration
 	MotionCheckPeriod time.Duration
 	AICheckPeriod     time.Duration
-	EventBufferSize   int
-	MaxWorkers        int
+	EventBufferSize   int           // Also caps the events waiting for a single subscriber
+	MaxWorkers        int           // Subscribers served concurrently; each subscriber gets its events in order
+	DrainTimeout      time.Duration // Max time Stop waits for buffered events to be delivered
+	MaxBatchSize      int           // Max events handed to a BatchSubscriber at once, values below 2 disable batching
+
+	// Per-camera event cap; events beyond it are coalesced into an event_storm event
+	EventQuota       int // Max events per camera per window, zero disables the quota
+	EventQuotaWindow time.Duration
+
+	// Repeats of a polled detection (same type, camera and channel) seen within
+	// the cooldown of the previous one are suppressed; zero disables suppression
+	EventCooldown  time.Duration
+	EventCooldowns map[models.EventType]time.Duration // Per-type overrides of EventCooldown
+
+	// Snapshot differencing motion fallback for cameras without motion/AI support
+	SnapshotMotionEnabled   bool
+	SnapshotMotionPeriod    time.Duration
+	SnapshotMotionThreshold float64
+	SnapshotTimeout         time.Duration // Bounds a single snapshot capture so a slow camera cannot stall its poller
+
+	// Cameras exposing ONVIF are subscribed to instead of polled for motion
+	// and AI detections; after a subscription fails they are polled for this
+	// long before subscribing is tried again
+	ONVIFRetryInterval time.Duration
 }
 
 // DefaultConfig returns default processor configuration
@@ -41,6 +107,18 @@ func DefaultConfig() *Config {
 		AICheckPeriod:     10 * time.Second,
 		EventBufferSize:   1000,
 		MaxWorkers:        10,
+		DrainTimeout:      defaultDrainTimeout,
+		MaxBatchSize:      100,
+		EventQuota:        0,
+		EventQuotaWindow:  time.Minute,
+		EventCooldown:     DefaultEventCooldown,
+
+		SnapshotMotionEnabled:   false,
+		SnapshotMotionPeriod:    2 * time.Second,
+		SnapshotMotionThreshold: DefaultSnapshotMotionThreshold,
+		SnapshotTimeout:         DefaultSnapshotTimeout,
+
+		ONVIFRetryInterval: DefaultONVIFRetryInterval,
 	}
 }
 
@@ -49,19 +127,62 @@ type Subscriber interface {
 	OnEvent(event *models.Event) error
 }
 
+// BatchSubscriber is implemented by subscribers that can handle several events
+// at once, e.g. to write them in a single round trip. When more than one event
+// is pending the dispatcher hands them over in one OnEvents call instead of
+// calling OnEvent for each.
+type BatchSubscriber interface {
+	Subscriber
+	OnEvents(events []*models.Event) error
+}
+
+// subscriberQueue holds the events waiting for one subscriber. At most one
+// worker delivers from a queue at a time, so a subscriber sees events in
+// publishing order while the other subscribers are served by other workers.
+type subscriberQueue struct {
+	subscriber Subscriber
+	batches    [][]*models.Event
+	pending    int  // Events in batches
+	active     bool // A worker is delivering from the queue
+}
+
 // NewProcessor creates a new event processor
 func NewProcessor(cameraManager *camera.Manager, config *Config) *Processor {
 	if config == nil {
 		config = DefaultConfig()
 	}
 
-	return &Processor{
+	p := &Processor{
 		cameraManager: cameraManager,
 		config:        config,
 		subscribers:   make([]Subscriber, 0),
 		stopCh:        make(chan struct{}),
 		eventCh:       make(chan *models.Event, config.EventBufferSize),
+		dispatchDone:  make(chan struct{}),
+		abortCh:       make(chan struct{}),
+		clock:         clock.Real,
+		pollers:       make(map[string]*poller),
+	}
+
+	if config.SnapshotMotionEnabled {
+		p.motionDiff = NewSnapshotMotionDetector(config.SnapshotMotionThreshold)
 	}
+
+	if config.EventCooldown > 0 || len(config.EventCooldowns) > 0 {
+		p.dedup = newEventDedup(config.EventCooldown, config.EventCooldowns)
+	}
+
+	if config.EventQuota > 0 && config.EventQuotaWindow > 0 {
+		p.quota = newEventQuota(config.EventQuota, config.EventQuotaWindow)
+	}
+
+	return p
+}
+
+// SetClock sets the time source of the processor, e.g. a fake clock in
+// tests. It must be called before the processor is started.
+func (p *Processor) SetClock(c clock.Clock) {
+	p.clock = c
 }
 
 // Subscribe adds a subscriber to receive events
@@ -69,6 +190,7 @@ func (p *Processor) Subscribe(subscriber Subscriber) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.subscribers = append(p.subscribers, subscriber)
+	p.queues = append(p.queues, &subscriberQueue{subscriber: subscriber})
 }
 
 // Start begins event processing
@@ -76,7 +198,7 @@ func (p *Processor) Start(ctx context.Context) error {
 	logger.Info("Starting event processor")
 
 	// Start event dispatcher
-	p.wg.Add(1)
+	p.dispatching = true
 	go p.dispatchEvents(ctx)
 
 	// Start camera pollers
@@ -91,8 +213,7 @@ func (p *Processor) Start(ctx context.Context) error {
 			continue
 		}
 
-		p.wg.Add(1)
-		go p.pollCamera(ctx, client)
+		p.startPoller(ctx, client)
 	}
 
 	logger.Info("Event processor started", zap.Int("cameras", len(cameras)))
@@ -102,9 +223,39 @@ func (p *Processor) Start(ctx context.Context) error {
 // Stop stops event processing
 func (p *Processor) Stop() error {
 	logger.Info("Stopping event processor")
+
+	// Stop pollers first so nothing new is produced while draining
 	close(p.stopCh)
 	p.wg.Wait()
+
+	p.closeMu.Lock()
+	p.closed = true
 	close(p.eventCh)
+	p.closeMu.Unlock()
+
+	// Let the dispatcher deliver what is still buffered before subscribers
+	// (e.g. the event store) are closed
+	if p.dispatching {
+		drainTimeout := p.config.DrainTimeout
+		if drainTimeout <= 0 {
+			drainTimeout = defaultDrainTimeout
+		}
+
+		timer := time.NewTimer(drainTimeout)
+		defer timer.Stop()
+
+		select {
+		case <-p.dispatchDone:
+		case <-timer.C:
+			close(p.abortCh)
+			remaining := len(p.eventCh)
+			logger.Warn("Timed out draining events",
+				zap.Duration("timeout", drainTimeout),
+				zap.Int("undelivered", remaining))
+			return fmt.Errorf("timed out draining events: %d undelivered", remaining)
+		}
+	}
+
 	logger.Info("Event processor stopped")
 	return nil
 }
@@ -126,6 +277,23 @@ func (p *Processor) pollCamera(ctx context.Context, cameraClient *camera.CameraC
 	aiTicker := time.NewTicker(p.config.AICheckPeriod)
 	defer aiTicker.Stop()
 
+	// Snapshot differencing only runs when enabled, for cameras without
+	// motion or AI detection of their own; a nil channel never fires
+	var snapshotCh <-chan time.Time
+	if p.motionDiff != nil && needsSnapshotMotion(cameraClient.Camera) {
+		snapshotTicker := time.NewTicker(p.config.SnapshotMotionPeriod)
+		defer snapshotTicker.Stop()
+		defer p.motionDiff.Reset(cameraID)
+		snapshotCh = snapshotTicker.C
+	}
+
+	// While an ONVIF subscription runs it reports motion and AI detections,
+	// so they are not polled; the subscription ends with the poller
+	onvifCtx, cancelONVIF := context.WithCancel(ctx)
+	defer cancelONVIF()
+	onvifDone := p.startONVIF(onvifCtx, cameraClient)
+	var onvifRetry <-chan time.Time
+
 	for {
 		select {
 		case <-p.stopCh:
@@ -134,38 +302,65 @@ func (p *Processor) pollCamera(ctx context.Context, cameraClient *camera.CameraC
 		case <-ctx.Done():
 			logger.Info("Camera poller context cancelled", zap.String("camera_id", cameraID))
 			return
+		case err := <-onvifDone:
+			onvifDone = nil
+			if errors.Is(err, errONVIFDisabled) {
+				logger.Debug("ONVIF unavailable, polling camera for events", zap.String("camera_id", cameraID))
+				continue
+			}
+			logger.Warn("ONVIF event subscription ended, polling camera for events",
+				zap.String("camera_id", cameraID),
+				zap.Error(err))
+			retry := p.config.ONVIFRetryInterval
+			if retry <= 0 {
+				retry = DefaultONVIFRetryInterval
+			}
+			onvifRetry = time.After(retry)
+		case <-onvifRetry:
+			onvifRetry = nil
+			onvifDone = p.startONVIF(onvifCtx, cameraClient)
 		case <-motionTicker.C:
-			p.checkMotionDetection(ctx, cameraClient)
+			if onvifDone == nil {
+				p.checkMotionDetection(ctx, cameraClient)
+			}
 		case <-aiTicker.C:
-			p.checkAIDetection(ctx, cameraClient)
+			if onvifDone == nil {
+				p.checkAIDetection(ctx, cameraClient)
+			}
+		case <-snapshotCh:
+			p.checkSnapshotMotion(ctx, cameraClient)
 		}
 	}
 }
 
-// checkMotionDetection checks for motion detection events
+// checkMotionDetection checks every active channel for motion detection events
 func (p *Processor) checkMotionDetection(ctx context.Context, cameraClient *camera.CameraClient) {
-	// Check motion state for channel 0 (most cameras have single channel)
-	state, err := cameraClient.GetMotionState(ctx, 0)
-	if err != nil {
-		logger.Debug("Failed to get motion state",
-			zap.String("camera_id", cameraClient.Camera.ID),
-			zap.Error(err))
-		return
-	}
+	for _, channel := range cameraClient.ActiveChannels(ctx) {
+		state, err := cameraClient.GetMotionState(ctx, channel)
+		if err != nil {
+			logger.Debug("Failed to get motion state",
+				zap.String("camera_id", cameraClient.Camera.ID),
+				zap.Int("channel", channel),
+				zap.Error(err))
+			continue
+		}
+
+		// State 1 typically means motion detected
+		if state != 1 {
+			continue
+		}
 
-	// State 1 typically means motion detected
-	if state == 1 {
+		// GetMdState reports no device time, so the event is stamped on receipt
 		event := &models.Event{
 			ID:         uuid.New().String(),
 			CameraID:   cameraClient.Camera.ID,
 			CameraName: cameraClient.Camera.Name,
 			Type:       models.EventMotionDetected,
-			Timestamp:  time.Now(),
-			CreatedAt:  time.Now(),
 		}
+		stampEvent(event, time.Time{}, p.clock.Now())
 
 		metadata := models.EventMetadata{
-			Channel: 0,
+			Channel: channel,
 			Extra: map[string]interface{}{
 				"state": state,
 			},
@@ -175,54 +370,119 @@ func (p *Processor) checkMotionDetection(ctx context.Context, cameraClient *came
 			event.Metadata = string(metadataJSON)
 		}
 
-		p.publishEvent(event)
+		p.publishDetection(event)
 	}
 }
 
-// checkAIDetection checks for AI detection events
-func (p *Processor) checkAIDetection(ctx context.Context, cameraClient *camera.CameraClient) {
-	// Check AI state for channel 0
-	aiState, err := cameraClient.GetAIState(ctx, 0)
+// checkSnapshotMotion grabs a snapshot and compares it with the previous one.
+// The capture gets its own deadline rather than inheriting the poll context,
+// which lives as long as the poller, so a slow snapshot gives up before it
+// delays the next motion and AI checks.
+func (p *Processor) checkSnapshotMotion(ctx context.Context, cameraClient *camera.CameraClient) {
+	timeout := p.config.SnapshotTimeout
+	if timeout <= 0 {
+		timeout = DefaultSnapshotTimeout
+	}
+	captureCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := cameraClient.GetSnapshot(captureCtx, 0)
 	if err != nil {
-		logger.Debug("Failed to get AI state",
+		logger.Debug("Failed to get snapshot for motion detection",
 			zap.String("camera_id", cameraClient.Camera.ID),
 			zap.Error(err))
 		return
 	}
 
-	if aiState == nil {
+	p.detectSnapshotMotion(cameraClient.Camera, data)
+}
+
+// detectSnapshotMotion decodes a snapshot and publishes a motion event if it
+// differs enough from the previous snapshot of the camera
+func (p *Processor) detectSnapshotMotion(cam *models.Camera, data []byte) {
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		logger.Debug("Failed to decode snapshot for motion detection",
+			zap.String("camera_id", cam.ID),
+			zap.Error(err))
 		return
 	}
 
-	// Check for pet detection (dog/cat)
-	if aiState.DogCat.Support == 1 && aiState.DogCat.AlarmState == 1 {
-		p.publishAIEvent(cameraClient, models.EventAIPet, aiState)
+	detected, score := p.motionDiff.Detect(cam.ID, img)
+	if !detected {
+		return
 	}
 
-	// Check for people detection
-	if aiState.People.Support == 1 && aiState.People.AlarmState == 1 {
-		p.publishAIEvent(cameraClient, models.EventAIPerson, aiState)
+	event := &models.Event{
+		ID:         uuid.New().String(),
+		CameraID:   cam.ID,
+		CameraName: cam.Name,
+		Type:       models.EventMotionDetected,
 	}
+	stampEvent(event, time.Time{}, p.clock.Now())
+
+	metadata := models.EventMetadata{
+		Channel:    0,
+		Confidence: score,
+		Extra: map[string]interface{}{
+			"source": "snapshot_diff",
+			"score":  score,
+		},
+	}
+
+	if metadataJSON, err := json.Marshal(metadata); err == nil {
+		event.Metadata = string(metadataJSON)
+	}
+
+	p.publishDetection(event)
+}
+
+// checkAIDetection checks every active channel for AI detection events
+func (p *Processor) checkAIDetection(ctx context.Context, cameraClient *camera.CameraClient) {
+	for _, channel := range cameraClient.ActiveChannels(ctx) {
+		aiState, err := cameraClient.GetAIState(ctx, channel)
+		if err != nil {
+			logger.Debug("Failed to get AI state",
+				zap.String("camera_id", cameraClient.Camera.ID),
+				zap.Int("channel", channel),
+				zap.Error(err))
+			continue
+		}
+
+		if aiState == nil {
+			continue
+		}
+
+		// Check for pet detection (dog/cat)
+		if aiState.DogCat.Support == 1 && aiState.DogCat.AlarmState == 1 {
+			p.publishAIEvent(cameraClient, channel, models.EventAIPet, aiState)
+		}
 
-	// Check for vehicle detection
-	if aiState.Vehicle.Support == 1 && aiState.Vehicle.AlarmState == 1 {
-		p.publishAIEvent(cameraClient, models.EventAIVehicle, aiState)
+		// Check for people detection
+		if aiState.People.Support == 1 && aiState.People.AlarmState == 1 {
+			p.publishAIEvent(cameraClient, channel, models.EventAIPerson, aiState)
+		}
+
+		// Check for vehicle detection
+		if aiState.Vehicle.Support == 1 && aiState.Vehicle.AlarmState == 1 {
+			p.publishAIEvent(cameraClient, channel, models.EventAIVehicle, aiState)
+		}
 	}
 }
 
 // publishAIEvent publishes an AI detection event
-func (p *Processor) publishAIEvent(cameraClient *camera.CameraClient, eventType models.EventType, aiState interface{}) {
+func (p *Processor) publishAIEvent(cameraClient *camera.CameraClient, channel int, eventType models.EventType, aiState interface{}) {
+	// GetAiState reports no device time, so the event is stamped on receipt
 	event := &models.Event{
 		ID:         uuid.New().String(),
 		CameraID:   cameraClient.Camera.ID,
 		CameraName: cameraClient.Camera.Name,
 		Type:       eventType,
-		Timestamp:  time.Now(),
-		CreatedAt:  time.Now(),
 	}
+	stampEvent(event, time.Time{}, p.clock.Now())
 
 	metadata := models.EventMetadata{
-		Channel: 0,
+		Channel: channel,
 		Extra: map[string]interface{}{
 			"ai_state": aiState,
 		},
@@ -232,60 +492,272 @@ func (p *Processor) publishAIEvent(cameraClient *camera.CameraClient, eventType
 		event.Metadata = string(metadataJSON)
 	}
 
+	p.publishDetection(event)
+}
+
+// stampEvent dates an event. Timestamp is when the device saw the event if
+// it reported a time, and CreatedAt when the server received it. A device time
+// after the receive time, from a camera clock running ahead, is not trusted.
+func stampEvent(event *models.Event, deviceTime, received time.Time) {
+	event.CreatedAt = received
+	event.Timestamp = received
+	if !deviceTime.IsZero() && !deviceTime.After(received) {
+		event.Timestamp = deviceTime
+	}
+}
+
+// publishDetection publishes an event detected by polling a camera, unless it
+// repeats a detection still within its cooldown
+func (p *Processor) publishDetection(event *models.Event) {
+	if p.dedup != nil && !p.dedup.admit(event, p.clock.Now()) {
+		logger.Debug("Repeated detection within cooldown, suppressing",
+			correlation(event),
+			zap.String("camera_id", event.CameraID),
+			zap.String("type", string(event.Type)))
+		return
+	}
+
 	p.publishEvent(event)
 }
 
-// publishEvent sends an event to the event channel
+// publishEvent sends an event to the event channel. It is safe to call
+// concurrently with Stop; events published once the channel is closed are dropped.
 func (p *Processor) publishEvent(event *models.Event) {
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		logger.Warn("Event processor stopped, dropping event",
+			correlation(event),
+			zap.String("camera_id", event.CameraID))
+		return
+	}
+
+	if event.Severity == "" {
+		event.Severity = event.Type.DefaultSeverity()
+	}
+
+	if p.quota != nil {
+		allowed, summary := p.quota.admit(event, p.clock.Now())
+		if !allowed {
+			if summary == nil {
+				logger.Debug("Event quota exceeded, dropping event",
+					correlation(event),
+					zap.String("camera_id", event.CameraID))
+				return
+			}
+			event = summary
+		}
+	}
+
 	select {
 	case p.eventCh <- event:
 		logger.Debug("Event published",
-			zap.String("event_id", event.ID),
+			correlation(event),
 			zap.String("camera_id", event.CameraID),
 			zap.String("type", string(event.Type)))
 	default:
 		logger.Warn("Event channel full, dropping event",
-			zap.String("event_id", event.ID),
+			correlation(event),
 			zap.String("camera_id", event.CameraID))
 	}
 }
 
-// dispatchEvents dispatches events to subscribers
+// dispatchEvents dispatches events to subscribers until the event channel is
+// closed and drained. Events are queued per subscriber and delivered by up to
+// MaxWorkers workers, so a slow subscriber holds up only its own queue. When
+// every worker is busy the dispatcher waits, the event channel fills up and
+// publishers start dropping events.
 func (p *Processor) dispatchEvents(ctx context.Context) {
-	defer p.wg.Done()
+	defer close(p.dispatchDone)
+
+	workers := p.config.MaxWorkers
+	if workers < 1 {
+		workers = 1
+	}
 
-	logger.Info("Event dispatcher started")
+	work := make(chan *subscriberQueue)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.deliverQueues(ctx, work)
+		}()
+	}
+	// Workers finish the queued events before the dispatcher reports done
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
+	logger.Info("Event dispatcher started", zap.Int("workers", workers))
 
 	for {
 		select {
-		case <-p.stopCh:
-			logger.Info("Event dispatcher stopped")
+		case <-p.abortCh:
+			logger.Info("Event dispatcher aborted")
 			return
 		case <-ctx.Done():
 			logger.Info("Event dispatcher context cancelled")
 			return
 		case event, ok := <-p.eventCh:
 			if !ok {
-				logger.Info("Event channel closed")
+				logger.Info("Event dispatcher stopped, event channel drained")
 				return
 			}
 
-			p.notifySubscribers(event)
+			batch, open := p.collectBatch(event)
+			if !p.enqueueBatch(ctx, batch, work) {
+				return
+			}
+
+			if !open {
+				logger.Info("Event dispatcher stopped, event channel drained")
+				return
+			}
 		}
 	}
 }
 
-// notifySubscribers notifies all subscribers of an event
-func (p *Processor) notifySubscribers(event *models.Event) {
+// enqueueBatch adds events to the queue of every subscriber and hands queues
+// without a worker to the next free one. Subscribers whose queue is full miss
+// the events. It reports false if dispatching was cancelled or aborted while
+// waiting for a worker.
+func (p *Processor) enqueueBatch(ctx context.Context, events []*models.Event, work chan<- *subscriberQueue) bool {
 	p.mu.RLock()
-	subscribers := make([]Subscriber, len(p.subscribers))
-	copy(subscribers, p.subscribers)
+	queues := make([]*subscriberQueue, len(p.queues))
+	copy(queues, p.queues)
 	p.mu.RUnlock()
 
-	for _, subscriber := range subscribers {
+	for _, queue := range queues {
+		p.queueMu.Lock()
+		if limit := p.config.EventBufferSize; limit > 0 && queue.pending+len(events) > limit {
+			p.queueMu.Unlock()
+			logger.Warn("Subscriber queue full, dropping events",
+				zap.String("subscriber", fmt.Sprintf("%T", queue.subscriber)),
+				zap.Int("events", len(events)),
+				correlations(events))
+			continue
+		}
+		queue.batches = append(queue.batches, events)
+		queue.pending += len(events)
+		idle := !queue.active
+		queue.active = true
+		p.queueMu.Unlock()
+
+		if !idle {
+			continue
+		}
+		select {
+		case work <- queue:
+		case <-p.abortCh:
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	for _, event := range events {
+		logger.Debug("Event dispatched",
+			correlation(event),
+			zap.String("camera_id", event.CameraID),
+			zap.Int("subscribers", len(queues)))
+	}
+	return true
+}
+
+// deliverQueues delivers the events of the queues received from work until
+// work is closed. A queue is delivered until it is empty.
+func (p *Processor) deliverQueues(ctx context.Context, work <-chan *subscriberQueue) {
+	for queue := range work {
+		for {
+			select {
+			case <-p.abortCh:
+				return
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			events := p.nextEvents(queue)
+			if events == nil {
+				break
+			}
+			p.deliver(queue.subscriber, events)
+		}
+	}
+}
+
+// nextEvents takes the oldest batch off a queue, joined with the batches
+// behind it while they fit in MaxBatchSize, so a subscriber that fell behind
+// catches up in larger batches. It returns nil and releases the queue once it
+// is empty.
+func (p *Processor) nextEvents(queue *subscriberQueue) []*models.Event {
+	p.queueMu.Lock()
+	defer p.queueMu.Unlock()
+
+	if len(queue.batches) == 0 {
+		queue.active = false
+		return nil
+	}
+
+	events := queue.batches[0]
+	taken := 1
+	for taken < len(queue.batches) && len(events)+len(queue.batches[taken]) <= p.config.MaxBatchSize {
+		if taken == 1 {
+			events = append([]*models.Event(nil), events...)
+		}
+		events = append(events, queue.batches[taken]...)
+		taken++
+	}
+
+	for i := 0; i < taken; i++ {
+		queue.batches[i] = nil
+	}
+	queue.batches = queue.batches[taken:]
+	queue.pending -= len(events)
+	return events
+}
+
+// collectBatch gathers the events already pending behind first, up to the
+// configured batch size, without waiting for new ones. It reports false once
+// the event channel has been closed.
+func (p *Processor) collectBatch(first *models.Event) ([]*models.Event, bool) {
+	batch := []*models.Event{first}
+
+	for len(batch) < p.config.MaxBatchSize {
+		select {
+		case event, ok := <-p.eventCh:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, event)
+		default:
+			return batch, true
+		}
+	}
+
+	return batch, true
+}
+
+// deliver hands events to a subscriber, in a single call for batch
+// subscribers and one event at a time for the others
+func (p *Processor) deliver(subscriber Subscriber, events []*models.Event) {
+	if batcher, ok := subscriber.(BatchSubscriber); ok && len(events) > 1 {
+		if err := batcher.OnEvents(events); err != nil {
+			logger.Error("Subscriber batch error",
+				zap.Int("events", len(events)),
+				correlations(events),
+				zap.Error(err))
+		}
+		return
+	}
+
+	for _, event := range events {
 		if err := subscriber.OnEvent(event); err != nil {
 			logger.Error("Subscriber error",
-				zap.String("event_id", event.ID),
+				correlation(event),
 				zap.Error(err))
 		}
 	}
@@ -298,23 +770,99 @@ func (p *Processor) GetEventChannel() <-chan *models.Event {
 
 // PublishCameraEvent publishes a camera status event (online/offline)
 func (p *Processor) PublishCameraEvent(cameraID, cameraName string, eventType models.EventType) {
+	now := p.clock.Now()
 	event := &models.Event{
 		ID:         uuid.New().String(),
 		CameraID:   cameraID,
 		CameraName: cameraName,
 		Type:       eventType,
-		Timestamp:  time.Now(),
-		CreatedAt:  time.Now(),
+		Timestamp:  now,
+		CreatedAt:  now,
+	}
+
+	p.publishEvent(event)
+}
+
+// PublishConfigChangedEvent publishes an event recording a camera configuration change
+func (p *Processor) PublishConfigChangedEvent(cameraID, cameraName, configType string, changes interface{}) {
+	now := p.clock.Now()
+	event := &models.Event{
+		ID:         uuid.New().String(),
+		CameraID:   cameraID,
+		CameraName: cameraName,
+		Type:       models.EventConfigChanged,
+		Severity:   models.SeverityInfo,
+		Timestamp:  now,
+		CreatedAt:  now,
+	}
+
+	metadata := models.EventMetadata{
+		Extra: map[string]interface{}{
+			"config_type": configType,
+			"changes":     changes,
+		},
+	}
+
+	if metadataJSON, err := json.Marshal(metadata); err == nil {
+		event.Metadata = string(metadataJSON)
 	}
 
 	p.publishEvent(event)
 }
 
-// AddCamera starts polling a new camera
+// poller is the running poller of a camera
+type poller struct {
+	cancel context.CancelFunc
+}
+
+// AddCamera starts polling a new camera. A camera already polled, e.g. one
+// re-added with new settings, has its poller replaced.
 func (p *Processor) AddCamera(ctx context.Context, cameraClient *camera.CameraClient) {
-	p.wg.Add(1)
-	go p.pollCamera(ctx, cameraClient)
+	p.startPoller(ctx, cameraClient)
 
 	logger.Info("Added camera to event processor",
 		zap.String("camera_id", cameraClient.Camera.ID))
 }
+
+// RemoveCamera stops polling a camera removed from the camera manager
+func (p *Processor) RemoveCamera(cameraID string) {
+	p.pollersMu.Lock()
+	running, ok := p.pollers[cameraID]
+	delete(p.pollers, cameraID)
+	p.pollersMu.Unlock()
+
+	if p.quota != nil {
+		p.quota.forget(cameraID)
+	}
+
+	if ok {
+		running.cancel()
+		logger.Info("Removed camera from event processor", zap.String("camera_id", cameraID))
+	}
+}
+
+// startPoller starts the poller of a camera, stopping the one it replaces
+func (p *Processor) startPoller(ctx context.Context, cameraClient *camera.CameraClient) {
+	cameraID := cameraClient.Camera.ID
+	pollCtx, cancel := context.WithCancel(ctx)
+	running := &poller{cancel: cancel}
+
+	p.pollersMu.Lock()
+	if replaced, ok := p.pollers[cameraID]; ok {
+		replaced.cancel()
+	}
+	p.pollers[cameraID] = running
+	p.pollersMu.Unlock()
+
+	p.wg.Add(1)
+	go func() {
+		defer cancel()
+		p.pollCamera(pollCtx, cameraClient)
+
+		p.pollersMu.Lock()
+		if p.pollers[cameraID] == running {
+			delete(p.pollers, cameraID)
+		}
+		p.pollersMu.Unlock()
+	}()
+}
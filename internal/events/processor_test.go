@@ -2,13 +2,20 @@ package events
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
 )
 
 // MockSubscriber implements the Subscriber interface for testing
@@ -81,7 +88,6 @@ func TestProcessor_PublishCameraEvent(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	processor.wg.Add(1)
 	go processor.dispatchEvents(ctx)
 
 	// Give dispatcher time to start
@@ -95,7 +101,7 @@ func TestProcessor_PublishCameraEvent(t *testing.T) {
 
 	// Stop processor
 	cancel()
-	processor.wg.Wait()
+	<-processor.dispatchDone
 
 	// Verify event was received
 	require.Len(t, subscriber.events, 1)
@@ -103,6 +109,7 @@ func TestProcessor_PublishCameraEvent(t *testing.T) {
 	assert.Equal(t, "cam-123", event.CameraID)
 	assert.Equal(t, "Test Camera", event.CameraName)
 	assert.Equal(t, models.EventCameraOnline, event.Type)
+	assert.Equal(t, models.SeverityInfo, event.Severity, "severity defaults from the event type")
 	assert.NotEmpty(t, event.ID)
 }
 
@@ -133,6 +140,51 @@ func TestProcessor_StartStop(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestProcessor_AddRemoveCamera(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MotionCheckPeriod = time.Hour
+	cfg.AICheckPeriod = time.Hour
+	processor := NewProcessor(camera.NewManager(nil, nil), cfg)
+	ctx := context.Background()
+
+	pollerCount := func() int {
+		processor.pollersMu.Lock()
+		defer processor.pollersMu.Unlock()
+		return len(processor.pollers)
+	}
+	running := func() *poller {
+		processor.pollersMu.Lock()
+		defer processor.pollersMu.Unlock()
+		return processor.pollers["cam-1"]
+	}
+	client := func() *camera.CameraClient {
+		return &camera.CameraClient{Camera: &models.Camera{ID: "cam-1", EventMode: models.EventModePoll}}
+	}
+
+	// Adding a camera again replaces its poller instead of starting a second one
+	processor.AddCamera(ctx, client())
+	first := running()
+	processor.AddCamera(ctx, client())
+	assert.Equal(t, 1, pollerCount())
+	assert.NotSame(t, first, running())
+
+	// Removed cameras are no longer polled
+	processor.RemoveCamera("cam-1")
+	assert.Equal(t, 0, pollerCount())
+	processor.RemoveCamera("cam-1")
+
+	done := make(chan struct{})
+	go func() {
+		processor.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("pollers kept running after their camera was removed")
+	}
+}
+
 func TestProcessor_PublishEvent(t *testing.T) {
 	manager := camera.NewManager(nil, nil)
 	config := &Config{
@@ -190,7 +242,17 @@ func TestProcessor_PublishEvent_FullChannel(t *testing.T) {
 	assert.Len(t, processor.eventCh, 2)
 }
 
-func TestProcessor_NotifySubscribers(t *testing.T) {
+// dispatch publishes events and runs the processor until they are delivered
+func dispatch(t *testing.T, processor *Processor, events ...*models.Event) {
+	t.Helper()
+	for _, event := range events {
+		processor.publishEvent(event)
+	}
+	require.NoError(t, processor.Start(context.Background()))
+	require.NoError(t, processor.Stop())
+}
+
+func TestProcessor_DispatchesToSubscribers(t *testing.T) {
 	manager := camera.NewManager(nil, nil)
 	processor := NewProcessor(manager, nil)
 
@@ -206,7 +268,7 @@ func TestProcessor_NotifySubscribers(t *testing.T) {
 		Type:     models.EventMotionDetected,
 	}
 
-	processor.notifySubscribers(event)
+	dispatch(t, processor, event)
 
 	// Both subscribers should receive the event
 	assert.Len(t, subscriber1.events, 1)
@@ -248,24 +310,377 @@ func BenchmarkProcessor_PublishEvent(b *testing.B) {
 	}
 }
 
-func BenchmarkProcessor_NotifySubscribers(b *testing.B) {
+func BenchmarkProcessor_Deliver(b *testing.B) {
 	manager := camera.NewManager(nil, nil)
 	processor := NewProcessor(manager, nil)
 
-	// Add 10 subscribers
-	for i := 0; i < 10; i++ {
-		processor.Subscribe(&MockSubscriber{})
+	// 10 subscribers
+	subscribers := make([]Subscriber, 10)
+	for i := range subscribers {
+		subscribers[i] = &MockSubscriber{}
 	}
 
-	event := &models.Event{
+	events := []*models.Event{{
 		ID:       "evt-123",
 		CameraID: "cam-123",
 		Type:     models.EventMotionDetected,
-	}
+	}}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		processor.notifySubscribers(event)
+		for _, subscriber := range subscribers {
+			processor.deliver(subscriber, events)
+		}
+	}
+}
+
+func TestProcessor_PublishConfigChangedEvent(t *testing.T) {
+	manager := camera.NewManager(nil, nil)
+	processor := NewProcessor(manager, nil)
+
+	processor.PublishConfigChangedEvent("cam-123", "Test Camera", "device_name", map[string]string{"name": "Garage"})
+
+	select {
+	case event := <-processor.eventCh:
+		assert.Equal(t, "cam-123", event.CameraID)
+		assert.Equal(t, "Test Camera", event.CameraName)
+		assert.Equal(t, models.EventConfigChanged, event.Type)
+		assert.Equal(t, models.SeverityInfo, event.Severity)
+
+		var metadata models.EventMetadata
+		require.NoError(t, json.Unmarshal([]byte(event.Metadata), &metadata))
+		assert.Equal(t, "device_name", metadata.Extra["config_type"])
+		assert.Equal(t, map[string]interface{}{"name": "Garage"}, metadata.Extra["changes"])
+	case <-time.After(1 * time.Second):
+		t.Fatal("Timeout waiting for event")
+	}
+}
+
+// slowSubscriber records events after an artificial delay, like a store under load
+type slowSubscriber struct {
+	delay  time.Duration
+	mu     sync.Mutex
+	events []*models.Event
+}
+
+func (s *slowSubscriber) OnEvent(event *models.Event) error {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *slowSubscriber) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.events)
+}
+
+func TestProcessor_Stop_DrainsBufferedEvents(t *testing.T) {
+	processor := NewProcessor(camera.NewManager(nil, nil), nil)
+	store := &slowSubscriber{delay: 5 * time.Millisecond}
+	processor.Subscribe(store)
+
+	require.NoError(t, processor.Start(context.Background()))
+
+	for i := 0; i < 20; i++ {
+		processor.PublishCameraEvent("cam-1", "Camera 1", models.EventMotionDetected)
+	}
+
+	require.NoError(t, processor.Stop())
+	assert.Equal(t, 20, store.count(), "all buffered events must reach the store before Stop returns")
+}
+
+func TestProcessor_Stop_DrainTimeout(t *testing.T) {
+	config := DefaultConfig()
+	config.DrainTimeout = 50 * time.Millisecond
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+	store := &slowSubscriber{delay: 200 * time.Millisecond}
+	processor.Subscribe(store)
+
+	require.NoError(t, processor.Start(context.Background()))
+
+	for i := 0; i < 5; i++ {
+		processor.PublishCameraEvent("cam-1", "Camera 1", models.EventMotionDetected)
+	}
+
+	start := time.Now()
+	err := processor.Stop()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "timed out draining events")
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestProcessor_PublishAfterStop(t *testing.T) {
+	processor := NewProcessor(camera.NewManager(nil, nil), nil)
+	require.NoError(t, processor.Start(context.Background()))
+	require.NoError(t, processor.Stop())
+
+	assert.NotPanics(t, func() {
+		processor.PublishCameraEvent("cam-1", "Camera 1", models.EventMotionDetected)
+	})
+}
+
+func TestProcessor_ConcurrentPublishAndStop(t *testing.T) {
+	for round := 0; round < 20; round++ {
+		processor := NewProcessor(camera.NewManager(nil, nil), nil)
+		processor.Subscribe(&slowSubscriber{})
+		require.NoError(t, processor.Start(context.Background()))
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for j := 0; j < 200; j++ {
+					processor.PublishCameraEvent("cam-1", "Camera 1", models.EventCameraOnline)
+				}
+			}()
+		}
+
+		require.NoError(t, processor.Stop())
+		wg.Wait()
 	}
 }
 
+// batchRecorder is a BatchSubscriber recording how events were delivered
+type batchRecorder struct {
+	mu      sync.Mutex
+	batches [][]*models.Event
+	singles []*models.Event
+}
+
+func (b *batchRecorder) OnEvent(event *models.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.singles = append(b.singles, event)
+	return nil
+}
+
+func (b *batchRecorder) OnEvents(events []*models.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.batches = append(b.batches, events)
+	return nil
+}
+
+func TestProcessor_BatchDelivery(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxBatchSize = 3
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+
+	batcher := &batchRecorder{}
+	single := &slowSubscriber{}
+	processor.Subscribe(batcher)
+	processor.Subscribe(single)
+
+	// Queue events before the dispatcher runs so they are all pending at once
+	for i := 0; i < 7; i++ {
+		processor.PublishCameraEvent("cam-1", "Camera 1", models.EventMotionDetected)
+	}
+
+	require.NoError(t, processor.Start(context.Background()))
+	require.NoError(t, processor.Stop())
+
+	require.Len(t, batcher.batches, 2)
+	assert.Len(t, batcher.batches[0], 3)
+	assert.Len(t, batcher.batches[1], 3)
+	assert.Len(t, batcher.singles, 1, "a lone trailing event is delivered through OnEvent")
+
+	assert.Equal(t, 7, single.count(), "plain subscribers still receive every event")
+}
+
+func TestProcessor_Dispatch_SingleEventToBatchSubscriber(t *testing.T) {
+	processor := NewProcessor(camera.NewManager(nil, nil), nil)
+	batcher := &batchRecorder{}
+	processor.Subscribe(batcher)
+
+	dispatch(t, processor, &models.Event{ID: "event-1"})
+
+	assert.Empty(t, batcher.batches)
+	require.Len(t, batcher.singles, 1)
+	assert.Equal(t, "event-1", batcher.singles[0].ID)
+}
+
+// blockingSubscriber reports each event on entered and then waits for release
+type blockingSubscriber struct {
+	entered chan string
+	release chan struct{}
+	mu      sync.Mutex
+	events  []string
+}
+
+func newBlockingSubscriber() *blockingSubscriber {
+	return &blockingSubscriber{entered: make(chan string, 100), release: make(chan struct{})}
+}
+
+func (b *blockingSubscriber) OnEvent(event *models.Event) error {
+	b.entered <- event.ID
+	<-b.release
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, event.ID)
+	return nil
+}
+
+func (b *blockingSubscriber) received() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]string(nil), b.events...)
+}
+
+// publishIDs publishes events with the given IDs
+func publishIDs(processor *Processor, ids ...string) {
+	for _, id := range ids {
+		processor.publishEvent(&models.Event{ID: id, CameraID: "cam-1", Type: models.EventMotionDetected})
+	}
+}
+
+func TestProcessor_ParallelDispatch(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxWorkers = 2
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+
+	first, second := newBlockingSubscriber(), newBlockingSubscriber()
+	processor.Subscribe(first)
+	processor.Subscribe(second)
+
+	require.NoError(t, processor.Start(context.Background()))
+	publishIDs(processor, "event-1")
+
+	// Both subscribers are inside OnEvent at the same time
+	for _, subscriber := range []*blockingSubscriber{first, second} {
+		select {
+		case id := <-subscriber.entered:
+			assert.Equal(t, "event-1", id)
+		case <-time.After(time.Second):
+			t.Fatal("subscribers were not served in parallel")
+		}
+	}
+
+	close(first.release)
+	close(second.release)
+	require.NoError(t, processor.Stop())
+}
+
+func TestProcessor_SlowSubscriberDoesNotStallOthers(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxWorkers = 2
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+
+	slow := newBlockingSubscriber()
+	fast := &slowSubscriber{}
+	processor.Subscribe(slow)
+	processor.Subscribe(fast)
+
+	require.NoError(t, processor.Start(context.Background()))
+	publishIDs(processor, "event-1", "event-2", "event-3", "event-4", "event-5")
+
+	assert.Eventually(t, func() bool { return fast.count() == 5 }, time.Second, 5*time.Millisecond,
+		"a blocked subscriber must not hold up the others")
+	assert.Empty(t, slow.received())
+
+	close(slow.release)
+	require.NoError(t, processor.Stop())
+
+	assert.Equal(t, []string{"event-1", "event-2", "event-3", "event-4", "event-5"}, slow.received(),
+		"each subscriber gets its events in publishing order")
+	for i, event := range fast.events {
+		assert.Equal(t, slow.received()[i], event.ID)
+	}
+}
+
+func TestProcessor_FullSubscriberQueueDropsEvents(t *testing.T) {
+	config := DefaultConfig()
+	config.MaxWorkers = 2
+	config.EventBufferSize = 2
+	config.MaxBatchSize = 1
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+
+	slow := newBlockingSubscriber()
+	fast := &slowSubscriber{}
+	processor.Subscribe(slow)
+	processor.Subscribe(fast)
+
+	require.NoError(t, processor.Start(context.Background()))
+
+	// event-1 is being delivered, event-2 and event-3 fill the queue and
+	// event-4 does not fit anymore
+	for i, id := range []string{"event-1", "event-2", "event-3", "event-4"} {
+		publishIDs(processor, id)
+		require.Eventually(t, func() bool { return fast.count() == i+1 }, time.Second, time.Millisecond)
+		if i == 0 {
+			<-slow.entered
+		}
+	}
+
+	close(slow.release)
+	require.NoError(t, processor.Stop())
+
+	assert.Equal(t, []string{"event-1", "event-2", "event-3"}, slow.received())
+	assert.Equal(t, 4, fast.count(), "other subscribers keep receiving events")
+}
+
+// failingSubscriber rejects every event
+type failingSubscriber struct{}
+
+func (failingSubscriber) OnEvent(event *models.Event) error {
+	return errors.New("subscriber down")
+}
+
+func TestProcessor_CorrelationLogging(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	prev := logger.Log
+	logger.Log = zap.New(core)
+	t.Cleanup(func() { logger.Log = prev })
+
+	config := DefaultConfig()
+	config.SnapshotMotionEnabled = true
+	processor := NewProcessor(camera.NewManager(nil, nil), config)
+	received := &MockSubscriber{}
+	processor.Subscribe(received)
+	processor.Subscribe(failingSubscriber{})
+
+	// Capture: a snapshot differing from the previous one publishes a motion event
+	cam := &models.Camera{ID: "cam-1", Name: "Driveway"}
+	base := solidImage(320, 240, 60)
+	processor.detectSnapshotMotion(cam, encodeJPEG(t, base))
+	processor.detectSnapshotMotion(cam, encodeJPEG(t, withSquare(base, 40, 40, 120)))
+	require.Len(t, processor.eventCh, 1)
+
+	// Dispatch
+	dispatch(t, processor)
+	require.Len(t, received.events, 1)
+	event := received.events[0]
+
+	for _, msg := range []string{"Event published", "Subscriber error", "Event dispatched"} {
+		entries := logs.FilterMessage(msg).All()
+		require.Len(t, entries, 1, msg)
+		assert.Equal(t, event.ID, entries[0].ContextMap()[correlationKey], msg)
+	}
+}
+
+func TestStampEvent(t *testing.T) {
+	received := time.Date(2025, 10, 27, 10, 0, 5, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		deviceTime time.Time
+		want       time.Time
+	}{
+		{"device time preferred", received.Add(-3 * time.Second), received.Add(-3 * time.Second)},
+		{"no device time", time.Time{}, received},
+		{"device clock ahead", received.Add(time.Minute), received},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := &models.Event{}
+			stampEvent(event, tt.deviceTime, received)
+
+			assert.Equal(t, tt.want, event.Timestamp)
+			assert.Equal(t, received, event.CreatedAt, "receive time is kept separately")
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package events
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// eventQuota caps the number of events each camera may publish per window.
+// The first event over the cap is replaced by a single event_storm summary and
+// the rest are dropped until the window resets. Status and config events are
+// never counted or dropped, so a storm cannot hide a camera going offline.
+type eventQuota struct {
+	limit   int
+	window  time.Duration
+	cameras map[string]*quotaWindow
+	mu      sync.Mutex
+}
+
+// quotaExempt lists the event types the quota never applies to
+var quotaExempt = map[models.EventType]bool{
+	models.EventCameraOnline:  true,
+	models.EventCameraOffline: true,
+	models.EventConfigChanged: true,
+}
+
+// quotaWindow tracks the events of one camera in the current window
+type quotaWindow struct {
+	start   time.Time
+	count   int
+	dropped int
+}
+
+// newEventQuota creates a quota of limit events per window for each camera
+func newEventQuota(limit int, window time.Duration) *eventQuota {
+	return &eventQuota{
+		limit:   limit,
+		window:  window,
+		cameras: make(map[string]*quotaWindow),
+	}
+}
+
+// admit reports whether event may be published. When the camera has just
+// exceeded its quota, the storm summary event to publish instead is returned.
+func (q *eventQuota) admit(event *models.Event, now time.Time) (bool, *models.Event) {
+	if quotaExempt[event.Type] {
+		return true, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	w, ok := q.cameras[event.CameraID]
+	if !ok || now.Sub(w.start) >= q.window {
+		if ok && w.dropped > 0 {
+			logger.Info("Event storm ended",
+				zap.String("camera_id", event.CameraID),
+				zap.Int("dropped", w.dropped))
+		}
+		w = &quotaWindow{start: now}
+		q.cameras[event.CameraID] = w
+	}
+
+	w.count++
+	if w.count <= q.limit {
+		return true, nil
+	}
+
+	if w.count == q.limit+1 {
+		logger.Warn("Event quota exceeded, coalescing events",
+			zap.String("camera_id", event.CameraID),
+			zap.Int("limit", q.limit),
+			zap.Duration("window", q.window))
+		return false, q.stormEvent(event, w.start, now)
+	}
+
+	w.dropped++
+	return false, nil
+}
+
+// forget drops the window of a camera, e.g. one removed from the server
+func (q *eventQuota) forget(cameraID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.cameras, cameraID)
+}
+
+// stormEvent builds the summary event published at now in place of a flood of events
+func (q *eventQuota) stormEvent(trigger *models.Event, windowStart, now time.Time) *models.Event {
+	event := &models.Event{
+		ID:         uuid.New().String(),
+		CameraID:   trigger.CameraID,
+		CameraName: trigger.CameraName,
+		Type:       models.EventStorm,
+		Severity:   models.SeverityWarning,
+		Timestamp:  now,
+		CreatedAt:  now,
+	}
+
+	metadata := models.EventMetadata{
+		Extra: map[string]interface{}{
+			"limit":          q.limit,
+			"window":         q.window.String(),
+			"window_ends_at": windowStart.Add(q.window),
+			"trigger_type":   string(trigger.Type),
+		},
+	}
+
+	if metadataJSON, err := json.Marshal(metadata); err == nil {
+		event.Metadata = string(metadataJSON)
+	}
+
+	return event
+}
@@ -0,0 +1,117 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestEventQuota_CoalescesBeyondLimit(t *testing.T) {
+	q := newEventQuota(3, time.Minute)
+	now := time.Now()
+	motion := &models.Event{CameraID: "cam-1", CameraName: "Driveway", Type: models.EventMotionDetected}
+
+	for i := 0; i < 3; i++ {
+		allowed, summary := q.admit(motion, now)
+		assert.True(t, allowed)
+		assert.Nil(t, summary)
+	}
+
+	// First event over the cap becomes the storm summary
+	allowed, summary := q.admit(motion, now)
+	assert.False(t, allowed)
+	require.NotNil(t, summary)
+	assert.Equal(t, models.EventStorm, summary.Type)
+	assert.Equal(t, models.SeverityWarning, summary.Severity)
+	assert.Equal(t, "cam-1", summary.CameraID)
+
+	var metadata models.EventMetadata
+	require.NoError(t, json.Unmarshal([]byte(summary.Metadata), &metadata))
+	assert.EqualValues(t, 3, metadata.Extra["limit"])
+	assert.Equal(t, "motion_detected", metadata.Extra["trigger_type"])
+
+	// The rest are dropped silently
+	for i := 0; i < 10; i++ {
+		allowed, summary := q.admit(motion, now.Add(time.Second))
+		assert.False(t, allowed)
+		assert.Nil(t, summary)
+	}
+	assert.Equal(t, 10, q.cameras["cam-1"].dropped)
+
+	// Other cameras are unaffected
+	allowed, _ = q.admit(&models.Event{CameraID: "cam-2"}, now)
+	assert.True(t, allowed)
+
+	// A new window resets the quota
+	allowed, summary = q.admit(motion, now.Add(time.Minute))
+	assert.True(t, allowed)
+	assert.Nil(t, summary)
+}
+
+func TestProcessor_EventQuota(t *testing.T) {
+	config := DefaultConfig()
+	config.EventQuota = 5
+	config.EventQuotaWindow = time.Minute
+	processor := NewProcessor(nil, config)
+
+	for i := 0; i < 50; i++ {
+		processor.PublishCameraEvent("cam-1", "Driveway", models.EventMotionDetected)
+	}
+
+	require.Len(t, processor.eventCh, 6, "5 events plus one storm summary")
+	var types []models.EventType
+	for len(processor.eventCh) > 0 {
+		types = append(types, (<-processor.eventCh).Type)
+	}
+	assert.Equal(t, models.EventStorm, types[5])
+	for _, eventType := range types[:5] {
+		assert.Equal(t, models.EventMotionDetected, eventType)
+	}
+}
+
+func TestProcessor_EventQuotaDisabled(t *testing.T) {
+	config := DefaultConfig()
+	config.EventQuota = 0
+	processor := NewProcessor(nil, config)
+
+	for i := 0; i < 200; i++ {
+		processor.PublishCameraEvent("cam-1", "Driveway", models.EventMotionDetected)
+	}
+
+	assert.Len(t, processor.eventCh, 200)
+}
+
+func TestEventQuota_ExemptsStatusEvents(t *testing.T) {
+	q := newEventQuota(1, time.Minute)
+	now := time.Now()
+
+	allowed, _ := q.admit(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected}, now)
+	require.True(t, allowed)
+	allowed, summary := q.admit(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected}, now)
+	require.False(t, allowed)
+	require.NotNil(t, summary)
+
+	// Status and config events get through a storm
+	for _, eventType := range []models.EventType{models.EventCameraOffline, models.EventCameraOnline, models.EventConfigChanged} {
+		allowed, summary := q.admit(&models.Event{CameraID: "cam-1", Type: eventType}, now)
+		assert.True(t, allowed, eventType)
+		assert.Nil(t, summary, eventType)
+	}
+}
+
+func TestProcessor_RemoveCameraForgetsQuota(t *testing.T) {
+	config := DefaultConfig()
+	config.EventQuota = 5
+	processor := NewProcessor(nil, config)
+
+	processor.PublishCameraEvent("cam-1", "Driveway", models.EventMotionDetected)
+	require.Contains(t, processor.quota.cameras, "cam-1")
+
+	processor.RemoveCamera("cam-1")
+	assert.NotContains(t, processor.quota.cameras, "cam-1")
+}
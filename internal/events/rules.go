@@ -0,0 +1,314 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// Rule action types
+const (
+	RuleActionSiren    = "siren"
+	RuleActionWhiteLED = "white_led"
+
+	// ruleActionWhiteLEDOff switches off a white LED a rule turned on for a while
+	ruleActionWhiteLEDOff = "white_led_off"
+)
+
+// ruleActionTimeout bounds how long a single rule action may take
+const ruleActionTimeout = 30 * time.Second
+
+// RuleAction is a camera action executed when a rule matches
+type RuleAction struct {
+	Type     string
+	Duration int // Siren: number of plays; white LED: seconds before switching off again (0 leaves it on)
+}
+
+// Rule triggers actions on the camera an event came from when the camera
+// carries one of the rule's tags, the event type matches and the event
+// happened within the rule's active hours
+type Rule struct {
+	Name        string
+	Tags        []string           // Camera must have at least one of these tags; empty matches every camera
+	EventTypes  []models.EventType // Empty matches every event type
+	ActiveFrom  string             // Local time "HH:MM"; with ActiveUntil limits the rule to part of the day
+	ActiveUntil string             // May be earlier than ActiveFrom for windows spanning midnight
+	Cooldown    time.Duration      // Minimum time between two firings on the same camera
+	Actions     []RuleAction
+}
+
+// RuleActionExecutor runs rule actions against cameras
+type RuleActionExecutor interface {
+	ExecuteAction(ctx context.Context, cameraID string, action RuleAction) error
+}
+
+// compiledRule is a validated rule with its active hours in minutes after midnight
+type compiledRule struct {
+	Rule
+	from, until int
+	allDay      bool
+}
+
+// RuleEngine is a subscriber matching events against automation rules and
+// executing their actions. Actions run in the background so slow cameras do
+// not hold up event dispatching.
+type RuleEngine struct {
+	rules      []compiledRule
+	cameraTags func(cameraID string) []string
+	executor   RuleActionExecutor
+	lastFired  map[string]time.Time
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+}
+
+// NewRuleEngine creates a rule engine executing actions through the camera manager
+func NewRuleEngine(cameraManager *camera.Manager, rules []Rule) (*RuleEngine, error) {
+	compiled := make([]compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		c, err := compileRule(rule)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, c)
+	}
+
+	return &RuleEngine{
+		rules: compiled,
+		cameraTags: func(cameraID string) []string {
+			client, err := cameraManager.GetCamera(cameraID)
+			if err != nil {
+				return nil
+			}
+			return client.Camera.Tags
+		},
+		executor:  &cameraActionExecutor{cameraManager: cameraManager},
+		lastFired: make(map[string]time.Time),
+		stopCh:    make(chan struct{}),
+	}, nil
+}
+
+// compileRule validates a rule and parses its active hours
+func compileRule(rule Rule) (compiledRule, error) {
+	c := compiledRule{Rule: rule, allDay: true}
+
+	if rule.Name == "" {
+		return c, fmt.Errorf("automation rule without name")
+	}
+	if len(rule.Actions) == 0 {
+		return c, fmt.Errorf("automation rule %q has no actions", rule.Name)
+	}
+	for _, action := range rule.Actions {
+		if action.Type != RuleActionSiren && action.Type != RuleActionWhiteLED {
+			return c, fmt.Errorf("automation rule %q: unknown action %q", rule.Name, action.Type)
+		}
+	}
+
+	if rule.ActiveFrom == "" && rule.ActiveUntil == "" {
+		return c, nil
+	}
+
+	from, err := parseClock(rule.ActiveFrom)
+	if err != nil {
+		return c, fmt.Errorf("automation rule %q active_from: %w", rule.Name, err)
+	}
+	until, err := parseClock(rule.ActiveUntil)
+	if err != nil {
+		return c, fmt.Errorf("automation rule %q active_until: %w", rule.Name, err)
+	}
+
+	c.from, c.until, c.allDay = from, until, false
+	return c, nil
+}
+
+// parseClock parses "HH:MM" into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, use HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// OnEvent implements the Subscriber interface
+func (e *RuleEngine) OnEvent(event *models.Event) error {
+	for i := range e.rules {
+		rule := &e.rules[i]
+		if !e.matches(rule, event) || !e.claim(rule, event) {
+			continue
+		}
+
+		logger.Info("Automation rule triggered",
+			zap.String("rule", rule.Name),
+			zap.String("camera_id", event.CameraID),
+			correlation(event),
+			zap.String("type", string(event.Type)))
+
+		e.wg.Add(1)
+		go e.runActions(rule.Name, event, rule.Actions)
+	}
+
+	return nil
+}
+
+// Wait blocks until all running rule actions, including white LEDs waiting
+// to be switched off, have finished
+func (e *RuleEngine) Wait() {
+	e.wg.Wait()
+}
+
+// Stop switches off white LEDs still waiting for their duration to pass and
+// waits for all rule actions to finish
+func (e *RuleEngine) Stop() {
+	e.stopOnce.Do(func() { close(e.stopCh) })
+	e.wg.Wait()
+}
+
+// matches reports whether an event satisfies a rule's conditions
+func (e *RuleEngine) matches(rule *compiledRule, event *models.Event) bool {
+	if len(rule.EventTypes) > 0 && !containsEventType(rule.EventTypes, event.Type) {
+		return false
+	}
+
+	if !rule.allDay {
+		local := event.Timestamp.Local()
+		minute := local.Hour()*60 + local.Minute()
+		if rule.from <= rule.until {
+			if minute < rule.from || minute >= rule.until {
+				return false
+			}
+		} else if minute < rule.from && minute >= rule.until {
+			return false
+		}
+	}
+
+	if len(rule.Tags) > 0 && !hasAnyTag(e.cameraTags(event.CameraID), rule.Tags) {
+		return false
+	}
+
+	return true
+}
+
+// claim records a firing of the rule on the event's camera unless it is still cooling down
+func (e *RuleEngine) claim(rule *compiledRule, event *models.Event) bool {
+	if rule.Cooldown <= 0 {
+		return true
+	}
+
+	key := rule.Name + "/" + event.CameraID
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if last, ok := e.lastFired[key]; ok && event.Timestamp.Sub(last) < rule.Cooldown {
+		return false
+	}
+	e.lastFired[key] = event.Timestamp
+	return true
+}
+
+// runActions executes a rule's actions on the event's camera in order, continuing past failures
+func (e *RuleEngine) runActions(ruleName string, event *models.Event, actions []RuleAction) {
+	defer e.wg.Done()
+
+	for _, action := range actions {
+		ctx, cancel := context.WithTimeout(context.Background(), ruleActionTimeout)
+		err := e.executor.ExecuteAction(ctx, event.CameraID, action)
+		cancel()
+
+		if err != nil {
+			logger.Error("Automation rule action failed",
+				zap.String("rule", ruleName),
+				zap.String("camera_id", event.CameraID),
+				correlation(event),
+				zap.String("action", action.Type),
+				zap.Error(err))
+			continue
+		}
+
+		logger.Debug("Automation rule action executed",
+			zap.String("rule", ruleName),
+			zap.String("camera_id", event.CameraID),
+			correlation(event),
+			zap.String("action", action.Type))
+
+		if action.Type == RuleActionWhiteLED && action.Duration > 0 {
+			e.scheduleLEDOff(ruleName, event, time.Duration(action.Duration)*time.Second)
+		}
+	}
+}
+
+// scheduleLEDOff switches the white LED of the event's camera off after
+// delay, or right away once the engine is stopped
+func (e *RuleEngine) scheduleLEDOff(ruleName string, event *models.Event, delay time.Duration) {
+	e.wg.Add(1)
+	go func() {
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-e.stopCh:
+		}
+		e.runActions(ruleName, event, []RuleAction{{Type: ruleActionWhiteLEDOff}})
+	}()
+}
+
+// containsEventType reports whether eventType is in types
+func containsEventType(types []models.EventType, eventType models.EventType) bool {
+	for _, t := range types {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// hasAnyTag reports whether tags and wanted share at least one tag
+func hasAnyTag(tags []string, wanted []string) bool {
+	for _, tag := range tags {
+		for _, w := range wanted {
+			if tag == w {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// cameraActionExecutor runs rule actions on cameras of the camera manager
+type cameraActionExecutor struct {
+	cameraManager *camera.Manager
+}
+
+// ExecuteAction implements the RuleActionExecutor interface
+func (x *cameraActionExecutor) ExecuteAction(ctx context.Context, cameraID string, action RuleAction) error {
+	client, err := x.cameraManager.GetCamera(cameraID)
+	if err != nil {
+		return err
+	}
+
+	switch action.Type {
+	case RuleActionSiren:
+		times := action.Duration
+		if times <= 0 {
+			times = 1
+		}
+		return client.TriggerSiren(ctx, 0, times)
+	case RuleActionWhiteLED:
+		return client.SetWhiteLED(ctx, &reolink.WhiteLed{Channel: 0, State: 1, Bright: 100})
+	case ruleActionWhiteLEDOff:
+		return client.SetWhiteLED(ctx, &reolink.WhiteLed{Channel: 0, State: 0})
+	default:
+		return fmt.Errorf("unknown action %q", action.Type)
+	}
+}
@@ -0,0 +1,163 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// recordingExecutor is a RuleActionExecutor recording executed actions
+type recordingExecutor struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (r *recordingExecutor) ExecuteAction(ctx context.Context, cameraID string, action RuleAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actions = append(r.actions, cameraID+":"+action.Type)
+	return nil
+}
+
+func (r *recordingExecutor) executed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.actions...)
+}
+
+// newTestRuleEngine builds a rule engine with fixed camera tags and a recording executor
+func newTestRuleEngine(t *testing.T, rules []Rule, tags map[string][]string) (*RuleEngine, *recordingExecutor) {
+	engine, err := NewRuleEngine(nil, rules)
+	require.NoError(t, err)
+
+	executor := &recordingExecutor{}
+	engine.executor = executor
+	engine.cameraTags = func(cameraID string) []string { return tags[cameraID] }
+	return engine, executor
+}
+
+var perimeterNightRule = Rule{
+	Name:        "perimeter-night-person",
+	Tags:        []string{"perimeter"},
+	EventTypes:  []models.EventType{models.EventAIPerson},
+	ActiveFrom:  "20:00",
+	ActiveUntil: "06:00",
+	Actions:     []RuleAction{{Type: RuleActionSiren, Duration: 3}, {Type: RuleActionWhiteLED}},
+}
+
+func at(hour, minute int) time.Time {
+	return time.Date(2025, 10, 27, hour, minute, 0, 0, time.Local)
+}
+
+func TestRuleEngine_MatchingEventExecutesActions(t *testing.T) {
+	engine, executor := newTestRuleEngine(t, []Rule{perimeterNightRule}, map[string][]string{
+		"gate": {"outdoor", "perimeter"},
+	})
+
+	require.NoError(t, engine.OnEvent(&models.Event{ID: "evt-1", CameraID: "gate", Type: models.EventAIPerson, Timestamp: at(23, 30)}))
+	engine.Wait()
+
+	assert.Equal(t, []string{"gate:siren", "gate:white_led"}, executor.executed())
+}
+
+func TestRuleEngine_NonMatchingEvents(t *testing.T) {
+	tags := map[string][]string{
+		"gate":    {"perimeter"},
+		"kitchen": {"indoor"},
+	}
+
+	tests := []struct {
+		name  string
+		event *models.Event
+	}{
+		{"camera without tag", &models.Event{CameraID: "kitchen", Type: models.EventAIPerson, Timestamp: at(23, 30)}},
+		{"unknown camera", &models.Event{CameraID: "missing", Type: models.EventAIPerson, Timestamp: at(23, 30)}},
+		{"other event type", &models.Event{CameraID: "gate", Type: models.EventAIVehicle, Timestamp: at(23, 30)}},
+		{"daytime", &models.Event{CameraID: "gate", Type: models.EventAIPerson, Timestamp: at(12, 0)}},
+		{"end of window", &models.Event{CameraID: "gate", Type: models.EventAIPerson, Timestamp: at(6, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine, executor := newTestRuleEngine(t, []Rule{perimeterNightRule}, tags)
+
+			require.NoError(t, engine.OnEvent(tt.event))
+			engine.Wait()
+
+			assert.Empty(t, executor.executed())
+		})
+	}
+}
+
+func TestRuleEngine_WindowWithinDay(t *testing.T) {
+	rule := Rule{Name: "office-hours", ActiveFrom: "09:00", ActiveUntil: "17:00", Actions: []RuleAction{{Type: RuleActionSiren}}}
+	engine, executor := newTestRuleEngine(t, []Rule{rule}, nil)
+
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(8, 59)})
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(9, 0)})
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(17, 0)})
+	engine.Wait()
+
+	assert.Equal(t, []string{"cam-1:siren"}, executor.executed())
+}
+
+func TestRuleEngine_Cooldown(t *testing.T) {
+	rule := Rule{Name: "any-motion", Cooldown: time.Minute, Actions: []RuleAction{{Type: RuleActionSiren}}}
+	engine, executor := newTestRuleEngine(t, []Rule{rule}, nil)
+
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(10, 0)})
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(10, 0).Add(30 * time.Second)})
+	engine.OnEvent(&models.Event{CameraID: "cam-2", Type: models.EventMotionDetected, Timestamp: at(10, 0).Add(30 * time.Second)})
+	engine.OnEvent(&models.Event{CameraID: "cam-1", Type: models.EventMotionDetected, Timestamp: at(10, 1)})
+	engine.Wait()
+
+	assert.ElementsMatch(t, []string{"cam-1:siren", "cam-2:siren", "cam-1:siren"}, executor.executed())
+}
+
+func TestRuleEngine_StopSwitchesLEDOff(t *testing.T) {
+	engine, executor := newTestRuleEngine(t, []Rule{{
+		Name:    "light",
+		Actions: []RuleAction{{Type: RuleActionWhiteLED, Duration: 3600}},
+	}}, nil)
+
+	require.NoError(t, engine.OnEvent(&models.Event{CameraID: "gate", Type: models.EventMotionDetected, Timestamp: at(21, 0)}))
+
+	// The LED waiting to be switched off does not hold up shutdown
+	done := make(chan struct{})
+	go func() {
+		engine.Stop()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Stop waited for the LED duration")
+	}
+	assert.Equal(t, []string{"gate:white_led", "gate:white_led_off"}, executor.executed())
+}
+
+func TestNewRuleEngine_InvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule Rule
+	}{
+		{"missing name", Rule{Actions: []RuleAction{{Type: RuleActionSiren}}}},
+		{"no actions", Rule{Name: "r"}},
+		{"unknown action", Rule{Name: "r", Actions: []RuleAction{{Type: "fireworks"}}}},
+		{"bad time", Rule{Name: "r", ActiveFrom: "8pm", ActiveUntil: "06:00", Actions: []RuleAction{{Type: RuleActionSiren}}}},
+		{"half window", Rule{Name: "r", ActiveFrom: "20:00", Actions: []RuleAction{{Type: RuleActionSiren}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRuleEngine(nil, []Rule{tt.rule})
+			assert.Error(t, err)
+		})
+	}
+}
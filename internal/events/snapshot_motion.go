@@ -0,0 +1,129 @@
+package events
+
+import (
+	"image"
+	"sync"
+	"time"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+const (
+	// snapshotGridWidth and snapshotGridHeight are the dimensions snapshots are
+	// downsampled to before comparison, which keeps differencing cheap and
+	// smooths out sensor noise
+	snapshotGridWidth  = 64
+	snapshotGridHeight = 48
+
+	// snapshotPixelDelta is the luminance change (0-255) for a cell to count as changed
+	snapshotPixelDelta = 25
+
+	// DefaultSnapshotMotionThreshold is the default fraction of changed cells that reports motion
+	DefaultSnapshotMotionThreshold = 0.02
+
+	// DefaultSnapshotTimeout is how long a snapshot capture for motion detection may take
+	DefaultSnapshotTimeout = 5 * time.Second
+)
+
+// detectionAbilities are the abilities of cameras that detect motion or AI
+// objects themselves, which snapshot motion is not run for
+var detectionAbilities = []string{"alarmMd", "supportAi", "supportAiPeople", "supportAiVehicle", "supportAiDogCat"}
+
+// needsSnapshotMotion reports whether a camera lacks built-in motion and AI
+// detection according to its cached abilities. Cameras whose abilities are
+// unknown are assumed to lack them.
+func needsSnapshotMotion(cam *models.Camera) bool {
+	for _, ability := range detectionAbilities {
+		if cam.Capabilities[ability] {
+			return false
+		}
+	}
+	return true
+}
+
+// SnapshotMotionDetector detects motion by differencing consecutive snapshots.
+// It is a fallback for cameras that do not report motion or AI state themselves.
+type SnapshotMotionDetector struct {
+	threshold float64
+	previous  map[string][]uint8
+	mu        sync.Mutex
+}
+
+// NewSnapshotMotionDetector creates a detector reporting motion when at least
+// threshold (0-1) of the frame has changed. Lower thresholds are more sensitive.
+func NewSnapshotMotionDetector(threshold float64) *SnapshotMotionDetector {
+	if threshold <= 0 || threshold > 1 {
+		threshold = DefaultSnapshotMotionThreshold
+	}
+
+	return &SnapshotMotionDetector{
+		threshold: threshold,
+		previous:  make(map[string][]uint8),
+	}
+}
+
+// Detect compares img with the previous snapshot of the same camera and
+// returns whether motion was detected and the fraction of the frame that changed.
+// The first snapshot of a camera only establishes the baseline.
+func (d *SnapshotMotionDetector) Detect(cameraID string, img image.Image) (bool, float64) {
+	current := downsampleGray(img)
+
+	d.mu.Lock()
+	previous, ok := d.previous[cameraID]
+	d.previous[cameraID] = current
+	d.mu.Unlock()
+
+	if !ok {
+		return false, 0
+	}
+
+	changed := 0
+	for i := range current {
+		diff := int(current[i]) - int(previous[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff >= snapshotPixelDelta {
+			changed++
+		}
+	}
+
+	score := float64(changed) / float64(len(current))
+	return score >= d.threshold, score
+}
+
+// Reset forgets the baseline snapshot of a camera
+func (d *SnapshotMotionDetector) Reset(cameraID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.previous, cameraID)
+}
+
+// downsampleGray averages img into a fixed-size grid of luminance values
+func downsampleGray(img image.Image) []uint8 {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	sums := make([]uint64, snapshotGridWidth*snapshotGridHeight)
+	counts := make([]uint64, snapshotGridWidth*snapshotGridHeight)
+
+	for y := 0; y < height; y++ {
+		cy := y * snapshotGridHeight / height
+		for x := 0; x < width; x++ {
+			cx := x * snapshotGridWidth / width
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA()
+			// ITU-R BT.601 luma, scaled from 16-bit channels to 8 bits
+			luma := (299*uint64(r) + 587*uint64(g) + 114*uint64(b)) / 1000 >> 8
+			sums[cy*snapshotGridWidth+cx] += luma
+			counts[cy*snapshotGridWidth+cx]++
+		}
+	}
+
+	grid := make([]uint8, len(sums))
+	for i := range sums {
+		if counts[i] > 0 {
+			grid[i] = uint8(sums[i] / counts[i])
+		}
+	}
+	return grid
+}
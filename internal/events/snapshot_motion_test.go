@@ -0,0 +1,186 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	reolink "github.com/mosleyit/reolink_api_wrapper"
+	"github.com/mosleyit/reolink_server/internal/camera"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// solidImage returns a uniform gray image
+func solidImage(w, h int, gray uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.Gray{Y: gray})
+		}
+	}
+	return img
+}
+
+// withSquare returns a copy of img with a white square drawn at (x0, y0)
+func withSquare(img *image.RGBA, x0, y0, size int) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	copy(out.Pix, img.Pix)
+	for y := y0; y < y0+size; y++ {
+		for x := x0; x < x0+size; x++ {
+			out.Set(x, y, color.White)
+		}
+	}
+	return out
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	require.NoError(t, jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}))
+	return buf.Bytes()
+}
+
+func TestSnapshotMotionDetector_Detect(t *testing.T) {
+	base := solidImage(640, 480, 60)
+
+	t.Run("first frame only sets baseline", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		detected, score := d.Detect("cam-1", base)
+		assert.False(t, detected)
+		assert.Zero(t, score)
+	})
+
+	t.Run("identical frames report no motion", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		d.Detect("cam-1", base)
+		detected, score := d.Detect("cam-1", solidImage(640, 480, 60))
+		assert.False(t, detected)
+		assert.Zero(t, score)
+	})
+
+	t.Run("differing frames report motion", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		d.Detect("cam-1", base)
+		detected, score := d.Detect("cam-1", withSquare(base, 100, 100, 160))
+		assert.True(t, detected)
+		assert.InDelta(t, 160.0*160.0/(640.0*480.0), score, 0.02)
+	})
+
+	t.Run("small change below threshold is ignored", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.2)
+		d.Detect("cam-1", base)
+		detected, score := d.Detect("cam-1", withSquare(base, 100, 100, 80))
+		assert.False(t, detected)
+		assert.Greater(t, score, 0.0)
+	})
+
+	t.Run("slight brightness noise is ignored", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		d.Detect("cam-1", base)
+		detected, _ := d.Detect("cam-1", solidImage(640, 480, 70))
+		assert.False(t, detected)
+	})
+
+	t.Run("cameras are tracked independently", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		d.Detect("cam-1", base)
+		detected, _ := d.Detect("cam-2", withSquare(base, 0, 0, 320))
+		assert.False(t, detected, "first frame of cam-2 must only set its baseline")
+	})
+
+	t.Run("reset clears baseline", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0.02)
+		d.Detect("cam-1", base)
+		d.Reset("cam-1")
+		detected, _ := d.Detect("cam-1", withSquare(base, 0, 0, 320))
+		assert.False(t, detected)
+	})
+
+	t.Run("invalid threshold falls back to default", func(t *testing.T) {
+		d := NewSnapshotMotionDetector(0)
+		assert.Equal(t, DefaultSnapshotMotionThreshold, d.threshold)
+	})
+}
+
+func TestProcessor_DetectSnapshotMotion(t *testing.T) {
+	config := DefaultConfig()
+	config.SnapshotMotionEnabled = true
+	processor := NewProcessor(nil, config)
+	require.NotNil(t, processor.motionDiff)
+
+	cam := &models.Camera{ID: "cam-1", Name: "Driveway"}
+	base := solidImage(320, 240, 60)
+
+	processor.detectSnapshotMotion(cam, encodeJPEG(t, base))
+	processor.detectSnapshotMotion(cam, encodeJPEG(t, base))
+	assert.Len(t, processor.eventCh, 0, "identical snapshots must not emit events")
+
+	processor.detectSnapshotMotion(cam, encodeJPEG(t, withSquare(base, 40, 40, 120)))
+	require.Len(t, processor.eventCh, 1)
+
+	event := <-processor.eventCh
+	assert.Equal(t, models.EventMotionDetected, event.Type)
+	assert.Equal(t, "cam-1", event.CameraID)
+
+	var metadata models.EventMetadata
+	require.NoError(t, json.Unmarshal([]byte(event.Metadata), &metadata))
+	assert.Equal(t, "snapshot_diff", metadata.Extra["source"])
+
+	// Undecodable snapshots are skipped
+	processor.detectSnapshotMotion(cam, []byte("not an image"))
+	assert.Len(t, processor.eventCh, 0)
+}
+
+func TestNewProcessor_SnapshotMotionDisabledByDefault(t *testing.T) {
+	processor := NewProcessor(nil, nil)
+	assert.Nil(t, processor.motionDiff)
+}
+
+func TestNeedsSnapshotMotion(t *testing.T) {
+	// Cameras detecting motion or AI objects themselves are not sampled
+	assert.False(t, needsSnapshotMotion(&models.Camera{Capabilities: models.CameraCapabilities{"alarmMd": true}}))
+	assert.False(t, needsSnapshotMotion(&models.Camera{Capabilities: models.CameraCapabilities{"alarmMd": false, "supportAiPeople": true}}))
+
+	assert.True(t, needsSnapshotMotion(&models.Camera{Capabilities: models.CameraCapabilities{"alarmMd": false, "ptzCtrl": true}}))
+	assert.True(t, needsSnapshotMotion(&models.Camera{}), "cameras with unknown abilities are sampled")
+}
+
+func TestProcessor_CheckSnapshotMotion_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A camera that takes far longer than the capture timeout
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	config := DefaultConfig()
+	config.SnapshotMotionEnabled = true
+	config.SnapshotTimeout = 50 * time.Millisecond
+	processor := NewProcessor(nil, config)
+
+	client := &camera.CameraClient{
+		Camera: &models.Camera{ID: "cam-1"},
+		Client: reolink.NewClient(strings.TrimPrefix(server.URL, "http://"), reolink.WithToken("test-token")),
+	}
+
+	// The poll context has no deadline; the capture gives up on its own
+	start := time.Now()
+	processor.checkSnapshotMotion(context.Background(), client)
+
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Len(t, processor.eventCh, 0)
+}
@@ -72,8 +72,58 @@ func (s *Store) OnEvent(event *models.Event) error {
 	return s.SaveEvent(ctx, event)
 }
 
+// OnEvents implements the BatchSubscriber interface, saving all events in one round trip
+func (s *Store) OnEvents(events []*models.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return s.SaveEvents(ctx, events)
+}
+
 // SaveEvent saves an event to Redis Stream
 func (s *Store) SaveEvent(ctx context.Context, event *models.Event) error {
+	// Add to Redis Stream
+	_, err := s.client.XAdd(ctx, s.xAddArgs(event)).Result()
+
+	if err != nil {
+		logger.Error("Failed to save event to Redis",
+			correlation(event),
+			zap.Error(err))
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+
+	logger.Debug("Event saved to Redis",
+		correlation(event),
+		zap.String("stream", s.streamName))
+
+	return nil
+}
+
+// SaveEvents saves several events to Redis Stream in a single pipeline
+func (s *Store) SaveEvents(ctx context.Context, events []*models.Event) error {
+	pipe := s.client.Pipeline()
+	for _, event := range events {
+		pipe.XAdd(ctx, s.xAddArgs(event))
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		logger.Error("Failed to save events to Redis",
+			zap.Int("events", len(events)),
+			correlations(events),
+			zap.Error(err))
+		return fmt.Errorf("failed to save events: %w", err)
+	}
+
+	logger.Debug("Events saved to Redis",
+		zap.Int("events", len(events)),
+		correlations(events),
+		zap.String("stream", s.streamName))
+
+	return nil
+}
+
+// xAddArgs builds the stream entry for an event
+func (s *Store) xAddArgs(event *models.Event) *redis.XAddArgs {
 	eventData := map[string]interface{}{
 		"id":           event.ID,
 		"camera_id":    event.CameraID,
@@ -92,24 +142,10 @@ func (s *Store) SaveEvent(ctx context.Context, event *models.Event) error {
 		eventData["snapshot_path"] = event.SnapshotPath
 	}
 
-	// Add to Redis Stream
-	_, err := s.client.XAdd(ctx, &redis.XAddArgs{
+	return &redis.XAddArgs{
 		Stream: s.streamName,
 		Values: eventData,
-	}).Result()
-
-	if err != nil {
-		logger.Error("Failed to save event to Redis",
-			zap.String("event_id", event.ID),
-			zap.Error(err))
-		return fmt.Errorf("failed to save event: %w", err)
 	}
-
-	logger.Debug("Event saved to Redis",
-		zap.String("event_id", event.ID),
-		zap.String("stream", s.streamName))
-
-	return nil
 }
 
 // GetEvents retrieves events from Redis Stream
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"time"
+)
+
+// APIKeyScope limits what requests authenticated with an API key may do
+type APIKeyScope string
+
+const (
+	// APIKeyScopeRead keys may only make read-only (GET, HEAD, OPTIONS) requests
+	APIKeyScopeRead APIKeyScope = "read"
+	// APIKeyScopeControl keys may do everything the role of their user allows
+	APIKeyScopeControl APIKeyScope = "control"
+)
+
+// ValidAPIKeyScope reports whether scope is a known API key scope
+func ValidAPIKeyScope(scope APIKeyScope) bool {
+	return scope == APIKeyScopeRead || scope == APIKeyScopeControl
+}
+
+// APIKey represents a long-lived credential for integrations. Only a hash of
+// the key is stored; the key itself is shown once when it is created.
+type APIKey struct {
+	ID         string      `json:"id" db:"id"`
+	UserID     string      `json:"user_id" db:"user_id"`
+	Name       string      `json:"name" db:"name"`
+	Prefix     string      `json:"prefix" db:"key_prefix"` // Start of the key, to tell keys apart
+	KeyHash    string      `json:"-" db:"key_hash"`
+	Scope      APIKeyScope `json:"scope" db:"scope"`
+	CreatedAt  time.Time   `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time  `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time  `json:"revoked_at,omitempty" db:"revoked_at"`
+
+	// Owner of the key, filled in when a key is authenticated
+	Username string   `json:"-" db:"-"`
+	Role     UserRole `json:"-" db:"-"`
+}
+
+// CreateAPIKeyRequest represents a request to create an API key
+type CreateAPIKeyRequest struct {
+	Name  string      `json:"name" validate:"required"`
+	Scope APIKeyScope `json:"scope" validate:"required,oneof=read control"`
+}
+
+// CreateAPIKeyResponse holds a new API key, the only time it is returned
+type CreateAPIKeyResponse struct {
+	Key    string  `json:"key"`
+	APIKey *APIKey `json:"api_key"`
+}
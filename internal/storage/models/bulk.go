@@ -0,0 +1,47 @@
+package models
+
+// MaxBulkIDs is the most IDs a single bulk request may list
+const MaxBulkIDs = 500
+
+// Reasons an ID of a bulk operation failed
+const (
+	BulkReasonInvalidID = "invalid_id"
+	BulkReasonNotFound  = "not_found"
+	BulkReasonError     = "error"
+)
+
+// BulkRequest represents a request applying an operation to several IDs
+type BulkRequest struct {
+	IDs []string `json:"ids"`
+}
+
+// BulkResult reports which IDs of a bulk operation succeeded and why the
+// others failed
+type BulkResult struct {
+	Succeeded []string      `json:"succeeded"`
+	Failed    []BulkFailure `json:"failed"`
+}
+
+// BulkFailure is an ID a bulk operation failed for
+type BulkFailure struct {
+	ID     string `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// NewBulkResult creates an empty bulk result
+func NewBulkResult() *BulkResult {
+	return &BulkResult{
+		Succeeded: []string{},
+		Failed:    []BulkFailure{},
+	}
+}
+
+// Succeed records that the operation succeeded for id
+func (r *BulkResult) Succeed(id string) {
+	r.Succeeded = append(r.Succeeded, id)
+}
+
+// Fail records that the operation failed for id
+func (r *BulkResult) Fail(id, reason string) {
+	r.Failed = append(r.Failed, BulkFailure{ID: id, Reason: reason})
+}
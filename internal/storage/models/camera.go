@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
@@ -11,24 +12,121 @@ import (
 
 // Camera represents a Reolink camera in the system
 type Camera struct {
-	ID           string             `json:"id" db:"id"`
-	Name         string             `json:"name" db:"name"`
-	Host         string             `json:"host" db:"host"`
-	Port         int                `json:"port" db:"port"`
-	Username     string             `json:"username" db:"username"`
-	Password     string             `json:"-" db:"password"` // Never expose in JSON
-	UseHTTPS     bool               `json:"use_https" db:"use_https"`
-	SkipVerify   bool               `json:"skip_verify" db:"skip_verify"`
-	Status       string             `json:"status" db:"status"` // online, offline, error
-	Model        string             `json:"model" db:"model"`
-	FirmwareVer  string             `json:"firmware_version" db:"firmware_version"`
-	HardwareVer  string             `json:"hardware_version" db:"hardware_version"`
-	Capabilities CameraCapabilities `json:"capabilities" db:"capabilities"`
-	Tags         pq.StringArray     `json:"tags" db:"tags"`
-	GroupID      *string            `json:"group_id,omitempty" db:"group_id"`
-	LastSeen     time.Time          `json:"last_seen" db:"last_seen"`
-	CreatedAt    time.Time          `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time          `json:"updated_at" db:"updated_at"`
+	ID            string             `json:"id" db:"id"`
+	Name          string             `json:"name" db:"name"`
+	Host          string             `json:"host" db:"host"`
+	Port          int                `json:"port" db:"port"`
+	Username      string             `json:"username" db:"username"`
+	Password      string             `json:"-" db:"password"` // Never expose in JSON
+	UseHTTPS      bool               `json:"use_https" db:"use_https"`
+	SkipVerify    bool               `json:"skip_verify" db:"skip_verify"`
+	RTSPTransport string             `json:"rtsp_transport,omitempty" db:"rtsp_transport"` // tcp or udp; empty uses the server default
+	RebootTime    string             `json:"reboot_time,omitempty" db:"reboot_time"`       // Daily "HH:MM" (server local time) the server reboots the camera; empty disables
+	AlwaysReady   bool               `json:"always_ready" db:"always_ready"`               // Keep connections warm between requests, see cameras.keep_alive_interval
+	EventMode     string             `json:"event_mode,omitempty" db:"event_mode"`         // onvif or poll; empty uses ONVIF when the camera has it enabled
+	Status        string             `json:"status" db:"status"`                           // online, degraded, offline, error
+	Model         string             `json:"model" db:"model"`
+	FirmwareVer   string             `json:"firmware_version" db:"firmware_version"`
+	HardwareVer   string             `json:"hardware_version" db:"hardware_version"`
+	Capabilities  CameraCapabilities `json:"capabilities" db:"capabilities"`
+	Tags          pq.StringArray     `json:"tags" db:"tags"`
+	GroupID       *string            `json:"group_id,omitempty" db:"group_id"`
+	LastSeen      time.Time          `json:"last_seen" db:"last_seen"`
+	CreatedAt     time.Time          `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at" db:"updated_at"`
+
+	// Scheduled snapshots, written to cameras.snapshot_dir for timelapses
+	SnapshotEnabled  bool `json:"snapshot_enabled" db:"snapshot_enabled"`
+	SnapshotInterval int  `json:"snapshot_interval" db:"snapshot_interval"` // seconds between snapshots
+	SnapshotChannel  int  `json:"snapshot_channel" db:"snapshot_channel"`
+
+	// Scheduled PTZ patrol, run by the server during a daily time window
+	PatrolSchedule string `json:"patrol_schedule,omitempty" db:"patrol_schedule"` // "HH:MM-HH:MM" (server local time); empty disables
+	PatrolID       int    `json:"patrol_id" db:"patrol_id"`
+	PatrolChannel  int    `json:"patrol_channel" db:"patrol_channel"`
+
+	// Named bundle of timeout, retry, keep-alive and RTSP transport settings
+	// (see cameras.connection_profiles); empty uses the server defaults
+	ConnectionProfile string `json:"connection_profile,omitempty" db:"connection_profile"`
+}
+
+// RTSP transports for pulling a camera's RTSP stream
+const (
+	RTSPTransportTCP = "tcp"
+	RTSPTransportUDP = "udp"
+)
+
+// ValidRTSPTransport reports whether transport is a supported RTSP transport
+// or empty, which leaves the choice to the server default
+func ValidRTSPTransport(transport string) bool {
+	return transport == "" || transport == RTSPTransportTCP || transport == RTSPTransportUDP
+}
+
+// Event modes for receiving a camera's motion and AI events
+const (
+	EventModeAuto  = ""      // ONVIF when the camera has it enabled, polling otherwise
+	EventModeONVIF = "onvif" // ONVIF PullPoint subscription, polling while it is unavailable
+	EventModePoll  = "poll"  // Polling GetMdState and GetAiState only
+)
+
+// ValidEventMode reports whether mode is a supported event mode or empty,
+// which picks ONVIF when the camera has it enabled
+func ValidEventMode(mode string) bool {
+	return mode == EventModeAuto || mode == EventModeONVIF || mode == EventModePoll
+}
+
+// ValidRebootTime reports whether rebootTime is a "HH:MM" time of day or empty,
+// which disables the scheduled reboot
+func ValidRebootTime(rebootTime string) bool {
+	if rebootTime == "" {
+		return true
+	}
+	_, err := time.Parse("15:04", rebootTime)
+	return err == nil && len(rebootTime) == 5
+}
+
+// ValidSnapshotSchedule reports whether a camera's snapshot settings are
+// usable: a non-negative channel and interval, with a positive interval when
+// scheduled snapshots are enabled
+func ValidSnapshotSchedule(enabled bool, interval, channel int) bool {
+	if interval < 0 || channel < 0 {
+		return false
+	}
+	return !enabled || interval > 0
+}
+
+// ParsePatrolSchedule parses a "HH:MM-HH:MM" patrol window into its start
+// and stop times of day. A window whose stop is before its start runs over
+// midnight.
+func ParsePatrolSchedule(schedule string) (start, stop time.Time, err error) {
+	startStr, stopStr, ok := strings.Cut(schedule, "-")
+	if !ok || len(startStr) != 5 || len(stopStr) != 5 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid patrol schedule %q", schedule)
+	}
+	if start, err = time.Parse("15:04", startStr); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid patrol schedule %q: %w", schedule, err)
+	}
+	if stop, err = time.Parse("15:04", stopStr); err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid patrol schedule %q: %w", schedule, err)
+	}
+	if start.Equal(stop) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid patrol schedule %q: empty window", schedule)
+	}
+	return start, stop, nil
+}
+
+// ValidPatrolSchedule reports whether a camera's patrol settings are usable:
+// an empty schedule, which disables the scheduled patrol, or a valid
+// "HH:MM-HH:MM" window, and a non-negative patrol ID and channel
+func ValidPatrolSchedule(schedule string, patrolID, channel int) bool {
+	if patrolID < 0 || channel < 0 {
+		return false
+	}
+	if schedule == "" {
+		return true
+	}
+	_, _, err := ParsePatrolSchedule(schedule)
+	return err == nil
 }
 
 // CameraCapabilities represents camera capabilities stored as JSONB
@@ -59,33 +157,114 @@ func (c *CameraCapabilities) Scan(value interface{}) error {
 
 // CameraStatus represents the current status of a camera
 type CameraStatus struct {
-	CameraID    string    `json:"camera_id"`
-	Status      string    `json:"status"` // online, offline, error
-	Model       string    `json:"model"`
-	FirmwareVer string    `json:"firmware_version"`
-	Uptime      int64     `json:"uptime"` // seconds
-	LastSeen    time.Time `json:"last_seen"`
-	Error       string    `json:"error,omitempty"`
+	CameraID         string    `json:"camera_id"`
+	Status           string    `json:"status"`            // online, degraded, offline
+	Reasons          []string  `json:"reasons,omitempty"` // why the camera is degraded
+	Model            string    `json:"model"`
+	FirmwareVer      string    `json:"firmware_version"`
+	Uptime           int64     `json:"uptime"` // seconds health checks have passed without interruption
+	LastSeen         time.Time `json:"last_seen"`
+	LastHealthy      time.Time `json:"last_healthy"`  // last successful health check
+	FailureCount     int       `json:"failure_count"` // consecutive failed health checks
+	CircuitOpen      bool      `json:"circuit_open"`
+	Relogins         int       `json:"relogins"`          // logins after the camera session expired
+	EventSubscribers int       `json:"event_subscribers"` // live SSE/WebSocket event streams of the camera
+	Error            string    `json:"error,omitempty"`   // error of the latest failed health check
+
+	// Commands holds the latency of the operations run on the camera since
+	// it was connected, by operation
+	Commands map[string]CommandStats `json:"commands,omitempty"`
+}
+
+// CommandStats is the latency of an operation run on a camera
+type CommandStats struct {
+	Count    int64   `json:"count"`
+	Failures int64   `json:"failures"`
+	AvgMs    float64 `json:"avg_ms"`
+	MaxMs    float64 `json:"max_ms"`
+}
+
+// CameraDeviceInfo is what a camera reports about itself, queried live
+type CameraDeviceInfo struct {
+	CameraID     string `json:"camera_id"`
+	Name         string `json:"name"` // device name set on the camera
+	Model        string `json:"model"`
+	FirmwareVer  string `json:"firmware_version"`
+	HardwareVer  string `json:"hardware_version"`
+	ChannelCount int    `json:"channel_count"`
+	Detail       string `json:"detail"`
+	Serial       string `json:"serial,omitempty"`
+	Uptime       int64  `json:"uptime"` // seconds, see CameraStatus.Uptime
+}
+
+// CameraDisk is the state of a camera's HDD or SD card
+type CameraDisk struct {
+	ID          int     `json:"id"` // index of the disk, as used to format it
+	CapacityMB  int     `json:"capacity_mb"`
+	UsedMB      int     `json:"used_mb"`
+	FreeMB      int     `json:"free_mb"`
+	UsedPercent float64 `json:"used_percent"`
+	Formatted   bool    `json:"formatted"`
+	Mounted     bool    `json:"mounted"`
+	Status      string  `json:"status"` // as reported by the camera, e.g. "ok"
 }
 
 // CreateCameraRequest represents a request to add a new camera
 type CreateCameraRequest struct {
-	Name       string `json:"name" validate:"required"`
-	Host       string `json:"host" validate:"required"`
-	Port       int    `json:"port"`
-	Username   string `json:"username" validate:"required"`
-	Password   string `json:"password" validate:"required"`
-	UseHTTPS   bool   `json:"use_https"`
-	SkipVerify bool   `json:"skip_verify"`
+	Name          string `json:"name" validate:"required"`
+	Host          string `json:"host" validate:"required"`
+	Port          int    `json:"port"`
+	Username      string `json:"username" validate:"required"`
+	Password      string `json:"password" validate:"required"`
+	UseHTTPS      bool   `json:"use_https"`
+	SkipVerify    bool   `json:"skip_verify"`
+	RTSPTransport string `json:"rtsp_transport,omitempty"`
+	RebootTime    string `json:"reboot_time,omitempty"`
+	AlwaysReady   bool   `json:"always_ready,omitempty"`
+	EventMode     string `json:"event_mode,omitempty"`
+
+	ConnectionProfile string `json:"connection_profile,omitempty"`
+
+	SnapshotEnabled  bool `json:"snapshot_enabled,omitempty"`
+	SnapshotInterval int  `json:"snapshot_interval,omitempty"`
+	SnapshotChannel  int  `json:"snapshot_channel,omitempty"`
+
+	PatrolSchedule string `json:"patrol_schedule,omitempty"`
+	PatrolID       int    `json:"patrol_id,omitempty"`
+	PatrolChannel  int    `json:"patrol_channel,omitempty"`
 }
 
 // UpdateCameraRequest represents a request to update camera settings
 type UpdateCameraRequest struct {
-	Name       *string `json:"name,omitempty"`
-	Host       *string `json:"host,omitempty"`
-	Port       *int    `json:"port,omitempty"`
-	Username   *string `json:"username,omitempty"`
-	Password   *string `json:"password,omitempty"`
-	UseHTTPS   *bool   `json:"use_https,omitempty"`
-	SkipVerify *bool   `json:"skip_verify,omitempty"`
+	Name          *string `json:"name,omitempty"`
+	Host          *string `json:"host,omitempty"`
+	Port          *int    `json:"port,omitempty"`
+	Username      *string `json:"username,omitempty"`
+	Password      *string `json:"password,omitempty"`
+	UseHTTPS      *bool   `json:"use_https,omitempty"`
+	SkipVerify    *bool   `json:"skip_verify,omitempty"`
+	RTSPTransport *string `json:"rtsp_transport,omitempty"`
+	RebootTime    *string `json:"reboot_time,omitempty"`
+	AlwaysReady   *bool   `json:"always_ready,omitempty"`
+	EventMode     *string `json:"event_mode,omitempty"`
+
+	ConnectionProfile *string `json:"connection_profile,omitempty"`
+
+	SnapshotEnabled  *bool `json:"snapshot_enabled,omitempty"`
+	SnapshotInterval *int  `json:"snapshot_interval,omitempty"`
+	SnapshotChannel  *int  `json:"snapshot_channel,omitempty"`
+
+	PatrolSchedule *string `json:"patrol_schedule,omitempty"`
+	PatrolID       *int    `json:"patrol_id,omitempty"`
+	PatrolChannel  *int    `json:"patrol_channel,omitempty"`
+}
+
+// CameraHistoryPurge reports what was removed when purging a camera's history
+type CameraHistoryPurge struct {
+	CameraID          string       `json:"camera_id"`
+	EventsDeleted     int64        `json:"events_deleted"`
+	RecordingsDeleted int64        `json:"recordings_deleted"`
+	FilesDeleted      int          `json:"files_deleted"`
+	SnapshotPaths     []string     `json:"-"` // Local event snapshot files to remove
+	Recordings        []*Recording `json:"-"` // Deleted recordings whose files to remove
 }
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// CameraConfigVersion represents a versioned snapshot of a camera config type
+type CameraConfigVersion struct {
+	ID         string          `json:"id" db:"id"`
+	CameraID   string          `json:"camera_id" db:"camera_id"`
+	ConfigType string          `json:"config_type" db:"config_type"`
+	Version    int             `json:"version" db:"version"`
+	Config     json.RawMessage `json:"config" db:"config"`
+	CreatedAt  time.Time       `json:"created_at" db:"created_at"`
+}
@@ -1,6 +1,11 @@
 package models
 
 import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,8 +22,55 @@ const (
 	EventRecordingStop  EventType = "recording_stop"
 	EventCameraOnline   EventType = "camera_online"
 	EventCameraOffline  EventType = "camera_offline"
+	EventConfigChanged  EventType = "config_changed"
+	EventStorm          EventType = "event_storm" // Summary of events dropped by the per-camera quota
 )
 
+// EventTypeInfo describes an event type for clients building filters
+type EventTypeInfo struct {
+	Type            EventType     `json:"type"`
+	Description     string        `json:"description"`
+	DefaultSeverity EventSeverity `json:"default_severity"`
+}
+
+// EventTypes lists every event type the server emits
+var EventTypes = []EventTypeInfo{
+	{EventMotionDetected, "Motion detected by the camera or by snapshot differencing", SeverityInfo},
+	{EventAIPerson, "Person detected by the camera's AI", SeverityInfo},
+	{EventAIVehicle, "Vehicle detected by the camera's AI", SeverityInfo},
+	{EventAIPet, "Pet detected by the camera's AI", SeverityInfo},
+	{EventAudioAlarm, "Audio alarm raised by the camera", SeverityInfo},
+	{EventRecordingStart, "Camera started recording", SeverityInfo},
+	{EventRecordingStop, "Camera stopped recording", SeverityInfo},
+	{EventCameraOnline, "Camera came back online", SeverityInfo},
+	{EventCameraOffline, "Camera went offline", SeverityWarning},
+	{EventConfigChanged, "Camera configuration was changed through the API", SeverityInfo},
+	{EventStorm, "Summary of events dropped by the per-camera quota", SeverityWarning},
+}
+
+// DefaultSeverity returns the severity assigned to events of this type
+func (t EventType) DefaultSeverity() EventSeverity {
+	for _, info := range EventTypes {
+		if info.Type == t {
+			return info.DefaultSeverity
+		}
+	}
+	return SeverityInfo
+}
+
+// ParseEventType validates an event type name. The error of unknown names
+// lists the valid ones.
+func ParseEventType(s string) (EventType, error) {
+	names := make([]string, len(EventTypes))
+	for i, info := range EventTypes {
+		if string(info.Type) == s {
+			return info.Type, nil
+		}
+		names[i] = string(info.Type)
+	}
+	return "", fmt.Errorf("invalid event type %q, use one of: %s", s, strings.Join(names, ", "))
+}
+
 // EventSeverity represents the severity level of an event
 type EventSeverity string
 
@@ -28,6 +80,29 @@ const (
 	SeverityCritical EventSeverity = "critical"
 )
 
+// eventSeverities lists severities from least to most important
+var eventSeverities = []EventSeverity{SeverityInfo, SeverityWarning, SeverityCritical}
+
+// ParseEventSeverity validates a severity name
+func ParseEventSeverity(s string) (EventSeverity, error) {
+	for _, severity := range eventSeverities {
+		if string(severity) == s {
+			return severity, nil
+		}
+	}
+	return "", fmt.Errorf("invalid severity %q: must be one of info, warning, critical", s)
+}
+
+// SeveritiesAtLeast returns the severities at or above min, most important last
+func SeveritiesAtLeast(min EventSeverity) []EventSeverity {
+	for i, severity := range eventSeverities {
+		if severity == min {
+			return eventSeverities[i:]
+		}
+	}
+	return eventSeverities
+}
+
 // Event represents a camera event
 type Event struct {
 	ID             string        `json:"id" db:"id"`
@@ -44,6 +119,19 @@ type Event struct {
 	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
 }
 
+// EventSearchRequest represents a request to search events. Nil and empty
+// fields do not filter. Events are listed in Sort order, by default
+// DefaultEventSort.
+type EventSearchRequest struct {
+	CameraID     *string
+	Type         *EventType
+	Severities   []EventSeverity
+	Acknowledged *bool
+	Sort         SortOrder
+	Limit        int
+	Offset       int
+}
+
 // EventMetadata represents additional event information
 type EventMetadata struct {
 	Channel    int                    `json:"channel,omitempty"`
@@ -51,3 +139,60 @@ type EventMetadata struct {
 	Region     []int                  `json:"region,omitempty"`
 	Extra      map[string]interface{} `json:"extra,omitempty"`
 }
+
+// UnmarshalJSON decodes metadata without losing precision in Extra numbers.
+// Integers become int64, or stay json.Number when they do not fit one, and
+// only fractional numbers become float64, so IDs and timestamps round-trip
+// unchanged.
+func (m *EventMetadata) UnmarshalJSON(data []byte) error {
+	type plain EventMetadata
+	var decoded plain
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&decoded); err != nil {
+		return err
+	}
+
+	for key, value := range decoded.Extra {
+		decoded.Extra[key] = normalizeJSONNumbers(value)
+	}
+
+	*m = EventMetadata(decoded)
+	return nil
+}
+
+// normalizeJSONNumbers replaces the json.Number values of a decoded JSON
+// value with int64 or float64 where that is lossless
+func normalizeJSONNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if f, err := v.Float64(); err == nil && strconv.FormatFloat(f, 'g', -1, 64) == v.String() {
+			return f
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = normalizeJSONNumbers(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeJSONNumbers(item)
+		}
+	}
+	return value
+}
+
+// EventActivity summarizes a burst of recurring events of one type on one
+// camera as a single activity window
+type EventActivity struct {
+	CameraID   string    `json:"camera_id"`
+	CameraName string    `json:"camera_name"`
+	Type       EventType `json:"type"`
+	FirstSeen  time.Time `json:"first_seen"`
+	LastSeen   time.Time `json:"last_seen"`
+	Count      int       `json:"count"`
+}
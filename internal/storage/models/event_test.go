@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventMetadata_RoundTripPreservesLargeIntegers(t *testing.T) {
+	original := `{"channel":1,"confidence":0.75,"extra":{"event_id":9007199254740993,"huge":123456789012345678901234567890,"ratio":0.1,"nested":{"ts":1761559200123456789},"list":[9007199254740995]}}`
+
+	var metadata EventMetadata
+	require.NoError(t, json.Unmarshal([]byte(original), &metadata))
+
+	assert.Equal(t, 1, metadata.Channel)
+	assert.Equal(t, 0.75, metadata.Confidence)
+	assert.Equal(t, int64(9007199254740993), metadata.Extra["event_id"])
+	assert.Equal(t, json.Number("123456789012345678901234567890"), metadata.Extra["huge"])
+	assert.Equal(t, 0.1, metadata.Extra["ratio"])
+	assert.Equal(t, int64(1761559200123456789), metadata.Extra["nested"].(map[string]interface{})["ts"])
+	assert.Equal(t, []interface{}{int64(9007199254740995)}, metadata.Extra["list"])
+
+	encoded, err := json.Marshal(metadata)
+	require.NoError(t, err)
+	assert.JSONEq(t, original, string(encoded))
+	assert.Contains(t, string(encoded), "9007199254740993")
+	assert.Contains(t, string(encoded), "123456789012345678901234567890")
+}
+
+func TestParseEventType(t *testing.T) {
+	eventType, err := ParseEventType("motion_detected")
+	require.NoError(t, err)
+	assert.Equal(t, EventMotionDetected, eventType)
+
+	// Unknown names are answered with the valid ones
+	_, err = ParseEventType("motion")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid event type "motion"`)
+	assert.Contains(t, err.Error(), "motion_detected, ai_person")
+}
@@ -0,0 +1,30 @@
+package models
+
+// FirmwareReport lists the firmware every camera runs, grouped by model, so
+// cameras left behind on older firmware stand out
+type FirmwareReport struct {
+	Models           []FirmwareModel `json:"models"`
+	Total            int             `json:"total"`             // cameras in the report
+	Behind           int             `json:"behind"`            // cameras older than the latest firmware of their model
+	UpdatesChecked   bool            `json:"updates_checked"`   // cameras were asked for newer firmware
+	UpdatesAvailable int             `json:"updates_available"` // cameras reporting newer firmware
+}
+
+// FirmwareModel is the firmware of the cameras of one model
+type FirmwareModel struct {
+	Model         string           `json:"model"`          // empty for cameras that never reported one
+	LatestVersion string           `json:"latest_version"` // newest firmware any camera of the model runs
+	Versions      []string         `json:"versions"`       // distinct firmware versions, newest first
+	Drift         bool             `json:"drift"`          // cameras of the model run different firmware
+	Cameras       []CameraFirmware `json:"cameras"`
+}
+
+// CameraFirmware is the firmware of a single camera
+type CameraFirmware struct {
+	CameraID        string `json:"camera_id"`
+	Name            string `json:"name"`
+	FirmwareVer     string `json:"firmware_version"`
+	Behind          bool   `json:"behind"`                     // older than the model's latest version
+	UpdateAvailable *bool  `json:"update_available,omitempty"` // unset unless checked successfully
+	CheckError      string `json:"check_error,omitempty"`      // why checking for updates failed
+}
@@ -14,6 +14,7 @@ const (
 	RecordingAIVehicle  RecordingType = "ai_vehicle"
 	RecordingAIPet      RecordingType = "ai_pet"
 	RecordingManual     RecordingType = "manual"
+	RecordingClip       RecordingType = "clip" // Exported by the server, stored in its clip directory
 )
 
 // StreamType represents the stream type
@@ -48,7 +49,15 @@ type RecordingSearchRequest struct {
 	EndTime       *time.Time     `json:"end_time,omitempty"`
 	RecordingType *RecordingType `json:"recording_type,omitempty"`
 	StreamType    *StreamType    `json:"stream_type,omitempty"`
+	Sort          SortOrder      `json:"-"` // Zero lists by DefaultRecordingSort
 	Limit         int            `json:"limit,omitempty"`
 	Offset        int            `json:"offset,omitempty"`
 }
 
+// CreateClipRequest represents a request to export the recordings of a camera
+// between two times as a single clip
+type CreateClipRequest struct {
+	CameraID  string    `json:"camera_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+}
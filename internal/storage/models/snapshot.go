@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+// Snapshot is a still captured on a camera's snapshot schedule
+type Snapshot struct {
+	ID         string    `json:"id" db:"id"`
+	CameraID   string    `json:"camera_id" db:"camera_id"`
+	Channel    int       `json:"channel" db:"channel"`
+	FilePath   string    `json:"file_path" db:"file_path"`
+	FileSize   int64     `json:"file_size" db:"file_size"`
+	CapturedAt time.Time `json:"captured_at" db:"captured_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
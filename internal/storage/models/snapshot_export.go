@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Reasons an event is left out of a snapshot export, besides the bulk reasons
+const (
+	SnapshotSkipNoSnapshot = "no_snapshot"      // the event never had a snapshot stored
+	SnapshotSkipMissing    = "snapshot_missing" // the snapshot file no longer exists
+)
+
+// SnapshotExportRequest selects the events whose snapshots are exported,
+// either by ID or by filter
+type SnapshotExportRequest struct {
+	IDs    []string              `json:"ids,omitempty"`
+	Filter *SnapshotExportFilter `json:"filter,omitempty"`
+}
+
+// SnapshotExportFilter selects the events of a snapshot export, newest first
+type SnapshotExportFilter struct {
+	CameraID     string `json:"camera_id,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Acknowledged *bool  `json:"acknowledged,omitempty"`
+	Limit        int    `json:"limit,omitempty"` // defaults to and is capped at MaxBulkIDs
+}
+
+// SnapshotExportManifest describes the contents of a snapshot export and the
+// events left out of it
+type SnapshotExportManifest struct {
+	ExportedAt time.Time          `json:"exported_at"`
+	Snapshots  []ExportedSnapshot `json:"snapshots"`
+	Skipped    []BulkFailure      `json:"skipped"`
+}
+
+// ExportedSnapshot is an event snapshot included in an export
+type ExportedSnapshot struct {
+	File       string    `json:"file"` // name of the snapshot in the archive
+	EventID    string    `json:"event_id"`
+	CameraID   string    `json:"camera_id"`
+	CameraName string    `json:"camera_name"`
+	Type       EventType `json:"type"`
+	Timestamp  time.Time `json:"timestamp"`
+}
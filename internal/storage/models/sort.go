@@ -0,0 +1,143 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// SortOrder is the order of a list: one of the list's sortable fields and a
+// direction. The zero value uses the list's default order.
+type SortOrder struct {
+	Field      string
+	Descending bool
+}
+
+// SortFields maps the sortable fields of a list to the SQL expressions
+// ordering by them
+type SortFields map[string]string
+
+var (
+	// CameraSortFields are the fields cameras can be sorted by
+	CameraSortFields = SortFields{
+		"name":       "name",
+		"status":     "status",
+		"model":      "model",
+		"last_seen":  "last_seen",
+		"created_at": "created_at",
+	}
+
+	// EventSortFields are the fields events can be sorted by. Severities
+	// sort by importance rather than by name.
+	EventSortFields = SortFields{
+		"timestamp":   "timestamp",
+		"type":        "type",
+		"severity":    "CASE severity WHEN 'critical' THEN 2 WHEN 'warning' THEN 1 ELSE 0 END",
+		"camera_name": "camera_name",
+		"created_at":  "created_at",
+	}
+
+	// RecordingSortFields are the fields recordings can be sorted by
+	RecordingSortFields = SortFields{
+		"start_time": "start_time",
+		"end_time":   "end_time",
+		"duration":   "duration",
+		"file_size":  "file_size",
+		"created_at": "created_at",
+	}
+)
+
+var (
+	// DefaultCameraSort lists cameras by name
+	DefaultCameraSort = SortOrder{Field: "name"}
+
+	// DefaultEventSort lists events newest first
+	DefaultEventSort = SortOrder{Field: "timestamp", Descending: true}
+
+	// DefaultRecordingSort lists recordings newest first
+	DefaultRecordingSort = SortOrder{Field: "start_time", Descending: true}
+)
+
+// names returns the sortable fields in alphabetical order
+func (f SortFields) names() []string {
+	names := make([]string, 0, len(f))
+	for name := range f {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ParseSortOrder validates the sort and order query parameters of a list
+// against its sortable fields. Without sort the list's default field is
+// used, and without order the field's default direction: descending for the
+// default field when the default is, ascending otherwise. Without either the
+// zero SortOrder is returned.
+func ParseSortOrder(field, order string, fields SortFields, def SortOrder) (SortOrder, error) {
+	if field == "" && order == "" {
+		return SortOrder{}, nil
+	}
+
+	result := def
+	if field != "" && field != def.Field {
+		if _, ok := fields[field]; !ok {
+			return SortOrder{}, fmt.Errorf("invalid sort field %q: must be one of %s", field, strings.Join(fields.names(), ", "))
+		}
+		result = SortOrder{Field: field}
+	}
+
+	switch order {
+	case "":
+	case "asc":
+		result.Descending = false
+	case "desc":
+		result.Descending = true
+	default:
+		return SortOrder{}, fmt.Errorf("invalid order %q: must be asc or desc", order)
+	}
+	return result, nil
+}
+
+// OrderBy returns the ORDER BY expression of the sort, with the id column
+// breaking ties so pages are stable. Zero or unknown fields use def, so the
+// result always comes from the allowlist and is safe to put into a query.
+func (s SortOrder) OrderBy(fields SortFields, def SortOrder) string {
+	expr, ok := fields[s.Field]
+	if !ok {
+		s = def
+		expr = fields[def.Field]
+	}
+
+	direction := "ASC"
+	if s.Descending {
+		direction = "DESC"
+	}
+	return expr + " " + direction + ", id " + direction
+}
+
+// SortCameras sorts cameras in place, with the ID breaking ties. Unknown
+// fields sort by name.
+func SortCameras(cameras []*Camera, order SortOrder) {
+	slices.SortFunc(cameras, func(a, b *Camera) int {
+		var c int
+		switch order.Field {
+		case "status":
+			c = strings.Compare(a.Status, b.Status)
+		case "model":
+			c = strings.Compare(a.Model, b.Model)
+		case "last_seen":
+			c = a.LastSeen.Compare(b.LastSeen)
+		case "created_at":
+			c = a.CreatedAt.Compare(b.CreatedAt)
+		default:
+			c = strings.Compare(a.Name, b.Name)
+		}
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if order.Descending {
+			return -c
+		}
+		return c
+	})
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		name  string
+		field string
+		order string
+		want  SortOrder
+	}{
+		{"default", "", "", SortOrder{}},
+		{"default field reversed", "", "asc", SortOrder{Field: "timestamp"}},
+		{"default field", "timestamp", "", SortOrder{Field: "timestamp", Descending: true}},
+		{"other field ascending by default", "severity", "", SortOrder{Field: "severity"}},
+		{"other field descending", "camera_name", "desc", SortOrder{Field: "camera_name", Descending: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order, err := ParseSortOrder(tt.field, tt.order, EventSortFields, DefaultEventSort)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, order)
+		})
+	}
+
+	_, err := ParseSortOrder("metadata", "", EventSortFields, DefaultEventSort)
+	assert.ErrorContains(t, err, "camera_name, created_at, severity, timestamp, type", "lists the allowed fields")
+	_, err = ParseSortOrder("timestamp; DROP TABLE events", "", EventSortFields, DefaultEventSort)
+	assert.Error(t, err)
+	_, err = ParseSortOrder("timestamp", "up", EventSortFields, DefaultEventSort)
+	assert.Error(t, err)
+}
+
+func TestSortOrder_OrderBy(t *testing.T) {
+	assert.Equal(t, "start_time DESC, id DESC", SortOrder{}.OrderBy(RecordingSortFields, DefaultRecordingSort))
+	assert.Equal(t, "file_size ASC, id ASC", SortOrder{Field: "file_size"}.OrderBy(RecordingSortFields, DefaultRecordingSort))
+	assert.Equal(t, "start_time DESC, id DESC", SortOrder{Field: "storage_path"}.OrderBy(RecordingSortFields, DefaultRecordingSort),
+		"fields outside the allowlist never reach the query")
+	assert.Contains(t, SortOrder{Field: "severity", Descending: true}.OrderBy(EventSortFields, DefaultEventSort), "WHEN 'critical' THEN 2")
+}
+
+func TestSortCameras(t *testing.T) {
+	now := time.Now()
+	cameras := []*Camera{
+		{ID: "cam-3", Name: "Garage", Status: "online", LastSeen: now.Add(-time.Hour)},
+		{ID: "cam-1", Name: "Front Door", Status: "offline", LastSeen: now.Add(-2 * time.Hour)},
+		{ID: "cam-2", Name: "Backyard", Status: "online", LastSeen: now},
+	}
+	ids := func() []string {
+		var ids []string
+		for _, camera := range cameras {
+			ids = append(ids, camera.ID)
+		}
+		return ids
+	}
+
+	SortCameras(cameras, SortOrder{})
+	assert.Equal(t, []string{"cam-2", "cam-1", "cam-3"}, ids(), "by name")
+
+	SortCameras(cameras, SortOrder{Field: "last_seen", Descending: true})
+	assert.Equal(t, []string{"cam-2", "cam-3", "cam-1"}, ids())
+
+	SortCameras(cameras, SortOrder{Field: "status"})
+	assert.Equal(t, []string{"cam-1", "cam-2", "cam-3"}, ids(), "ties broken by ID")
+}
@@ -45,6 +45,16 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// SSETokenAudience is the JWT audience of the short-lived tokens browser
+// EventSource clients pass in the query string
+const SSETokenAudience = "sse"
+
+// SSETokenResponse represents a short-lived token for SSE authentication
+type SSETokenResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // LoginResponse represents a login response with JWT token
 type LoginResponse struct {
 	Token     string    `json:"token"`
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestAPIKeyRepository_GetActiveByHash(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	repo := NewAPIKeyRepository(&db.DB{DB: sqlDB})
+	created := time.Now().Add(-time.Hour)
+
+	mock.ExpectQuery(`FROM api_keys k\s+JOIN users u ON u.id = k.user_id\s+WHERE k.key_hash = \$1 AND k.revoked_at IS NULL`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "key_prefix", "key_hash", "scope", "created_at",
+			"last_used_at", "username", "role"}).
+			AddRow("key-1", "user-1", "nvr", "rlk_0123abcd", "hash", "read", created, nil, "admin", "admin"))
+
+	key, err := repo.GetActiveByHash(context.Background(), "hash")
+
+	require.NoError(t, err)
+	assert.Equal(t, models.APIKeyScopeRead, key.Scope)
+	assert.Equal(t, models.RoleAdmin, key.Role)
+	assert.Nil(t, key.LastUsedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	mock.ExpectQuery(`FROM api_keys`).WithArgs("revoked").WillReturnRows(sqlmock.NewRows(nil))
+	_, err = repo.GetActiveByHash(context.Background(), "revoked")
+	assert.ErrorIs(t, err, ErrAPIKeyNotFound)
+}
+
+func TestAPIKeyRepository_Revoke(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	repo := NewAPIKeyRepository(&db.DB{DB: sqlDB})
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = \$3 WHERE id = \$1 AND user_id = \$2 AND revoked_at IS NULL`).
+		WithArgs("key-1", "user-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at`).
+		WithArgs("key-1", "user-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.Revoke(context.Background(), "key-1", "user-1"))
+	assert.ErrorIs(t, repo.Revoke(context.Background(), "key-1", "user-1"), ErrAPIKeyNotFound, "keys are revoked once")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
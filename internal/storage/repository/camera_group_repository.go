@@ -3,14 +3,30 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/mosleyit/reolink_server/internal/storage/db"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
+var (
+	// ErrCameraGroupNotFound is returned for unknown camera groups
+	ErrCameraGroupNotFound = errors.New("camera group not found")
+
+	// ErrCameraGroupExists is returned when another group already has the name
+	ErrCameraGroupExists = errors.New("camera group name already in use")
+
+	// ErrNotGroupMember is returned when removing a camera from a group it is not in
+	ErrNotGroupMember = errors.New("camera is not in the group")
+)
+
+// uniqueViolation is the PostgreSQL error code of unique constraint violations
+const uniqueViolation = "23505"
+
 // CameraGroupRepository handles camera group database operations
 type CameraGroupRepository struct {
 	db *db.DB
@@ -39,6 +55,9 @@ func (r *CameraGroupRepository) Create(ctx context.Context, group *models.Camera
 	_, err := r.db.ExecContext(ctx, query,
 		group.ID, group.Name, group.Description, group.CreatedAt, group.UpdatedAt)
 
+	if isUniqueViolation(err) {
+		return ErrCameraGroupExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create camera group: %w", err)
 	}
@@ -59,7 +78,7 @@ func (r *CameraGroupRepository) GetByID(ctx context.Context, id string) (*models
 		&group.ID, &group.Name, &group.Description, &group.CreatedAt, &group.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("camera group not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrCameraGroupNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get camera group: %w", err)
@@ -81,7 +100,7 @@ func (r *CameraGroupRepository) GetByName(ctx context.Context, name string) (*mo
 		&group.ID, &group.Name, &group.Description, &group.CreatedAt, &group.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("camera group not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrCameraGroupNotFound, name)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get camera group: %w", err)
@@ -131,6 +150,9 @@ func (r *CameraGroupRepository) Update(ctx context.Context, group *models.Camera
 	`
 
 	result, err := r.db.ExecContext(ctx, query, group.ID, group.Name, group.Description)
+	if isUniqueViolation(err) {
+		return ErrCameraGroupExists
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update camera group: %w", err)
 	}
@@ -141,7 +163,7 @@ func (r *CameraGroupRepository) Update(ctx context.Context, group *models.Camera
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("camera group not found: %s", group.ID)
+		return fmt.Errorf("%w: %s", ErrCameraGroupNotFound, group.ID)
 	}
 
 	return nil
@@ -162,7 +184,7 @@ func (r *CameraGroupRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("camera group not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrCameraGroupNotFound, id)
 	}
 
 	return nil
@@ -181,3 +203,8 @@ func (r *CameraGroupRepository) Count(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+// isUniqueViolation reports whether err is a unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation
+}
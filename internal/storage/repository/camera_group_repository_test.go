@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func newCameraGroupRepoWithMock(t *testing.T) (*CameraGroupRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return NewCameraGroupRepository(&db.DB{DB: sqlDB}), mock
+}
+
+func TestCameraGroupRepository_Create(t *testing.T) {
+	repo, mock := newCameraGroupRepoWithMock(t)
+
+	mock.ExpectExec(`INSERT INTO camera_groups`).
+		WithArgs(sqlmock.AnyArg(), "Garden", "Backyard cameras", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	group := &models.CameraGroup{Name: "Garden", Description: "Backyard cameras"}
+	require.NoError(t, repo.Create(context.Background(), group))
+	assert.NotEmpty(t, group.ID)
+	assert.False(t, group.CreatedAt.IsZero())
+
+	mock.ExpectExec(`INSERT INTO camera_groups`).WillReturnError(&pq.Error{Code: uniqueViolation})
+	err := repo.Create(context.Background(), &models.CameraGroup{Name: "Garden"})
+	assert.ErrorIs(t, err, ErrCameraGroupExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraGroupRepository_GetByID(t *testing.T) {
+	repo, mock := newCameraGroupRepoWithMock(t)
+	now := time.Now()
+
+	mock.ExpectQuery(`FROM camera_groups\s+WHERE id = \$1`).
+		WithArgs("group-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "description", "created_at", "updated_at"}).
+			AddRow("group-1", "Garden", "", now, now))
+
+	group, err := repo.GetByID(context.Background(), "group-1")
+	require.NoError(t, err)
+	assert.Equal(t, "Garden", group.Name)
+
+	mock.ExpectQuery(`FROM camera_groups`).WithArgs("missing").WillReturnRows(sqlmock.NewRows(nil))
+	_, err = repo.GetByID(context.Background(), "missing")
+	assert.ErrorIs(t, err, ErrCameraGroupNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraGroupRepository_UpdateAndDelete_NotFound(t *testing.T) {
+	repo, mock := newCameraGroupRepoWithMock(t)
+
+	mock.ExpectExec(`UPDATE camera_groups`).
+		WithArgs("missing", "Garden", "").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	err := repo.Update(context.Background(), &models.CameraGroup{ID: "missing", Name: "Garden"})
+	assert.ErrorIs(t, err, ErrCameraGroupNotFound)
+
+	mock.ExpectExec(`UPDATE camera_groups`).WillReturnError(&pq.Error{Code: uniqueViolation})
+	err = repo.Update(context.Background(), &models.CameraGroup{ID: "group-1", Name: "Garden"})
+	assert.ErrorIs(t, err, ErrCameraGroupExists)
+
+	mock.ExpectExec(`DELETE FROM camera_groups WHERE id = \$1`).
+		WithArgs("missing").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.Delete(context.Background(), "missing"), ErrCameraGroupNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,14 +3,29 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/mosleyit/reolink_server/internal/storage/db"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
+var (
+	// ErrCameraNotFound is returned for unknown cameras
+	ErrCameraNotFound = errors.New("camera not found")
+
+	// ErrCameraNameExists is returned when unique names are enforced and
+	// another camera already has the name
+	ErrCameraNameExists = errors.New("camera name already in use")
+)
+
+// cameraNameIndex is the unique index on lower-cased camera names that
+// EnforceUniqueNames maintains
+const cameraNameIndex = "idx_cameras_name_lower"
+
 // CameraRepository handles camera database operations
 type CameraRepository struct {
 	db *db.DB
@@ -32,18 +47,26 @@ func (r *CameraRepository) Create(ctx context.Context, camera *models.Camera) er
 	camera.UpdatedAt = now
 
 	query := `
-		INSERT INTO cameras (id, name, host, port, username, password, use_https, skip_verify,
+		INSERT INTO cameras (id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
 			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
 			last_seen, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21,
+			$22, $23, $24, $25, $26, $27, $28, $29)
 	`
 
 	_, err := r.db.ExecContext(ctx, query,
 		camera.ID, camera.Name, camera.Host, camera.Port, camera.Username, camera.Password,
-		camera.UseHTTPS, camera.SkipVerify, camera.Status, camera.Model, camera.FirmwareVer,
+		camera.UseHTTPS, camera.SkipVerify, camera.RTSPTransport, camera.RebootTime, camera.AlwaysReady, camera.EventMode, camera.ConnectionProfile,
+		camera.SnapshotEnabled, camera.SnapshotInterval, camera.SnapshotChannel,
+		camera.PatrolSchedule, camera.PatrolID, camera.PatrolChannel, camera.Status, camera.Model, camera.FirmwareVer,
 		camera.HardwareVer, camera.Capabilities, camera.Tags, camera.GroupID,
 		camera.LastSeen, camera.CreatedAt, camera.UpdatedAt)
 
+	if isCameraNameViolation(err) {
+		return fmt.Errorf("%w: %s", ErrCameraNameExists, camera.Name)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create camera: %w", err)
 	}
@@ -54,7 +77,9 @@ func (r *CameraRepository) Create(ctx context.Context, camera *models.Camera) er
 // GetByID retrieves a camera by ID
 func (r *CameraRepository) GetByID(ctx context.Context, id string) (*models.Camera, error) {
 	query := `
-		SELECT id, name, host, port, username, password, use_https, skip_verify,
+		SELECT id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
 			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
 			last_seen, created_at, updated_at
 		FROM cameras
@@ -64,12 +89,14 @@ func (r *CameraRepository) GetByID(ctx context.Context, id string) (*models.Came
 	camera := &models.Camera{}
 	err := r.db.QueryRowContext(ctx, query, id).Scan(
 		&camera.ID, &camera.Name, &camera.Host, &camera.Port, &camera.Username, &camera.Password,
-		&camera.UseHTTPS, &camera.SkipVerify, &camera.Status, &camera.Model, &camera.FirmwareVer,
+		&camera.UseHTTPS, &camera.SkipVerify, &camera.RTSPTransport, &camera.RebootTime, &camera.AlwaysReady, &camera.EventMode, &camera.ConnectionProfile,
+		&camera.SnapshotEnabled, &camera.SnapshotInterval, &camera.SnapshotChannel,
+		&camera.PatrolSchedule, &camera.PatrolID, &camera.PatrolChannel, &camera.Status, &camera.Model, &camera.FirmwareVer,
 		&camera.HardwareVer, &camera.Capabilities, &camera.Tags, &camera.GroupID,
 		&camera.LastSeen, &camera.CreatedAt, &camera.UpdatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("camera not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrCameraNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get camera: %w", err)
@@ -81,7 +108,9 @@ func (r *CameraRepository) GetByID(ctx context.Context, id string) (*models.Came
 // GetByHost retrieves a camera by host and port
 func (r *CameraRepository) GetByHost(ctx context.Context, host string, port int) (*models.Camera, error) {
 	query := `
-		SELECT id, name, host, port, username, password, use_https, skip_verify,
+		SELECT id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
 			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
 			last_seen, created_at, updated_at
 		FROM cameras
@@ -91,7 +120,9 @@ func (r *CameraRepository) GetByHost(ctx context.Context, host string, port int)
 	camera := &models.Camera{}
 	err := r.db.QueryRowContext(ctx, query, host, port).Scan(
 		&camera.ID, &camera.Name, &camera.Host, &camera.Port, &camera.Username, &camera.Password,
-		&camera.UseHTTPS, &camera.SkipVerify, &camera.Status, &camera.Model, &camera.FirmwareVer,
+		&camera.UseHTTPS, &camera.SkipVerify, &camera.RTSPTransport, &camera.RebootTime, &camera.AlwaysReady, &camera.EventMode, &camera.ConnectionProfile,
+		&camera.SnapshotEnabled, &camera.SnapshotInterval, &camera.SnapshotChannel,
+		&camera.PatrolSchedule, &camera.PatrolID, &camera.PatrolChannel, &camera.Status, &camera.Model, &camera.FirmwareVer,
 		&camera.HardwareVer, &camera.Capabilities, &camera.Tags, &camera.GroupID,
 		&camera.LastSeen, &camera.CreatedAt, &camera.UpdatedAt)
 
@@ -105,10 +136,56 @@ func (r *CameraRepository) GetByHost(ctx context.Context, host string, port int)
 	return camera, nil
 }
 
+// NameExists reports whether a camera other than excludeID already uses the
+// given name. Names are compared case-insensitively. An empty excludeID, as for
+// cameras not yet created, checks against all cameras.
+func (r *CameraRepository) NameExists(ctx context.Context, name, excludeID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM cameras WHERE LOWER(name) = LOWER($1)`
+	args := []interface{}{name}
+
+	// id is a UUID column, so it cannot be compared with an empty string
+	if excludeID != "" {
+		query += ` AND id <> $2`
+		args = append(args, excludeID)
+	}
+	query += `)`
+
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, query, args...).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check camera name: %w", err)
+	}
+
+	return exists, nil
+}
+
+// EnforceUniqueNames creates the unique index on lower-cased camera names, or
+// drops it when disabled. The index closes the race between concurrent adds
+// that both pass NameExists. Creating it fails while duplicate names exist.
+func (r *CameraRepository) EnforceUniqueNames(ctx context.Context, enabled bool) error {
+	query := `DROP INDEX IF EXISTS ` + cameraNameIndex
+	if enabled {
+		query = `CREATE UNIQUE INDEX IF NOT EXISTS ` + cameraNameIndex + ` ON cameras (LOWER(name))`
+	}
+
+	if _, err := r.db.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to update camera name index: %w", err)
+	}
+
+	return nil
+}
+
+// isCameraNameViolation reports whether err violates the unique camera name index
+func isCameraNameViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == uniqueViolation && pqErr.Constraint == cameraNameIndex
+}
+
 // List retrieves all cameras
 func (r *CameraRepository) List(ctx context.Context) ([]*models.Camera, error) {
 	query := `
-		SELECT id, name, host, port, username, password, use_https, skip_verify,
+		SELECT id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
 			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
 			last_seen, created_at, updated_at
 		FROM cameras
@@ -126,7 +203,9 @@ func (r *CameraRepository) List(ctx context.Context) ([]*models.Camera, error) {
 		camera := &models.Camera{}
 		err := rows.Scan(
 			&camera.ID, &camera.Name, &camera.Host, &camera.Port, &camera.Username, &camera.Password,
-			&camera.UseHTTPS, &camera.SkipVerify, &camera.Status, &camera.Model, &camera.FirmwareVer,
+			&camera.UseHTTPS, &camera.SkipVerify, &camera.RTSPTransport, &camera.RebootTime, &camera.AlwaysReady, &camera.EventMode, &camera.ConnectionProfile,
+			&camera.SnapshotEnabled, &camera.SnapshotInterval, &camera.SnapshotChannel,
+			&camera.PatrolSchedule, &camera.PatrolID, &camera.PatrolChannel, &camera.Status, &camera.Model, &camera.FirmwareVer,
 			&camera.HardwareVer, &camera.Capabilities, &camera.Tags, &camera.GroupID,
 			&camera.LastSeen, &camera.CreatedAt, &camera.UpdatedAt)
 		if err != nil {
@@ -147,17 +226,24 @@ func (r *CameraRepository) Update(ctx context.Context, camera *models.Camera) er
 	query := `
 		UPDATE cameras
 		SET name = $2, host = $3, port = $4, username = $5, password = $6,
-			use_https = $7, skip_verify = $8, status = $9, model = $10,
-			firmware_version = $11, hardware_version = $12, capabilities = $13,
-			tags = $14, group_id = $15, last_seen = $16
+			use_https = $7, skip_verify = $8, rtsp_transport = $9, reboot_time = $10, always_ready = $11, event_mode = $12, connection_profile = $13,
+			snapshot_enabled = $14, snapshot_interval = $15, snapshot_channel = $16,
+			patrol_schedule = $17, patrol_id = $18, patrol_channel = $19,
+			status = $20, model = $21, firmware_version = $22, hardware_version = $23, capabilities = $24,
+			tags = $25, group_id = $26, last_seen = $27
 		WHERE id = $1
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
 		camera.ID, camera.Name, camera.Host, camera.Port, camera.Username, camera.Password,
-		camera.UseHTTPS, camera.SkipVerify, camera.Status, camera.Model, camera.FirmwareVer,
+		camera.UseHTTPS, camera.SkipVerify, camera.RTSPTransport, camera.RebootTime, camera.AlwaysReady, camera.EventMode, camera.ConnectionProfile,
+		camera.SnapshotEnabled, camera.SnapshotInterval, camera.SnapshotChannel,
+		camera.PatrolSchedule, camera.PatrolID, camera.PatrolChannel, camera.Status, camera.Model, camera.FirmwareVer,
 		camera.HardwareVer, camera.Capabilities, camera.Tags, camera.GroupID, camera.LastSeen)
 
+	if isCameraNameViolation(err) {
+		return fmt.Errorf("%w: %s", ErrCameraNameExists, camera.Name)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to update camera: %w", err)
 	}
@@ -168,7 +254,7 @@ func (r *CameraRepository) Update(ctx context.Context, camera *models.Camera) er
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("camera not found: %s", camera.ID)
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, camera.ID)
 	}
 
 	return nil
@@ -193,7 +279,32 @@ func (r *CameraRepository) UpdateStatus(ctx context.Context, id string, status s
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("camera not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, id)
+	}
+
+	return nil
+}
+
+// UpdateDeviceInfo updates the model and versions a camera reports about itself
+func (r *CameraRepository) UpdateDeviceInfo(ctx context.Context, id, model, firmwareVer, hardwareVer string) error {
+	query := `
+		UPDATE cameras
+		SET model = $2, firmware_version = $3, hardware_version = $4
+		WHERE id = $1
+	`
+
+	result, err := r.db.ExecContext(ctx, query, id, model, firmwareVer, hardwareVer)
+	if err != nil {
+		return fmt.Errorf("failed to update camera device info: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, id)
 	}
 
 	return nil
@@ -214,7 +325,7 @@ func (r *CameraRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("camera not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, id)
 	}
 
 	return nil
@@ -236,7 +347,9 @@ func (r *CameraRepository) Count(ctx context.Context) (int, error) {
 // ListByStatus retrieves cameras by status
 func (r *CameraRepository) ListByStatus(ctx context.Context, status string) ([]*models.Camera, error) {
 	query := `
-		SELECT id, name, host, port, username, password, use_https, skip_verify,
+		SELECT id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
 			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
 			last_seen, created_at, updated_at
 		FROM cameras
@@ -255,7 +368,51 @@ func (r *CameraRepository) ListByStatus(ctx context.Context, status string) ([]*
 		camera := &models.Camera{}
 		err := rows.Scan(
 			&camera.ID, &camera.Name, &camera.Host, &camera.Port, &camera.Username, &camera.Password,
-			&camera.UseHTTPS, &camera.SkipVerify, &camera.Status, &camera.Model, &camera.FirmwareVer,
+			&camera.UseHTTPS, &camera.SkipVerify, &camera.RTSPTransport, &camera.RebootTime, &camera.AlwaysReady, &camera.EventMode, &camera.ConnectionProfile,
+			&camera.SnapshotEnabled, &camera.SnapshotInterval, &camera.SnapshotChannel,
+			&camera.PatrolSchedule, &camera.PatrolID, &camera.PatrolChannel, &camera.Status, &camera.Model, &camera.FirmwareVer,
+			&camera.HardwareVer, &camera.Capabilities, &camera.Tags, &camera.GroupID,
+			&camera.LastSeen, &camera.CreatedAt, &camera.UpdatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan camera: %w", err)
+		}
+		cameras = append(cameras, camera)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating cameras: %w", err)
+	}
+
+	return cameras, nil
+}
+
+// ListByGroup retrieves the cameras of a camera group
+func (r *CameraRepository) ListByGroup(ctx context.Context, groupID string) ([]*models.Camera, error) {
+	query := `
+		SELECT id, name, host, port, username, password, use_https, skip_verify, rtsp_transport, reboot_time, always_ready, event_mode, connection_profile,
+			snapshot_enabled, snapshot_interval, snapshot_channel,
+			patrol_schedule, patrol_id, patrol_channel,
+			status, model, firmware_version, hardware_version, capabilities, tags, group_id,
+			last_seen, created_at, updated_at
+		FROM cameras
+		WHERE group_id = $1
+		ORDER BY name
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cameras by group: %w", err)
+	}
+	defer rows.Close()
+
+	cameras := []*models.Camera{}
+	for rows.Next() {
+		camera := &models.Camera{}
+		err := rows.Scan(
+			&camera.ID, &camera.Name, &camera.Host, &camera.Port, &camera.Username, &camera.Password,
+			&camera.UseHTTPS, &camera.SkipVerify, &camera.RTSPTransport, &camera.RebootTime, &camera.AlwaysReady, &camera.EventMode, &camera.ConnectionProfile,
+			&camera.SnapshotEnabled, &camera.SnapshotInterval, &camera.SnapshotChannel,
+			&camera.PatrolSchedule, &camera.PatrolID, &camera.PatrolChannel, &camera.Status, &camera.Model, &camera.FirmwareVer,
 			&camera.HardwareVer, &camera.Capabilities, &camera.Tags, &camera.GroupID,
 			&camera.LastSeen, &camera.CreatedAt, &camera.UpdatedAt)
 		if err != nil {
@@ -270,3 +427,104 @@ func (r *CameraRepository) ListByStatus(ctx context.Context, status string) ([]*
 
 	return cameras, nil
 }
+
+// AssignGroup moves a camera into a camera group, out of any group it was in
+func (r *CameraRepository) AssignGroup(ctx context.Context, cameraID, groupID string) error {
+	query := `UPDATE cameras SET group_id = $2 WHERE id = $1`
+
+	result, err := r.db.ExecContext(ctx, query, cameraID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to assign camera group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: %s", ErrCameraNotFound, cameraID)
+	}
+
+	return nil
+}
+
+// UnassignGroup removes a camera from a camera group. It returns
+// ErrNotGroupMember when the camera is not in that group.
+func (r *CameraRepository) UnassignGroup(ctx context.Context, cameraID, groupID string) error {
+	query := `UPDATE cameras SET group_id = NULL WHERE id = $1 AND group_id = $2`
+
+	result, err := r.db.ExecContext(ctx, query, cameraID, groupID)
+	if err != nil {
+		return fmt.Errorf("failed to unassign camera group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%w: camera %s, group %s", ErrNotGroupMember, cameraID, groupID)
+	}
+
+	return nil
+}
+
+// PurgeHistory deletes all events and recordings of a camera in one transaction.
+// The snapshot paths of the deleted events and the ID, storage path and
+// thumbnail URL of the deleted recordings are returned so their files can be removed.
+func (r *CameraRepository) PurgeHistory(ctx context.Context, cameraID string) (*models.CameraHistoryPurge, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	purge := &models.CameraHistoryPurge{CameraID: cameraID}
+
+	rows, err := tx.QueryContext(ctx, `DELETE FROM events WHERE camera_id = $1 RETURNING COALESCE(snapshot_path, '')`, cameraID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete events: %w", err)
+	}
+	for rows.Next() {
+		var snapshotPath string
+		if err := rows.Scan(&snapshotPath); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted event: %w", err)
+		}
+		purge.EventsDeleted++
+		if snapshotPath != "" {
+			purge.SnapshotPaths = append(purge.SnapshotPaths, snapshotPath)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete events: %w", err)
+	}
+
+	rows, err = tx.QueryContext(ctx, `DELETE FROM recordings WHERE camera_id = $1 RETURNING id, storage_path, thumbnail_url`, cameraID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete recordings: %w", err)
+	}
+	for rows.Next() {
+		var id string
+		var path, thumbnailURL sql.NullString
+		if err := rows.Scan(&id, &path, &thumbnailURL); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan deleted recording: %w", err)
+		}
+		purge.RecordingsDeleted++
+		purge.Recordings = append(purge.Recordings, &models.Recording{ID: id, StoragePath: path.String, ThumbnailURL: thumbnailURL.String})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete recordings: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return purge, nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func newCameraRepoWithMock(t *testing.T) (*CameraRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return NewCameraRepository(&db.DB{DB: sqlDB}), mock
+}
+
+func TestCameraRepository_PurgeHistory(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM events WHERE camera_id = \$1 RETURNING`).
+		WithArgs("cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot_path"}).
+			AddRow("/data/snapshots/1.jpg").
+			AddRow("").
+			AddRow("/data/snapshots/3.jpg"))
+	mock.ExpectQuery(`DELETE FROM recordings WHERE camera_id = \$1 RETURNING id, storage_path, thumbnail_url`).
+		WithArgs("cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage_path", "thumbnail_url"}).
+			AddRow("rec-1", "/data/clips/1.mp4", "/api/v1/recordings/rec-1/thumbnail").
+			AddRow("rec-2", nil, nil))
+	mock.ExpectCommit()
+
+	purge, err := repo.PurgeHistory(context.Background(), "cam-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, "cam-1", purge.CameraID)
+	assert.Equal(t, int64(3), purge.EventsDeleted)
+	assert.Equal(t, int64(2), purge.RecordingsDeleted)
+	assert.Equal(t, []string{"/data/snapshots/1.jpg", "/data/snapshots/3.jpg"}, purge.SnapshotPaths)
+	assert.Equal(t, []*models.Recording{
+		{ID: "rec-1", StoragePath: "/data/clips/1.mp4", ThumbnailURL: "/api/v1/recordings/rec-1/thumbnail"},
+		{ID: "rec-2"},
+	}, purge.Recordings)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_PurgeHistory_RollsBackOnError(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM events`).
+		WithArgs("cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"snapshot_path"}).AddRow(""))
+	mock.ExpectQuery(`DELETE FROM recordings`).WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	purge, err := repo.PurgeHistory(context.Background(), "cam-1")
+
+	assert.Nil(t, purge)
+	assert.ErrorContains(t, err, "failed to delete recordings")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_UpdateDeviceInfo(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectExec(`UPDATE cameras\s+SET model = \$2, firmware_version = \$3, hardware_version = \$4\s+WHERE id = \$1`).
+		WithArgs("cam-1", "RLC-810A", "v3.1.0", "IPC_523").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE cameras`).
+		WithArgs("missing", "RLC-810A", "v3.1.0", "IPC_523").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.UpdateDeviceInfo(context.Background(), "cam-1", "RLC-810A", "v3.1.0", "IPC_523"))
+	assert.ErrorContains(t, repo.UpdateDeviceInfo(context.Background(), "missing", "RLC-810A", "v3.1.0", "IPC_523"), "camera not found")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_ListByGroup(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+	now := time.Now()
+	groupID := "group-1"
+
+	columns := []string{"id", "name", "host", "port", "username", "password", "use_https", "skip_verify",
+		"rtsp_transport", "reboot_time", "always_ready", "event_mode", "connection_profile", "snapshot_enabled", "snapshot_interval", "snapshot_channel",
+		"patrol_schedule", "patrol_id", "patrol_channel", "status", "model", "firmware_version", "hardware_version",
+		"capabilities", "tags", "group_id", "last_seen", "created_at", "updated_at"}
+	mock.ExpectQuery(`FROM cameras\s+WHERE group_id = \$1\s+ORDER BY name`).
+		WithArgs(groupID).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("cam-1", "Front", "192.168.1.10", 80, "admin", "secret", false, false,
+				"tcp", "", false, "onvif", "cellular", false, 0, 0,
+				"", 0, 0, "online", "RLC-810A", "v3", "IPC",
+				[]byte(`{}`), "{}", groupID, now, now, now))
+
+	cameras, err := repo.ListByGroup(context.Background(), groupID)
+
+	require.NoError(t, err)
+	require.Len(t, cameras, 1)
+	assert.Equal(t, "cam-1", cameras[0].ID)
+	assert.Equal(t, models.EventModeONVIF, cameras[0].EventMode)
+	assert.Equal(t, "cellular", cameras[0].ConnectionProfile)
+	require.NotNil(t, cameras[0].GroupID)
+	assert.Equal(t, groupID, *cameras[0].GroupID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_AssignGroup(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectExec(`UPDATE cameras SET group_id = \$2 WHERE id = \$1`).
+		WithArgs("cam-1", "group-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.AssignGroup(context.Background(), "cam-1", "group-1"))
+
+	mock.ExpectExec(`UPDATE cameras SET group_id`).
+		WithArgs("missing", "group-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.AssignGroup(context.Background(), "missing", "group-1"), ErrCameraNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_UnassignGroup(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectExec(`UPDATE cameras SET group_id = NULL WHERE id = \$1 AND group_id = \$2`).
+		WithArgs("cam-1", "group-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, repo.UnassignGroup(context.Background(), "cam-1", "group-1"))
+
+	mock.ExpectExec(`UPDATE cameras SET group_id = NULL`).
+		WithArgs("cam-1", "group-2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, repo.UnassignGroup(context.Background(), "cam-1", "group-2"), ErrNotGroupMember)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_NameExists(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM cameras WHERE LOWER\(name\) = LOWER\(\$1\)\)`).
+		WithArgs("Front Door").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM cameras WHERE LOWER\(name\) = LOWER\(\$1\) AND id <> \$2\)`).
+		WithArgs("Front Door", "cam-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := repo.NameExists(context.Background(), "Front Door", "")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = repo.NameExists(context.Background(), "Front Door", "cam-1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCameraRepository_EnforceUniqueNames(t *testing.T) {
+	repo, mock := newCameraRepoWithMock(t)
+
+	mock.ExpectExec(`CREATE UNIQUE INDEX IF NOT EXISTS idx_cameras_name_lower ON cameras \(LOWER\(name\)\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`DROP INDEX IF EXISTS idx_cameras_name_lower`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	require.NoError(t, repo.EnforceUniqueNames(context.Background(), true))
+	require.NoError(t, repo.EnforceUniqueNames(context.Background(), false))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
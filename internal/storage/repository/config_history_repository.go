@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// ConfigHistoryRepository handles camera config history database operations
+type ConfigHistoryRepository struct {
+	db *db.DB
+}
+
+// NewConfigHistoryRepository creates a new config history repository
+func NewConfigHistoryRepository(database *db.DB) *ConfigHistoryRepository {
+	return &ConfigHistoryRepository{db: database}
+}
+
+// Create stores a new config snapshot, assigning it the next version for the camera and config type
+func (r *ConfigHistoryRepository) Create(ctx context.Context, entry *models.CameraConfigVersion) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	entry.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO camera_config_history (id, camera_id, config_type, version, config, created_at)
+		SELECT $1, $2, $3, COALESCE(MAX(version), 0) + 1, $4, $5
+		FROM camera_config_history
+		WHERE camera_id = $2 AND config_type = $3
+		RETURNING version
+	`
+
+	err := r.db.QueryRowContext(ctx, query,
+		entry.ID, entry.CameraID, entry.ConfigType, []byte(entry.Config), entry.CreatedAt,
+	).Scan(&entry.Version)
+
+	if err != nil {
+		return fmt.Errorf("failed to create config history entry: %w", err)
+	}
+
+	return nil
+}
+
+// GetVersion retrieves a specific config version for a camera and config type
+func (r *ConfigHistoryRepository) GetVersion(ctx context.Context, cameraID, configType string, version int) (*models.CameraConfigVersion, error) {
+	query := `
+		SELECT id, camera_id, config_type, version, config, created_at
+		FROM camera_config_history
+		WHERE camera_id = $1 AND config_type = $2 AND version = $3
+	`
+
+	entry := &models.CameraConfigVersion{}
+	err := r.db.QueryRowContext(ctx, query, cameraID, configType, version).Scan(
+		&entry.ID, &entry.CameraID, &entry.ConfigType, &entry.Version, &entry.Config, &entry.CreatedAt)
+
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("config version not found: %s/%s/%d", cameraID, configType, version)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get config version: %w", err)
+	}
+
+	return entry, nil
+}
+
+// ListByCameraAndType retrieves config history for a camera and config type, newest first
+func (r *ConfigHistoryRepository) ListByCameraAndType(ctx context.Context, cameraID, configType string, limit int, offset int) ([]*models.CameraConfigVersion, error) {
+	query := `
+		SELECT id, camera_id, config_type, version, config, created_at
+		FROM camera_config_history
+		WHERE camera_id = $1 AND config_type = $2
+		ORDER BY version DESC
+		LIMIT $3 OFFSET $4
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cameraID, configType, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list config history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []*models.CameraConfigVersion
+	for rows.Next() {
+		entry := &models.CameraConfigVersion{}
+		if err := rows.Scan(&entry.ID, &entry.CameraID, &entry.ConfigType, &entry.Version, &entry.Config, &entry.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan config history entry: %w", err)
+		}
+		history = append(history, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating config history: %w", err)
+	}
+
+	return history, nil
+}
@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func newConfigHistoryRepoWithMock(t *testing.T) (*ConfigHistoryRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return NewConfigHistoryRepository(&db.DB{DB: sqlDB}), mock
+}
+
+func TestConfigHistoryRepository_Create_AssignsNextVersion(t *testing.T) {
+	repo, mock := newConfigHistoryRepoWithMock(t)
+
+	config := json.RawMessage(`{"name":"Front Door"}`)
+	mock.ExpectQuery(`INSERT INTO camera_config_history .* COALESCE\(MAX\(version\), 0\) \+ 1`).
+		WithArgs(sqlmock.AnyArg(), "cam-1", "device_name", []byte(config), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+
+	entry := &models.CameraConfigVersion{CameraID: "cam-1", ConfigType: "device_name", Config: config}
+	err := repo.Create(context.Background(), entry)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, entry.Version)
+	assert.NotEmpty(t, entry.ID)
+	assert.False(t, entry.CreatedAt.IsZero())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigHistoryRepository_Create_Error(t *testing.T) {
+	repo, mock := newConfigHistoryRepoWithMock(t)
+
+	mock.ExpectQuery(`INSERT INTO camera_config_history`).WillReturnError(assert.AnError)
+
+	err := repo.Create(context.Background(), &models.CameraConfigVersion{CameraID: "cam-1", ConfigType: "time", Config: json.RawMessage(`{}`)})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to create config history entry")
+}
+
+func TestConfigHistoryRepository_GetVersion(t *testing.T) {
+	repo, mock := newConfigHistoryRepoWithMock(t)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM camera_config_history WHERE camera_id = \$1 AND config_type = \$2 AND version = \$3`).
+		WithArgs("cam-1", "device_name", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "config_type", "version", "config", "created_at"}).
+			AddRow("id-2", "cam-1", "device_name", 2, []byte(`{"name":"Garage"}`), createdAt))
+
+	entry, err := repo.GetVersion(context.Background(), "cam-1", "device_name", 2)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, entry.Version)
+	assert.JSONEq(t, `{"name":"Garage"}`, string(entry.Config))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigHistoryRepository_GetVersion_NotFound(t *testing.T) {
+	repo, mock := newConfigHistoryRepoWithMock(t)
+
+	mock.ExpectQuery(`SELECT .* FROM camera_config_history`).
+		WithArgs("cam-1", "device_name", 9).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "config_type", "version", "config", "created_at"}))
+
+	entry, err := repo.GetVersion(context.Background(), "cam-1", "device_name", 9)
+
+	assert.Nil(t, entry)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "config version not found")
+}
+
+func TestConfigHistoryRepository_ListByCameraAndType(t *testing.T) {
+	repo, mock := newConfigHistoryRepoWithMock(t)
+	createdAt := time.Now()
+
+	mock.ExpectQuery(`SELECT .* FROM camera_config_history .* ORDER BY version DESC LIMIT \$3 OFFSET \$4`).
+		WithArgs("cam-1", "device_name", 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "config_type", "version", "config", "created_at"}).
+			AddRow("id-2", "cam-1", "device_name", 2, []byte(`{"name":"Garage"}`), createdAt).
+			AddRow("id-1", "cam-1", "device_name", 1, []byte(`{"name":"Front Door"}`), createdAt))
+
+	history, err := repo.ListByCameraAndType(context.Background(), "cam-1", "device_name", 10, 0)
+
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, 2, history[0].Version)
+	assert.Equal(t, 1, history[1].Version)
+	assert.JSONEq(t, `{"name":"Front Door"}`, string(history[1].Config))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,14 +3,19 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/mosleyit/reolink_server/internal/storage/db"
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
+// ErrEventNotFound is returned for unknown events
+var ErrEventNotFound = errors.New("event not found")
+
 // EventRepository handles event database operations
 type EventRepository struct {
 	db *db.DB
@@ -51,6 +56,45 @@ func (r *EventRepository) Create(ctx context.Context, event *models.Event) error
 	return nil
 }
 
+// CreateIfNotExists inserts an event unless an event with the same ID and
+// timestamp is already stored. It reports whether the event was inserted and
+// leaves the event itself unchanged, so it is safe for events shared with
+// other subscribers. The event's CreatedAt, when the server received it, is
+// kept apart from its timestamp, which may come from the device.
+func (r *EventRepository) CreateIfNotExists(ctx context.Context, event *models.Event) (bool, error) {
+	metadata := event.Metadata
+	if metadata == "" {
+		metadata = "{}"
+	}
+
+	createdAt := event.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO events (id, camera_id, camera_name, type, severity, timestamp, acknowledged,
+			acknowledged_at, metadata, snapshot_path, video_clip_url, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		ON CONFLICT (id, timestamp) DO NOTHING
+	`
+
+	result, err := r.db.ExecContext(ctx, query,
+		event.ID, event.CameraID, event.CameraName, event.Type, event.Severity, event.Timestamp,
+		event.Acknowledged, event.AcknowledgedAt, metadata, event.SnapshotPath,
+		event.VideoClipURL, createdAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected > 0, nil
+}
+
 // GetByID retrieves an event by ID
 func (r *EventRepository) GetByID(ctx context.Context, id string) (*models.Event, error) {
 	query := `
@@ -67,7 +111,7 @@ func (r *EventRepository) GetByID(ctx context.Context, id string) (*models.Event
 		&event.VideoClipURL, &event.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("event not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrEventNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get event: %w", err)
@@ -175,6 +219,134 @@ func (r *EventRepository) List(ctx context.Context, limit int, offset int) ([]*m
 	return r.scanEvents(rows)
 }
 
+// ListBySeverity retrieves events with one of the given severities with pagination
+func (r *EventRepository) ListBySeverity(ctx context.Context, severities []models.EventSeverity, limit int, offset int) ([]*models.Event, error) {
+	query := `
+		SELECT id, camera_id, camera_name, type, severity, timestamp, acknowledged, acknowledged_at,
+			metadata, snapshot_path, video_clip_url, created_at
+		FROM events
+		WHERE severity = ANY($1)
+		ORDER BY timestamp DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, severityArray(severities), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events by severity: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanEvents(rows)
+}
+
+// ListForActivity retrieves events within a time range, optionally for a single
+// camera, ordered by camera, type and time so recurring events are adjacent
+func (r *EventRepository) ListForActivity(ctx context.Context, cameraID string, startTime, endTime time.Time, limit int) ([]*models.Event, error) {
+	query := `
+		SELECT id, camera_id, camera_name, type, severity, timestamp, acknowledged, acknowledged_at,
+			metadata, snapshot_path, video_clip_url, created_at
+		FROM events
+		WHERE timestamp >= $1 AND timestamp <= $2`
+	args := []interface{}{startTime, endTime}
+
+	// camera_id is a UUID column, so an empty filter cannot be compared to it
+	if cameraID != "" {
+		args = append(args, cameraID)
+		query += fmt.Sprintf(" AND camera_id = $%d", len(args))
+	}
+
+	args = append(args, limit)
+	query += fmt.Sprintf(" ORDER BY camera_id, type, timestamp LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list events for activity: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanEvents(rows)
+}
+
+// Search searches events with flexible filters
+func (r *EventRepository) Search(ctx context.Context, req *models.EventSearchRequest) ([]*models.Event, error) {
+	where, args := eventSearchFilter(req)
+	query := `
+		SELECT id, camera_id, camera_name, type, severity, timestamp, acknowledged, acknowledged_at,
+			metadata, snapshot_path, video_clip_url, created_at
+		FROM events
+		WHERE 1=1` + where
+
+	query += " ORDER BY " + req.Sort.OrderBy(models.EventSortFields, models.DefaultEventSort)
+
+	argCount := len(args) + 1
+	if req.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, req.Limit)
+		argCount++
+	}
+
+	if req.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, req.Offset)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search events: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanEvents(rows)
+}
+
+// CountSearch returns the number of events matching the filters of a search,
+// ignoring its limit and offset
+func (r *EventRepository) CountSearch(ctx context.Context, req *models.EventSearchRequest) (int, error) {
+	where, args := eventSearchFilter(req)
+	query := `SELECT COUNT(*) FROM events WHERE 1=1` + where
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	return count, nil
+}
+
+// eventSearchFilter builds the conditions of an event search, to follow
+// WHERE 1=1, numbering its arguments from $1
+func eventSearchFilter(req *models.EventSearchRequest) (string, []interface{}) {
+	where := ""
+	args := []interface{}{}
+	argCount := 1
+
+	if req.CameraID != nil {
+		where += fmt.Sprintf(" AND camera_id = $%d", argCount)
+		args = append(args, *req.CameraID)
+		argCount++
+	}
+
+	if req.Type != nil {
+		where += fmt.Sprintf(" AND type = $%d", argCount)
+		args = append(args, *req.Type)
+		argCount++
+	}
+
+	if len(req.Severities) > 0 {
+		where += fmt.Sprintf(" AND severity = ANY($%d)", argCount)
+		args = append(args, severityArray(req.Severities))
+		argCount++
+	}
+
+	if req.Acknowledged != nil {
+		where += fmt.Sprintf(" AND acknowledged = $%d", argCount)
+		args = append(args, *req.Acknowledged)
+	}
+
+	return where, args
+}
+
 // Acknowledge marks an event as acknowledged
 func (r *EventRepository) Acknowledge(ctx context.Context, id string) error {
 	query := `
@@ -194,12 +366,34 @@ func (r *EventRepository) Acknowledge(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("event not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrEventNotFound, id)
 	}
 
 	return nil
 }
 
+// AcknowledgeByCamera marks all unacknowledged events of a camera as
+// acknowledged and returns how many events were updated
+func (r *EventRepository) AcknowledgeByCamera(ctx context.Context, cameraID string) (int64, error) {
+	query := `
+		UPDATE events
+		SET acknowledged = TRUE, acknowledged_at = NOW()
+		WHERE camera_id = $1 AND acknowledged = FALSE
+	`
+
+	result, err := r.db.ExecContext(ctx, query, cameraID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to acknowledge camera events: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rowsAffected, nil
+}
+
 // Delete deletes an event
 func (r *EventRepository) Delete(ctx context.Context, id string) error {
 	query := `DELETE FROM events WHERE id = $1`
@@ -215,7 +409,7 @@ func (r *EventRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("event not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrEventNotFound, id)
 	}
 
 	return nil
@@ -238,6 +432,19 @@ func (r *EventRepository) DeleteOlderThan(ctx context.Context, olderThan time.Ti
 	return rowsAffected, nil
 }
 
+// CountOlderThan returns the number of events DeleteOlderThan would delete
+func (r *EventRepository) CountOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	query := `SELECT COUNT(*) FROM events WHERE timestamp < $1`
+
+	var count int64
+	err := r.db.QueryRowContext(ctx, query, olderThan).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count old events: %w", err)
+	}
+
+	return count, nil
+}
+
 // Count returns the total number of events
 func (r *EventRepository) Count(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM events`
@@ -251,6 +458,28 @@ func (r *EventRepository) Count(ctx context.Context) (int, error) {
 	return count, nil
 }
 
+// CountBySeverity returns the number of events with one of the given severities
+func (r *EventRepository) CountBySeverity(ctx context.Context, severities []models.EventSeverity) (int, error) {
+	query := `SELECT COUNT(*) FROM events WHERE severity = ANY($1)`
+
+	var count int
+	err := r.db.QueryRowContext(ctx, query, severityArray(severities)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count events: %w", err)
+	}
+
+	return count, nil
+}
+
+// severityArray converts severities to a Postgres text array parameter
+func severityArray(severities []models.EventSeverity) pq.StringArray {
+	values := make(pq.StringArray, len(severities))
+	for i, severity := range severities {
+		values[i] = string(severity)
+	}
+	return values
+}
+
 // CountByCameraID returns the number of events for a specific camera
 func (r *EventRepository) CountByCameraID(ctx context.Context, cameraID string) (int, error) {
 	query := `SELECT COUNT(*) FROM events WHERE camera_id = $1`
@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func newEventRepoWithMock(t *testing.T) (*EventRepository, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	return NewEventRepository(&db.DB{DB: sqlDB}), mock
+}
+
+func TestEventRepository_ListBySeverity_ExcludesBelowThreshold(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	now := time.Now()
+
+	columns := []string{"id", "camera_id", "camera_name", "type", "severity", "timestamp", "acknowledged",
+		"acknowledged_at", "metadata", "snapshot_path", "video_clip_url", "created_at"}
+	mock.ExpectQuery(`SELECT .* FROM events\s+WHERE severity = ANY\(\$1\)`).
+		WithArgs(pq.StringArray{"warning", "critical"}, 50, 0).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("evt-1", "cam-1", "Door", "camera_offline", "warning", now, false, nil, "", "", "", now))
+
+	events, err := repo.ListBySeverity(context.Background(), models.SeveritiesAtLeast(models.SeverityWarning), 50, 0)
+
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.SeverityWarning, events[0].Severity)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_CountBySeverity(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM events WHERE severity = ANY\(\$1\)`).
+		WithArgs(pq.StringArray{"critical"}).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(7))
+
+	count, err := repo.CountBySeverity(context.Background(), models.SeveritiesAtLeast(models.SeverityCritical))
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_ListForActivity(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	columns := []string{"id", "camera_id", "camera_name", "type", "severity", "timestamp", "acknowledged",
+		"acknowledged_at", "metadata", "snapshot_path", "video_clip_url", "created_at"}
+	mock.ExpectQuery(`SELECT .* FROM events\s+WHERE timestamp >= \$1 AND timestamp <= \$2 AND camera_id = \$3 ORDER BY camera_id, type, timestamp LIMIT \$4`).
+		WithArgs(start, end, "cam-1", 100).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("evt-1", "cam-1", "Door", "motion_detected", "info", start, false, nil, "", "", "", start).
+			AddRow("evt-2", "cam-1", "Door", "motion_detected", "info", start.Add(time.Minute), false, nil, "", "", "", start))
+
+	events, err := repo.ListForActivity(context.Background(), "cam-1", start, end, 100)
+
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, "evt-2", events[1].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_ListForActivity_AllCameras(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	start := time.Date(2025, 10, 27, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+
+	// No camera filter means no comparison against the UUID column at all
+	mock.ExpectQuery(`SELECT .* FROM events\s+WHERE timestamp >= \$1 AND timestamp <= \$2 ORDER BY camera_id, type, timestamp LIMIT \$3$`).
+		WithArgs(start, end, 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	events, err := repo.ListForActivity(context.Background(), "", start, end, 100)
+
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_CountOlderThan(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	cutoff := time.Now().AddDate(0, 0, -90)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM events WHERE timestamp < \$1`).
+		WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1200))
+
+	count, err := repo.CountOlderThan(context.Background(), cutoff)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(1200), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_AcknowledgeByCamera(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+
+	mock.ExpectExec(`UPDATE events\s+SET acknowledged = TRUE, acknowledged_at = NOW\(\)\s+WHERE camera_id = \$1 AND acknowledged = FALSE`).
+		WithArgs("cam-1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	count, err := repo.AcknowledgeByCamera(context.Background(), "cam-1")
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_CreateIfNotExists(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	event := &models.Event{ID: "evt-1", CameraID: "cam-1", Type: models.EventMotionDetected, Severity: models.SeverityInfo, Timestamp: time.Now()}
+
+	mock.ExpectExec(`INSERT INTO events .* ON CONFLICT \(id, timestamp\) DO NOTHING`).
+		WithArgs("evt-1", "cam-1", "", models.EventMotionDetected, models.SeverityInfo, event.Timestamp,
+			false, nil, "{}", "", "", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO events`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	inserted, err := repo.CreateIfNotExists(context.Background(), event)
+	require.NoError(t, err)
+	assert.True(t, inserted)
+
+	inserted, err = repo.CreateIfNotExists(context.Background(), event)
+	require.NoError(t, err)
+	assert.False(t, inserted, "duplicate events are skipped")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_CreateIfNotExists_KeepsReceiveTime(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	received := time.Date(2025, 10, 27, 10, 0, 5, 0, time.UTC)
+	event := &models.Event{ID: "evt-1", CameraID: "cam-1", Type: models.EventMotionDetected, Severity: models.SeverityInfo,
+		Timestamp: received.Add(-3 * time.Second), CreatedAt: received}
+
+	mock.ExpectExec(`INSERT INTO events .* ON CONFLICT \(id, timestamp\) DO NOTHING`).
+		WithArgs("evt-1", "cam-1", "", models.EventMotionDetected, models.SeverityInfo, received.Add(-3*time.Second),
+			false, nil, "{}", "", "", received).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	_, err := repo.CreateIfNotExists(context.Background(), event)
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEventRepository_Search(t *testing.T) {
+	columns := []string{"id", "camera_id", "camera_name", "type", "severity", "timestamp", "acknowledged",
+		"acknowledged_at", "metadata", "snapshot_path", "video_clip_url", "created_at"}
+	cameraID := "cam-1"
+	eventType := models.EventAIPerson
+	acknowledged := true
+
+	tests := []struct {
+		name  string
+		req   models.EventSearchRequest
+		query string
+		args  []driver.Value
+	}{
+		{
+			name:  "no filters",
+			req:   models.EventSearchRequest{},
+			query: `FROM events\s+WHERE 1=1 ORDER BY timestamp DESC, id DESC$`,
+		},
+		{
+			name:  "camera with pagination",
+			req:   models.EventSearchRequest{CameraID: &cameraID, Limit: 50, Offset: 100},
+			query: `WHERE 1=1 AND camera_id = \$1 ORDER BY timestamp DESC, id DESC LIMIT \$2 OFFSET \$3$`,
+			args:  []driver.Value{"cam-1", 50, 100},
+		},
+		{
+			name:  "type and severities",
+			req:   models.EventSearchRequest{Type: &eventType, Severities: models.SeveritiesAtLeast(models.SeverityWarning)},
+			query: `WHERE 1=1 AND type = \$1 AND severity = ANY\(\$2\) ORDER BY timestamp DESC, id DESC$`,
+			args:  []driver.Value{"ai_person", pq.StringArray{"warning", "critical"}},
+		},
+		{
+			name:  "acknowledged oldest first",
+			req:   models.EventSearchRequest{Acknowledged: &acknowledged, Sort: models.SortOrder{Field: "timestamp"}, Limit: 10},
+			query: `WHERE 1=1 AND acknowledged = \$1 ORDER BY timestamp ASC, id ASC LIMIT \$2$`,
+			args:  []driver.Value{true, 10},
+		},
+		{
+			name:  "by severity",
+			req:   models.EventSearchRequest{Sort: models.SortOrder{Field: "severity", Descending: true}},
+			query: `WHERE 1=1 ORDER BY CASE severity WHEN 'critical' THEN 2 WHEN 'warning' THEN 1 ELSE 0 END DESC, id DESC$`,
+			args:  []driver.Value{},
+		},
+		{
+			name: "all filters",
+			req: models.EventSearchRequest{
+				CameraID:     &cameraID,
+				Type:         &eventType,
+				Severities:   []models.EventSeverity{models.SeverityCritical},
+				Acknowledged: &acknowledged,
+				Limit:        5,
+				Offset:       15,
+			},
+			query: `WHERE 1=1 AND camera_id = \$1 AND type = \$2 AND severity = ANY\(\$3\) AND acknowledged = \$4 ` +
+				`ORDER BY timestamp DESC, id DESC LIMIT \$5 OFFSET \$6$`,
+			args: []driver.Value{"cam-1", "ai_person", pq.StringArray{"critical"}, true, 5, 15},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mock := newEventRepoWithMock(t)
+			now := time.Now()
+
+			mock.ExpectQuery(`SELECT .* ` + tt.query).
+				WithArgs(tt.args...).
+				WillReturnRows(sqlmock.NewRows(columns).
+					AddRow("evt-1", "cam-1", "Door", "ai_person", "critical", now, true, now, "", "", "", now))
+
+			events, err := repo.Search(context.Background(), &tt.req)
+
+			require.NoError(t, err)
+			require.Len(t, events, 1)
+			assert.Equal(t, "evt-1", events[0].ID)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestEventRepository_CountSearch(t *testing.T) {
+	repo, mock := newEventRepoWithMock(t)
+	cameraID := "cam-1"
+	acknowledged := false
+
+	// Pagination and ordering do not apply to the count
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM events WHERE 1=1 AND camera_id = \$1 AND acknowledged = \$2$`).
+		WithArgs("cam-1", false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	count, err := repo.CountSearch(context.Background(), &models.EventSearchRequest{
+		CameraID:     &cameraID,
+		Acknowledged: &acknowledged,
+		Sort:         models.SortOrder{Field: "timestamp"},
+		Limit:        10,
+		Offset:       20,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
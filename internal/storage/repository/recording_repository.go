@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 	"github.com/mosleyit/reolink_server/internal/storage/models"
 )
 
+// ErrRecordingNotFound is returned for unknown recordings
+var ErrRecordingNotFound = errors.New("recording not found")
+
 // RecordingRepository handles recording database operations
 type RecordingRepository struct {
 	db *db.DB
@@ -64,7 +68,7 @@ func (r *RecordingRepository) GetByID(ctx context.Context, id string) (*models.R
 		&recording.RecordingType, &recording.StoragePath, &recording.ThumbnailURL, &recording.CreatedAt)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("recording not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrRecordingNotFound, id)
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recording: %w", err)
@@ -113,6 +117,26 @@ func (r *RecordingRepository) ListByTimeRange(ctx context.Context, cameraID stri
 	return r.scanRecordings(rows)
 }
 
+// ListOverlapping retrieves the recordings of a camera overlapping a time
+// range, oldest first
+func (r *RecordingRepository) ListOverlapping(ctx context.Context, cameraID string, startTime, endTime time.Time) ([]*models.Recording, error) {
+	query := `
+		SELECT id, camera_id, file_name, file_size, start_time, end_time, duration,
+			stream_type, recording_type, storage_path, thumbnail_url, created_at
+		FROM recordings
+		WHERE camera_id = $1 AND start_time < $3 AND end_time > $2
+		ORDER BY start_time ASC
+	`
+
+	rows, err := r.db.QueryContext(ctx, query, cameraID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list overlapping recordings: %w", err)
+	}
+	defer rows.Close()
+
+	return r.scanRecordings(rows)
+}
+
 // Search searches recordings with flexible filters
 func (r *RecordingRepository) Search(ctx context.Context, req *models.RecordingSearchRequest) ([]*models.Recording, error) {
 	query := `
@@ -155,7 +179,7 @@ func (r *RecordingRepository) Search(ctx context.Context, req *models.RecordingS
 		argCount++
 	}
 
-	query += " ORDER BY start_time DESC"
+	query += " ORDER BY " + req.Sort.OrderBy(models.RecordingSortFields, models.DefaultRecordingSort)
 
 	if req.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT $%d", argCount)
@@ -193,7 +217,7 @@ func (r *RecordingRepository) Delete(ctx context.Context, id string) error {
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("recording not found: %s", id)
+		return fmt.Errorf("%w: %s", ErrRecordingNotFound, id)
 	}
 
 	return nil
@@ -216,6 +240,49 @@ func (r *RecordingRepository) DeleteOlderThan(ctx context.Context, olderThan tim
 	return rowsAffected, nil
 }
 
+// DeleteOlderThanWithFiles deletes recordings older than the specified time
+// and returns the deleted recordings with the ID, storage path and thumbnail
+// URL needed to remove their files
+func (r *RecordingRepository) DeleteOlderThanWithFiles(ctx context.Context, olderThan time.Time) ([]*models.Recording, error) {
+	query := `DELETE FROM recordings WHERE end_time < $1 RETURNING id, storage_path, thumbnail_url`
+
+	rows, err := r.db.QueryContext(ctx, query, olderThan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete old recordings: %w", err)
+	}
+	defer rows.Close()
+
+	var recordings []*models.Recording
+	for rows.Next() {
+		var id string
+		var path, thumbnailURL sql.NullString
+		if err := rows.Scan(&id, &path, &thumbnailURL); err != nil {
+			return nil, fmt.Errorf("failed to scan recording files: %w", err)
+		}
+		recordings = append(recordings, &models.Recording{ID: id, StoragePath: path.String, ThumbnailURL: thumbnailURL.String})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to delete old recordings: %w", err)
+	}
+
+	return recordings, nil
+}
+
+// StatsOlderThan returns the number and total size in bytes of the recordings
+// DeleteOlderThan would delete
+func (r *RecordingRepository) StatsOlderThan(ctx context.Context, olderThan time.Time) (int64, int64, error) {
+	query := `SELECT COUNT(*), COALESCE(SUM(file_size), 0) FROM recordings WHERE end_time < $1`
+
+	var count, totalSize int64
+	err := r.db.QueryRowContext(ctx, query, olderThan).Scan(&count, &totalSize)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to get old recording stats: %w", err)
+	}
+
+	return count, totalSize, nil
+}
+
 // Count returns the total number of recordings
 func (r *RecordingRepository) Count(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM recordings`
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+func TestRecordingRepository_StatsOlderThan(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	repo := NewRecordingRepository(&db.DB{DB: sqlDB})
+	cutoff := time.Now().AddDate(0, 0, -90)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\), COALESCE\(SUM\(file_size\), 0\) FROM recordings WHERE end_time < \$1`).
+		WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "sum"}).AddRow(14, 7340032))
+
+	count, size, err := repo.StatsOlderThan(context.Background(), cutoff)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(14), count)
+	assert.Equal(t, int64(7340032), size)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordingRepository_DeleteOlderThanWithFiles(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	repo := NewRecordingRepository(&db.DB{DB: sqlDB})
+	cutoff := time.Now().AddDate(0, 0, -30)
+
+	mock.ExpectQuery(`DELETE FROM recordings WHERE end_time < \$1 RETURNING id, storage_path, thumbnail_url`).
+		WithArgs(cutoff).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "storage_path", "thumbnail_url"}).
+			AddRow("clip-1", "/data/clips/clip-1.mp4", "/api/v1/recordings/clip-1/thumbnail").
+			AddRow("rec-2", nil, nil))
+
+	recordings, err := repo.DeleteOlderThanWithFiles(context.Background(), cutoff)
+
+	require.NoError(t, err)
+	require.Len(t, recordings, 2)
+	assert.Equal(t, &models.Recording{ID: "clip-1", StoragePath: "/data/clips/clip-1.mp4", ThumbnailURL: "/api/v1/recordings/clip-1/thumbnail"}, recordings[0])
+	assert.Equal(t, &models.Recording{ID: "rec-2"}, recordings[1])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordingRepository_ListOverlapping(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	repo := NewRecordingRepository(&db.DB{DB: sqlDB})
+	start := time.Date(2025, 10, 27, 12, 0, 30, 0, time.UTC)
+	end := start.Add(time.Minute)
+
+	mock.ExpectQuery(`FROM recordings\s+WHERE camera_id = \$1 AND start_time < \$3 AND end_time > \$2\s+ORDER BY start_time ASC`).
+		WithArgs("cam-123", start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "camera_id", "file_name", "file_size", "start_time", "end_time", "duration",
+			"stream_type", "recording_type", "storage_path", "thumbnail_url", "created_at"}).
+			AddRow("rec-1", "cam-123", "a.mp4", 1024, start.Add(-30*time.Second), start.Add(30*time.Second), 60,
+				"main", "timing", "Mp4Record/a.mp4", "", start))
+
+	recordings, err := repo.ListOverlapping(context.Background(), "cam-123", start, end)
+
+	require.NoError(t, err)
+	require.Len(t, recordings, 1)
+	assert.Equal(t, "rec-1", recordings[0].ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordingRepository_Search_Sort(t *testing.T) {
+	cameraID := "cam-123"
+
+	tests := []struct {
+		name  string
+		sort  models.SortOrder
+		order string
+	}{
+		{"default", models.SortOrder{}, "start_time DESC, id DESC"},
+		{"by size", models.SortOrder{Field: "file_size"}, "file_size ASC, id ASC"},
+		{"by duration descending", models.SortOrder{Field: "duration", Descending: true}, "duration DESC, id DESC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer sqlDB.Close()
+			repo := NewRecordingRepository(&db.DB{DB: sqlDB})
+
+			mock.ExpectQuery(`AND camera_id = \$1 ORDER BY `+tt.order+` LIMIT \$2$`).
+				WithArgs("cam-123", 10).
+				WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+			_, err = repo.Search(context.Background(), &models.RecordingSearchRequest{CameraID: &cameraID, Sort: tt.sort, Limit: 10})
+			require.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
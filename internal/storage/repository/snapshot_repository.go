@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mosleyit/reolink_server/internal/storage/db"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// SnapshotRepository handles scheduled snapshot database operations
+type SnapshotRepository struct {
+	db *db.DB
+}
+
+// NewSnapshotRepository creates a new snapshot repository
+func NewSnapshotRepository(database *db.DB) *SnapshotRepository {
+	return &SnapshotRepository{db: database}
+}
+
+// Create records a captured snapshot
+func (r *SnapshotRepository) Create(ctx context.Context, snapshot *models.Snapshot) error {
+	if snapshot.ID == "" {
+		snapshot.ID = uuid.New().String()
+	}
+
+	snapshot.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO snapshots (id, camera_id, channel, file_path, file_size, captured_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err := r.db.ExecContext(ctx, query,
+		snapshot.ID, snapshot.CameraID, snapshot.Channel, snapshot.FilePath, snapshot.FileSize,
+		snapshot.CapturedAt, snapshot.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	return nil
+}
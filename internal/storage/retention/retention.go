@@ -0,0 +1,204 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// EventStore is the event storage used by the cleaner
+type EventStore interface {
+	CountOlderThan(ctx context.Context, olderThan time.Time) (int64, error)
+	DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error)
+}
+
+// RecordingStore is the recording storage used by the cleaner
+type RecordingStore interface {
+	StatsOlderThan(ctx context.Context, olderThan time.Time) (int64, int64, error)
+	DeleteOlderThanWithFiles(ctx context.Context, olderThan time.Time) ([]*models.Recording, error)
+}
+
+// Policy holds the cutoffs of a retention run. A zero cutoff skips that kind of data.
+type Policy struct {
+	EventsBefore     time.Time
+	RecordingsBefore time.Time
+}
+
+// PolicyForDays returns a policy removing data older than the given number of days
+func PolicyForDays(now time.Time, eventDays, recordingDays int) Policy {
+	var policy Policy
+	if eventDays > 0 {
+		policy.EventsBefore = now.AddDate(0, 0, -eventDays)
+	}
+	if recordingDays > 0 {
+		policy.RecordingsBefore = now.AddDate(0, 0, -recordingDays)
+	}
+	return policy
+}
+
+// Report summarizes what a retention run deleted, or would delete in dry-run mode
+type Report struct {
+	DryRun           bool       `json:"dry_run"`
+	EventsBefore     *time.Time `json:"events_before,omitempty"`
+	RecordingsBefore *time.Time `json:"recordings_before,omitempty"`
+	Events           int64      `json:"events"`
+	Recordings       int64      `json:"recordings"`
+	RecordingBytes   int64      `json:"recording_bytes"`
+	FilesDeleted     int        `json:"files_deleted"`
+}
+
+// Cleaner removes events and recordings past their retention period
+type Cleaner struct {
+	events     EventStore
+	recordings RecordingStore
+
+	// ownedDirs holds the directories the server writes recording files
+	// to; files elsewhere, e.g. on the camera, are left alone
+	ownedDirs []string
+
+	// thumbnailDir holds recording thumbnails, named <recording id>.jpg
+	thumbnailDir string
+}
+
+// NewCleaner creates a new retention cleaner
+func NewCleaner(events EventStore, recordings RecordingStore) *Cleaner {
+	return &Cleaner{
+		events:     events,
+		recordings: recordings,
+	}
+}
+
+// SetOwnedDirs sets the directories the server writes recording files to,
+// such as exported clips. Only files inside them are removed with their
+// recordings; without any, no files are removed. Empty entries are ignored.
+func (c *Cleaner) SetOwnedDirs(dirs ...string) {
+	c.ownedDirs = c.ownedDirs[:0]
+	for _, dir := range dirs {
+		if dir != "" {
+			c.ownedDirs = append(c.ownedDirs, filepath.Clean(dir))
+		}
+	}
+}
+
+// SetThumbnailDir sets the directory recording thumbnails are written to, so
+// they are removed with their recordings
+func (c *Cleaner) SetThumbnailDir(dir string) {
+	c.thumbnailDir = dir
+}
+
+// Run applies a retention policy. In dry-run mode it only reports what would
+// be deleted and leaves all data in place.
+func (c *Cleaner) Run(ctx context.Context, policy Policy, dryRun bool) (*Report, error) {
+	report := &Report{DryRun: dryRun}
+
+	if !policy.EventsBefore.IsZero() {
+		cutoff := policy.EventsBefore
+		report.EventsBefore = &cutoff
+
+		count, err := c.events.CountOlderThan(ctx, cutoff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count expired events: %w", err)
+		}
+		report.Events = count
+
+		if !dryRun && count > 0 {
+			deleted, err := c.events.DeleteOlderThan(ctx, cutoff)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expired events: %w", err)
+			}
+			report.Events = deleted
+		}
+	}
+
+	if !policy.RecordingsBefore.IsZero() {
+		cutoff := policy.RecordingsBefore
+		report.RecordingsBefore = &cutoff
+
+		count, size, err := c.recordings.StatsOlderThan(ctx, cutoff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count expired recordings: %w", err)
+		}
+		report.Recordings = count
+		report.RecordingBytes = size
+
+		if !dryRun && count > 0 {
+			deleted, err := c.recordings.DeleteOlderThanWithFiles(ctx, cutoff)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete expired recordings: %w", err)
+			}
+			report.Recordings = int64(len(deleted))
+			report.FilesDeleted = c.removeFiles(deleted)
+		}
+	}
+
+	logger.Info("Retention run completed",
+		zap.Bool("dry_run", dryRun),
+		zap.Int64("events", report.Events),
+		zap.Int64("recordings", report.Recordings),
+		zap.Int64("recording_bytes", report.RecordingBytes),
+		zap.Int("files_deleted", report.FilesDeleted))
+
+	return report, nil
+}
+
+// RemoveFiles removes the files and thumbnails of recordings and the event
+// snapshots deleted outside a retention run, e.g. when a camera's history is
+// purged, and returns how many were removed. Like in a retention run, files
+// outside the owned directories and files already gone are skipped.
+func (c *Cleaner) RemoveFiles(recordings []*models.Recording, snapshotPaths []string) int {
+	removed := c.removeFiles(recordings)
+	for _, path := range snapshotPaths {
+		if c.owns(path) && removeFile(path) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeFiles removes the files and thumbnails of deleted recordings and
+// returns how many were removed. Recordings without a file, files outside the
+// owned directories and files already gone are skipped.
+func (c *Cleaner) removeFiles(recordings []*models.Recording) int {
+	removed := 0
+	for _, recording := range recordings {
+		if recording.StoragePath != "" && c.owns(recording.StoragePath) && removeFile(recording.StoragePath) {
+			removed++
+		}
+		if c.thumbnailDir != "" && recording.ThumbnailURL != "" && removeFile(filepath.Join(c.thumbnailDir, recording.ID+".jpg")) {
+			removed++
+		}
+	}
+	return removed
+}
+
+// removeFile removes the file of a deleted recording or event, reporting whether it was removed
+func removeFile(path string) bool {
+	if err := os.Remove(path); err != nil {
+		if !os.IsNotExist(err) {
+			logger.Warn("Failed to remove deleted file",
+				zap.String("path", path),
+				zap.Error(err))
+		}
+		return false
+	}
+	return true
+}
+
+// owns reports whether path lies inside one of the owned directories
+func (c *Cleaner) owns(path string) bool {
+	for _, dir := range c.ownedDirs {
+		rel, err := filepath.Rel(dir, filepath.Clean(path))
+		if err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,289 @@
+package retention
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/mosleyit/reolink_server/internal/storage/models"
+)
+
+// fakeEventStore is an in-memory EventStore holding event timestamps
+type fakeEventStore struct {
+	timestamps []time.Time
+	deletes    int
+}
+
+func (f *fakeEventStore) CountOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	var count int64
+	for _, ts := range f.timestamps {
+		if ts.Before(olderThan) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeEventStore) DeleteOlderThan(ctx context.Context, olderThan time.Time) (int64, error) {
+	f.deletes++
+	kept := f.timestamps[:0]
+	var deleted int64
+	for _, ts := range f.timestamps {
+		if ts.Before(olderThan) {
+			deleted++
+			continue
+		}
+		kept = append(kept, ts)
+	}
+	f.timestamps = kept
+	return deleted, nil
+}
+
+// fakeRecordingStore is an in-memory RecordingStore holding end times, sizes,
+// file paths and which recordings have a thumbnail
+type fakeRecordingStore struct {
+	endTimes   []time.Time
+	sizes      []int64
+	paths      []string
+	thumbnails []bool
+	deletes    int
+}
+
+func (f *fakeRecordingStore) StatsOlderThan(ctx context.Context, olderThan time.Time) (int64, int64, error) {
+	var count, size int64
+	for i, ts := range f.endTimes {
+		if ts.Before(olderThan) {
+			count++
+			size += f.sizes[i]
+		}
+	}
+	return count, size, nil
+}
+
+func (f *fakeRecordingStore) DeleteOlderThanWithFiles(ctx context.Context, olderThan time.Time) ([]*models.Recording, error) {
+	f.deletes++
+	var deleted []*models.Recording
+	for i, ts := range f.endTimes {
+		if ts.Before(olderThan) {
+			recording := &models.Recording{ID: fmt.Sprintf("rec-%d", i)}
+			if i < len(f.paths) {
+				recording.StoragePath = f.paths[i]
+			}
+			if i < len(f.thumbnails) && f.thumbnails[i] {
+				recording.ThumbnailURL = "/api/v1/recordings/" + recording.ID + "/thumbnail"
+			}
+			deleted = append(deleted, recording)
+		}
+	}
+	return deleted, nil
+}
+
+func newFakeStores(now time.Time) (*fakeEventStore, *fakeRecordingStore) {
+	events := &fakeEventStore{timestamps: []time.Time{
+		now.AddDate(0, 0, -100), now.AddDate(0, 0, -95), now.AddDate(0, 0, -10),
+	}}
+	recordings := &fakeRecordingStore{
+		endTimes: []time.Time{now.AddDate(0, 0, -120), now.AddDate(0, 0, -1)},
+		sizes:    []int64{4096, 1024},
+	}
+	return events, recordings
+}
+
+func TestCleaner_DryRunReportsAndDeletesNothing(t *testing.T) {
+	now := time.Now()
+	events, recordings := newFakeStores(now)
+	cleaner := NewCleaner(events, recordings)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 90, 90), true)
+
+	require.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, int64(2), report.Events)
+	assert.Equal(t, int64(1), report.Recordings)
+	assert.Equal(t, int64(4096), report.RecordingBytes)
+
+	assert.Zero(t, events.deletes)
+	assert.Zero(t, recordings.deletes)
+	assert.Len(t, events.timestamps, 3)
+}
+
+func TestCleaner_RunDeletes(t *testing.T) {
+	now := time.Now()
+	events, recordings := newFakeStores(now)
+	cleaner := NewCleaner(events, recordings)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 90, 90), false)
+
+	require.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.Equal(t, int64(2), report.Events)
+	assert.Equal(t, 1, events.deletes)
+	assert.Equal(t, 1, recordings.deletes)
+	assert.Len(t, events.timestamps, 1)
+}
+
+func TestCleaner_ZeroCutoffSkipped(t *testing.T) {
+	now := time.Now()
+	events, recordings := newFakeStores(now)
+	cleaner := NewCleaner(events, recordings)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 0, 30), false)
+
+	require.NoError(t, err)
+	assert.Nil(t, report.EventsBefore)
+	assert.NotNil(t, report.RecordingsBefore)
+	assert.Zero(t, report.Events)
+	assert.Zero(t, events.deletes)
+	assert.Equal(t, int64(1), report.Recordings)
+}
+
+func TestCleaner_RunRemovesRecordingFiles(t *testing.T) {
+	now := time.Now()
+	dir := t.TempDir()
+
+	expired := filepath.Join(dir, "expired.mp4")
+	require.NoError(t, os.WriteFile(expired, []byte("mp4"), 0o644))
+	current := filepath.Join(dir, "current.mp4")
+	require.NoError(t, os.WriteFile(current, []byte("mp4"), 0o644))
+
+	events := &fakeEventStore{}
+	recordings := &fakeRecordingStore{
+		endTimes: []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -35), now.AddDate(0, 0, -31), now.AddDate(0, 0, -1)},
+		sizes:    []int64{3, 0, 0, 3},
+		paths:    []string{expired, filepath.Join(dir, "already-gone.mp4"), "", current},
+	}
+	cleaner := NewCleaner(events, recordings)
+	cleaner.SetOwnedDirs(dir)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 0, 30), false)
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), report.Recordings)
+	assert.Equal(t, 1, report.FilesDeleted)
+	assert.NoFileExists(t, expired)
+	assert.FileExists(t, current)
+}
+
+func TestCleaner_RunKeepsFilesOutsideOwnedDirs(t *testing.T) {
+	now := time.Now()
+	owned := t.TempDir()
+	other := t.TempDir()
+
+	clip := filepath.Join(owned, "clip.mp4")
+	require.NoError(t, os.WriteFile(clip, []byte("mp4"), 0o644))
+	foreign := filepath.Join(other, "recording.mp4")
+	require.NoError(t, os.WriteFile(foreign, []byte("mp4"), 0o644))
+	escaping := filepath.Join(owned, "..", filepath.Base(other), "recording.mp4")
+
+	recordings := &fakeRecordingStore{
+		endTimes: []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -40), now.AddDate(0, 0, -40), now.AddDate(0, 0, -40)},
+		sizes:    []int64{3, 3, 3, 3},
+		paths:    []string{clip, foreign, escaping, "Mp4Record/2026-01-01/RecM01_0000.mp4"},
+	}
+	cleaner := NewCleaner(&fakeEventStore{}, recordings)
+	cleaner.SetOwnedDirs(owned, "")
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 0, 30), false)
+
+	// Recordings are deleted, but only the server's own files are removed
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), report.Recordings)
+	assert.Equal(t, 1, report.FilesDeleted)
+	assert.NoFileExists(t, clip)
+	assert.FileExists(t, foreign)
+
+	// Without owned directories no file is touched
+	require.NoError(t, os.WriteFile(clip, []byte("mp4"), 0o644))
+	recordings.endTimes, recordings.sizes, recordings.paths = []time.Time{now.AddDate(0, 0, -40)}, []int64{3}, []string{clip}
+	report, err = NewCleaner(&fakeEventStore{}, recordings).Run(context.Background(), PolicyForDays(now, 0, 30), false)
+	require.NoError(t, err)
+	assert.Zero(t, report.FilesDeleted)
+	assert.FileExists(t, clip)
+}
+
+func TestCleaner_DryRunKeepsRecordingFiles(t *testing.T) {
+	now := time.Now()
+	expired := filepath.Join(t.TempDir(), "expired.mp4")
+	require.NoError(t, os.WriteFile(expired, []byte("mp4"), 0o644))
+
+	recordings := &fakeRecordingStore{
+		endTimes: []time.Time{now.AddDate(0, 0, -40)},
+		sizes:    []int64{3},
+		paths:    []string{expired},
+	}
+	cleaner := NewCleaner(&fakeEventStore{}, recordings)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 0, 30), true)
+
+	require.NoError(t, err)
+	assert.Zero(t, report.FilesDeleted)
+	assert.FileExists(t, expired)
+}
+
+func TestWorker_RunCycle(t *testing.T) {
+	now := time.Now()
+	events, recordings := newFakeStores(now)
+	worker := NewWorker(NewCleaner(events, recordings), 0, 90, 30)
+
+	assert.Equal(t, DefaultInterval, worker.interval)
+
+	worker.runCycle(context.Background(), now)
+
+	assert.Len(t, events.timestamps, 1)
+	assert.Equal(t, 1, recordings.deletes)
+}
+
+func TestWorker_DisabledWithoutRetentionPeriod(t *testing.T) {
+	events, recordings := newFakeStores(time.Now())
+	worker := NewWorker(NewCleaner(events, recordings), time.Millisecond, 0, 0)
+
+	done := make(chan struct{})
+	go func() {
+		worker.Start(context.Background())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker without retention period should return immediately")
+	}
+	assert.Zero(t, events.deletes)
+	assert.Zero(t, recordings.deletes)
+}
+
+func TestCleaner_RunRemovesThumbnails(t *testing.T) {
+	now := time.Now()
+	clipDir, thumbnailDir := t.TempDir(), t.TempDir()
+
+	clip := filepath.Join(clipDir, "clip.mp4")
+	require.NoError(t, os.WriteFile(clip, []byte("mp4"), 0o644))
+	for _, id := range []string{"rec-0", "rec-1"} {
+		require.NoError(t, os.WriteFile(filepath.Join(thumbnailDir, id+".jpg"), []byte("jpeg"), 0o644))
+	}
+
+	recordings := &fakeRecordingStore{
+		endTimes:   []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -1)},
+		sizes:      []int64{3, 3},
+		paths:      []string{clip, ""},
+		thumbnails: []bool{true, true},
+	}
+	cleaner := NewCleaner(&fakeEventStore{}, recordings)
+	cleaner.SetOwnedDirs(clipDir)
+	cleaner.SetThumbnailDir(thumbnailDir)
+
+	report, err := cleaner.Run(context.Background(), PolicyForDays(now, 0, 30), false)
+
+	// The expired clip goes with its thumbnail, the current one keeps its own
+	require.NoError(t, err)
+	assert.Equal(t, 2, report.FilesDeleted)
+	assert.NoFileExists(t, clip)
+	assert.NoFileExists(t, filepath.Join(thumbnailDir, "rec-0.jpg"))
+	assert.FileExists(t, filepath.Join(thumbnailDir, "rec-1.jpg"))
+}
@@ -0,0 +1,70 @@
+package retention
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mosleyit/reolink_server/internal/logger"
+)
+
+// DefaultInterval is how often the worker runs when no interval is configured
+const DefaultInterval = 24 * time.Hour
+
+// Worker periodically deletes events and recordings past their retention period
+type Worker struct {
+	cleaner       *Cleaner
+	interval      time.Duration
+	eventDays     int
+	recordingDays int
+}
+
+// NewWorker creates a retention worker. A zero day count keeps that kind of
+// data forever.
+func NewWorker(cleaner *Cleaner, interval time.Duration, eventDays, recordingDays int) *Worker {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Worker{
+		cleaner:       cleaner,
+		interval:      interval,
+		eventDays:     eventDays,
+		recordingDays: recordingDays,
+	}
+}
+
+// Start runs a retention cycle right away and then on every interval until ctx is done
+func (w *Worker) Start(ctx context.Context) {
+	if w.eventDays <= 0 && w.recordingDays <= 0 {
+		logger.Info("Retention worker disabled, no retention period configured")
+		return
+	}
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	logger.Info("Retention worker started",
+		zap.Duration("interval", w.interval),
+		zap.Int("event_days", w.eventDays),
+		zap.Int("recording_days", w.recordingDays))
+
+	w.runCycle(ctx, time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Retention worker stopped")
+			return
+		case now := <-ticker.C:
+			w.runCycle(ctx, now)
+		}
+	}
+}
+
+// runCycle deletes the data that has expired at now
+func (w *Worker) runCycle(ctx context.Context, now time.Time) {
+	if _, err := w.cleaner.Run(ctx, PolicyForDays(now, w.eventDays, w.recordingDays), false); err != nil {
+		logger.Error("Retention run failed", zap.Error(err))
+	}
+}
@@ -0,0 +1,5 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_camera_config_history_camera_type;
+
+-- Drop tables
+DROP TABLE IF EXISTS camera_config_history;
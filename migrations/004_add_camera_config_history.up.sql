@@ -0,0 +1,12 @@
+-- Create camera_config_history table for versioned camera config snapshots
+CREATE TABLE IF NOT EXISTS camera_config_history (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    camera_id UUID NOT NULL REFERENCES cameras(id) ON DELETE CASCADE,
+    config_type VARCHAR(50) NOT NULL,
+    version INTEGER NOT NULL,
+    config JSONB NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW(),
+    UNIQUE(camera_id, config_type, version)
+);
+
+CREATE INDEX idx_camera_config_history_camera_type ON camera_config_history(camera_id, config_type, version DESC);
@@ -0,0 +1,12 @@
+-- Add per-camera RTSP transport used when transcoding to HLS
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS rtsp_transport VARCHAR(10) NOT NULL DEFAULT '';
+
+-- Add check constraint for RTSP transport values (skip if already exists)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_camera_rtsp_transport') THEN
+        ALTER TABLE cameras ADD CONSTRAINT check_camera_rtsp_transport
+        CHECK (rtsp_transport IN ('', 'tcp', 'udp'));
+    END IF;
+END $$;
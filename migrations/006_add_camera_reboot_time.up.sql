@@ -0,0 +1,12 @@
+-- Add per-camera daily reboot time ("HH:MM" server local time, empty disables)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS reboot_time VARCHAR(5) NOT NULL DEFAULT '';
+
+-- Add check constraint for reboot time values (skip if already exists)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_camera_reboot_time') THEN
+        ALTER TABLE cameras ADD CONSTRAINT check_camera_reboot_time
+        CHECK (reboot_time = '' OR reboot_time ~ '^([01][0-9]|2[0-3]):[0-5][0-9]$');
+    END IF;
+END $$;
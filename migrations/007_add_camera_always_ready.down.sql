@@ -0,0 +1,3 @@
+-- Remove added column from cameras table
+ALTER TABLE cameras
+    DROP COLUMN IF EXISTS always_ready;
@@ -0,0 +1,3 @@
+-- Add per-camera keep-alive flag (connections of always-ready cameras are kept warm)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS always_ready BOOLEAN NOT NULL DEFAULT FALSE;
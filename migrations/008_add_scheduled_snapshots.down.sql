@@ -0,0 +1,11 @@
+-- Drop indexes
+DROP INDEX IF EXISTS idx_snapshots_camera_id;
+
+-- Drop tables
+DROP TABLE IF EXISTS snapshots;
+
+-- Remove added columns from cameras table
+ALTER TABLE cameras
+    DROP COLUMN IF EXISTS snapshot_enabled,
+    DROP COLUMN IF EXISTS snapshot_interval,
+    DROP COLUMN IF EXISTS snapshot_channel;
@@ -0,0 +1,18 @@
+-- Add per-camera snapshot schedule (stills captured every snapshot_interval seconds)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS snapshot_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS snapshot_interval INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS snapshot_channel INTEGER NOT NULL DEFAULT 0;
+
+-- Create snapshots table for scheduled snapshot files
+CREATE TABLE IF NOT EXISTS snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    camera_id UUID NOT NULL REFERENCES cameras(id) ON DELETE CASCADE,
+    channel INTEGER NOT NULL DEFAULT 0,
+    file_path TEXT NOT NULL,
+    file_size BIGINT NOT NULL,
+    captured_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ DEFAULT NOW()
+);
+
+CREATE INDEX idx_snapshots_camera_id ON snapshots(camera_id, captured_at DESC);
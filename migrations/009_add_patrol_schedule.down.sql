@@ -0,0 +1,5 @@
+-- Remove added columns from cameras table
+ALTER TABLE cameras
+    DROP COLUMN IF EXISTS patrol_schedule,
+    DROP COLUMN IF EXISTS patrol_id,
+    DROP COLUMN IF EXISTS patrol_channel;
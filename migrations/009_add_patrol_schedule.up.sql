@@ -0,0 +1,5 @@
+-- Add per-camera PTZ patrol schedule (patrol patrol_id runs daily during the "HH:MM-HH:MM" window)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS patrol_schedule VARCHAR(11) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS patrol_id INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS patrol_channel INTEGER NOT NULL DEFAULT 0;
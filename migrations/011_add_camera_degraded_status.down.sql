@@ -0,0 +1,6 @@
+-- Degraded cameras are reachable; count them as online again
+UPDATE cameras SET status = 'online' WHERE status = 'degraded';
+
+ALTER TABLE cameras DROP CONSTRAINT IF EXISTS check_camera_status;
+ALTER TABLE cameras ADD CONSTRAINT check_camera_status
+    CHECK (status IN ('online', 'offline', 'error'));
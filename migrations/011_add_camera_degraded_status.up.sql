@@ -0,0 +1,5 @@
+-- Allow the degraded status for cameras that are reachable but failing
+-- features, e.g. with a full or failed disk
+ALTER TABLE cameras DROP CONSTRAINT IF EXISTS check_camera_status;
+ALTER TABLE cameras ADD CONSTRAINT check_camera_status
+    CHECK (status IN ('online', 'degraded', 'offline', 'error'));
@@ -0,0 +1,6 @@
+-- Remove constraint
+ALTER TABLE cameras DROP CONSTRAINT IF EXISTS check_camera_event_mode;
+
+-- Remove added column from cameras table
+ALTER TABLE cameras
+    DROP COLUMN IF EXISTS event_mode;
@@ -0,0 +1,12 @@
+-- Add per-camera event mode: ONVIF PullPoint subscription or polling ('' picks ONVIF when enabled on the camera)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS event_mode VARCHAR(10) NOT NULL DEFAULT '';
+
+-- Add check constraint for event mode values (skip if already exists)
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_camera_event_mode') THEN
+        ALTER TABLE cameras ADD CONSTRAINT check_camera_event_mode
+        CHECK (event_mode IN ('', 'onvif', 'poll'));
+    END IF;
+END $$;
@@ -0,0 +1,3 @@
+-- Add per-camera connection profile bundling timeout, retry, keep-alive and RTSP transport settings ('' uses the server defaults)
+ALTER TABLE cameras
+    ADD COLUMN IF NOT EXISTS connection_profile VARCHAR(50) NOT NULL DEFAULT '';